@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, ttl time.Duration) *Signer {
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return NewSigner([]ed25519.PrivateKey{key}, DefaultIssuer, ttl)
+}
+
+func customer(id int32) Identity {
+	return Identity{UserID: id, Role: RoleCustomer}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer := newTestSigner(t, time.Minute)
+	other := newTestSigner(t, time.Minute)
+	verifier := NewVerifier(StaticKeys(signer.JWKS()), DefaultIssuer)
+
+	t.Run("Valid Token", func(t *testing.T) {
+		// Arrange
+		token, expiresAt, err := signer.Sign(customer(42))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Act
+		identity, err := verifier.Verify(context.Background(), token)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if identity != customer(42) {
+			t.Errorf("expected customer 42, got %+v", identity)
+		}
+		if time.Until(expiresAt) > time.Minute {
+			t.Errorf("expected the token to expire within a minute, got %v", expiresAt)
+		}
+	})
+
+	t.Run("Role Claims", func(t *testing.T) {
+		staff := Identity{UserID: 5, Role: RoleRestaurant, RestaurantID: 3}
+		token, _, _ := signer.Sign(staff)
+
+		identity, err := verifier.Verify(context.Background(), token)
+
+		if err != nil || identity != staff {
+			t.Errorf("expected %+v, got %+v, %v", staff, identity, err)
+		}
+	})
+
+	t.Run("Expired Token", func(t *testing.T) {
+		expired := NewSigner(signer.keys, DefaultIssuer, -time.Hour)
+		token, _, _ := expired.Sign(customer(42))
+
+		_, err := verifier.Verify(context.Background(), token)
+
+		if !errors.Is(err, ErrTokenExpired) {
+			t.Errorf("got error %v, want %v", err, ErrTokenExpired)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		token func() string
+	}{
+		{"Unknown Key", func() string { token, _, _ := other.Sign(customer(42)); return token }},
+		{"Other Issuer", func() string {
+			token, _, _ := NewSigner(signer.keys, "someone-else", time.Minute).Sign(customer(42))
+			return token
+		}},
+		{"Not A Token", func() string { return "change-me" }},
+		{"Unknown Role", func() string { token, _, _ := signer.Sign(Identity{UserID: 42, Role: "owner"}); return token }},
+		{"Restaurant Staff Without Restaurant", func() string {
+			token, _, _ := signer.Sign(Identity{UserID: 42, Role: RoleRestaurant})
+			return token
+		}},
+		{"Service Without Client ID", func() string {
+			token, _, _ := signer.Sign(Identity{Role: RoleService})
+			return token
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token())
+
+			if !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestSignerKeyRotation(t *testing.T) {
+	// Arrange
+	previous := newTestSigner(t, time.Minute)
+	current, _ := GenerateSigningKey()
+	rotated := NewSigner([]ed25519.PrivateKey{current, previous.keys[0]}, DefaultIssuer, time.Minute)
+	verifier := NewVerifier(StaticKeys(rotated.JWKS()), DefaultIssuer)
+	token, _, _ := previous.Sign(customer(7))
+
+	// Act
+	identity, err := verifier.Verify(context.Background(), token)
+
+	// Assert
+	if err != nil || identity.UserID != 7 {
+		t.Errorf("expected tokens of the previous key to stay valid, got %+v, %v", identity, err)
+	}
+	if len(rotated.JWKS().Keys) != 2 {
+		t.Errorf("expected both keys to be published, got %+v", rotated.JWKS())
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	// Arrange
+	signer := newTestSigner(t, time.Minute)
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		json.NewEncoder(w).Encode(signer.JWKS())
+	}))
+	defer server.Close()
+	verifier := NewVerifier(NewRemoteKeySet(server.URL), DefaultIssuer)
+	token, _, _ := signer.Sign(customer(3))
+	unknown, _, _ := newTestSigner(t, time.Minute).Sign(customer(3))
+
+	// Act
+	for range 3 {
+		if _, err := verifier.Verify(context.Background(), token); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	_, err := verifier.Verify(context.Background(), unknown)
+
+	// Assert
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidToken)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected the keys to be fetched once, got %d fetches", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	captureAuditLog(t)
+	signer := newTestSigner(t, time.Minute)
+	verifier := NewVerifier(StaticKeys(signer.JWKS()), DefaultIssuer)
+	token, _, _ := signer.Sign(customer(42))
+	expired, _, _ := NewSigner(signer.keys, DefaultIssuer, -time.Hour).Sign(customer(42))
+
+	var caller *Identity
+	handler := verifier.Middleware(Allow(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := IdentityFrom(r.Context())
+		caller = &identity
+	}, Authenticated))
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+		wantChallenge string
+	}{
+		{"Valid Token", "Bearer " + token, http.StatusOK, ""},
+		{"No Token", "", http.StatusUnauthorized, `Bearer`},
+		{"Expired Token", "Bearer " + expired, http.StatusUnauthorized, `Bearer error="invalid_token", error_description="access token has expired"`},
+		{"Other Scheme", "Basic " + token, http.StatusUnauthorized, `Bearer`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			caller = nil
+			req := httptest.NewRequest(http.MethodGet, "/api/orders", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if got := rr.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("expected challenge %q, got %q", tt.wantChallenge, got)
+			}
+			if tt.wantStatus == http.StatusOK && (caller == nil || caller.UserID != 42) {
+				t.Errorf("expected customer 42 in the request context, got %+v", caller)
+			}
+		})
+	}
+
+	t.Run("Anonymous Routes", func(t *testing.T) {
+		anonymous := false
+		open := verifier.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok := IdentityFrom(r.Context())
+			anonymous = !ok
+		}))
+		req := httptest.NewRequest(http.MethodGet, "/api/restaurants", nil)
+		req.Header.Set("Authorization", "Bearer change-me")
+
+		open.ServeHTTP(httptest.NewRecorder(), req)
+
+		if !anonymous {
+			t.Errorf("expected an unverified token to pass on anonymously")
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// captureAuditLog sends the audit log to a buffer for the test
+func captureAuditLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := auditLog
+	SetAuditLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { SetAuditLogger(previous) })
+	return &buf
+}
+
+func TestAllow(t *testing.T) {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux.HandleFunc("GET /api/customer", Allow(ok))
+	mux.HandleFunc("GET /api/customer/{id}", Allow(ok, Self("id"), Roles(RoleDeliveryAgent)))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/orders/{orderId}/accept", Allow(ok, RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/delivery-agent/{deliveryAgentId}/location", Allow(ok, DeliveryAgent("deliveryAgentId")))
+
+	admin := Identity{UserID: 1, Role: RoleAdmin}
+	staff := Identity{UserID: 5, Role: RoleRestaurant, RestaurantID: 3}
+	agent := Identity{UserID: 6, Role: RoleDeliveryAgent, DeliveryAgentID: 4}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		identity   *Identity
+		wantStatus int
+	}{
+		{"Anonymous", http.MethodGet, "/api/customer/2", nil, http.StatusUnauthorized},
+		{"Admin Only Route As Admin", http.MethodGet, "/api/customer", &admin, http.StatusOK},
+		{"Admin Only Route As Customer", http.MethodGet, "/api/customer", &Identity{UserID: 2, Role: RoleCustomer}, http.StatusForbidden},
+		{"Own Account", http.MethodGet, "/api/customer/2", &Identity{UserID: 2, Role: RoleCustomer}, http.StatusOK},
+		{"Other Account", http.MethodGet, "/api/customer/3", &Identity{UserID: 2, Role: RoleCustomer}, http.StatusForbidden},
+		{"Granted Role", http.MethodGet, "/api/customer/2", &agent, http.StatusOK},
+		{"Own Restaurant", http.MethodPost, "/api/restaurants/3/orders/9/accept", &staff, http.StatusOK},
+		{"Other Restaurant", http.MethodPost, "/api/restaurants/4/orders/9/accept", &staff, http.StatusForbidden},
+		{"Customer With Matching ID", http.MethodPost, "/api/restaurants/2/orders/9/accept", &Identity{UserID: 2, Role: RoleCustomer}, http.StatusForbidden},
+		{"Own Agent", http.MethodPost, "/api/delivery-agent/4/location", &agent, http.StatusOK},
+		{"Other Agent", http.MethodPost, "/api/delivery-agent/5/location", &agent, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			captureAuditLog(t)
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.identity != nil {
+				req = req.WithContext(WithIdentity(req.Context(), *tt.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			// Act
+			mux.ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	// Arrange
+	buf := captureAuditLog(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("DELETE /api/customer/{id}", Allow(func(w http.ResponseWriter, r *http.Request) {}, Self("id")))
+	req := httptest.NewRequest(http.MethodDelete, "/api/customer/3", nil)
+	req = req.WithContext(WithIdentity(req.Context(), customer(2)))
+
+	// Act
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Assert
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected one JSON audit entry, got %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"decision": "deny",
+		"route":    "DELETE /api/customer/{id}",
+		"path":     "/api/customer/3",
+		"user_id":  float64(2),
+		"role":     "customer",
+		"reason":   "no rule matched",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, entry[key])
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		identity   *Identity
+		allowed    bool
+		wantOK     bool
+		wantStatus int
+	}{
+		{"Owner", &Identity{UserID: 2, Role: RoleCustomer}, true, true, http.StatusOK},
+		{"Not The Owner", &Identity{UserID: 2, Role: RoleCustomer}, false, false, http.StatusForbidden},
+		{"Admin", &Identity{UserID: 1, Role: RoleAdmin}, false, true, http.StatusOK},
+		{"Anonymous", nil, true, false, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureAuditLog(t)
+			req := httptest.NewRequest(http.MethodGet, "/api/orders/1", nil)
+			if tt.identity != nil {
+				req = req.WithContext(WithIdentity(req.Context(), *tt.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			ok := Check(rr, req, tt.allowed)
+
+			if ok != tt.wantOK || rr.Code != tt.wantStatus {
+				t.Errorf("expected %v with status %d, got %v with %d", tt.wantOK, tt.wantStatus, ok, rr.Code)
+			}
+		})
+	}
+}
+
+func TestServiceClient(t *testing.T) {
+	// Arrange
+	signer := newTestSigner(t, time.Hour)
+	verifier := NewVerifier(StaticKeys(signer.JWKS()), DefaultIssuer)
+	var issued atomic.Int32
+	tokens := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var credentials ServiceCredentials
+		json.NewDecoder(r.Body).Decode(&credentials)
+		if credentials != (ServiceCredentials{ClientID: "order-service", ClientSecret: "secret"}) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		issued.Add(1)
+		token, _, _ := signer.Sign(Identity{Role: RoleService, Service: credentials.ClientID})
+		json.NewEncoder(w).Encode(map[string]any{"access_token": token, "expires_in": 3600})
+	}))
+	defer tokens.Close()
+	client := NewServiceClient(tokens.URL, ServiceCredentials{ClientID: "order-service", ClientSecret: "secret"})
+
+	// Act
+	var identity Identity
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/api/customer/2/location", nil)
+		if err := client.Authorize(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		token, _ := bearerToken(req)
+		identity, _ = verifier.Verify(context.Background(), token)
+	}
+	wrongSecret := NewServiceClient(tokens.URL, ServiceCredentials{ClientID: "order-service", ClientSecret: "guess"})
+	err := wrongSecret.Authorize(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// Assert
+	if identity != (Identity{Role: RoleService, Service: "order-service"}) {
+		t.Errorf("expected the order service, got %+v", identity)
+	}
+	if issued.Load() != 1 {
+		t.Errorf("expected the token to be reused, got %d tokens", issued.Load())
+	}
+	if err == nil {
+		t.Error("expected an error for the wrong secret")
+	}
+}
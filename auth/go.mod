@@ -0,0 +1,5 @@
+module github.com/rasm445f/soft-exam-2/auth
+
+go 1.23.0
+
+require github.com/golang-jwt/jwt/v5 v5.2.1
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+)
+
+// Role is the kind of actor a user is
+type Role string
+
+const (
+	RoleCustomer      Role = "customer"
+	RoleRestaurant    Role = "restaurant"
+	RoleDeliveryAgent Role = "delivery_agent"
+	RoleAdmin         Role = "admin"
+	// RoleService is another service calling on its own behalf, e.g. to look
+	// up a customer's address while handling a broker event
+	RoleService Role = "service"
+)
+
+// Valid reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	switch r {
+	case RoleCustomer, RoleRestaurant, RoleDeliveryAgent, RoleAdmin, RoleService:
+		return true
+	}
+	return false
+}
+
+// Identity is who is calling, as vouched for by an access token. Restaurant
+// staff carry the restaurant they work for, delivery agents their agent ID,
+// and services the client ID they authenticated with.
+type Identity struct {
+	UserID          int32
+	Role            Role
+	RestaurantID    int32
+	DeliveryAgentID int32
+	Service         string
+}
+
+// IsAdmin reports whether the caller is an MTOGO admin
+func (i Identity) IsAdmin() bool {
+	return i.Role == RoleAdmin
+}
+
+type contextKey int
+
+const (
+	identityKey contextKey = iota
+	tokenErrorKey
+)
+
+// WithIdentity returns a copy of ctx carrying the caller's identity
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey, identity)
+}
+
+// IdentityFrom returns the caller's identity, false for anonymous requests
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,201 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("token is signed with an unknown key")
+
+// JWK is an Ed25519 public key in the JSON Web Key format (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty" example:"OKP"`
+	Curve     string `json:"crv" example:"Ed25519"`
+	X         string `json:"x" example:"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"`
+	KeyID     string `json:"kid" example:"kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k"`
+	Use       string `json:"use" example:"sig"`
+	Algorithm string `json:"alg" example:"EdDSA"`
+}
+
+// JWKS is the set of public keys access tokens may be signed with
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicJWK describes an Ed25519 public key, identified by its RFC 7638
+// thumbprint
+func PublicJWK(key ed25519.PublicKey) JWK {
+	x := base64.RawURLEncoding.EncodeToString(key)
+	// The thumbprint is the hash of the required members in lexical order
+	thumbprint := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         x,
+		KeyID:     base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+		Use:       "sig",
+		Algorithm: "EdDSA",
+	}
+}
+
+// publicKey decodes the key, failing for anything but an Ed25519 key
+func (k JWK) publicKey() (ed25519.PublicKey, error) {
+	if k.KeyType != "OKP" || k.Curve != "Ed25519" {
+		return nil, fmt.Errorf("unsupported key type %s %s", k.KeyType, k.Curve)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// ParseSigningKeys reads PKCS #8 "PRIVATE KEY" PEM blocks holding Ed25519
+// keys. The first key signs new tokens; the others are previous keys, kept
+// so tokens signed before a rotation stay valid until they expire.
+func ParseSigningKeys(data []byte) ([]ed25519.PrivateKey, error) {
+	var keys []ed25519.PrivateKey
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("signing keys must be Ed25519 keys")
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	return keys, nil
+}
+
+// GenerateSigningKey makes a new random Ed25519 key
+func GenerateSigningKey() (ed25519.PrivateKey, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	return key, err
+}
+
+// KeySource finds the public key a token was signed with
+type KeySource interface {
+	PublicKey(ctx context.Context, keyID string) (ed25519.PublicKey, error)
+}
+
+// StaticKeys is a fixed key set
+type StaticKeys JWKS
+
+func (s StaticKeys) PublicKey(ctx context.Context, keyID string) (ed25519.PublicKey, error) {
+	for _, key := range s.Keys {
+		if key.KeyID == keyID {
+			return key.publicKey()
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// How long fetched keys are trusted, and how often an unknown key id may
+// trigger a fetch, so forged key ids cannot flood the issuer
+const (
+	keySetRefresh         = time.Hour
+	keySetMinFetchBackoff = time.Minute
+)
+
+// RemoteKeySet fetches the issuer's public keys from its JWKS endpoint and
+// keeps them, so tokens are verified without calling the issuer. The keys
+// are fetched again once they get old, or when a token is signed with an
+// unknown key after a rotation.
+type RemoteKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (s *RemoteKeySet) PublicKey(ctx context.Context, keyID string) (ed25519.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[keyID]
+	stale := time.Since(s.fetchedAt) > keySetRefresh
+	if ok && !stale {
+		return key, nil
+	}
+	if time.Since(s.triedAt) < keySetMinFetchBackoff {
+		if ok {
+			return key, nil
+		}
+		return nil, ErrUnknownKey
+	}
+
+	s.triedAt = time.Now()
+	if err := s.fetch(ctx); err != nil {
+		// Keep using known keys while the issuer is unreachable
+		if ok {
+			return key, nil
+		}
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	if key, ok := s.keys[keyID]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, s.url)
+	}
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Skip keys this service cannot use rather than failing on them
+		if key, err := jwk.publicKey(); err == nil && jwk.KeyID != "" {
+			keys[jwk.KeyID] = key
+		}
+	}
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// Middleware verifies the bearer token of every request and puts the caller's
+// identity into the request context. Requests without a valid token are
+// passed on anonymously, as some routes need no identity at all; Allow turns
+// them away where one is needed.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		identity, err := v.Verify(ctx, token)
+		if err != nil {
+			ctx = context.WithValue(ctx, tokenErrorKey, err)
+		} else {
+			ctx = WithIdentity(ctx, identity)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// unauthorized answers 401 with a challenge, telling the client why its
+// token was not accepted
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	challenge := `Bearer`
+	if err, ok := r.Context().Value(tokenErrorKey).(error); ok {
+		challenge = `Bearer error="invalid_token", error_description="` + err.Error() + `"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// bearerToken reads the token from the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const defaultServiceTokenURL = "http://localhost:8081/api/auth/service-token"
+
+// Service tokens are fetched again this long before they expire, so a token
+// does not run out on its way to the other service
+const serviceTokenMargin = time.Minute
+
+// ServiceCredentials are what a service authenticates with to get a service
+// access token
+type ServiceCredentials struct {
+	ClientID     string `json:"client_id" example:"order-service"`
+	ClientSecret string `json:"client_secret" example:"change-me"`
+}
+
+// ServiceClient authorizes calls to other services as the service itself,
+// not on behalf of a caller, so calls made from broker consumers and
+// background jobs are authorized too. It trades its credentials for a
+// service access token at the token endpoint and keeps the token until it
+// is about to expire.
+type ServiceClient struct {
+	tokenURL    string
+	credentials ServiceCredentials
+	httpClient  *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewServiceClient(tokenURL string, credentials ServiceCredentials) *ServiceClient {
+	return &ServiceClient{
+		tokenURL:    tokenURL,
+		credentials: credentials,
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// NewServiceClientFromEnv authenticates with SERVICE_CLIENT_ID and
+// SERVICE_CLIENT_SECRET at SERVICE_TOKEN_URL, falling back to the customer
+// service on its local development port
+func NewServiceClientFromEnv() *ServiceClient {
+	url := os.Getenv("SERVICE_TOKEN_URL")
+	if url == "" {
+		url = defaultServiceTokenURL
+	}
+	return NewServiceClient(url, ServiceCredentials{
+		ClientID:     os.Getenv("SERVICE_CLIENT_ID"),
+		ClientSecret: os.Getenv("SERVICE_CLIENT_SECRET"),
+	})
+}
+
+// Authorize puts a service access token on the request
+func (c *ServiceClient) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (c *ServiceClient) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Until(c.expiresAt) > serviceTokenMargin {
+		return c.token, nil
+	}
+
+	body, err := json.Marshal(c.credentials)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get a service token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a service token: unexpected status %d from %s", resp.StatusCode, c.tokenURL)
+	}
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return "", err
+	}
+
+	c.token = tokens.AccessToken
+	c.expiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	return c.token, nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("access token is invalid")
+	ErrTokenExpired = errors.New("access token has expired")
+)
+
+// DefaultIssuer names the customer service, which issues all access tokens
+const (
+	DefaultIssuer  = "customer-service"
+	defaultJWKSURL = "http://localhost:8081/.well-known/jwks.json"
+)
+
+// claims are the registered claims plus the caller's role, and what the
+// role is tied to
+type claims struct {
+	jwt.RegisteredClaims
+	Role            Role   `json:"role"`
+	RestaurantID    int32  `json:"restaurant_id,omitempty"`
+	DeliveryAgentID int32  `json:"delivery_agent_id,omitempty"`
+	Service         string `json:"service,omitempty"`
+}
+
+// Signer issues access tokens signed with the first of its keys
+type Signer struct {
+	keys   []ed25519.PrivateKey
+	keyID  string
+	issuer string
+	ttl    time.Duration
+}
+
+func NewSigner(keys []ed25519.PrivateKey, issuer string, ttl time.Duration) *Signer {
+	return &Signer{
+		keys:   keys,
+		keyID:  PublicJWK(keys[0].Public().(ed25519.PublicKey)).KeyID,
+		issuer: issuer,
+		ttl:    ttl,
+	}
+}
+
+// Sign issues an access token for the identity, returning when it expires
+func (s *Signer) Sign(identity Identity) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    s.issuer,
+			Subject:   strconv.Itoa(int(identity.UserID)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		Role:            identity.Role,
+		RestaurantID:    identity.RestaurantID,
+		DeliveryAgentID: identity.DeliveryAgentID,
+		Service:         identity.Service,
+	})
+	token.Header["kid"] = s.keyID
+
+	signed, err := token.SignedString(s.keys[0])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return signed, expiresAt, nil
+}
+
+// JWKS lists the public keys of the signer, current and previous
+func (s *Signer) JWKS() JWKS {
+	set := JWKS{Keys: make([]JWK, len(s.keys))}
+	for i, key := range s.keys {
+		set.Keys[i] = PublicJWK(key.Public().(ed25519.PublicKey))
+	}
+	return set
+}
+
+// Verifier checks access tokens against the issuer's public keys
+type Verifier struct {
+	keys   KeySource
+	issuer string
+}
+
+func NewVerifier(keys KeySource, issuer string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer}
+}
+
+// NewVerifierFromEnv verifies tokens from JWT_ISSUER with the keys published
+// at JWKS_URL, falling back to the customer service on its local
+// development port
+func NewVerifierFromEnv() *Verifier {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = DefaultIssuer
+	}
+	url := os.Getenv("JWKS_URL")
+	if url == "" {
+		url = defaultJWKSURL
+	}
+	return NewVerifier(NewRemoteKeySet(url), issuer)
+}
+
+// Verify checks the signature, issuer and expiry of an access token and
+// returns who it was issued to. Tokens without a known role, or with a role
+// missing what it is tied to, are invalid.
+func (v *Verifier) Verify(ctx context.Context, token string) (Identity, error) {
+	claims := claims{}
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		keyID, _ := t.Header["kid"].(string)
+		return v.keys.PublicKey(ctx, keyID)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return Identity{}, ErrTokenExpired
+	}
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+
+	userId, err := strconv.ParseInt(claims.Subject, 10, 32)
+	if err != nil || !claims.Role.Valid() {
+		return Identity{}, ErrInvalidToken
+	}
+	if (claims.Role == RoleRestaurant && claims.RestaurantID == 0) ||
+		(claims.Role == RoleDeliveryAgent && claims.DeliveryAgentID == 0) ||
+		(claims.Role == RoleService && claims.Service == "") {
+		return Identity{}, ErrInvalidToken
+	}
+	return Identity{
+		UserID:          int32(userId),
+		Role:            claims.Role,
+		RestaurantID:    claims.RestaurantID,
+		DeliveryAgentID: claims.DeliveryAgentID,
+		Service:         claims.Service,
+	}, nil
+}
@@ -33,4 +33,66 @@ func Consume(queue string, handler func(Event)) {
 			handler(event)
 		}
 	}()
-}
\ No newline at end of file
+}
+
+// ConsumeFanout binds a queue to the fanout exchange and handles every event
+// published to it. An empty queue name creates an exclusive, server-named queue
+// so that every replica of a service receives its own copy of each event, while
+// a named queue is shared between replicas (each event is handled once).
+func ConsumeFanout(exchange string, queue string, handler func(Event)) {
+	channel := GetChannel()
+
+	err := channel.ExchangeDeclare(
+		exchange, // Name
+		"fanout", // Kind
+		true,     // Durable
+		false,    // Auto-deleted
+		false,    // Internal
+		false,    // No-wait
+		nil,      // Arguments
+	)
+	if err != nil {
+		log.Fatalf("Failed to declare exchange: %v", err)
+	}
+
+	q, err := channel.QueueDeclare(
+		queue,       // Name
+		queue != "", // Durable
+		queue == "", // Delete when unused
+		queue == "", // Exclusive
+		false,       // No-wait
+		nil,         // Arguments
+	)
+	if err != nil {
+		log.Fatalf("Failed to declare queue: %v", err)
+	}
+
+	err = channel.QueueBind(q.Name, "", exchange, false, nil)
+	if err != nil {
+		log.Fatalf("Failed to bind queue: %v", err)
+	}
+
+	msgs, err := channel.Consume(
+		q.Name,      // Queue Name
+		"",          // Consumer Name
+		true,        // Auto Acknowledge
+		queue == "", // Exclusive
+		false,       // No Local
+		false,       // No Wait
+		nil,         // Args
+	)
+	if err != nil {
+		log.Fatalf("Failed to register a consumer key: %v", err)
+	}
+
+	go func() {
+		for d := range msgs {
+			var event Event
+			if err := json.Unmarshal(d.Body, &event); err != nil {
+				log.Printf("Failed to parse event: %v", err)
+				continue
+			}
+			handler(event)
+		}
+	}()
+}
@@ -11,4 +11,27 @@ const (
 	MenuItemSelected = "menu_item_selected"
 	CartUpdated = "cart_updated"
 	OrderCreated = "order_created"
-)
\ No newline at end of file
+)
+
+// Order event types, published on the "order_events" fanout exchange
+const (
+	OrderStatusChanged = "order.status_changed"
+	OrderAgentAssigned = "order.agent_assigned"
+	// The delivery estimate is computed after the status change it follows
+	OrderETAUpdated = "order.eta_updated"
+	// Live only, agent locations are not replayed on reconnect
+	AgentLocationUpdated = "agent.location_updated"
+)
+
+// Feedback event types, published on the "feedback_events" fanout exchange
+const (
+	FeedbackCreated = "feedback.created"
+	FeedbackUpdated = "feedback.updated"
+)
+
+// Restaurant event types, published on the "restaurant_events" fanout exchange
+const (
+	MenuUpdated       = "menu.updated"
+	RestaurantUpdated = "restaurant.updated"
+	CategoryUpdated   = "category.updated"
+)
@@ -49,3 +49,46 @@ func Publish(queue string, event Event) error {
 	}
 	return err
 }
+
+// PublishFanout publishes an event to a fanout exchange, so every queue bound
+// to the exchange (e.g. one per service replica) receives a copy.
+func PublishFanout(exchange string, event Event) error {
+	channel := GetChannel()
+
+	err := channel.ExchangeDeclare(
+		exchange, // Name
+		"fanout", // Kind
+		true,     // Durable
+		false,    // Auto-deleted
+		false,    // Internal
+		false,    // No-wait
+		nil,      // Arguments
+	)
+	if err != nil {
+		log.Printf("Failed to declare exchange: %v", err)
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = channel.PublishWithContext(ctx,
+		exchange, // Exchange
+		"",       // Routing Key (ignored by fanout exchanges)
+		false,    // Mandatory
+		false,    // Immediate
+		amqp091.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to publish message: %v", err)
+	}
+	return err
+}
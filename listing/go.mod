@@ -0,0 +1,3 @@
+module github.com/rasm445f/soft-exam-2/listing
+
+go 1.23.0
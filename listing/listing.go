@@ -0,0 +1,93 @@
+// Package listing builds the queries of paged listings. Listings are written
+// by hand rather than generated by sqlc: sqlc queries have a fixed ORDER BY,
+// and sorting on CASE expressions chosen by a parameter keeps Postgres from
+// walking the indexes the sorts have. A listing writes out the filters that
+// are set and the ORDER BY of its whitelisted sort fields.
+package listing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SortKey is the expression a listing is sorted on for a sort field, and the
+// type of cursor values of it. The zero key sorts on the ID alone.
+type SortKey struct {
+	Expr       string
+	CursorType string
+}
+
+// SortKeyOf looks up the key of a sort field. "id" is sortable in every
+// listing, other fields only when they are in keys.
+func SortKeyOf(keys map[string]SortKey, field string) (SortKey, error) {
+	if field == "id" {
+		return SortKey{}, nil
+	}
+	key, ok := keys[field]
+	if !ok {
+		return SortKey{}, fmt.Errorf("unknown sort field %q", field)
+	}
+	return key, nil
+}
+
+// Query builds a listing query with its arguments
+type Query struct {
+	selectFrom string
+	conditions []string
+	args       []any
+}
+
+// New starts a listing query from its SELECT and FROM clauses
+func New(selectFrom string) *Query {
+	return &Query{selectFrom: selectFrom}
+}
+
+// Arg adds an argument, returning its placeholder
+func (q *Query) Arg(value any) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// Where adds a condition rows must meet
+func (q *Query) Where(condition string) {
+	q.conditions = append(q.conditions, condition)
+}
+
+// WhereSet adds the condition when value is set. The condition refers to the
+// value as %[1]s.
+func WhereSet[T any](q *Query, condition string, value *T) {
+	if value != nil {
+		q.Where(fmt.Sprintf(condition, q.Arg(value)))
+	}
+}
+
+// Page sorts the listing on key, with id breaking ties, continues it after
+// the cursor when there is one and limits it to limit rows. It returns the
+// query and its arguments.
+func (q *Query) Page(key SortKey, id string, descending bool, cursorValue any, cursorID *int32, limit int32) (string, []any) {
+	op, direction := ">", "ASC"
+	if descending {
+		op, direction = "<", "DESC"
+	}
+
+	if cursorID != nil {
+		if key.Expr == "" {
+			q.Where(fmt.Sprintf("%s %s %s::int", id, op, q.Arg(cursorID)))
+		} else {
+			value := q.Arg(cursorValue)
+			q.Where(fmt.Sprintf("(%s, %s) %s (%s::%s, %s::int)", key.Expr, id, op, value, key.CursorType, q.Arg(cursorID)))
+		}
+	}
+
+	var sql strings.Builder
+	sql.WriteString(q.selectFrom)
+	if len(q.conditions) > 0 {
+		sql.WriteString("\nWHERE " + strings.Join(q.conditions, "\nAND "))
+	}
+	sql.WriteString("\nORDER BY ")
+	if key.Expr != "" {
+		fmt.Fprintf(&sql, "%s %s, ", key.Expr, direction)
+	}
+	fmt.Fprintf(&sql, "%s %s\nLIMIT %s::int", id, direction, q.Arg(limit))
+	return sql.String(), q.args
+}
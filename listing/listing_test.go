@@ -0,0 +1,70 @@
+package listing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortKeyOf(t *testing.T) {
+	keys := map[string]SortKey{"name": {Expr: "COALESCE(name, '')", CursorType: "text"}}
+
+	if key, err := SortKeyOf(keys, "id"); err != nil || key != (SortKey{}) {
+		t.Errorf("got %+v, %v for id, want the zero key", key, err)
+	}
+	if key, err := SortKeyOf(keys, "name"); err != nil || key != keys["name"] {
+		t.Errorf("got %+v, %v for name, want %+v", key, err, keys["name"])
+	}
+	if _, err := SortKeyOf(keys, "name; DROP TABLE customer"); err == nil {
+		t.Error("expected an error for an unknown sort field")
+	}
+}
+
+func TestPage(t *testing.T) {
+	status := "Pending"
+	cursorID := int32(12)
+	cursorName := "Bo"
+	name := SortKey{Expr: "COALESCE(name, '')", CursorType: "text"}
+
+	tests := []struct {
+		name        string
+		key         SortKey
+		descending  bool
+		cursorValue any
+		cursorID    *int32
+		wantSQL     string
+		wantArgs    []any
+	}{
+		{
+			name:     "First page on the ID",
+			wantSQL:  "SELECT id FROM t\nWHERE status = $1::text\nORDER BY id ASC\nLIMIT $2::int",
+			wantArgs: []any{&status, int32(21)},
+		},
+		{
+			name:        "Next page on a sort key, descending",
+			key:         name,
+			descending:  true,
+			cursorValue: &cursorName,
+			cursorID:    &cursorID,
+			wantSQL: "SELECT id FROM t\nWHERE status = $1::text\nAND (COALESCE(name, ''), id) < ($2::text, $3::int)\n" +
+				"ORDER BY COALESCE(name, '') DESC, id DESC\nLIMIT $4::int",
+			wantArgs: []any{&status, &cursorName, &cursorID, int32(21)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := New("SELECT id FROM t")
+			WhereSet(q, "status = %[1]s::text", &status)
+			WhereSet(q, "zip_code = %[1]s::int", (*int32)(nil))
+
+			sql, args := q.Page(tt.key, "id", tt.descending, tt.cursorValue, tt.cursorID, 21)
+
+			if sql != tt.wantSQL {
+				t.Errorf("got SQL\n%s\nwant\n%s", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -4,22 +4,50 @@
 
 package generated
 
+import (
+	"time"
+)
+
 type Address struct {
-	ID            int32   `json:"id"`
-	StreetAddress *string `json:"street_address"`
-	ZipCode       *int32  `json:"zip_code"`
+	ID            int32    `json:"id"`
+	StreetAddress *string  `json:"street_address"`
+	ZipCode       *int32   `json:"zip_code"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
 }
 
 type Customer struct {
-	ID          int32   `json:"id"`
-	Name        *string `json:"name"`
-	Email       *string `json:"email"`
-	Password    *string `json:"password"`
-	Phonenumber *string `json:"phonenumber"`
-	Addressid   *int32  `json:"addressid"`
+	ID              int32   `json:"id"`
+	Name            *string `json:"name"`
+	Email           *string `json:"email"`
+	Phonenumber     *string `json:"phonenumber"`
+	Addressid       *int32  `json:"addressid"`
+	PasswordHash    *string `json:"password_hash"`
+	Role            string  `json:"role"`
+	RestaurantID    *int32  `json:"restaurant_id"`
+	DeliveryAgentID *int32  `json:"delivery_agent_id"`
+}
+
+type PasswordReset struct {
+	ID         int32      `json:"id"`
+	CustomerID int32      `json:"customer_id"`
+	TokenHash  string     `json:"token_hash"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	UsedAt     *time.Time `json:"used_at"`
+}
+
+type RefreshToken struct {
+	ID         int32      `json:"id"`
+	CustomerID int32      `json:"customer_id"`
+	TokenHash  string     `json:"token_hash"`
+	FamilyID   string     `json:"family_id"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
 }
 
 type Zipcode struct {
-	ZipCode int32   `json:"zip_code"`
-	City    *string `json:"city"`
+	ZipCode   int32    `json:"zip_code"`
+	City      *string  `json:"city"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
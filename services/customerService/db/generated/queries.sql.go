@@ -7,15 +7,18 @@ package generated
 
 import (
 	"context"
+	"time"
 )
 
 const createCustomer = `-- name: CreateCustomer :exec
 WITH new_address AS (
-    INSERT INTO address (street_address, zip_code)
-    VALUES ($1, $2)
+    INSERT INTO address (street_address, zip_code, latitude, longitude)
+    VALUES ($1, $2,
+        (SELECT z.latitude FROM zipcode z WHERE z.zip_code = $2),
+        (SELECT z.longitude FROM zipcode z WHERE z.zip_code = $2))
     RETURNING id AS address_id
 )
-INSERT INTO customer (name, email, phonenumber, addressid, password)
+INSERT INTO customer (name, email, phonenumber, addressid, password_hash)
 VALUES ($3, $4, $5, (SELECT address_id FROM new_address), $6)
 `
 
@@ -25,7 +28,7 @@ type CreateCustomerParams struct {
 	Name          *string `json:"name"`
 	Email         *string `json:"email"`
 	Phonenumber   *string `json:"phonenumber"`
-	Password      *string `json:"password"`
+	PasswordHash  *string `json:"password_hash"`
 }
 
 func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams) error {
@@ -35,7 +38,45 @@ func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams)
 		arg.Name,
 		arg.Email,
 		arg.Phonenumber,
-		arg.Password,
+		arg.PasswordHash,
+	)
+	return err
+}
+
+const createPasswordReset = `-- name: CreatePasswordReset :exec
+INSERT INTO password_reset (customer_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreatePasswordResetParams struct {
+	CustomerID int32     `json:"customer_id"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) error {
+	_, err := q.db.Exec(ctx, createPasswordReset, arg.CustomerID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :exec
+INSERT INTO refresh_token (customer_id, token_hash, family_id, expires_at)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateRefreshTokenParams struct {
+	CustomerID int32     `json:"customer_id"`
+	TokenHash  string    `json:"token_hash"`
+	FamilyID   string    `json:"family_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
+	_, err := q.db.Exec(ctx, createRefreshToken,
+		arg.CustomerID,
+		arg.TokenHash,
+		arg.FamilyID,
+		arg.ExpiresAt,
 	)
 	return err
 }
@@ -65,60 +106,28 @@ func (q *Queries) DeleteCustomer(ctx context.Context, id int32) error {
 	return err
 }
 
-const getAllCustomers = `-- name: GetAllCustomers :many
-SELECT 
-    c.id,
-    c.name,
-    c.email,
-    c.phonenumber,
-    c.password,
-    a.street_address AS street_address,
-    z.zip_code,
-    z.city
-FROM customer c
-LEFT JOIN address a ON c.addressid = a.id
-LEFT JOIN zipcode z ON a.zip_code = z.zip_code
-ORDER BY c.name
+const deleteExpiredPasswordResets = `-- name: DeleteExpiredPasswordResets :execrows
+DELETE FROM password_reset WHERE expires_at < $1
 `
 
-type GetAllCustomersRow struct {
-	ID            int32   `json:"id"`
-	Name          *string `json:"name"`
-	Email         *string `json:"email"`
-	Phonenumber   *string `json:"phonenumber"`
-	Password      *string `json:"password"`
-	StreetAddress *string `json:"street_address"`
-	ZipCode       *int32  `json:"zip_code"`
-	City          *string `json:"city"`
+func (q *Queries) DeleteExpiredPasswordResets(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredPasswordResets, expiresAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
-func (q *Queries) GetAllCustomers(ctx context.Context) ([]GetAllCustomersRow, error) {
-	rows, err := q.db.Query(ctx, getAllCustomers)
+const deleteExpiredRefreshTokens = `-- name: DeleteExpiredRefreshTokens :execrows
+DELETE FROM refresh_token WHERE expires_at < $1
+`
+
+func (q *Queries) DeleteExpiredRefreshTokens(ctx context.Context, expiresAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRefreshTokens, expiresAt)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer rows.Close()
-	var items []GetAllCustomersRow
-	for rows.Next() {
-		var i GetAllCustomersRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Name,
-			&i.Email,
-			&i.Phonenumber,
-			&i.Password,
-			&i.StreetAddress,
-			&i.ZipCode,
-			&i.City,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
 const getCustomerByID = `-- name: GetCustomerByID :one
@@ -127,10 +136,11 @@ SELECT
     c.name,
     c.email,
     c.phonenumber,
-    c.password,
     a.street_address,
     z.zip_code,
-    z.city
+    z.city,
+    a.latitude,
+    a.longitude
 FROM customer c
 LEFT JOIN address a ON c.addressid = a.id
 LEFT JOIN zipcode z ON a.zip_code = z.zip_code
@@ -138,14 +148,15 @@ WHERE c.id = $1
 `
 
 type GetCustomerByIDRow struct {
-	ID            int32   `json:"id"`
-	Name          *string `json:"name"`
-	Email         *string `json:"email"`
-	Phonenumber   *string `json:"phonenumber"`
-	Password      *string `json:"password"`
-	StreetAddress *string `json:"street_address"`
-	ZipCode       *int32  `json:"zip_code"`
-	City          *string `json:"city"`
+	ID            int32    `json:"id"`
+	Name          *string  `json:"name"`
+	Email         *string  `json:"email"`
+	Phonenumber   *string  `json:"phonenumber"`
+	StreetAddress *string  `json:"street_address"`
+	ZipCode       *int32   `json:"zip_code"`
+	City          *string  `json:"city"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
 }
 
 func (q *Queries) GetCustomerByID(ctx context.Context, id int32) (GetCustomerByIDRow, error) {
@@ -156,19 +167,131 @@ func (q *Queries) GetCustomerByID(ctx context.Context, id int32) (GetCustomerByI
 		&i.Name,
 		&i.Email,
 		&i.Phonenumber,
-		&i.Password,
 		&i.StreetAddress,
 		&i.ZipCode,
 		&i.City,
+		&i.Latitude,
+		&i.Longitude,
 	)
 	return i, err
 }
 
+const getCustomerCredentials = `-- name: GetCustomerCredentials :one
+SELECT id, password_hash
+FROM customer
+WHERE email = $1
+`
+
+type GetCustomerCredentialsRow struct {
+	ID           int32   `json:"id"`
+	PasswordHash *string `json:"password_hash"`
+}
+
+func (q *Queries) GetCustomerCredentials(ctx context.Context, email *string) (GetCustomerCredentialsRow, error) {
+	row := q.db.QueryRow(ctx, getCustomerCredentials, email)
+	var i GetCustomerCredentialsRow
+	err := row.Scan(
+		&i.ID,
+		&i.PasswordHash,
+	)
+	return i, err
+}
+
+const getCustomerRole = `-- name: GetCustomerRole :one
+SELECT role, restaurant_id, delivery_agent_id
+FROM customer
+WHERE id = $1
+`
+
+type GetCustomerRoleRow struct {
+	Role            string `json:"role"`
+	RestaurantID    *int32 `json:"restaurant_id"`
+	DeliveryAgentID *int32 `json:"delivery_agent_id"`
+}
+
+func (q *Queries) GetCustomerRole(ctx context.Context, id int32) (GetCustomerRoleRow, error) {
+	row := q.db.QueryRow(ctx, getCustomerRole, id)
+	var i GetCustomerRoleRow
+	err := row.Scan(
+		&i.Role,
+		&i.RestaurantID,
+		&i.DeliveryAgentID,
+	)
+	return i, err
+}
+
+const getRefreshTokenFamily = `-- name: GetRefreshTokenFamily :one
+SELECT family_id FROM refresh_token WHERE token_hash = $1
+`
+
+func (q *Queries) GetRefreshTokenFamily(ctx context.Context, tokenHash string) (string, error) {
+	row := q.db.QueryRow(ctx, getRefreshTokenFamily, tokenHash)
+	var familyId string
+	err := row.Scan(&familyId)
+	return familyId, err
+}
+
+const rehashPassword = `-- name: RehashPassword :exec
+UPDATE customer
+SET password_hash = $1
+WHERE id = $2 AND password_hash = $3
+`
+
+type RehashPasswordParams struct {
+	PasswordHash *string `json:"password_hash"`
+	ID           int32   `json:"id"`
+	CurrentHash  *string `json:"current_hash"`
+}
+
+// Replaces a hash made with outdated parameters, unless the password was
+// changed since it was read
+func (q *Queries) RehashPassword(ctx context.Context, arg RehashPasswordParams) error {
+	_, err := q.db.Exec(ctx, rehashPassword, arg.PasswordHash, arg.ID, arg.CurrentHash)
+	return err
+}
+
+const revokeCustomerRefreshTokens = `-- name: RevokeCustomerRefreshTokens :exec
+UPDATE refresh_token
+SET revoked_at = $2
+WHERE customer_id = $1 AND revoked_at IS NULL
+`
+
+type RevokeCustomerRefreshTokensParams struct {
+	CustomerID int32      `json:"customer_id"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// Logs the customer out everywhere, e.g. after their password was reset
+func (q *Queries) RevokeCustomerRefreshTokens(ctx context.Context, arg RevokeCustomerRefreshTokensParams) error {
+	_, err := q.db.Exec(ctx, revokeCustomerRefreshTokens, arg.CustomerID, arg.RevokedAt)
+	return err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_token
+SET revoked_at = $2
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+type RevokeRefreshTokenFamilyParams struct {
+	FamilyID  string     `json:"family_id"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, arg RevokeRefreshTokenFamilyParams) error {
+	_, err := q.db.Exec(ctx, revokeRefreshTokenFamily, arg.FamilyID, arg.RevokedAt)
+	return err
+}
+
 const updateAddress = `-- name: UpdateAddress :exec
 UPDATE address
 SET 
     street_address = COALESCE($2, street_address),
-    zip_code = COALESCE($3, zip_code)
+    zip_code = COALESCE($3, zip_code),
+    latitude = CASE WHEN $3 IS NULL THEN latitude
+        ELSE (SELECT z.latitude FROM zipcode z WHERE z.zip_code = $3) END,
+    longitude = CASE WHEN $3 IS NULL THEN longitude
+        ELSE (SELECT z.longitude FROM zipcode z WHERE z.zip_code = $3) END
 WHERE id = $1
 `
 
@@ -189,16 +312,16 @@ SET
     name = COALESCE($2, name),
     email = COALESCE($3, email),
     phonenumber = COALESCE($4, phonenumber),
-    password = COALESCE($5, password)
+    password_hash = COALESCE($5, password_hash)
 WHERE id = $1
 `
 
 type UpdateCustomerParams struct {
-	ID          int32   `json:"id"`
-	Name        *string `json:"name"`
-	Email       *string `json:"email"`
-	Phonenumber *string `json:"phonenumber"`
-	Password    *string `json:"password"`
+	ID           int32   `json:"id"`
+	Name         *string `json:"name"`
+	Email        *string `json:"email"`
+	Phonenumber  *string `json:"phonenumber"`
+	PasswordHash *string `json:"password_hash"`
 }
 
 func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) error {
@@ -207,7 +330,82 @@ func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams)
 		arg.Name,
 		arg.Email,
 		arg.Phonenumber,
-		arg.Password,
+		arg.PasswordHash,
 	)
 	return err
 }
+
+const updateCustomerRole = `-- name: UpdateCustomerRole :execrows
+UPDATE customer
+SET role = $2, restaurant_id = $3, delivery_agent_id = $4
+WHERE id = $1
+`
+
+type UpdateCustomerRoleParams struct {
+	ID              int32  `json:"id"`
+	Role            string `json:"role"`
+	RestaurantID    *int32 `json:"restaurant_id"`
+	DeliveryAgentID *int32 `json:"delivery_agent_id"`
+}
+
+func (q *Queries) UpdateCustomerRole(ctx context.Context, arg UpdateCustomerRoleParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateCustomerRole,
+		arg.ID,
+		arg.Role,
+		arg.RestaurantID,
+		arg.DeliveryAgentID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const usePasswordReset = `-- name: UsePasswordReset :one
+UPDATE password_reset
+SET used_at = $1::timestamp
+WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1::timestamp
+RETURNING customer_id
+`
+
+type UsePasswordResetParams struct {
+	Now       time.Time `json:"now"`
+	TokenHash string    `json:"token_hash"`
+}
+
+// Uses up a reset token, returning nothing once it was used or has expired
+func (q *Queries) UsePasswordReset(ctx context.Context, arg UsePasswordResetParams) (int32, error) {
+	row := q.db.QueryRow(ctx, usePasswordReset, arg.Now, arg.TokenHash)
+	var customer_id int32
+	err := row.Scan(&customer_id)
+	return customer_id, err
+}
+
+const useRefreshToken = `-- name: UseRefreshToken :one
+UPDATE refresh_token
+SET revoked_at = $1::timestamp
+WHERE token_hash = $2 AND revoked_at IS NULL AND expires_at > $1::timestamp
+RETURNING customer_id, family_id
+`
+
+type UseRefreshTokenParams struct {
+	Now       time.Time `json:"now"`
+	TokenHash string    `json:"token_hash"`
+}
+
+type UseRefreshTokenRow struct {
+	CustomerID int32  `json:"customer_id"`
+	FamilyID   string `json:"family_id"`
+}
+
+// Uses up a refresh token, returning nothing once it was used, revoked or
+// has expired
+func (q *Queries) UseRefreshToken(ctx context.Context, arg UseRefreshTokenParams) (UseRefreshTokenRow, error) {
+	row := q.db.QueryRow(ctx, useRefreshToken, arg.Now, arg.TokenHash)
+	var i UseRefreshTokenRow
+	err := row.Scan(
+		&i.CustomerID,
+		&i.FamilyID,
+	)
+	return i, err
+}
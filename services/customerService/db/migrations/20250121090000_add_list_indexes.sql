@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination walks this index instead of sorting the whole table
+CREATE INDEX idx_customer_name ON Customer ((COALESCE(Name, '')), ID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_customer_name;
+-- +goose StatementEnd
@@ -0,0 +1,185 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Approximate centre of each zip code, an offline geocoding of the seeded
+-- Danish zip codes. Addresses are placed at the centre of their zip code.
+ALTER TABLE ZipCode
+    ADD COLUMN Latitude double precision,
+    ADD COLUMN Longitude double precision;
+
+UPDATE ZipCode z
+SET Latitude = c.Latitude, Longitude = c.Longitude
+FROM (VALUES
+    (1000, 55.6790, 12.5850),
+    (1050, 55.6790, 12.5850),
+    (1100, 55.6790, 12.5850),
+    (1150, 55.6790, 12.5850),
+    (1200, 55.6790, 12.5850),
+    (1250, 55.6790, 12.5850),
+    (1300, 55.6790, 12.5850),
+    (1350, 55.6790, 12.5850),
+    (1400, 55.6790, 12.5850),
+    (1450, 55.6790, 12.5850),
+    (1500, 55.6700, 12.5550),
+    (1550, 55.6700, 12.5550),
+    (1600, 55.6700, 12.5550),
+    (1650, 55.6700, 12.5550),
+    (1700, 55.6700, 12.5550),
+    (1750, 55.6700, 12.5550),
+    (1800, 55.6780, 12.5350),
+    (1850, 55.6780, 12.5350),
+    (1900, 55.6780, 12.5350),
+    (1950, 55.6780, 12.5350),
+    (2000, 55.6795, 12.5250),
+    (2100, 55.7060, 12.5770),
+    (2150, 55.7180, 12.5990),
+    (2200, 55.6970, 12.5450),
+    (2300, 55.6600, 12.6050),
+    (2400, 55.7070, 12.5250),
+    (2450, 55.6500, 12.5400),
+    (2500, 55.6620, 12.5050),
+    (2600, 55.6660, 12.4030),
+    (2605, 55.6480, 12.4180),
+    (2610, 55.6810, 12.4540),
+    (2620, 55.6570, 12.3630),
+    (2625, 55.6350, 12.3800),
+    (2630, 55.6520, 12.2990),
+    (2635, 55.6150, 12.3520),
+    (2640, 55.6470, 12.1950),
+    (2650, 55.6420, 12.4730),
+    (2660, 55.6230, 12.4180),
+    (2665, 55.6230, 12.3850),
+    (2670, 55.5830, 12.3000),
+    (2680, 55.5320, 12.2200),
+    (2690, 55.5650, 12.2330),
+    (2700, 55.7060, 12.4900),
+    (2720, 55.6870, 12.4910),
+    (2730, 55.7240, 12.4400),
+    (2740, 55.7200, 12.4010),
+    (2750, 55.7310, 12.3630),
+    (2760, 55.7480, 12.3200),
+    (2765, 55.7430, 12.3000),
+    (2770, 55.6300, 12.6400),
+    (2791, 55.5930, 12.6700),
+    (2800, 55.7704, 12.5038),
+    (2820, 55.7500, 12.5500),
+    (2830, 55.7960, 12.4730),
+    (2840, 55.8100, 12.4700),
+    (2850, 55.8160, 12.5330),
+    (2860, 55.7330, 12.5100),
+    (2870, 55.7350, 12.5300),
+    (2880, 55.7600, 12.4550),
+    (2900, 55.7310, 12.5700),
+    (2920, 55.7520, 12.5750),
+    (2930, 55.7700, 12.5900),
+    (2942, 55.8250, 12.5700),
+    (2950, 55.8530, 12.5650),
+    (2960, 55.8850, 12.5450),
+    (2970, 55.8810, 12.5010),
+    (2980, 55.9080, 12.5000),
+    (2990, 55.9330, 12.5070),
+    (3000, 56.0360, 12.6130),
+    (3050, 55.9620, 12.5330),
+    (3060, 55.9940, 12.5470),
+    (3070, 56.0050, 12.5900),
+    (3080, 56.0200, 12.4700),
+    (3100, 56.0900, 12.4570),
+    (3120, 56.1000, 12.3950),
+    (3140, 56.0760, 12.5400),
+    (3150, 56.0670, 12.5600),
+    (3200, 56.0220, 12.1970),
+    (3210, 56.0800, 12.1400),
+    (3220, 56.0550, 12.0800),
+    (3230, 56.0660, 12.2850),
+    (3250, 56.1220, 12.3100),
+    (3300, 55.9700, 12.0200),
+    (3310, 55.9170, 12.0750),
+    (3320, 55.9080, 12.1500),
+    (3330, 55.8800, 12.2000),
+    (3360, 56.0100, 11.9650),
+    (3370, 55.9950, 11.9900),
+    (3390, 55.9640, 11.8550),
+    (3400, 55.9270, 12.3000),
+    (3450, 55.8700, 12.3570),
+    (3460, 55.8470, 12.4270),
+    (3480, 55.9750, 12.4050),
+    (3490, 56.0000, 12.4900),
+    (3500, 55.7830, 12.3700),
+    (3520, 55.8080, 12.3600),
+    (3540, 55.8400, 12.2800),
+    (3550, 55.8500, 12.1800),
+    (3600, 55.8400, 12.0650),
+    (3630, 55.8520, 11.9850),
+    (3650, 55.7960, 12.1600),
+    (3660, 55.7700, 12.1950),
+    (3670, 55.7530, 12.2370),
+    (3700, 55.1000, 14.7000),
+    (3720, 55.0710, 14.9200),
+    (3730, 55.0600, 15.1300),
+    (3740, 55.1360, 15.1420),
+    (3751, 55.1400, 15.0000),
+    (3760, 55.2120, 14.9720),
+    (3770, 55.2760, 14.8020),
+    (3782, 55.1760, 14.8240),
+    (3790, 55.1830, 14.7100),
+    (4000, 55.6420, 12.0800),
+    (4040, 55.7500, 12.1000),
+    (4050, 55.7500, 11.9630),
+    (4060, 55.6500, 11.8700),
+    (4070, 55.7100, 11.8800),
+    (4100, 55.4430, 11.7900),
+    (4130, 55.5480, 12.0250),
+    (4140, 55.4950, 11.9750),
+    (4160, 55.3200, 11.7600),
+    (4171, 55.3550, 11.6950),
+    (4173, 55.4250, 11.6750),
+    (4174, 55.5200, 11.8600),
+    (4180, 55.4320, 11.5550),
+    (4190, 55.4900, 11.5400),
+    (4200, 55.4030, 11.3540),
+    (4220, 55.3300, 11.1400),
+    (4230, 55.2500, 11.2950),
+    (4241, 55.3700, 11.2500),
+    (4242, 55.3000, 11.2700),
+    (4243, 55.2300, 11.4000),
+    (4250, 55.3050, 11.5500),
+    (4261, 55.2900, 11.4200),
+    (4262, 55.2550, 11.5000),
+    (4270, 55.5100, 11.2900),
+    (4281, 55.5400, 11.2300),
+    (4291, 55.5400, 11.3800),
+    (4293, 55.5300, 11.4900),
+    (4295, 55.5400, 11.5900),
+    (4296, 55.4900, 11.6200),
+    (4300, 55.7170, 11.7100),
+    (4320, 55.6050, 11.9700),
+    (4330, 55.5900, 11.8600),
+    (4340, 55.6150, 11.7700),
+    (4350, 55.5850, 11.6500),
+    (4360, 55.5600, 11.7900),
+    (4370, 55.5500, 11.7100),
+    (4390, 55.6650, 11.7350),
+    (4400, 55.6800, 11.0880),
+    (9000, 57.0480, 9.9190)
+) AS c (Zip_Code, Latitude, Longitude)
+WHERE z.Zip_Code = c.Zip_Code;
+
+ALTER TABLE Address
+    ADD COLUMN Latitude double precision,
+    ADD COLUMN Longitude double precision;
+
+UPDATE Address a
+SET Latitude = z.Latitude, Longitude = z.Longitude
+FROM ZipCode z
+WHERE a.Zip_Code = z.Zip_Code;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Address
+    DROP COLUMN Latitude,
+    DROP COLUMN Longitude;
+
+ALTER TABLE ZipCode
+    DROP COLUMN Latitude,
+    DROP COLUMN Longitude;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Passwords are stored as argon2id hashes, never in plaintext. The plaintext
+-- passwords are dropped rather than hashed, as they may have been exposed,
+-- so existing customers have no password until they set a new one with a
+-- password reset (POST /api/auth/password-reset).
+ALTER TABLE Customer ADD COLUMN Password_Hash text;
+ALTER TABLE Customer DROP COLUMN PASSWORD;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The hashes cannot be turned back into passwords
+ALTER TABLE Customer ADD COLUMN PASSWORD varchar(255);
+ALTER TABLE Customer DROP COLUMN Password_Hash;
+-- +goose StatementEnd
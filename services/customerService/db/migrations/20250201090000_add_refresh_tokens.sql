@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Refresh tokens are only stored as SHA-256 hashes. Every refresh replaces
+-- the token with a new one of the same family, so a token used twice gives
+-- away a stolen token and revokes the whole family.
+CREATE TABLE Refresh_Token (
+    ID SERIAL PRIMARY KEY,
+    Customer_ID int NOT NULL REFERENCES Customer(ID) ON DELETE CASCADE,
+    Token_Hash text NOT NULL UNIQUE,
+    Family_ID text NOT NULL,
+    Expires_At timestamp NOT NULL,
+    Revoked_At timestamp
+);
+
+CREATE INDEX idx_refresh_token_family ON Refresh_Token (Family_ID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Refresh_Token;
+-- +goose StatementEnd
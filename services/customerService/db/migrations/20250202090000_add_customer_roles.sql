@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Every account has a role. Restaurant staff are tied to the restaurant they
+-- work for and delivery agents to their agent, both kept by other services,
+-- so there is no foreign key to check them against.
+ALTER TABLE Customer ADD COLUMN Role text NOT NULL DEFAULT 'customer';
+ALTER TABLE Customer ADD COLUMN Restaurant_ID int;
+ALTER TABLE Customer ADD COLUMN Delivery_Agent_ID int;
+ALTER TABLE Customer ADD CONSTRAINT customer_role_check CHECK (
+    (Role IN ('customer', 'admin') AND Restaurant_ID IS NULL AND Delivery_Agent_ID IS NULL)
+    OR (Role = 'restaurant' AND Restaurant_ID IS NOT NULL AND Delivery_Agent_ID IS NULL)
+    OR (Role = 'delivery_agent' AND Delivery_Agent_ID IS NOT NULL AND Restaurant_ID IS NULL)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Customer DROP CONSTRAINT customer_role_check;
+ALTER TABLE Customer DROP COLUMN Delivery_Agent_ID;
+ALTER TABLE Customer DROP COLUMN Restaurant_ID;
+ALTER TABLE Customer DROP COLUMN Role;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Customers who lost their password, including those whose plaintext
+-- password was dropped when passwords were hashed, set a new one with a
+-- one-time reset token. Tokens are only stored as SHA-256 hashes.
+CREATE TABLE Password_Reset (
+    ID SERIAL PRIMARY KEY,
+    Customer_ID int NOT NULL REFERENCES Customer(ID) ON DELETE CASCADE,
+    Token_Hash text NOT NULL UNIQUE,
+    Expires_At timestamp NOT NULL,
+    Used_At timestamp
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE Password_Reset;
+-- +goose StatementEnd
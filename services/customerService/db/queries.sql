@@ -1,10 +1,12 @@
 -- name: CreateCustomer :exec
 WITH new_address AS (
-    INSERT INTO address (street_address, zip_code)
-    VALUES ($1, $2)
+    INSERT INTO address (street_address, zip_code, latitude, longitude)
+    VALUES ($1, $2,
+        (SELECT z.latitude FROM zipcode z WHERE z.zip_code = $2),
+        (SELECT z.longitude FROM zipcode z WHERE z.zip_code = $2))
     RETURNING id AS address_id
 )
-INSERT INTO customer (name, email, phonenumber, addressid, password)
+INSERT INTO customer (name, email, phonenumber, addressid, password_hash)
 VALUES ($3, $4, $5, (SELECT address_id FROM new_address), $6);
 
 
@@ -14,30 +16,18 @@ SELECT
     c.name,
     c.email,
     c.phonenumber,
-    c.password,
     a.street_address,
     z.zip_code,
-    z.city
+    z.city,
+    a.latitude,
+    a.longitude
 FROM customer c
 LEFT JOIN address a ON c.addressid = a.id
 LEFT JOIN zipcode z ON a.zip_code = z.zip_code
 WHERE c.id = $1;
 
--- name: GetAllCustomers :many
-SELECT 
-    c.id,
-    c.name,
-    c.email,
-    c.phonenumber,
-    c.password,
-    a.street_address AS street_address,
-    z.zip_code,
-    z.city
-FROM customer c
-LEFT JOIN address a ON c.addressid = a.id
-LEFT JOIN zipcode z ON a.zip_code = z.zip_code
-ORDER BY c.name;
-
+-- The pages of customers are queried in domain/listings.go, which writes
+-- out the ORDER BY of the requested sort so it can be served from an index
 
 -- name: UpdateCustomer :exec
 UPDATE customer
@@ -45,7 +35,7 @@ SET
     name = COALESCE($2, name),
     email = COALESCE($3, email),
     phonenumber = COALESCE($4, phonenumber),
-    password = COALESCE($5, password)
+    password_hash = COALESCE($5, password_hash)
 WHERE id = $1;
 
 
@@ -53,7 +43,11 @@ WHERE id = $1;
 UPDATE address
 SET 
     street_address = COALESCE($2, street_address),
-    zip_code = COALESCE($3, zip_code)
+    zip_code = COALESCE($3, zip_code),
+    latitude = CASE WHEN $3 IS NULL THEN latitude
+        ELSE (SELECT z.latitude FROM zipcode z WHERE z.zip_code = $3) END,
+    longitude = CASE WHEN $3 IS NULL THEN longitude
+        ELSE (SELECT z.longitude FROM zipcode z WHERE z.zip_code = $3) END
 WHERE id = $1;
 
 
@@ -63,5 +57,70 @@ INSERT INTO zipcode (zip_code, city)
 VALUES ($1, $2)
 ON CONFLICT DO NOTHING;
 
+-- name: GetCustomerCredentials :one
+SELECT id, password_hash
+FROM customer
+WHERE email = $1;
+
+-- Replaces a hash made with outdated parameters, unless the password was
+-- changed since it was read
+-- name: RehashPassword :exec
+UPDATE customer
+SET password_hash = @password_hash
+WHERE id = @id AND password_hash = @current_hash;
+
+-- name: GetCustomerRole :one
+SELECT role, restaurant_id, delivery_agent_id
+FROM customer
+WHERE id = $1;
+
+-- name: UpdateCustomerRole :execrows
+UPDATE customer
+SET role = $2, restaurant_id = $3, delivery_agent_id = $4
+WHERE id = $1;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_token (customer_id, token_hash, family_id, expires_at)
+VALUES ($1, $2, $3, $4);
+
+-- Uses up a refresh token, returning nothing once it was used, revoked or
+-- has expired
+-- name: UseRefreshToken :one
+UPDATE refresh_token
+SET revoked_at = @now::timestamp
+WHERE token_hash = @token_hash AND revoked_at IS NULL AND expires_at > @now::timestamp
+RETURNING customer_id, family_id;
+
+-- name: GetRefreshTokenFamily :one
+SELECT family_id FROM refresh_token WHERE token_hash = $1;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_token
+SET revoked_at = $2
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: DeleteExpiredRefreshTokens :execrows
+DELETE FROM refresh_token WHERE expires_at < $1;
+
+-- name: CreatePasswordReset :exec
+INSERT INTO password_reset (customer_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- Uses up a reset token, returning nothing once it was used or has expired
+-- name: UsePasswordReset :one
+UPDATE password_reset
+SET used_at = @now::timestamp
+WHERE token_hash = @token_hash AND used_at IS NULL AND expires_at > @now::timestamp
+RETURNING customer_id;
+
+-- name: DeleteExpiredPasswordResets :execrows
+DELETE FROM password_reset WHERE expires_at < $1;
+
+-- Logs the customer out everywhere, e.g. after their password was reset
+-- name: RevokeCustomerRefreshTokens :exec
+UPDATE refresh_token
+SET revoked_at = $2
+WHERE customer_id = $1 AND revoked_at IS NULL;
+
 -- name: DeleteCustomer :exec
 DELETE FROM customer WHERE id = $1;
\ No newline at end of file
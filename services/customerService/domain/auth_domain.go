@@ -0,0 +1,221 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Refresh tokens outlive access tokens by far, so customers stay logged in
+// as long as they come back within a month
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken       = errors.New("refresh token is invalid or has expired")
+	ErrInvalidServiceCredentials = errors.New("invalid client id or secret")
+)
+
+type AuthPort interface {
+	LoginDomain(ctx context.Context, email, password string) (Tokens, error)
+	RefreshDomain(ctx context.Context, refreshToken string) (Tokens, error)
+	LogoutDomain(ctx context.Context, refreshToken string) error
+	ServiceTokenDomain(ctx context.Context, credentials auth.ServiceCredentials) (Tokens, error)
+	RequestPasswordResetDomain(ctx context.Context, email string) error
+	ResetPasswordDomain(ctx context.Context, resetToken, password string) error
+	JWKS() auth.JWKS
+}
+
+type AuthDomain struct {
+	queries   *generated.Queries
+	customers CustomerPort
+	signer    *auth.Signer
+	// services maps the client ID of each service allowed to call the
+	// others to its secret
+	services map[string]string
+	sendMail MailSender
+}
+
+func NewAuthDomain(queries *generated.Queries, customers CustomerPort, signer *auth.Signer, services map[string]string, sendMail MailSender) *AuthDomain {
+	return &AuthDomain{queries: queries, customers: customers, signer: signer, services: services, sendMail: sendMail}
+}
+
+// Tokens are handed out on login and refresh. The refresh token can be used
+// once, to get the next tokens. Services get no refresh token, they
+// authenticate again instead.
+type Tokens struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJFZERTQSIsImtpZCI6Ii4uLiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"2bq0Mv9nRZ0xW0w7m3Dk9b5yQm1p8s6Vt4uHc2aLxJE"`
+}
+
+// randomToken returns size random bytes, URL-safe encoded
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken is what is stored of a refresh or reset token. The tokens
+// are random, so a plain hash is enough to keep them from being used if the
+// table leaks.
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// identity reads the user's current role, so role changes take effect on
+// the next refresh
+func (d *AuthDomain) identity(ctx context.Context, customerId int32) (auth.Identity, error) {
+	role, err := d.queries.GetCustomerRole(ctx, customerId)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	identity := auth.Identity{UserID: customerId, Role: auth.Role(role.Role)}
+	if role.RestaurantID != nil {
+		identity.RestaurantID = *role.RestaurantID
+	}
+	if role.DeliveryAgentID != nil {
+		identity.DeliveryAgentID = *role.DeliveryAgentID
+	}
+	return identity, nil
+}
+
+// issueTokens signs an access token for the customer and stores a new
+// refresh token in the family
+func (d *AuthDomain) issueTokens(ctx context.Context, customerId int32, familyId string) (Tokens, error) {
+	identity, err := d.identity(ctx, customerId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	accessToken, expiresAt, err := d.signer.Sign(identity)
+	if err != nil {
+		return Tokens{}, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return Tokens{}, err
+	}
+	err = d.queries.CreateRefreshToken(ctx, generated.CreateRefreshTokenParams{
+		CustomerID: customerId,
+		TokenHash:  hashRefreshToken(refreshToken),
+		FamilyID:   familyId,
+		ExpiresAt:  time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(time.Until(expiresAt).Round(time.Second).Seconds()),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+// LoginDomain checks the customer's credentials and starts a new family of
+// refresh tokens
+func (d *AuthDomain) LoginDomain(ctx context.Context, email, password string) (Tokens, error) {
+	customerId, err := d.customers.VerifyCredentials(ctx, email, password)
+	if err != nil {
+		return Tokens{}, err
+	}
+	familyId, err := randomToken(16)
+	if err != nil {
+		return Tokens{}, err
+	}
+	return d.issueTokens(ctx, customerId, familyId)
+}
+
+// RefreshDomain trades a refresh token for new tokens. A refresh token that
+// was already used may have been stolen, so using it again revokes every
+// token of its family, logging out both the thief and the customer.
+func (d *AuthDomain) RefreshDomain(ctx context.Context, refreshToken string) (Tokens, error) {
+	hash := hashRefreshToken(refreshToken)
+	used, err := d.queries.UseRefreshToken(ctx, generated.UseRefreshTokenParams{Now: time.Now(), TokenHash: hash})
+	if errors.Is(err, pgx.ErrNoRows) {
+		familyId, err := d.queries.GetRefreshTokenFamily(ctx, hash)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Tokens{}, ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return Tokens{}, err
+		}
+		if err := d.revokeFamily(ctx, familyId); err != nil {
+			return Tokens{}, err
+		}
+		log.Printf("Refresh token of family %s used again, revoked the family", familyId)
+		return Tokens{}, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return d.issueTokens(ctx, used.CustomerID, used.FamilyID)
+}
+
+// LogoutDomain revokes the refresh token and the others of its family.
+// Access tokens already handed out stay valid until they expire, as other
+// services verify them without asking this one.
+func (d *AuthDomain) LogoutDomain(ctx context.Context, refreshToken string) error {
+	familyId, err := d.queries.GetRefreshTokenFamily(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return d.revokeFamily(ctx, familyId)
+}
+
+func (d *AuthDomain) revokeFamily(ctx context.Context, familyId string) error {
+	now := time.Now()
+	return d.queries.RevokeRefreshTokenFamily(ctx, generated.RevokeRefreshTokenFamilyParams{
+		FamilyID:  familyId,
+		RevokedAt: &now,
+	})
+}
+
+// PruneRefreshTokensDomain deletes refresh tokens that have expired
+func (d *AuthDomain) PruneRefreshTokensDomain(ctx context.Context) (int64, error) {
+	return d.queries.DeleteExpiredRefreshTokens(ctx, time.Now())
+}
+
+// ServiceTokenDomain issues a service access token to a service that
+// authenticates with its client ID and secret
+func (d *AuthDomain) ServiceTokenDomain(ctx context.Context, credentials auth.ServiceCredentials) (Tokens, error) {
+	secret, ok := d.services[credentials.ClientID]
+	// Comparing digests takes the same time whatever the secrets' lengths
+	given, want := sha256.Sum256([]byte(credentials.ClientSecret)), sha256.Sum256([]byte(secret))
+	if !ok || credentials.ClientID == "" || subtle.ConstantTimeCompare(given[:], want[:]) != 1 {
+		return Tokens{}, ErrInvalidServiceCredentials
+	}
+
+	accessToken, expiresAt, err := d.signer.Sign(auth.Identity{Role: auth.RoleService, Service: credentials.ClientID})
+	if err != nil {
+		return Tokens{}, err
+	}
+	return Tokens{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(time.Until(expiresAt).Round(time.Second).Seconds()),
+	}, nil
+}
+
+// JWKS lists the public keys access tokens are signed with
+func (d *AuthDomain) JWKS() auth.JWKS {
+	return d.signer.JWKS()
+}
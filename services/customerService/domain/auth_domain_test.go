@@ -0,0 +1,346 @@
+package domain
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/auth"
+)
+
+var roleColumns = []string{"role", "restaurant_id", "delivery_agent_id"}
+
+func discardMail(to, subject, body string) error { return nil }
+
+func setupAuthDomain(t *testing.T) (pgxmock.PgxPoolIface, *AuthDomain, *auth.Verifier) {
+	mock, queries, customerDomain := SetupTestMocks(t)
+	customerDomain.passwordParams = testPasswordParams
+
+	key, err := auth.GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := auth.NewSigner([]ed25519.PrivateKey{key}, auth.DefaultIssuer, 15*time.Minute)
+	verifier := auth.NewVerifier(auth.StaticKeys(signer.JWKS()), auth.DefaultIssuer)
+
+	return mock, NewAuthDomain(queries, customerDomain, signer, map[string]string{"order-service": "secret"}, discardMail), verifier
+}
+
+func TestLoginDomain(t *testing.T) {
+	hash, _ := HashPassword("Password1!", testPasswordParams)
+
+	t.Run("Valid Credentials", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, verifier := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("alice@example.com")).
+			WillReturnRows(pgxmock.NewRows(credentialColumns).AddRow(int32(1), &hash))
+		mock.ExpectQuery(`SELECT role, restaurant_id, delivery_agent_id`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows(roleColumns).AddRow("customer", nil, nil))
+		mock.ExpectExec(`INSERT INTO refresh_token`).
+			WithArgs(int32(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		// Act
+		tokens, err := authDomain.LoginDomain(context.Background(), "alice@example.com", "Password1!")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		identity, err := verifier.Verify(context.Background(), tokens.AccessToken)
+		if err != nil || identity != (auth.Identity{UserID: 1, Role: auth.RoleCustomer}) {
+			t.Errorf("expected an access token for customer 1, got %+v, %v", identity, err)
+		}
+		if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 900 || tokens.RefreshToken == "" {
+			t.Errorf("unexpected tokens: %+v", tokens)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("alice@example.com")).
+			WillReturnRows(pgxmock.NewRows(credentialColumns).AddRow(int32(1), &hash))
+
+		_, err := authDomain.LoginDomain(context.Background(), "alice@example.com", "Password2!")
+
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestRefreshDomain(t *testing.T) {
+	refreshHash := hashRefreshToken("refresh-token")
+
+	t.Run("Rotates The Refresh Token", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, verifier := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`UPDATE refresh_token`).
+			WithArgs(pgxmock.AnyArg(), refreshHash).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id", "family_id"}).AddRow(int32(1), "family"))
+		mock.ExpectQuery(`SELECT role, restaurant_id, delivery_agent_id`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows(roleColumns).AddRow("restaurant", int32Ptr(3), nil))
+		mock.ExpectExec(`INSERT INTO refresh_token`).
+			WithArgs(int32(1), pgxmock.AnyArg(), "family", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		// Act
+		tokens, err := authDomain.RefreshDomain(context.Background(), "refresh-token")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tokens.RefreshToken == "" || tokens.RefreshToken == "refresh-token" {
+			t.Errorf("expected a new refresh token, got %q", tokens.RefreshToken)
+		}
+		identity, err := verifier.Verify(context.Background(), tokens.AccessToken)
+		staff := auth.Identity{UserID: 1, Role: auth.RoleRestaurant, RestaurantID: 3}
+		if err != nil || identity != staff {
+			t.Errorf("expected the current role in the access token, got %+v, %v", identity, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Reuse Revokes The Family", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`UPDATE refresh_token`).
+			WithArgs(pgxmock.AnyArg(), refreshHash).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectQuery(`SELECT family_id FROM refresh_token`).
+			WithArgs(refreshHash).
+			WillReturnRows(pgxmock.NewRows([]string{"family_id"}).AddRow("family"))
+		mock.ExpectExec(`UPDATE refresh_token\s+SET revoked_at = \$2\s+WHERE family_id = \$1`).
+			WithArgs("family", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+		// Act
+		_, err := authDomain.RefreshDomain(context.Background(), "refresh-token")
+
+		// Assert
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidRefreshToken)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`UPDATE refresh_token`).WithArgs(pgxmock.AnyArg(), refreshHash).WillReturnError(pgx.ErrNoRows)
+		mock.ExpectQuery(`SELECT family_id FROM refresh_token`).WithArgs(refreshHash).WillReturnError(pgx.ErrNoRows)
+
+		_, err := authDomain.RefreshDomain(context.Background(), "refresh-token")
+
+		if !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidRefreshToken)
+		}
+	})
+}
+
+func TestLogoutDomain(t *testing.T) {
+	t.Run("Revokes The Family", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`SELECT family_id FROM refresh_token`).
+			WithArgs(hashRefreshToken("refresh-token")).
+			WillReturnRows(pgxmock.NewRows([]string{"family_id"}).AddRow("family"))
+		mock.ExpectExec(`UPDATE refresh_token`).
+			WithArgs("family", pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		// Act
+		err := authDomain.LogoutDomain(context.Background(), "refresh-token")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`SELECT family_id FROM refresh_token`).WithArgs(hashRefreshToken("refresh-token")).WillReturnError(pgx.ErrNoRows)
+
+		err := authDomain.LogoutDomain(context.Background(), "refresh-token")
+
+		if err != nil {
+			t.Errorf("expected logging out twice to succeed, got %v", err)
+		}
+	})
+}
+
+func TestServiceTokenDomain(t *testing.T) {
+	mock, authDomain, verifier := setupAuthDomain(t)
+	defer CloseMocks(mock)
+
+	t.Run("Valid Credentials", func(t *testing.T) {
+		// Act
+		tokens, err := authDomain.ServiceTokenDomain(context.Background(), auth.ServiceCredentials{ClientID: "order-service", ClientSecret: "secret"})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		identity, err := verifier.Verify(context.Background(), tokens.AccessToken)
+		if err != nil || identity != (auth.Identity{Role: auth.RoleService, Service: "order-service"}) {
+			t.Errorf("expected a token for the order service, got %+v, %v", identity, err)
+		}
+		if tokens.RefreshToken != "" {
+			t.Errorf("expected no refresh token for a service")
+		}
+	})
+
+	tests := []struct {
+		name        string
+		credentials auth.ServiceCredentials
+	}{
+		{"Wrong Secret", auth.ServiceCredentials{ClientID: "order-service", ClientSecret: "guess"}},
+		{"Unknown Client", auth.ServiceCredentials{ClientID: "other-service", ClientSecret: "secret"}},
+		{"No Credentials", auth.ServiceCredentials{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := authDomain.ServiceTokenDomain(context.Background(), tt.credentials)
+
+			if !errors.Is(err, ErrInvalidServiceCredentials) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidServiceCredentials)
+			}
+		})
+	}
+}
+
+func TestRequestPasswordResetDomain(t *testing.T) {
+	t.Run("Known Email", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		var sentTo, sentBody string
+		authDomain.sendMail = func(to, subject, body string) error {
+			sentTo, sentBody = to, body
+			return nil
+		}
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("alice@example.com")).
+			WillReturnRows(pgxmock.NewRows(credentialColumns).AddRow(int32(1), nil))
+		mock.ExpectExec(`INSERT INTO password_reset`).
+			WithArgs(int32(1), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+		// Act
+		err := authDomain.RequestPasswordResetDomain(context.Background(), "alice@example.com")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if sentTo != "alice@example.com" || sentBody == "" {
+			t.Errorf("expected the reset token to be emailed to alice, got %q", sentTo)
+		}
+	})
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		authDomain.sendMail = func(to, subject, body string) error {
+			t.Errorf("expected no email for an unknown email")
+			return nil
+		}
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("nobody@example.com")).
+			WillReturnError(pgx.ErrNoRows)
+
+		// Act
+		err := authDomain.RequestPasswordResetDomain(context.Background(), "nobody@example.com")
+
+		// Assert
+		if err != nil {
+			t.Errorf("expected unknown emails to be ignored, got %v", err)
+		}
+	})
+}
+
+func TestResetPasswordDomain(t *testing.T) {
+	t.Run("Valid Token", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`UPDATE password_reset`).
+			WithArgs(pgxmock.AnyArg(), hashRefreshToken("reset")).
+			WillReturnRows(pgxmock.NewRows([]string{"customer_id"}).AddRow(int32(1)))
+		mock.ExpectExec(`UPDATE customer`).
+			WithArgs(int32(1), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE refresh_token\s+SET revoked_at = \$2\s+WHERE customer_id = \$1`).
+			WithArgs(int32(1), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+
+		// Act
+		err := authDomain.ResetPasswordDomain(context.Background(), "reset", "Password1!")
+
+		// Assert
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Weak Password", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+
+		// Act
+		err := authDomain.ResetPasswordDomain(context.Background(), "reset", "weak")
+
+		// Assert
+		if !errors.Is(err, ErrInvalidPassword) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidPassword)
+		}
+	})
+
+	t.Run("Used Or Expired Token", func(t *testing.T) {
+		// Arrange
+		mock, authDomain, _ := setupAuthDomain(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`UPDATE password_reset`).
+			WithArgs(pgxmock.AnyArg(), hashRefreshToken("reset")).
+			WillReturnError(pgx.ErrNoRows)
+
+		// Act
+		err := authDomain.ResetPasswordDomain(context.Background(), "reset", "Password1!")
+
+		// Assert
+		if !errors.Is(err, ErrInvalidResetToken) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidResetToken)
+		}
+	})
+}
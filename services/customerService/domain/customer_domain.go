@@ -6,22 +6,69 @@ import (
 	"errors"
 	"log"
 	"regexp"
+	"sync"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	"github.com/rasm445f/soft-exam-2/mailer"
 )
 
 type CustomerPort interface {
-	GetAllCustomersDomain(ctx context.Context) ([]generated.GetAllCustomersRow, error)
+	GetAllCustomersDomain(ctx context.Context, filter CustomerFilter, list ListParams) (*Page[CustomerListing], error)
 	GetCustomerByIdDomain(ctx context.Context, id int32) (generated.GetCustomerByIDRow, error)
 	DeleteCustomerDomain(ctx context.Context, id int32) error
-	CreateCustomerDomain(ctx context.Context, customerParams generated.CreateCustomerParams) error
-	UpdateCustomerDomain(ctx context.Context, customerParams generated.UpdateCustomerParams) error
+	CreateCustomerDomain(ctx context.Context, customerParams CustomerParams) error
+	UpdateCustomerDomain(ctx context.Context, customerParams CustomerUpdateParams) error
 	UpdateAddress(ctx context.Context, addressParams generated.UpdateAddressParams) error
+	VerifyCredentials(ctx context.Context, email, password string) (int32, error)
+	UpdateCustomerRoleDomain(ctx context.Context, id int32, roleParams RoleParams) error
 }
 
+var (
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrInvalidRole        = errors.New("restaurant staff need a restaurant, delivery agents an agent ID, and other roles neither")
+	ErrCustomerNotFound   = errors.New("customer not found")
+)
+
 type CustomerDomain struct {
-	Queries *generated.Queries
+	Queries        *generated.Queries
+	db             generated.DBTX
+	passwordParams PasswordParams
+
+	// dummyHash is verified for unknown emails, so they take as long to
+	// reject as wrong passwords
+	dummyHash     string
+	dummyHashOnce sync.Once
+}
+
+// CustomerParams signs up a customer. The password is hashed before it is
+// stored.
+type CustomerParams struct {
+	StreetAddress *string `json:"street_address" example:"123 Main St"`
+	ZipCode       *int32  `json:"zip_code" example:"2800"`
+	Name          *string `json:"name" example:"John Doe"`
+	Email         *string `json:"email" example:"john.doe@example.com"`
+	Phonenumber   *string `json:"phonenumber" example:"12345678"`
+	Password      *string `json:"password" example:"Password123!"`
+}
+
+// CustomerUpdateParams changes a customer. Nil fields are left unchanged.
+type CustomerUpdateParams struct {
+	ID          int32
+	Name        *string
+	Email       *string
+	Phonenumber *string
+	Password    *string
+}
+
+// RoleParams makes an account a customer, restaurant staff, delivery agent
+// or admin. Restaurant staff are tied to their restaurant and delivery agents
+// to their agent ID.
+type RoleParams struct {
+	Role            auth.Role `json:"role" example:"restaurant"`
+	RestaurantID    *int32    `json:"restaurant_id" example:"1"`
+	DeliveryAgentID *int32    `json:"delivery_agent_id"`
 }
 
 // Define individual regex patterns
@@ -69,12 +116,65 @@ func ValidateEmail(email string) error {
 	return nil
 }
 
-func NewCustomerDomain(queries *generated.Queries) *CustomerDomain {
-	return &CustomerDomain{Queries: queries}
+// NewCustomerDomain initializes the domain layer. Customers are listed on db.
+func NewCustomerDomain(queries *generated.Queries, db generated.DBTX) *CustomerDomain {
+	return &CustomerDomain{Queries: queries, db: db, passwordParams: DefaultPasswordParams}
 }
 
-func (d *CustomerDomain) GetAllCustomersDomain(ctx context.Context) ([]generated.GetAllCustomersRow, error) {
-	return d.Queries.GetAllCustomers(ctx)
+// CustomerFilter narrows the listed customers. Search matches part of the
+// name or email. Nil fields do not filter.
+type CustomerFilter struct {
+	ZipCode *int32
+	Search  *string
+}
+
+// CustomerSortFields are the fields customers can be sorted on, by name by default
+var CustomerSortFields = []string{"id", "name"}
+
+func (d *CustomerDomain) GetAllCustomersDomain(ctx context.Context, filter CustomerFilter, list ListParams) (*Page[CustomerListing], error) {
+	sortField, descending, err := list.sortField(CustomerSortFields, "name")
+	if err != nil {
+		return nil, err
+	}
+	after, err := list.decodeCursor(list.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	params := customerListParams{
+		ZipCode:    filter.ZipCode,
+		Search:     filter.Search,
+		SortField:  sortField,
+		Descending: descending,
+		PageLimit:  int32(list.limit() + 1),
+	}
+	if after != nil {
+		params.CursorID = &after.ID
+		if sortField == "name" {
+			params.CursorName = &after.Value
+		}
+	}
+
+	customers, err := listCustomers(ctx, d.db, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return newPage(customers, list.limit(), func(last CustomerListing) cursor {
+		next := cursor{Sort: list.Sort, ID: last.ID}
+		if sortField == "name" && last.Name != nil {
+			next.Value = *last.Name
+		}
+		return next
+	}), nil
+}
+
+// CustomerLocation is where a customer's orders are delivered to, all other
+// services need to know about the customer
+type CustomerLocation struct {
+	ZipCode   *int32   `json:"zip_code" example:"2800"`
+	Latitude  *float64 `json:"latitude" example:"55.7704"`
+	Longitude *float64 `json:"longitude" example:"12.5038"`
 }
 
 func (d *CustomerDomain) GetCustomerByIdDomain(ctx context.Context, id int32) (generated.GetCustomerByIDRow, error) {
@@ -85,8 +185,9 @@ func (d *CustomerDomain) DeleteCustomerDomain(ctx context.Context, id int32) err
 	return d.Queries.DeleteCustomer(ctx, id)
 }
 
-func (d *CustomerDomain) CreateCustomerDomain(ctx context.Context, customerParams generated.CreateCustomerParams) error {
-	if *customerParams.Name == "" || *customerParams.Email == "" || *customerParams.Password == "" {
+func (d *CustomerDomain) CreateCustomerDomain(ctx context.Context, customerParams CustomerParams) error {
+	if customerParams.Name == nil || customerParams.Email == nil || customerParams.Password == nil ||
+		*customerParams.Name == "" || *customerParams.Email == "" || *customerParams.Password == "" {
 		return errors.New("all required fields must be filled")
 	}
 
@@ -98,7 +199,19 @@ func (d *CustomerDomain) CreateCustomerDomain(ctx context.Context, customerParam
 		return err
 	}
 
-	err := d.Queries.CreateCustomer(ctx, customerParams)
+	hash, err := HashPassword(*customerParams.Password, d.passwordParams)
+	if err != nil {
+		return err
+	}
+
+	err = d.Queries.CreateCustomer(ctx, generated.CreateCustomerParams{
+		StreetAddress: customerParams.StreetAddress,
+		ZipCode:       customerParams.ZipCode,
+		Name:          customerParams.Name,
+		Email:         customerParams.Email,
+		Phonenumber:   customerParams.Phonenumber,
+		PasswordHash:  &hash,
+	})
 	if err != nil {
 		return err
 	}
@@ -139,12 +252,24 @@ func (d *CustomerDomain) CreateCustomerDomain(ctx context.Context, customerParam
 	return nil
 }
 
-func (d *CustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParams generated.UpdateCustomerParams) error {
+func (d *CustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParams CustomerUpdateParams) error {
+	update := generated.UpdateCustomerParams{
+		ID:          customerParams.ID,
+		Name:        customerParams.Name,
+		Email:       customerParams.Email,
+		Phonenumber: customerParams.Phonenumber,
+	}
+
 	// Validate optional fields if provided
 	if customerParams.Password != nil {
 		if err := ValidatePassword(*customerParams.Password); err != nil {
 			return err
 		}
+		hash, err := HashPassword(*customerParams.Password, d.passwordParams)
+		if err != nil {
+			return err
+		}
+		update.PasswordHash = &hash
 	}
 
 	if customerParams.Email != nil {
@@ -153,7 +278,7 @@ func (d *CustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParam
 		}
 	}
 
-	err := d.Queries.UpdateCustomer(ctx, customerParams)
+	err := d.Queries.UpdateCustomer(ctx, update)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New("customer not found")
@@ -164,6 +289,41 @@ func (d *CustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParam
 	return nil
 }
 
+// UpdateCustomerRoleDomain changes the role of an account. It takes effect
+// when the user next logs in or refreshes their tokens.
+func (d *CustomerDomain) UpdateCustomerRoleDomain(ctx context.Context, id int32, roleParams RoleParams) error {
+	switch roleParams.Role {
+	case auth.RoleCustomer, auth.RoleAdmin:
+		if roleParams.RestaurantID != nil || roleParams.DeliveryAgentID != nil {
+			return ErrInvalidRole
+		}
+	case auth.RoleRestaurant:
+		if roleParams.RestaurantID == nil || roleParams.DeliveryAgentID != nil {
+			return ErrInvalidRole
+		}
+	case auth.RoleDeliveryAgent:
+		if roleParams.DeliveryAgentID == nil || roleParams.RestaurantID != nil {
+			return ErrInvalidRole
+		}
+	default:
+		return ErrInvalidRole
+	}
+
+	updated, err := d.Queries.UpdateCustomerRole(ctx, generated.UpdateCustomerRoleParams{
+		ID:              id,
+		Role:            string(roleParams.Role),
+		RestaurantID:    roleParams.RestaurantID,
+		DeliveryAgentID: roleParams.DeliveryAgentID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrCustomerNotFound
+	}
+	return nil
+}
+
 func (d *CustomerDomain) UpdateAddress(ctx context.Context, addressParams generated.UpdateAddressParams) error {
 	err := d.Queries.UpdateAddress(ctx, addressParams)
 	if err != nil {
@@ -175,3 +335,47 @@ func (d *CustomerDomain) UpdateAddress(ctx context.Context, addressParams genera
 
 	return nil
 }
+
+// VerifyCredentials finds the customer with the email and password. Hashes
+// made with outdated parameters are replaced on the way. Unknown emails,
+// wrong passwords and customers without a password all fail with
+// ErrInvalidCredentials.
+func (d *CustomerDomain) VerifyCredentials(ctx context.Context, email, password string) (int32, error) {
+	d.dummyHashOnce.Do(func() {
+		d.dummyHash, _ = HashPassword("", d.passwordParams)
+	})
+
+	credentials, err := d.Queries.GetCustomerCredentials(ctx, &email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+	found := err == nil && credentials.PasswordHash != nil
+	hash := d.dummyHash
+	if found {
+		hash = *credentials.PasswordHash
+	}
+
+	match, outdated, err := VerifyPassword(password, hash, d.passwordParams)
+	if err != nil {
+		return 0, err
+	}
+	if !found || !match {
+		return 0, ErrInvalidCredentials
+	}
+
+	if outdated {
+		rehashed, err := HashPassword(password, d.passwordParams)
+		if err == nil {
+			err = d.Queries.RehashPassword(ctx, generated.RehashPasswordParams{
+				PasswordHash: &rehashed,
+				ID:           credentials.ID,
+				CurrentHash:  credentials.PasswordHash,
+			})
+		}
+		if err != nil {
+			log.Println("Failed to rehash password:", err)
+		}
+	}
+
+	return credentials.ID, nil
+}
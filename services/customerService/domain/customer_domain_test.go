@@ -3,10 +3,12 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"regexp"
 	"testing"
 
 	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 )
 
@@ -17,7 +19,7 @@ func SetupTestMocks(t *testing.T) (pgxmock.PgxPoolIface, *generated.Queries, *Cu
 	}
 
 	queries := generated.New(mock)
-	domain := NewCustomerDomain(queries)
+	domain := NewCustomerDomain(queries, mock)
 
 	return mock, queries, domain
 }
@@ -34,6 +36,10 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
 func TestGetAllCustomersDomain(t *testing.T) {
 	mock, _, customerDomain := SetupTestMocks(t)
 	defer CloseMocks(mock)
@@ -41,56 +47,49 @@ func TestGetAllCustomersDomain(t *testing.T) {
 	t.Run("Valid Data", func(t *testing.T) {
 		// Arrange
 		// Use your actual query string here
-		expectedQuery := `SELECT 
-            c.id,
-            c.name,
-            c.email,
-            c.phonenumber,
-            c.password,
-            a.street_address AS street_address,
-            z.zip_code,
-            z.city
-        FROM customer c
-        LEFT JOIN address a ON c.addressid = a.id
-        LEFT JOIN zipcode z ON a.zip_code = z.zip_code
-        ORDER BY c.name`
+		expectedQuery := `LEFT JOIN zipcode z ON a.zip_code = z.zip_code
+        ORDER BY COALESCE(c.name, '') ASC, c.id ASC`
 
 		rows := pgxmock.NewRows([]string{
-			"id", "name", "email", "phonenumber", "password", "street_address", "zip_code", "city",
+			"id", "name", "email", "phonenumber", "street_address", "zip_code", "city", "latitude", "longitude",
 		}).
 			AddRow(
 				int32(1),
 				stringPtr("Alice Wonderland"),
 				stringPtr("alice@example.com"),
 				stringPtr("1234567890"),
-				stringPtr("somehashedpass"),
 				stringPtr("123 Main St"),
 				int32Ptr(12345),
 				stringPtr("ExampleCity"),
+				nil,
+				nil,
 			).
 			AddRow(
 				int32(2),
 				stringPtr("Bob Builder"),
 				stringPtr("bob@example.com"),
 				stringPtr("0987654321"),
-				stringPtr("anotherhashedpass"),
 				stringPtr("456 Elm St"),
 				int32Ptr(67890),
 				stringPtr("OtherCity"),
+				nil,
+				nil,
 			)
 
 		// Expect query and return rows
-		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).WillReturnRows(rows)
+		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+			WithArgs(int32(21)).
+			WillReturnRows(rows)
 
 		// Act
-		got, err := customerDomain.GetAllCustomersDomain(context.Background())
+		got, err := customerDomain.GetAllCustomersDomain(context.Background(), CustomerFilter{}, ListParams{})
 
 		// Assert
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if len(got) != 2 {
-			t.Errorf("expected 2 customers, got %d", len(got))
+		if len(got.Items) != 2 || got.NextCursor != nil {
+			t.Errorf("expected 2 customers on the last page, got %+v", got)
 		}
 
 		// (Optional) Example: check specific fields
@@ -104,36 +103,27 @@ func TestGetAllCustomersDomain(t *testing.T) {
 
 	t.Run("No Data", func(t *testing.T) {
 		// Arrange
-		expectedQuery := `SELECT 
-            c.id,
-            c.name,
-            c.email,
-            c.phonenumber,
-            c.password,
-            a.street_address AS street_address,
-            z.zip_code,
-            z.city
-        FROM customer c
-        LEFT JOIN address a ON c.addressid = a.id
-        LEFT JOIN zipcode z ON a.zip_code = z.zip_code
-        ORDER BY c.name`
+		expectedQuery := `LEFT JOIN zipcode z ON a.zip_code = z.zip_code
+        ORDER BY COALESCE(c.name, '') ASC, c.id ASC`
 
 		// Return an empty result set
 		emptyRows := pgxmock.NewRows([]string{
-			"id", "name", "email", "phonenumber", "password", "street_address", "zip_code", "city",
+			"id", "name", "email", "phonenumber", "street_address", "zip_code", "city", "latitude", "longitude",
 		})
 
-		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).WillReturnRows(emptyRows)
+		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+			WithArgs(int32(21)).
+			WillReturnRows(emptyRows)
 
 		// Act
-		got, err := customerDomain.GetAllCustomersDomain(context.Background())
+		got, err := customerDomain.GetAllCustomersDomain(context.Background(), CustomerFilter{}, ListParams{})
 
 		// Assert
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if len(got) != 0 {
-			t.Errorf("expected 0 customers, got %d", len(got))
+		if len(got.Items) != 0 {
+			t.Errorf("expected 0 customers, got %d", len(got.Items))
 		}
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("unmet mock expectations: %v", err)
@@ -142,25 +132,15 @@ func TestGetAllCustomersDomain(t *testing.T) {
 
 	t.Run("Database Error", func(t *testing.T) {
 		// Arrange
-		expectedQuery := `SELECT 
-            c.id,
-            c.name,
-            c.email,
-            c.phonenumber,
-            c.password,
-            a.street_address AS street_address,
-            z.zip_code,
-            z.city
-        FROM customer c
-        LEFT JOIN address a ON c.addressid = a.id
-        LEFT JOIN zipcode z ON a.zip_code = z.zip_code
-        ORDER BY c.name`
+		expectedQuery := `LEFT JOIN zipcode z ON a.zip_code = z.zip_code
+        ORDER BY COALESCE(c.name, '') ASC, c.id ASC`
 
 		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+			WithArgs(int32(21)).
 			WillReturnError(context.DeadlineExceeded)
 
 		// Act
-		got, err := customerDomain.GetAllCustomersDomain(context.Background())
+		got, err := customerDomain.GetAllCustomersDomain(context.Background(), CustomerFilter{}, ListParams{})
 
 		// Assert
 		if err == nil {
@@ -173,6 +153,55 @@ func TestGetAllCustomersDomain(t *testing.T) {
 			t.Errorf("unmet mock expectations: %v", err)
 		}
 	})
+
+	t.Run("Next Page", func(t *testing.T) {
+		// Arrange
+		expectedQuery := `LEFT JOIN zipcode z ON a.zip_code = z.zip_code
+        WHERE z.zip_code = $1::int`
+		columns := []string{"id", "name", "email", "phonenumber", "street_address", "zip_code", "city", "latitude", "longitude"}
+
+		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+			WithArgs(int32Ptr(2100), stringPtr("example"), int32(2)).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(int32(2), stringPtr("Bob Builder"), stringPtr("bob@example.com"), nil, nil, int32Ptr(2100), nil, float64Ptr(55.7), float64Ptr(12.55)).
+				AddRow(int32(1), stringPtr("Alice Wonderland"), stringPtr("alice@example.com"), nil, nil, int32Ptr(2100), nil, float64Ptr(55.7), float64Ptr(12.55)))
+		mock.ExpectQuery(regexp.QuoteMeta(`AND (COALESCE(c.name, ''), c.id) < ($3::text, $4::int) ORDER BY COALESCE(c.name, '') DESC, c.id DESC`)).
+			WithArgs(int32Ptr(2100), stringPtr("example"), stringPtr("Bob Builder"), int32Ptr(2), int32(2)).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(int32(1), stringPtr("Alice Wonderland"), stringPtr("alice@example.com"), nil, nil, int32Ptr(2100), nil, float64Ptr(55.7), float64Ptr(12.55)))
+		filter := CustomerFilter{ZipCode: int32Ptr(2100), Search: stringPtr("example")}
+
+		// Act
+		first, err := customerDomain.GetAllCustomersDomain(context.Background(), filter, ListParams{Limit: 1, Sort: "-name"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		next, err := customerDomain.GetAllCustomersDomain(context.Background(), filter, ListParams{Limit: 1, Sort: "-name", Cursor: *first.NextCursor})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(first.Items) != 1 || first.Items[0].ID != 2 {
+			t.Errorf("unexpected first page: %+v", first.Items)
+		}
+		if len(next.Items) != 1 || next.Items[0].ID != 1 || next.NextCursor != nil {
+			t.Errorf("unexpected last page: %+v", next)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Invalid Sort", func(t *testing.T) {
+		// Act
+		_, err := customerDomain.GetAllCustomersDomain(context.Background(), CustomerFilter{}, ListParams{Sort: "password"})
+
+		// Assert
+		if !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("expected %v, got %v", ErrInvalidSort, err)
+		}
+	})
 }
 
 func TestCreateCustomerDomain(t *testing.T) {
@@ -181,7 +210,7 @@ func TestCreateCustomerDomain(t *testing.T) {
 
 	t.Run("Valid Email & Password", func(t *testing.T) {
 		// Arrange
-		params := generated.CreateCustomerParams{
+		params := CustomerParams{
 			StreetAddress: stringPtr("123 Main St"),
 			ZipCode:       int32Ptr(12345),
 			Name:          stringPtr("Charlie Chaplin"),
@@ -192,11 +221,13 @@ func TestCreateCustomerDomain(t *testing.T) {
 
 		// Expect the INSERT with proper arguments
 		mock.ExpectExec(regexp.QuoteMeta(`WITH new_address AS (
-            INSERT INTO address (street_address, zip_code)
-            VALUES ($1, $2)
+            INSERT INTO address (street_address, zip_code, latitude, longitude)
+            VALUES ($1, $2,
+                (SELECT z.latitude FROM zipcode z WHERE z.zip_code = $2),
+                (SELECT z.longitude FROM zipcode z WHERE z.zip_code = $2))
             RETURNING id AS address_id
         )
-        INSERT INTO customer (name, email, phonenumber, addressid, password)
+        INSERT INTO customer (name, email, phonenumber, addressid, password_hash)
         VALUES ($3, $4, $5, (SELECT address_id FROM new_address), $6)`)).
 			WithArgs(
 				params.StreetAddress,
@@ -204,7 +235,7 @@ func TestCreateCustomerDomain(t *testing.T) {
 				params.Name,
 				params.Email,
 				params.Phonenumber,
-				params.Password,
+				pgxmock.AnyArg(),
 			).
 			WillReturnResult(pgxmock.NewResult("INSERT", 1))
 
@@ -222,7 +253,7 @@ func TestCreateCustomerDomain(t *testing.T) {
 
 	t.Run("Invalid Email", func(t *testing.T) {
 		// Arrange
-		params := generated.CreateCustomerParams{
+		params := CustomerParams{
 			StreetAddress: stringPtr("123 Main St"),
 			ZipCode:       int32Ptr(12345),
 			Name:          stringPtr("Invalid Email Person"),
@@ -246,7 +277,7 @@ func TestCreateCustomerDomain(t *testing.T) {
 
 	t.Run("Invalid Password", func(t *testing.T) {
 		// Arrange
-		params := generated.CreateCustomerParams{
+		params := CustomerParams{
 			StreetAddress: stringPtr("123 Main St"),
 			ZipCode:       int32Ptr(12345),
 			Name:          stringPtr("Weak Password Person"),
@@ -279,10 +310,11 @@ func TestGetCustomerByIdDomain(t *testing.T) {
     c.name,
     c.email,
     c.phonenumber,
-    c.password,
     a.street_address,
     z.zip_code,
-    z.city
+    z.city,
+    a.latitude,
+    a.longitude
 FROM customer c
 LEFT JOIN address a ON c.addressid = a.id
 LEFT JOIN zipcode z ON a.zip_code = z.zip_code
@@ -291,16 +323,17 @@ WHERE c.id = $1`
 
 		// Mock returning a valid customer with address and zip code
 		row := pgxmock.NewRows([]string{
-			"id", "name", "email", "phonenumber", "password", "street_address", "zip_code", "city"}).
+			"id", "name", "email", "phonenumber", "street_address", "zip_code", "city", "latitude", "longitude"}).
 			AddRow(
 				int32(1),
 				stringPtr("Alice Wonderland"),
 				stringPtr("alice@example.com"),
 				stringPtr("1234567890"),
-				stringPtr("hashedpassword"),
 				stringPtr("123 Main St"),
 				int32Ptr(12345),
 				stringPtr("Wonderland City"),
+				float64Ptr(55.7),
+				float64Ptr(12.55),
 			)
 
 		mock.ExpectQuery(regexp.QuoteMeta(expectedQuery)).WithArgs(id).WillReturnRows(row)
@@ -324,9 +357,6 @@ WHERE c.id = $1`
 		if *customer.Phonenumber != "1234567890" {
 			t.Errorf("expected phone number %s, got %s", "1234567890", *customer.Phonenumber)
 		}
-		if *customer.Password != "hashedpassword" {
-			t.Errorf("expected password %s, got %s", "hashedpassword", *customer.Password)
-		}
 		if *customer.StreetAddress != "123 Main St" {
 			t.Errorf("expected street address %s, got %s", "123 Main St", *customer.StreetAddress)
 		}
@@ -336,6 +366,9 @@ WHERE c.id = $1`
 		if *customer.City != "Wonderland City" {
 			t.Errorf("expected city %s, got %s", "Wonderland City", *customer.City)
 		}
+		if *customer.Latitude != 55.7 || *customer.Longitude != 12.55 {
+			t.Errorf("expected coordinates 55.7,12.55, got %v,%v", *customer.Latitude, *customer.Longitude)
+		}
 	})
 }
 
@@ -385,7 +418,7 @@ func TestUpdateCustomerDomain(t *testing.T) {
 
 	t.Run("Valid Update", func(t *testing.T) {
 		// Arrange
-		params := generated.UpdateCustomerParams{
+		params := CustomerUpdateParams{
 			ID:          int32(1),
 			Name:        stringPtr("Updated Name"),
 			Email:       stringPtr("updated@example.com"),
@@ -398,7 +431,7 @@ SET
     name = COALESCE($2, name),
     email = COALESCE($3, email),
     phonenumber = COALESCE($4, phonenumber),
-    password = COALESCE($5, password)
+    password_hash = COALESCE($5, password_hash)
 WHERE id = $1`
 
 		mock.ExpectExec(regexp.QuoteMeta(expectedQuery)).
@@ -407,7 +440,7 @@ WHERE id = $1`
 				params.Name,
 				params.Email,
 				params.Phonenumber,
-				params.Password,
+				pgxmock.AnyArg(),
 			).
 			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
 
@@ -460,3 +493,43 @@ WHERE id = $1`
 	//		}
 	//	})
 }
+
+func TestUpdateCustomerRoleDomain(t *testing.T) {
+	tests := []struct {
+		name       string
+		roleParams RoleParams
+		rows       int64
+		wantErr    error
+	}{
+		{"Restaurant Staff", RoleParams{Role: auth.RoleRestaurant, RestaurantID: int32Ptr(3)}, 1, nil},
+		{"Delivery Agent", RoleParams{Role: auth.RoleDeliveryAgent, DeliveryAgentID: int32Ptr(4)}, 1, nil},
+		{"Admin", RoleParams{Role: auth.RoleAdmin}, 1, nil},
+		{"Unknown Customer", RoleParams{Role: auth.RoleCustomer}, 0, ErrCustomerNotFound},
+		{"Staff Without Restaurant", RoleParams{Role: auth.RoleRestaurant}, 0, ErrInvalidRole},
+		{"Customer With Restaurant", RoleParams{Role: auth.RoleCustomer, RestaurantID: int32Ptr(3)}, 0, ErrInvalidRole},
+		{"Unknown Role", RoleParams{Role: "owner"}, 0, ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, customerDomain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			if !errors.Is(tt.wantErr, ErrInvalidRole) {
+				mock.ExpectExec(`UPDATE customer\s+SET role = \$2`).
+					WithArgs(int32(1), string(tt.roleParams.Role), tt.roleParams.RestaurantID, tt.roleParams.DeliveryAgentID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", tt.rows))
+			}
+
+			// Act
+			err := customerDomain.UpdateCustomerRoleDomain(context.Background(), 1, tt.roleParams)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
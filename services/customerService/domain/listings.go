@@ -0,0 +1,95 @@
+package domain
+
+// Listings are built with the listing package rather than generated by sqlc,
+// so they write out the ORDER BY of their sort fields and Postgres can walk
+// the indexes the sorts have.
+
+import (
+	"context"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/listing"
+)
+
+const customerListing = `SELECT
+    c.id,
+    c.name,
+    c.email,
+    c.phonenumber,
+    a.street_address AS street_address,
+    z.zip_code,
+    z.city,
+    a.latitude,
+    a.longitude
+FROM customer c
+LEFT JOIN address a ON c.addressid = a.id
+LEFT JOIN zipcode z ON a.zip_code = z.zip_code`
+
+// customerSortKeys are the fields customers sort on besides their id
+var customerSortKeys = map[string]listing.SortKey{
+	"name": {Expr: "COALESCE(c.name, '')", CursorType: "text"},
+}
+
+type customerListParams struct {
+	ZipCode    *int32
+	Search     *string
+	CursorID   *int32
+	SortField  string
+	Descending bool
+	CursorName *string
+	PageLimit  int32
+}
+
+// CustomerListing is a customer as listed, with their address
+type CustomerListing struct {
+	ID            int32    `json:"id"`
+	Name          *string  `json:"name"`
+	Email         *string  `json:"email"`
+	Phonenumber   *string  `json:"phonenumber"`
+	StreetAddress *string  `json:"street_address"`
+	ZipCode       *int32   `json:"zip_code"`
+	City          *string  `json:"city"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+}
+
+// listCustomers fetches a page of customers, sorted on a whitelisted field
+// with id breaking ties and continuing after the cursor of the previous page
+func listCustomers(ctx context.Context, db generated.DBTX, arg customerListParams) ([]CustomerListing, error) {
+	key, err := listing.SortKeyOf(customerSortKeys, arg.SortField)
+	if err != nil {
+		return nil, err
+	}
+	list := listing.New(customerListing)
+	listing.WhereSet(list, "z.zip_code = %[1]s::int", arg.ZipCode)
+	listing.WhereSet(list, "(c.name ILIKE '%%' || %[1]s::text || '%%' OR c.email ILIKE '%%' || %[1]s || '%%')", arg.Search)
+	query, args := list.Page(key, "c.id", arg.Descending, arg.CursorName, arg.CursorID, arg.PageLimit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CustomerListing
+	for rows.Next() {
+		var i CustomerListing
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.Phonenumber,
+			&i.StreetAddress,
+			&i.ZipCode,
+			&i.City,
+			&i.Latitude,
+			&i.Longitude,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+// ListParams pages and sorts a listing. Sort is a field of the listing,
+// prefixed by "-" for descending order, and Cursor is the NextCursor of the
+// previous page.
+type ListParams struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// Page is a page of a listing. NextCursor is nil on the last page.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// cursor is the position after the last item of a page. Value is the sort
+// field of that item, and the ID breaks ties between equal values.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v,omitempty"`
+	ID    int32  `json:"id"`
+}
+
+func (p ListParams) limit() int {
+	if p.Limit < 1 {
+		return defaultPageLimit
+	}
+	return min(p.Limit, maxPageLimit)
+}
+
+// sortField returns the field and direction to sort on. Fields must be in
+// fields, and an empty sort falls back to fallback.
+func (p ListParams) sortField(fields []string, fallback string) (string, bool, error) {
+	sort := p.Sort
+	if sort == "" {
+		sort = fallback
+	}
+	field, descending := strings.CutPrefix(sort, "-")
+	if !slices.Contains(fields, field) {
+		return "", false, ErrInvalidSort
+	}
+	return field, descending, nil
+}
+
+// decodeCursor reads the cursor of the params. Cursors only continue the
+// sort they were made for.
+func (p ListParams) decodeCursor(sort string) (*cursor, error) {
+	if p.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// newPage makes a page of items fetched with one more row than the limit,
+// which tells whether there is a next page
+func newPage[T any](items []T, limit int, next func(last T) cursor) *Page[T] {
+	page := &Page[T]{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		nextCursor := next(page.Items[limit-1]).encode()
+		page.NextCursor = &nextCursor
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Reset tokens are sent by email, and are only good for a short while
+const passwordResetTTL = time.Hour
+
+var (
+	ErrInvalidResetToken = errors.New("reset token is invalid or has expired")
+	ErrInvalidPassword   = errors.New("invalid password")
+)
+
+// MailSender sends an HTML email, e.g. mailer.SendMailWithGomail
+type MailSender func(to, subject, body string) error
+
+// RequestPasswordResetDomain emails a one-time reset token to the customer
+// with the email. Unknown emails are ignored without telling the caller, so
+// the endpoint cannot be used to find out who has an account.
+func (d *AuthDomain) RequestPasswordResetDomain(ctx context.Context, email string) error {
+	credentials, err := d.queries.GetCustomerCredentials(ctx, &email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resetToken, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+	err = d.queries.CreatePasswordReset(ctx, generated.CreatePasswordResetParams{
+		CustomerID: credentials.ID,
+		TokenHash:  hashRefreshToken(resetToken),
+		ExpiresAt:  time.Now().Add(passwordResetTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	subject := "Reset your MTOGO password"
+
+	body := `
+    <html>
+        <body style="font-family: Arial, sans-serif; color: #333;">
+            <h1 style="color: #4CAF50;">Reset your password</h1>
+            <p>Use this code to set a new password. It can be used once, and expires in an hour:</p>
+            <p style="font-family: monospace; font-size: 1.2em;">` + resetToken + `</p>
+            <footer style="margin-top: 20px; font-size: 0.9em; color: #666;">
+                <hr>
+                <p>If you did not ask to reset your password, please ignore this email.</p>
+            </footer>
+        </body>
+    </html>
+`
+
+	return d.sendMail(email, subject, body)
+}
+
+// ResetPasswordDomain sets a new password with a reset token, which is used
+// up, and logs the customer out everywhere else
+func (d *AuthDomain) ResetPasswordDomain(ctx context.Context, resetToken, password string) error {
+	// Checked first, so a weak password does not use up the token
+	if err := ValidatePassword(password); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPassword, err)
+	}
+
+	now := time.Now()
+	customerId, err := d.queries.UsePasswordReset(ctx, generated.UsePasswordResetParams{
+		Now:       now,
+		TokenHash: hashRefreshToken(resetToken),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+
+	err = d.customers.UpdateCustomerDomain(ctx, CustomerUpdateParams{ID: customerId, Password: &password})
+	if err != nil {
+		return err
+	}
+	return d.queries.RevokeCustomerRefreshTokens(ctx, generated.RevokeCustomerRefreshTokensParams{
+		CustomerID: customerId,
+		RevokedAt:  &now,
+	})
+}
+
+// PrunePasswordResetsDomain deletes reset tokens that have expired
+func (d *AuthDomain) PrunePasswordResetsDomain(ctx context.Context) (int64, error) {
+	return d.queries.DeleteExpiredPasswordResets(ctx, time.Now())
+}
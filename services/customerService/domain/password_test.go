@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+// testPasswordParams keep the tests fast; they are far too cheap for production
+var testPasswordParams = PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+var credentialColumns = []string{"id", "password_hash"}
+
+func TestHashPassword(t *testing.T) {
+	// Arrange
+	hash, err := HashPassword("Password1!", testPasswordParams)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, _ := HashPassword("Password1!", testPasswordParams)
+
+	// Act
+	match, outdated, err := VerifyPassword("Password1!", hash, testPasswordParams)
+
+	// Assert
+	if err != nil || !match || outdated {
+		t.Errorf("got match=%v outdated=%v err=%v, want a current match", match, outdated, err)
+	}
+	if hash == other {
+		t.Errorf("expected a new salt for every hash, got %s twice", hash)
+	}
+	if match, _, _ := VerifyPassword("Password2!", hash, testPasswordParams); match {
+		t.Errorf("expected the wrong password not to match")
+	}
+}
+
+func TestVerifyPasswordOutdated(t *testing.T) {
+	// Arrange
+	hash, _ := HashPassword("Password1!", testPasswordParams)
+	stronger := testPasswordParams
+	stronger.Iterations = 2
+
+	// Act
+	match, outdated, err := VerifyPassword("Password1!", hash, stronger)
+
+	// Assert
+	if err != nil || !match || !outdated {
+		t.Errorf("got match=%v outdated=%v err=%v, want an outdated match", match, outdated, err)
+	}
+}
+
+func TestVerifyPasswordInvalidHash(t *testing.T) {
+	for _, hash := range []string{"", "Password1!", "$2a$10$abcdefghijklmnopqrstuv", "$argon2id$v=19$m=1024,t=1,p=1$!!$!!"} {
+		if _, _, err := VerifyPassword("Password1!", hash, testPasswordParams); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%q: got error %v, want %v", hash, err, ErrInvalidHash)
+		}
+	}
+}
+
+func TestVerifyCredentials(t *testing.T) {
+	hash, _ := HashPassword("Password1!", testPasswordParams)
+
+	t.Run("Valid Credentials", func(t *testing.T) {
+		// Arrange
+		mock, _, customerDomain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		customerDomain.passwordParams = testPasswordParams
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("alice@example.com")).
+			WillReturnRows(pgxmock.NewRows(credentialColumns).AddRow(int32(1), &hash))
+
+		// Act
+		id, err := customerDomain.VerifyCredentials(context.Background(), "alice@example.com", "Password1!")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if id != 1 {
+			t.Errorf("expected customer 1, got %d", id)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rehashes Outdated Hash", func(t *testing.T) {
+		// Arrange
+		mock, _, customerDomain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		customerDomain.passwordParams = testPasswordParams
+		customerDomain.passwordParams.Iterations = 2
+		mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).
+			WithArgs(stringPtr("alice@example.com")).
+			WillReturnRows(pgxmock.NewRows(credentialColumns).AddRow(int32(1), &hash))
+		mock.ExpectExec(`UPDATE customer\s+SET password_hash = \$1\s+WHERE id = \$2 AND password_hash = \$3`).
+			WithArgs(pgxmock.AnyArg(), int32(1), &hash).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		// Act
+		_, err := customerDomain.VerifyCredentials(context.Background(), "alice@example.com", "Password1!")
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		password string
+		rows     *pgxmock.Rows
+		err      error
+	}{
+		{"Wrong Password", "Password2!", pgxmock.NewRows(credentialColumns).AddRow(int32(1), &hash), nil},
+		{"No Password Set", "Password1!", pgxmock.NewRows(credentialColumns).AddRow(int32(1), nil), nil},
+		{"Unknown Email", "Password1!", nil, pgx.ErrNoRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, _, customerDomain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			customerDomain.passwordParams = testPasswordParams
+			expected := mock.ExpectQuery(`FROM customer\s+WHERE email = \$1`).WithArgs(stringPtr("alice@example.com"))
+			if tt.err != nil {
+				expected.WillReturnError(tt.err)
+			} else {
+				expected.WillReturnRows(tt.rows)
+			}
+
+			_, err := customerDomain.VerifyCredentials(context.Background(), "alice@example.com", tt.password)
+
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidCredentials)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ require (
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
@@ -36,9 +37,11 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/rabbitmq/amqp091-go v1.10.0 // indirect
+	github.com/rasm445f/soft-exam-2/auth v0.0.0
 	github.com/rasm445f/soft-exam-2/broker v0.0.0
+	github.com/rasm445f/soft-exam-2/listing v0.0.0
 	github.com/swaggo/files/v2 v2.0.1 // indirect
-	golang.org/x/crypto v0.29.0 // indirect
+	golang.org/x/crypto v0.29.0
 	golang.org/x/text v0.20.0 // indirect
 	golang.org/x/tools v0.27.0 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
@@ -47,3 +50,7 @@ require (
 )
 
 replace github.com/rasm445f/soft-exam-2/broker => ../../broker
+
+replace github.com/rasm445f/soft-exam-2/auth => ../../auth
+
+replace github.com/rasm445f/soft-exam-2/listing => ../../listing
@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+type AuthHandler struct {
+	domain domain.AuthPort
+}
+
+func NewAuthHandler(domain domain.AuthPort) *AuthHandler {
+	return &AuthHandler{domain: domain}
+}
+
+// LoginRequest holds a customer's credentials
+type LoginRequest struct {
+	Email    string `json:"email" example:"john.doe@example.com"`
+	Password string `json:"password" example:"Password123!"`
+}
+
+// RefreshRequest holds a refresh token from an earlier login or refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" example:"2bq0Mv9nRZ0xW0w7m3Dk9b5yQm1p8s6Vt4uHc2aLxJE"`
+}
+
+// writeTokens answers with freshly issued tokens, which must not be cached
+func writeTokens(w http.ResponseWriter, tokens domain.Tokens) {
+	res, _ := json.Marshal(tokens)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res)
+}
+
+// Login godoc
+//
+// @Summary Log in
+// @Description Checks the customer's email and password, and issues an access token and a refresh token
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param credentials body LoginRequest true "Email and password"
+// @Success 200 {object} domain.Tokens
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Invalid email or password"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/login [post]
+func (h *AuthHandler) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var credentials LoginRequest
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+		if credentials.Email == "" || credentials.Password == "" {
+			http.Error(w, "Email and password are required", http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := h.domain.LoginDomain(ctx, credentials.Email, credentials.Password)
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		writeTokens(w, tokens)
+	}
+}
+
+// Refresh godoc
+//
+// @Summary Refresh tokens
+// @Description Trades a refresh token for a new access token and refresh token. Every refresh token can be used once; using one again logs the customer out everywhere.
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param refresh body RefreshRequest true "Refresh token"
+// @Success 200 {object} domain.Tokens
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Refresh token is invalid or has expired"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/refresh [post]
+func (h *AuthHandler) Refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var refresh RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&refresh); err != nil || refresh.RefreshToken == "" {
+			http.Error(w, "A refresh token is required", http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := h.domain.RefreshDomain(ctx, refresh.RefreshToken)
+		if errors.Is(err, domain.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to refresh tokens", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		writeTokens(w, tokens)
+	}
+}
+
+// Logout godoc
+//
+// @Summary Log out
+// @Description Revokes the refresh token. Access tokens stay valid until they expire.
+// @Tags Auth
+// @Accept application/json
+// @Param refresh body RefreshRequest true "Refresh token"
+// @Success 204 "Logged out"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/logout [post]
+func (h *AuthHandler) Logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var refresh RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&refresh); err != nil || refresh.RefreshToken == "" {
+			http.Error(w, "A refresh token is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.LogoutDomain(ctx, refresh.RefreshToken); err != nil {
+			http.Error(w, "Failed to log out", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ServiceToken godoc
+//
+// @Summary Service token
+// @Description Issues an access token to another service, which authenticates with the client ID and secret it was configured with. Services call each other with it when no customer is involved, e.g. when handling broker events.
+// @Tags Auth
+// @Accept application/json
+// @Produce application/json
+// @Param credentials body auth.ServiceCredentials true "Client ID and secret"
+// @Success 200 {object} domain.Tokens
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Invalid client id or secret"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/service-token [post]
+func (h *AuthHandler) ServiceToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var credentials auth.ServiceCredentials
+		if err := json.NewDecoder(r.Body).Decode(&credentials); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := h.domain.ServiceTokenDomain(ctx, credentials)
+		if errors.Is(err, domain.ErrInvalidServiceCredentials) {
+			log.Printf("Rejected service token request for client %q", credentials.ClientID)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to issue service token", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		writeTokens(w, tokens)
+	}
+}
+
+// PasswordResetRequest names the account to send a reset token for
+type PasswordResetRequest struct {
+	Email string `json:"email" example:"john.doe@example.com"`
+}
+
+// ResetPasswordRequest sets a new password with an emailed reset token
+type ResetPasswordRequest struct {
+	ResetToken  string `json:"reset_token" example:"q3Jm0x8Vt2cWn5Lk9Rz1bY7uHd4sPa6fGe0iTo2jKcM"`
+	NewPassword string `json:"new_password" example:"Password123!"`
+}
+
+// RequestPasswordReset godoc
+//
+// @Summary Request a password reset
+// @Description Emails a one-time reset token, valid for an hour, to the customer with the email. The answer is the same whether or not the email has an account.
+// @Tags Auth
+// @Accept application/json
+// @Param request body PasswordResetRequest true "Email"
+// @Success 202 "Reset token sent if the account exists"
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/password-reset [post]
+func (h *AuthHandler) RequestPasswordReset() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var request PasswordResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Email == "" {
+			http.Error(w, "An email is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.RequestPasswordResetDomain(ctx, request.Email); err != nil {
+			http.Error(w, "Failed to request password reset", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// ResetPassword godoc
+//
+// @Summary Reset password
+// @Description Sets a new password with a reset token, which can only be used once, and logs the customer out everywhere
+// @Tags Auth
+// @Accept application/json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 204 "Password reset"
+// @Failure 400 {string} string "Invalid password, or reset token invalid or expired"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/auth/password-reset/confirm [post]
+func (h *AuthHandler) ResetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var request ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ResetToken == "" || request.NewPassword == "" {
+			http.Error(w, "A reset token and new password are required", http.StatusBadRequest)
+			return
+		}
+
+		err := h.domain.ResetPasswordDomain(ctx, request.ResetToken, request.NewPassword)
+		if errors.Is(err, domain.ErrInvalidPassword) || errors.Is(err, domain.ErrInvalidResetToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// JWKS godoc
+//
+// @Summary Public signing keys
+// @Description Lists the public keys access tokens are signed with, for other services to verify them
+// @Tags Auth
+// @Produce application/json
+// @Success 200 {object} auth.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res, _ := json.Marshal(h.domain.JWKS())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+type MockAuthDomain struct {
+	LoginDomainFunc   func(ctx context.Context, email, password string) (domain.Tokens, error)
+	RefreshDomainFunc func(ctx context.Context, refreshToken string) (domain.Tokens, error)
+	LogoutDomainFunc  func(ctx context.Context, refreshToken string) error
+}
+
+func (m *MockAuthDomain) LoginDomain(ctx context.Context, email, password string) (domain.Tokens, error) {
+	if m.LoginDomainFunc != nil {
+		return m.LoginDomainFunc(ctx, email, password)
+	}
+	return domain.Tokens{}, nil
+}
+
+func (m *MockAuthDomain) RefreshDomain(ctx context.Context, refreshToken string) (domain.Tokens, error) {
+	if m.RefreshDomainFunc != nil {
+		return m.RefreshDomainFunc(ctx, refreshToken)
+	}
+	return domain.Tokens{}, nil
+}
+
+func (m *MockAuthDomain) LogoutDomain(ctx context.Context, refreshToken string) error {
+	if m.LogoutDomainFunc != nil {
+		return m.LogoutDomainFunc(ctx, refreshToken)
+	}
+	return nil
+}
+
+func (m *MockAuthDomain) ServiceTokenDomain(ctx context.Context, credentials auth.ServiceCredentials) (domain.Tokens, error) {
+	if credentials.ClientID == "order-service" && credentials.ClientSecret == "secret" {
+		return domain.Tokens{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900}, nil
+	}
+	return domain.Tokens{}, domain.ErrInvalidServiceCredentials
+}
+
+func (m *MockAuthDomain) RequestPasswordResetDomain(ctx context.Context, email string) error {
+	return nil
+}
+
+func (m *MockAuthDomain) ResetPasswordDomain(ctx context.Context, resetToken, password string) error {
+	switch {
+	case resetToken != "reset":
+		return domain.ErrInvalidResetToken
+	case password != "Password1!":
+		return domain.ErrInvalidPassword
+	}
+	return nil
+}
+
+func (m *MockAuthDomain) JWKS() auth.JWKS {
+	return auth.JWKS{Keys: []auth.JWK{{KeyType: "OKP", Curve: "Ed25519", X: "x", KeyID: "kid", Use: "sig", Algorithm: "EdDSA"}}}
+}
+
+var testTokens = domain.Tokens{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 900, RefreshToken: "refresh"}
+
+func TestLoginHandler(t *testing.T) {
+	mockDomain := &MockAuthDomain{
+		LoginDomainFunc: func(ctx context.Context, email, password string) (domain.Tokens, error) {
+			if email == "alice@example.com" && password == "Password1!" {
+				return testTokens, nil
+			}
+			return domain.Tokens{}, domain.ErrInvalidCredentials
+		},
+	}
+	handler := NewAuthHandler(mockDomain)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Credentials", `{"email": "alice@example.com", "password": "Password1!"}`, http.StatusOK},
+		{"Wrong Password", `{"email": "alice@example.com", "password": "Password2!"}`, http.StatusUnauthorized},
+		{"Missing Password", `{"email": "alice@example.com"}`, http.StatusBadRequest},
+		{"Invalid Payload", `not json`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			// Act
+			handler.Login().ServeHTTP(rr, req)
+
+			// Assert
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got domain.Tokens
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil || got != testTokens {
+				t.Errorf("expected %+v, got %+v (%v)", testTokens, got, err)
+			}
+			if rr.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("expected tokens not to be cached, got Cache-Control %q", rr.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	mockDomain := &MockAuthDomain{
+		RefreshDomainFunc: func(ctx context.Context, refreshToken string) (domain.Tokens, error) {
+			if refreshToken == "refresh" {
+				return testTokens, nil
+			}
+			return domain.Tokens{}, domain.ErrInvalidRefreshToken
+		},
+	}
+	handler := NewAuthHandler(mockDomain)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Token", `{"refresh_token": "refresh"}`, http.StatusOK},
+		{"Used Token", `{"refresh_token": "used"}`, http.StatusUnauthorized},
+		{"Missing Token", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.Refresh().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestLogoutHandler(t *testing.T) {
+	// Arrange
+	var revoked string
+	mockDomain := &MockAuthDomain{
+		LogoutDomainFunc: func(ctx context.Context, refreshToken string) error {
+			revoked = refreshToken
+			return nil
+		},
+	}
+	handler := NewAuthHandler(mockDomain)
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/logout", bytes.NewBufferString(`{"refresh_token": "refresh"}`))
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.Logout().ServeHTTP(rr, req)
+
+	// Assert
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if revoked != "refresh" {
+		t.Errorf("expected the refresh token to be revoked, got %q", revoked)
+	}
+}
+
+func TestServiceTokenHandler(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthDomain{})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Credentials", `{"client_id": "order-service", "client_secret": "secret"}`, http.StatusOK},
+		{"Wrong Secret", `{"client_id": "order-service", "client_secret": "guess"}`, http.StatusUnauthorized},
+		{"Invalid Body", `{"client_id":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/service-token", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServiceToken().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestRequestPasswordResetHandler(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthDomain{})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Email", `{"email": "alice@example.com"}`, http.StatusAccepted},
+		{"Missing Email", `{}`, http.StatusBadRequest},
+		{"Invalid Body", `{"email":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/password-reset", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.RequestPasswordReset().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	handler := NewAuthHandler(&MockAuthDomain{})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Token", `{"reset_token": "reset", "new_password": "Password1!"}`, http.StatusNoContent},
+		{"Invalid Token", `{"reset_token": "guess", "new_password": "Password1!"}`, http.StatusBadRequest},
+		{"Weak Password", `{"reset_token": "reset", "new_password": "weak"}`, http.StatusBadRequest},
+		{"Missing Fields", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/password-reset/confirm", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			handler.ResetPassword().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	// Arrange
+	handler := NewAuthHandler(&MockAuthDomain{})
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	rr := httptest.NewRecorder()
+
+	// Act
+	handler.JWKS().ServeHTTP(rr, req)
+
+	// Assert
+	var got auth.JWKS
+	if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if rr.Code != http.StatusOK || len(got.Keys) != 1 || got.Keys[0].KeyID != "kid" {
+		t.Errorf("unexpected response %d: %+v", rr.Code, got)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -22,17 +23,39 @@ func NewCustomerHandler(domain domain.CustomerPort) *CustomerHandler {
 // GetAllCustomers godoc
 //
 // @Summary Get all customers
-// @Description Fetches a list of all customers from the database
+// @Description Fetches a page of customers, optionally filtered by zip code or a search on name and email
 // @Tags Customer CRUD
 // @Produce application/json
-// @Success 200 {array} generated.Customer
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "id or name, prefixed by - for descending order" default(name)
+// @Param zip_code query int false "Zip code"
+// @Param q query string false "Part of the name or email"
+// @Success 200 {object} domain.Page[domain.CustomerListing]
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/customer [get]
 func (h *CustomerHandler) GetAllCustomers() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		customers, err := h.domain.GetAllCustomersDomain(ctx)
+		query := queryParser{r: r}
+		list := query.listParams()
+		filter := domain.CustomerFilter{
+			ZipCode: query.int32("zip_code"),
+			Search:  query.string("q"),
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		customers, err := h.domain.GetAllCustomersDomain(ctx, filter, list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to fetch customers", http.StatusInternalServerError)
 			log.Println(err)
@@ -53,8 +76,10 @@ func (h *CustomerHandler) GetAllCustomers() http.HandlerFunc {
 // @Tags Customer CRUD
 // @Produce application/json
 // @Param id path string true "Customer ID"
-// @Success 200 {object} generated.Customer
+// @Success 200 {object} generated.GetCustomerByIDRow
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/customer/{id} [get]
 func (h *CustomerHandler) GetCustomerById() http.HandlerFunc {
@@ -85,6 +110,47 @@ func (h *CustomerHandler) GetCustomerById() http.HandlerFunc {
 	}
 }
 
+// GetCustomerLocation godoc
+//
+// @Summary Get customer location
+// @Description Fetches where a customer's orders are delivered to, and nothing else about the customer. Other services use it with a service token.
+// @Tags Customer CRUD
+// @Produce application/json
+// @Param id path string true "Customer ID"
+// @Success 200 {object} domain.CustomerLocation
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Customer not found"
+// @Router /api/customer/{id}/location [get]
+func (h *CustomerHandler) GetCustomerLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+			return
+		}
+
+		customer, err := h.domain.GetCustomerByIdDomain(ctx, int32(id))
+		if err != nil {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(domain.CustomerLocation{
+			ZipCode:   customer.ZipCode,
+			Latitude:  customer.Latitude,
+			Longitude: customer.Longitude,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
 // DeleteCustomer godoc
 //
 // @Summary Delete customer
@@ -94,6 +160,8 @@ func (h *CustomerHandler) GetCustomerById() http.HandlerFunc {
 // @Param id path string true "Customer ID"
 // @Success 200 {string} string "Customer deleted"
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/customer/{id} [delete]
 func (h *CustomerHandler) DeleteCustomer() http.HandlerFunc {
@@ -128,15 +196,15 @@ func (h *CustomerHandler) DeleteCustomer() http.HandlerFunc {
 // @Tags Customer CRUD
 // @Accept  application/json
 // @Produce application/json
-// @Param customer body generated.CreateCustomerParams true "Customer object"
-// @Success 201 {object} generated.Customer
+// @Param customer body domain.CustomerParams true "Customer object"
+// @Success 201 "Created"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/customer [post]
 func (h *CustomerHandler) CreateCustomer() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
-		var customer generated.CreateCustomerParams
+		var customer domain.CustomerParams
 
 		err := json.NewDecoder(r.Body).Decode(&customer)
 		if err != nil {
@@ -145,7 +213,8 @@ func (h *CustomerHandler) CreateCustomer() http.HandlerFunc {
 			return
 		}
 
-		if *customer.Name == "" || *customer.Email == "" || *customer.Password == "" {
+		if customer.Name == nil || customer.Email == nil || customer.Password == nil ||
+			*customer.Name == "" || *customer.Email == "" || *customer.Password == "" {
 			http.Error(w, "All required fields must be filled", http.StatusBadRequest)
 			return
 		}
@@ -185,6 +254,8 @@ type UpdateCustomerWithAddress struct {
 // @Param customer body UpdateCustomerWithAddress true "Updated customer details"
 // @Success 200 {object} map[string]string "Customer updated successfully"
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/customer/{id} [patch]
 func (h *CustomerHandler) UpdateCustomer() http.HandlerFunc {
@@ -208,7 +279,7 @@ func (h *CustomerHandler) UpdateCustomer() http.HandlerFunc {
 		}
 
 		// Create an UpdateCustomerParams struct and fill it based on the JSON payload
-		customerUpdates := generated.UpdateCustomerParams{
+		customerUpdates := domain.CustomerUpdateParams{
 			ID: int32(id),
 		}
 		if name, ok := updatePayload["name"].(string); ok {
@@ -266,3 +337,49 @@ func (h *CustomerHandler) UpdateCustomer() http.HandlerFunc {
 		w.Write([]byte(`{"message": "Customer updated successfully"}`)) //returning json data therfore not a sink
 	}
 }
+
+// UpdateCustomerRole godoc
+//
+// @Summary Change the role of an account
+// @Description Makes an account a customer, restaurant staff, delivery agent or admin. Restaurant staff need the restaurant they work for, delivery agents their agent ID. The new role takes effect when the user next logs in or refreshes their tokens. Only admins may change roles.
+// @Tags Customer CRUD
+// @Accept application/json
+// @Param id path int true "Customer ID"
+// @Param role body domain.RoleParams true "New role"
+// @Success 204 "Role changed"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Customer not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/customer/{id}/role [put]
+func (h *CustomerHandler) UpdateCustomerRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid customer ID", http.StatusBadRequest)
+			return
+		}
+
+		var roleParams domain.RoleParams
+		if err := json.NewDecoder(r.Body).Decode(&roleParams); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		err = h.domain.UpdateCustomerRoleDomain(ctx, int32(id), roleParams)
+		switch {
+		case errors.Is(err, domain.ErrInvalidRole):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrCustomerNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, "Failed to change role", http.StatusInternalServerError)
+			log.Println(err)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
@@ -7,19 +7,24 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/domain"
 )
 
 // Mock the domain layer to test the handler in isolation
 type MockCustomerDomain struct {
-	GetAllCustomersDomainFunc func(ctx context.Context) ([]generated.GetAllCustomersRow, error)
+	GetAllCustomersDomainFunc func(ctx context.Context, filter domain.CustomerFilter, list domain.ListParams) (*domain.Page[domain.CustomerListing], error)
 	GetCustomerByIdDomainFunc func(ctx context.Context, id int32) (generated.GetCustomerByIDRow, error)
 	DeleteCustomerDomainFunc  func(ctx context.Context, id int32) error
-	CreateCustomerDomainFunc  func(ctx context.Context, customerParams generated.CreateCustomerParams) error
-	UpdateCustomerDomainFunc  func(ctx context.Context, customerParams generated.UpdateCustomerParams) error
+	CreateCustomerDomainFunc  func(ctx context.Context, customerParams domain.CustomerParams) error
+	UpdateCustomerDomainFunc  func(ctx context.Context, customerParams domain.CustomerUpdateParams) error
 	UpdateAddressFunc         func(ctx context.Context, addressParams generated.UpdateAddressParams) error
+	VerifyCredentialsFunc     func(ctx context.Context, email, password string) (int32, error)
+	UpdateCustomerRoleFunc    func(ctx context.Context, id int32, roleParams domain.RoleParams) error
 }
 
 func int32Ptr(i int32) *int32 {
@@ -30,9 +35,9 @@ func stringPtr(s string) *string {
 	return &s
 }
 
-func (m *MockCustomerDomain) GetAllCustomersDomain(ctx context.Context) ([]generated.GetAllCustomersRow, error) {
+func (m *MockCustomerDomain) GetAllCustomersDomain(ctx context.Context, filter domain.CustomerFilter, list domain.ListParams) (*domain.Page[domain.CustomerListing], error) {
 	if m.GetAllCustomersDomainFunc != nil {
-		return m.GetAllCustomersDomainFunc(ctx)
+		return m.GetAllCustomersDomainFunc(ctx, filter, list)
 	}
 	return nil, nil
 }
@@ -51,14 +56,14 @@ func (m *MockCustomerDomain) DeleteCustomerDomain(ctx context.Context, id int32)
 	return nil
 }
 
-func (m *MockCustomerDomain) CreateCustomerDomain(ctx context.Context, customerParams generated.CreateCustomerParams) error {
+func (m *MockCustomerDomain) CreateCustomerDomain(ctx context.Context, customerParams domain.CustomerParams) error {
 	if m.CreateCustomerDomainFunc != nil {
 		return m.CreateCustomerDomainFunc(ctx, customerParams)
 	}
 	return nil
 }
 
-func (m *MockCustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParams generated.UpdateCustomerParams) error {
+func (m *MockCustomerDomain) UpdateCustomerDomain(ctx context.Context, customerParams domain.CustomerUpdateParams) error {
 	if m.UpdateCustomerDomainFunc != nil {
 		return m.UpdateCustomerDomainFunc(ctx, customerParams)
 	}
@@ -72,13 +77,27 @@ func (m *MockCustomerDomain) UpdateAddress(ctx context.Context, addressParams ge
 	return nil
 }
 
+func (m *MockCustomerDomain) VerifyCredentials(ctx context.Context, email, password string) (int32, error) {
+	if m.VerifyCredentialsFunc != nil {
+		return m.VerifyCredentialsFunc(ctx, email, password)
+	}
+	return 0, domain.ErrInvalidCredentials
+}
+
+func (m *MockCustomerDomain) UpdateCustomerRoleDomain(ctx context.Context, id int32, roleParams domain.RoleParams) error {
+	if m.UpdateCustomerRoleFunc != nil {
+		return m.UpdateCustomerRoleFunc(ctx, id, roleParams)
+	}
+	return nil
+}
+
 func TestGetAllCustomersHandler(t *testing.T) {
 	mockDomain := &MockCustomerDomain{
-		GetAllCustomersDomainFunc: func(ctx context.Context) ([]generated.GetAllCustomersRow, error) {
-			return []generated.GetAllCustomersRow{
+		GetAllCustomersDomainFunc: func(ctx context.Context, filter domain.CustomerFilter, list domain.ListParams) (*domain.Page[domain.CustomerListing], error) {
+			return &domain.Page[domain.CustomerListing]{Items: []domain.CustomerListing{
 				{ID: 1, Name: stringPtr("John Doe"), Email: stringPtr("john@example.com")},
 				{ID: 2, Name: stringPtr("Jane Smith"), Email: stringPtr("jane@example.com")},
-			}, nil
+			}}, nil
 		},
 	}
 	handler := NewCustomerHandler(mockDomain)
@@ -93,8 +112,24 @@ func TestGetAllCustomersHandler(t *testing.T) {
 		}
 	})
 
+	t.Run("status 400", func(t *testing.T) {
+		for _, target := range []string{"/api/customer?limit=0", "/api/customer?zip_code=abc", "/api/customer?sort=email"} {
+			mockDomain.GetAllCustomersDomainFunc = func(ctx context.Context, filter domain.CustomerFilter, list domain.ListParams) (*domain.Page[domain.CustomerListing], error) {
+				return nil, domain.ErrInvalidSort
+			}
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			handler.GetAllCustomers().ServeHTTP(rec, req)
+
+			if rec.Result().StatusCode != http.StatusBadRequest {
+				t.Errorf("%s: expected status %v, got %v", target, http.StatusBadRequest, rec.Result().StatusCode)
+			}
+		}
+	})
+
 	t.Run("internal server error", func(t *testing.T) {
-		mockDomain.GetAllCustomersDomainFunc = func(ctx context.Context) ([]generated.GetAllCustomersRow, error) {
+		mockDomain.GetAllCustomersDomainFunc = func(ctx context.Context, filter domain.CustomerFilter, list domain.ListParams) (*domain.Page[domain.CustomerListing], error) {
 			return nil, sql.ErrConnDone
 		}
 
@@ -110,14 +145,14 @@ func TestGetAllCustomersHandler(t *testing.T) {
 
 func TestCreateCustomerHandler(t *testing.T) {
 	mockDomain := &MockCustomerDomain{
-		CreateCustomerDomainFunc: func(ctx context.Context, customerParams generated.CreateCustomerParams) error {
+		CreateCustomerDomainFunc: func(ctx context.Context, customerParams domain.CustomerParams) error {
 			return nil
 		},
 	}
 	handler := NewCustomerHandler(mockDomain)
 
 	t.Run("status 201", func(t *testing.T) {
-		customer := generated.CreateCustomerParams{
+		customer := domain.CustomerParams{
 			Name:          stringPtr("John Doe"),
 			Email:         stringPtr("john@example.com"),
 			Phonenumber:   stringPtr("1234567890"),
@@ -141,7 +176,7 @@ func TestCreateCustomerHandler(t *testing.T) {
 	})
 
 	t.Run("invalid email", func(t *testing.T) {
-		params := generated.CreateCustomerParams{
+		params := domain.CustomerParams{
 			StreetAddress: stringPtr("123 Main St"),
 			ZipCode:       int32Ptr(12345),
 			Name:          stringPtr("John Doe"),
@@ -170,11 +205,11 @@ func TestCreateCustomerHandler(t *testing.T) {
 	})
 
 	t.Run("internal server error", func(t *testing.T) {
-		mockDomain.CreateCustomerDomainFunc = func(ctx context.Context, customerParams generated.CreateCustomerParams) error {
+		mockDomain.CreateCustomerDomainFunc = func(ctx context.Context, customerParams domain.CustomerParams) error {
 			return sql.ErrConnDone
 		}
 
-		customer := generated.CreateCustomerParams{
+		customer := domain.CustomerParams{
 			Name:          stringPtr("John Doe"),
 			Email:         stringPtr("john@example.com"),
 			Phonenumber:   stringPtr("1234567890"),
@@ -203,7 +238,6 @@ func TestGetCustomerByIdHandler(t *testing.T) {
 					Name:          stringPtr("John Doe"),
 					Email:         stringPtr("charlie@example.com"),
 					Phonenumber:   stringPtr("12341212"),
-					Password:      stringPtr("Password1!"),
 					StreetAddress: stringPtr("123 Main St"),
 					ZipCode:       int32Ptr(12345),
 					City:          stringPtr("New York"),
@@ -224,6 +258,9 @@ func TestGetCustomerByIdHandler(t *testing.T) {
 		if rec.Result().StatusCode != http.StatusOK {
 			t.Fatalf("expected status %v, got %v", http.StatusOK, rec.Result().StatusCode)
 		}
+		if strings.Contains(rec.Body.String(), "password") {
+			t.Errorf("expected no password in the response, got %s", rec.Body.String())
+		}
 	})
 
 	t.Run("not found", func(t *testing.T) {
@@ -251,9 +288,55 @@ func TestGetCustomerByIdHandler(t *testing.T) {
 	})
 }
 
+func TestGetCustomerLocationHandler(t *testing.T) {
+	mockDomain := &MockCustomerDomain{
+		GetCustomerByIdDomainFunc: func(ctx context.Context, id int32) (generated.GetCustomerByIDRow, error) {
+			if id == 1 {
+				return generated.GetCustomerByIDRow{
+					ID:            1,
+					Name:          stringPtr("John Doe"),
+					Email:         stringPtr("charlie@example.com"),
+					StreetAddress: stringPtr("123 Main St"),
+					ZipCode:       int32Ptr(2800),
+				}, nil
+			}
+			return generated.GetCustomerByIDRow{}, sql.ErrNoRows
+		},
+	}
+	handler := NewCustomerHandler(mockDomain)
+
+	t.Run("Only The Location", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/customer/1/location", nil)
+		req.SetPathValue("id", "1")
+
+		handler.GetCustomerLocation().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %v, got %v", http.StatusOK, rec.Code)
+		}
+		body := rec.Body.String()
+		if !strings.Contains(body, `"zip_code":2800`) || strings.Contains(body, "John Doe") || strings.Contains(body, "Main St") {
+			t.Errorf("expected only the zip code and coordinates, got %s", body)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/customer/999/location", nil)
+		req.SetPathValue("id", "999")
+
+		handler.GetCustomerLocation().ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected status %v, got %v", http.StatusNotFound, rec.Code)
+		}
+	})
+}
+
 func TestUpdateCustomerHandler(t *testing.T) {
 	mockDomain := &MockCustomerDomain{
-		UpdateCustomerDomainFunc: func(ctx context.Context, customerParams generated.UpdateCustomerParams) error {
+		UpdateCustomerDomainFunc: func(ctx context.Context, customerParams domain.CustomerUpdateParams) error {
 			return nil
 		},
 		UpdateAddressFunc: func(ctx context.Context, addressParams generated.UpdateAddressParams) error {
@@ -296,7 +379,7 @@ func TestUpdateCustomerHandler(t *testing.T) {
 	})
 
 	t.Run("customer not found", func(t *testing.T) {
-		mockDomain.UpdateCustomerDomainFunc = func(ctx context.Context, customerParams generated.UpdateCustomerParams) error {
+		mockDomain.UpdateCustomerDomainFunc = func(ctx context.Context, customerParams domain.CustomerUpdateParams) error {
 			return sql.ErrNoRows
 		}
 
@@ -404,3 +487,53 @@ func TestDeleteCustomerHandler(t *testing.T) {
 	})
 
 }
+
+func TestUpdateCustomerRoleHandler(t *testing.T) {
+	var changed domain.RoleParams
+	mockDomain := &MockCustomerDomain{
+		UpdateCustomerRoleFunc: func(ctx context.Context, id int32, roleParams domain.RoleParams) error {
+			if id != 1 {
+				return domain.ErrCustomerNotFound
+			}
+			if roleParams.Role == auth.RoleRestaurant && roleParams.RestaurantID == nil {
+				return domain.ErrInvalidRole
+			}
+			changed = roleParams
+			return nil
+		},
+	}
+	handler := NewCustomerHandler(mockDomain)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"Valid Role", "1", `{"role": "restaurant", "restaurant_id": 3}`, http.StatusNoContent},
+		{"Missing Restaurant", "1", `{"role": "restaurant"}`, http.StatusBadRequest},
+		{"Unknown Customer", "999", `{"role": "admin"}`, http.StatusNotFound},
+		{"Invalid ID", "invalid", `{"role": "admin"}`, http.StatusBadRequest},
+		{"Invalid Payload", "1", `not json`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPut, "/api/customer/"+tt.id+"/role", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", tt.id)
+
+			// Act
+			handler.UpdateCustomerRole().ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+
+	if changed.Role != auth.RoleRestaurant || changed.RestaurantID == nil || *changed.RestaurantID != 3 {
+		t.Errorf("expected the account to become staff of restaurant 3, got %+v", changed)
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+var errInvalidQuery = errors.New("invalid query parameter")
+
+// isListError tells whether err is caused by invalid paging, sorting or filters
+func isListError(err error) bool {
+	return errors.Is(err, domain.ErrInvalidCursor) || errors.Is(err, domain.ErrInvalidSort) || errors.Is(err, errInvalidQuery)
+}
+
+// queryParser reads optional query parameters. Missing parameters are nil,
+// and the first parameter that does not parse is kept in err.
+type queryParser struct {
+	r   *http.Request
+	err error
+}
+
+func (p *queryParser) value(name string, parse func(string) error) {
+	value := p.r.URL.Query().Get(name)
+	if value == "" || p.err != nil {
+		return
+	}
+	if err := parse(value); err != nil {
+		p.err = fmt.Errorf("%w: %s", errInvalidQuery, name)
+	}
+}
+
+// listParams reads the limit, cursor and sort query parameters
+func (p *queryParser) listParams() domain.ListParams {
+	query := p.r.URL.Query()
+	params := domain.ListParams{Cursor: query.Get("cursor"), Sort: query.Get("sort")}
+	p.value("limit", func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err == nil && n < 1 {
+			err = errInvalidQuery
+		}
+		params.Limit = n
+		return err
+	})
+	return params
+}
+
+func (p *queryParser) string(name string) *string {
+	if value := p.r.URL.Query().Get(name); value != "" {
+		return &value
+	}
+	return nil
+}
+
+func (p *queryParser) int32(name string) *int32 {
+	var result *int32
+	p.value(name, func(value string) error {
+		n, err := strconv.ParseInt(value, 10, 32)
+		n32 := int32(n)
+		result = &n32
+		return err
+	})
+	return result
+}
@@ -1,44 +1,124 @@
 package main
 
 import (
+	"context"
+	"crypto/ed25519"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	_ "github.com/rasm445f/soft-exam-2/docs"
 	"github.com/rasm445f/soft-exam-2/domain"
 	"github.com/rasm445f/soft-exam-2/handlers"
+	"github.com/rasm445f/soft-exam-2/mailer"
 	"github.com/rasm445f/soft-exam-2/metrics"
 	"github.com/rs/cors"
 
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
+// Access tokens are verified offline by the other services, so they cannot
+// be revoked and are kept short-lived
+const (
+	accessTokenTTL       = 15 * time.Minute
+	pruneRefreshInterval = time.Hour
+)
+
+// signingKeys reads the PEM encoded Ed25519 keys in JWT_SIGNING_KEY, the
+// first signing new tokens. Without it a key is generated, and tokens stop
+// being valid when the service restarts.
+func signingKeys() ([]ed25519.PrivateKey, error) {
+	pemKeys := os.Getenv("JWT_SIGNING_KEY")
+	if pemKeys != "" {
+		return auth.ParseSigningKeys([]byte(pemKeys))
+	}
+	log.Println("JWT_SIGNING_KEY is not set, signing access tokens with a temporary key")
+	key, err := auth.GenerateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return []ed25519.PrivateKey{key}, nil
+}
+
+// serviceClients reads the services allowed to get service tokens from
+// SERVICE_CLIENTS, comma separated client-id:secret pairs
+func serviceClients() (map[string]string, error) {
+	clients := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("SERVICE_CLIENTS"), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		clientId, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || clientId == "" || secret == "" {
+			return nil, fmt.Errorf("SERVICE_CLIENTS entry %q is not a client-id:secret pair", pair)
+		}
+		clients[clientId] = secret
+	}
+	if len(clients) == 0 {
+		log.Println("SERVICE_CLIENTS is not set, other services cannot get service tokens")
+	}
+	return clients, nil
+}
+
 func run() (http.Handler, error) {
 	db, err := db.ConnectDB()
 	if err != nil {
 		return nil, err
 	}
 
+	keys, err := signingKeys()
+	if err != nil {
+		return nil, err
+	}
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = auth.DefaultIssuer
+	}
+	services, err := serviceClients()
+	if err != nil {
+		return nil, err
+	}
+	signer := auth.NewSigner(keys, issuer, accessTokenTTL)
+	verifier := auth.NewVerifier(auth.StaticKeys(signer.JWKS()), issuer)
+
 	// Initialize Queries with DB
 	queries := generated.New(db)
-	customerDomain := domain.NewCustomerDomain(queries)
+	customerDomain := domain.NewCustomerDomain(queries, db)
 	customerHandler := handlers.NewCustomerHandler(customerDomain)
+	authDomain := domain.NewAuthDomain(queries, customerDomain, signer, services, mailer.SendMailWithGomail)
+	authHandler := handlers.NewAuthHandler(authDomain)
+	go pruneExpiredTokens(authDomain)
 
 	mux := http.NewServeMux()
 
 	// Routes
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("GET /api/docs/", httpSwagger.WrapHandler)
-	mux.HandleFunc("GET /api/customer", customerHandler.GetAllCustomers())
-	mux.HandleFunc("GET /api/customer/{id}", customerHandler.GetCustomerById())
-	mux.HandleFunc("DELETE /api/customer/{id}", customerHandler.DeleteCustomer())
+	// Anyone may sign up; accounts are only seen by their owner. Other
+	// services look up where a customer is delivered to, and nothing more.
+	mux.HandleFunc("GET /api/customer", auth.Allow(customerHandler.GetAllCustomers()))
+	mux.HandleFunc("GET /api/customer/{id}", auth.Allow(customerHandler.GetCustomerById(), auth.Self("id")))
+	mux.HandleFunc("GET /api/customer/{id}/location", auth.Allow(customerHandler.GetCustomerLocation(), auth.Self("id"), auth.Roles(auth.RoleService)))
+	mux.HandleFunc("DELETE /api/customer/{id}", auth.Allow(customerHandler.DeleteCustomer(), auth.Self("id")))
 	mux.HandleFunc("POST /api/customer", customerHandler.CreateCustomer())
-	mux.HandleFunc("PATCH /api/customer/{id}", customerHandler.UpdateCustomer())
+	mux.HandleFunc("PATCH /api/customer/{id}", auth.Allow(customerHandler.UpdateCustomer(), auth.Self("id")))
+	mux.HandleFunc("PUT /api/customer/{id}/role", auth.Allow(customerHandler.UpdateCustomerRole()))
+	// Auth
+	mux.HandleFunc("POST /api/auth/login", authHandler.Login())
+	mux.HandleFunc("POST /api/auth/refresh", authHandler.Refresh())
+	mux.HandleFunc("POST /api/auth/logout", authHandler.Logout())
+	mux.HandleFunc("POST /api/auth/password-reset", authHandler.RequestPasswordReset())
+	mux.HandleFunc("POST /api/auth/password-reset/confirm", authHandler.ResetPassword())
+	mux.HandleFunc("POST /api/auth/service-token", authHandler.ServiceToken())
+	mux.HandleFunc("GET /.well-known/jwks.json", authHandler.JWKS())
 
 	//CORS stuff
 	corsHandler := cors.New(cors.Options{
@@ -49,12 +129,35 @@ func run() (http.Handler, error) {
 		Debug:            true,
 	})
 
-	metrics := metrics.MetricsMiddleware(mux)
+	metrics := metrics.MetricsMiddleware(verifier.Middleware(mux))
 	handler := corsHandler.Handler(metrics)
 
 	return handler, err
 }
 
+// pruneExpiredTokens deletes expired refresh and password reset tokens every
+// hour
+func pruneExpiredTokens(authDomain *domain.AuthDomain) {
+	ticker := time.NewTicker(pruneRefreshInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		deleted, err := authDomain.PruneRefreshTokensDomain(context.Background())
+		if err != nil {
+			log.Println(err)
+		} else if deleted > 0 {
+			log.Printf("Pruned %d expired refresh tokens", deleted)
+		}
+
+		deleted, err = authDomain.PrunePasswordResetsDomain(context.Background())
+		if err != nil {
+			log.Println(err)
+		} else if deleted > 0 {
+			log.Printf("Pruned %d expired password reset tokens", deleted)
+		}
+	}
+}
+
 // @title Customer Service API
 // @version 1.0
 // @description This is the API documentation for the Customer Service.
@@ -0,0 +1,101 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+const (
+	defaultRestaurantServiceURL = "http://localhost:8083"
+	defaultCustomerServiceURL   = "http://localhost:8081"
+	requestTimeout              = 3 * time.Second
+)
+
+// LocationClient looks up where restaurants and customers are in the
+// services that own and geocode them
+type LocationClient struct {
+	restaurantServiceURL string
+	customerServiceURL   string
+	httpClient           *http.Client
+	services             *auth.ServiceClient
+}
+
+// NewLocationClient reads the service URLs from RESTAURANT_SERVICE_URL and
+// CUSTOMER_SERVICE_URL, falling back to the local development ports, and the
+// service credentials customers are looked up with from the environment
+func NewLocationClient() *LocationClient {
+	return &LocationClient{
+		restaurantServiceURL: getEnv("RESTAURANT_SERVICE_URL", defaultRestaurantServiceURL),
+		customerServiceURL:   getEnv("CUSTOMER_SERVICE_URL", defaultCustomerServiceURL),
+		httpClient:           &http.Client{Timeout: requestTimeout},
+		services:             auth.NewServiceClientFromEnv(),
+	}
+}
+
+func (c *LocationClient) RestaurantLocation(ctx context.Context, restaurantId int32) (*domain.Location, error) {
+	var body struct {
+		Location *domain.Location `json:"location"`
+	}
+	url := fmt.Sprintf("%s/api/restaurants/%d", c.restaurantServiceURL, restaurantId)
+	if err := c.fetch(ctx, url, nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Location, nil
+}
+
+func (c *LocationClient) CustomerLocation(ctx context.Context, customerId int32) (*domain.Location, error) {
+	var body struct {
+		Latitude  *float64 `json:"latitude"`
+		Longitude *float64 `json:"longitude"`
+	}
+	// Customers are only shown to themselves, other services only get to
+	// see where they are delivered to
+	url := fmt.Sprintf("%s/api/customer/%d/location", c.customerServiceURL, customerId)
+	if err := c.fetch(ctx, url, c.services, &body); err != nil {
+		return nil, err
+	}
+	if body.Latitude == nil || body.Longitude == nil {
+		return nil, nil
+	}
+	return &domain.Location{Latitude: *body.Latitude, Longitude: *body.Longitude}, nil
+}
+
+// fetch decodes the JSON at url into body, authorizing the request as this
+// service when services is given
+func (c *LocationClient) fetch(ctx context.Context, url string, services *auth.ServiceClient, body any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if services != nil {
+		if err := services.Authorize(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(body)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
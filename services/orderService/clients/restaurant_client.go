@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// RestaurantClient looks up kitchens and takes the stock of orders in the
+// restaurant service
+type RestaurantClient struct {
+	restaurantServiceURL string
+	httpClient           *http.Client
+	services             *auth.ServiceClient
+}
+
+// NewRestaurantClient reads the service URL from RESTAURANT_SERVICE_URL,
+// falling back to the local development port, and the service credentials
+// stock is reserved with from the environment
+func NewRestaurantClient() *RestaurantClient {
+	return &RestaurantClient{
+		restaurantServiceURL: getEnv("RESTAURANT_SERVICE_URL", defaultRestaurantServiceURL),
+		httpClient:           &http.Client{Timeout: requestTimeout},
+		services:             auth.NewServiceClientFromEnv(),
+	}
+}
+
+// KitchenAtCapacity tells whether the restaurant's kitchen takes no more
+// orders until orders leave it
+func (c *RestaurantClient) KitchenAtCapacity(ctx context.Context, restaurantId int32) (bool, error) {
+	url := fmt.Sprintf("%s/api/restaurants/%d/status", c.restaurantServiceURL, restaurantId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body struct {
+		AtCapacity bool `json:"at_capacity"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, err
+	}
+	return body.AtCapacity, nil
+}
+
+// ReserveStock counts the items of the order as sold today. When there is not
+// enough left, reserved is false and reason tells which item ran out.
+func (c *RestaurantClient) ReserveStock(ctx context.Context, restaurantId, orderId int32, items []domain.OrderedItem) (bool, string, error) {
+	payload, err := json.Marshal(struct {
+		Items []domain.OrderedItem `json:"items"`
+	}{Items: items})
+	if err != nil {
+		return false, "", err
+	}
+
+	url := fmt.Sprintf("%s/api/restaurants/%d/orders/%d/stock", c.restaurantServiceURL, restaurantId, orderId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.services.Authorize(ctx, req); err != nil {
+		return false, "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return true, "", nil
+	case http.StatusConflict:
+		body, _ := io.ReadAll(resp.Body)
+		return false, strings.TrimSpace(string(body)), nil
+	default:
+		return false, "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+}
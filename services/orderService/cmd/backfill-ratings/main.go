@@ -0,0 +1,33 @@
+// Command backfill-ratings republishes historical feedback as feedback.created
+// events, so the restaurant service recomputes its ratings from them.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+func main() {
+	broker.InitRabbitMQ()
+	defer broker.CloseRabbitMQ()
+
+	conn, err := db.ConnectDB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	feedbackDomain := domain.NewFeedbackDomain(generated.New(conn), conn, broker.PublishFanout)
+	published, err := feedbackDomain.RepublishFeedbackEventsDomain(context.Background())
+	if err != nil {
+		log.Fatalf("Published %d feedback events before failing: %v", published, err)
+	}
+
+	fmt.Printf("Published %d feedback events\n", published)
+}
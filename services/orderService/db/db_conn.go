@@ -5,15 +5,18 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func ConnectDB() (*pgx.Conn, error) {
+// ConnectDB opens a connection pool. A single connection cannot be shared by
+// concurrent requests, and transactions must hold on to their connection
+// while other requests keep running.
+func ConnectDB() (*pgxpool.Pool, error) {
 
 	connStr := os.Getenv("DBSTRING")
 	ctx := context.Background()
 
-	db, err := pgx.Connect(ctx, connStr)
+	db, err := pgxpool.New(ctx, connStr)
 
 	if err != nil {
 		return nil, err
@@ -21,6 +24,7 @@ func ConnectDB() (*pgx.Conn, error) {
 
 	err = db.Ping(ctx)
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 	fmt.Println("Successfully connected to the database!")
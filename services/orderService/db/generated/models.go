@@ -21,6 +21,17 @@ type Deliveryagent struct {
 	Contactinfo  *string  `json:"contactinfo"`
 	Availability *bool    `json:"availability"`
 	Rating       *float64 `json:"rating"`
+	Ratingsum    int32    `json:"ratingsum"`
+	Ratingcount  int32    `json:"ratingcount"`
+}
+
+type Deliveryagentlocation struct {
+	ID              int64      `json:"id"`
+	Deliveryagentid int32      `json:"deliveryagentid"`
+	Orderid         *int32     `json:"orderid"`
+	Latitude        float64    `json:"latitude"`
+	Longitude       float64    `json:"longitude"`
+	Recordedat      *time.Time `json:"recordedat"`
 }
 
 type Fee struct {
@@ -31,35 +42,65 @@ type Fee struct {
 }
 
 type Feedback struct {
-	ID                  int32   `json:"id"`
-	Orderid             int32   `json:"orderid"`
-	Customerid          int32   `json:"customerid"`
-	Deliveryagentrating *int32  `json:"deliveryagentrating"`
-	Restaurantrating    *int32  `json:"restaurantrating"`
-	Comment             *string `json:"comment"`
+	ID                  int32      `json:"id"`
+	Orderid             int32      `json:"orderid"`
+	Customerid          int32      `json:"customerid"`
+	Deliveryagentrating *int32     `json:"deliveryagentrating"`
+	Restaurantrating    *int32     `json:"restaurantrating"`
+	Comment             *string    `json:"comment"`
+	Createdat           *time.Time `json:"createdat"`
+	Updatedat           *time.Time `json:"updatedat"`
 }
 
 type Order struct {
-	ID              int32      `json:"id"`
-	Totalamount     float64    `json:"totalamount"`
-	Vatamount       float64    `json:"vatamount"`
-	Status          string     `json:"status"`
-	Timestamp       *time.Time `json:"timestamp"`
-	Comment         *string    `json:"comment"`
-	Customerid      *int32     `json:"customerid"`
-	Restaurantid    *int32     `json:"restaurantid"`
-	Deliveryagentid *int32     `json:"deliveryagentid"`
-	Paymentid       *int32     `json:"paymentid"`
-	Bonusid         *int32     `json:"bonusid"`
-	Feeid           *int32     `json:"feeid"`
+	ID                    int32      `json:"id"`
+	Totalamount           float64    `json:"totalamount"`
+	Vatamount             float64    `json:"vatamount"`
+	Status                string     `json:"status"`
+	Timestamp             *time.Time `json:"timestamp"`
+	Comment               *string    `json:"comment"`
+	Customerid            *int32     `json:"customerid"`
+	Restaurantid          *int32     `json:"restaurantid"`
+	Deliveryagentid       *int32     `json:"deliveryagentid"`
+	Paymentid             *int32     `json:"paymentid"`
+	Bonusid               *int32     `json:"bonusid"`
+	Feeid                 *int32     `json:"feeid"`
+	Preptimeminutes       *int32     `json:"preptimeminutes"`
+	Estimateddeliverytime *time.Time `json:"estimateddeliverytime"`
+	Deliveredat           *time.Time `json:"deliveredat"`
+	Deliveryfee           float64    `json:"deliveryfee"`
+	Discountamount        float64    `json:"discountamount"`
+	Promotionid           *int32     `json:"promotionid"`
+}
+
+type Orderevent struct {
+	ID        int64      `json:"id"`
+	Orderid   int32      `json:"orderid"`
+	Type      string     `json:"type"`
+	Payload   []byte     `json:"payload"`
+	Createdat *time.Time `json:"createdat"`
 }
 
 type Orderitem struct {
-	ID       int32   `json:"id"`
-	Orderid  int32   `json:"orderid"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity float64 `json:"quantity"`
+	ID             int32    `json:"id"`
+	Orderid        int32    `json:"orderid"`
+	Name           string   `json:"name"`
+	Price          float64  `json:"price"`
+	Quantity       float64  `json:"quantity"`
+	Menuitemid     *int32   `json:"menuitemid"`
+	Options        []byte   `json:"options"`
+	Components     []byte   `json:"components"`
+	Allergens      []string `json:"allergens"`
+	Dietarytags    []string `json:"dietarytags"`
+	Priceversionid *int32   `json:"priceversionid"`
+}
+
+type Orderresponse struct {
+	Orderid      int32      `json:"orderid"`
+	Accepted     bool       `json:"accepted"`
+	Reason       *string    `json:"reason"`
+	Autorejected bool       `json:"autorejected"`
+	Respondedat  *time.Time `json:"respondedat"`
 }
 
 type Payment struct {
@@ -67,3 +108,20 @@ type Payment struct {
 	Paymentstatus string `json:"paymentstatus"`
 	Paymentmethod string `json:"paymentmethod"`
 }
+
+type Promotion struct {
+	ID                 int32      `json:"id"`
+	Code               string     `json:"code"`
+	Description        *string    `json:"description"`
+	Discounttype       string     `json:"discounttype"`
+	Discountvalue      float64    `json:"discountvalue"`
+	Restaurantid       *int32     `json:"restaurantid"`
+	Minimumorder       float64    `json:"minimumorder"`
+	Validfrom          *time.Time `json:"validfrom"`
+	Validto            *time.Time `json:"validto"`
+	Maxuses            *int32     `json:"maxuses"`
+	Maxusespercustomer *int32     `json:"maxusespercustomer"`
+	Firstorderonly     bool       `json:"firstorderonly"`
+	Active             bool       `json:"active"`
+	Createdat          *time.Time `json:"createdat"`
+}
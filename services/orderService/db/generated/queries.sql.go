@@ -10,6 +10,81 @@ import (
 	"time"
 )
 
+const addDeliveryAgentRating = `-- name: AddDeliveryAgentRating :exec
+UPDATE
+    DeliveryAgent
+SET
+    RatingSum = RatingSum + $1,
+    RatingCount = RatingCount + $2,
+    Rating = ROUND((RatingSum + $1)::numeric / NULLIF(RatingCount + $2, 0), 1)
+WHERE
+    ID = $3
+`
+
+type AddDeliveryAgentRatingParams struct {
+	Ratingsum   int32 `json:"ratingsum"`
+	Ratingcount int32 `json:"ratingcount"`
+	ID          int32 `json:"id"`
+}
+
+// Add ratings to a Delivery Agent's rating aggregate. A changed rating adds the
+// difference with a count of 0.
+func (q *Queries) AddDeliveryAgentRating(ctx context.Context, arg AddDeliveryAgentRatingParams) error {
+	_, err := q.db.Exec(ctx, addDeliveryAgentRating, arg.Ratingsum, arg.Ratingcount, arg.ID)
+	return err
+}
+
+const countCustomerOrders = `-- name: CountCustomerOrders :one
+SELECT
+    COUNT(*)
+FROM
+    "Order"
+WHERE
+    CustomerID = $1
+    AND Status NOT IN ('Rejected', 'Cancelled')
+`
+
+// Orders of the customer, leaving out rejected and cancelled ones
+func (q *Queries) CountCustomerOrders(ctx context.Context, customerid *int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countCustomerOrders, customerid)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countPromotionUses = `-- name: CountPromotionUses :one
+SELECT
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE CustomerID = $2) AS by_customer
+FROM
+    "Order"
+WHERE
+    PromotionID = $1
+    AND Status NOT IN ('Rejected', 'Cancelled')
+`
+
+type CountPromotionUsesParams struct {
+	Promotionid *int32 `json:"promotionid"`
+	Customerid  *int32 `json:"customerid"`
+}
+
+type CountPromotionUsesRow struct {
+	Total      int64 `json:"total"`
+	ByCustomer int64 `json:"by_customer"`
+}
+
+// Orders placed with the Promotion, in total and by the customer. Rejected
+// and cancelled orders do not use up the Promotion.
+func (q *Queries) CountPromotionUses(ctx context.Context, arg CountPromotionUsesParams) (CountPromotionUsesRow, error) {
+	row := q.db.QueryRow(ctx, countPromotionUses, arg.Promotionid, arg.Customerid)
+	var i CountPromotionUsesRow
+	err := row.Scan(
+		&i.Total,
+		&i.ByCustomer,
+	)
+	return i, err
+}
+
 const createBonus = `-- name: CreateBonus :one
 INSERT INTO Bonus (Description, EarlyLateAmount, Percentage)
     VALUES ($1, $2, $3)
@@ -58,6 +133,45 @@ func (q *Queries) CreateDeliveryAgent(ctx context.Context, arg CreateDeliveryAge
 	return id, err
 }
 
+const createDeliveryAgentLocation = `-- name: CreateDeliveryAgentLocation :one
+INSERT INTO DeliveryAgentLocation (DeliveryAgentID, OrderID, Latitude, Longitude)
+    VALUES ($1, $2, $3, $4)
+RETURNING
+    ID,
+    DeliveryAgentID,
+    OrderID,
+    Latitude,
+    Longitude,
+    RecordedAt
+`
+
+type CreateDeliveryAgentLocationParams struct {
+	Deliveryagentid int32   `json:"deliveryagentid"`
+	Orderid         *int32  `json:"orderid"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+}
+
+// Record a Delivery Agent location ping
+func (q *Queries) CreateDeliveryAgentLocation(ctx context.Context, arg CreateDeliveryAgentLocationParams) (Deliveryagentlocation, error) {
+	row := q.db.QueryRow(ctx, createDeliveryAgentLocation,
+		arg.Deliveryagentid,
+		arg.Orderid,
+		arg.Latitude,
+		arg.Longitude,
+	)
+	var i Deliveryagentlocation
+	err := row.Scan(
+		&i.ID,
+		&i.Deliveryagentid,
+		&i.Orderid,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Recordedat,
+	)
+	return i, err
+}
+
 const createFee = `-- name: CreateFee :one
 INSERT INTO Fee (Percentage, Amount, Description)
     VALUES ($1, $2, $3)
@@ -109,8 +223,8 @@ func (q *Queries) CreateFeedback(ctx context.Context, arg CreateFeedbackParams)
 }
 
 const createOrder = `-- name: CreateOrder :one
-INSERT INTO "Order" (TotalAmount, VATAmount, Status, Timestamp, Comment, CustomerID, RestaurantID, DeliveryAgentID, PaymentID, BonusID, FeeID)
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+INSERT INTO "Order" (TotalAmount, VATAmount, Status, Timestamp, Comment, CustomerID, RestaurantID, DeliveryAgentID, PaymentID, BonusID, FeeID, DeliveryFee, DiscountAmount, PromotionID)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING
     ID
 `
@@ -127,6 +241,9 @@ type CreateOrderParams struct {
 	Paymentid       *int32     `json:"paymentid"`
 	Bonusid         *int32     `json:"bonusid"`
 	Feeid           *int32     `json:"feeid"`
+	Deliveryfee     float64    `json:"deliveryfee"`
+	Discountamount  float64    `json:"discountamount"`
+	Promotionid     *int32     `json:"promotionid"`
 }
 
 // Create a new Order
@@ -143,24 +260,64 @@ func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (int32
 		arg.Paymentid,
 		arg.Bonusid,
 		arg.Feeid,
+		arg.Deliveryfee,
+		arg.Discountamount,
+		arg.Promotionid,
 	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createOrderEvent = `-- name: CreateOrderEvent :one
+INSERT INTO OrderEvent (OrderID, Type, Payload)
+    VALUES ($1, $2, $3)
+RETURNING
+    ID,
+    OrderID,
+    Type,
+    Payload,
+    CreatedAt
+`
+
+type CreateOrderEventParams struct {
+	Orderid int32  `json:"orderid"`
+	Type    string `json:"type"`
+	Payload []byte `json:"payload"`
+}
+
+// Record an Order Event
+func (q *Queries) CreateOrderEvent(ctx context.Context, arg CreateOrderEventParams) (Orderevent, error) {
+	row := q.db.QueryRow(ctx, createOrderEvent, arg.Orderid, arg.Type, arg.Payload)
+	var i Orderevent
+	err := row.Scan(
+		&i.ID,
+		&i.Orderid,
+		&i.Type,
+		&i.Payload,
+		&i.Createdat,
+	)
+	return i, err
+}
+
 const createOrderItem = `-- name: CreateOrderItem :one
-INSERT INTO OrderItem (OrderID, Name, Price, Quantity)
-    VALUES ($1, $2, $3, $4)
+INSERT INTO OrderItem (OrderID, Name, Price, Quantity, MenuItemID, Options, Components, Allergens, DietaryTags, PriceVersionID)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
     ID
 `
 
 type CreateOrderItemParams struct {
-	Orderid  int32   `json:"orderid"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity float64 `json:"quantity"`
+	Orderid        int32    `json:"orderid"`
+	Name           string   `json:"name"`
+	Price          float64  `json:"price"`
+	Quantity       float64  `json:"quantity"`
+	Menuitemid     *int32   `json:"menuitemid"`
+	Options        []byte   `json:"options"`
+	Components     []byte   `json:"components"`
+	Allergens      []string `json:"allergens"`
+	Dietarytags    []string `json:"dietarytags"`
+	Priceversionid *int32   `json:"priceversionid"`
 }
 
 // Create a new Order Item
@@ -170,12 +327,40 @@ func (q *Queries) CreateOrderItem(ctx context.Context, arg CreateOrderItemParams
 		arg.Name,
 		arg.Price,
 		arg.Quantity,
+		arg.Menuitemid,
+		arg.Options,
+		arg.Components,
+		arg.Allergens,
+		arg.Dietarytags,
+		arg.Priceversionid,
 	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const createOrderResponse = `-- name: CreateOrderResponse :exec
+INSERT INTO OrderResponse (OrderID, Accepted, Reason, AutoRejected)
+    VALUES ($1, $2, $3, $4)
+`
+
+type CreateOrderResponseParams struct {
+	Orderid      int32   `json:"orderid"`
+	Accepted     bool    `json:"accepted"`
+	Reason       *string `json:"reason"`
+	Autorejected bool    `json:"autorejected"`
+}
+
+func (q *Queries) CreateOrderResponse(ctx context.Context, arg CreateOrderResponseParams) error {
+	_, err := q.db.Exec(ctx, createOrderResponse,
+		arg.Orderid,
+		arg.Accepted,
+		arg.Reason,
+		arg.Autorejected,
+	)
+	return err
+}
+
 const createPayment = `-- name: CreatePayment :one
 INSERT INTO Payment (PaymentStatus, PaymentMethod)
     VALUES ($1, $2)
@@ -196,6 +381,92 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (i
 	return id, err
 }
 
+const createPromotion = `-- name: CreatePromotion :one
+INSERT INTO Promotion (Code, Description, DiscountType, DiscountValue, RestaurantID, MinimumOrder, ValidFrom, ValidTo, MaxUses, MaxUsesPerCustomer, FirstOrderOnly)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING
+    id, code, description, discounttype, discountvalue, restaurantid, minimumorder, validfrom, validto, maxuses, maxusespercustomer, firstorderonly, active, createdat
+`
+
+type CreatePromotionParams struct {
+	Code               string     `json:"code"`
+	Description        *string    `json:"description"`
+	Discounttype       string     `json:"discounttype"`
+	Discountvalue      float64    `json:"discountvalue"`
+	Restaurantid       *int32     `json:"restaurantid"`
+	Minimumorder       float64    `json:"minimumorder"`
+	Validfrom          *time.Time `json:"validfrom"`
+	Validto            *time.Time `json:"validto"`
+	Maxuses            *int32     `json:"maxuses"`
+	Maxusespercustomer *int32     `json:"maxusespercustomer"`
+	Firstorderonly     bool       `json:"firstorderonly"`
+}
+
+func (q *Queries) CreatePromotion(ctx context.Context, arg CreatePromotionParams) (Promotion, error) {
+	row := q.db.QueryRow(ctx, createPromotion,
+		arg.Code,
+		arg.Description,
+		arg.Discounttype,
+		arg.Discountvalue,
+		arg.Restaurantid,
+		arg.Minimumorder,
+		arg.Validfrom,
+		arg.Validto,
+		arg.Maxuses,
+		arg.Maxusespercustomer,
+		arg.Firstorderonly,
+	)
+	var i Promotion
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Description,
+		&i.Discounttype,
+		&i.Discountvalue,
+		&i.Restaurantid,
+		&i.Minimumorder,
+		&i.Validfrom,
+		&i.Validto,
+		&i.Maxuses,
+		&i.Maxusespercustomer,
+		&i.Firstorderonly,
+		&i.Active,
+		&i.Createdat,
+	)
+	return i, err
+}
+
+const deactivatePromotion = `-- name: DeactivatePromotion :execrows
+UPDATE
+    Promotion
+SET
+    Active = FALSE
+WHERE
+    ID = $1
+`
+
+func (q *Queries) DeactivatePromotion(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deactivatePromotion, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteDeliveryAgentLocationsBefore = `-- name: DeleteDeliveryAgentLocationsBefore :execrows
+DELETE FROM DeliveryAgentLocation
+WHERE RecordedAt < $1
+`
+
+// Delete location pings recorded before the retention cutoff
+func (q *Queries) DeleteDeliveryAgentLocationsBefore(ctx context.Context, recordedat *time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeliveryAgentLocationsBefore, recordedat)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const deleteOrder = `-- name: DeleteOrder :exec
 DELETE FROM "Order"
 WHERE ID = $1
@@ -218,68 +489,39 @@ func (q *Queries) DeleteOrderItemsByOrderId(ctx context.Context, orderid int32)
 	return err
 }
 
-const getAllDeliveryAgents = `-- name: GetAllDeliveryAgents :many
+const getAllPromotions = `-- name: GetAllPromotions :many
 SELECT
-    id, fullname, contactinfo, availability, rating
+    id, code, description, discounttype, discountvalue, restaurantid, minimumorder, validfrom, validto, maxuses, maxusespercustomer, firstorderonly, active, createdat
 FROM
-    DeliveryAgent
+    Promotion
 ORDER BY
-    ID DESC
-`
-
-// Fetch all DeliveryAgents
-func (q *Queries) GetAllDeliveryAgents(ctx context.Context) ([]Deliveryagent, error) {
-	rows, err := q.db.Query(ctx, getAllDeliveryAgents)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Deliveryagent
-	for rows.Next() {
-		var i Deliveryagent
-		if err := rows.Scan(
-			&i.ID,
-			&i.Fullname,
-			&i.Contactinfo,
-			&i.Availability,
-			&i.Rating,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const getAllFeedbacks = `-- name: GetAllFeedbacks :many
-SELECT
-    id, orderid, customerid, deliveryagentrating, restaurantrating, comment
-FROM
-    Feedback
-ORDER BY
-    ID DESC
+    ID
 `
 
-// Fetch all Feedbacks
-func (q *Queries) GetAllFeedbacks(ctx context.Context) ([]Feedback, error) {
-	rows, err := q.db.Query(ctx, getAllFeedbacks)
+func (q *Queries) GetAllPromotions(ctx context.Context) ([]Promotion, error) {
+	rows, err := q.db.Query(ctx, getAllPromotions)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Feedback
+	var items []Promotion
 	for rows.Next() {
-		var i Feedback
+		var i Promotion
 		if err := rows.Scan(
 			&i.ID,
-			&i.Orderid,
-			&i.Customerid,
-			&i.Deliveryagentrating,
-			&i.Restaurantrating,
-			&i.Comment,
+			&i.Code,
+			&i.Description,
+			&i.Discounttype,
+			&i.Discountvalue,
+			&i.Restaurantid,
+			&i.Minimumorder,
+			&i.Validfrom,
+			&i.Validto,
+			&i.Maxuses,
+			&i.Maxusespercustomer,
+			&i.Firstorderonly,
+			&i.Active,
+			&i.Createdat,
 		); err != nil {
 			return nil, err
 		}
@@ -291,120 +533,23 @@ func (q *Queries) GetAllFeedbacks(ctx context.Context) ([]Feedback, error) {
 	return items, nil
 }
 
-const getAllFeedbacksFromDeliveryAgentByOrderId = `-- name: GetAllFeedbacksFromDeliveryAgentByOrderId :many
+const getAverageDeliveryDurationByRestaurant = `-- name: GetAverageDeliveryDurationByRestaurant :one
 SELECT
-    f.ID,
-    f.OrderID,
-    f.CustomerID,
-    f.DeliveryAgentRating,
-    f.RestaurantRating,
-    f.Comment,
-    o.DeliveryAgentID
-FROM
-    Feedback f
-    JOIN "Order" o ON f.OrderID = o.ID
-WHERE
-    o.DeliveryAgentID = (
-        SELECT
-            DeliveryAgentID
-        FROM
-            "Order"
-        WHERE
-            OrderID = $1
-        LIMIT 1)
-`
-
-type GetAllFeedbacksFromDeliveryAgentByOrderIdRow struct {
-	ID                  int32   `json:"id"`
-	Orderid             int32   `json:"orderid"`
-	Customerid          int32   `json:"customerid"`
-	Deliveryagentrating *int32  `json:"deliveryagentrating"`
-	Restaurantrating    *int32  `json:"restaurantrating"`
-	Comment             *string `json:"comment"`
-	Deliveryagentid     *int32  `json:"deliveryagentid"`
-}
-
-// Get all Feedbacks for a Delivery Agent
-func (q *Queries) GetAllFeedbacksFromDeliveryAgentByOrderId(ctx context.Context, orderid int32) ([]GetAllFeedbacksFromDeliveryAgentByOrderIdRow, error) {
-	rows, err := q.db.Query(ctx, getAllFeedbacksFromDeliveryAgentByOrderId, orderid)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []GetAllFeedbacksFromDeliveryAgentByOrderIdRow
-	for rows.Next() {
-		var i GetAllFeedbacksFromDeliveryAgentByOrderIdRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Orderid,
-			&i.Customerid,
-			&i.Deliveryagentrating,
-			&i.Restaurantrating,
-			&i.Comment,
-			&i.Deliveryagentid,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
-}
-
-const getAllOrders = `-- name: GetAllOrders :many
-SELECT
-    ID,
-    TotalAmount,
-    VATAmount,
-    Status,
-    Timestamp,
-    Comment,
-    CustomerID,
-    RestaurantID,
-    DeliveryAgentID,
-    PaymentID,
-    BonusID,
-    FeeID
+    COALESCE(AVG(EXTRACT(EPOCH FROM (DeliveredAt - Timestamp))), 0)::float8 AS avg_seconds
 FROM
     "Order"
-ORDER BY
-    Timestamp DESC
+WHERE
+    RestaurantID = $1
+    AND DeliveredAt IS NOT NULL
+    AND DeliveredAt > NOW() - INTERVAL '30 days'
 `
 
-// Fetch all Orders
-func (q *Queries) GetAllOrders(ctx context.Context) ([]Order, error) {
-	rows, err := q.db.Query(ctx, getAllOrders)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Order
-	for rows.Next() {
-		var i Order
-		if err := rows.Scan(
-			&i.ID,
-			&i.Totalamount,
-			&i.Vatamount,
-			&i.Status,
-			&i.Timestamp,
-			&i.Comment,
-			&i.Customerid,
-			&i.Restaurantid,
-			&i.Deliveryagentid,
-			&i.Paymentid,
-			&i.Bonusid,
-			&i.Feeid,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+// Average seconds from placing to delivering a restaurant's orders the last 30 days, 0 if none
+func (q *Queries) GetAverageDeliveryDurationByRestaurant(ctx context.Context, restaurantid *int32) (float64, error) {
+	row := q.db.QueryRow(ctx, getAverageDeliveryDurationByRestaurant, restaurantid)
+	var avgSeconds float64
+	err := row.Scan(&avgSeconds)
+	return avgSeconds, err
 }
 
 const getBonusById = `-- name: GetBonusById :one
@@ -434,7 +579,7 @@ func (q *Queries) GetBonusById(ctx context.Context, id int32) (Bonu, error) {
 
 const getDeliveryAgentById = `-- name: GetDeliveryAgentById :one
 SELECT
-    id, fullname, contactinfo, availability, rating
+    id, fullname, contactinfo, availability, rating, ratingsum, ratingcount
 FROM
     DeliveryAgent
 WHERE
@@ -451,10 +596,57 @@ func (q *Queries) GetDeliveryAgentById(ctx context.Context, id int32) (Deliverya
 		&i.Contactinfo,
 		&i.Availability,
 		&i.Rating,
+		&i.Ratingsum,
+		&i.Ratingcount,
 	)
 	return i, err
 }
 
+const getDeliveryAgentRatingDistribution = `-- name: GetDeliveryAgentRatingDistribution :many
+SELECT
+    f.DeliveryAgentRating::int AS rating,
+    COUNT(*)::int AS count
+FROM
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID
+WHERE
+    o.DeliveryAgentID = $1
+    AND f.DeliveryAgentRating IS NOT NULL
+GROUP BY
+    f.DeliveryAgentRating
+ORDER BY
+    f.DeliveryAgentRating
+`
+
+type GetDeliveryAgentRatingDistributionRow struct {
+	Rating int32 `json:"rating"`
+	Count  int32 `json:"count"`
+}
+
+// Count a Delivery Agent's ratings per star
+func (q *Queries) GetDeliveryAgentRatingDistribution(ctx context.Context, deliveryagentid *int32) ([]GetDeliveryAgentRatingDistributionRow, error) {
+	rows, err := q.db.Query(ctx, getDeliveryAgentRatingDistribution, deliveryagentid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeliveryAgentRatingDistributionRow
+	for rows.Next() {
+		var i GetDeliveryAgentRatingDistributionRow
+		if err := rows.Scan(
+			&i.Rating,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeeById = `-- name: GetFeeById :one
 SELECT
     ID,
@@ -482,7 +674,7 @@ func (q *Queries) GetFeeById(ctx context.Context, id int32) (Fee, error) {
 
 const getFeedbackById = `-- name: GetFeedbackById :one
 SELECT
-    id, orderid, customerid, deliveryagentrating, restaurantrating, comment
+    id, orderid, customerid, deliveryagentrating, restaurantrating, comment, createdat, updatedat
 FROM
     Feedback
 WHERE
@@ -500,13 +692,15 @@ func (q *Queries) GetFeedbackById(ctx context.Context, id int32) (Feedback, erro
 		&i.Deliveryagentrating,
 		&i.Restaurantrating,
 		&i.Comment,
+		&i.Createdat,
+		&i.Updatedat,
 	)
 	return i, err
 }
 
 const getFeedbackByOrderId = `-- name: GetFeedbackByOrderId :one
 SELECT
-    id, orderid, customerid, deliveryagentrating, restaurantrating, comment
+    id, orderid, customerid, deliveryagentrating, restaurantrating, comment, createdat, updatedat
 FROM
     Feedback
 WHERE
@@ -524,6 +718,41 @@ func (q *Queries) GetFeedbackByOrderId(ctx context.Context, orderid int32) (Feed
 		&i.Deliveryagentrating,
 		&i.Restaurantrating,
 		&i.Comment,
+		&i.Createdat,
+		&i.Updatedat,
+	)
+	return i, err
+}
+
+const getLatestDeliveryAgentLocation = `-- name: GetLatestDeliveryAgentLocation :one
+SELECT
+    ID,
+    DeliveryAgentID,
+    OrderID,
+    Latitude,
+    Longitude,
+    RecordedAt
+FROM
+    DeliveryAgentLocation
+WHERE
+    DeliveryAgentID = $1
+ORDER BY
+    RecordedAt DESC,
+    ID DESC
+LIMIT 1
+`
+
+// Fetch the latest location of a Delivery Agent
+func (q *Queries) GetLatestDeliveryAgentLocation(ctx context.Context, deliveryagentid int32) (Deliveryagentlocation, error) {
+	row := q.db.QueryRow(ctx, getLatestDeliveryAgentLocation, deliveryagentid)
+	var i Deliveryagentlocation
+	err := row.Scan(
+		&i.ID,
+		&i.Deliveryagentid,
+		&i.Orderid,
+		&i.Latitude,
+		&i.Longitude,
+		&i.Recordedat,
 	)
 	return i, err
 }
@@ -541,7 +770,13 @@ SELECT
     DeliveryAgentID,
     PaymentID,
     BonusID,
-    FeeID
+    FeeID,
+    PrepTimeMinutes,
+    EstimatedDeliveryTime,
+    DeliveredAt,
+    DeliveryFee,
+    DiscountAmount,
+    PromotionID
 FROM
     "Order"
 WHERE
@@ -565,17 +800,79 @@ func (q *Queries) GetOrderById(ctx context.Context, id int32) (Order, error) {
 		&i.Paymentid,
 		&i.Bonusid,
 		&i.Feeid,
+		&i.Preptimeminutes,
+		&i.Estimateddeliverytime,
+		&i.Deliveredat,
+		&i.Deliveryfee,
+		&i.Discountamount,
+		&i.Promotionid,
 	)
 	return i, err
 }
 
+const getOrderEventsAfter = `-- name: GetOrderEventsAfter :many
+SELECT
+    ID,
+    OrderID,
+    Type,
+    Payload,
+    CreatedAt
+FROM
+    OrderEvent
+WHERE
+    OrderID = $1
+    AND ID > $2
+ORDER BY
+    ID ASC
+LIMIT $3
+`
+
+type GetOrderEventsAfterParams struct {
+	Orderid int32 `json:"orderid"`
+	ID      int64 `json:"id"`
+	Limit   int32 `json:"limit"`
+}
+
+// Fetch the Order Events recorded after a given event ID
+func (q *Queries) GetOrderEventsAfter(ctx context.Context, arg GetOrderEventsAfterParams) ([]Orderevent, error) {
+	rows, err := q.db.Query(ctx, getOrderEventsAfter, arg.Orderid, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Orderevent
+	for rows.Next() {
+		var i Orderevent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Orderid,
+			&i.Type,
+			&i.Payload,
+			&i.Createdat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getOrderItemsByOrderId = `-- name: GetOrderItemsByOrderId :many
 SELECT
     ID,
     OrderID,
     Name,
     Price,
-    Quantity
+    Quantity,
+    MenuItemID,
+    Options,
+    Components,
+    Allergens,
+    DietaryTags,
+    PriceVersionID
 FROM
     OrderItem
 WHERE
@@ -598,6 +895,12 @@ func (q *Queries) GetOrderItemsByOrderId(ctx context.Context, orderid int32) ([]
 			&i.Name,
 			&i.Price,
 			&i.Quantity,
+			&i.Menuitemid,
+			&i.Options,
+			&i.Components,
+			&i.Allergens,
+			&i.Dietarytags,
+			&i.Priceversionid,
 		); err != nil {
 			return nil, err
 		}
@@ -609,6 +912,32 @@ func (q *Queries) GetOrderItemsByOrderId(ctx context.Context, orderid int32) ([]
 	return items, nil
 }
 
+const getOrderResponse = `-- name: GetOrderResponse :one
+SELECT
+    OrderID,
+    Accepted,
+    Reason,
+    AutoRejected,
+    RespondedAt
+FROM
+    OrderResponse
+WHERE
+    OrderID = $1
+`
+
+func (q *Queries) GetOrderResponse(ctx context.Context, orderid int32) (Orderresponse, error) {
+	row := q.db.QueryRow(ctx, getOrderResponse, orderid)
+	var i Orderresponse
+	err := row.Scan(
+		&i.Orderid,
+		&i.Accepted,
+		&i.Reason,
+		&i.Autorejected,
+		&i.Respondedat,
+	)
+	return i, err
+}
+
 const getPaymentById = `-- name: GetPaymentById :one
 SELECT
     ID,
@@ -628,6 +957,259 @@ func (q *Queries) GetPaymentById(ctx context.Context, id int32) (Payment, error)
 	return i, err
 }
 
+const getPromotionByCode = `-- name: GetPromotionByCode :one
+SELECT
+    id, code, description, discounttype, discountvalue, restaurantid, minimumorder, validfrom, validto, maxuses, maxusespercustomer, firstorderonly, active, createdat
+FROM
+    Promotion
+WHERE
+    Code = $1
+`
+
+func (q *Queries) GetPromotionByCode(ctx context.Context, code string) (Promotion, error) {
+	row := q.db.QueryRow(ctx, getPromotionByCode, code)
+	var i Promotion
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Description,
+		&i.Discounttype,
+		&i.Discountvalue,
+		&i.Restaurantid,
+		&i.Minimumorder,
+		&i.Validfrom,
+		&i.Validto,
+		&i.Maxuses,
+		&i.Maxusespercustomer,
+		&i.Firstorderonly,
+		&i.Active,
+		&i.Createdat,
+	)
+	return i, err
+}
+
+const getRecentDeliveryAgentComments = `-- name: GetRecentDeliveryAgentComments :many
+SELECT
+    f.OrderID,
+    f.DeliveryAgentRating,
+    f.Comment,
+    f.CreatedAt
+FROM
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID
+WHERE
+    o.DeliveryAgentID = $1
+    AND f.Comment IS NOT NULL
+    AND f.Comment <> ''
+ORDER BY
+    f.CreatedAt DESC,
+    f.ID DESC
+LIMIT $2
+`
+
+type GetRecentDeliveryAgentCommentsParams struct {
+	Deliveryagentid *int32 `json:"deliveryagentid"`
+	Limit           int32  `json:"limit"`
+}
+
+type GetRecentDeliveryAgentCommentsRow struct {
+	Orderid             int32      `json:"orderid"`
+	Deliveryagentrating *int32     `json:"deliveryagentrating"`
+	Comment             *string    `json:"comment"`
+	Createdat           *time.Time `json:"createdat"`
+}
+
+// Fetch the latest comments on a Delivery Agent's deliveries
+func (q *Queries) GetRecentDeliveryAgentComments(ctx context.Context, arg GetRecentDeliveryAgentCommentsParams) ([]GetRecentDeliveryAgentCommentsRow, error) {
+	rows, err := q.db.Query(ctx, getRecentDeliveryAgentComments, arg.Deliveryagentid, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRecentDeliveryAgentCommentsRow
+	for rows.Next() {
+		var i GetRecentDeliveryAgentCommentsRow
+		if err := rows.Scan(
+			&i.Orderid,
+			&i.Deliveryagentrating,
+			&i.Comment,
+			&i.Createdat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestaurantRatingsFromFeedbacks = `-- name: GetRestaurantRatingsFromFeedbacks :many
+SELECT
+    f.ID,
+    f.OrderID,
+    o.RestaurantID,
+    f.RestaurantRating
+FROM
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID
+WHERE
+    f.RestaurantRating IS NOT NULL
+    AND o.RestaurantID IS NOT NULL
+ORDER BY
+    f.ID
+`
+
+type GetRestaurantRatingsFromFeedbacksRow struct {
+	ID               int32  `json:"id"`
+	Orderid          int32  `json:"orderid"`
+	Restaurantid     *int32 `json:"restaurantid"`
+	Restaurantrating *int32 `json:"restaurantrating"`
+}
+
+// Fetch the restaurant ratings of all Feedbacks, used to backfill restaurant ratings
+func (q *Queries) GetRestaurantRatingsFromFeedbacks(ctx context.Context) ([]GetRestaurantRatingsFromFeedbacksRow, error) {
+	rows, err := q.db.Query(ctx, getRestaurantRatingsFromFeedbacks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRestaurantRatingsFromFeedbacksRow
+	for rows.Next() {
+		var i GetRestaurantRatingsFromFeedbacksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Orderid,
+			&i.Restaurantid,
+			&i.Restaurantrating,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnansweredOrders = `-- name: GetUnansweredOrders :many
+SELECT
+    ID,
+    RestaurantID
+FROM
+    "Order"
+WHERE
+    Status = 'Pending'
+    AND Timestamp < $1
+ORDER BY
+    Timestamp
+LIMIT $2
+`
+
+type GetUnansweredOrdersParams struct {
+	Timestamp *time.Time `json:"timestamp"`
+	Limit     int32      `json:"limit"`
+}
+
+type GetUnansweredOrdersRow struct {
+	ID           int32  `json:"id"`
+	Restaurantid *int32 `json:"restaurantid"`
+}
+
+// Pending Orders placed before the deadline, oldest first
+func (q *Queries) GetUnansweredOrders(ctx context.Context, arg GetUnansweredOrdersParams) ([]GetUnansweredOrdersRow, error) {
+	rows, err := q.db.Query(ctx, getUnansweredOrders, arg.Timestamp, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnansweredOrdersRow
+	for rows.Next() {
+		var i GetUnansweredOrdersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Restaurantid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockPromotionByCode = `-- name: LockPromotionByCode :one
+SELECT
+    id, code, description, discounttype, discountvalue, restaurantid, minimumorder, validfrom, validto, maxuses, maxusespercustomer, firstorderonly, active, createdat
+FROM
+    Promotion
+WHERE
+    Code = $1
+FOR UPDATE
+`
+
+// Locks the Promotion until the end of the transaction, so concurrent orders
+// cannot exceed its usage limits
+func (q *Queries) LockPromotionByCode(ctx context.Context, code string) (Promotion, error) {
+	row := q.db.QueryRow(ctx, lockPromotionByCode, code)
+	var i Promotion
+	err := row.Scan(
+		&i.ID,
+		&i.Code,
+		&i.Description,
+		&i.Discounttype,
+		&i.Discountvalue,
+		&i.Restaurantid,
+		&i.Minimumorder,
+		&i.Validfrom,
+		&i.Validto,
+		&i.Maxuses,
+		&i.Maxusespercustomer,
+		&i.Firstorderonly,
+		&i.Active,
+		&i.Createdat,
+	)
+	return i, err
+}
+
+const respondToOrder = `-- name: RespondToOrder :execrows
+UPDATE
+    "Order"
+SET
+    Status = $1,
+    PrepTimeMinutes = COALESCE($2, PrepTimeMinutes)
+WHERE
+    ID = $3
+    AND RestaurantID = $4
+    AND Status = 'Pending'
+`
+
+type RespondToOrderParams struct {
+	Status          string `json:"status"`
+	Preptimeminutes *int32 `json:"preptimeminutes"`
+	ID              int32  `json:"id"`
+	Restaurantid    *int32 `json:"restaurantid"`
+}
+
+// Answer a pending Order of the restaurant. Orders that are no longer
+// pending are left alone, so an order is only answered once.
+func (q *Queries) RespondToOrder(ctx context.Context, arg RespondToOrderParams) (int64, error) {
+	result, err := q.db.Exec(ctx, respondToOrder,
+		arg.Status,
+		arg.Preptimeminutes,
+		arg.ID,
+		arg.Restaurantid,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateDeliveryAgentAvailability = `-- name: UpdateDeliveryAgentAvailability :exec
 UPDATE
     DeliveryAgent
@@ -668,6 +1250,36 @@ func (q *Queries) UpdateDeliveryAgentRating(ctx context.Context, arg UpdateDeliv
 	return err
 }
 
+const updateFeedback = `-- name: UpdateFeedback :exec
+UPDATE
+    Feedback
+SET
+    DeliveryAgentRating = $1,
+    RestaurantRating = $2,
+    Comment = $3,
+    UpdatedAt = NOW()
+WHERE
+    OrderID = $4
+`
+
+type UpdateFeedbackParams struct {
+	Deliveryagentrating *int32  `json:"deliveryagentrating"`
+	Restaurantrating    *int32  `json:"restaurantrating"`
+	Comment             *string `json:"comment"`
+	Orderid             int32   `json:"orderid"`
+}
+
+// Update the Feedback of an Order
+func (q *Queries) UpdateFeedback(ctx context.Context, arg UpdateFeedbackParams) error {
+	_, err := q.db.Exec(ctx, updateFeedback,
+		arg.Deliveryagentrating,
+		arg.Restaurantrating,
+		arg.Comment,
+		arg.Orderid,
+	)
+	return err
+}
+
 const updateOrderBonus = `-- name: UpdateOrderBonus :exec
 UPDATE
     "Order"
@@ -688,27 +1300,58 @@ func (q *Queries) UpdateOrderBonus(ctx context.Context, arg UpdateOrderBonusPara
 	return err
 }
 
-const updateOrderStatus = `-- name: UpdateOrderStatus :exec
+const updateOrderEstimatedDeliveryTime = `-- name: UpdateOrderEstimatedDeliveryTime :exec
 UPDATE
     "Order"
 SET
-    Status = $1
+    EstimatedDeliveryTime = $1
 WHERE
     ID = $2
 `
 
-type UpdateOrderStatusParams struct {
-	Status string `json:"status"`
-	ID     int32  `json:"id"`
+type UpdateOrderEstimatedDeliveryTimeParams struct {
+	Estimateddeliverytime *time.Time `json:"estimateddeliverytime"`
+	ID                    int32      `json:"id"`
 }
 
-// Update an Order's status
-func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) error {
-	_, err := q.db.Exec(ctx, updateOrderStatus, arg.Status, arg.ID)
+// Update an Order's estimated delivery time
+func (q *Queries) UpdateOrderEstimatedDeliveryTime(ctx context.Context, arg UpdateOrderEstimatedDeliveryTimeParams) error {
+	_, err := q.db.Exec(ctx, updateOrderEstimatedDeliveryTime, arg.Estimateddeliverytime, arg.ID)
 	return err
 }
 
-const updateOrderStatusAndDeliveryAgent = `-- name: UpdateOrderStatusAndDeliveryAgent :exec
+const updateOrderStatus = `-- name: UpdateOrderStatus :execrows
+UPDATE
+    "Order"
+SET
+    Status = $1,
+    DeliveredAt = CASE WHEN $1 = 'Delivered' THEN
+        NOW()
+    ELSE
+        DeliveredAt
+    END
+WHERE
+    ID = $2
+    AND Status = $3
+`
+
+type UpdateOrderStatusParams struct {
+	Status     string `json:"status"`
+	ID         int32  `json:"id"`
+	FromStatus string `json:"from_status"`
+}
+
+// Update an Order's status, provided it still has the status it was read
+// with, so a status change made in the meantime is not overwritten
+func (q *Queries) UpdateOrderStatus(ctx context.Context, arg UpdateOrderStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateOrderStatus, arg.Status, arg.ID, arg.FromStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateOrderStatusAndDeliveryAgent = `-- name: UpdateOrderStatusAndDeliveryAgent :execrows
 UPDATE
     "Order"
 SET
@@ -716,16 +1359,28 @@ SET
     DeliveryAgentID = $2
 WHERE
     ID = $3
+    AND Status = $4
+    AND (DeliveryAgentID IS NULL OR DeliveryAgentID = $2)
 `
 
 type UpdateOrderStatusAndDeliveryAgentParams struct {
 	Status          string `json:"status"`
 	Deliveryagentid *int32 `json:"deliveryagentid"`
 	ID              int32  `json:"id"`
+	FromStatus      string `json:"from_status"`
 }
 
-// Update an Order's status and deliveryAgent
-func (q *Queries) UpdateOrderStatusAndDeliveryAgent(ctx context.Context, arg UpdateOrderStatusAndDeliveryAgentParams) error {
-	_, err := q.db.Exec(ctx, updateOrderStatusAndDeliveryAgent, arg.Status, arg.Deliveryagentid, arg.ID)
-	return err
+// Update an Order's status and deliveryAgent, provided it still has the
+// status it was read with and no other deliveryAgent
+func (q *Queries) UpdateOrderStatusAndDeliveryAgent(ctx context.Context, arg UpdateOrderStatusAndDeliveryAgentParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateOrderStatusAndDeliveryAgent,
+		arg.Status,
+		arg.Deliveryagentid,
+		arg.ID,
+		arg.FromStatus,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE OrderEvent (
+    ID bigserial PRIMARY KEY,
+    OrderID int NOT NULL REFERENCES "Order" (ID) ON DELETE CASCADE,
+    Type varchar(50) NOT NULL,
+    Payload jsonb NOT NULL DEFAULT '{}',
+    CreatedAt timestamp DEFAULT NOW()
+);
+
+CREATE INDEX idx_orderevent_order_id ON OrderEvent (OrderID, ID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE OrderEvent;
+-- +goose StatementEnd
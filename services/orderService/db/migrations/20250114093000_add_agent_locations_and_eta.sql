@@ -0,0 +1,141 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE DeliveryAgentLocation (
+    ID bigserial PRIMARY KEY,
+    DeliveryAgentID int NOT NULL REFERENCES DeliveryAgent (ID) ON DELETE CASCADE,
+    OrderID int REFERENCES "Order" (ID) ON DELETE SET NULL,
+    Latitude double precision NOT NULL,
+    Longitude double precision NOT NULL,
+    RecordedAt timestamp DEFAULT NOW()
+);
+
+CREATE INDEX idx_deliveryagentlocation_agent ON DeliveryAgentLocation (DeliveryAgentID, RecordedAt DESC);
+
+CREATE INDEX idx_deliveryagentlocation_recorded_at ON DeliveryAgentLocation (RecordedAt);
+
+ALTER TABLE "Order"
+    ADD COLUMN PrepTimeMinutes int,
+    ADD COLUMN EstimatedDeliveryTime timestamp,
+    ADD COLUMN DeliveredAt timestamp;
+
+-- Approximate centre of each zip code, used to estimate delivery distances
+CREATE TABLE ZipCodeLocation (
+    Zip_Code int PRIMARY KEY,
+    Latitude double precision NOT NULL,
+    Longitude double precision NOT NULL
+);
+
+INSERT INTO ZipCodeLocation (Zip_Code, Latitude, Longitude) VALUES
+    (1000, 55.6790, 12.5850),
+    (1050, 55.6790, 12.5850),
+    (1100, 55.6790, 12.5850),
+    (1150, 55.6790, 12.5850),
+    (1200, 55.6790, 12.5850),
+    (1250, 55.6790, 12.5850),
+    (1300, 55.6790, 12.5850),
+    (1350, 55.6790, 12.5850),
+    (1400, 55.6790, 12.5850),
+    (1450, 55.6790, 12.5850),
+    (1500, 55.6700, 12.5550),
+    (1550, 55.6700, 12.5550),
+    (1600, 55.6700, 12.5550),
+    (1650, 55.6700, 12.5550),
+    (1700, 55.6700, 12.5550),
+    (1750, 55.6700, 12.5550),
+    (1800, 55.6780, 12.5350),
+    (1850, 55.6780, 12.5350),
+    (1900, 55.6780, 12.5350),
+    (1950, 55.6780, 12.5350),
+    (2000, 55.6795, 12.5250),
+    (2100, 55.7060, 12.5770),
+    (2150, 55.7180, 12.5990),
+    (2200, 55.6970, 12.5450),
+    (2300, 55.6600, 12.6050),
+    (2400, 55.7070, 12.5250),
+    (2450, 55.6500, 12.5400),
+    (2500, 55.6620, 12.5050),
+    (2600, 55.6660, 12.4030),
+    (2605, 55.6480, 12.4180),
+    (2610, 55.6810, 12.4540),
+    (2620, 55.6570, 12.3630),
+    (2625, 55.6350, 12.3800),
+    (2630, 55.6520, 12.2990),
+    (2635, 55.6150, 12.3520),
+    (2640, 55.6470, 12.1950),
+    (2650, 55.6420, 12.4730),
+    (2660, 55.6230, 12.4180),
+    (2665, 55.6230, 12.3850),
+    (2670, 55.5830, 12.3000),
+    (2680, 55.5320, 12.2200),
+    (2690, 55.5650, 12.2330),
+    (2700, 55.7060, 12.4900),
+    (2720, 55.6870, 12.4910),
+    (2730, 55.7240, 12.4400),
+    (2740, 55.7200, 12.4010),
+    (2750, 55.7310, 12.3630),
+    (2760, 55.7480, 12.3200),
+    (2765, 55.7430, 12.3000),
+    (2770, 55.6300, 12.6400),
+    (2791, 55.5930, 12.6700),
+    (2800, 55.7704, 12.5038),
+    (2820, 55.7500, 12.5500),
+    (2830, 55.7960, 12.4730),
+    (2840, 55.8100, 12.4700),
+    (2850, 55.8160, 12.5330),
+    (2860, 55.7330, 12.5100),
+    (2870, 55.7350, 12.5300),
+    (2880, 55.7600, 12.4550),
+    (2900, 55.7310, 12.5700),
+    (2920, 55.7520, 12.5750),
+    (2930, 55.7700, 12.5900),
+    (2942, 55.8250, 12.5700),
+    (2950, 55.8530, 12.5650),
+    (2960, 55.8850, 12.5450),
+    (2970, 55.8810, 12.5010),
+    (2980, 55.9080, 12.5000),
+    (2990, 55.9330, 12.5070),
+    (3000, 56.0360, 12.6130),
+    (3050, 55.9620, 12.5330),
+    (3060, 55.9940, 12.5470),
+    (3070, 56.0050, 12.5900),
+    (3080, 56.0200, 12.4700),
+    (3100, 56.0900, 12.4570),
+    (3120, 56.1000, 12.3950),
+    (3140, 56.0760, 12.5400),
+    (3150, 56.0670, 12.5600),
+    (3200, 56.0220, 12.1970),
+    (3210, 56.0800, 12.1400),
+    (3220, 56.0550, 12.0800),
+    (3230, 56.0660, 12.2850),
+    (3250, 56.1220, 12.3100),
+    (3300, 55.9700, 12.0200),
+    (3400, 55.9270, 12.3000),
+    (3450, 55.8700, 12.3570),
+    (3460, 55.8470, 12.4270),
+    (3480, 55.9750, 12.4050),
+    (3490, 56.0000, 12.4900),
+    (3500, 55.7830, 12.3700),
+    (3520, 55.8080, 12.3600),
+    (3540, 55.8400, 12.2800),
+    (3550, 55.8500, 12.1800),
+    (3600, 55.8400, 12.0650),
+    (3700, 55.1000, 14.7000),
+    (4000, 55.6420, 12.0800),
+    (4100, 55.4430, 11.7900),
+    (4200, 55.4030, 11.3540),
+    (4300, 55.7170, 11.7100),
+    (4400, 55.6800, 11.0880),
+    (9000, 57.0480, 9.9190);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE ZipCodeLocation;
+
+ALTER TABLE "Order"
+    DROP COLUMN PrepTimeMinutes,
+    DROP COLUMN EstimatedDeliveryTime,
+    DROP COLUMN DeliveredAt;
+
+DROP TABLE DeliveryAgentLocation;
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keep the first feedback of orders that were reviewed more than once
+DELETE FROM Feedback f
+USING Feedback earlier
+WHERE f.OrderID = earlier.OrderID
+    AND f.ID > earlier.ID;
+
+ALTER TABLE Feedback
+    ADD CONSTRAINT feedback_order_unique UNIQUE (OrderID),
+    -- NOT VALID leaves existing out of range ratings alone but checks new ones
+    ADD CONSTRAINT feedback_deliveryagentrating_range CHECK (DeliveryAgentRating BETWEEN 1 AND 5) NOT VALID,
+    ADD CONSTRAINT feedback_restaurantrating_range CHECK (RestaurantRating BETWEEN 1 AND 5) NOT VALID,
+    ADD COLUMN CreatedAt timestamp DEFAULT NOW(),
+    ADD COLUMN UpdatedAt timestamp DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Feedback
+    DROP CONSTRAINT feedback_order_unique,
+    DROP CONSTRAINT feedback_deliveryagentrating_range,
+    DROP CONSTRAINT feedback_restaurantrating_range,
+    DROP COLUMN CreatedAt,
+    DROP COLUMN UpdatedAt;
+-- +goose StatementEnd
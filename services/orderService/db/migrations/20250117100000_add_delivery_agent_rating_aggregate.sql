@@ -0,0 +1,40 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE DeliveryAgent
+    ADD COLUMN RatingSum int NOT NULL DEFAULT 0,
+    ADD COLUMN RatingCount int NOT NULL DEFAULT 0;
+
+-- Start the aggregate from the feedback given so far
+UPDATE
+    DeliveryAgent d
+SET
+    RatingSum = r.total,
+    RatingCount = r.count,
+    Rating = ROUND(r.total::numeric / r.count, 1)
+FROM (
+    SELECT
+        o.DeliveryAgentID,
+        SUM(f.DeliveryAgentRating) AS total,
+        COUNT(*) AS count
+    FROM
+        Feedback f
+        JOIN "Order" o ON f.OrderID = o.ID
+    WHERE
+        f.DeliveryAgentRating IS NOT NULL
+        AND o.DeliveryAgentID IS NOT NULL
+    GROUP BY
+        o.DeliveryAgentID) r
+WHERE
+    d.ID = r.DeliveryAgentID;
+
+CREATE INDEX idx_order_deliveryagent ON "Order" (DeliveryAgentID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_order_deliveryagent;
+
+ALTER TABLE DeliveryAgent
+    DROP COLUMN RatingSum,
+    DROP COLUMN RatingCount;
+-- +goose StatementEnd
@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Delivery fee of the restaurant's delivery zone, charged on top of TotalAmount
+ALTER TABLE "Order"
+    ADD COLUMN DeliveryFee DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (DeliveryFee >= 0);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE "Order"
+    DROP COLUMN DeliveryFee;
+-- +goose StatementEnd
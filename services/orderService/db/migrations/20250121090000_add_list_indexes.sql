@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination walks these indexes instead of sorting whole tables
+CREATE INDEX idx_order_timestamp ON "Order" (Timestamp, ID);
+CREATE INDEX idx_order_customer ON "Order" (CustomerID);
+CREATE INDEX idx_order_restaurant ON "Order" (RestaurantID);
+CREATE INDEX idx_feedback_createdat ON Feedback (CreatedAt, ID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_feedback_createdat;
+DROP INDEX idx_order_restaurant;
+DROP INDEX idx_order_customer;
+DROP INDEX idx_order_timestamp;
+-- +goose StatementEnd
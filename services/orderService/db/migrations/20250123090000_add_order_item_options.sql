@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Options chosen for an item and the items of a combo, as priced by the restaurant
+ALTER TABLE OrderItem
+    ADD COLUMN MenuItemID int,
+    ADD COLUMN Options jsonb NOT NULL DEFAULT '[]',
+    ADD COLUMN Components jsonb NOT NULL DEFAULT '[]';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE OrderItem
+    DROP COLUMN MenuItemID,
+    DROP COLUMN Options,
+    DROP COLUMN Components;
+-- +goose StatementEnd
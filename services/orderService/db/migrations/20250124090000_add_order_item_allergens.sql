@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Allergens and dietary tags of the menu item when it was ordered, for traceability
+ALTER TABLE OrderItem
+    ADD COLUMN Allergens TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN DietaryTags TEXT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE OrderItem
+    DROP COLUMN Allergens,
+    DROP COLUMN DietaryTags;
+-- +goose StatementEnd
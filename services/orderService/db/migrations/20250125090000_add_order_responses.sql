@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- A restaurant's answer to an order. Orders left unanswered past the
+-- response timeout are rejected automatically.
+CREATE TABLE OrderResponse (
+    OrderID int PRIMARY KEY REFERENCES "Order" (ID) ON DELETE CASCADE,
+    Accepted boolean NOT NULL,
+    Reason text,
+    AutoRejected boolean NOT NULL DEFAULT FALSE,
+    RespondedAt timestamp DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_pending ON "Order" (Timestamp)
+WHERE
+    Status = 'Pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_order_pending;
+
+DROP TABLE OrderResponse;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The version of the menu item price that was charged, from the restaurant
+-- service's price history
+ALTER TABLE OrderItem
+    ADD COLUMN PriceVersionID INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE OrderItem
+    DROP COLUMN PriceVersionID;
+-- +goose StatementEnd
@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Discount codes, taking a percentage or a fixed amount off the items of an
+-- order. Promotions without a restaurant apply platform-wide, and missing
+-- limits and validity bounds do not restrict the promotion.
+CREATE TABLE Promotion (
+    ID serial PRIMARY KEY,
+    Code varchar(50) NOT NULL UNIQUE CHECK (Code = UPPER(Code)),
+    Description text,
+    DiscountType varchar(20) NOT NULL CHECK (DiscountType IN ('percentage', 'fixed')),
+    DiscountValue DECIMAL(10, 2) NOT NULL CHECK (DiscountValue > 0),
+    RestaurantID int,
+    MinimumOrder DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (MinimumOrder >= 0),
+    ValidFrom timestamp,
+    ValidTo timestamp,
+    MaxUses int CHECK (MaxUses > 0),
+    MaxUsesPerCustomer int CHECK (MaxUsesPerCustomer > 0),
+    FirstOrderOnly boolean NOT NULL DEFAULT FALSE,
+    Active boolean NOT NULL DEFAULT TRUE,
+    CreatedAt timestamp DEFAULT NOW(),
+    CHECK (DiscountType <> 'percentage' OR DiscountValue <= 100),
+    CHECK (ValidTo > ValidFrom)
+);
+
+-- The discount is taken off TotalAmount, so fees are computed on the
+-- discounted amount
+ALTER TABLE "Order"
+    ADD COLUMN DiscountAmount DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (DiscountAmount >= 0),
+    ADD COLUMN PromotionID int REFERENCES Promotion (ID);
+
+CREATE INDEX idx_order_promotion ON "Order" (PromotionID, CustomerID)
+WHERE
+    PromotionID IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_order_promotion;
+
+ALTER TABLE "Order"
+    DROP COLUMN PromotionID,
+    DROP COLUMN DiscountAmount;
+
+DROP TABLE Promotion;
+-- +goose StatementEnd
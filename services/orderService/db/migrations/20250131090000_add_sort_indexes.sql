@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Listings sort on these expressions, with the ID breaking ties, so the
+-- indexes have to be on the same expressions to be walked
+DROP INDEX idx_order_timestamp;
+CREATE INDEX idx_order_timestamp ON "Order" ((COALESCE(Timestamp, 'epoch')), ID);
+CREATE INDEX idx_order_total_amount ON "Order" (TotalAmount, ID);
+DROP INDEX idx_feedback_createdat;
+CREATE INDEX idx_feedback_createdat ON Feedback ((COALESCE(CreatedAt, 'epoch')), ID);
+CREATE INDEX idx_deliveryagent_rating ON DeliveryAgent ((COALESCE(Rating, 0)), ID);
+CREATE INDEX idx_deliveryagent_fullname ON DeliveryAgent ((COALESCE(FullName, '')), ID);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_deliveryagent_fullname;
+DROP INDEX idx_deliveryagent_rating;
+DROP INDEX idx_feedback_createdat;
+CREATE INDEX idx_feedback_createdat ON Feedback (CreatedAt, ID);
+DROP INDEX idx_order_total_amount;
+DROP INDEX idx_order_timestamp;
+CREATE INDEX idx_order_timestamp ON "Order" (Timestamp, ID);
+-- +goose StatementEnd
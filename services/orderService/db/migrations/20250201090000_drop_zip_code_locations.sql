@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Restaurants and customers are geocoded by the services that own them, and
+-- their coordinates looked up there
+DROP TABLE ZipCodeLocation;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TABLE ZipCodeLocation (
+    Zip_Code int PRIMARY KEY,
+    Latitude double precision NOT NULL,
+    Longitude double precision NOT NULL
+);
+-- +goose StatementEnd
@@ -1,7 +1,7 @@
 -- Create a new Order
 -- name: CreateOrder :one
-INSERT INTO "Order" (TotalAmount, VATAmount, Status, Timestamp, Comment, CustomerID, RestaurantID, DeliveryAgentID, PaymentID, BonusID, FeeID)
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+INSERT INTO "Order" (TotalAmount, VATAmount, Status, Timestamp, Comment, CustomerID, RestaurantID, DeliveryAgentID, PaymentID, BonusID, FeeID, DeliveryFee, DiscountAmount, PromotionID)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 RETURNING
     ID;
 
@@ -19,50 +19,50 @@ SELECT
     DeliveryAgentID,
     PaymentID,
     BonusID,
-    FeeID
+    FeeID,
+    PrepTimeMinutes,
+    EstimatedDeliveryTime,
+    DeliveredAt,
+    DeliveryFee,
+    DiscountAmount,
+    PromotionID
 FROM
     "Order"
 WHERE
     ID = $1;
 
--- Fetch all Orders
--- name: GetAllOrders :many
-SELECT
-    ID,
-    TotalAmount,
-    VATAmount,
-    Status,
-    Timestamp,
-    Comment,
-    CustomerID,
-    RestaurantID,
-    DeliveryAgentID,
-    PaymentID,
-    BonusID,
-    FeeID
-FROM
-    "Order"
-ORDER BY
-    Timestamp DESC;
+-- The pages of Orders, Feedbacks and DeliveryAgents are queried in
+-- domain/listings.go, which writes out the ORDER BY of the requested sort
+-- so it can be served from an index
 
--- Update an Order's status
--- name: UpdateOrderStatus :exec
+-- Update an Order's status, provided it still has the status it was read
+-- with, so a status change made in the meantime is not overwritten
+-- name: UpdateOrderStatus :execrows
 UPDATE
     "Order"
 SET
-    Status = $1
+    Status = @status,
+    DeliveredAt = CASE WHEN @status = 'Delivered' THEN
+        NOW()
+    ELSE
+        DeliveredAt
+    END
 WHERE
-    ID = $2;
+    ID = @id
+    AND Status = @from_status;
 
--- Update an Order's status and deliveryAgent
--- name: UpdateOrderStatusAndDeliveryAgent :exec
+-- Update an Order's status and deliveryAgent, provided it still has the
+-- status it was read with and no other deliveryAgent
+-- name: UpdateOrderStatusAndDeliveryAgent :execrows
 UPDATE
     "Order"
 SET
-    Status = $1,
-    DeliveryAgentID = $2
+    Status = @status,
+    DeliveryAgentID = @deliveryagentid
 WHERE
-    ID = $3;
+    ID = @id
+    AND Status = @from_status
+    AND (DeliveryAgentID IS NULL OR DeliveryAgentID = @deliveryagentid);
 
 -- Delete an Order
 -- name: DeleteOrder :exec
@@ -71,8 +71,8 @@ WHERE ID = $1;
 
 -- Create a new Order Item
 -- name: CreateOrderItem :one
-INSERT INTO OrderItem (OrderID, Name, Price, Quantity)
-    VALUES ($1, $2, $3, $4)
+INSERT INTO OrderItem (OrderID, Name, Price, Quantity, MenuItemID, Options, Components, Allergens, DietaryTags, PriceVersionID)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING
     ID;
 
@@ -83,7 +83,13 @@ SELECT
     OrderID,
     Name,
     Price,
-    Quantity
+    Quantity,
+    MenuItemID,
+    Options,
+    Components,
+    Allergens,
+    DietaryTags,
+    PriceVersionID
 FROM
     OrderItem
 WHERE
@@ -150,15 +156,6 @@ FROM
 WHERE
     ID = $1;
 
--- Fetch all Feedbacks
--- name: GetAllFeedbacks :many
-SELECT
-    *
-FROM
-    Feedback
-ORDER BY
-    ID DESC;
-
 -- Create Feedback
 -- name: CreateFeedback :one
 INSERT INTO Feedback (OrderID, CustomerID, DeliveryAgentRating, RestaurantRating, Comment)
@@ -184,14 +181,33 @@ FROM
 WHERE
     OrderID = $1;
 
--- Fetch all DeliveryAgents
--- name: GetAllDeliveryAgents :many
+-- Update the Feedback of an Order
+-- name: UpdateFeedback :exec
+UPDATE
+    Feedback
+SET
+    DeliveryAgentRating = $1,
+    RestaurantRating = $2,
+    Comment = $3,
+    UpdatedAt = NOW()
+WHERE
+    OrderID = $4;
+
+-- Fetch the restaurant ratings of all Feedbacks, used to backfill restaurant ratings
+-- name: GetRestaurantRatingsFromFeedbacks :many
 SELECT
-    *
+    f.ID,
+    f.OrderID,
+    o.RestaurantID,
+    f.RestaurantRating
 FROM
-    DeliveryAgent
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID
+WHERE
+    f.RestaurantRating IS NOT NULL
+    AND o.RestaurantID IS NOT NULL
 ORDER BY
-    ID DESC;
+    f.ID;
 
 -- Create DeliveryAgent
 -- name: CreateDeliveryAgent :one
@@ -227,28 +243,52 @@ SET
 WHERE
     ID = $2;
 
--- Get all Feedbacks for a Delivery Agent
--- name: GetAllFeedbacksFromDeliveryAgentByOrderId :many
+-- Add ratings to a Delivery Agent's rating aggregate. A changed rating adds the
+-- difference with a count of 0.
+-- name: AddDeliveryAgentRating :exec
+UPDATE
+    DeliveryAgent
+SET
+    RatingSum = RatingSum + $1,
+    RatingCount = RatingCount + $2,
+    Rating = ROUND((RatingSum + $1)::numeric / NULLIF(RatingCount + $2, 0), 1)
+WHERE
+    ID = $3;
+
+-- Count a Delivery Agent's ratings per star
+-- name: GetDeliveryAgentRatingDistribution :many
+SELECT
+    f.DeliveryAgentRating::int AS rating,
+    COUNT(*)::int AS count
+FROM
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID
+WHERE
+    o.DeliveryAgentID = $1
+    AND f.DeliveryAgentRating IS NOT NULL
+GROUP BY
+    f.DeliveryAgentRating
+ORDER BY
+    f.DeliveryAgentRating;
+
+-- Fetch the latest comments on a Delivery Agent's deliveries
+-- name: GetRecentDeliveryAgentComments :many
 SELECT
-    f.ID,
     f.OrderID,
-    f.CustomerID,
     f.DeliveryAgentRating,
-    f.RestaurantRating,
     f.Comment,
-    o.DeliveryAgentID
+    f.CreatedAt
 FROM
     Feedback f
     JOIN "Order" o ON f.OrderID = o.ID
 WHERE
-    o.DeliveryAgentID = (
-        SELECT
-            DeliveryAgentID
-        FROM
-            "Order"
-        WHERE
-            OrderID = $1
-        LIMIT 1);
+    o.DeliveryAgentID = $1
+    AND f.Comment IS NOT NULL
+    AND f.Comment <> ''
+ORDER BY
+    f.CreatedAt DESC,
+    f.ID DESC
+LIMIT $2;
 
 -- Update an Order's bonus
 -- name: UpdateOrderBonus :exec
@@ -260,3 +300,191 @@ WHERE
     ID = $2;
 
 
+-- Record an Order Event
+-- name: CreateOrderEvent :one
+INSERT INTO OrderEvent (OrderID, Type, Payload)
+    VALUES ($1, $2, $3)
+RETURNING
+    ID,
+    OrderID,
+    Type,
+    Payload,
+    CreatedAt;
+
+-- Fetch the Order Events recorded after a given event ID
+-- name: GetOrderEventsAfter :many
+SELECT
+    ID,
+    OrderID,
+    Type,
+    Payload,
+    CreatedAt
+FROM
+    OrderEvent
+WHERE
+    OrderID = $1
+    AND ID > $2
+ORDER BY
+    ID ASC
+LIMIT $3;
+
+-- Update an Order's estimated delivery time
+-- name: UpdateOrderEstimatedDeliveryTime :exec
+UPDATE
+    "Order"
+SET
+    EstimatedDeliveryTime = $1
+WHERE
+    ID = $2;
+
+-- Average seconds from placing to delivering a restaurant's orders the last 30 days, 0 if none
+-- name: GetAverageDeliveryDurationByRestaurant :one
+SELECT
+    COALESCE(AVG(EXTRACT(EPOCH FROM (DeliveredAt - Timestamp))), 0)::float8 AS avg_seconds
+FROM
+    "Order"
+WHERE
+    RestaurantID = $1
+    AND DeliveredAt IS NOT NULL
+    AND DeliveredAt > NOW() - INTERVAL '30 days';
+
+-- Record a Delivery Agent location ping
+-- name: CreateDeliveryAgentLocation :one
+INSERT INTO DeliveryAgentLocation (DeliveryAgentID, OrderID, Latitude, Longitude)
+    VALUES ($1, $2, $3, $4)
+RETURNING
+    ID,
+    DeliveryAgentID,
+    OrderID,
+    Latitude,
+    Longitude,
+    RecordedAt;
+
+-- Fetch the latest location of a Delivery Agent
+-- name: GetLatestDeliveryAgentLocation :one
+SELECT
+    ID,
+    DeliveryAgentID,
+    OrderID,
+    Latitude,
+    Longitude,
+    RecordedAt
+FROM
+    DeliveryAgentLocation
+WHERE
+    DeliveryAgentID = $1
+ORDER BY
+    RecordedAt DESC,
+    ID DESC
+LIMIT 1;
+
+-- Delete location pings recorded before the retention cutoff
+-- name: DeleteDeliveryAgentLocationsBefore :execrows
+DELETE FROM DeliveryAgentLocation
+WHERE RecordedAt < $1;
+
+-- Answer a pending Order of the restaurant. Orders that are no longer
+-- pending are left alone, so an order is only answered once.
+-- name: RespondToOrder :execrows
+UPDATE
+    "Order"
+SET
+    Status = $1,
+    PrepTimeMinutes = COALESCE($2, PrepTimeMinutes)
+WHERE
+    ID = $3
+    AND RestaurantID = $4
+    AND Status = 'Pending';
+
+-- name: CreateOrderResponse :exec
+INSERT INTO OrderResponse (OrderID, Accepted, Reason, AutoRejected)
+    VALUES ($1, $2, $3, $4);
+
+-- name: GetOrderResponse :one
+SELECT
+    OrderID,
+    Accepted,
+    Reason,
+    AutoRejected,
+    RespondedAt
+FROM
+    OrderResponse
+WHERE
+    OrderID = $1;
+
+-- Pending Orders placed before the deadline, oldest first
+-- name: GetUnansweredOrders :many
+SELECT
+    ID,
+    RestaurantID
+FROM
+    "Order"
+WHERE
+    Status = 'Pending'
+    AND Timestamp < $1
+ORDER BY
+    Timestamp
+LIMIT $2;
+
+-- name: CreatePromotion :one
+INSERT INTO Promotion (Code, Description, DiscountType, DiscountValue, RestaurantID, MinimumOrder, ValidFrom, ValidTo, MaxUses, MaxUsesPerCustomer, FirstOrderOnly)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING
+    *;
+
+-- name: GetAllPromotions :many
+SELECT
+    *
+FROM
+    Promotion
+ORDER BY
+    ID;
+
+-- name: GetPromotionByCode :one
+SELECT
+    *
+FROM
+    Promotion
+WHERE
+    Code = $1;
+
+-- Locks the Promotion until the end of the transaction, so concurrent orders
+-- cannot exceed its usage limits
+-- name: LockPromotionByCode :one
+SELECT
+    *
+FROM
+    Promotion
+WHERE
+    Code = $1
+FOR UPDATE;
+
+-- name: DeactivatePromotion :execrows
+UPDATE
+    Promotion
+SET
+    Active = FALSE
+WHERE
+    ID = $1;
+
+-- Orders placed with the Promotion, in total and by the customer. Rejected
+-- and cancelled orders do not use up the Promotion.
+-- name: CountPromotionUses :one
+SELECT
+    COUNT(*) AS total,
+    COUNT(*) FILTER (WHERE CustomerID = $2) AS by_customer
+FROM
+    "Order"
+WHERE
+    PromotionID = $1
+    AND Status NOT IN ('Rejected', 'Cancelled');
+
+-- Orders of the customer, leaving out rejected and cancelled ones
+-- name: CountCustomerOrders :one
+SELECT
+    COUNT(*)
+FROM
+    "Order"
+WHERE
+    CustomerID = $1
+    AND Status NOT IN ('Rejected', 'Cancelled');
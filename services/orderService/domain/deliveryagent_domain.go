@@ -3,26 +3,80 @@ package domain
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/rasm445f/soft-exam-2/db/generated"
 )
 
 type DeliveryAgentDomain struct {
-	repo *generated.Queries
+	repo   *generated.Queries
+	db     generated.DBTX
+	events *OrderEventHub
 }
 
-// NewDeliveryAgentDomain initializes the domain layer
-func NewDeliveryAgentDomain(repo *generated.Queries) *DeliveryAgentDomain {
-	return &DeliveryAgentDomain{repo: repo}
+// NewDeliveryAgentDomain initializes the domain layer. Delivery agents are
+// listed on db.
+func NewDeliveryAgentDomain(repo *generated.Queries, db generated.DBTX, events *OrderEventHub) *DeliveryAgentDomain {
+	return &DeliveryAgentDomain{repo: repo, db: db, events: events}
 }
 
-func (d *DeliveryAgentDomain) GetAllDeliveryAgentsDomain(ctx context.Context) ([]generated.Deliveryagent, error) {
-	deliveryAgents, err := d.repo.GetAllDeliveryAgents(ctx)
+// DeliveryAgentFilter narrows the listed delivery agents. Nil fields do not filter.
+type DeliveryAgentFilter struct {
+	Availability *bool
+	MinRating    *float64
+}
+
+// DeliveryAgentSortFields are the fields delivery agents can be sorted on,
+// newest first by default. Agents without a rating sort as rated 0.
+var DeliveryAgentSortFields = []string{"id", "rating", "name"}
+
+func (d *DeliveryAgentDomain) GetAllDeliveryAgentsDomain(ctx context.Context, filter DeliveryAgentFilter, list ListParams) (*Page[generated.Deliveryagent], error) {
+	sortField, descending, err := list.sortField(DeliveryAgentSortFields, "-id")
+	if err != nil {
+		return nil, err
+	}
+	after, err := list.decodeCursor(list.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	params := deliveryAgentListParams{
+		Availability: filter.Availability,
+		MinRating:    filter.MinRating,
+		SortField:    sortField,
+		Descending:   descending,
+		PageLimit:    int32(list.limit() + 1),
+	}
+	if after != nil {
+		params.CursorID = &after.ID
+		switch sortField {
+		case "rating":
+			params.CursorRating, err = parseCursorNumber(after.Value)
+		case "name":
+			params.CursorName = &after.Value
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deliveryAgents, err := listDeliveryAgents(ctx, d.db, params)
 	if err != nil {
 		return nil, errors.New("failed to fetch deliveryAgents")
 	}
 
-	return deliveryAgents, nil
+	return newPage(deliveryAgents, list.limit(), func(last generated.Deliveryagent) cursor {
+		next := cursor{Sort: list.Sort, ID: last.ID}
+		switch sortField {
+		case "rating":
+			next.Value = formatCursorNumber(last.Rating)
+		case "name":
+			if last.Fullname != nil {
+				next.Value = *last.Fullname
+			}
+		}
+		return next
+	}), nil
 }
 
 func (d *DeliveryAgentDomain) GetDeliveryAgentByIdDomain(ctx context.Context, deliveryAgentId int32) (*generated.Deliveryagent, error) {
@@ -42,39 +96,63 @@ func (d *DeliveryAgentDomain) CreateDeliveryAgentDomain(ctx context.Context, del
 	return deliveryAgentId, nil
 }
 
-// func (d *DeliveryAgentDomain) UpdateDeliveryAgentRatingDomain(ctx context.Context, deliveryAgentId int32) error {
-// 	// Fetch all feedbacks for all the delivery agent
-// 	feedbacks, err := d.repo.GetAllFeedbacksByDeliveryAgentId(ctx, &deliveryAgentId)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to fetch feedbacks: %w", err)
-// 	}
-
-// 	// Calculate the average rating
-// 	var totalRating float64
-// 	var count int
-// 	for _, feedback := range feedbacks {
-// 		if feedback.Deliveryagentrating != nil {
-// 			totalRating += float64(*feedback.Deliveryagentrating)
-// 			count++
-// 		}
-// 	}
-
-// 	if count == 0 {
-// 		return fmt.Errorf("no ratings found for delivery agent %d", deliveryAgentId)
-// 	}
-
-// 	avgRating := totalRating / float64(count)
-
-// 	UpdateParams := generated.UpdateDeliveryAgentRatingParams {
-// 		Rating: &avgRating,
-// 		ID: deliveryAgentId,
-// 	}
-
-// 	// Update the delivery agent's rating
-// 	err = d.repo.UpdateDeliveryAgentRating(ctx, UpdateParams)
-// 	if err != nil {
-// 		return fmt.Errorf("failed to update delivery agent rating: %w", err)
-// 	}
-
-// 	return nil
-// }
+const recentCommentsLimit = 10
+
+// AgentRatings summarizes the ratings a delivery agent has received
+type AgentRatings struct {
+	DeliveryAgentID int32    `json:"delivery_agent_id"`
+	Rating          *float64 `json:"rating"`
+	RatingCount     int32    `json:"rating_count"`
+	// Distribution maps each star, 1 to 5, to the number of ratings with it
+	Distribution   map[int32]int32 `json:"distribution"`
+	RecentComments []AgentComment  `json:"recent_comments"`
+}
+
+// AgentComment is a feedback comment on one of the agent's deliveries
+type AgentComment struct {
+	OrderID   int32      `json:"order_id"`
+	Rating    *int32     `json:"rating"`
+	Comment   string     `json:"comment"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+func (d *DeliveryAgentDomain) GetDeliveryAgentRatingsDomain(ctx context.Context, deliveryAgentId int32) (*AgentRatings, error) {
+	deliveryAgent, err := d.repo.GetDeliveryAgentById(ctx, deliveryAgentId)
+	if err != nil {
+		return nil, errors.New("delivery agent not found")
+	}
+
+	distribution, err := d.repo.GetDeliveryAgentRatingDistribution(ctx, &deliveryAgentId)
+	if err != nil {
+		return nil, errors.New("failed to fetch rating distribution: " + err.Error())
+	}
+
+	comments, err := d.repo.GetRecentDeliveryAgentComments(ctx, generated.GetRecentDeliveryAgentCommentsParams{
+		Deliveryagentid: &deliveryAgentId,
+		Limit:           recentCommentsLimit,
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch comments: " + err.Error())
+	}
+
+	ratings := &AgentRatings{
+		DeliveryAgentID: deliveryAgent.ID,
+		Rating:          deliveryAgent.Rating,
+		RatingCount:     deliveryAgent.Ratingcount,
+		Distribution:    map[int32]int32{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		RecentComments:  []AgentComment{},
+	}
+	for _, row := range distribution {
+		ratings.Distribution[row.Rating] = row.Count
+	}
+	for _, row := range comments {
+		ratings.RecentComments = append(ratings.RecentComments, AgentComment{
+			OrderID:   row.Orderid,
+			Rating:    row.Deliveryagentrating,
+			Comment:   *row.Comment,
+			CreatedAt: row.Createdat,
+		})
+	}
+
+	return ratings, nil
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+func TestGetDeliveryAgentRatingsDomain(t *testing.T) {
+	// Arrange
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create pgxmock pool: %v", err)
+	}
+	defer CloseMocks(mock)
+	domain := NewDeliveryAgentDomain(generated.New(mock), mock, nil)
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM\s+DeliveryAgent\s+WHERE`).WithArgs(int32(3)).WillReturnRows(
+		pgxmock.NewRows([]string{"id", "fullname", "contactinfo", "availability", "rating", "ratingsum", "ratingcount"}).
+			AddRow(int32(3), stringPtr("Anna"), nil, nil, float64Ptr(4.3), int32(13), int32(3)))
+	mock.ExpectQuery(`GROUP BY`).WithArgs(int32Ptr(3)).WillReturnRows(
+		pgxmock.NewRows([]string{"rating", "count"}).AddRow(int32(4), int32(2)).AddRow(int32(5), int32(1)))
+	mock.ExpectQuery(`f.Comment IS NOT NULL`).WithArgs(int32Ptr(3), int32(recentCommentsLimit)).WillReturnRows(
+		pgxmock.NewRows([]string{"orderid", "deliveryagentrating", "comment", "createdat"}).
+			AddRow(int32(8), int32Ptr(5), stringPtr("Fast and friendly"), &now))
+
+	// Act
+	ratings, err := domain.GetDeliveryAgentRatingsDomain(context.Background(), 3)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ratings.RatingCount != 3 || *ratings.Rating != 4.3 {
+		t.Errorf("got rating %v from %d ratings, want 4.3 from 3", *ratings.Rating, ratings.RatingCount)
+	}
+	want := map[int32]int32{1: 0, 2: 0, 3: 0, 4: 2, 5: 1}
+	for star, count := range want {
+		if ratings.Distribution[star] != count {
+			t.Errorf("got %d ratings with %d stars, want %d", ratings.Distribution[star], star, count)
+		}
+	}
+	if len(ratings.RecentComments) != 1 || ratings.RecentComments[0].Comment != "Fast and friendly" {
+		t.Errorf("got comments %+v", ratings.RecentComments)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// LocationParams is a GPS ping sent by a delivery agent
+type LocationParams struct {
+	Latitude  *float64 `json:"latitude" example:"55.6761"`
+	Longitude *float64 `json:"longitude" example:"12.5683"`
+	OrderID   *int32   `json:"order_id"`
+}
+
+// AgentLocation is the reported position of a delivery agent
+type AgentLocation struct {
+	DeliveryAgentID int32      `json:"delivery_agent_id"`
+	OrderID         *int32     `json:"order_id"`
+	Latitude        float64    `json:"latitude"`
+	Longitude       float64    `json:"longitude"`
+	RecordedAt      *time.Time `json:"recorded_at"`
+}
+
+func toAgentLocation(row generated.Deliveryagentlocation) *AgentLocation {
+	return &AgentLocation{
+		DeliveryAgentID: row.Deliveryagentid,
+		OrderID:         row.Orderid,
+		Latitude:        row.Latitude,
+		Longitude:       row.Longitude,
+		RecordedAt:      row.Recordedat,
+	}
+}
+
+// RecordLocationDomain stores a location ping. A ping for an order is also
+// streamed live to the order's subscribers.
+func (d *DeliveryAgentDomain) RecordLocationDomain(ctx context.Context, deliveryAgentId int32, params LocationParams) (*AgentLocation, error) {
+	if params.Latitude == nil || params.Longitude == nil ||
+		*params.Latitude < -90 || *params.Latitude > 90 ||
+		*params.Longitude < -180 || *params.Longitude > 180 {
+		return nil, errors.New("invalid coordinates")
+	}
+
+	_, err := d.repo.GetDeliveryAgentById(ctx, deliveryAgentId)
+	if err != nil {
+		return nil, errors.New("delivery agent not found")
+	}
+
+	if params.OrderID != nil {
+		order, err := d.repo.GetOrderById(ctx, *params.OrderID)
+		if err != nil {
+			return nil, errors.New("order not found")
+		}
+		if order.Deliveryagentid == nil || *order.Deliveryagentid != deliveryAgentId {
+			return nil, errors.New("order is not assigned to the delivery agent")
+		}
+	}
+
+	row, err := d.repo.CreateDeliveryAgentLocation(ctx, generated.CreateDeliveryAgentLocationParams{
+		Deliveryagentid: deliveryAgentId,
+		Orderid:         params.OrderID,
+		Latitude:        *params.Latitude,
+		Longitude:       *params.Longitude,
+	})
+	if err != nil {
+		return nil, errors.New("failed to record location: " + err.Error())
+	}
+
+	location := toAgentLocation(row)
+	if params.OrderID != nil && d.events != nil {
+		d.publishLocation(*params.OrderID, location)
+	}
+
+	return location, nil
+}
+
+func (d *DeliveryAgentDomain) publishLocation(orderId int32, location *AgentLocation) {
+	data, err := json.Marshal(location)
+	if err != nil {
+		log.Printf("Failed to marshal agent location: %v", err)
+		return
+	}
+
+	err = d.events.Publish(OrderEvent{
+		OrderID:   orderId,
+		Type:      broker.AgentLocationUpdated,
+		Data:      data,
+		CreatedAt: location.RecordedAt,
+	})
+	if err != nil {
+		log.Printf("Failed to publish agent location for order %d: %v", orderId, err)
+	}
+}
+
+func (d *DeliveryAgentDomain) GetLatestLocationDomain(ctx context.Context, deliveryAgentId int32) (*AgentLocation, error) {
+	row, err := d.repo.GetLatestDeliveryAgentLocation(ctx, deliveryAgentId)
+	if err != nil {
+		return nil, errors.New("no location recorded")
+	}
+
+	return toAgentLocation(row), nil
+}
+
+// PruneLocationsDomain deletes the location pings older than the retention period
+func (d *DeliveryAgentDomain) PruneLocationsDomain(ctx context.Context, retention time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-retention)
+	deleted, err := d.repo.DeleteDeliveryAgentLocationsBefore(ctx, &cutoff)
+	if err != nil {
+		return 0, errors.New("failed to prune locations: " + err.Error())
+	}
+
+	return deleted, nil
+}
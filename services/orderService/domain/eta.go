@@ -0,0 +1,232 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	defaultPrepTime   = 15 * time.Minute
+	handoverTime      = 5 * time.Minute
+	courierSpeedKmh   = 18.0
+	defaultDistanceKm = 3.0
+	// share of the estimate taken from the restaurant's delivered orders
+	historicalWeight = 0.5
+	earthRadiusKm    = 6371.0
+	// ETAs waiting to be estimated before status changes skip theirs
+	etaQueueSize = 256
+	// time one estimate may take, lookups in other services included
+	etaTimeout = 10 * time.Second
+)
+
+// Location is a point on the map, in degrees
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Locator finds where restaurants and customers are. They are geocoded by the
+// services that own them, and nil is returned where they are not on the map.
+type Locator interface {
+	RestaurantLocation(ctx context.Context, restaurantId int32) (*Location, error)
+	CustomerLocation(ctx context.Context, customerId int32) (*Location, error)
+}
+
+// ETAInput holds what is known about an order when estimating its delivery time
+type ETAInput struct {
+	Status   string
+	PlacedAt time.Time
+	Now      time.Time
+	// PrepTime is the restaurant's preparation time, zero means unknown
+	PrepTime time.Duration
+	// DistanceKm is the distance from restaurant to customer, zero means unknown
+	DistanceKm float64
+	// RemainingKm is the distance from the delivery agent to the customer,
+	// nil until the agent has reported a location
+	RemainingKm *float64
+	// HistoricalDuration is the restaurant's average time from placing to
+	// delivering an order, zero if it has no delivered orders
+	HistoricalDuration time.Duration
+}
+
+// EstimateDeliveryTime combines prep time, travel time and the restaurant's
+// historical delivery durations. Once the order is on its way only the
+// remaining travel counts. The estimate is never in the past.
+func EstimateDeliveryTime(input ETAInput) time.Time {
+	distance := input.DistanceKm
+	if distance <= 0 {
+		distance = defaultDistanceKm
+	}
+
+	var eta time.Time
+	if input.Status == "On its way" {
+		remaining := distance
+		if input.RemainingKm != nil {
+			remaining = *input.RemainingKm
+		}
+		eta = input.Now.Add(travelTime(remaining))
+	} else {
+		prep := input.PrepTime
+		if prep <= 0 {
+			prep = defaultPrepTime
+		}
+		duration := prep + handoverTime + travelTime(distance)
+		if input.HistoricalDuration > 0 {
+			duration = time.Duration(float64(duration)*(1-historicalWeight) + float64(input.HistoricalDuration)*historicalWeight)
+		}
+		eta = input.PlacedAt.Add(duration)
+	}
+
+	if eta.Before(input.Now) {
+		return input.Now
+	}
+	return eta
+}
+
+func travelTime(distanceKm float64) time.Duration {
+	return time.Duration(distanceKm / courierSpeedKmh * float64(time.Hour))
+}
+
+// HaversineKm returns the great-circle distance between two coordinates
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return earthRadiusKm * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// requestETA queues the order's delivery time to be estimated, so status
+// changes do not wait on lookups in other services. The estimate is published
+// as an OrderETAUpdated event once stored.
+func (d *OrderDomain) requestETA(orderId int32) {
+	select {
+	case d.etaRequests <- orderId:
+	default:
+		log.Printf("Skipped ETA of order %d, too many waiting", orderId)
+	}
+}
+
+// lastETA is the order's last estimate while it is still to be delivered.
+// Status changes carry it until their new estimate follows.
+func lastETA(order generated.Order, status string) *time.Time {
+	if status == StatusDelivered || status == StatusRejected || status == StatusCancelled {
+		return nil
+	}
+	return order.Estimateddeliverytime
+}
+
+// EstimateDeliveryTimesDomain estimates the delivery times queued by status
+// changes, one at a time, until ctx is done
+func (d *OrderDomain) EstimateDeliveryTimesDomain(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case orderId := <-d.etaRequests:
+			estimateCtx, cancel := context.WithTimeout(ctx, etaTimeout)
+			d.refreshEstimatedDeliveryTime(estimateCtx, orderId)
+			cancel()
+		}
+	}
+}
+
+// refreshEstimatedDeliveryTime recomputes, stores and publishes the ETA of an
+// order. Lookups that fail fall back to defaults, so an order always gets an
+// estimate. Orders that are delivered, rejected or cancelled keep their last
+// estimate.
+func (d *OrderDomain) refreshEstimatedDeliveryTime(ctx context.Context, orderId int32) {
+	order, err := d.repo.GetOrderById(ctx, orderId)
+	if err != nil {
+		log.Printf("Failed to fetch order %d for ETA: %v", orderId, err)
+		return
+	}
+	if order.Status == StatusDelivered || order.Status == StatusRejected || order.Status == StatusCancelled {
+		return
+	}
+
+	now := time.Now()
+	input := ETAInput{
+		Status:   order.Status,
+		PlacedAt: now,
+		Now:      now,
+	}
+	if order.Timestamp != nil {
+		input.PlacedAt = *order.Timestamp
+	}
+	if order.Preptimeminutes != nil {
+		input.PrepTime = time.Duration(*order.Preptimeminutes) * time.Minute
+	}
+
+	restaurant, customer := d.orderLocations(ctx, order)
+	if restaurant != nil && customer != nil {
+		input.DistanceKm = HaversineKm(restaurant.Latitude, restaurant.Longitude, customer.Latitude, customer.Longitude)
+	}
+
+	if order.Status == "On its way" && order.Deliveryagentid != nil && customer != nil {
+		location, err := d.repo.GetLatestDeliveryAgentLocation(ctx, *order.Deliveryagentid)
+		if err == nil {
+			remaining := HaversineKm(location.Latitude, location.Longitude, customer.Latitude, customer.Longitude)
+			input.RemainingKm = &remaining
+		}
+	}
+
+	if order.Restaurantid != nil {
+		avgSeconds, err := d.repo.GetAverageDeliveryDurationByRestaurant(ctx, order.Restaurantid)
+		if err != nil {
+			log.Printf("Failed to fetch delivery history for order %d: %v", orderId, err)
+		}
+		input.HistoricalDuration = time.Duration(avgSeconds * float64(time.Second))
+	}
+
+	eta := EstimateDeliveryTime(input)
+	err = d.repo.UpdateOrderEstimatedDeliveryTime(ctx, generated.UpdateOrderEstimatedDeliveryTimeParams{
+		Estimateddeliverytime: &eta,
+		ID:                    orderId,
+	})
+	if err != nil {
+		log.Printf("Failed to store ETA for order %d: %v", orderId, err)
+		return
+	}
+
+	d.recordOrderEvent(ctx, orderId, broker.OrderETAUpdated, OrderStatusChange{
+		OrderID:               orderId,
+		Status:                order.Status,
+		CustomerID:            order.Customerid,
+		RestaurantID:          order.Restaurantid,
+		DeliveryAgentID:       order.Deliveryagentid,
+		EstimatedDeliveryTime: &eta,
+	})
+}
+
+// orderLocations returns the locations of the order's restaurant and
+// customer, nil where unknown
+func (d *OrderDomain) orderLocations(ctx context.Context, order generated.Order) (restaurant, customer *Location) {
+	if d.locator == nil {
+		return nil, nil
+	}
+
+	var err error
+	if order.Restaurantid != nil {
+		restaurant, err = d.locator.RestaurantLocation(ctx, *order.Restaurantid)
+		if err != nil {
+			log.Printf("Failed to look up location of restaurant %d: %v", *order.Restaurantid, err)
+		}
+	}
+
+	if order.Customerid != nil {
+		customer, err = d.locator.CustomerLocation(ctx, *order.Customerid)
+		if err != nil {
+			log.Printf("Failed to look up location of customer %d: %v", *order.Customerid, err)
+		}
+	}
+
+	return restaurant, customer
+}
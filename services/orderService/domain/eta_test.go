@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestEstimateDeliveryTime(t *testing.T) {
+	placedAt := time.Date(2025, 1, 14, 18, 0, 0, 0, time.UTC)
+	remaining := 1.5
+
+	tests := []struct {
+		name  string
+		input ETAInput
+		want  time.Time
+	}{
+		{
+			name:  "Pending order without history uses defaults",
+			input: ETAInput{Status: "Pending", PlacedAt: placedAt, Now: placedAt},
+			// 15m prep + 5m handover + 3km at 18km/h
+			want: placedAt.Add(30 * time.Minute),
+		},
+		{
+			name: "Pending order uses prep time and distance",
+			input: ETAInput{
+				Status:     "Pending",
+				PlacedAt:   placedAt,
+				Now:        placedAt,
+				PrepTime:   25 * time.Minute,
+				DistanceKm: 6,
+			},
+			want: placedAt.Add(50 * time.Minute),
+		},
+		{
+			name: "Pending order is blended with history",
+			input: ETAInput{
+				Status:             "Pending",
+				PlacedAt:           placedAt,
+				Now:                placedAt,
+				HistoricalDuration: 50 * time.Minute,
+			},
+			want: placedAt.Add(40 * time.Minute),
+		},
+		{
+			name: "On its way uses distance left from the agent",
+			input: ETAInput{
+				Status:      "On its way",
+				PlacedAt:    placedAt,
+				Now:         placedAt.Add(20 * time.Minute),
+				DistanceKm:  6,
+				RemainingKm: &remaining,
+			},
+			want: placedAt.Add(25 * time.Minute),
+		},
+		{
+			name:  "Estimate is never in the past",
+			input: ETAInput{Status: "Pending", PlacedAt: placedAt, Now: placedAt.Add(2 * time.Hour)},
+			want:  placedAt.Add(2 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := EstimateDeliveryTime(tt.input)
+
+			// Assert
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHaversineKm(t *testing.T) {
+	// Copenhagen Central Station to Aarhus Central Station
+	got := HaversineKm(55.6726, 12.5647, 56.1505, 10.2045)
+
+	if math.Abs(got-156) > 2 {
+		t.Errorf("got %.1f km, want about 156 km", got)
+	}
+	if HaversineKm(55.6726, 12.5647, 55.6726, 12.5647) != 0 {
+		t.Error("expected zero distance for the same point")
+	}
+}
+
+// stubLocator places every restaurant and customer, recording who was looked up
+type stubLocator struct {
+	restaurantId, customerId int32
+}
+
+func (s *stubLocator) RestaurantLocation(ctx context.Context, restaurantId int32) (*Location, error) {
+	s.restaurantId = restaurantId
+	return &Location{Latitude: 55.6726, Longitude: 12.5647}, nil
+}
+
+func (s *stubLocator) CustomerLocation(ctx context.Context, customerId int32) (*Location, error) {
+	s.customerId = customerId
+	return &Location{Latitude: 55.7704, Longitude: 12.5038}, nil
+}
+
+func TestRefreshEstimatedDeliveryTime(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	locator := &stubLocator{}
+	domain.locator = locator
+	expectOrder(mock, StatusAccepted)
+	mock.ExpectQuery(`AS avg_seconds`).
+		WithArgs(int32Ptr(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"avg_seconds"}).AddRow(0.0))
+	mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+EstimatedDeliveryTime`).
+		WithArgs(pgxmock.AnyArg(), int32(5)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	// Act
+	domain.refreshEstimatedDeliveryTime(context.Background(), 5)
+
+	// Assert
+	if locator.restaurantId != 2 || locator.customerId != 1 {
+		t.Errorf("got restaurant %d and customer %d located, want 2 and 1", locator.restaurantId, locator.customerId)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
@@ -4,89 +4,354 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 )
 
+// Feedback can be edited for this long after it was given
+const feedbackEditWindow = 24 * time.Hour
+
+// uniqueViolation is the postgres error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+// Feedback errors, distinct so handlers can map them to status codes
+var (
+	ErrInvalidRating          = errors.New("ratings must be between 1 and 5 and at least one must be given")
+	ErrOrderNotFound          = errors.New("order not found")
+	ErrFeedbackNotFound       = errors.New("feedback not found")
+	ErrFeedbackForbidden      = errors.New("feedback can only be given by the ordering customer")
+	ErrOrderNotDelivered      = errors.New("feedback can only be given on delivered orders")
+	ErrFeedbackExists         = errors.New("feedback already given for this order")
+	ErrFeedbackEditWindowOver = errors.New("feedback can no longer be edited")
+)
+
+// UpdateFeedbackParams changes a feedback. Nil fields are left unchanged.
+type UpdateFeedbackParams struct {
+	Customerid          int32   `json:"customerid"`
+	Deliveryagentrating *int32  `json:"deliveryagentrating"`
+	Restaurantrating    *int32  `json:"restaurantrating"`
+	Comment             *string `json:"comment"`
+}
+
+// FeedbackEventsExchange is the fanout exchange feedback events are published on
+const FeedbackEventsExchange = "feedback_events"
+
+// EventPublisher publishes an event on an exchange, e.g. broker.PublishFanout
+type EventPublisher func(exchange string, event broker.Event) error
+
+// FeedbackEvent is the payload of feedback.created and feedback.updated events
+type FeedbackEvent struct {
+	FeedbackID       int32  `json:"feedback_id"`
+	OrderID          int32  `json:"order_id"`
+	RestaurantID     *int32 `json:"restaurant_id"`
+	RestaurantRating *int32 `json:"restaurant_rating"`
+}
+
 type FeedbackDomain struct {
-	repo *generated.Queries
+	repo    *generated.Queries
+	db      DB
+	publish EventPublisher
+}
+
+// NewFeedbackDomain initializes the domain layer. Events are not published
+// when publish is nil.
+func NewFeedbackDomain(repo *generated.Queries, db DB, publish EventPublisher) *FeedbackDomain {
+	return &FeedbackDomain{repo: repo, db: db, publish: publish}
 }
 
-// NewFeedbackDomain initializes the domain layer
-func NewFeedbackDomain(repo *generated.Queries) *FeedbackDomain {
-	return &FeedbackDomain{repo: repo}
+// FeedbackFilter narrows the listed feedbacks. Nil fields do not filter.
+type FeedbackFilter struct {
+	CustomerID      *int32
+	RestaurantID    *int32
+	DeliveryAgentID *int32
 }
 
-func (d *FeedbackDomain) GetAllFeedbacksDomain(ctx context.Context) ([]generated.Feedback, error) {
-	feedbacks, err := d.repo.GetAllFeedbacks(ctx)
+// FeedbackSortFields are the fields feedbacks can be sorted on, newest first by default
+var FeedbackSortFields = []string{"id", "created_at"}
+
+func (d *FeedbackDomain) GetAllFeedbacksDomain(ctx context.Context, filter FeedbackFilter, list ListParams) (*Page[generated.Feedback], error) {
+	sortField, descending, err := list.sortField(FeedbackSortFields, "-id")
+	if err != nil {
+		return nil, err
+	}
+	after, err := list.decodeCursor(list.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	params := feedbackListParams{
+		CustomerID:      filter.CustomerID,
+		RestaurantID:    filter.RestaurantID,
+		DeliveryAgentID: filter.DeliveryAgentID,
+		SortField:       sortField,
+		Descending:      descending,
+		PageLimit:       int32(list.limit() + 1),
+	}
+	if after != nil {
+		params.CursorID = &after.ID
+		if sortField == "created_at" {
+			if params.CursorTime, err = parseCursorTime(after.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	feedbacks, err := listFeedbacks(ctx, d.db, params)
 	if err != nil {
 		return nil, errors.New("failed to fetch feedbacks")
 	}
 
-	return feedbacks, nil
+	return newPage(feedbacks, list.limit(), func(last generated.Feedback) cursor {
+		next := cursor{Sort: list.Sort, ID: last.ID}
+		if sortField == "created_at" {
+			next.Value = formatCursorTime(last.Createdat)
+		}
+		return next
+	}), nil
+}
+
+// GetFeedbackOrderDomain returns the order feedback is given on, to tell who
+// may read the feedback
+func (d *FeedbackDomain) GetFeedbackOrderDomain(ctx context.Context, orderId int32) (*generated.Order, error) {
+	order, err := d.repo.GetOrderById(ctx, orderId)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+	return &order, nil
 }
 
 func (d *FeedbackDomain) GetFeedbackByOrderIdDomain(ctx context.Context, orderId int32) (*generated.Feedback, error) {
 	feedback, err := d.repo.GetFeedbackByOrderId(ctx, orderId)
 	if err != nil {
-		return nil, errors.New("failed to get feedback by order")
+		return nil, ErrFeedbackNotFound
 	}
 
 	return &feedback, nil
 }
 
-func (d *FeedbackDomain) CreateFeedbackDomain(ctx context.Context, feedbackParams generated.CreateFeedbackParams) (int32, error) {
-	feedbackid, err := d.repo.CreateFeedback(ctx, feedbackParams)
+func validRating(rating *int32) bool {
+	return rating == nil || (*rating >= 1 && *rating <= 5)
+}
+
+func validateRatings(deliveryAgentRating, restaurantRating *int32) error {
+	if deliveryAgentRating == nil && restaurantRating == nil {
+		return ErrInvalidRating
+	}
+	if !validRating(deliveryAgentRating) || !validRating(restaurantRating) {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+// checkFeedbackOrder makes sure the order exists, is delivered and was placed
+// by the customer
+func (d *FeedbackDomain) checkFeedbackOrder(ctx context.Context, orderId int32, customerId int32) (*generated.Order, error) {
+	order, err := d.repo.GetOrderById(ctx, orderId)
 	if err != nil {
-		return 0, errors.New("failed to create feedback: " + err.Error())
+		return nil, ErrOrderNotFound
+	}
+	if order.Customerid == nil || *order.Customerid != customerId {
+		return nil, ErrFeedbackForbidden
+	}
+	if order.Status != "Delivered" {
+		return nil, ErrOrderNotDelivered
 	}
+	return &order, nil
+}
 
-	err = d.UpdateDeliveryAgentRatingDomain(ctx, feedbackParams.Orderid)
+// publishFeedbackEvent lets other services, e.g. the restaurant service, react
+// to the feedback. Failures are logged, as the feedback has already been saved.
+func (d *FeedbackDomain) publishFeedbackEvent(eventType string, payload FeedbackEvent) {
+	if d.publish == nil {
+		return
+	}
+	err := d.publish(FeedbackEventsExchange, broker.Event{Type: eventType, Payload: payload})
 	if err != nil {
-		return 0, errors.New("failed to update average rating: " + err.Error())
+		log.Printf("Failed to publish %s for order %d: %v", eventType, payload.OrderID, err)
 	}
+}
 
-	return feedbackid, nil
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
 }
 
-func (d *FeedbackDomain) UpdateDeliveryAgentRatingDomain(ctx context.Context, orderId int32) error {
-	// Fetch all feedbacks for all the delivery agent
-	feedbacks, err := d.repo.GetAllFeedbacksFromDeliveryAgentByOrderId(ctx, orderId)
+func (d *FeedbackDomain) CreateFeedbackDomain(ctx context.Context, feedbackParams generated.CreateFeedbackParams) (int32, error) {
+	err := validateRatings(feedbackParams.Deliveryagentrating, feedbackParams.Restaurantrating)
+	if err != nil {
+		return 0, err
+	}
+
+	order, err := d.checkFeedbackOrder(ctx, feedbackParams.Orderid, feedbackParams.Customerid)
 	if err != nil {
-		return fmt.Errorf("failed to fetch feedbacks: %w", err)
+		return 0, err
 	}
 
-	// Calculate the average rating
-	var totalRating float64
-	var count int
-	for _, feedback := range feedbacks {
-		if feedback.Deliveryagentrating != nil {
-			totalRating += float64(*feedback.Deliveryagentrating)
-			count++
+	_, err = d.repo.GetFeedbackByOrderId(ctx, feedbackParams.Orderid)
+	if err == nil {
+		return 0, ErrFeedbackExists
+	}
+
+	var feedbackid int32
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		feedbackid, err = q.CreateFeedback(ctx, feedbackParams)
+		if err != nil {
+			// Another request got in between the check and the insert
+			if isUniqueViolation(err) {
+				return ErrFeedbackExists
+			}
+			return errors.New("failed to create feedback: " + err.Error())
 		}
+
+		return addDeliveryAgentRating(ctx, q, order.Deliveryagentid, nil, feedbackParams.Deliveryagentrating)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	d.publishFeedbackEvent(broker.FeedbackCreated, FeedbackEvent{
+		FeedbackID:       feedbackid,
+		OrderID:          feedbackParams.Orderid,
+		RestaurantID:     order.Restaurantid,
+		RestaurantRating: feedbackParams.Restaurantrating,
+	})
+
+	return feedbackid, nil
+}
+
+// ratingDelta is what changing a rating from previous to current adds to the
+// rating sum and count of an aggregate
+func ratingDelta(previous, current *int32) (sum int32, count int32) {
+	if previous != nil {
+		sum -= *previous
+		count--
+	}
+	if current != nil {
+		sum += *current
+		count++
+	}
+	return sum, count
+}
+
+// addDeliveryAgentRating updates the agent's rating aggregate for a changed
+// feedback rating. It runs in the feedback's transaction, so the aggregate
+// never drifts from the feedback.
+func addDeliveryAgentRating(ctx context.Context, q *generated.Queries, deliveryAgentId *int32, previous, current *int32) error {
+	if deliveryAgentId == nil {
+		return nil
 	}
 
-	if count == 0 {
-		return fmt.Errorf("no ratings found for delivery agent %d", orderId)
+	sum, count := ratingDelta(previous, current)
+	if sum == 0 && count == 0 {
+		return nil
+	}
+
+	err := q.AddDeliveryAgentRating(ctx, generated.AddDeliveryAgentRatingParams{
+		Ratingsum:   sum,
+		Ratingcount: count,
+		ID:          *deliveryAgentId,
+	})
+	if err != nil {
+		return errors.New("failed to update delivery agent rating: " + err.Error())
+	}
+	return nil
+}
+
+// UpdateFeedbackDomain lets the customer change their feedback within the edit window
+func (d *FeedbackDomain) UpdateFeedbackDomain(ctx context.Context, orderId int32, params UpdateFeedbackParams) error {
+	feedback, err := d.repo.GetFeedbackByOrderId(ctx, orderId)
+	if err != nil {
+		return ErrFeedbackNotFound
+	}
+	if feedback.Customerid != params.Customerid {
+		return ErrFeedbackForbidden
+	}
+	if feedback.Createdat != nil && time.Since(*feedback.Createdat) > feedbackEditWindow {
+		return ErrFeedbackEditWindowOver
 	}
 
-	avgRating := totalRating / float64(count)
+	update := generated.UpdateFeedbackParams{
+		Deliveryagentrating: feedback.Deliveryagentrating,
+		Restaurantrating:    feedback.Restaurantrating,
+		Comment:             feedback.Comment,
+		Orderid:             orderId,
+	}
+	if params.Deliveryagentrating != nil {
+		update.Deliveryagentrating = params.Deliveryagentrating
+	}
+	if params.Restaurantrating != nil {
+		update.Restaurantrating = params.Restaurantrating
+	}
+	if params.Comment != nil {
+		update.Comment = params.Comment
+	}
 
-	fmt.Print(avgRating)
+	err = validateRatings(update.Deliveryagentrating, update.Restaurantrating)
+	if err != nil {
+		return err
+	}
 
-	UpdateParams := generated.UpdateDeliveryAgentRatingParams{
-		Rating: &avgRating,
-		ID:     *feedbacks[0].Deliveryagentid,
+	order, err := d.repo.GetOrderById(ctx, orderId)
+	if err != nil {
+		return ErrOrderNotFound
 	}
 
-	fmt.Println(UpdateParams)
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		err := q.UpdateFeedback(ctx, update)
+		if err != nil {
+			return errors.New("failed to update feedback: " + err.Error())
+		}
 
-	// Update the delivery agent's rating
-	err = d.repo.UpdateDeliveryAgentRating(ctx, UpdateParams)
+		return addDeliveryAgentRating(ctx, q, order.Deliveryagentid, feedback.Deliveryagentrating, update.Deliveryagentrating)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update delivery agent rating: %w", err)
+		return err
+	}
+
+	if params.Restaurantrating != nil {
+		d.publishFeedbackEvent(broker.FeedbackUpdated, FeedbackEvent{
+			FeedbackID:       feedback.ID,
+			OrderID:          orderId,
+			RestaurantID:     order.Restaurantid,
+			RestaurantRating: update.Restaurantrating,
+		})
 	}
 
 	return nil
 }
 
+// RepublishFeedbackEventsDomain publishes a feedback.created event for every
+// feedback with a restaurant rating, so the restaurant service can recompute
+// its ratings from historical feedback. Consumers treat the events
+// idempotently, so it is safe to run more than once.
+func (d *FeedbackDomain) RepublishFeedbackEventsDomain(ctx context.Context) (int, error) {
+	if d.publish == nil {
+		return 0, errors.New("no event publisher configured")
+	}
+
+	rows, err := d.repo.GetRestaurantRatingsFromFeedbacks(ctx)
+	if err != nil {
+		return 0, errors.New("failed to fetch feedbacks: " + err.Error())
+	}
+
+	for i, row := range rows {
+		err := d.publish(FeedbackEventsExchange, broker.Event{
+			Type: broker.FeedbackCreated,
+			Payload: FeedbackEvent{
+				FeedbackID:       row.ID,
+				OrderID:          row.Orderid,
+				RestaurantID:     row.Restaurantid,
+				RestaurantRating: row.Restaurantrating,
+			},
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to publish feedback %d: %w", row.ID, err)
+		}
+	}
+
+	return len(rows), nil
+}
@@ -0,0 +1,259 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+var orderColumns = []string{
+	"id", "totalamount", "vatamount", "status", "timestamp", "comment", "customerid", "restaurantid",
+	"deliveryagentid", "paymentid", "bonusid", "feeid", "preptimeminutes", "estimateddeliverytime", "deliveredat",
+	"deliveryfee", "discountamount", "promotionid",
+}
+
+var feedbackColumns = []string{
+	"id", "orderid", "customerid", "deliveryagentrating", "restaurantrating", "comment", "createdat", "updatedat",
+}
+
+func orderRow(status string, customerId int32) *pgxmock.Rows {
+	now := time.Now()
+	return pgxmock.NewRows(orderColumns).AddRow(
+		int32(1), 100.0, 20.0, status, &now, nil, &customerId, int32Ptr(2),
+		int32Ptr(3), nil, nil, nil, nil, nil, nil, 0.0, 0.0, nil,
+	)
+}
+
+func SetupFeedbackTestMocks(t *testing.T) (pgxmock.PgxPoolIface, *FeedbackDomain) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create pgxmock pool: %v", err)
+	}
+
+	return mock, NewFeedbackDomain(generated.New(mock), mock, nil)
+}
+
+func TestCreateFeedbackDomain(t *testing.T) {
+	validParams := generated.CreateFeedbackParams{
+		Orderid:             1,
+		Customerid:          5,
+		Deliveryagentrating: int32Ptr(4),
+		Restaurantrating:    int32Ptr(5),
+	}
+
+	t.Run("Rating out of range", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		params := validParams
+		params.Restaurantrating = int32Ptr(6)
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), params)
+
+		// Assert
+		if !errors.Is(err, ErrInvalidRating) {
+			t.Errorf("got %v, want %v", err, ErrInvalidRating)
+		}
+	})
+
+	t.Run("No ratings", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		params := generated.CreateFeedbackParams{Orderid: 1, Customerid: 5}
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), params)
+
+		// Assert
+		if !errors.Is(err, ErrInvalidRating) {
+			t.Errorf("got %v, want %v", err, ErrInvalidRating)
+		}
+	})
+
+	t.Run("Order not found", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnError(errors.New("no rows in result set"))
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), validParams)
+
+		// Assert
+		if !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("got %v, want %v", err, ErrOrderNotFound)
+		}
+	})
+
+	t.Run("Other customer", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnRows(orderRow("Delivered", 6))
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), validParams)
+
+		// Assert
+		if !errors.Is(err, ErrFeedbackForbidden) {
+			t.Errorf("got %v, want %v", err, ErrFeedbackForbidden)
+		}
+	})
+
+	t.Run("Order not delivered", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnRows(orderRow("On its way", 5))
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), validParams)
+
+		// Assert
+		if !errors.Is(err, ErrOrderNotDelivered) {
+			t.Errorf("got %v, want %v", err, ErrOrderNotDelivered)
+		}
+	})
+
+	t.Run("Feedback already given", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		now := time.Now()
+		mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnRows(orderRow("Delivered", 5))
+		mock.ExpectQuery(`FROM\s+Feedback`).WithArgs(int32(1)).WillReturnRows(
+			pgxmock.NewRows(feedbackColumns).AddRow(int32(9), int32(1), int32(5), int32Ptr(3), int32Ptr(3), nil, &now, &now))
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), validParams)
+
+		// Assert
+		if !errors.Is(err, ErrFeedbackExists) {
+			t.Errorf("got %v, want %v", err, ErrFeedbackExists)
+		}
+	})
+
+	t.Run("Concurrent feedback hits the unique constraint", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnRows(orderRow("Delivered", 5))
+		mock.ExpectQuery(`FROM\s+Feedback`).WithArgs(int32(1)).WillReturnError(errors.New("no rows in result set"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO Feedback`).WithArgs(int32(1), int32(5), int32Ptr(4), int32Ptr(5), (*string)(nil)).WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+		mock.ExpectRollback()
+
+		// Act
+		_, err := domain.CreateFeedbackDomain(context.Background(), validParams)
+
+		// Assert
+		if !errors.Is(err, ErrFeedbackExists) {
+			t.Errorf("got %v, want %v", err, ErrFeedbackExists)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestCreateFeedbackUpdatesAgentRatingInTransaction(t *testing.T) {
+	// Arrange
+	mock, domain := SetupFeedbackTestMocks(t)
+	defer CloseMocks(mock)
+	params := generated.CreateFeedbackParams{
+		Orderid:             1,
+		Customerid:          5,
+		Deliveryagentrating: int32Ptr(4),
+		Restaurantrating:    int32Ptr(5),
+	}
+	mock.ExpectQuery(`FROM\s+"Order"`).WithArgs(int32(1)).WillReturnRows(orderRow("Delivered", 5))
+	mock.ExpectQuery(`FROM\s+Feedback`).WithArgs(int32(1)).WillReturnError(errors.New("no rows in result set"))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO Feedback`).
+		WithArgs(int32(1), int32(5), int32Ptr(4), int32Ptr(5), (*string)(nil)).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(9)))
+	mock.ExpectExec(`UPDATE\s+DeliveryAgent`).
+		WithArgs(int32(4), int32(1), int32(3)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	// Act
+	feedbackId, err := domain.CreateFeedbackDomain(context.Background(), params)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if feedbackId != 9 {
+		t.Errorf("got feedback ID %d, want 9", feedbackId)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestRatingDelta(t *testing.T) {
+	tests := []struct {
+		name              string
+		previous, current *int32
+		wantSum           int32
+		wantCount         int32
+	}{
+		{"New rating", nil, int32Ptr(4), 4, 1},
+		{"Changed rating", int32Ptr(4), int32Ptr(2), -2, 0},
+		{"Unchanged rating", int32Ptr(3), int32Ptr(3), 0, 0},
+		{"No rating", nil, nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sum, count := ratingDelta(tt.previous, tt.current)
+			if sum != tt.wantSum || count != tt.wantCount {
+				t.Errorf("got (%d, %d), want (%d, %d)", sum, count, tt.wantSum, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestUpdateFeedbackDomain(t *testing.T) {
+	t.Run("Edit window is over", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		createdAt := time.Now().Add(-feedbackEditWindow - time.Minute)
+		mock.ExpectQuery(`FROM\s+Feedback`).WithArgs(int32(1)).WillReturnRows(
+			pgxmock.NewRows(feedbackColumns).AddRow(int32(9), int32(1), int32(5), int32Ptr(3), int32Ptr(3), nil, &createdAt, &createdAt))
+
+		// Act
+		err := domain.UpdateFeedbackDomain(context.Background(), 1, UpdateFeedbackParams{Customerid: 5, Restaurantrating: int32Ptr(4)})
+
+		// Assert
+		if !errors.Is(err, ErrFeedbackEditWindowOver) {
+			t.Errorf("got %v, want %v", err, ErrFeedbackEditWindowOver)
+		}
+	})
+
+	t.Run("Other customer", func(t *testing.T) {
+		// Arrange
+		mock, domain := SetupFeedbackTestMocks(t)
+		defer CloseMocks(mock)
+		now := time.Now()
+		mock.ExpectQuery(`FROM\s+Feedback`).WithArgs(int32(1)).WillReturnRows(
+			pgxmock.NewRows(feedbackColumns).AddRow(int32(9), int32(1), int32(5), int32Ptr(3), int32Ptr(3), nil, &now, &now))
+
+		// Act
+		err := domain.UpdateFeedbackDomain(context.Background(), 1, UpdateFeedbackParams{Customerid: 6, Restaurantrating: int32Ptr(4)})
+
+		// Assert
+		if !errors.Is(err, ErrFeedbackForbidden) {
+			t.Errorf("got %v, want %v", err, ErrFeedbackForbidden)
+		}
+	})
+}
@@ -0,0 +1,262 @@
+package domain
+
+// Listings are built with the listing package rather than generated by sqlc,
+// so they write out the ORDER BY of their sort fields and Postgres can walk
+// the indexes the sorts have.
+
+import (
+	"context"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/listing"
+)
+
+const orderListing = `SELECT
+    ID,
+    TotalAmount,
+    VATAmount,
+    Status,
+    Timestamp,
+    Comment,
+    CustomerID,
+    RestaurantID,
+    DeliveryAgentID,
+    PaymentID,
+    BonusID,
+    FeeID,
+    PrepTimeMinutes,
+    EstimatedDeliveryTime,
+    DeliveredAt,
+    DeliveryFee,
+    DiscountAmount,
+    PromotionID
+FROM
+    "Order"`
+
+// orderSortKeys are the fields Orders sort on besides their ID
+var orderSortKeys = map[string]listing.SortKey{
+	"timestamp":    {Expr: "COALESCE(Timestamp, 'epoch')", CursorType: "timestamp"},
+	"total_amount": {Expr: "TotalAmount", CursorType: "numeric"},
+}
+
+type orderListParams struct {
+	Status          *string
+	CustomerID      *int32
+	RestaurantID    *int32
+	DeliveryAgentID *int32
+	FromTime        *time.Time
+	ToTime          *time.Time
+	CursorID        *int32
+	SortField       string
+	Descending      bool
+	CursorTime      *time.Time
+	CursorAmount    *float64
+	PageLimit       int32
+}
+
+// listOrders fetches a page of Orders, sorted on a whitelisted field with ID
+// breaking ties and continuing after the cursor of the previous page
+func listOrders(ctx context.Context, db generated.DBTX, arg orderListParams) ([]generated.Order, error) {
+	key, err := listing.SortKeyOf(orderSortKeys, arg.SortField)
+	if err != nil {
+		return nil, err
+	}
+	list := listing.New(orderListing)
+	listing.WhereSet(list, "Status = %[1]s::text", arg.Status)
+	listing.WhereSet(list, "CustomerID = %[1]s::int", arg.CustomerID)
+	listing.WhereSet(list, "RestaurantID = %[1]s::int", arg.RestaurantID)
+	listing.WhereSet(list, "DeliveryAgentID = %[1]s::int", arg.DeliveryAgentID)
+	listing.WhereSet(list, "Timestamp >= %[1]s::timestamp", arg.FromTime)
+	listing.WhereSet(list, "Timestamp < %[1]s::timestamp", arg.ToTime)
+	var cursorValue any
+	switch arg.SortField {
+	case "timestamp":
+		cursorValue = arg.CursorTime
+	case "total_amount":
+		cursorValue = arg.CursorAmount
+	}
+	query, args := list.Page(key, "ID", arg.Descending, cursorValue, arg.CursorID, arg.PageLimit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []generated.Order
+	for rows.Next() {
+		var i generated.Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.Totalamount,
+			&i.Vatamount,
+			&i.Status,
+			&i.Timestamp,
+			&i.Comment,
+			&i.Customerid,
+			&i.Restaurantid,
+			&i.Deliveryagentid,
+			&i.Paymentid,
+			&i.Bonusid,
+			&i.Feeid,
+			&i.Preptimeminutes,
+			&i.Estimateddeliverytime,
+			&i.Deliveredat,
+			&i.Deliveryfee,
+			&i.Discountamount,
+			&i.Promotionid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const feedbackListing = `SELECT
+    f.ID,
+    f.OrderID,
+    f.CustomerID,
+    f.DeliveryAgentRating,
+    f.RestaurantRating,
+    f.Comment,
+    f.CreatedAt,
+    f.UpdatedAt
+FROM
+    Feedback f
+    JOIN "Order" o ON f.OrderID = o.ID`
+
+// feedbackSortKeys are the fields Feedbacks sort on besides their ID
+var feedbackSortKeys = map[string]listing.SortKey{
+	"created_at": {Expr: "COALESCE(f.CreatedAt, 'epoch')", CursorType: "timestamp"},
+}
+
+type feedbackListParams struct {
+	CustomerID      *int32
+	RestaurantID    *int32
+	DeliveryAgentID *int32
+	CursorID        *int32
+	SortField       string
+	Descending      bool
+	CursorTime      *time.Time
+	PageLimit       int32
+}
+
+// listFeedbacks fetches a page of Feedbacks, sorted on a whitelisted field
+// with ID breaking ties and continuing after the cursor of the previous page
+func listFeedbacks(ctx context.Context, db generated.DBTX, arg feedbackListParams) ([]generated.Feedback, error) {
+	key, err := listing.SortKeyOf(feedbackSortKeys, arg.SortField)
+	if err != nil {
+		return nil, err
+	}
+	list := listing.New(feedbackListing)
+	listing.WhereSet(list, "f.CustomerID = %[1]s::int", arg.CustomerID)
+	listing.WhereSet(list, "o.RestaurantID = %[1]s::int", arg.RestaurantID)
+	listing.WhereSet(list, "o.DeliveryAgentID = %[1]s::int", arg.DeliveryAgentID)
+	query, args := list.Page(key, "f.ID", arg.Descending, arg.CursorTime, arg.CursorID, arg.PageLimit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []generated.Feedback
+	for rows.Next() {
+		var i generated.Feedback
+		if err := rows.Scan(
+			&i.ID,
+			&i.Orderid,
+			&i.Customerid,
+			&i.Deliveryagentrating,
+			&i.Restaurantrating,
+			&i.Comment,
+			&i.Createdat,
+			&i.Updatedat,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deliveryAgentListing = `SELECT
+    ID,
+    FullName,
+    ContactInfo,
+    Availability,
+    Rating,
+    RatingSum,
+    RatingCount
+FROM
+    DeliveryAgent`
+
+// deliveryAgentSortKeys are the fields DeliveryAgents sort on besides their ID
+var deliveryAgentSortKeys = map[string]listing.SortKey{
+	"rating": {Expr: "COALESCE(Rating, 0)", CursorType: "numeric"},
+	"name":   {Expr: "COALESCE(FullName, '')", CursorType: "text"},
+}
+
+type deliveryAgentListParams struct {
+	Availability *bool
+	MinRating    *float64
+	CursorID     *int32
+	SortField    string
+	Descending   bool
+	CursorRating *float64
+	CursorName   *string
+	PageLimit    int32
+}
+
+// listDeliveryAgents fetches a page of DeliveryAgents, sorted on a
+// whitelisted field with ID breaking ties and continuing after the cursor of
+// the previous page
+func listDeliveryAgents(ctx context.Context, db generated.DBTX, arg deliveryAgentListParams) ([]generated.Deliveryagent, error) {
+	key, err := listing.SortKeyOf(deliveryAgentSortKeys, arg.SortField)
+	if err != nil {
+		return nil, err
+	}
+	list := listing.New(deliveryAgentListing)
+	listing.WhereSet(list, "Availability = %[1]s::bool", arg.Availability)
+	listing.WhereSet(list, "Rating >= %[1]s::numeric", arg.MinRating)
+	var cursorValue any
+	switch arg.SortField {
+	case "rating":
+		cursorValue = arg.CursorRating
+	case "name":
+		cursorValue = arg.CursorName
+	}
+	query, args := list.Page(key, "ID", arg.Descending, cursorValue, arg.CursorID, arg.PageLimit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []generated.Deliveryagent
+	for rows.Next() {
+		var i generated.Deliveryagent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Fullname,
+			&i.Contactinfo,
+			&i.Availability,
+			&i.Rating,
+			&i.Ratingsum,
+			&i.Ratingcount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -3,23 +3,95 @@ package domain
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
+	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 )
 
 type OrderDomain struct {
-	repo *generated.Queries
+	repo            *generated.Queries
+	db              DB
+	events          *OrderEventHub
+	locator         Locator
+	restaurants     Restaurants
+	responseTimeout time.Duration
+	etaRequests     chan int32
 }
 
-// NewOrderDomain initializes the domain layer
-func NewOrderDomain(repo *generated.Queries) *OrderDomain {
-	return &OrderDomain{repo: repo}
+// NewOrderDomain initializes the domain layer. Restaurants have
+// responseTimeout to answer an order, DefaultResponseTimeout when zero, and
+// orders are only accepted while the restaurant's kitchen has room and its
+// stock has them reserved. Delivery times are estimated from where locator
+// puts the restaurant and customer, once EstimateDeliveryTimesDomain runs.
+func NewOrderDomain(repo *generated.Queries, db DB, events *OrderEventHub, locator Locator, restaurants Restaurants, responseTimeout time.Duration) *OrderDomain {
+	if responseTimeout <= 0 {
+		responseTimeout = DefaultResponseTimeout
+	}
+	return &OrderDomain{
+		repo:            repo,
+		db:              db,
+		events:          events,
+		locator:         locator,
+		restaurants:     restaurants,
+		responseTimeout: responseTimeout,
+		etaRequests:     make(chan int32, etaQueueSize),
+	}
+}
+
+// OrderFilter narrows the listed orders. Nil fields do not filter, and the
+// time range includes From but not To.
+type OrderFilter struct {
+	Status          *string
+	CustomerID      *int32
+	RestaurantID    *int32
+	DeliveryAgentID *int32
+	From            *time.Time
+	To              *time.Time
 }
 
-func (d *OrderDomain) GetAllOrdersDomain(ctx context.Context) ([]generated.Order, error) {
-	rows, err := d.repo.GetAllOrders(ctx)
+// OrderSortFields are the fields orders can be sorted on, newest first by default
+var OrderSortFields = []string{"id", "timestamp", "total_amount"}
+
+func (d *OrderDomain) GetAllOrdersDomain(ctx context.Context, filter OrderFilter, list ListParams) (*Page[generated.Order], error) {
+	sortField, descending, err := list.sortField(OrderSortFields, "-timestamp")
+	if err != nil {
+		return nil, err
+	}
+	after, err := list.decodeCursor(list.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	params := orderListParams{
+		Status:          filter.Status,
+		CustomerID:      filter.CustomerID,
+		RestaurantID:    filter.RestaurantID,
+		DeliveryAgentID: filter.DeliveryAgentID,
+		FromTime:        filter.From,
+		ToTime:          filter.To,
+		SortField:       sortField,
+		Descending:      descending,
+		PageLimit:       int32(list.limit() + 1),
+	}
+	if after != nil {
+		params.CursorID = &after.ID
+		switch sortField {
+		case "timestamp":
+			params.CursorTime, err = parseCursorTime(after.Value)
+		case "total_amount":
+			params.CursorAmount, err = parseCursorNumber(after.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := listOrders(ctx, d.db, params)
 	if err != nil {
 		return nil, errors.New("failed to fetch orders")
 	}
@@ -27,21 +99,37 @@ func (d *OrderDomain) GetAllOrdersDomain(ctx context.Context) ([]generated.Order
 	var orders []generated.Order
 	for _, row := range rows {
 		orders = append(orders, generated.Order{
-			ID:              row.ID,
-			Totalamount:     row.Totalamount,
-			Vatamount:       row.Vatamount,
-			Status:          row.Status,
-			Timestamp:       row.Timestamp,
-			Comment:         row.Comment,
-			Customerid:      row.Customerid,
-			Restaurantid:    row.Restaurantid,
-			Deliveryagentid: row.Deliveryagentid,
-			Paymentid:       row.Paymentid,
-			Bonusid:         row.Bonusid,
-			Feeid:           row.Feeid,
+			ID:                    row.ID,
+			Totalamount:           row.Totalamount,
+			Vatamount:             row.Vatamount,
+			Status:                row.Status,
+			Timestamp:             row.Timestamp,
+			Comment:               row.Comment,
+			Customerid:            row.Customerid,
+			Restaurantid:          row.Restaurantid,
+			Deliveryagentid:       row.Deliveryagentid,
+			Paymentid:             row.Paymentid,
+			Bonusid:               row.Bonusid,
+			Feeid:                 row.Feeid,
+			Preptimeminutes:       row.Preptimeminutes,
+			Estimateddeliverytime: row.Estimateddeliverytime,
+			Deliveredat:           row.Deliveredat,
+			Deliveryfee:           row.Deliveryfee,
+			Discountamount:        row.Discountamount,
+			Promotionid:           row.Promotionid,
 		})
 	}
-	return orders, nil
+
+	return newPage(orders, list.limit(), func(last generated.Order) cursor {
+		next := cursor{Sort: list.Sort, ID: last.ID}
+		switch sortField {
+		case "timestamp":
+			next.Value = formatCursorTime(last.Timestamp)
+		case "total_amount":
+			next.Value = formatCursorNumber(&last.Totalamount)
+		}
+		return next
+	}), nil
 }
 
 func (d *OrderDomain) GetOrderByIdDomain(ctx context.Context, orderId int32) (*generated.Order, error) {
@@ -55,54 +143,160 @@ func (d *OrderDomain) GetOrderByIdDomain(ctx context.Context, orderId int32) (*g
 	}
 
 	order := &generated.Order{
-		ID:              row.ID,
-		Totalamount:     row.Totalamount,
-		Vatamount:       row.Vatamount,
-		Status:          row.Status,
-		Timestamp:       row.Timestamp,
-		Comment:         row.Comment,
-		Customerid:      row.Customerid,
-		Restaurantid:    row.Restaurantid,
-		Deliveryagentid: row.Deliveryagentid,
-		Paymentid:       row.Paymentid,
-		Bonusid:         row.Bonusid,
-		Feeid:           row.Feeid,
+		ID:                    row.ID,
+		Totalamount:           row.Totalamount,
+		Vatamount:             row.Vatamount,
+		Status:                row.Status,
+		Timestamp:             row.Timestamp,
+		Comment:               row.Comment,
+		Customerid:            row.Customerid,
+		Restaurantid:          row.Restaurantid,
+		Deliveryagentid:       row.Deliveryagentid,
+		Paymentid:             row.Paymentid,
+		Bonusid:               row.Bonusid,
+		Feeid:                 row.Feeid,
+		Preptimeminutes:       row.Preptimeminutes,
+		Estimateddeliverytime: row.Estimateddeliverytime,
+		Deliveredat:           row.Deliveredat,
+		Deliveryfee:           row.Deliveryfee,
+		Discountamount:        row.Discountamount,
+		Promotionid:           row.Promotionid,
 	}
 
 	return order, nil
 }
 
-func (d *OrderDomain) CreateOrderDomain(ctx context.Context, orderParams generated.CreateOrderParams) (int32, error) {
-	amountExcludingVAT := orderParams.Totalamount - orderParams.Vatamount
+// CreateOrderDomain places an order with its items. With a promotion code
+// the promotion is redeemed, its discount already being taken off the total,
+// so the fee is computed on the discounted amount. When the promotion no
+// longer applies, the order is placed rejected with the reason, for the
+// customer to see why it did not go through.
+func (d *OrderDomain) CreateOrderDomain(ctx context.Context, orderParams generated.CreateOrderParams, items []OrderLineItem, promotionCode *string) (int32, error) {
+	var orderid int32
+	var rejection *string
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if promotionCode != nil {
+			promotion, err := redeemPromotion(ctx, q, *promotionCode, orderParams)
+			if errors.Is(err, ErrPromotionNotFound) || errors.Is(err, ErrPromotionNotApplicable) {
+				reason := "Promotion code " + *promotionCode + " no longer applies: " + err.Error()
+				rejection = &reason
+				orderParams.Status = StatusRejected
+			} else if err != nil {
+				return err
+			} else {
+				orderParams.Promotionid = &promotion.PromotionID
+			}
+		}
 
-	feeid, err := d.CalculateFee(ctx, amountExcludingVAT)
+		amountExcludingVAT := orderParams.Totalamount - orderParams.Vatamount
+		feeid, err := calculateFee(ctx, q, amountExcludingVAT)
+		if err != nil {
+			return err
+		}
+		orderParams.Feeid = &feeid
+
+		// Call the repository layer to create the order
+		orderid, err = q.CreateOrder(ctx, orderParams)
+		if err != nil {
+			return errors.New("failed to create order: " + err.Error())
+		}
+
+		// The items go in with the order, which is never placed without them
+		for _, item := range items {
+			itemParams, err := item.OrderItemParams(orderid)
+			if err != nil {
+				return fmt.Errorf("failed to encode order item %s: %w", item.Name, err)
+			}
+			if _, err := q.CreateOrderItem(ctx, itemParams); err != nil {
+				return errors.New("failed to create order item: " + err.Error())
+			}
+		}
+
+		if rejection == nil {
+			return nil
+		}
+		return q.CreateOrderResponse(ctx, generated.CreateOrderResponseParams{
+			Orderid:  orderid,
+			Accepted: false,
+			Reason:   rejection,
+		})
+	})
 	if err != nil {
-		fmt.Printf("%v", err)
 		return 0, err
 	}
-	orderParams.Feeid = &feeid
 
-	// Call the repository layer to create the order
-	orderid, err := d.repo.CreateOrder(ctx, orderParams)
-	if err != nil {
-		return 0, errors.New("failed to create order: " + err.Error())
+	if rejection != nil {
+		d.recordOrderEvent(ctx, orderid, broker.OrderStatusChanged, OrderStatusChange{
+			OrderID:      orderid,
+			Status:       StatusRejected,
+			CustomerID:   orderParams.Customerid,
+			RestaurantID: orderParams.Restaurantid,
+			Reason:       rejection,
+		})
+		log.Printf("Rejected order %d: %s", orderid, *rejection)
+		return orderid, nil
 	}
 
+	d.requestETA(orderid)
+
 	return orderid, nil
 }
 
+// UpdateOrderStatusDomain moves an order along after the restaurant has
+// answered it. Pending orders are accepted and rejected through the
+// restaurant's inbox only, which records the answer and the prep time.
 func (d *OrderDomain) UpdateOrderStatusDomain(ctx context.Context, orderId int32, status string) error {
-	// Call the repository layer to update the order
-	err := d.repo.UpdateOrderStatus(ctx, generated.UpdateOrderStatusParams{
-		Status: status,
-		ID:     orderId,
-	})
+	order, err := d.repo.GetOrderById(ctx, orderId)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errors.New("order not found")
+		return errors.New("order not found")
+	}
+	return d.changeStatus(ctx, order, status)
+}
+
+// changeStatus moves the order to the status, as long as it still has the
+// status it was read with
+func (d *OrderDomain) changeStatus(ctx context.Context, order generated.Order, status string) error {
+	orderId := order.ID
+	if answersOrder(order.Status, status) {
+		return fmt.Errorf("%w: pending orders are answered through the restaurant inbox", ErrInvalidTransition)
+	}
+	if !CanTransition(order.Status, status) {
+		return fmt.Errorf("%w: order %d is %s", ErrInvalidTransition, orderId, order.Status)
+	}
+
+	var items []OrderedItem
+	if status == StatusCancelled {
+		orderItems, err := d.repo.GetOrderItemsByOrderId(ctx, orderId)
+		if err != nil {
+			return errors.New("failed to fetch order items")
 		}
+		items = orderedItems(orderItems)
+	}
+
+	// Call the repository layer to update the order, unless its status
+	// changed since it was read
+	updated, err := d.repo.UpdateOrderStatus(ctx, generated.UpdateOrderStatusParams{
+		Status:     status,
+		ID:         orderId,
+		FromStatus: order.Status,
+	})
+	if err != nil {
 		return err
 	}
+	if updated == 0 {
+		return fmt.Errorf("%w: order %d changed status in the meantime", ErrInvalidTransition, orderId)
+	}
+
+	d.recordOrderEvent(ctx, orderId, broker.OrderStatusChanged, OrderStatusChange{
+		OrderID:               orderId,
+		Status:                status,
+		CustomerID:            order.Customerid,
+		RestaurantID:          order.Restaurantid,
+		DeliveryAgentID:       order.Deliveryagentid,
+		EstimatedDeliveryTime: lastETA(order, status),
+		Items:                 items,
+	})
+	d.requestETA(orderId)
 
 	return nil
 }
@@ -111,19 +305,53 @@ func boolPtr(i bool) *bool {
 	value := bool(i)
 	return &value
 }
+
+// UpdateOrderStatusAndDeliveryAgentDomain lets a delivery agent take an
+// accepted order on its way, or deliver the order they carry. An order is
+// only taken by one agent, and others cannot move it along.
 func (d *OrderDomain) UpdateOrderStatusAndDeliveryAgentDomain(ctx context.Context, orderId int32, status string, deliveryAgentId int32) error {
-	// Call the repository layer to update the order
-	err := d.repo.UpdateOrderStatusAndDeliveryAgent(ctx, generated.UpdateOrderStatusAndDeliveryAgentParams{
-		Status:          status,
+	order, err := d.repo.GetOrderById(ctx, orderId)
+	if err != nil {
+		return errors.New("order not found")
+	}
+	if order.Deliveryagentid != nil && *order.Deliveryagentid != deliveryAgentId {
+		return ErrNotAssignedAgent
+	}
+
+	switch status {
+	case StatusOnItsWay:
+		return d.assignDeliveryAgent(ctx, order, deliveryAgentId)
+	case StatusDelivered:
+		if order.Deliveryagentid == nil {
+			return ErrNotAssignedAgent
+		}
+		return d.changeStatus(ctx, order, status)
+	}
+	return fmt.Errorf("%w: delivery agents only take orders on their way and deliver them", ErrInvalidTransition)
+}
+
+// assignDeliveryAgent sends an accepted order on its way with the delivery
+// agent, who is no longer available for other orders
+func (d *OrderDomain) assignDeliveryAgent(ctx context.Context, order generated.Order, deliveryAgentId int32) error {
+	orderId := order.ID
+	if !CanTransition(order.Status, StatusOnItsWay) {
+		return fmt.Errorf("%w: order %d is %s", ErrInvalidTransition, orderId, order.Status)
+	}
+
+	// Call the repository layer to update the order, unless its status or
+	// delivery agent changed since it was read
+	updated, err := d.repo.UpdateOrderStatusAndDeliveryAgent(ctx, generated.UpdateOrderStatusAndDeliveryAgentParams{
+		Status:          StatusOnItsWay,
 		ID:              orderId,
 		Deliveryagentid: &deliveryAgentId,
+		FromStatus:      order.Status,
 	})
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return errors.New("order not found")
-		}
 		return err
 	}
+	if updated == 0 {
+		return fmt.Errorf("%w: order %d changed in the meantime", ErrInvalidTransition, orderId)
+	}
 
 	// Set availability to false for the delivery agent
 	availability := generated.UpdateDeliveryAgentAvailabilityParams{
@@ -135,9 +363,82 @@ func (d *OrderDomain) UpdateOrderStatusAndDeliveryAgentDomain(ctx context.Contex
 		return errors.New("cant update delivery agent availability")
 	}
 
+	d.recordOrderEvent(ctx, orderId, broker.OrderAgentAssigned, OrderStatusChange{
+		OrderID:               orderId,
+		Status:                StatusOnItsWay,
+		CustomerID:            order.Customerid,
+		RestaurantID:          order.Restaurantid,
+		DeliveryAgentID:       &deliveryAgentId,
+		EstimatedDeliveryTime: lastETA(order, StatusOnItsWay),
+	})
+	d.requestETA(orderId)
+
 	return nil
 }
 
+// recordOrderEvent stores the event, so subscribers can resume from it, and
+// publishes it to the subscribers of every replica. Failures are logged, as the
+// order change itself has already been saved.
+func (d *OrderDomain) recordOrderEvent(ctx context.Context, orderId int32, eventType string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal order event: %v", err)
+		return
+	}
+
+	row, err := d.repo.CreateOrderEvent(ctx, generated.CreateOrderEventParams{
+		Orderid: orderId,
+		Type:    eventType,
+		Payload: payload,
+	})
+	if err != nil {
+		log.Printf("Failed to record order event for order %d: %v", orderId, err)
+		return
+	}
+
+	if d.events == nil {
+		return
+	}
+	if err := d.events.Publish(toOrderEvent(row)); err != nil {
+		log.Printf("Failed to publish order event for order %d: %v", orderId, err)
+	}
+}
+
+// GetOrderEventsAfterDomain returns the events of an order recorded after
+// lastEventId. They are read in batches until none are left, so a client
+// that reconnects after a long time still gets every event it missed.
+func (d *OrderDomain) GetOrderEventsAfterDomain(ctx context.Context, orderId int32, lastEventId int64) ([]OrderEvent, error) {
+	var events []OrderEvent
+	for {
+		rows, err := d.repo.GetOrderEventsAfter(ctx, generated.GetOrderEventsAfterParams{
+			Orderid: orderId,
+			ID:      lastEventId,
+			Limit:   orderEventReplayBatch,
+		})
+		if err != nil {
+			return nil, errors.New("failed to fetch order events")
+		}
+
+		for _, row := range rows {
+			events = append(events, toOrderEvent(row))
+			lastEventId = row.ID
+		}
+		if len(rows) < orderEventReplayBatch {
+			return events, nil
+		}
+	}
+}
+
+// SubscribeOrderEventsDomain registers for live events on an order
+func (d *OrderDomain) SubscribeOrderEventsDomain(orderId int32) (<-chan OrderEvent, func(), error) {
+	if d.events == nil {
+		return nil, nil, errors.New("order events are not available")
+	}
+
+	events, unsubscribe := d.events.Subscribe(orderId)
+	return events, unsubscribe, nil
+}
+
 func (d *OrderDomain) DeleteOrderDomain(ctx context.Context, orderId int32) error {
 	err := d.repo.DeleteOrderItemsByOrderId(ctx, orderId)
 	if err != nil {
@@ -165,6 +466,10 @@ func (d *OrderDomain) CreateOrderItemDomain(ctx context.Context, itemParams gene
 }
 
 func (d *OrderDomain) CalculateFee(ctx context.Context, amount float64) (int32, error) {
+	return calculateFee(ctx, d.repo, amount)
+}
+
+func calculateFee(ctx context.Context, q *generated.Queries, amount float64) (int32, error) {
 	var fee float64
 	var percent float64
 
@@ -189,7 +494,7 @@ func (d *OrderDomain) CalculateFee(ctx context.Context, amount float64) (int32,
 		Description: &desc,
 	}
 
-	feeid, err := d.repo.CreateFee(ctx, newFee)
+	feeid, err := q.CreateFee(ctx, newFee)
 	if err != nil {
 		return 0, errors.New("failed to create fee: " + err.Error())
 	}
@@ -284,4 +589,3 @@ func (d *OrderDomain) CalculateBonus(ctx context.Context, orderId int32) (int32,
 // create fee
 // create bonus
 // create payment
-
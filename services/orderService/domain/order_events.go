@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	orderEventsExchange  = "order_events"
+	subscriberBufferSize = 16
+	// Missed events are replayed in batches of this size
+	orderEventReplayBatch = 500
+)
+
+// Roles that may follow the progress of an order
+const (
+	RoleCustomer      = "customer"
+	RoleRestaurant    = "restaurant"
+	RoleDeliveryAgent = "delivery_agent"
+)
+
+// OrderEvent is a recorded change to an order, streamed to its subscribers
+type OrderEvent struct {
+	ID        int64           `json:"id"`
+	OrderID   int32           `json:"order_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt *time.Time      `json:"created_at"`
+}
+
+// OrderStatusChange is the data of order status and agent assignment events
+type OrderStatusChange struct {
+	OrderID               int32      `json:"order_id"`
+	Status                string     `json:"status"`
+	CustomerID            *int32     `json:"customer_id"`
+	RestaurantID          *int32     `json:"restaurant_id"`
+	DeliveryAgentID       *int32     `json:"delivery_agent_id"`
+	EstimatedDeliveryTime *time.Time `json:"estimated_delivery_time"`
+	// Items are sent when an order is accepted or cancelled, so the
+	// restaurant service can take and give back their stock
+	Items []OrderedItem `json:"items,omitempty"`
+	// PrepTimeMinutes is sent when the restaurant accepts the order, and
+	// Reason when it rejects it
+	PrepTimeMinutes *int32  `json:"prep_time_minutes,omitempty"`
+	Reason          *string `json:"reason,omitempty"`
+}
+
+// OrderParticipant identifies the caller that wants to follow an order
+type OrderParticipant struct {
+	Role string
+	ID   int32
+}
+
+// CanFollowOrder reports whether the participant is the order's customer,
+// restaurant or assigned delivery agent
+func CanFollowOrder(order *generated.Order, participant OrderParticipant) bool {
+	var owner *int32
+	switch participant.Role {
+	case RoleCustomer:
+		owner = order.Customerid
+	case RoleRestaurant:
+		owner = order.Restaurantid
+	case RoleDeliveryAgent:
+		owner = order.Deliveryagentid
+	}
+	return owner != nil && *owner == participant.ID
+}
+
+func toOrderEvent(row generated.Orderevent) OrderEvent {
+	return OrderEvent{
+		ID:        row.ID,
+		OrderID:   row.Orderid,
+		Type:      row.Type,
+		Data:      json.RawMessage(row.Payload),
+		CreatedAt: row.Createdat,
+	}
+}
+
+// OrderEventHub delivers order events to the subscribers connected to this
+// replica. Events travel between replicas through the order events fanout
+// exchange, so subscribers get every event regardless of where it was recorded.
+type OrderEventHub struct {
+	mu          sync.Mutex
+	subscribers map[int32]map[chan OrderEvent]struct{}
+}
+
+func NewOrderEventHub() *OrderEventHub {
+	return &OrderEventHub{subscribers: make(map[int32]map[chan OrderEvent]struct{})}
+}
+
+// Listen consumes the order events exchange and dispatches to local subscribers
+func (h *OrderEventHub) Listen() {
+	broker.ConsumeFanout(orderEventsExchange, "", func(event broker.Event) {
+		payloadBytes, err := json.Marshal(event.Payload)
+		if err != nil {
+			log.Printf("Failed to marshal event payload: %v", err)
+			return
+		}
+
+		var orderEvent OrderEvent
+		if err := json.Unmarshal(payloadBytes, &orderEvent); err != nil {
+			log.Printf("Failed to unmarshal order event: %v", err)
+			return
+		}
+
+		h.Dispatch(orderEvent)
+	})
+}
+
+// Publish sends the event to every replica, including this one
+func (h *OrderEventHub) Publish(event OrderEvent) error {
+	return broker.PublishFanout(orderEventsExchange, broker.Event{
+		Type:    event.Type,
+		Payload: event,
+	})
+}
+
+// Subscribe registers for events on an order. The returned function must be
+// called to unsubscribe.
+func (h *OrderEventHub) Subscribe(orderId int32) (<-chan OrderEvent, func()) {
+	ch := make(chan OrderEvent, subscriberBufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[orderId] == nil {
+		h.subscribers[orderId] = make(map[chan OrderEvent]struct{})
+	}
+	h.subscribers[orderId][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() { h.remove(orderId, ch) }
+}
+
+// Dispatch hands the event to the local subscribers of its order. A subscriber
+// that cannot keep up is disconnected, it can resume with Last-Event-ID.
+func (h *OrderEventHub) Dispatch(event OrderEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.OrderID] {
+		select {
+		case ch <- event:
+		default:
+			h.removeLocked(event.OrderID, ch)
+		}
+	}
+}
+
+func (h *OrderEventHub) remove(orderId int32, ch chan OrderEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(orderId, ch)
+}
+
+func (h *OrderEventHub) removeLocked(orderId int32, ch chan OrderEvent) {
+	subscribers := h.subscribers[orderId]
+	if _, ok := subscribers[ch]; !ok {
+		return
+	}
+	delete(subscribers, ch)
+	close(ch)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, orderId)
+	}
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+func TestOrderEventHub(t *testing.T) {
+	t.Run("Dispatch only reaches subscribers of the order", func(t *testing.T) {
+		// Arrange
+		hub := NewOrderEventHub()
+		events, unsubscribe := hub.Subscribe(1)
+		defer unsubscribe()
+		otherEvents, unsubscribeOther := hub.Subscribe(2)
+		defer unsubscribeOther()
+
+		// Act
+		hub.Dispatch(OrderEvent{ID: 10, OrderID: 1, Type: "order.status_changed"})
+
+		// Assert
+		select {
+		case event := <-events:
+			if event.ID != 10 {
+				t.Errorf("got event ID %d, want 10", event.ID)
+			}
+		default:
+			t.Fatal("expected an event for order 1")
+		}
+		select {
+		case event := <-otherEvents:
+			t.Errorf("unexpected event for order 2: %+v", event)
+		default:
+		}
+	})
+
+	t.Run("Unsubscribe closes the channel", func(t *testing.T) {
+		// Arrange
+		hub := NewOrderEventHub()
+		events, unsubscribe := hub.Subscribe(1)
+
+		// Act
+		unsubscribe()
+		unsubscribe()
+
+		// Assert
+		if _, ok := <-events; ok {
+			t.Error("expected channel to be closed")
+		}
+		if len(hub.subscribers) != 0 {
+			t.Errorf("got %d subscribed orders, want 0", len(hub.subscribers))
+		}
+	})
+
+	t.Run("Slow subscriber is disconnected", func(t *testing.T) {
+		// Arrange
+		hub := NewOrderEventHub()
+		events, unsubscribe := hub.Subscribe(1)
+		defer unsubscribe()
+
+		// Act
+		for i := 0; i <= subscriberBufferSize; i++ {
+			hub.Dispatch(OrderEvent{ID: int64(i + 1), OrderID: 1})
+		}
+
+		// Assert
+		received := 0
+		for range events {
+			received++
+		}
+		if received != subscriberBufferSize {
+			t.Errorf("got %d buffered events, want %d", received, subscriberBufferSize)
+		}
+	})
+}
+
+func TestCanFollowOrder(t *testing.T) {
+	order := &generated.Order{
+		ID:              1,
+		Customerid:      int32Ptr(5),
+		Restaurantid:    int32Ptr(7),
+		Deliveryagentid: nil,
+	}
+
+	tests := []struct {
+		name        string
+		participant OrderParticipant
+		want        bool
+	}{
+		{"Ordering customer", OrderParticipant{Role: RoleCustomer, ID: 5}, true},
+		{"Other customer", OrderParticipant{Role: RoleCustomer, ID: 6}, false},
+		{"Order restaurant", OrderParticipant{Role: RoleRestaurant, ID: 7}, true},
+		{"Unassigned delivery agent", OrderParticipant{Role: RoleDeliveryAgent, ID: 5}, false},
+		{"Unknown role", OrderParticipant{Role: "admin", ID: 5}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanFollowOrder(order, tt.participant); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetOrderEventsAfterDomain(t *testing.T) {
+	t.Run("Replays every batch of missed events", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		columns := []string{"id", "orderid", "type", "payload", "createdat"}
+		createdAt := time.Date(2025, time.January, 25, 12, 0, 0, 0, time.UTC)
+		full := pgxmock.NewRows(columns)
+		for id := int64(1); id <= orderEventReplayBatch; id++ {
+			full.AddRow(id, int32(5), "order.status_changed", []byte("{}"), &createdAt)
+		}
+		mock.ExpectQuery(`FROM\s+OrderEvent`).
+			WithArgs(int32(5), int64(0), int32(orderEventReplayBatch)).
+			WillReturnRows(full)
+		mock.ExpectQuery(`FROM\s+OrderEvent`).
+			WithArgs(int32(5), int64(orderEventReplayBatch), int32(orderEventReplayBatch)).
+			WillReturnRows(pgxmock.NewRows(columns).
+				AddRow(int64(orderEventReplayBatch+1), int32(5), "order.eta_updated", []byte("{}"), &createdAt))
+
+		// Act
+		events, err := domain.GetOrderEventsAfterDomain(context.Background(), 5, 0)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(events) != orderEventReplayBatch+1 {
+			t.Errorf("got %d events, want %d", len(events), orderEventReplayBatch+1)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
@@ -0,0 +1,243 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	// DefaultResponseTimeout is how long restaurants have to answer an order
+	DefaultResponseTimeout = 10 * time.Minute
+	maxPrepTimeMinutes     = 180
+	// Unanswered orders are rejected in batches, oldest first
+	autoRejectBatchSize = 100
+	autoRejectReason    = "The restaurant did not respond in time"
+)
+
+var (
+	ErrOrderNotPending = errors.New("order is no longer pending")
+	ErrInvalidResponse = errors.New("invalid order response")
+	ErrOutOfStock      = errors.New("not enough stock for order")
+	ErrKitchenFull     = errors.New("kitchen is at capacity")
+)
+
+// Restaurants looks up the kitchen load and takes the stock of orders in the
+// restaurant service, which owns both
+type Restaurants interface {
+	KitchenAtCapacity(ctx context.Context, restaurantId int32) (bool, error)
+	ReserveStock(ctx context.Context, restaurantId, orderId int32, items []OrderedItem) (reserved bool, reason string, err error)
+}
+
+// InboxOrder is a pending order as the restaurant sees it, with the time it
+// is rejected by unless answered
+type InboxOrder struct {
+	generated.Order
+	Items     []OrderLineItem `json:"items"`
+	RespondBy *time.Time      `json:"respond_by"`
+}
+
+// orderResponse is a restaurant's answer to an order
+type orderResponse struct {
+	accepted        bool
+	prepTimeMinutes *int32
+	reason          *string
+	autoRejected    bool
+}
+
+// GetRestaurantInboxDomain lists the pending orders of a restaurant, oldest
+// first unless sorted otherwise
+func (d *OrderDomain) GetRestaurantInboxDomain(ctx context.Context, restaurantId int32, list ListParams) (*Page[InboxOrder], error) {
+	if list.Sort == "" {
+		list.Sort = "timestamp"
+	}
+	status := StatusPending
+	page, err := d.GetAllOrdersDomain(ctx, OrderFilter{Status: &status, RestaurantID: &restaurantId}, list)
+	if err != nil {
+		return nil, err
+	}
+
+	inbox := &Page[InboxOrder]{Items: []InboxOrder{}, NextCursor: page.NextCursor}
+	for _, order := range page.Items {
+		rows, err := d.repo.GetOrderItemsByOrderId(ctx, order.ID)
+		if err != nil {
+			return nil, errors.New("failed to fetch order items")
+		}
+		items, err := toOrderLineItems(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		inboxOrder := InboxOrder{Order: order, Items: items}
+		if order.Timestamp != nil {
+			respondBy := order.Timestamp.Add(d.responseTimeout)
+			inboxOrder.RespondBy = &respondBy
+		}
+		inbox.Items = append(inbox.Items, inboxOrder)
+	}
+	return inbox, nil
+}
+
+// AcceptOrderDomain accepts a pending order of the restaurant, which expects
+// to have it ready in prepTimeMinutes
+func (d *OrderDomain) AcceptOrderDomain(ctx context.Context, restaurantId, orderId, prepTimeMinutes int32) error {
+	if prepTimeMinutes < 1 || prepTimeMinutes > maxPrepTimeMinutes {
+		return fmt.Errorf("%w: prep time must be between 1 and %d minutes", ErrInvalidResponse, maxPrepTimeMinutes)
+	}
+	return d.respondToOrder(ctx, restaurantId, orderId, orderResponse{accepted: true, prepTimeMinutes: &prepTimeMinutes})
+}
+
+// RejectOrderDomain rejects a pending order of the restaurant. The reason is
+// passed on to the customer.
+func (d *OrderDomain) RejectOrderDomain(ctx context.Context, restaurantId, orderId int32, reason string) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return fmt.Errorf("%w: a reason is required", ErrInvalidResponse)
+	}
+	return d.respondToOrder(ctx, restaurantId, orderId, orderResponse{reason: &reason})
+}
+
+// AutoRejectOrdersDomain rejects the orders that have been pending for longer
+// than the response timeout, returning how many were rejected
+func (d *OrderDomain) AutoRejectOrdersDomain(ctx context.Context) (int, error) {
+	deadline := time.Now().Add(-d.responseTimeout)
+	rows, err := d.repo.GetUnansweredOrders(ctx, generated.GetUnansweredOrdersParams{
+		Timestamp: &deadline,
+		Limit:     autoRejectBatchSize,
+	})
+	if err != nil {
+		return 0, errors.New("failed to fetch unanswered orders: " + err.Error())
+	}
+
+	rejected := 0
+	reason := autoRejectReason
+	for _, row := range rows {
+		if row.Restaurantid == nil {
+			continue
+		}
+		err := d.respondToOrder(ctx, *row.Restaurantid, row.ID, orderResponse{reason: &reason, autoRejected: true})
+		// The restaurant may have answered in the meantime
+		if errors.Is(err, ErrOrderNotPending) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to reject order %d: %v", row.ID, err)
+			continue
+		}
+		rejected++
+	}
+	return rejected, nil
+}
+
+// respondToOrder moves a pending order of the restaurant to accepted or
+// rejected and records the answer, which is sent to the order's subscribers
+func (d *OrderDomain) respondToOrder(ctx context.Context, restaurantId, orderId int32, response orderResponse) error {
+	order, err := d.repo.GetOrderById(ctx, orderId)
+	if err != nil || order.Restaurantid == nil || *order.Restaurantid != restaurantId {
+		return ErrOrderNotFound
+	}
+	if order.Status != StatusPending {
+		return fmt.Errorf("%w: order %d is %s", ErrOrderNotPending, orderId, order.Status)
+	}
+
+	status := StatusRejected
+	var items []OrderedItem
+	if response.accepted {
+		status = StatusAccepted
+		orderItems, err := d.repo.GetOrderItemsByOrderId(ctx, orderId)
+		if err != nil {
+			return errors.New("failed to fetch order items")
+		}
+		items = orderedItems(orderItems)
+		if err := d.checkKitchen(ctx, restaurantId); err != nil {
+			return err
+		}
+		if err := d.reserveStock(ctx, restaurantId, orderId, items); err != nil {
+			return err
+		}
+	}
+
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		updated, err := q.RespondToOrder(ctx, generated.RespondToOrderParams{
+			Status:          status,
+			Preptimeminutes: response.prepTimeMinutes,
+			ID:              orderId,
+			Restaurantid:    &restaurantId,
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return fmt.Errorf("%w: order %d has already been answered", ErrOrderNotPending, orderId)
+		}
+		return q.CreateOrderResponse(ctx, generated.CreateOrderResponseParams{
+			Orderid:      orderId,
+			Accepted:     response.accepted,
+			Reason:       response.reason,
+			Autorejected: response.autoRejected,
+		})
+	})
+	if errors.Is(err, ErrOrderNotPending) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to answer order: " + err.Error())
+	}
+
+	d.recordOrderEvent(ctx, orderId, broker.OrderStatusChanged, OrderStatusChange{
+		OrderID:               orderId,
+		Status:                status,
+		CustomerID:            order.Customerid,
+		RestaurantID:          order.Restaurantid,
+		DeliveryAgentID:       order.Deliveryagentid,
+		EstimatedDeliveryTime: lastETA(order, status),
+		Items:                 items,
+		PrepTimeMinutes:       response.prepTimeMinutes,
+		Reason:                response.reason,
+	})
+	if response.accepted {
+		d.requestETA(orderId)
+	}
+
+	return nil
+}
+
+// checkKitchen keeps restaurants from accepting orders while their kitchen is
+// at capacity. They can reject the order, or accept it once orders leave the
+// kitchen.
+func (d *OrderDomain) checkKitchen(ctx context.Context, restaurantId int32) error {
+	if d.restaurants == nil {
+		return nil
+	}
+	atCapacity, err := d.restaurants.KitchenAtCapacity(ctx, restaurantId)
+	if err != nil {
+		return errors.New("failed to check the kitchen load: " + err.Error())
+	}
+	if atCapacity {
+		return fmt.Errorf("%w: accept the order once orders leave the kitchen", ErrKitchenFull)
+	}
+	return nil
+}
+
+// reserveStock takes the stock of the order's items before it is accepted, so
+// restaurants cannot accept more than they have left today. The stock is
+// given back when the order is later rejected or cancelled.
+func (d *OrderDomain) reserveStock(ctx context.Context, restaurantId, orderId int32, items []OrderedItem) error {
+	if d.restaurants == nil || len(items) == 0 {
+		return nil
+	}
+	reserved, reason, err := d.restaurants.ReserveStock(ctx, restaurantId, orderId, items)
+	if err != nil {
+		return errors.New("failed to reserve stock: " + err.Error())
+	}
+	if !reserved {
+		return fmt.Errorf("%w: %s", ErrOutOfStock, reason)
+	}
+	return nil
+}
@@ -0,0 +1,407 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/broker"
+)
+
+var orderItemColumns = []string{"id", "orderid", "name", "price", "quantity", "menuitemid", "options", "components", "allergens", "dietarytags", "priceversionid"}
+
+// expectOrder expects order 5 of restaurant 2 to be looked up, finding it
+// with the status
+func expectOrder(mock pgxmock.PgxPoolIface, status string) {
+	expectOrderCarriedBy(mock, status, nil)
+}
+
+// expectOrderCarriedBy expects order 5 of restaurant 2 to be looked up,
+// finding it with the status and delivery agent
+func expectOrderCarriedBy(mock pgxmock.PgxPoolIface, status string, deliveryAgentId *int32) {
+	placedAt := time.Date(2025, time.January, 25, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`FROM\s+"Order"\s+WHERE\s+ID = \$1`).
+		WithArgs(int32(5)).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(int32(5), 100.0, 20.0, status, &placedAt, nil, int32Ptr(1), int32Ptr(2), deliveryAgentId, nil, nil, nil, nil, nil, nil, 0.0, 0.0, nil))
+}
+
+// eventItems matches the payload of an order event carrying the items
+type eventItems []OrderedItem
+
+func (want eventItems) Match(value interface{}) bool {
+	payload, ok := value.([]byte)
+	if !ok {
+		return false
+	}
+	var change OrderStatusChange
+	if err := json.Unmarshal(payload, &change); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(change.Items, []OrderedItem(want))
+}
+
+// stubRestaurants reserves stock unless reason is set
+type stubRestaurants struct {
+	atCapacity bool
+	reason     string
+	reserved   []OrderedItem
+}
+
+func (s *stubRestaurants) KitchenAtCapacity(ctx context.Context, restaurantId int32) (bool, error) {
+	return s.atCapacity, nil
+}
+
+func (s *stubRestaurants) ReserveStock(ctx context.Context, restaurantId, orderId int32, items []OrderedItem) (bool, string, error) {
+	if s.reason != "" {
+		return false, s.reason, nil
+	}
+	s.reserved = items
+	return true, "", nil
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusPending, StatusAccepted, true},
+		{StatusPending, StatusRejected, true},
+		{StatusPending, StatusOnItsWay, false},
+		{StatusAccepted, StatusOnItsWay, true},
+		{StatusAccepted, StatusRejected, false},
+		{StatusOnItsWay, StatusCancelled, false},
+		{StatusRejected, StatusAccepted, false},
+		{StatusDelivered, StatusCancelled, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateOrderStatusDomain(t *testing.T) {
+	t.Run("Pending orders are answered through the inbox", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectOrder(mock, StatusPending)
+
+		err := domain.UpdateOrderStatusDomain(context.Background(), 5, StatusAccepted)
+
+		if !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidTransition)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Status changed in the meantime", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectOrder(mock, StatusAccepted)
+		mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+DeliveredAt`).
+			WithArgs(StatusOnItsWay, int32(5), StatusAccepted).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+		// Act
+		err := domain.UpdateOrderStatusDomain(context.Background(), 5, StatusOnItsWay)
+
+		// Assert
+		if !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidTransition)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestUpdateOrderStatusDomainCancelled(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	expectOrder(mock, StatusAccepted)
+	mock.ExpectQuery(`FROM\s+OrderItem`).
+		WithArgs(int32(5)).
+		WillReturnRows(pgxmock.NewRows(orderItemColumns).
+			AddRow(int32(1), int32(5), "Cheese Pizza", 12.5, 2.0, int32Ptr(7), []byte("[]"), []byte("[]"), []string{}, []string{}, int32Ptr(3)))
+	mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+DeliveredAt`).
+		WithArgs(StatusCancelled, int32(5), StatusAccepted).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// The restaurant service gives back the stock of the items in the event
+	mock.ExpectQuery(`INSERT INTO OrderEvent`).
+		WithArgs(int32(5), broker.OrderStatusChanged, eventItems{{MenuItemID: 7, Quantity: 2}}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "orderid", "type", "payload", "createdat"}).
+			AddRow(int64(1), int32(5), broker.OrderStatusChanged, []byte("{}"), (*time.Time)(nil)))
+
+	// Act
+	err := domain.UpdateOrderStatusDomain(context.Background(), 5, StatusCancelled)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateOrderStatusAndDeliveryAgentDomain(t *testing.T) {
+	t.Run("Takes an accepted order on its way", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectOrder(mock, StatusAccepted)
+		mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+DeliveryAgentID = \$2`).
+			WithArgs(StatusOnItsWay, int32Ptr(4), int32(5), StatusAccepted).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE\s+DeliveryAgent\s+SET\s+Availability`).
+			WithArgs(boolPtr(false), int32(4)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery(`INSERT INTO OrderEvent`).
+			WithArgs(int32(5), broker.OrderAgentAssigned, pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "orderid", "type", "payload", "createdat"}).
+				AddRow(int64(1), int32(5), broker.OrderAgentAssigned, []byte("{}"), (*time.Time)(nil)))
+
+		// Act
+		err := domain.UpdateOrderStatusAndDeliveryAgentDomain(context.Background(), 5, StatusOnItsWay, 4)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name            string
+		status          string
+		deliveryAgentId *int32
+		newStatus       string
+		wantErr         error
+	}{
+		{"Second agent cannot take the order", StatusOnItsWay, int32Ptr(3), StatusOnItsWay, ErrNotAssignedAgent},
+		{"Second agent cannot deliver the order", StatusOnItsWay, int32Ptr(3), StatusDelivered, ErrNotAssignedAgent},
+		{"Unassigned agent cannot deliver the order", StatusAccepted, nil, StatusDelivered, ErrNotAssignedAgent},
+		{"Repeated status is not a change", StatusOnItsWay, int32Ptr(4), StatusOnItsWay, ErrInvalidTransition},
+		{"Delivered order stays delivered", StatusDelivered, int32Ptr(4), StatusDelivered, ErrInvalidTransition},
+		{"Orders are cancelled by their restaurant", StatusAccepted, nil, StatusCancelled, ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			expectOrderCarriedBy(mock, tt.status, tt.deliveryAgentId)
+
+			// Act
+			err := domain.UpdateOrderStatusAndDeliveryAgentDomain(context.Background(), 5, tt.newStatus, 4)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			// The order is left alone
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestAcceptOrderDomain(t *testing.T) {
+	t.Run("Accepts a pending order", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		stock := &stubRestaurants{}
+		domain.restaurants = stock
+		expectOrder(mock, StatusPending)
+		mock.ExpectQuery(`FROM\s+OrderItem`).
+			WithArgs(int32(5)).
+			WillReturnRows(pgxmock.NewRows(orderItemColumns).
+				AddRow(int32(1), int32(5), "Cheese Pizza", 12.5, 2.0, int32Ptr(7), []byte("[]"), []byte("[]"), []string{}, []string{}, int32Ptr(3)))
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+PrepTimeMinutes`).
+			WithArgs(StatusAccepted, int32Ptr(20), int32(5), int32Ptr(2)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO OrderResponse`).
+			WithArgs(int32(5), true, (*string)(nil), false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		// Act
+		err := domain.AcceptOrderDomain(context.Background(), 2, 5, 20)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(stock.reserved) != 1 || stock.reserved[0] != (OrderedItem{MenuItemID: 7, Quantity: 2}) {
+			t.Errorf("got reserved %v, want 2 of menu item 7", stock.reserved)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects an invalid prep time", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		err := domain.AcceptOrderDomain(context.Background(), 2, 5, 0)
+
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidResponse)
+		}
+	})
+
+	t.Run("Order of another restaurant", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectOrder(mock, StatusPending)
+
+		err := domain.AcceptOrderDomain(context.Background(), 3, 5, 20)
+
+		if !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrOrderNotFound)
+		}
+	})
+
+	t.Run("Order already answered", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectOrder(mock, StatusRejected)
+
+		err := domain.AcceptOrderDomain(context.Background(), 2, 5, 20)
+
+		if !errors.Is(err, ErrOrderNotPending) {
+			t.Errorf("got error %v, want %v", err, ErrOrderNotPending)
+		}
+	})
+
+	t.Run("Not enough stock left", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		domain.restaurants = &stubRestaurants{reason: "less than 2 of menu item 7 left today"}
+		expectOrder(mock, StatusPending)
+		mock.ExpectQuery(`FROM\s+OrderItem`).
+			WithArgs(int32(5)).
+			WillReturnRows(pgxmock.NewRows(orderItemColumns).
+				AddRow(int32(1), int32(5), "Cheese Pizza", 12.5, 2.0, int32Ptr(7), []byte("[]"), []byte("[]"), []string{}, []string{}, int32Ptr(3)))
+
+		err := domain.AcceptOrderDomain(context.Background(), 2, 5, 20)
+
+		if !errors.Is(err, ErrOutOfStock) {
+			t.Errorf("got error %v, want %v", err, ErrOutOfStock)
+		}
+		// The order stays pending
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Kitchen at capacity", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		restaurants := &stubRestaurants{atCapacity: true}
+		domain.restaurants = restaurants
+		expectOrder(mock, StatusPending)
+		mock.ExpectQuery(`FROM\s+OrderItem`).
+			WithArgs(int32(5)).
+			WillReturnRows(pgxmock.NewRows(orderItemColumns).
+				AddRow(int32(1), int32(5), "Cheese Pizza", 12.5, 2.0, int32Ptr(7), []byte("[]"), []byte("[]"), []string{}, []string{}, int32Ptr(3)))
+
+		err := domain.AcceptOrderDomain(context.Background(), 2, 5, 20)
+
+		if !errors.Is(err, ErrKitchenFull) {
+			t.Errorf("got error %v, want %v", err, ErrKitchenFull)
+		}
+		if restaurants.reserved != nil {
+			t.Errorf("expected no stock to be taken, got %v", restaurants.reserved)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestRejectOrderDomain(t *testing.T) {
+	t.Run("Requires a reason", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		err := domain.RejectOrderDomain(context.Background(), 2, 5, "  ")
+
+		if !errors.Is(err, ErrInvalidResponse) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidResponse)
+		}
+	})
+
+	t.Run("Answered in the meantime", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		reason := "Out of fresh dough"
+		expectOrder(mock, StatusPending)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+PrepTimeMinutes`).
+			WithArgs(StatusRejected, (*int32)(nil), int32(5), int32Ptr(2)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+		mock.ExpectRollback()
+
+		// Act
+		err := domain.RejectOrderDomain(context.Background(), 2, 5, reason)
+
+		// Assert
+		if !errors.Is(err, ErrOrderNotPending) {
+			t.Errorf("got error %v, want %v", err, ErrOrderNotPending)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestAutoRejectOrdersDomain(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	reason := autoRejectReason
+	mock.ExpectQuery(`FROM\s+"Order"\s+WHERE\s+Status = 'Pending'\s+AND Timestamp < \$1`).
+		WithArgs(pgxmock.AnyArg(), int32(autoRejectBatchSize)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid"}).AddRow(int32(5), int32Ptr(2)))
+	expectOrder(mock, StatusPending)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE\s+"Order"\s+SET\s+Status = \$1,\s+PrepTimeMinutes`).
+		WithArgs(StatusRejected, (*int32)(nil), int32(5), int32Ptr(2)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO OrderResponse`).
+		WithArgs(int32(5), false, &reason, true).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	// Act
+	rejected, err := domain.AutoRejectOrdersDomain(context.Background())
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rejected != 1 {
+		t.Errorf("got %d rejected orders, want 1", rejected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// OrderLineItem is an item of a checked out cart. The price is the unit price
+// of the menu item including the options chosen, charged from the price
+// version of the menu item, and the allergens and dietary tags are those of
+// the menu item when it was ordered.
+type OrderLineItem struct {
+	MenuItemId     int                 `json:"menu_item_id"`
+	Name           string              `json:"name"`
+	Price          float64             `json:"price"`
+	Quantity       float64             `json:"quantity"`
+	Options        []LineItemOption    `json:"options"`
+	Components     []LineItemComponent `json:"components"`
+	Allergens      []string            `json:"allergens"`
+	DietaryTags    []string            `json:"dietary_tags"`
+	PriceVersionId *int                `json:"price_version_id,omitempty"`
+}
+
+// LineItemOption is an option chosen for an order item
+type LineItemOption struct {
+	OptionId   int     `json:"option_id"`
+	Group      string  `json:"group"`
+	Name       string  `json:"name"`
+	PriceDelta float64 `json:"price_delta"`
+}
+
+// LineItemComponent is a menu item served as part of a combo
+type LineItemComponent struct {
+	MenuItemId int    `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Quantity   int    `json:"quantity"`
+}
+
+// OrderItemParams returns the params storing the item on an order, with its
+// options and components as JSON arrays
+func (i OrderLineItem) OrderItemParams(orderId int32) (generated.CreateOrderItemParams, error) {
+	if i.Options == nil {
+		i.Options = []LineItemOption{}
+	}
+	if i.Components == nil {
+		i.Components = []LineItemComponent{}
+	}
+	if i.Allergens == nil {
+		i.Allergens = []string{}
+	}
+	if i.DietaryTags == nil {
+		i.DietaryTags = []string{}
+	}
+	options, err := json.Marshal(i.Options)
+	if err != nil {
+		return generated.CreateOrderItemParams{}, err
+	}
+	components, err := json.Marshal(i.Components)
+	if err != nil {
+		return generated.CreateOrderItemParams{}, err
+	}
+
+	params := generated.CreateOrderItemParams{
+		Orderid:     orderId,
+		Name:        i.Name,
+		Price:       i.Price,
+		Quantity:    i.Quantity,
+		Options:     options,
+		Components:  components,
+		Allergens:   i.Allergens,
+		Dietarytags: i.DietaryTags,
+	}
+	if i.MenuItemId != 0 {
+		menuItemId := int32(i.MenuItemId)
+		params.Menuitemid = &menuItemId
+	}
+	if i.PriceVersionId != nil {
+		priceVersionId := int32(*i.PriceVersionId)
+		params.Priceversionid = &priceVersionId
+	}
+	return params, nil
+}
+
+// toOrderLineItems decodes stored order items, with their options and components
+func toOrderLineItems(rows []generated.Orderitem) ([]OrderLineItem, error) {
+	items := make([]OrderLineItem, 0, len(rows))
+	for _, row := range rows {
+		item := OrderLineItem{
+			Name:        row.Name,
+			Price:       row.Price,
+			Quantity:    row.Quantity,
+			Options:     []LineItemOption{},
+			Components:  []LineItemComponent{},
+			Allergens:   row.Allergens,
+			DietaryTags: row.Dietarytags,
+		}
+		if row.Menuitemid != nil {
+			item.MenuItemId = int(*row.Menuitemid)
+		}
+		if row.Priceversionid != nil {
+			priceVersionId := int(*row.Priceversionid)
+			item.PriceVersionId = &priceVersionId
+		}
+		if len(row.Options) > 0 {
+			if err := json.Unmarshal(row.Options, &item.Options); err != nil {
+				return nil, fmt.Errorf("failed to decode options of order item %d: %w", row.ID, err)
+			}
+		}
+		if len(row.Components) > 0 {
+			if err := json.Unmarshal(row.Components, &item.Components); err != nil {
+				return nil, fmt.Errorf("failed to decode components of order item %d: %w", row.ID, err)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// OrderedItem is a menu item of an order with the quantity ordered
+type OrderedItem struct {
+	MenuItemID int32 `json:"menu_item_id"`
+	Quantity   int32 `json:"quantity"`
+}
+
+// orderedItems lists the menu items of an order, leaving out items that were
+// not ordered from a menu
+func orderedItems(items []generated.Orderitem) []OrderedItem {
+	ordered := []OrderedItem{}
+	for _, item := range items {
+		if item.Menuitemid == nil {
+			continue
+		}
+		ordered = append(ordered, OrderedItem{MenuItemID: *item.Menuitemid, Quantity: int32(item.Quantity)})
+	}
+	return ordered
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+func TestOrderItemParams(t *testing.T) {
+	t.Run("Stores options and components", func(t *testing.T) {
+		// Arrange
+		priceVersionId := 12
+		item := OrderLineItem{
+			MenuItemId:     7,
+			Name:           "Pizza Menu",
+			Price:          13.5,
+			Quantity:       2,
+			Options:        []LineItemOption{{OptionId: 2, Group: "Size", Name: "Large", PriceDelta: 2.5}},
+			Components:     []LineItemComponent{{MenuItemId: 4, Name: "Coca-Cola", Quantity: 1}},
+			Allergens:      []string{"gluten", "milk"},
+			DietaryTags:    []string{"vegetarian"},
+			PriceVersionId: &priceVersionId,
+		}
+
+		// Act
+		params, err := item.OrderItemParams(3)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if params.Orderid != 3 || params.Menuitemid == nil || *params.Menuitemid != 7 {
+			t.Errorf("got order %d and menu item %v, want 3 and 7", params.Orderid, params.Menuitemid)
+		}
+		wantOptions := `[{"option_id":2,"group":"Size","name":"Large","price_delta":2.5}]`
+		if string(params.Options) != wantOptions {
+			t.Errorf("got options %s, want %s", params.Options, wantOptions)
+		}
+		wantComponents := `[{"menu_item_id":4,"name":"Coca-Cola","quantity":1}]`
+		if string(params.Components) != wantComponents {
+			t.Errorf("got components %s, want %s", params.Components, wantComponents)
+		}
+		if len(params.Allergens) != 2 || len(params.Dietarytags) != 1 {
+			t.Errorf("got allergens %v and dietary tags %v", params.Allergens, params.Dietarytags)
+		}
+		if params.Priceversionid == nil || *params.Priceversionid != 12 {
+			t.Errorf("got price version %v, want 12", params.Priceversionid)
+		}
+	})
+
+	t.Run("Plain item", func(t *testing.T) {
+		// Arrange
+		item := OrderLineItem{Name: "Cheese Burger", Price: 10, Quantity: 1}
+
+		// Act
+		params, err := item.OrderItemParams(3)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if params.Menuitemid != nil || params.Priceversionid != nil {
+			t.Errorf("got menu item %v and price version %v, want none", params.Menuitemid, params.Priceversionid)
+		}
+		if string(params.Options) != "[]" || string(params.Components) != "[]" {
+			t.Errorf("got options %s and components %s, want empty arrays", params.Options, params.Components)
+		}
+		if params.Allergens == nil || params.Dietarytags == nil {
+			t.Errorf("got nil allergens or dietary tags, want empty lists")
+		}
+	})
+}
+
+func TestOrderedItems(t *testing.T) {
+	// Arrange
+	menuItemId := int32(7)
+	items := []generated.Orderitem{
+		{Name: "Pizza Menu", Quantity: 2, Menuitemid: &menuItemId},
+		{Name: "Extra napkins", Quantity: 1},
+	}
+
+	// Act
+	got := orderedItems(items)
+
+	// Assert
+	want := []OrderedItem{{MenuItemID: 7, Quantity: 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+)
+
+// Order statuses. Restaurants accept or reject pending orders, and accepted
+// orders are delivered or cancelled.
+const (
+	StatusPending   = "Pending"
+	StatusAccepted  = "Accepted"
+	StatusRejected  = "Rejected"
+	StatusOnItsWay  = "On its way"
+	StatusDelivered = "Delivered"
+	StatusCancelled = "Cancelled"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid order status change")
+	ErrNotAssignedAgent  = errors.New("order is delivered by another delivery agent")
+)
+
+// orderTransitions lists the statuses an order may move to from each status.
+// Rejected, delivered and cancelled orders are final.
+var orderTransitions = map[string][]string{
+	StatusPending:  {StatusAccepted, StatusRejected, StatusCancelled},
+	StatusAccepted: {StatusOnItsWay, StatusCancelled},
+	StatusOnItsWay: {StatusDelivered},
+}
+
+// CanTransition reports whether an order may move from one status to another
+func CanTransition(from, to string) bool {
+	return slices.Contains(orderTransitions[from], to)
+}
+
+// answersOrder reports whether the change accepts or rejects a pending order,
+// which restaurants only do through their inbox
+func answersOrder(from, to string) bool {
+	return from == StatusPending && (to == StatusAccepted || to == StatusRejected)
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestListParamsSortField(t *testing.T) {
+	tests := []struct {
+		sort           string
+		wantField      string
+		wantDescending bool
+		wantErr        error
+	}{
+		{"", "timestamp", true, nil},
+		{"total_amount", "total_amount", false, nil},
+		{"-id", "id", true, nil},
+		{"comment", "", false, ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			field, descending, err := ListParams{Sort: tt.sort}.sortField(OrderSortFields, "-timestamp")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if field != tt.wantField || descending != tt.wantDescending {
+				t.Errorf("got %q descending %v, want %q descending %v", field, descending, tt.wantField, tt.wantDescending)
+			}
+		})
+	}
+}
+
+func TestListParamsDecodeCursor(t *testing.T) {
+	encoded := cursor{Sort: "-timestamp", Value: "2025-01-20T12:00:00Z", ID: 7}.encode()
+
+	got, err := ListParams{Cursor: encoded, Sort: "-timestamp"}.decodeCursor("-timestamp")
+	if err != nil || got.ID != 7 || got.Value != "2025-01-20T12:00:00Z" {
+		t.Errorf("got %+v, %v", got, err)
+	}
+
+	if _, err := (ListParams{Cursor: encoded}).decodeCursor("id"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another sort: got error %v, want %v", err, ErrInvalidCursor)
+	}
+	if _, err := (ListParams{Cursor: "not a cursor"}).decodeCursor(""); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("garbage cursor: got error %v, want %v", err, ErrInvalidCursor)
+	}
+}
+
+func TestGetAllOrdersDomain(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	first := time.Date(2025, time.January, 20, 12, 0, 0, 0, time.UTC)
+	second := first.Add(-time.Hour)
+	status := "Delivered"
+
+	// Only the filters that are set are queried, and the sort is written out
+	mock.ExpectQuery(`FROM\s+"Order"\s+WHERE Status = \$1::text\s+ORDER BY COALESCE\(Timestamp, 'epoch'\) DESC, ID DESC\s+LIMIT \$2::int`).
+		WithArgs(&status, int32(2)).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(int32(9), 100.0, 20.0, status, &first, nil, int32Ptr(1), int32Ptr(2), nil, nil, nil, nil, nil, nil, nil, 0.0, 0.0, nil).
+			AddRow(int32(8), 50.0, 10.0, status, &second, nil, int32Ptr(1), int32Ptr(2), nil, nil, nil, nil, nil, nil, nil, 0.0, 0.0, nil))
+	mock.ExpectQuery(`WHERE Status = \$1::text\s+AND \(COALESCE\(Timestamp, 'epoch'\), ID\) < \(\$2::timestamp, \$3::int\)\s+ORDER BY`).
+		WithArgs(&status, &first, int32Ptr(9), int32(2)).
+		WillReturnRows(pgxmock.NewRows(orderColumns).
+			AddRow(int32(8), 50.0, 10.0, status, &second, nil, int32Ptr(1), int32Ptr(2), nil, nil, nil, nil, nil, nil, nil, 0.0, 0.0, nil))
+
+	// Act
+	page, err := domain.GetAllOrdersDomain(context.Background(), OrderFilter{Status: &status}, ListParams{Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	next, err := domain.GetAllOrdersDomain(context.Background(), OrderFilter{Status: &status}, ListParams{Limit: 1, Cursor: *page.NextCursor})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Items) != 1 || page.Items[0].ID != 9 {
+		t.Errorf("unexpected first page: %+v", page.Items)
+	}
+	if len(next.Items) != 1 || next.Items[0].ID != 8 || next.NextCursor != nil {
+		t.Errorf("unexpected last page: %+v", next)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
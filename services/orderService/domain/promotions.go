@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Discount types of promotions
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+)
+
+const maxPromotionCodeLength = 50
+
+var (
+	ErrPromotionNotFound      = errors.New("promotion not found")
+	ErrPromotionExists        = errors.New("promotion code already exists")
+	ErrInvalidPromotion       = errors.New("invalid promotion")
+	ErrPromotionNotApplicable = errors.New("promotion does not apply")
+)
+
+// PromotionParams creates a promotion. Without a restaurant the promotion
+// applies platform-wide, and missing limits and validity bounds do not
+// restrict it.
+type PromotionParams struct {
+	Code               string     `json:"code" example:"WELCOME10"`
+	Description        *string    `json:"description" example:"10% off your first order"`
+	DiscountType       string     `json:"discount_type" example:"percentage"`
+	DiscountValue      float64    `json:"discount_value" example:"10"`
+	RestaurantID       *int32     `json:"restaurant_id"`
+	MinimumOrder       float64    `json:"minimum_order" example:"100"`
+	ValidFrom          *time.Time `json:"valid_from" example:"2025-02-01T00:00:00Z"`
+	ValidTo            *time.Time `json:"valid_to" example:"2025-03-01T00:00:00Z"`
+	MaxUses            *int32     `json:"max_uses" example:"1000"`
+	MaxUsesPerCustomer *int32     `json:"max_uses_per_customer" example:"1"`
+	FirstOrderOnly     bool       `json:"first_order_only"`
+}
+
+// PromotionCheck asks whether a promotion code applies to the items of a cart
+type PromotionCheck struct {
+	Code         string  `json:"code" example:"WELCOME10"`
+	RestaurantID int32   `json:"restaurant_id" example:"1"`
+	CustomerID   int32   `json:"customer_id" example:"1"`
+	Subtotal     float64 `json:"subtotal" example:"150"`
+}
+
+// AppliedPromotion is the discount a promotion gives on a subtotal
+type AppliedPromotion struct {
+	PromotionID    int32   `json:"promotion_id"`
+	Code           string  `json:"code"`
+	Description    *string `json:"description"`
+	DiscountType   string  `json:"discount_type"`
+	DiscountValue  float64 `json:"discount_value"`
+	DiscountAmount float64 `json:"discount_amount"`
+}
+
+// normalizePromotionCode makes codes case-insensitive
+func normalizePromotionCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func validatePromotion(params PromotionParams) error {
+	switch {
+	case params.Code == "" || len(params.Code) > maxPromotionCodeLength:
+		return fmt.Errorf("%w: code must be between 1 and %d characters", ErrInvalidPromotion, maxPromotionCodeLength)
+	case params.DiscountType != DiscountPercentage && params.DiscountType != DiscountFixed:
+		return fmt.Errorf("%w: discount_type must be %s or %s", ErrInvalidPromotion, DiscountPercentage, DiscountFixed)
+	case params.DiscountValue <= 0:
+		return fmt.Errorf("%w: discount_value must be positive", ErrInvalidPromotion)
+	case params.DiscountType == DiscountPercentage && params.DiscountValue > 100:
+		return fmt.Errorf("%w: a percentage discount cannot exceed 100", ErrInvalidPromotion)
+	case params.MinimumOrder < 0:
+		return fmt.Errorf("%w: minimum_order cannot be negative", ErrInvalidPromotion)
+	case params.ValidFrom != nil && params.ValidTo != nil && !params.ValidTo.After(*params.ValidFrom):
+		return fmt.Errorf("%w: valid_to must be after valid_from", ErrInvalidPromotion)
+	case params.MaxUses != nil && *params.MaxUses <= 0, params.MaxUsesPerCustomer != nil && *params.MaxUsesPerCustomer <= 0:
+		return fmt.Errorf("%w: usage limits must be positive", ErrInvalidPromotion)
+	}
+	return nil
+}
+
+// CreatePromotionDomain creates a promotion. Codes are stored upper case.
+func (d *OrderDomain) CreatePromotionDomain(ctx context.Context, params PromotionParams) (*generated.Promotion, error) {
+	params.Code = normalizePromotionCode(params.Code)
+	if err := validatePromotion(params); err != nil {
+		return nil, err
+	}
+
+	promotion, err := d.repo.CreatePromotion(ctx, generated.CreatePromotionParams{
+		Code:               params.Code,
+		Description:        params.Description,
+		Discounttype:       params.DiscountType,
+		Discountvalue:      params.DiscountValue,
+		Restaurantid:       params.RestaurantID,
+		Minimumorder:       params.MinimumOrder,
+		Validfrom:          params.ValidFrom,
+		Validto:            params.ValidTo,
+		Maxuses:            params.MaxUses,
+		Maxusespercustomer: params.MaxUsesPerCustomer,
+		Firstorderonly:     params.FirstOrderOnly,
+	})
+	if isUniqueViolation(err) {
+		return nil, ErrPromotionExists
+	}
+	if err != nil {
+		return nil, errors.New("failed to create promotion: " + err.Error())
+	}
+	return &promotion, nil
+}
+
+func (d *OrderDomain) GetAllPromotionsDomain(ctx context.Context) ([]generated.Promotion, error) {
+	promotions, err := d.repo.GetAllPromotions(ctx)
+	if err != nil {
+		return nil, errors.New("failed to fetch promotions: " + err.Error())
+	}
+	if promotions == nil {
+		promotions = []generated.Promotion{}
+	}
+	return promotions, nil
+}
+
+// DeactivatePromotionDomain stops a promotion from applying to new orders.
+// Orders placed with it keep their discount.
+func (d *OrderDomain) DeactivatePromotionDomain(ctx context.Context, promotionId int32) error {
+	deactivated, err := d.repo.DeactivatePromotion(ctx, promotionId)
+	if err != nil {
+		return errors.New("failed to deactivate promotion: " + err.Error())
+	}
+	if deactivated == 0 {
+		return ErrPromotionNotFound
+	}
+	return nil
+}
+
+// ValidatePromotionDomain checks whether a promotion code applies to the
+// items of a cart, and returns the discount it gives
+func (d *OrderDomain) ValidatePromotionDomain(ctx context.Context, check PromotionCheck) (*AppliedPromotion, error) {
+	promotion, err := d.repo.GetPromotionByCode(ctx, normalizePromotionCode(check.Code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch promotion: " + err.Error())
+	}
+	return checkPromotion(ctx, d.repo, promotion, check, time.Now())
+}
+
+// discountAmount is the discount of the promotion on the subtotal, rounded to
+// cents. Fixed discounts never exceed the subtotal.
+func discountAmount(promotion generated.Promotion, subtotal float64) float64 {
+	if promotion.Discounttype == DiscountPercentage {
+		return math.Round(subtotal*promotion.Discountvalue) / 100
+	}
+	return math.Min(promotion.Discountvalue, subtotal)
+}
+
+// checkPromotion checks the rules of the promotion at now, counting the
+// orders placed with it through q
+func checkPromotion(ctx context.Context, q *generated.Queries, promotion generated.Promotion, check PromotionCheck, now time.Time) (*AppliedPromotion, error) {
+	switch {
+	case !promotion.Active:
+		return nil, fmt.Errorf("%w: %s is no longer active", ErrPromotionNotApplicable, promotion.Code)
+	case promotion.Validfrom != nil && now.Before(*promotion.Validfrom):
+		return nil, fmt.Errorf("%w: %s is valid from %s", ErrPromotionNotApplicable, promotion.Code, promotion.Validfrom.Format(time.RFC3339))
+	case promotion.Validto != nil && !now.Before(*promotion.Validto):
+		return nil, fmt.Errorf("%w: %s expired at %s", ErrPromotionNotApplicable, promotion.Code, promotion.Validto.Format(time.RFC3339))
+	case promotion.Restaurantid != nil && *promotion.Restaurantid != check.RestaurantID:
+		return nil, fmt.Errorf("%w: %s is not valid at this restaurant", ErrPromotionNotApplicable, promotion.Code)
+	case check.Subtotal < promotion.Minimumorder:
+		return nil, fmt.Errorf("%w: %s requires an order of at least %.2f", ErrPromotionNotApplicable, promotion.Code, promotion.Minimumorder)
+	}
+
+	customerId := check.CustomerID
+	if promotion.Maxuses != nil || promotion.Maxusespercustomer != nil {
+		uses, err := q.CountPromotionUses(ctx, generated.CountPromotionUsesParams{Promotionid: &promotion.ID, Customerid: &customerId})
+		if err != nil {
+			return nil, errors.New("failed to count promotion uses: " + err.Error())
+		}
+		if promotion.Maxuses != nil && uses.Total >= int64(*promotion.Maxuses) {
+			return nil, fmt.Errorf("%w: %s has been used up", ErrPromotionNotApplicable, promotion.Code)
+		}
+		if promotion.Maxusespercustomer != nil && uses.ByCustomer >= int64(*promotion.Maxusespercustomer) {
+			return nil, fmt.Errorf("%w: %s can be used %d times per customer", ErrPromotionNotApplicable, promotion.Code, *promotion.Maxusespercustomer)
+		}
+	}
+	if promotion.Firstorderonly {
+		orders, err := q.CountCustomerOrders(ctx, &customerId)
+		if err != nil {
+			return nil, errors.New("failed to count customer orders: " + err.Error())
+		}
+		if orders > 0 {
+			return nil, fmt.Errorf("%w: %s only applies to a first order", ErrPromotionNotApplicable, promotion.Code)
+		}
+	}
+
+	return &AppliedPromotion{
+		PromotionID:    promotion.ID,
+		Code:           promotion.Code,
+		Description:    promotion.Description,
+		DiscountType:   promotion.Discounttype,
+		DiscountValue:  promotion.Discountvalue,
+		DiscountAmount: discountAmount(promotion, check.Subtotal),
+	}, nil
+}
+
+// redeemPromotion checks the promotion of an order once more as it is placed,
+// with the promotion locked so concurrent orders cannot exceed its limits. The
+// order total is discounted already, and the discount must still be the one
+// shown in the cart.
+func redeemPromotion(ctx context.Context, q *generated.Queries, code string, order generated.CreateOrderParams) (*AppliedPromotion, error) {
+	promotion, err := q.LockPromotionByCode(ctx, normalizePromotionCode(code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrPromotionNotFound
+	}
+	if err != nil {
+		return nil, errors.New("failed to fetch promotion: " + err.Error())
+	}
+
+	check := PromotionCheck{Code: code, Subtotal: order.Totalamount + order.Discountamount}
+	if order.Restaurantid != nil {
+		check.RestaurantID = *order.Restaurantid
+	}
+	if order.Customerid != nil {
+		check.CustomerID = *order.Customerid
+	}
+	applied, err := checkPromotion(ctx, q, promotion, check, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if math.Abs(applied.DiscountAmount-order.Discountamount) >= 0.005 {
+		return nil, fmt.Errorf("%w: the discount of %s is %.2f, not %.2f", ErrPromotionNotApplicable, promotion.Code, applied.DiscountAmount, order.Discountamount)
+	}
+	return applied, nil
+}
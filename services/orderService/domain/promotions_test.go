@@ -0,0 +1,311 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+var promotionColumns = []string{
+	"id", "code", "description", "discounttype", "discountvalue", "restaurantid", "minimumorder", "validfrom", "validto",
+	"maxuses", "maxusespercustomer", "firstorderonly", "active", "createdat",
+}
+
+// testPromotion is 10% off orders of at least 50 at restaurant 2, once per
+// customer, changed by the given function
+type testPromotion struct {
+	discountType       string
+	discountValue      float64
+	restaurantId       *int32
+	validFrom, validTo *time.Time
+	maxUses            *int32
+	maxUsesPerCustomer *int32
+	firstOrderOnly     bool
+	active             bool
+}
+
+func promotionRows(change func(p *testPromotion)) *pgxmock.Rows {
+	p := testPromotion{
+		discountType:       DiscountPercentage,
+		discountValue:      10,
+		restaurantId:       int32Ptr(2),
+		maxUsesPerCustomer: int32Ptr(1),
+		active:             true,
+	}
+	if change != nil {
+		change(&p)
+	}
+	return pgxmock.NewRows(promotionColumns).AddRow(
+		int32(7), "WELCOME10", nil, p.discountType, p.discountValue, p.restaurantId, 50.0, p.validFrom, p.validTo,
+		p.maxUses, p.maxUsesPerCustomer, p.firstOrderOnly, p.active, nil,
+	)
+}
+
+func TestCreatePromotionDomain(t *testing.T) {
+	valid := func() PromotionParams {
+		return PromotionParams{Code: " welcome10 ", DiscountType: DiscountPercentage, DiscountValue: 10}
+	}
+
+	t.Run("Stores codes upper case", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`INSERT INTO Promotion`).
+			WithArgs("WELCOME10", (*string)(nil), DiscountPercentage, 10.0, (*int32)(nil), 0.0,
+				(*time.Time)(nil), (*time.Time)(nil), (*int32)(nil), (*int32)(nil), false).
+			WillReturnRows(promotionRows(nil))
+
+		// Act
+		promotion, err := domain.CreatePromotionDomain(context.Background(), valid())
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if promotion.Code != "WELCOME10" {
+			t.Errorf("got code %s, want WELCOME10", promotion.Code)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects duplicate codes", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`INSERT INTO Promotion`).
+			WithArgs("WELCOME10", (*string)(nil), DiscountPercentage, 10.0, (*int32)(nil), 0.0,
+				(*time.Time)(nil), (*time.Time)(nil), (*int32)(nil), (*int32)(nil), false).
+			WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+
+		// Act
+		_, err := domain.CreatePromotionDomain(context.Background(), valid())
+
+		// Assert
+		if !errors.Is(err, ErrPromotionExists) {
+			t.Errorf("got error %v, want %v", err, ErrPromotionExists)
+		}
+	})
+
+	t.Run("Rejects invalid promotions", func(t *testing.T) {
+		now := time.Now()
+		tests := []struct {
+			name   string
+			change func(p *PromotionParams)
+		}{
+			{"No code", func(p *PromotionParams) { p.Code = " " }},
+			{"Unknown discount type", func(p *PromotionParams) { p.DiscountType = "bogo" }},
+			{"No discount", func(p *PromotionParams) { p.DiscountValue = 0 }},
+			{"More than 100 percent", func(p *PromotionParams) { p.DiscountValue = 101 }},
+			{"Negative minimum order", func(p *PromotionParams) { p.MinimumOrder = -1 }},
+			{"Ends before it starts", func(p *PromotionParams) { p.ValidFrom, p.ValidTo = &now, &now }},
+			{"No uses", func(p *PromotionParams) { p.MaxUses = int32Ptr(0) }},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mock, _, domain := SetupTestMocks(t)
+				defer CloseMocks(mock)
+				params := valid()
+				tt.change(&params)
+
+				_, err := domain.CreatePromotionDomain(context.Background(), params)
+
+				if !errors.Is(err, ErrInvalidPromotion) {
+					t.Errorf("got error %v, want %v", err, ErrInvalidPromotion)
+				}
+			})
+		}
+	})
+}
+
+func TestValidatePromotionDomain(t *testing.T) {
+	yesterday := time.Now().Add(-24 * time.Hour)
+	tomorrow := time.Now().Add(24 * time.Hour)
+
+	tests := []struct {
+		name         string
+		change       func(p *testPromotion)
+		restaurantId int32
+		subtotal     float64
+		usesByOthers int64
+		usesByCustom int64
+		orders       *int64
+		wantDiscount float64
+		wantErr      error
+	}{
+		{"Percentage off", nil, 2, 125, 0, 0, nil, 12.5, nil},
+		{"Fixed amount", func(p *testPromotion) { p.discountType, p.discountValue = DiscountFixed, 15 }, 2, 60, 0, 0, nil, 15, nil},
+		{"Fixed amount up to the subtotal", func(p *testPromotion) { p.discountType, p.discountValue, p.restaurantId = DiscountFixed, 80, nil }, 9, 60, 0, 0, nil, 60, nil},
+		{"Platform-wide", func(p *testPromotion) { p.restaurantId = nil }, 9, 100, 0, 0, nil, 10, nil},
+		{"Other restaurant", nil, 9, 100, 0, 0, nil, 0, ErrPromotionNotApplicable},
+		{"Below the minimum order", nil, 2, 49.99, 0, 0, nil, 0, ErrPromotionNotApplicable},
+		{"Not started", func(p *testPromotion) { p.validFrom = &tomorrow }, 2, 100, 0, 0, nil, 0, ErrPromotionNotApplicable},
+		{"Expired", func(p *testPromotion) { p.validTo = &yesterday }, 2, 100, 0, 0, nil, 0, ErrPromotionNotApplicable},
+		{"Inactive", func(p *testPromotion) { p.active = false }, 2, 100, 0, 0, nil, 0, ErrPromotionNotApplicable},
+		{"Used by the customer", nil, 2, 100, 3, 1, nil, 0, ErrPromotionNotApplicable},
+		{"Used up", func(p *testPromotion) { p.maxUses = int32Ptr(3) }, 2, 100, 3, 0, nil, 0, ErrPromotionNotApplicable},
+		{"First order", func(p *testPromotion) { p.firstOrderOnly = true }, 2, 100, 0, 0, int64Ptr(0), 10, nil},
+		{"Not the first order", func(p *testPromotion) { p.firstOrderOnly = true }, 2, 100, 0, 0, int64Ptr(2), 0, ErrPromotionNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			mock.ExpectQuery(`FROM\s+Promotion\s+WHERE\s+Code = \$1`).
+				WithArgs("WELCOME10").
+				WillReturnRows(promotionRows(tt.change))
+			mock.ExpectQuery(`COUNT\(\*\) AS total`).
+				WithArgs(int32Ptr(7), int32Ptr(1)).
+				WillReturnRows(pgxmock.NewRows([]string{"total", "by_customer"}).AddRow(tt.usesByOthers+tt.usesByCustom, tt.usesByCustom))
+			if tt.orders != nil {
+				mock.ExpectQuery(`WHERE\s+CustomerID = \$1\s+AND Status NOT IN`).
+					WithArgs(int32Ptr(1)).
+					WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(*tt.orders))
+			}
+
+			// Act
+			applied, err := domain.ValidatePromotionDomain(context.Background(), PromotionCheck{
+				Code:         "welcome10",
+				RestaurantID: tt.restaurantId,
+				CustomerID:   1,
+				Subtotal:     tt.subtotal,
+			})
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && applied.DiscountAmount != tt.wantDiscount {
+				t.Errorf("got discount %v, want %v", applied.DiscountAmount, tt.wantDiscount)
+			}
+		})
+	}
+
+	t.Run("Unknown code", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM\s+Promotion\s+WHERE\s+Code = \$1`).
+			WithArgs("NOPE").
+			WillReturnError(pgx.ErrNoRows)
+
+		_, err := domain.ValidatePromotionDomain(context.Background(), PromotionCheck{Code: "nope"})
+
+		if !errors.Is(err, ErrPromotionNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrPromotionNotFound)
+		}
+	})
+}
+
+func TestCreateOrderDomainWithPromotion(t *testing.T) {
+	orderParams := func(discount float64) generated.CreateOrderParams {
+		return generated.CreateOrderParams{
+			Totalamount:    112.5,
+			Vatamount:      22.5,
+			Status:         StatusPending,
+			Customerid:     int32Ptr(1),
+			Restaurantid:   int32Ptr(2),
+			Discountamount: discount,
+		}
+	}
+
+	items := []OrderLineItem{{MenuItemId: 7, Name: "Cheese Pizza", Price: 62.5, Quantity: 2}}
+	expectItem := func(mock pgxmock.PgxPoolIface, orderId int32) {
+		mock.ExpectQuery(`INSERT INTO OrderItem`).
+			WithArgs(orderId, "Cheese Pizza", 62.5, 2.0, int32Ptr(7), []byte("[]"), []byte("[]"), []string{}, []string{}, (*int32)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(1)))
+	}
+
+	t.Run("Records the promotion, with the fee on the discounted amount", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		amountExcludingVAT := 112.5 - 22.5
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM\s+Promotion\s+WHERE\s+Code = \$1\s+FOR UPDATE`).
+			WithArgs("WELCOME10").
+			WillReturnRows(promotionRows(nil))
+		mock.ExpectQuery(`COUNT\(\*\) AS total`).
+			WithArgs(int32Ptr(7), int32Ptr(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"total", "by_customer"}).AddRow(int64(4), int64(0)))
+		mock.ExpectQuery(`INSERT INTO Fee`).
+			WithArgs(float64Ptr(0.06), float64Ptr(amountExcludingVAT*0.06), stringPtr("some description")).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(3)))
+		mock.ExpectQuery(`INSERT INTO "Order"`).
+			WithArgs(112.5, 22.5, StatusPending, (*time.Time)(nil), (*string)(nil), int32Ptr(1), int32Ptr(2),
+				(*int32)(nil), (*int32)(nil), (*int32)(nil), int32Ptr(3), 0.0, 12.5, int32Ptr(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(9)))
+		expectItem(mock, 9)
+		mock.ExpectCommit()
+
+		// Act
+		orderId, err := domain.CreateOrderDomain(context.Background(), orderParams(12.5), items, stringPtr("welcome10"))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if orderId != 9 {
+			t.Errorf("got order %d, want 9", orderId)
+		}
+		if queued := <-domain.etaRequests; queued != 9 {
+			t.Errorf("got ETA queued for order %d, want 9", queued)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Places the order rejected when the discount differs from the cart", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		amountExcludingVAT := 112.5 - 22.5
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM\s+Promotion\s+WHERE\s+Code = \$1\s+FOR UPDATE`).
+			WithArgs("WELCOME10").
+			WillReturnRows(promotionRows(nil))
+		mock.ExpectQuery(`COUNT\(\*\) AS total`).
+			WithArgs(int32Ptr(7), int32Ptr(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"total", "by_customer"}).AddRow(int64(0), int64(0)))
+		mock.ExpectQuery(`INSERT INTO Fee`).
+			WithArgs(float64Ptr(0.06), float64Ptr(amountExcludingVAT*0.06), stringPtr("some description")).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(3)))
+		mock.ExpectQuery(`INSERT INTO "Order"`).
+			WithArgs(112.5, 22.5, StatusRejected, (*time.Time)(nil), (*string)(nil), int32Ptr(1), int32Ptr(2),
+				(*int32)(nil), (*int32)(nil), (*int32)(nil), int32Ptr(3), 0.0, 20.0, (*int32)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(9)))
+		expectItem(mock, 9)
+		mock.ExpectExec(`INSERT INTO OrderResponse`).
+			WithArgs(int32(9), false, pgxmock.AnyArg(), false).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		// Act
+		orderId, err := domain.CreateOrderDomain(context.Background(), orderParams(20), items, stringPtr("WELCOME10"))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if orderId != 9 {
+			t.Errorf("got order %d, want 9", orderId)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
@@ -21,18 +21,40 @@ func NewDeliveryAgentHandler(domain *domain.DeliveryAgentDomain) *DeliveryAgentH
 // GetAllDeliveryAgents godoc
 //
 // @Summary Get all deliveryAgents
-// @Description Fetches a list of all Delivery Agents from the database
+// @Description Fetches a page of Delivery Agents, newest first unless sorted otherwise. Pass next_cursor as cursor to get the next page. Only admins may list delivery agents.
 // @Tags DeliveryAgent CRUD
 // @Produce application/json
-// @Success 200 {array} generated.Deliveryagent
+// @Param availability query bool false "Only available or unavailable agents"
+// @Param min_rating query number false "Only agents rated at least this"
+// @Param sort query string false "id, rating or name, prefixed by - for descending. Defaults to -id"
+// @Param limit query int false "Agents per page, at most 100, defaults to 20"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} domain.Page[generated.Deliveryagent]
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/delivery-agent [get]
 func (h *DeliveryAgentHandler) GetAllDeliveryAgents() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		deliveryAgent, err := h.domain.GetAllDeliveryAgentsDomain(ctx)
+		query := queryParser{r: r}
+		list := query.listParams()
+		filter := domain.DeliveryAgentFilter{
+			Availability: query.bool("availability"),
+			MinRating:    query.float("min_rating"),
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		deliveryAgent, err := h.domain.GetAllDeliveryAgentsDomain(ctx, filter, list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to get deliveryAgent", http.StatusInternalServerError)
 			log.Println(err)
@@ -49,12 +71,14 @@ func (h *DeliveryAgentHandler) GetAllDeliveryAgents() http.HandlerFunc {
 // GetDeliveryAgentById godoc
 //
 // @Summary Get deliveryAgent by deliveryAgent id
-// @Description Fetches a deliveryAgent based on the id from the database
+// @Description Fetches a deliveryAgent based on the id from the database. Delivery agents may only fetch themselves.
 // @Tags DeliveryAgent CRUD
 // @Produce application/json
 // @Param deliveryAgentId path string true "DeliveryAgent ID"
 // @Success 200 {object} generated.Deliveryagent
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/delivery-agent/{deliveryAgentId} [get]
 func (h *DeliveryAgentHandler) GetDeliveryAgentById() http.HandlerFunc {
@@ -90,13 +114,15 @@ func (h *DeliveryAgentHandler) GetDeliveryAgentById() http.HandlerFunc {
 // CreateDeliveryAgent godoc
 //
 // @Summary Create a new deliveryAgent
-// @Description Creates a new deliveryAgent entry in the database
+// @Description Creates a new deliveryAgent entry in the database. Only admins may create delivery agents.
 // @Tags DeliveryAgent CRUD
 // @Accept  application/json
 // @Produce application/json
 // @Param deliveryAgent body generated.CreateDeliveryAgentParams true "DeliveryAgent object"
 // @Success 201 {object} generated.Deliveryagent
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/delivery-agent [post]
 func (h *DeliveryAgentHandler) CreateDeliveryAgent() http.HandlerFunc {
@@ -122,4 +148,141 @@ func (h *DeliveryAgentHandler) CreateDeliveryAgent() http.HandlerFunc {
 		w.WriteHeader(http.StatusCreated)
 	}
 }
-	
+
+// RecordLocation godoc
+//
+// @Summary Record a delivery agent location
+// @Description Stores a GPS ping from a delivery agent. With an order_id the location is also streamed to the order's subscribers.
+// @Tags DeliveryAgent Location
+// @Accept  application/json
+// @Produce application/json
+// @Param deliveryAgentId path string true "DeliveryAgent ID"
+// @Param location body domain.LocationParams true "Location ping"
+// @Success 201 {object} domain.AgentLocation
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Order is not assigned to the delivery agent"
+// @Failure 404 {string} string "DeliveryAgent or order not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/delivery-agent/{deliveryAgentId}/location [post]
+func (h *DeliveryAgentHandler) RecordLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		deliveryAgentId, err := strconv.Atoi(r.PathValue("deliveryAgentId"))
+		if err != nil {
+			http.Error(w, "Invalid DeliveryAgent ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.LocationParams
+		err = json.NewDecoder(r.Body).Decode(&params)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			log.Println(err)
+			return
+		}
+
+		location, err := h.domain.RecordLocationDomain(ctx, int32(deliveryAgentId), params)
+		if err != nil {
+			switch err.Error() {
+			case "invalid coordinates":
+				http.Error(w, "Latitude must be between -90 and 90 and longitude between -180 and 180", http.StatusBadRequest)
+			case "delivery agent not found":
+				http.Error(w, "DeliveryAgent not found", http.StatusNotFound)
+			case "order not found":
+				http.Error(w, "Order not found", http.StatusNotFound)
+			case "order is not assigned to the delivery agent":
+				http.Error(w, "Order is not assigned to the delivery agent", http.StatusForbidden)
+			default:
+				http.Error(w, "Failed to record location", http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(location)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// GetLatestLocation godoc
+//
+// @Summary Get the latest delivery agent location
+// @Description Fetches the most recent GPS ping of a delivery agent
+// @Tags DeliveryAgent Location
+// @Produce application/json
+// @Param deliveryAgentId path string true "DeliveryAgent ID"
+// @Success 200 {object} domain.AgentLocation
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "No location recorded"
+// @Router /api/delivery-agent/{deliveryAgentId}/location [get]
+func (h *DeliveryAgentHandler) GetLatestLocation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		deliveryAgentId, err := strconv.Atoi(r.PathValue("deliveryAgentId"))
+		if err != nil {
+			http.Error(w, "Invalid DeliveryAgent ID", http.StatusBadRequest)
+			return
+		}
+
+		location, err := h.domain.GetLatestLocationDomain(ctx, int32(deliveryAgentId))
+		if err != nil {
+			http.Error(w, "No location recorded for DeliveryAgent", http.StatusNotFound)
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(location)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// GetDeliveryAgentRatings godoc
+//
+// @Summary Get the ratings of a delivery agent
+// @Description Fetches the average rating, the number of ratings per star and the latest comments of a delivery agent
+// @Tags DeliveryAgent CRUD
+// @Produce application/json
+// @Param deliveryAgentId path string true "DeliveryAgent ID"
+// @Success 200 {object} domain.AgentRatings
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "DeliveryAgent not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/delivery-agent/{deliveryAgentId}/ratings [get]
+func (h *DeliveryAgentHandler) GetDeliveryAgentRatings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		deliveryAgentId, err := strconv.Atoi(r.PathValue("deliveryAgentId"))
+		if err != nil {
+			http.Error(w, "Invalid DeliveryAgent ID", http.StatusBadRequest)
+			return
+		}
+
+		ratings, err := h.domain.GetDeliveryAgentRatingsDomain(ctx, int32(deliveryAgentId))
+		if err != nil {
+			if err.Error() == "delivery agent not found" {
+				http.Error(w, "DeliveryAgent not found", http.StatusNotFound)
+			} else {
+				http.Error(w, "Failed to get ratings", http.StatusInternalServerError)
+			}
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(ratings)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
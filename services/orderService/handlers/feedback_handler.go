@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	"github.com/rasm445f/soft-exam-2/domain"
 )
@@ -21,18 +23,53 @@ func NewFeedbackHandler(domain *domain.FeedbackDomain) *FeedbackHandler {
 // GetAllFeedbacks godoc
 //
 // @Summary Get all feedbacks
-// @Description Fetches a list of all feedbacks from the database
+// @Description Fetches a page of feedbacks, newest first unless sorted otherwise. Pass next_cursor as cursor to get the next page. Customers only get the feedback they gave, restaurant staff the feedback on their restaurant and delivery agents the feedback on their deliveries.
 // @Tags Feedback CRUD
 // @Produce application/json
-// @Success 200 {array} generated.Feedback
+// @Param customer_id query int false "Only feedback given by the customer"
+// @Param restaurant_id query int false "Only feedback on orders from the restaurant"
+// @Param delivery_agent_id query int false "Only feedback on orders of the delivery agent"
+// @Param sort query string false "id or created_at, prefixed by - for descending. Defaults to -id"
+// @Param limit query int false "Feedbacks per page, at most 100, defaults to 20"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} domain.Page[generated.Feedback]
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/feedbacks [get]
 func (h *FeedbackHandler) GetAllFeedbacks() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		feedbacks, err := h.domain.GetAllFeedbacksDomain(ctx)
+		query := queryParser{r: r}
+		list := query.listParams()
+		filter := domain.FeedbackFilter{
+			CustomerID:      query.int32("customer_id"),
+			RestaurantID:    query.int32("restaurant_id"),
+			DeliveryAgentID: query.int32("delivery_agent_id"),
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Only admins see all feedback, the others the feedback on orders they
+		// took part in
+		identity, _ := auth.IdentityFrom(ctx)
+		switch identity.Role {
+		case auth.RoleCustomer:
+			filter.CustomerID = &identity.UserID
+		case auth.RoleRestaurant:
+			filter.RestaurantID = &identity.RestaurantID
+		case auth.RoleDeliveryAgent:
+			filter.DeliveryAgentID = &identity.DeliveryAgentID
+		}
+
+		feedbacks, err := h.domain.GetAllFeedbacksDomain(ctx, filter, list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to get feedbacks", http.StatusInternalServerError)
 			log.Println(err)
@@ -49,12 +86,15 @@ func (h *FeedbackHandler) GetAllFeedbacks() http.HandlerFunc {
 // GetFeedbackByOrderId godoc
 //
 // @Summary Get feedback by order id
-// @Description Fetches a feedback based on the order id from the database
+// @Description Fetches a feedback based on the order id from the database. Only the order's customer, restaurant or delivery agent may fetch it.
 // @Tags Feedback CRUD
 // @Produce application/json
 // @Param orderId path string true "Order ID"
 // @Success 200 {object} generated.Feedback
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Feedback not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/feedbacks/{orderId} [get]
 func (h *FeedbackHandler) GetFeedbackByOrderId() http.HandlerFunc {
@@ -73,9 +113,18 @@ func (h *FeedbackHandler) GetFeedbackByOrderId() http.HandlerFunc {
 			return
 		}
 
+		order, err := h.domain.GetFeedbackOrderDomain(ctx, int32(orderId))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if !auth.Check(w, r, takesPartIn(r, order)) {
+			return
+		}
+
 		feedback, err := h.domain.GetFeedbackByOrderIdDomain(ctx, int32(orderId))
 		if err != nil {
-			http.Error(w, "Feedback not found", http.StatusNotFound)
+			http.Error(w, err.Error(), http.StatusNotFound)
 			log.Println(err)
 			return
 		}
@@ -97,6 +146,10 @@ func (h *FeedbackHandler) GetFeedbackByOrderId() http.HandlerFunc {
 // @Param feedback body generated.CreateFeedbackParams true "Feedback object"
 // @Success 201 {object} generated.Feedback
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the ordering customer"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "Order not delivered or feedback already given"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/feedback [post]
 func (h *FeedbackHandler) CreateFeedback() http.HandlerFunc {
@@ -111,10 +164,14 @@ func (h *FeedbackHandler) CreateFeedback() http.HandlerFunc {
 			return
 		}
 
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, feedbackParams.Customerid == identity.UserID) {
+			return
+		}
+
 		_, err = h.domain.CreateFeedbackDomain(ctx, feedbackParams)
 		if err != nil {
-			http.Error(w, "Failed to create feedback", http.StatusInternalServerError)
-			log.Println(err)
+			writeFeedbackError(w, err, "Failed to create feedback")
 			return
 		}
 
@@ -122,3 +179,70 @@ func (h *FeedbackHandler) CreateFeedback() http.HandlerFunc {
 		w.WriteHeader(http.StatusCreated)
 	}
 }
+
+// UpdateFeedback godoc
+//
+// @Summary Update the feedback of an order
+// @Description Changes the ratings or comment of a feedback. Only the customer who gave it may change it, within 24 hours.
+// @Tags Feedback CRUD
+// @Accept  application/json
+// @Param orderId path string true "Order ID"
+// @Param feedback body domain.UpdateFeedbackParams true "Changed fields"
+// @Success 204 "No content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Not the customer who gave the feedback"
+// @Failure 404 {string} string "Feedback not found"
+// @Failure 409 {string} string "Edit window is over"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/feedback/{orderId} [patch]
+func (h *FeedbackHandler) UpdateFeedback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		orderId, err := strconv.Atoi(r.PathValue("orderId"))
+		if err != nil {
+			http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.UpdateFeedbackParams
+		err = json.NewDecoder(r.Body).Decode(&params)
+		if err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			log.Println(err)
+			return
+		}
+
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, params.Customerid == identity.UserID) {
+			return
+		}
+
+		err = h.domain.UpdateFeedbackDomain(ctx, int32(orderId), params)
+		if err != nil {
+			writeFeedbackError(w, err, "Failed to update feedback")
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeFeedbackError maps the feedback domain errors to status codes
+func writeFeedbackError(w http.ResponseWriter, err error, fallback string) {
+	log.Println(err)
+	switch {
+	case errors.Is(err, domain.ErrInvalidRating):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrFeedbackForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrFeedbackNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrOrderNotDelivered), errors.Is(err, domain.ErrFeedbackExists),
+		errors.Is(err, domain.ErrFeedbackEditWindowOver):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -3,11 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	"github.com/rasm445f/soft-exam-2/domain"
@@ -24,20 +28,60 @@ func NewOrderHandler(domain *domain.OrderDomain) *OrderHandler {
 // GetAllOrders godoc
 //
 // @Summary Get all orders
-// @Description Fetches a list of all orders from the database
+// @Description Fetches a page of orders, newest first unless sorted otherwise. Pass next_cursor as cursor to get the next page. Customers only get their own orders, restaurant staff the orders of their restaurant and delivery agents their deliveries.
 // @Tags Order CRUD
 // @Produce application/json
-// @Success 200 {array} generated.Order
+// @Param status query string false "Only orders with the status"
+// @Param customer_id query int false "Only orders of the customer"
+// @Param restaurant_id query int false "Only orders from the restaurant"
+// @Param delivery_agent_id query int false "Only orders of the delivery agent"
+// @Param from query string false "Only orders placed at or after this time (RFC 3339)"
+// @Param to query string false "Only orders placed before this time (RFC 3339)"
+// @Param sort query string false "id, timestamp or total_amount, prefixed by - for descending. Defaults to -timestamp"
+// @Param limit query int false "Orders per page, at most 100, defaults to 20"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} domain.Page[generated.Order]
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/orders [get]
 func (h *OrderHandler) GetAllOrders() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		orders, err := h.domain.GetAllOrdersDomain(ctx)
+		query := queryParser{r: r}
+		list := query.listParams()
+		filter := domain.OrderFilter{
+			Status:          query.string("status"),
+			CustomerID:      query.int32("customer_id"),
+			RestaurantID:    query.int32("restaurant_id"),
+			DeliveryAgentID: query.int32("delivery_agent_id"),
+			From:            query.time("from"),
+			To:              query.time("to"),
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Only admins see every order, the others the orders they take part in
+		identity, _ := auth.IdentityFrom(ctx)
+		switch identity.Role {
+		case auth.RoleCustomer:
+			filter.CustomerID = &identity.UserID
+		case auth.RoleRestaurant:
+			filter.RestaurantID = &identity.RestaurantID
+		case auth.RoleDeliveryAgent:
+			filter.DeliveryAgentID = &identity.DeliveryAgentID
+		}
+
+		orders, err := h.domain.GetAllOrdersDomain(ctx, filter, list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
-			http.Error(w, "Failed to fetch restaurants", http.StatusInternalServerError)
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
@@ -52,12 +96,14 @@ func (h *OrderHandler) GetAllOrders() http.HandlerFunc {
 // GetOrderById godoc
 //
 // @Summary Get order by id
-// @Description Fetches an order based on the id from the database
+// @Description Fetches an order based on the id from the database. Only the order's customer, restaurant or delivery agent may fetch it.
 // @Tags Order CRUD
 // @Produce application/json
 // @Param id path string true "Order ID"
 // @Success 200 {object} generated.Order
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/orders/{id} [get]
 func (h *OrderHandler) GetOrderById() http.HandlerFunc {
@@ -82,6 +128,9 @@ func (h *OrderHandler) GetOrderById() http.HandlerFunc {
 			log.Println(err)
 			return
 		}
+		if !auth.Check(w, r, takesPartIn(r, order)) {
+			return
+		}
 
 		res, _ := json.Marshal(order)
 		w.Header().Set("Content-Type", "application/json")
@@ -90,14 +139,171 @@ func (h *OrderHandler) GetOrderById() http.HandlerFunc {
 	}
 }
 
+// Interval between SSE comments that keep idle connections (and proxies) open
+const orderEventsHeartbeat = 15 * time.Second
+
+// orderParticipant is who the caller is to orders. Restaurant staff take
+// part as their restaurant and delivery agents as their agent; admins take
+// part in none.
+func orderParticipant(identity auth.Identity) domain.OrderParticipant {
+	switch identity.Role {
+	case auth.RoleCustomer:
+		return domain.OrderParticipant{Role: domain.RoleCustomer, ID: identity.UserID}
+	case auth.RoleRestaurant:
+		return domain.OrderParticipant{Role: domain.RoleRestaurant, ID: identity.RestaurantID}
+	case auth.RoleDeliveryAgent:
+		return domain.OrderParticipant{Role: domain.RoleDeliveryAgent, ID: identity.DeliveryAgentID}
+	}
+	return domain.OrderParticipant{}
+}
+
+// takesPartIn reports whether the caller is the order's customer, restaurant
+// or delivery agent
+func takesPartIn(r *http.Request, order *generated.Order) bool {
+	identity, _ := auth.IdentityFrom(r.Context())
+	return domain.CanFollowOrder(order, orderParticipant(identity))
+}
+
+// writeOrderEvent writes the event in SSE format. Live-only events, such as
+// agent locations, have no ID and do not move the client's Last-Event-ID.
+func writeOrderEvent(w io.Writer, event domain.OrderEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.ID == 0 {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// OrderEvents godoc
+//
+// @Summary Stream order events
+// @Description Streams status changes, ETA updates and delivery agent locations of an order as Server-Sent Events. Send the Last-Event-ID header to resume after a reconnect. Only the order's customer, restaurant or delivery agent may subscribe.
+// @Tags Order Tracking
+// @Produce text/event-stream
+// @Param orderId path int true "Order ID"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Success 200 {object} domain.OrderEvent
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/orders/{orderId}/events [get]
+func (h *OrderHandler) OrderEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		orderId, err := strconv.Atoi(r.PathValue("orderId"))
+		if err != nil {
+			http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+			return
+		}
+
+		var lastEventId int64
+		lastEventIdStr := r.Header.Get("Last-Event-ID")
+		if lastEventIdStr == "" {
+			lastEventIdStr = r.URL.Query().Get("lastEventId")
+		}
+		if lastEventIdStr != "" {
+			lastEventId, err = strconv.ParseInt(lastEventIdStr, 10, 64)
+			if err != nil {
+				http.Error(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		order, err := h.domain.GetOrderByIdDomain(ctx, int32(orderId))
+		if err != nil {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			log.Println(err)
+			return
+		}
+
+		if !auth.Check(w, r, takesPartIn(r, order)) {
+			return
+		}
+
+		// Subscribe before replaying, so no event falls between the two
+		events, unsubscribe, err := h.domain.SubscribeOrderEventsDomain(order.ID)
+		if err != nil {
+			http.Error(w, "Failed to subscribe to order events", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+		defer unsubscribe()
+
+		missed, err := h.domain.GetOrderEventsAfterDomain(ctx, order.ID, lastEventId)
+		if err != nil {
+			http.Error(w, "Failed to fetch order events", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		for _, event := range missed {
+			if err := writeOrderEvent(w, event); err != nil {
+				return
+			}
+			lastEventId = event.ID
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(orderEventsHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			case event, ok := <-events:
+				if !ok {
+					// Dropped for being too slow, the client reconnects with Last-Event-ID
+					return
+				}
+				if event.ID != 0 && event.ID <= lastEventId {
+					continue
+				}
+				if err := writeOrderEvent(w, event); err != nil {
+					return
+				}
+				if event.ID != 0 {
+					lastEventId = event.ID
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
+
 type UpdateOrderStatusRequest struct {
-	Status string `json:"status" example:"Pending/On its way/Delivered"`
+	Status string `json:"status" example:"On its way/Delivered/Cancelled"`
 }
 
 // UpdateOrderStatus godoc
 //
 // @Summary Update Order Status
-// @Description Updates the status of an order
+// @Description Updates the status of an order. Restaurants accept and reject pending orders through their inbox; accepted orders are then on their way and delivered, and may be cancelled until they are on their way. The order's restaurant cancels it, its delivery agent reports it on its way and delivered.
 // @Tags Order CRUD
 // @Accept application/json
 // @Produce application/json
@@ -105,6 +311,10 @@ type UpdateOrderStatusRequest struct {
 // @Param status body UpdateOrderStatusRequest true "New Order Status"
 // @Success 200 {string} string "Order status updated successfully"
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "Status change not allowed from the current status"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/order/status/{orderId} [patch]
 func (h *OrderHandler) UpdateOrderStatus() http.HandlerFunc {
@@ -128,7 +338,7 @@ func (h *OrderHandler) UpdateOrderStatus() http.HandlerFunc {
 		}
 
 		// Validate the new status
-		validStates := []string{"Pending", "On its way", "Delivered"}
+		validStates := []string{"On its way", "Delivered", "Cancelled"}
 		isValid := false
 		for _, validStatus := range validStates {
 			if requestPayload.Status == validStatus {
@@ -137,7 +347,16 @@ func (h *OrderHandler) UpdateOrderStatus() http.HandlerFunc {
 			}
 		}
 		if !isValid {
-			http.Error(w, "Invalid status value, you can only choose between: Pending/On its way/Delivered", http.StatusBadRequest)
+			http.Error(w, "Invalid status value, you can only choose between: On its way/Delivered/Cancelled", http.StatusBadRequest)
+			return
+		}
+
+		order, err := h.domain.GetOrderByIdDomain(ctx, int32(orderId))
+		if err != nil {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if !auth.Check(w, r, mayChangeStatus(r, order, requestPayload.Status)) {
 			return
 		}
 
@@ -146,6 +365,8 @@ func (h *OrderHandler) UpdateOrderStatus() http.HandlerFunc {
 		if err != nil {
 			if err.Error() == "order not found" {
 				http.Error(w, "Order not found", http.StatusNotFound)
+			} else if errors.Is(err, domain.ErrInvalidTransition) {
+				http.Error(w, err.Error(), http.StatusConflict)
 			} else {
 				http.Error(w, "Failed to update order status", http.StatusInternalServerError)
 			}
@@ -158,15 +379,30 @@ func (h *OrderHandler) UpdateOrderStatus() http.HandlerFunc {
 	}
 }
 
+// mayChangeStatus reports whether the caller may move the order to the
+// status. The restaurant cancels its orders, the assigned delivery agent
+// reports them on their way and delivered.
+func mayChangeStatus(r *http.Request, order *generated.Order, status string) bool {
+	identity, _ := auth.IdentityFrom(r.Context())
+	participant := orderParticipant(identity)
+	switch status {
+	case domain.StatusCancelled:
+		return participant.Role == domain.RoleRestaurant && domain.CanFollowOrder(order, participant)
+	case domain.StatusOnItsWay, domain.StatusDelivered:
+		return participant.Role == domain.RoleDeliveryAgent && domain.CanFollowOrder(order, participant)
+	}
+	return false
+}
+
 type UpdateOrderStatusRequestWithDeliveryAgentId struct {
 	DeliveryAgentId int32  `json:"id"`
-	Status          string `json:"status" example:"Pending/On its way/Delivered"`
+	Status          string `json:"status" example:"On its way/Delivered"`
 }
 
 // UpdateOrderStatus godoc
 //
 // @Summary Update Order Status
-// @Description Updates the status of an order
+// @Description Sends an accepted order on its way with the calling delivery agent, or delivers it. Only the agent carrying an order delivers it, and orders are cancelled by their restaurant.
 // @Tags Order CRUD
 // @Accept application/json
 // @Produce application/json
@@ -174,6 +410,10 @@ type UpdateOrderStatusRequestWithDeliveryAgentId struct {
 // @Param status body UpdateOrderStatusRequestWithDeliveryAgentId true "New Order Status"
 // @Success 200 {string} string "Order status updated successfully"
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "Status change not allowed from the current status"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/order/status-agent/{orderId} [patch]
 func (h *OrderHandler) UpdateOrderStatusWithDeliveryAgentId() http.HandlerFunc {
@@ -198,7 +438,7 @@ func (h *OrderHandler) UpdateOrderStatusWithDeliveryAgentId() http.HandlerFunc {
 		}
 
 		// Validate the new status
-		validStates := []string{"Pending", "On its way", "Delivered"}
+		validStates := []string{"On its way", "Delivered"}
 		isValid := false
 		for _, validStatus := range validStates {
 			if requestPayload.Status == validStatus {
@@ -207,7 +447,12 @@ func (h *OrderHandler) UpdateOrderStatusWithDeliveryAgentId() http.HandlerFunc {
 			}
 		}
 		if !isValid {
-			http.Error(w, "Invalid status value, you can only choose between: Pending/On its way/Delivered", http.StatusBadRequest)
+			http.Error(w, "Invalid status value, you can only choose between: On its way/Delivered", http.StatusBadRequest)
+			return
+		}
+
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, requestPayload.DeliveryAgentId == identity.DeliveryAgentID) {
 			return
 		}
 
@@ -216,6 +461,10 @@ func (h *OrderHandler) UpdateOrderStatusWithDeliveryAgentId() http.HandlerFunc {
 		if err != nil {
 			if err.Error() == "order not found" {
 				http.Error(w, "Order not found", http.StatusNotFound)
+			} else if errors.Is(err, domain.ErrNotAssignedAgent) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+			} else if errors.Is(err, domain.ErrInvalidTransition) {
+				http.Error(w, err.Error(), http.StatusConflict)
 			} else {
 				http.Error(w, "Failed to update order status", http.StatusInternalServerError)
 			}
@@ -231,11 +480,13 @@ func (h *OrderHandler) UpdateOrderStatusWithDeliveryAgentId() http.HandlerFunc {
 // DeleteOrder godoc
 //
 // @Summary Delete an order
-// @Description Deletes an order by its id from the database
+// @Description Deletes an order by its id from the database. Only admins may delete orders.
 // @Tags Order CRUD
 // @Param id path int true "Order ID"
 // @Success 200 {string} string "Order deleted successfully"
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/orders/{id} [delete]
 func (h *OrderHandler) DeleteOrder() http.HandlerFunc {
@@ -286,11 +537,13 @@ func toTimeNowPtr() *time.Time {
 // ConsumeOrder godoc
 //
 //	@Summary		Consume Order for a Customer
-//	@Description	Consume the created order for customer
+//	@Description	Consume the created order for customer. Only admins may start consuming.
 //	@Tags			Order Broker
 //	@Produce		application/json
 //	@Success		200	{string}	string	"Order Consumed Successfully"
 //	@Failure		400	{string}	string	"Bad request"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		403	{string}	string	"Forbidden"
 //	@Failure		500	{string}	string	"Internal server error"
 //	@Router			/api/order/consume [get]
 func (h *OrderHandler) ConsumeOrder() http.HandlerFunc {
@@ -311,12 +564,17 @@ func (h *OrderHandler) ConsumeOrder() http.HandlerFunc {
 
 			// Unmarshal JSON into a struct making the Redis payload from ShoppingCart
 			var payload struct {
-				Customerid   int                               `json:"customer_id"`
-				Restaurantid int                               `json:"restaurant_id"`
-				Totalamount  float64                           `json:"total_amount"`
-				Vatamount    float64                           `json:"vat_amount"`
-				Comment      string                            `json:"comment"`
-				Items        []generated.CreateOrderItemParams `json:"items"`
+				Customerid   int                    `json:"customer_id"`
+				Restaurantid int                    `json:"restaurant_id"`
+				Totalamount  float64                `json:"total_amount"`
+				Vatamount    float64                `json:"vat_amount"`
+				DeliveryFee  float64                `json:"delivery_fee"`
+				Comment      string                 `json:"comment"`
+				Items        []domain.OrderLineItem `json:"items"`
+				Discount     *struct {
+					Code   string  `json:"code"`
+					Amount float64 `json:"amount"`
+				} `json:"discount"`
 			}
 
 			if err := json.Unmarshal(payloadBytes, &payload); err != nil {
@@ -338,13 +596,22 @@ func (h *OrderHandler) ConsumeOrder() http.HandlerFunc {
 				Paymentid:       nil, // Not processed yet
 				Bonusid:         nil, // No bonus assigned
 				Feeid:           nil, // No fees applied
+				Deliveryfee:     payload.DeliveryFee,
+			}
+
+			// The total is discounted already, the promotion is redeemed with
+			// the order, which is rejected when the promotion no longer applies
+			var promotionCode *string
+			if payload.Discount != nil {
+				orderParams.Discountamount = payload.Discount.Amount
+				promotionCode = &payload.Discount.Code
 			}
 
 			// Create context
 			ctx := context.Background()
 
-			// Call the CreateOrder domain function
-			orderid, err := h.domain.CreateOrderDomain(ctx, orderParams)
+			// Call the CreateOrder domain function, which creates the items with the order
+			orderid, err := h.domain.CreateOrderDomain(ctx, orderParams, payload.Items, promotionCode)
 			if err != nil {
 				log.Printf("Failed to create order: %v", err)
 				return
@@ -352,25 +619,6 @@ func (h *OrderHandler) ConsumeOrder() http.HandlerFunc {
 
 			// Log success for the order creation
 			log.Printf("Successfully created order with ID: %d for customer: %d", orderid, payload.Customerid)
-
-			// Create order items for the created order
-			for _, item := range payload.Items {
-				itemParams := generated.CreateOrderItemParams{
-					Orderid:  orderid,
-					Name:     item.Name,
-					Price:    item.Price,
-					Quantity: item.Quantity,
-				}
-
-				// Call the CreateOrderItem domain function
-				_, err := h.domain.CreateOrderItemDomain(ctx, itemParams)
-				if err != nil {
-					log.Printf("Failed to create order item: %+v, err: %v", item, err)
-					continue
-				}
-
-				log.Printf("Successfully added item to order ID %d: %+v", orderid, item)
-			}
 		})
 
 		// Respond to the client
@@ -383,12 +631,15 @@ func (h *OrderHandler) ConsumeOrder() http.HandlerFunc {
 // CalculateOrderBonus godoc
 //
 // @Summary calculate order bonus
-// @Description calculates the order bonus
+// @Description calculates the order bonus. Only the order's delivery agent may calculate it.
 // @Tags Order Calculation Bonus
 // @Param orderId path string true "Order ID"
 // @Produce application/json
 // @Success 200 {array} generated.Order
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/order/bonus/{orderId} [get]
 func (h *OrderHandler) CalculateOrderBonus() http.HandlerFunc {
@@ -406,6 +657,16 @@ func (h *OrderHandler) CalculateOrderBonus() http.HandlerFunc {
 			return
 		}
 
+		order, err := h.domain.GetOrderByIdDomain(ctx, int32(orderId))
+		if err != nil {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, identity.Role == auth.RoleDeliveryAgent && takesPartIn(r, order)) {
+			return
+		}
+
 		totalBonus, err := h.domain.CalculateBonus(ctx, int32(orderId))
 		if err != nil {
 			http.Error(w, "Failed to calculate fee", http.StatusInternalServerError)
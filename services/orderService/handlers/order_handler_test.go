@@ -7,9 +7,11 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 )
 
@@ -22,10 +24,23 @@ type Customer struct {
 	Addressid   *int32  `json:"addressid"`
 }
 
+// adminTransport makes every call as an admin, as the steps act on behalf of
+// customers, restaurants and the platform alike
+type adminTransport struct {
+	token string
+}
+
+func (a adminTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
 // not called TestSystem so it is not ran in ci/cd
 func System(t *testing.T) {
-	// make sure all the services are running first
-	client := &http.Client{}
+	// make sure all the services are running first, and put the access token
+	// of an admin in SYSTEM_TEST_ADMIN_TOKEN
+	client := &http.Client{Transport: adminTransport{token: os.Getenv("SYSTEM_TEST_ADMIN_TOKEN")}}
 
 	// Step 1: Create a customer
 	customerPayload := `{
@@ -252,3 +267,43 @@ func System(t *testing.T) {
 		t.Fatalf("failed to delete order: %v", err)
 	}
 }
+
+// Order events used to be streamed to whoever the X-User-Role and X-User-Id
+// headers claimed to be. The caller is now only known from its access token.
+func TestTakesPartIn(t *testing.T) {
+	customerId := int32(1)
+	order := &generated.Order{ID: 5, Customerid: &customerId}
+
+	tests := []struct {
+		name     string
+		identity *auth.Identity
+		headers  map[string]string
+		want     bool
+	}{
+		{"Customer Of The Order", &auth.Identity{UserID: 1, Role: auth.RoleCustomer}, nil, true},
+		{"Other Customer", &auth.Identity{UserID: 2, Role: auth.RoleCustomer}, nil, false},
+		{"Identity Headers Without Token", nil, map[string]string{"X-User-Role": "customer", "X-User-Id": "1"}, false},
+		{"Identity Headers Over Token", &auth.Identity{UserID: 2, Role: auth.RoleCustomer}, map[string]string{"X-User-Role": "customer", "X-User-Id": "1"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req, _ := http.NewRequest(http.MethodGet, "/api/orders/5/events", nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			if tt.identity != nil {
+				req = req.WithContext(auth.WithIdentity(req.Context(), *tt.identity))
+			}
+
+			// Act
+			got := takesPartIn(req, order)
+
+			// Assert
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+type AcceptOrderRequest struct {
+	PrepTimeMinutes int32 `json:"prep_time_minutes" example:"20"`
+}
+
+type RejectOrderRequest struct {
+	Reason string `json:"reason" example:"Out of fresh dough"`
+}
+
+// writeResponseError maps errors of answering an order to status codes
+func writeResponseError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidResponse):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrOrderNotPending), errors.Is(err, domain.ErrOutOfStock), errors.Is(err, domain.ErrKitchenFull):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to answer order", http.StatusInternalServerError)
+		log.Println(err)
+	}
+}
+
+// restaurantOrderIds reads the restaurant and order ids of the path
+func restaurantOrderIds(r *http.Request) (int32, int32, error) {
+	restaurantId, err := strconv.ParseInt(r.PathValue("restaurantId"), 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	orderId, err := strconv.ParseInt(r.PathValue("orderId"), 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int32(restaurantId), int32(orderId), nil
+}
+
+// GetRestaurantInbox godoc
+//
+// @Summary Get the incoming orders of a restaurant
+// @Description Fetches a page of the restaurant's pending orders with their items, oldest first unless sorted otherwise. Orders not answered by respond_by are rejected automatically.
+// @Tags Restaurant Inbox
+// @Produce application/json
+// @Param restaurantId path int true "Restaurant ID"
+// @Param sort query string false "id, timestamp or total_amount, prefixed by - for descending. Defaults to timestamp"
+// @Param limit query int false "Orders per page, at most 100, defaults to 20"
+// @Param cursor query string false "Cursor of the next page"
+// @Success 200 {object} domain.Page[domain.InboxOrder]
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/orders [get]
+func (h *OrderHandler) GetRestaurantInbox() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := strconv.ParseInt(r.PathValue("restaurantId"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		query := queryParser{r: r}
+		list := query.listParams()
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		inbox, err := h.domain.GetRestaurantInboxDomain(ctx, int32(restaurantId), list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(inbox)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// AcceptOrder godoc
+//
+// @Summary Accept an order
+// @Description Accepts a pending order of the restaurant with the minutes it takes to prepare. The customer is notified with an updated delivery estimate.
+// @Tags Restaurant Inbox
+// @Accept application/json
+// @Produce application/json
+// @Param restaurantId path int true "Restaurant ID"
+// @Param orderId path int true "Order ID"
+// @Param acceptance body AcceptOrderRequest true "Prep time"
+// @Success 200 {string} string "Order accepted"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "Order is no longer pending, out of stock or the kitchen is at capacity"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/orders/{orderId}/accept [post]
+func (h *OrderHandler) AcceptOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, orderId, err := restaurantOrderIds(r)
+		if err != nil {
+			http.Error(w, "Invalid Restaurant or Order ID", http.StatusBadRequest)
+			return
+		}
+
+		var requestPayload AcceptOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.AcceptOrderDomain(ctx, restaurantId, orderId, requestPayload.PrepTimeMinutes); err != nil {
+			writeResponseError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Order accepted"}`))
+	}
+}
+
+// RejectOrder godoc
+//
+// @Summary Reject an order
+// @Description Rejects a pending order of the restaurant. The reason is passed on to the customer.
+// @Tags Restaurant Inbox
+// @Accept application/json
+// @Produce application/json
+// @Param restaurantId path int true "Restaurant ID"
+// @Param orderId path int true "Order ID"
+// @Param rejection body RejectOrderRequest true "Reason"
+// @Success 200 {string} string "Order rejected"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Order not found"
+// @Failure 409 {string} string "Order is no longer pending"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/orders/{orderId}/reject [post]
+func (h *OrderHandler) RejectOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, orderId, err := restaurantOrderIds(r)
+		if err != nil {
+			http.Error(w, "Invalid Restaurant or Order ID", http.StatusBadRequest)
+			return
+		}
+
+		var requestPayload RejectOrderRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestPayload); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.RejectOrderDomain(ctx, restaurantId, orderId, requestPayload.Reason); err != nil {
+			writeResponseError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Order rejected"}`))
+	}
+}
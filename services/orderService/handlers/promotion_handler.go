@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// writePromotionError maps promotion errors to status codes
+func writePromotionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrPromotionNotFound):
+		http.Error(w, "Promotion not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidPromotion):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrPromotionExists), errors.Is(err, domain.ErrPromotionNotApplicable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Failed to process promotion", http.StatusInternalServerError)
+		log.Println(err)
+	}
+}
+
+// GetAllPromotions godoc
+//
+// @Summary Get all promotions
+// @Description Fetches all promotions, including inactive and expired ones. Only admins may list promotions.
+// @Tags Promotions
+// @Produce application/json
+// @Success 200 {array} generated.Promotion
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/promotions [get]
+func (h *OrderHandler) GetAllPromotions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		promotions, err := h.domain.GetAllPromotionsDomain(ctx)
+		if err != nil {
+			writePromotionError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(promotions)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// CreatePromotion godoc
+//
+// @Summary Create a promotion
+// @Description Creates a percentage or fixed-amount discount code, for one restaurant or platform-wide. Codes are case-insensitive. Only admins may create promotions.
+// @Tags Promotions
+// @Accept application/json
+// @Produce application/json
+// @Param promotion body domain.PromotionParams true "Promotion"
+// @Success 201 {object} generated.Promotion
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Code already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/promotions [post]
+func (h *OrderHandler) CreatePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var params domain.PromotionParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		promotion, err := h.domain.CreatePromotionDomain(ctx, params)
+		if err != nil {
+			writePromotionError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(promotion)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// DeactivatePromotion godoc
+//
+// @Summary Deactivate a promotion
+// @Description Stops a promotion from applying to new orders. Orders placed with it keep their discount. Only admins may deactivate promotions.
+// @Tags Promotions
+// @Param promotionId path int true "Promotion ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Promotion not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/promotions/{promotionId} [delete]
+func (h *OrderHandler) DeactivatePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		promotionId, err := strconv.ParseInt(r.PathValue("promotionId"), 10, 32)
+		if err != nil {
+			http.Error(w, "Invalid Promotion ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeactivatePromotionDomain(ctx, int32(promotionId)); err != nil {
+			writePromotionError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ValidatePromotion godoc
+//
+// @Summary Validate a promotion code
+// @Description Checks whether a promotion code applies to the items of a customer's cart, and returns the discount it gives on the subtotal. Customers only check codes for their own cart; the shopping cart service checks them for any cart.
+// @Tags Promotions
+// @Accept application/json
+// @Produce application/json
+// @Param check body domain.PromotionCheck true "Code and cart"
+// @Success 200 {object} domain.AppliedPromotion
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Promotion not found"
+// @Failure 409 {string} string "Promotion does not apply"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/promotions/validate [post]
+func (h *OrderHandler) ValidatePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var check domain.PromotionCheck
+		if err := json.NewDecoder(r.Body).Decode(&check); err != nil {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, identity.Role == auth.RoleService || check.CustomerID == identity.UserID) {
+			return
+		}
+
+		applied, err := h.domain.ValidatePromotionDomain(ctx, check)
+		if err != nil {
+			writePromotionError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(applied)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
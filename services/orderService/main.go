@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/clients"
 	"github.com/rasm445f/soft-exam-2/db"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	_ "github.com/rasm445f/soft-exam-2/docs"
@@ -18,6 +24,11 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
+const (
+	defaultLocationRetention = 24 * time.Hour
+	autoRejectInterval       = 30 * time.Second
+)
+
 func run() (http.Handler, error) {
 	db, err := db.ConnectDB()
 	if err != nil {
@@ -26,34 +37,55 @@ func run() (http.Handler, error) {
 
 	// Initialize Queries with DB
 	queries := generated.New(db)
-	orderDomain := domain.NewOrderDomain(queries)
+	orderEvents := domain.NewOrderEventHub()
+	orderEvents.Listen()
+	orderDomain := domain.NewOrderDomain(queries, db, orderEvents, clients.NewLocationClient(), clients.NewRestaurantClient(), responseTimeout())
 	orderHandler := handlers.NewOrderHandler(orderDomain)
-	feedbackDomain := domain.NewFeedbackDomain(queries)
+	feedbackDomain := domain.NewFeedbackDomain(queries, db, broker.PublishFanout)
 	feedbackHandler := handlers.NewFeedbackHandler(feedbackDomain)
-	deliveryAgentDomain := domain.NewDeliveryAgentDomain(queries)
+	deliveryAgentDomain := domain.NewDeliveryAgentDomain(queries, db, orderEvents)
 	deliveryAgentHandler := handlers.NewDeliveryAgentHandler(deliveryAgentDomain)
+	go pruneLocations(deliveryAgentDomain, locationRetention())
+	go autoRejectOrders(orderDomain)
+	go orderDomain.EstimateDeliveryTimesDomain(context.Background())
 
 	mux := http.NewServeMux()
 
 	// Routes
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("GET /api/docs/", httpSwagger.WrapHandler)
-	mux.HandleFunc("GET /api/orders", orderHandler.GetAllOrders())
-	mux.HandleFunc("GET /api/orders/{orderId}", orderHandler.GetOrderById())
-	mux.HandleFunc("PATCH /api/order/status/{orderId}", orderHandler.UpdateOrderStatus())
-	mux.HandleFunc("DELETE /api/orders/{orderId}", orderHandler.DeleteOrder())
-	mux.HandleFunc("PATCH /api/order/status-agent/{orderId}", orderHandler.UpdateOrderStatusWithDeliveryAgentId())
-	mux.HandleFunc("GET /api/order/bonus/{orderId}", orderHandler.CalculateOrderBonus())
+	// Orders are seen by their customer, restaurant and delivery agent. The
+	// restaurant answers and cancels them, the delivery agent delivers them.
+	mux.HandleFunc("GET /api/orders", auth.Allow(orderHandler.GetAllOrders(), auth.Authenticated))
+	mux.HandleFunc("GET /api/orders/{orderId}", auth.Allow(orderHandler.GetOrderById(), auth.Authenticated))
+	mux.HandleFunc("GET /api/orders/{orderId}/events", auth.Allow(orderHandler.OrderEvents(), auth.Authenticated))
+	mux.HandleFunc("PATCH /api/order/status/{orderId}", auth.Allow(orderHandler.UpdateOrderStatus(), auth.Roles(auth.RoleRestaurant, auth.RoleDeliveryAgent)))
+	mux.HandleFunc("DELETE /api/orders/{orderId}", auth.Allow(orderHandler.DeleteOrder()))
+	mux.HandleFunc("PATCH /api/order/status-agent/{orderId}", auth.Allow(orderHandler.UpdateOrderStatusWithDeliveryAgentId(), auth.Roles(auth.RoleDeliveryAgent)))
+	mux.HandleFunc("GET /api/order/bonus/{orderId}", auth.Allow(orderHandler.CalculateOrderBonus(), auth.Roles(auth.RoleDeliveryAgent)))
+	// Restaurant inbox
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/orders", auth.Allow(orderHandler.GetRestaurantInbox(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/orders/{orderId}/accept", auth.Allow(orderHandler.AcceptOrder(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/orders/{orderId}/reject", auth.Allow(orderHandler.RejectOrder(), auth.RestaurantStaff("restaurantId")))
+	// Promotions
+	mux.HandleFunc("GET /api/promotions", auth.Allow(orderHandler.GetAllPromotions()))
+	mux.HandleFunc("POST /api/promotions", auth.Allow(orderHandler.CreatePromotion()))
+	mux.HandleFunc("POST /api/promotions/validate", auth.Allow(orderHandler.ValidatePromotion(), auth.Roles(auth.RoleCustomer, auth.RoleService)))
+	mux.HandleFunc("DELETE /api/promotions/{promotionId}", auth.Allow(orderHandler.DeactivatePromotion()))
 	// Feedback
-	mux.HandleFunc("GET /api/feedbacks", feedbackHandler.GetAllFeedbacks())
-	mux.HandleFunc("GET /api/feedbacks/{orderId}", feedbackHandler.GetFeedbackByOrderId())
-	mux.HandleFunc("POST /api/feedback", feedbackHandler.CreateFeedback())
+	mux.HandleFunc("GET /api/feedbacks", auth.Allow(feedbackHandler.GetAllFeedbacks(), auth.Authenticated))
+	mux.HandleFunc("GET /api/feedbacks/{orderId}", auth.Allow(feedbackHandler.GetFeedbackByOrderId(), auth.Authenticated))
+	mux.HandleFunc("POST /api/feedback", auth.Allow(feedbackHandler.CreateFeedback(), auth.Roles(auth.RoleCustomer)))
+	mux.HandleFunc("PATCH /api/feedback/{orderId}", auth.Allow(feedbackHandler.UpdateFeedback(), auth.Roles(auth.RoleCustomer)))
 	// DeliveryAgent
-	mux.HandleFunc("GET /api/delivery-agent", deliveryAgentHandler.GetAllDeliveryAgents())
-	mux.HandleFunc("GET /api/delivery-agent/{deliveryAgentId}", deliveryAgentHandler.GetDeliveryAgentById())
-	mux.HandleFunc("POST /api/delivery-agent", deliveryAgentHandler.CreateDeliveryAgent())
+	mux.HandleFunc("GET /api/delivery-agent", auth.Allow(deliveryAgentHandler.GetAllDeliveryAgents()))
+	mux.HandleFunc("GET /api/delivery-agent/{deliveryAgentId}", auth.Allow(deliveryAgentHandler.GetDeliveryAgentById(), auth.DeliveryAgent("deliveryAgentId")))
+	mux.HandleFunc("POST /api/delivery-agent", auth.Allow(deliveryAgentHandler.CreateDeliveryAgent()))
+	mux.HandleFunc("POST /api/delivery-agent/{deliveryAgentId}/location", auth.Allow(deliveryAgentHandler.RecordLocation(), auth.DeliveryAgent("deliveryAgentId")))
+	mux.HandleFunc("GET /api/delivery-agent/{deliveryAgentId}/location", auth.Allow(deliveryAgentHandler.GetLatestLocation(), auth.DeliveryAgent("deliveryAgentId")))
+	mux.HandleFunc("GET /api/delivery-agent/{deliveryAgentId}/ratings", auth.Allow(deliveryAgentHandler.GetDeliveryAgentRatings(), auth.DeliveryAgent("deliveryAgentId")))
 	// Broker
-	mux.HandleFunc("GET /api/order/consume", orderHandler.ConsumeOrder())
+	mux.HandleFunc("GET /api/order/consume", auth.Allow(orderHandler.ConsumeOrder()))
 
 	//CORS stuff
 	corsHandler := cors.New(cors.Options{
@@ -64,12 +96,68 @@ func run() (http.Handler, error) {
 		Debug:            true,
 	})
 
-	metrics := metrics.MetricsMiddleware(mux)
+	// Access tokens are verified against the keys the customer service
+	// publishes, and the caller's identity put into the request context
+	verifier := auth.NewVerifierFromEnv()
+	metrics := metrics.MetricsMiddleware(verifier.Middleware(mux))
 	handler := corsHandler.Handler(metrics)
 
 	return handler, err
 }
 
+// locationRetention reads LOCATION_RETENTION_HOURS, defaulting to a day
+func locationRetention() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv("LOCATION_RETENTION_HOURS"))
+	if err != nil || hours <= 0 {
+		return defaultLocationRetention
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// responseTimeout reads ORDER_RESPONSE_TIMEOUT_MINUTES, the time restaurants
+// have to answer an order, defaulting to domain.DefaultResponseTimeout
+func responseTimeout() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("ORDER_RESPONSE_TIMEOUT_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return domain.DefaultResponseTimeout
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// autoRejectOrders rejects orders the restaurant has not answered in time
+func autoRejectOrders(orderDomain *domain.OrderDomain) {
+	ticker := time.NewTicker(autoRejectInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		rejected, err := orderDomain.AutoRejectOrdersDomain(context.Background())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if rejected > 0 {
+			log.Printf("Rejected %d unanswered orders", rejected)
+		}
+	}
+}
+
+// pruneLocations deletes expired delivery agent locations every hour
+func pruneLocations(deliveryAgentDomain *domain.DeliveryAgentDomain, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		deleted, err := deliveryAgentDomain.PruneLocationsDomain(context.Background(), retention)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("Pruned %d delivery agent locations", deleted)
+		}
+	}
+}
+
 // @title Order Service API
 // @version 1.0
 // @description This is the API documentation for the Order Service.
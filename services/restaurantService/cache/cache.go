@@ -0,0 +1,153 @@
+// Package cache is a read-through cache of API responses, with an in-process
+// LRU in front of a shared store like Redis. Entries carry tags, e.g. the
+// restaurant they belong to, so a change invalidates every response about it.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/metrics"
+)
+
+// Cache layers, as labelled in the metrics
+const (
+	layerLocal  = "local"
+	layerShared = "redis"
+)
+
+// Entry is a cached response body with its validators, the tags it is
+// invalidated by and when it expires
+type Entry struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+	Tags         []string  `json:"tags"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// NewEntry creates the entry of a response body, modified at now. The ETag
+// is a hash of the body, so replicas agree on it.
+func NewEntry(body []byte, tags []string, now time.Time) Entry {
+	sum := sha256.Sum256(body)
+	return Entry{
+		Body:         body,
+		ETag:         `"` + hex.EncodeToString(sum[:16]) + `"`,
+		LastModified: now.UTC().Truncate(time.Second),
+		Tags:         tags,
+	}
+}
+
+// Store is a cache shared between replicas, e.g. Redis
+type Store interface {
+	Get(ctx context.Context, key string) (*Entry, error)
+	Set(ctx context.Context, key string, entry Entry, ttl time.Duration) error
+	Invalidate(ctx context.Context, tags ...string) error
+}
+
+// Cache looks up entries in the local LRU first and then in the shared
+// store. Store failures are logged and count as misses, so the service keeps
+// working without the store.
+type Cache struct {
+	local  *lru
+	shared Store
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// New creates a cache of at most size local entries, which expire after ttl.
+// The shared store is optional.
+func New(shared Store, size int, ttl time.Duration) *Cache {
+	return &Cache{local: newLRU(size), shared: shared, ttl: ttl, now: time.Now}
+}
+
+// Get looks up the entry of key, keeping entries found in the shared store
+// locally too. A nil cache never hits.
+func (c *Cache) Get(ctx context.Context, key string) (Entry, bool) {
+	if c == nil {
+		return Entry{}, false
+	}
+
+	if entry, ok := c.local.get(key, c.now()); ok {
+		record(layerLocal, true)
+		return entry, true
+	}
+	record(layerLocal, false)
+
+	if c.shared == nil {
+		return Entry{}, false
+	}
+	entry, err := c.shared.Get(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read cache entry %s: %v", key, err)
+	}
+	record(layerShared, entry != nil)
+	if entry == nil {
+		return Entry{}, false
+	}
+	c.local.set(key, *entry, c.expiry(entry.ExpiresAt))
+	return *entry, true
+}
+
+// Put caches the response body of key, tagged with tags, and returns its entry
+func (c *Cache) Put(ctx context.Context, key string, tags []string, body []byte) Entry {
+	return c.PutUntil(ctx, key, tags, body, time.Time{})
+}
+
+// PutUntil caches the response body of key like Put, but no longer than
+// until, when the response changes by itself at a known time. A zero until
+// caches for the full ttl.
+func (c *Cache) PutUntil(ctx context.Context, key string, tags []string, body []byte, until time.Time) Entry {
+	if c == nil {
+		return NewEntry(body, tags, time.Now())
+	}
+
+	now := c.now()
+	entry := NewEntry(body, tags, now)
+	entry.ExpiresAt = c.expiry(until)
+	if !entry.ExpiresAt.After(now) {
+		return entry
+	}
+	c.local.set(key, entry, entry.ExpiresAt)
+	if c.shared != nil {
+		if err := c.shared.Set(ctx, key, entry, entry.ExpiresAt.Sub(now)); err != nil {
+			log.Printf("Failed to write cache entry %s: %v", key, err)
+		}
+	}
+	return entry
+}
+
+// expiry is when an entry expires that must not outlive until. Entries
+// without an until expire after the ttl.
+func (c *Cache) expiry(until time.Time) time.Time {
+	expiresAt := c.now().Add(c.ttl)
+	if !until.IsZero() && until.Before(expiresAt) {
+		return until
+	}
+	return expiresAt
+}
+
+// Invalidate removes the entries carrying any of the tags
+func (c *Cache) Invalidate(ctx context.Context, tags ...string) {
+	if c == nil {
+		return
+	}
+
+	c.local.invalidate(tags...)
+	if c.shared != nil {
+		if err := c.shared.Invalidate(ctx, tags...); err != nil {
+			log.Printf("Failed to invalidate cache tags %v: %v", tags, err)
+		}
+	}
+}
+
+func record(layer string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	metrics.CacheRequestsTotal.WithLabelValues(layer, result).Inc()
+}
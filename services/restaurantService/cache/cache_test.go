@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryStore is a Store keeping entries in a map, ignoring their ttl
+type memoryStore struct {
+	entries map[string]Entry
+	tags    map[string][]string
+	err     error
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: map[string]Entry{}, tags: map[string][]string{}}
+}
+
+func (s *memoryStore) Get(ctx context.Context, key string) (*Entry, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *memoryStore) Set(ctx context.Context, key string, entry Entry, ttl time.Duration) error {
+	s.entries[key] = entry
+	for _, tag := range entry.Tags {
+		s.tags[tag] = append(s.tags[tag], key)
+	}
+	return nil
+}
+
+func (s *memoryStore) Invalidate(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		for _, key := range s.tags[tag] {
+			delete(s.entries, key)
+		}
+		delete(s.tags, tag)
+	}
+	return nil
+}
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2025, 1, 27, 12, 0, 0, 0, time.UTC)
+	later := now.Add(time.Minute)
+
+	t.Run("Evicts the least recently used entry", func(t *testing.T) {
+		// Arrange
+		c := newLRU(2)
+		c.set("a", Entry{ETag: "a"}, later)
+		c.set("b", Entry{ETag: "b"}, later)
+		c.get("a", now)
+
+		// Act
+		c.set("c", Entry{ETag: "c"}, later)
+
+		// Assert
+		if _, ok := c.get("b", now); ok {
+			t.Error("expected b to be evicted")
+		}
+		for _, key := range []string{"a", "c"} {
+			if _, ok := c.get(key, now); !ok {
+				t.Errorf("expected %s to be cached", key)
+			}
+		}
+	})
+
+	t.Run("Expires entries", func(t *testing.T) {
+		// Arrange
+		c := newLRU(2)
+		c.set("a", Entry{ETag: "a"}, later)
+
+		// Act
+		_, ok := c.get("a", later)
+
+		// Assert
+		if ok {
+			t.Error("expected a to be expired")
+		}
+		if len(c.items) != 0 {
+			t.Errorf("expected the expired entry to be removed, got %d entries", len(c.items))
+		}
+	})
+
+	t.Run("Invalidates entries by tag", func(t *testing.T) {
+		// Arrange
+		c := newLRU(3)
+		c.set("menu", Entry{ETag: "menu", Tags: []string{"restaurant:1"}}, later)
+		c.set("details", Entry{ETag: "details", Tags: []string{"restaurant:1", "restaurants"}}, later)
+		c.set("other", Entry{ETag: "other", Tags: []string{"restaurant:2"}}, later)
+
+		// Act
+		c.invalidate("restaurant:1")
+
+		// Assert
+		for _, key := range []string{"menu", "details"} {
+			if _, ok := c.get(key, now); ok {
+				t.Errorf("expected %s to be invalidated", key)
+			}
+		}
+		if _, ok := c.get("other", now); !ok {
+			t.Error("expected other to be cached")
+		}
+	})
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	body := []byte(`[{"id":1}]`)
+
+	t.Run("Fills the local cache from the store", func(t *testing.T) {
+		// Arrange
+		store := newMemoryStore()
+		writer := New(store, 10, time.Minute)
+		reader := New(store, 10, time.Minute)
+		put := writer.Put(ctx, "key", []string{"restaurant:1"}, body)
+
+		// Act
+		got, ok := reader.Get(ctx, "key")
+		store.entries = map[string]Entry{}
+		local, localOk := reader.Get(ctx, "key")
+
+		// Assert
+		if !ok || got.ETag != put.ETag || string(got.Body) != string(body) {
+			t.Errorf("got %+v, want %+v", got, put)
+		}
+		if !localOk || local.ETag != put.ETag {
+			t.Errorf("expected the entry to be cached locally, got %+v", local)
+		}
+	})
+
+	t.Run("Invalidates both layers", func(t *testing.T) {
+		// Arrange
+		store := newMemoryStore()
+		c := New(store, 10, time.Minute)
+		c.Put(ctx, "key", []string{"restaurant:1"}, body)
+
+		// Act
+		c.Invalidate(ctx, "restaurant:1")
+
+		// Assert
+		if _, ok := c.Get(ctx, "key"); ok {
+			t.Error("expected the entry to be invalidated")
+		}
+	})
+
+	t.Run("Misses when the store fails", func(t *testing.T) {
+		// Arrange
+		store := newMemoryStore()
+		store.err = errors.New("connection refused")
+		c := New(store, 10, time.Minute)
+
+		// Act
+		_, ok := c.Get(ctx, "key")
+
+		// Assert
+		if ok {
+			t.Error("expected a miss")
+		}
+	})
+
+	t.Run("Expires entries at their until", func(t *testing.T) {
+		// Arrange
+		now := time.Date(2025, 1, 27, 23, 59, 0, 0, time.UTC)
+		store := newMemoryStore()
+		writer := New(store, 10, 5*time.Minute)
+		writer.now = func() time.Time { return now }
+		reader := New(store, 10, 5*time.Minute)
+		reader.now = func() time.Time { return now }
+		midnight := now.Add(time.Minute)
+
+		// Act
+		put := writer.PutUntil(ctx, "key", []string{"restaurant:1"}, body, midnight)
+		_, readOk := reader.Get(ctx, "key")
+		now = midnight
+		store.entries = map[string]Entry{}
+		_, expiredOk := reader.Get(ctx, "key")
+
+		// Assert
+		if !put.ExpiresAt.Equal(midnight) {
+			t.Errorf("got expiry %v, want %v", put.ExpiresAt, midnight)
+		}
+		if !readOk {
+			t.Error("expected a hit before the entry expires")
+		}
+		if expiredOk {
+			t.Error("expected the local copy to expire with the entry")
+		}
+	})
+
+	t.Run("Does not cache entries that are already stale", func(t *testing.T) {
+		// Arrange
+		store := newMemoryStore()
+		c := New(store, 10, time.Minute)
+
+		// Act
+		c.PutUntil(ctx, "key", nil, body, time.Now().Add(-time.Second))
+		_, ok := c.Get(ctx, "key")
+
+		// Assert
+		if ok {
+			t.Error("expected a miss")
+		}
+	})
+
+	t.Run("Nil cache never hits", func(t *testing.T) {
+		// Arrange
+		var c *Cache
+
+		// Act
+		entry := c.Put(ctx, "key", nil, body)
+		_, ok := c.Get(ctx, "key")
+
+		// Assert
+		if ok {
+			t.Error("expected a miss")
+		}
+		if entry.ETag == "" || string(entry.Body) != string(body) {
+			t.Errorf("expected an entry of the body, got %+v", entry)
+		}
+	})
+}
+
+func TestNewEntry(t *testing.T) {
+	now := time.Date(2025, 1, 27, 12, 0, 0, 500, time.UTC)
+
+	a := NewEntry([]byte(`{"id":1}`), nil, now)
+	b := NewEntry([]byte(`{"id":1}`), nil, now.Add(time.Hour))
+	c := NewEntry([]byte(`{"id":2}`), nil, now)
+
+	if a.ETag != b.ETag {
+		t.Errorf("expected equal bodies to have equal ETags, got %s and %s", a.ETag, b.ETag)
+	}
+	if a.ETag == c.ETag {
+		t.Errorf("expected different bodies to have different ETags, got %s", a.ETag)
+	}
+	if !a.LastModified.Equal(now.Truncate(time.Second)) {
+		t.Errorf("got Last-Modified %v, want %v", a.LastModified, now.Truncate(time.Second))
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type lruItem struct {
+	key       string
+	entry     Entry
+	expiresAt time.Time
+}
+
+// lru is an in-process cache holding the most recently used entries. Entries
+// expire after their ttl, so replicas that missed an invalidation catch up.
+type lru struct {
+	mu      sync.Mutex
+	size    int
+	items   map[string]*list.Element
+	recency *list.List
+}
+
+func newLRU(size int) *lru {
+	return &lru{size: size, items: map[string]*list.Element{}, recency: list.New()}
+}
+
+func (c *lru) get(key string, now time.Time) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	item := element.Value.(*lruItem)
+	if !now.Before(item.expiresAt) {
+		c.remove(element)
+		return Entry{}, false
+	}
+	c.recency.MoveToFront(element)
+	return item.entry, true
+}
+
+func (c *lru) set(key string, entry Entry, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+	c.items[key] = c.recency.PushFront(&lruItem{key: key, entry: entry, expiresAt: expiresAt})
+	for c.recency.Len() > c.size {
+		c.remove(c.recency.Back())
+	}
+}
+
+// invalidate removes the entries carrying any of the tags
+func (c *lru) invalidate(tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.recency.Front(); element != nil; {
+		next := element.Next()
+		if hasAnyTag(element.Value.(*lruItem).entry.Tags, tags) {
+			c.remove(element)
+		}
+		element = next
+	}
+}
+
+func (c *lru) remove(element *list.Element) {
+	c.recency.Remove(element)
+	delete(c.items, element.Value.(*lruItem).key)
+}
+
+func hasAnyTag(tags, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	entryPrefix = "cache:entry:"
+	tagPrefix   = "cache:tag:"
+)
+
+// RedisStore shares cache entries between replicas. Each tag is a set of the
+// keys carrying it, so invalidating a tag deletes its keys.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get looks up the entry of key, returning nil when it is missing
+func (s *RedisStore) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := s.client.Get(ctx, entryPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Set stores the entry of key for ttl and adds it to the sets of its tags.
+// The tag sets live as long as their newest entry.
+func (s *RedisStore) Set(ctx context.Context, key string, entry Entry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, entryPrefix+key, data, ttl)
+		for _, tag := range entry.Tags {
+			pipe.SAdd(ctx, tagPrefix+tag, key)
+			pipe.Expire(ctx, tagPrefix+tag, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// Invalidate deletes the entries carrying any of the tags, and the tags
+func (s *RedisStore) Invalidate(ctx context.Context, tags ...string) error {
+	var keys []string
+	for _, tag := range tags {
+		members, err := s.client.SMembers(ctx, tagPrefix+tag).Result()
+		if err != nil {
+			return err
+		}
+		for _, member := range members {
+			keys = append(keys, entryPrefix+member)
+		}
+		keys = append(keys, tagPrefix+tag)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.client.Del(ctx, keys...).Err()
+}
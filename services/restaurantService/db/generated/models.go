@@ -4,24 +4,177 @@
 
 package generated
 
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type Category struct {
+	ID     int32  `json:"id"`
+	Slug   string `json:"slug"`
+	NameDa string `json:"name_da"`
+	NameEn string `json:"name_en"`
+}
+
+type ComboComponent struct {
+	ComboID    int32 `json:"combo_id"`
+	MenuItemID int32 `json:"menu_item_id"`
+	Quantity   int32 `json:"quantity"`
+}
+
+type DeliveryZone struct {
+	RestaurantID int32   `json:"restaurant_id"`
+	ZipCode      int32   `json:"zip_code"`
+	DeliveryFee  float64 `json:"delivery_fee"`
+	MinimumOrder float64 `json:"minimum_order"`
+}
+
+type Image struct {
+	ID           int32     `json:"id"`
+	RestaurantID int32     `json:"restaurant_id"`
+	MenuItemID   *int32    `json:"menu_item_id"`
+	Kind         string    `json:"kind"`
+	StorageKey   string    `json:"storage_key"`
+	ThumbnailKey string    `json:"thumbnail_key"`
+	ContentType  string    `json:"content_type"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	SizeBytes    int32     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type KitchenCapacity struct {
+	RestaurantID     int32  `json:"restaurant_id"`
+	MaxOpenOrders    *int32 `json:"max_open_orders"`
+	MaxItemsPerSlot  *int32 `json:"max_items_per_slot"`
+	PrepMinutes      int32  `json:"prep_minutes"`
+	BusyExtraMinutes int32  `json:"busy_extra_minutes"`
+	BusyMode         bool   `json:"busy_mode"`
+}
+
+type KitchenOrder struct {
+	OrderID      int32      `json:"order_id"`
+	RestaurantID int32      `json:"restaurant_id"`
+	ItemCount    int32      `json:"item_count"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+	ClosedAt     *time.Time `json:"closed_at"`
+}
+
+type MenuItemAvailability struct {
+	MenuItemID   int32       `json:"menu_item_id"`
+	Available    bool        `json:"available"`
+	DailyStock   *int32      `json:"daily_stock"`
+	Sold         int32       `json:"sold"`
+	StockDate    pgtype.Date `json:"stock_date"`
+	SoldOutUntil *time.Time  `json:"sold_out_until"`
+}
+
+type MenuItemDietary struct {
+	MenuItemID    int32    `json:"menu_item_id"`
+	Allergens     []string `json:"allergens"`
+	DietaryTags   []string `json:"dietary_tags"`
+	EnergyKcal    *int32   `json:"energy_kcal"`
+	Fat           *float64 `json:"fat"`
+	SaturatedFat  *float64 `json:"saturated_fat"`
+	Carbohydrates *float64 `json:"carbohydrates"`
+	Sugars        *float64 `json:"sugars"`
+	Protein       *float64 `json:"protein"`
+	Salt          *float64 `json:"salt"`
+}
+
+type MenuItemPrice struct {
+	ID         int32      `json:"id"`
+	MenuItemID int32      `json:"menu_item_id"`
+	Price      float64    `json:"price"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to"`
+}
+
+type MenuOption struct {
+	ID         int32   `json:"id"`
+	GroupID    int32   `json:"group_id"`
+	Name       string  `json:"name"`
+	PriceDelta float64 `json:"price_delta"`
+	Position   int32   `json:"position"`
+}
+
 type Menuitem struct {
-	ID           int32   `json:"id"`
-	Restaurantid int32   `json:"restaurantid"`
-	Name         string  `json:"name"`
-	Price        float64 `json:"price"`
-	Description  *string `json:"description"`
+	ID           int32      `json:"id"`
+	Restaurantid int32      `json:"restaurantid"`
+	Name         string     `json:"name"`
+	Price        float64    `json:"price"`
+	Description  *string    `json:"description"`
+	DeletedAt    *time.Time `json:"deleted_at"`
+}
+
+type OpeningException struct {
+	ID           int32       `json:"id"`
+	RestaurantID int32       `json:"restaurant_id"`
+	Date         pgtype.Date `json:"date"`
+	OpensAt      pgtype.Time `json:"opens_at"`
+	ClosesAt     pgtype.Time `json:"closes_at"`
+	Note         *string     `json:"note"`
+}
+
+type OpeningHour struct {
+	ID           int32       `json:"id"`
+	RestaurantID int32       `json:"restaurant_id"`
+	Weekday      int16       `json:"weekday"`
+	OpensAt      pgtype.Time `json:"opens_at"`
+	ClosesAt     pgtype.Time `json:"closes_at"`
+}
+
+type OptionGroup struct {
+	ID            int32  `json:"id"`
+	MenuItemID    int32  `json:"menu_item_id"`
+	Name          string `json:"name"`
+	SelectionType string `json:"selection_type"`
+	MinSelect     int32  `json:"min_select"`
+	MaxSelect     int32  `json:"max_select"`
+	Position      int32  `json:"position"`
 }
 
 type Restaurant struct {
-	ID       int32    `json:"id"`
-	Name     string   `json:"name"`
-	Rating   *float64 `json:"rating"`
-	Category *string  `json:"category"`
-	Address  *string  `json:"address"`
-	ZipCode  *int32   `json:"zip_code"`
+	ID             int32      `json:"id"`
+	Name           string     `json:"name"`
+	Rating         *float64   `json:"rating"`
+	Category       *string    `json:"category"`
+	Address        *string    `json:"address"`
+	ZipCode        *int32     `json:"zip_code"`
+	ReviewCount    int32      `json:"review_count"`
+	OrderingPaused bool       `json:"ordering_paused"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+}
+
+type RestaurantCategory struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	CategoryID   int32 `json:"category_id"`
+}
+
+type RestaurantLocation struct {
+	RestaurantID int32   `json:"restaurant_id"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+}
+
+type RestaurantReview struct {
+	FeedbackID   int32      `json:"feedback_id"`
+	RestaurantID int32      `json:"restaurant_id"`
+	Rating       int32      `json:"rating"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+type StockReservation struct {
+	OrderID    int32       `json:"order_id"`
+	MenuItemID int32       `json:"menu_item_id"`
+	Quantity   int32       `json:"quantity"`
+	StockDate  pgtype.Date `json:"stock_date"`
 }
 
 type Zipcode struct {
-	ZipCode int32  `json:"zip_code"`
-	City    string `json:"city"`
+	ZipCode   int32    `json:"zip_code"`
+	City      string   `json:"city"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
 }
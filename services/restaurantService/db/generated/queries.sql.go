@@ -7,8 +7,189 @@ package generated
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const addRestaurantCategory = `-- name: AddRestaurantCategory :exec
+INSERT INTO restaurant_category (restaurant_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT (restaurant_id, category_id) DO NOTHING
+`
+
+type AddRestaurantCategoryParams struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	CategoryID   int32 `json:"category_id"`
+}
+
+func (q *Queries) AddRestaurantCategory(ctx context.Context, arg AddRestaurantCategoryParams) error {
+	_, err := q.db.Exec(ctx, addRestaurantCategory, arg.RestaurantID, arg.CategoryID)
+	return err
+}
+
+const addSoldStock = `-- name: AddSoldStock :execrows
+UPDATE menu_item_availability
+SET sold = CASE WHEN stock_date = $1 THEN sold ELSE 0 END + $2,
+    stock_date = $1
+WHERE menu_item_id = $3
+    AND (daily_stock IS NULL OR CASE WHEN stock_date = $1 THEN sold ELSE 0 END + $2 <= daily_stock)
+`
+
+type AddSoldStockParams struct {
+	StockDate  pgtype.Date `json:"stock_date"`
+	Quantity   int32       `json:"quantity"`
+	MenuItemID int32       `json:"menu_item_id"`
+}
+
+// Counts the quantity as sold on the date, starting over on a new day.
+// Nothing is counted when less than the quantity is left of the daily stock.
+func (q *Queries) AddSoldStock(ctx context.Context, arg AddSoldStockParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addSoldStock, arg.StockDate, arg.Quantity, arg.MenuItemID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const applyCurrentPrices = `-- name: ApplyCurrentPrices :many
+UPDATE menuitem m
+SET price = p.price
+FROM menu_item_price p
+WHERE p.menu_item_id = m.id
+    AND p.valid_from <= $1 AND (p.valid_to IS NULL OR p.valid_to > $1)
+    AND m.price <> p.price AND m.deleted_at IS NULL
+RETURNING m.id, m.restaurantid
+`
+
+type ApplyCurrentPricesRow struct {
+	ID           int32 `json:"id"`
+	Restaurantid int32 `json:"restaurantid"`
+}
+
+// Sets menu items to the price of their version at the time, once scheduled
+// price changes take effect
+func (q *Queries) ApplyCurrentPrices(ctx context.Context, validFrom time.Time) ([]ApplyCurrentPricesRow, error) {
+	rows, err := q.db.Query(ctx, applyCurrentPrices, validFrom)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ApplyCurrentPricesRow
+	for rows.Next() {
+		var i ApplyCurrentPricesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Restaurantid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const closeKitchenOrder = `-- name: CloseKitchenOrder :exec
+UPDATE kitchen_order
+SET closed_at = NOW()
+WHERE order_id = $1 AND closed_at IS NULL
+`
+
+func (q *Queries) CloseKitchenOrder(ctx context.Context, orderID int32) error {
+	_, err := q.db.Exec(ctx, closeKitchenOrder, orderID)
+	return err
+}
+
+const createCategory = `-- name: CreateCategory :one
+INSERT INTO category (slug, name_da, name_en)
+VALUES ($1, $2, $3)
+RETURNING id, slug, name_da, name_en
+`
+
+type CreateCategoryParams struct {
+	Slug   string `json:"slug"`
+	NameDa string `json:"name_da"`
+	NameEn string `json:"name_en"`
+}
+
+func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, createCategory, arg.Slug, arg.NameDa, arg.NameEn)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.NameDa,
+		&i.NameEn,
+	)
+	return i, err
+}
+
+const createComboComponent = `-- name: CreateComboComponent :exec
+INSERT INTO combo_component (combo_id, menu_item_id, quantity)
+VALUES ($1, $2, $3)
+`
+
+type CreateComboComponentParams struct {
+	ComboID    int32 `json:"combo_id"`
+	MenuItemID int32 `json:"menu_item_id"`
+	Quantity   int32 `json:"quantity"`
+}
+
+func (q *Queries) CreateComboComponent(ctx context.Context, arg CreateComboComponentParams) error {
+	_, err := q.db.Exec(ctx, createComboComponent, arg.ComboID, arg.MenuItemID, arg.Quantity)
+	return err
+}
+
+const createImage = `-- name: CreateImage :one
+INSERT INTO image (restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+`
+
+type CreateImageParams struct {
+	RestaurantID int32  `json:"restaurant_id"`
+	MenuItemID   *int32 `json:"menu_item_id"`
+	Kind         string `json:"kind"`
+	StorageKey   string `json:"storage_key"`
+	ThumbnailKey string `json:"thumbnail_key"`
+	ContentType  string `json:"content_type"`
+	Width        int32  `json:"width"`
+	Height       int32  `json:"height"`
+	SizeBytes    int32  `json:"size_bytes"`
+}
+
+func (q *Queries) CreateImage(ctx context.Context, arg CreateImageParams) (Image, error) {
+	row := q.db.QueryRow(ctx, createImage,
+		arg.RestaurantID,
+		arg.MenuItemID,
+		arg.Kind,
+		arg.StorageKey,
+		arg.ThumbnailKey,
+		arg.ContentType,
+		arg.Width,
+		arg.Height,
+		arg.SizeBytes,
+	)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.RestaurantID,
+		&i.MenuItemID,
+		&i.Kind,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createMenuItem = `-- name: CreateMenuItem :one
 INSERT INTO menuitem (restaurantid, name, price, description)
 VALUES ($1, $2, $3, $4)
@@ -34,6 +215,144 @@ func (q *Queries) CreateMenuItem(ctx context.Context, arg CreateMenuItemParams)
 	return id, err
 }
 
+const createMenuOption = `-- name: CreateMenuOption :exec
+INSERT INTO menu_option (group_id, name, price_delta, position)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateMenuOptionParams struct {
+	GroupID    int32   `json:"group_id"`
+	Name       string  `json:"name"`
+	PriceDelta float64 `json:"price_delta"`
+	Position   int32   `json:"position"`
+}
+
+func (q *Queries) CreateMenuOption(ctx context.Context, arg CreateMenuOptionParams) error {
+	_, err := q.db.Exec(ctx, createMenuOption,
+		arg.GroupID,
+		arg.Name,
+		arg.PriceDelta,
+		arg.Position,
+	)
+	return err
+}
+
+const createOpeningException = `-- name: CreateOpeningException :one
+INSERT INTO opening_exception (restaurant_id, date, opens_at, closes_at, note)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, restaurant_id, date, opens_at, closes_at, note
+`
+
+type CreateOpeningExceptionParams struct {
+	RestaurantID int32       `json:"restaurant_id"`
+	Date         pgtype.Date `json:"date"`
+	OpensAt      pgtype.Time `json:"opens_at"`
+	ClosesAt     pgtype.Time `json:"closes_at"`
+	Note         *string     `json:"note"`
+}
+
+func (q *Queries) CreateOpeningException(ctx context.Context, arg CreateOpeningExceptionParams) (OpeningException, error) {
+	row := q.db.QueryRow(ctx, createOpeningException,
+		arg.RestaurantID,
+		arg.Date,
+		arg.OpensAt,
+		arg.ClosesAt,
+		arg.Note,
+	)
+	var i OpeningException
+	err := row.Scan(
+		&i.ID,
+		&i.RestaurantID,
+		&i.Date,
+		&i.OpensAt,
+		&i.ClosesAt,
+		&i.Note,
+	)
+	return i, err
+}
+
+const createOpeningHours = `-- name: CreateOpeningHours :exec
+INSERT INTO opening_hours (restaurant_id, weekday, opens_at, closes_at)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateOpeningHoursParams struct {
+	RestaurantID int32       `json:"restaurant_id"`
+	Weekday      int16       `json:"weekday"`
+	OpensAt      pgtype.Time `json:"opens_at"`
+	ClosesAt     pgtype.Time `json:"closes_at"`
+}
+
+func (q *Queries) CreateOpeningHours(ctx context.Context, arg CreateOpeningHoursParams) error {
+	_, err := q.db.Exec(ctx, createOpeningHours,
+		arg.RestaurantID,
+		arg.Weekday,
+		arg.OpensAt,
+		arg.ClosesAt,
+	)
+	return err
+}
+
+const createOptionGroup = `-- name: CreateOptionGroup :one
+INSERT INTO option_group (menu_item_id, name, selection_type, min_select, max_select, position)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id
+`
+
+type CreateOptionGroupParams struct {
+	MenuItemID    int32  `json:"menu_item_id"`
+	Name          string `json:"name"`
+	SelectionType string `json:"selection_type"`
+	MinSelect     int32  `json:"min_select"`
+	MaxSelect     int32  `json:"max_select"`
+	Position      int32  `json:"position"`
+}
+
+func (q *Queries) CreateOptionGroup(ctx context.Context, arg CreateOptionGroupParams) (int32, error) {
+	row := q.db.QueryRow(ctx, createOptionGroup,
+		arg.MenuItemID,
+		arg.Name,
+		arg.SelectionType,
+		arg.MinSelect,
+		arg.MaxSelect,
+		arg.Position,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createPriceVersion = `-- name: CreatePriceVersion :one
+INSERT INTO menu_item_price (menu_item_id, price, valid_from, valid_to)
+VALUES ($1, $2, $3, $4)
+RETURNING id, menu_item_id, price, valid_from, valid_to
+`
+
+type CreatePriceVersionParams struct {
+	MenuItemID int32      `json:"menu_item_id"`
+	Price      float64    `json:"price"`
+	ValidFrom  time.Time  `json:"valid_from"`
+	ValidTo    *time.Time `json:"valid_to"`
+}
+
+func (q *Queries) CreatePriceVersion(ctx context.Context, arg CreatePriceVersionParams) (MenuItemPrice, error) {
+	row := q.db.QueryRow(ctx, createPriceVersion,
+		arg.MenuItemID,
+		arg.Price,
+		arg.ValidFrom,
+		arg.ValidTo,
+	)
+	var i MenuItemPrice
+	err := row.Scan(
+		&i.ID,
+		&i.MenuItemID,
+		&i.Price,
+		&i.ValidFrom,
+		&i.ValidTo,
+	)
+	return i, err
+}
+
 const createRestaurant = `-- name: CreateRestaurant :one
 INSERT INTO restaurant (name, rating, category, address, zip_code)
 VALUES ($1, $2, $3, $4, $5)
@@ -61,6 +380,32 @@ func (q *Queries) CreateRestaurant(ctx context.Context, arg CreateRestaurantPara
 	return id, err
 }
 
+const createStockReservation = `-- name: CreateStockReservation :execrows
+INSERT INTO stock_reservation (order_id, menu_item_id, quantity, stock_date)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (order_id, menu_item_id) DO NOTHING
+`
+
+type CreateStockReservationParams struct {
+	OrderID    int32       `json:"order_id"`
+	MenuItemID int32       `json:"menu_item_id"`
+	Quantity   int32       `json:"quantity"`
+	StockDate  pgtype.Date `json:"stock_date"`
+}
+
+func (q *Queries) CreateStockReservation(ctx context.Context, arg CreateStockReservationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, createStockReservation,
+		arg.OrderID,
+		arg.MenuItemID,
+		arg.Quantity,
+		arg.StockDate,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const createZipCode = `-- name: CreateZipCode :exec
 INSERT INTO zipcode (zip_code, city)
 VALUES ($1, $2)
@@ -77,26 +422,154 @@ func (q *Queries) CreateZipCode(ctx context.Context, arg CreateZipCodeParams) er
 	return err
 }
 
-const fetchAllCategories = `-- name: FetchAllCategories :many
-SELECT DISTINCT category
-FROM restaurant
-WHERE category IS NOT NULL
-ORDER BY category
+const deleteCategory = `-- name: DeleteCategory :execrows
+DELETE FROM category
+WHERE id = $1
 `
 
-func (q *Queries) FetchAllCategories(ctx context.Context) ([]*string, error) {
-	rows, err := q.db.Query(ctx, fetchAllCategories)
+func (q *Queries) DeleteCategory(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteCategory, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteComboComponents = `-- name: DeleteComboComponents :exec
+DELETE FROM combo_component
+WHERE combo_id = $1
+`
+
+func (q *Queries) DeleteComboComponents(ctx context.Context, comboID int32) error {
+	_, err := q.db.Exec(ctx, deleteComboComponents, comboID)
+	return err
+}
+
+const deleteDeliveryZone = `-- name: DeleteDeliveryZone :execrows
+DELETE FROM delivery_zone
+WHERE restaurant_id = $1 AND zip_code = $2
+`
+
+type DeleteDeliveryZoneParams struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	ZipCode      int32 `json:"zip_code"`
+}
+
+func (q *Queries) DeleteDeliveryZone(ctx context.Context, arg DeleteDeliveryZoneParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteDeliveryZone, arg.RestaurantID, arg.ZipCode)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteImage = `-- name: DeleteImage :exec
+DELETE FROM image
+WHERE id = $1
+`
+
+func (q *Queries) DeleteImage(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deleteImage, id)
+	return err
+}
+
+const deleteOpeningException = `-- name: DeleteOpeningException :execrows
+DELETE FROM opening_exception
+WHERE restaurant_id = $1 AND id = $2
+`
+
+type DeleteOpeningExceptionParams struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	ID           int32 `json:"id"`
+}
+
+func (q *Queries) DeleteOpeningException(ctx context.Context, arg DeleteOpeningExceptionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOpeningException, arg.RestaurantID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteOpeningHours = `-- name: DeleteOpeningHours :exec
+DELETE FROM opening_hours
+WHERE restaurant_id = $1
+`
+
+func (q *Queries) DeleteOpeningHours(ctx context.Context, restaurantID int32) error {
+	_, err := q.db.Exec(ctx, deleteOpeningHours, restaurantID)
+	return err
+}
+
+const deleteOptionGroups = `-- name: DeleteOptionGroups :exec
+DELETE FROM option_group
+WHERE menu_item_id = $1
+`
+
+func (q *Queries) DeleteOptionGroups(ctx context.Context, menuItemID int32) error {
+	_, err := q.db.Exec(ctx, deleteOptionGroups, menuItemID)
+	return err
+}
+
+const deletePriceVersion = `-- name: DeletePriceVersion :exec
+DELETE FROM menu_item_price
+WHERE id = $1
+`
+
+func (q *Queries) DeletePriceVersion(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, deletePriceVersion, id)
+	return err
+}
+
+const deleteRestaurantCategories = `-- name: DeleteRestaurantCategories :exec
+DELETE FROM restaurant_category
+WHERE restaurant_id = $1
+`
+
+func (q *Queries) DeleteRestaurantCategories(ctx context.Context, restaurantID int32) error {
+	_, err := q.db.Exec(ctx, deleteRestaurantCategories, restaurantID)
+	return err
+}
+
+const deleteRestaurantLocation = `-- name: DeleteRestaurantLocation :exec
+DELETE FROM restaurant_location
+WHERE restaurant_id = $1
+`
+
+func (q *Queries) DeleteRestaurantLocation(ctx context.Context, restaurantID int32) error {
+	_, err := q.db.Exec(ctx, deleteRestaurantLocation, restaurantID)
+	return err
+}
+
+const deleteStockReservations = `-- name: DeleteStockReservations :many
+DELETE FROM stock_reservation
+WHERE order_id = $1
+RETURNING menu_item_id, quantity, stock_date
+`
+
+type DeleteStockReservationsRow struct {
+	MenuItemID int32       `json:"menu_item_id"`
+	Quantity   int32       `json:"quantity"`
+	StockDate  pgtype.Date `json:"stock_date"`
+}
+
+func (q *Queries) DeleteStockReservations(ctx context.Context, orderID int32) ([]DeleteStockReservationsRow, error) {
+	rows, err := q.db.Query(ctx, deleteStockReservations, orderID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []*string
+	var items []DeleteStockReservationsRow
 	for rows.Next() {
-		var category *string
-		if err := rows.Scan(&category); err != nil {
+		var i DeleteStockReservationsRow
+		if err := rows.Scan(
+			&i.MenuItemID,
+			&i.Quantity,
+			&i.StockDate,
+		); err != nil {
 			return nil, err
 		}
-		items = append(items, category)
+		items = append(items, i)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -104,28 +577,26 @@ func (q *Queries) FetchAllCategories(ctx context.Context) ([]*string, error) {
 	return items, nil
 }
 
-const fetchAllRestaurants = `-- name: FetchAllRestaurants :many
-SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code
-FROM restaurant r
-JOIN zipcode a ON r.zip_code = a.zip_code
+const fetchAllCategories = `-- name: FetchAllCategories :many
+SELECT id, slug, name_da, name_en
+FROM category
+ORDER BY slug
 `
 
-func (q *Queries) FetchAllRestaurants(ctx context.Context) ([]Restaurant, error) {
-	rows, err := q.db.Query(ctx, fetchAllRestaurants)
+func (q *Queries) FetchAllCategories(ctx context.Context) ([]Category, error) {
+	rows, err := q.db.Query(ctx, fetchAllCategories)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Restaurant
+	var items []Category
 	for rows.Next() {
-		var i Restaurant
+		var i Category
 		if err := rows.Scan(
 			&i.ID,
-			&i.Name,
-			&i.Rating,
-			&i.Category,
-			&i.Address,
-			&i.ZipCode,
+			&i.Slug,
+			&i.NameDa,
+			&i.NameEn,
 		); err != nil {
 			return nil, err
 		}
@@ -138,9 +609,9 @@ func (q *Queries) FetchAllRestaurants(ctx context.Context) ([]Restaurant, error)
 }
 
 const fetchMenuItemsByRestaurantId = `-- name: FetchMenuItemsByRestaurantId :many
-SELECT id, restaurantid, name, price, description
+SELECT id, restaurantid, name, price, description, deleted_at
 FROM menuitem
-WHERE restaurantid = $1
+WHERE restaurantid = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) FetchMenuItemsByRestaurantId(ctx context.Context, restaurantid int32) ([]Menuitem, error) {
@@ -158,6 +629,91 @@ func (q *Queries) FetchMenuItemsByRestaurantId(ctx context.Context, restaurantid
 			&i.Name,
 			&i.Price,
 			&i.Description,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const fetchNearbyRestaurants = `-- name: FetchNearbyRestaurants :many
+SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code, r.review_count, r.ordering_paused, r.deleted_at,
+    l.latitude, l.longitude, d.distance_km::float8 AS distance_km
+FROM restaurant r
+JOIN restaurant_location l ON l.restaurant_id = r.id
+CROSS JOIN LATERAL (
+    SELECT 2 * 6371 * asin(sqrt(
+        power(sin(radians(l.latitude - $1::float8) / 2), 2)
+        + cos(radians($1)) * cos(radians(l.latitude))
+        * power(sin(radians(l.longitude - $2::float8) / 2), 2))) AS distance_km
+) d
+WHERE r.deleted_at IS NULL
+AND l.latitude BETWEEN $1 - $3::float8 / 111.2 AND $1 + $3 / 111.2
+AND (abs($1) + $3 / 111.2 >= 90
+    OR l.longitude BETWEEN $2 - $3 / (111.2 * NULLIF(cos(radians($1)), 0))
+        AND $2 + $3 / (111.2 * NULLIF(cos(radians($1)), 0)))
+AND d.distance_km <= $3
+ORDER BY d.distance_km, r.id
+LIMIT $4::int
+`
+
+type FetchNearbyRestaurantsParams struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	RadiusKm  float64 `json:"radius_km"`
+	PageLimit int32   `json:"page_limit"`
+}
+
+type FetchNearbyRestaurantsRow struct {
+	ID             int32      `json:"id"`
+	Name           string     `json:"name"`
+	Rating         *float64   `json:"rating"`
+	Category       *string    `json:"category"`
+	Address        *string    `json:"address"`
+	ZipCode        *int32     `json:"zip_code"`
+	ReviewCount    int32      `json:"review_count"`
+	OrderingPaused bool       `json:"ordering_paused"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+	Latitude       float64    `json:"latitude"`
+	Longitude      float64    `json:"longitude"`
+	DistanceKm     float64    `json:"distance_km"`
+}
+
+// Restaurants within radius_km of a point, nearest first. The bounding box
+// lets the coordinates index skip far away restaurants before the haversine
+// distance is worked out. A box reaching a pole spans every longitude.
+func (q *Queries) FetchNearbyRestaurants(ctx context.Context, arg FetchNearbyRestaurantsParams) ([]FetchNearbyRestaurantsRow, error) {
+	rows, err := q.db.Query(ctx, fetchNearbyRestaurants,
+		arg.Latitude,
+		arg.Longitude,
+		arg.RadiusKm,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FetchNearbyRestaurantsRow
+	for rows.Next() {
+		var i FetchNearbyRestaurantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Rating,
+			&i.Category,
+			&i.Address,
+			&i.ZipCode,
+			&i.ReviewCount,
+			&i.OrderingPaused,
+			&i.DeletedAt,
+			&i.Latitude,
+			&i.Longitude,
+			&i.DistanceKm,
 		); err != nil {
 			return nil, err
 		}
@@ -170,13 +726,16 @@ func (q *Queries) FetchMenuItemsByRestaurantId(ctx context.Context, restaurantid
 }
 
 const filterRestaurantsByCategory = `-- name: FilterRestaurantsByCategory :many
-SELECT id, name, rating, category, address, zip_code
-FROM restaurant
-WHERE category ILIKE $1
+SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code, r.review_count, r.ordering_paused, r.deleted_at
+FROM restaurant r
+JOIN restaurant_category rc ON rc.restaurant_id = r.id
+JOIN category c ON c.id = rc.category_id
+WHERE c.slug = $1 AND r.deleted_at IS NULL
+ORDER BY r.id
 `
 
-func (q *Queries) FilterRestaurantsByCategory(ctx context.Context, category *string) ([]Restaurant, error) {
-	rows, err := q.db.Query(ctx, filterRestaurantsByCategory, category)
+func (q *Queries) FilterRestaurantsByCategory(ctx context.Context, slug string) ([]Restaurant, error) {
+	rows, err := q.db.Query(ctx, filterRestaurantsByCategory, slug)
 	if err != nil {
 		return nil, err
 	}
@@ -191,6 +750,9 @@ func (q *Queries) FilterRestaurantsByCategory(ctx context.Context, category *str
 			&i.Category,
 			&i.Address,
 			&i.ZipCode,
+			&i.ReviewCount,
+			&i.OrderingPaused,
+			&i.DeletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -202,10 +764,402 @@ func (q *Queries) FilterRestaurantsByCategory(ctx context.Context, category *str
 	return items, nil
 }
 
-const getMenuItemByRestaurantAndId = `-- name: GetMenuItemByRestaurantAndId :one
-SELECT id, restaurantid, name, price, description
-FROM menuitem
-WHERE restaurantid = $1 AND id = $2
+const getAvailabilityByMenuItemIds = `-- name: GetAvailabilityByMenuItemIds :many
+SELECT menu_item_id, available, daily_stock, sold, stock_date, sold_out_until
+FROM menu_item_availability
+WHERE menu_item_id = ANY($1::int[])
+`
+
+func (q *Queries) GetAvailabilityByMenuItemIds(ctx context.Context, menuItemIds []int32) ([]MenuItemAvailability, error) {
+	rows, err := q.db.Query(ctx, getAvailabilityByMenuItemIds, menuItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MenuItemAvailability
+	for rows.Next() {
+		var i MenuItemAvailability
+		if err := rows.Scan(
+			&i.MenuItemID,
+			&i.Available,
+			&i.DailyStock,
+			&i.Sold,
+			&i.StockDate,
+			&i.SoldOutUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategoriesByRestaurantId = `-- name: GetCategoriesByRestaurantId :many
+SELECT c.id, c.slug, c.name_da, c.name_en
+FROM category c
+JOIN restaurant_category rc ON rc.category_id = c.id
+JOIN restaurant r ON r.id = rc.restaurant_id
+WHERE rc.restaurant_id = $1
+ORDER BY c.slug = r.category DESC, c.slug
+`
+
+// The primary category comes first
+func (q *Queries) GetCategoriesByRestaurantId(ctx context.Context, restaurantID int32) ([]Category, error) {
+	rows, err := q.db.Query(ctx, getCategoriesByRestaurantId, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Category
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.Slug,
+			&i.NameDa,
+			&i.NameEn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategoriesBySlugs = `-- name: GetCategoriesBySlugs :many
+SELECT id, slug, name_da, name_en
+FROM category
+WHERE slug = ANY($1::text[])
+`
+
+func (q *Queries) GetCategoriesBySlugs(ctx context.Context, slugs []string) ([]Category, error) {
+	rows, err := q.db.Query(ctx, getCategoriesBySlugs, slugs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Category
+	for rows.Next() {
+		var i Category
+		if err := rows.Scan(
+			&i.ID,
+			&i.Slug,
+			&i.NameDa,
+			&i.NameEn,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCategoryById = `-- name: GetCategoryById :one
+SELECT id, slug, name_da, name_en
+FROM category
+WHERE id = $1
+`
+
+func (q *Queries) GetCategoryById(ctx context.Context, id int32) (Category, error) {
+	row := q.db.QueryRow(ctx, getCategoryById, id)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.NameDa,
+		&i.NameEn,
+	)
+	return i, err
+}
+
+const getComboComponentsByComboIds = `-- name: GetComboComponentsByComboIds :many
+SELECT c.combo_id, c.menu_item_id, m.name, c.quantity
+FROM combo_component c
+JOIN menuitem m ON m.id = c.menu_item_id
+WHERE c.combo_id = ANY($1::int[])
+ORDER BY c.combo_id, m.name
+`
+
+type GetComboComponentsByComboIdsRow struct {
+	ComboID    int32  `json:"combo_id"`
+	MenuItemID int32  `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Quantity   int32  `json:"quantity"`
+}
+
+func (q *Queries) GetComboComponentsByComboIds(ctx context.Context, comboIds []int32) ([]GetComboComponentsByComboIdsRow, error) {
+	rows, err := q.db.Query(ctx, getComboComponentsByComboIds, comboIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetComboComponentsByComboIdsRow
+	for rows.Next() {
+		var i GetComboComponentsByComboIdsRow
+		if err := rows.Scan(
+			&i.ComboID,
+			&i.MenuItemID,
+			&i.Name,
+			&i.Quantity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeliveryZone = `-- name: GetDeliveryZone :one
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE restaurant_id = $1 AND zip_code = $2
+`
+
+type GetDeliveryZoneParams struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	ZipCode      int32 `json:"zip_code"`
+}
+
+func (q *Queries) GetDeliveryZone(ctx context.Context, arg GetDeliveryZoneParams) (DeliveryZone, error) {
+	row := q.db.QueryRow(ctx, getDeliveryZone, arg.RestaurantID, arg.ZipCode)
+	var i DeliveryZone
+	err := row.Scan(
+		&i.RestaurantID,
+		&i.ZipCode,
+		&i.DeliveryFee,
+		&i.MinimumOrder,
+	)
+	return i, err
+}
+
+const getDeliveryZonesByRestaurantId = `-- name: GetDeliveryZonesByRestaurantId :many
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE restaurant_id = $1
+ORDER BY zip_code
+`
+
+func (q *Queries) GetDeliveryZonesByRestaurantId(ctx context.Context, restaurantID int32) ([]DeliveryZone, error) {
+	rows, err := q.db.Query(ctx, getDeliveryZonesByRestaurantId, restaurantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryZone
+	for rows.Next() {
+		var i DeliveryZone
+		if err := rows.Scan(
+			&i.RestaurantID,
+			&i.ZipCode,
+			&i.DeliveryFee,
+			&i.MinimumOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDeliveryZonesByZipCode = `-- name: GetDeliveryZonesByZipCode :many
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE zip_code = $1
+`
+
+func (q *Queries) GetDeliveryZonesByZipCode(ctx context.Context, zipCode int32) ([]DeliveryZone, error) {
+	rows, err := q.db.Query(ctx, getDeliveryZonesByZipCode, zipCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []DeliveryZone
+	for rows.Next() {
+		var i DeliveryZone
+		if err := rows.Scan(
+			&i.RestaurantID,
+			&i.ZipCode,
+			&i.DeliveryFee,
+			&i.MinimumOrder,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDietaryByMenuItemIds = `-- name: GetDietaryByMenuItemIds :many
+SELECT menu_item_id, allergens, dietary_tags, energy_kcal, fat, saturated_fat, carbohydrates, sugars, protein, salt
+FROM menu_item_dietary
+WHERE menu_item_id = ANY($1::int[])
+`
+
+func (q *Queries) GetDietaryByMenuItemIds(ctx context.Context, menuItemIds []int32) ([]MenuItemDietary, error) {
+	rows, err := q.db.Query(ctx, getDietaryByMenuItemIds, menuItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MenuItemDietary
+	for rows.Next() {
+		var i MenuItemDietary
+		if err := rows.Scan(
+			&i.MenuItemID,
+			&i.Allergens,
+			&i.DietaryTags,
+			&i.EnergyKcal,
+			&i.Fat,
+			&i.SaturatedFat,
+			&i.Carbohydrates,
+			&i.Sugars,
+			&i.Protein,
+			&i.Salt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getImageKeys = `-- name: GetImageKeys :many
+SELECT storage_key, thumbnail_key
+FROM image
+`
+
+type GetImageKeysRow struct {
+	StorageKey   string `json:"storage_key"`
+	ThumbnailKey string `json:"thumbnail_key"`
+}
+
+func (q *Queries) GetImageKeys(ctx context.Context) ([]GetImageKeysRow, error) {
+	rows, err := q.db.Query(ctx, getImageKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetImageKeysRow
+	for rows.Next() {
+		var i GetImageKeysRow
+		if err := rows.Scan(
+			&i.StorageKey,
+			&i.ThumbnailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKitchenCapacityByRestaurantIds = `-- name: GetKitchenCapacityByRestaurantIds :many
+SELECT restaurant_id, max_open_orders, max_items_per_slot, prep_minutes, busy_extra_minutes, busy_mode
+FROM kitchen_capacity
+WHERE restaurant_id = ANY($1::int[])
+`
+
+func (q *Queries) GetKitchenCapacityByRestaurantIds(ctx context.Context, restaurantIds []int32) ([]KitchenCapacity, error) {
+	rows, err := q.db.Query(ctx, getKitchenCapacityByRestaurantIds, restaurantIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []KitchenCapacity
+	for rows.Next() {
+		var i KitchenCapacity
+		if err := rows.Scan(
+			&i.RestaurantID,
+			&i.MaxOpenOrders,
+			&i.MaxItemsPerSlot,
+			&i.PrepMinutes,
+			&i.BusyExtraMinutes,
+			&i.BusyMode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKitchenLoadByRestaurantIds = `-- name: GetKitchenLoadByRestaurantIds :many
+SELECT restaurant_id,
+       COUNT(*) FILTER (WHERE closed_at IS NULL)::int AS open_orders,
+       COALESCE(SUM(item_count) FILTER (WHERE accepted_at >= $1), 0)::int AS slot_items
+FROM kitchen_order
+WHERE restaurant_id = ANY($2::int[])
+  AND (closed_at IS NULL OR accepted_at >= $1)
+GROUP BY restaurant_id
+`
+
+type GetKitchenLoadByRestaurantIdsParams struct {
+	SlotStart     *time.Time `json:"slot_start"`
+	RestaurantIds []int32    `json:"restaurant_ids"`
+}
+
+type GetKitchenLoadByRestaurantIdsRow struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	OpenOrders   int32 `json:"open_orders"`
+	SlotItems    int32 `json:"slot_items"`
+}
+
+// Open orders, and the items of the orders accepted since the slot started
+func (q *Queries) GetKitchenLoadByRestaurantIds(ctx context.Context, arg GetKitchenLoadByRestaurantIdsParams) ([]GetKitchenLoadByRestaurantIdsRow, error) {
+	rows, err := q.db.Query(ctx, getKitchenLoadByRestaurantIds, arg.SlotStart, arg.RestaurantIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetKitchenLoadByRestaurantIdsRow
+	for rows.Next() {
+		var i GetKitchenLoadByRestaurantIdsRow
+		if err := rows.Scan(
+			&i.RestaurantID,
+			&i.OpenOrders,
+			&i.SlotItems,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMenuItemByRestaurantAndId = `-- name: GetMenuItemByRestaurantAndId :one
+SELECT id, restaurantid, name, price, description, deleted_at
+FROM menuitem
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL
 `
 
 type GetMenuItemByRestaurantAndIdParams struct {
@@ -213,8 +1167,875 @@ type GetMenuItemByRestaurantAndIdParams struct {
 	ID           int32 `json:"id"`
 }
 
-func (q *Queries) GetMenuItemByRestaurantAndId(ctx context.Context, arg GetMenuItemByRestaurantAndIdParams) (Menuitem, error) {
-	row := q.db.QueryRow(ctx, getMenuItemByRestaurantAndId, arg.Restaurantid, arg.ID)
+func (q *Queries) GetMenuItemByRestaurantAndId(ctx context.Context, arg GetMenuItemByRestaurantAndIdParams) (Menuitem, error) {
+	row := q.db.QueryRow(ctx, getMenuItemByRestaurantAndId, arg.Restaurantid, arg.ID)
+	var i Menuitem
+	err := row.Scan(
+		&i.ID,
+		&i.Restaurantid,
+		&i.Name,
+		&i.Price,
+		&i.Description,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getMenuItemImages = `-- name: GetMenuItemImages :many
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE menu_item_id = ANY($1::int[])
+`
+
+func (q *Queries) GetMenuItemImages(ctx context.Context, menuItemIds []int32) ([]Image, error) {
+	rows, err := q.db.Query(ctx, getMenuItemImages, menuItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.RestaurantID,
+			&i.MenuItemID,
+			&i.Kind,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMenuOptionsByMenuItemIds = `-- name: GetMenuOptionsByMenuItemIds :many
+SELECT o.id, o.group_id, o.name, o.price_delta, o.position
+FROM menu_option o
+JOIN option_group g ON g.id = o.group_id
+WHERE g.menu_item_id = ANY($1::int[])
+ORDER BY o.group_id, o.position, o.id
+`
+
+func (q *Queries) GetMenuOptionsByMenuItemIds(ctx context.Context, menuItemIds []int32) ([]MenuOption, error) {
+	rows, err := q.db.Query(ctx, getMenuOptionsByMenuItemIds, menuItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MenuOption
+	for rows.Next() {
+		var i MenuOption
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.Name,
+			&i.PriceDelta,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOpeningExceptionsByRestaurantIds = `-- name: GetOpeningExceptionsByRestaurantIds :many
+SELECT id, restaurant_id, date, opens_at, closes_at, note
+FROM opening_exception
+WHERE restaurant_id = ANY($1::int[]) AND date >= $2
+ORDER BY restaurant_id, date, opens_at
+`
+
+type GetOpeningExceptionsByRestaurantIdsParams struct {
+	RestaurantIds []int32     `json:"restaurant_ids"`
+	FromDate      pgtype.Date `json:"from_date"`
+}
+
+func (q *Queries) GetOpeningExceptionsByRestaurantIds(ctx context.Context, arg GetOpeningExceptionsByRestaurantIdsParams) ([]OpeningException, error) {
+	rows, err := q.db.Query(ctx, getOpeningExceptionsByRestaurantIds, arg.RestaurantIds, arg.FromDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OpeningException
+	for rows.Next() {
+		var i OpeningException
+		if err := rows.Scan(
+			&i.ID,
+			&i.RestaurantID,
+			&i.Date,
+			&i.OpensAt,
+			&i.ClosesAt,
+			&i.Note,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOpeningHoursByRestaurantIds = `-- name: GetOpeningHoursByRestaurantIds :many
+SELECT id, restaurant_id, weekday, opens_at, closes_at
+FROM opening_hours
+WHERE restaurant_id = ANY($1::int[])
+ORDER BY restaurant_id, weekday, opens_at
+`
+
+func (q *Queries) GetOpeningHoursByRestaurantIds(ctx context.Context, restaurantIds []int32) ([]OpeningHour, error) {
+	rows, err := q.db.Query(ctx, getOpeningHoursByRestaurantIds, restaurantIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OpeningHour
+	for rows.Next() {
+		var i OpeningHour
+		if err := rows.Scan(
+			&i.ID,
+			&i.RestaurantID,
+			&i.Weekday,
+			&i.OpensAt,
+			&i.ClosesAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOptionGroupsByMenuItemIds = `-- name: GetOptionGroupsByMenuItemIds :many
+SELECT id, menu_item_id, name, selection_type, min_select, max_select, position
+FROM option_group
+WHERE menu_item_id = ANY($1::int[])
+ORDER BY menu_item_id, position, id
+`
+
+func (q *Queries) GetOptionGroupsByMenuItemIds(ctx context.Context, menuItemIds []int32) ([]OptionGroup, error) {
+	rows, err := q.db.Query(ctx, getOptionGroupsByMenuItemIds, menuItemIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []OptionGroup
+	for rows.Next() {
+		var i OptionGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.MenuItemID,
+			&i.Name,
+			&i.SelectionType,
+			&i.MinSelect,
+			&i.MaxSelect,
+			&i.Position,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrphanedImages = `-- name: GetOrphanedImages :many
+SELECT i.id, i.restaurant_id, i.menu_item_id, i.kind, i.storage_key, i.thumbnail_key, i.content_type, i.width, i.height, i.size_bytes, i.created_at
+FROM image i
+JOIN restaurant r ON r.id = i.restaurant_id
+LEFT JOIN menuitem m ON m.id = i.menu_item_id
+WHERE r.deleted_at IS NOT NULL OR m.deleted_at IS NOT NULL
+`
+
+// Images of deleted restaurants and menu items, which are no longer shown
+func (q *Queries) GetOrphanedImages(ctx context.Context) ([]Image, error) {
+	rows, err := q.db.Query(ctx, getOrphanedImages)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.RestaurantID,
+			&i.MenuItemID,
+			&i.Kind,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPriceVersionAt = `-- name: GetPriceVersionAt :one
+SELECT id, menu_item_id, price, valid_from, valid_to
+FROM menu_item_price
+WHERE menu_item_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2)
+`
+
+type GetPriceVersionAtParams struct {
+	MenuItemID int32     `json:"menu_item_id"`
+	ValidFrom  time.Time `json:"valid_from"`
+}
+
+// The price version of the menu item charged at the time
+func (q *Queries) GetPriceVersionAt(ctx context.Context, arg GetPriceVersionAtParams) (MenuItemPrice, error) {
+	row := q.db.QueryRow(ctx, getPriceVersionAt, arg.MenuItemID, arg.ValidFrom)
+	var i MenuItemPrice
+	err := row.Scan(
+		&i.ID,
+		&i.MenuItemID,
+		&i.Price,
+		&i.ValidFrom,
+		&i.ValidTo,
+	)
+	return i, err
+}
+
+const getPriceVersionsByMenuItemId = `-- name: GetPriceVersionsByMenuItemId :many
+SELECT id, menu_item_id, price, valid_from, valid_to
+FROM menu_item_price
+WHERE menu_item_id = $1
+ORDER BY valid_from
+`
+
+func (q *Queries) GetPriceVersionsByMenuItemId(ctx context.Context, menuItemID int32) ([]MenuItemPrice, error) {
+	rows, err := q.db.Query(ctx, getPriceVersionsByMenuItemId, menuItemID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MenuItemPrice
+	for rows.Next() {
+		var i MenuItemPrice
+		if err := rows.Scan(
+			&i.ID,
+			&i.MenuItemID,
+			&i.Price,
+			&i.ValidFrom,
+			&i.ValidTo,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestaurantById = `-- name: GetRestaurantById :one
+SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at
+FROM restaurant
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) GetRestaurantById(ctx context.Context, id int32) (Restaurant, error) {
+	row := q.db.QueryRow(ctx, getRestaurantById, id)
+	var i Restaurant
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Rating,
+		&i.Category,
+		&i.Address,
+		&i.ZipCode,
+		&i.ReviewCount,
+		&i.OrderingPaused,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getRestaurantImages = `-- name: GetRestaurantImages :many
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE restaurant_id = ANY($1::int[]) AND menu_item_id IS NULL
+`
+
+func (q *Queries) GetRestaurantImages(ctx context.Context, restaurantIds []int32) ([]Image, error) {
+	rows, err := q.db.Query(ctx, getRestaurantImages, restaurantIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Image
+	for rows.Next() {
+		var i Image
+		if err := rows.Scan(
+			&i.ID,
+			&i.RestaurantID,
+			&i.MenuItemID,
+			&i.Kind,
+			&i.StorageKey,
+			&i.ThumbnailKey,
+			&i.ContentType,
+			&i.Width,
+			&i.Height,
+			&i.SizeBytes,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestaurantLocations = `-- name: GetRestaurantLocations :many
+SELECT restaurant_id, latitude, longitude
+FROM restaurant_location
+WHERE restaurant_id = ANY($1::int[])
+`
+
+func (q *Queries) GetRestaurantLocations(ctx context.Context, restaurantIds []int32) ([]RestaurantLocation, error) {
+	rows, err := q.db.Query(ctx, getRestaurantLocations, restaurantIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RestaurantLocation
+	for rows.Next() {
+		var i RestaurantLocation
+		if err := rows.Scan(
+			&i.RestaurantID,
+			&i.Latitude,
+			&i.Longitude,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRestaurantReviewStats = `-- name: GetRestaurantReviewStats :one
+SELECT COUNT(*)::int AS review_count, COALESCE(SUM(rating), 0)::int AS rating_sum
+FROM restaurant_review
+WHERE restaurant_id = $1
+`
+
+type GetRestaurantReviewStatsRow struct {
+	ReviewCount int32 `json:"review_count"`
+	RatingSum   int32 `json:"rating_sum"`
+}
+
+func (q *Queries) GetRestaurantReviewStats(ctx context.Context, restaurantID int32) (GetRestaurantReviewStatsRow, error) {
+	row := q.db.QueryRow(ctx, getRestaurantReviewStats, restaurantID)
+	var i GetRestaurantReviewStatsRow
+	err := row.Scan(
+		&i.ReviewCount,
+		&i.RatingSum,
+	)
+	return i, err
+}
+
+const getZipCode = `-- name: GetZipCode :one
+SELECT zip_code, city, latitude, longitude
+FROM zipcode
+WHERE zip_code = $1
+`
+
+func (q *Queries) GetZipCode(ctx context.Context, zipCode int32) (Zipcode, error) {
+	row := q.db.QueryRow(ctx, getZipCode, zipCode)
+	var i Zipcode
+	err := row.Scan(
+		&i.ZipCode,
+		&i.City,
+		&i.Latitude,
+		&i.Longitude,
+	)
+	return i, err
+}
+
+const lockMenuItemImage = `-- name: LockMenuItemImage :one
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE menu_item_id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockMenuItemImage(ctx context.Context, menuItemID *int32) (Image, error) {
+	row := q.db.QueryRow(ctx, lockMenuItemImage, menuItemID)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.RestaurantID,
+		&i.MenuItemID,
+		&i.Kind,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const lockRestaurantImage = `-- name: LockRestaurantImage :one
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE restaurant_id = $1 AND kind = $2 AND menu_item_id IS NULL
+FOR UPDATE
+`
+
+type LockRestaurantImageParams struct {
+	RestaurantID int32  `json:"restaurantid"`
+	Kind         string `json:"kind"`
+}
+
+// The image a new upload replaces, locked until the upload is saved
+func (q *Queries) LockRestaurantImage(ctx context.Context, arg LockRestaurantImageParams) (Image, error) {
+	row := q.db.QueryRow(ctx, lockRestaurantImage, arg.RestaurantID, arg.Kind)
+	var i Image
+	err := row.Scan(
+		&i.ID,
+		&i.RestaurantID,
+		&i.MenuItemID,
+		&i.Kind,
+		&i.StorageKey,
+		&i.ThumbnailKey,
+		&i.ContentType,
+		&i.Width,
+		&i.Height,
+		&i.SizeBytes,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const openKitchenOrder = `-- name: OpenKitchenOrder :exec
+INSERT INTO kitchen_order (order_id, restaurant_id, item_count, accepted_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (order_id) DO NOTHING
+`
+
+type OpenKitchenOrderParams struct {
+	OrderID      int32      `json:"order_id"`
+	RestaurantID int32      `json:"restaurant_id"`
+	ItemCount    int32      `json:"item_count"`
+	AcceptedAt   *time.Time `json:"accepted_at"`
+}
+
+func (q *Queries) OpenKitchenOrder(ctx context.Context, arg OpenKitchenOrderParams) error {
+	_, err := q.db.Exec(ctx, openKitchenOrder,
+		arg.OrderID,
+		arg.RestaurantID,
+		arg.ItemCount,
+		arg.AcceptedAt,
+	)
+	return err
+}
+
+const removeSoldStock = `-- name: RemoveSoldStock :exec
+UPDATE menu_item_availability
+SET sold = GREATEST(sold - $1, 0)
+WHERE menu_item_id = $2 AND stock_date = $3
+`
+
+type RemoveSoldStockParams struct {
+	Quantity   int32       `json:"quantity"`
+	MenuItemID int32       `json:"menu_item_id"`
+	StockDate  pgtype.Date `json:"stock_date"`
+}
+
+// Stock sold on earlier days is not given back, as the daily stock has started over
+func (q *Queries) RemoveSoldStock(ctx context.Context, arg RemoveSoldStockParams) error {
+	_, err := q.db.Exec(ctx, removeSoldStock, arg.Quantity, arg.MenuItemID, arg.StockDate)
+	return err
+}
+
+const search = `-- name: Search :many
+WITH search_query AS (
+    SELECT websearch_to_tsquery('danish', $1::text) AS tsq
+),
+hits AS (
+    SELECT
+        'restaurant'::text AS kind,
+        r.id AS restaurant_id,
+        NULL::int AS menu_item_id,
+        r.name,
+        NULL::numeric AS price,
+        ts_headline('danish', concat_ws(' - ', r.name, r.category), q.tsq, 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5') AS snippet,
+        (ts_rank(restaurant_document(r.name, r.category), q.tsq) + word_similarity($1::text, r.name))::float8 AS rank
+    FROM restaurant r, search_query q
+    WHERE r.deleted_at IS NULL
+      AND (restaurant_document(r.name, r.category) @@ q.tsq OR $1::text <% r.name)
+    UNION ALL
+    SELECT
+        'menu_item'::text,
+        m.restaurantid,
+        m.id,
+        m.name,
+        m.price,
+        ts_headline('danish', concat_ws(' - ', m.name, m.description), q.tsq, 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5'),
+        (ts_rank(menu_item_document(m.name, m.description), q.tsq) + word_similarity($1::text, m.name))::float8
+    FROM menuitem m, search_query q
+    WHERE m.deleted_at IS NULL
+      AND (menu_item_document(m.name, m.description) @@ q.tsq OR $1::text <% m.name)
+      AND NOT EXISTS (
+            SELECT 1 FROM menu_item_dietary d
+            WHERE d.menu_item_id = m.id AND d.allergens && $2::text[])
+      AND (COALESCE(cardinality($3::text[]), 0) = 0 OR EXISTS (
+            SELECT 1 FROM menu_item_dietary d
+            WHERE d.menu_item_id = m.id AND d.dietary_tags @> $3::text[]))
+)
+SELECT
+    h.kind,
+    h.restaurant_id,
+    h.menu_item_id,
+    h.name,
+    h.price,
+    h.snippet,
+    h.rank,
+    r.name AS restaurant_name,
+    r.category,
+    r.rating,
+    r.ordering_paused
+FROM hits h
+JOIN restaurant r ON r.id = h.restaurant_id
+WHERE r.deleted_at IS NULL
+  AND ($4::text IS NULL OR EXISTS (
+        SELECT 1 FROM restaurant_category rc JOIN category c ON c.id = rc.category_id
+        WHERE rc.restaurant_id = r.id AND c.slug = $4))
+  AND ($5::int IS NULL OR EXISTS (
+        SELECT 1 FROM delivery_zone z WHERE z.restaurant_id = r.id AND z.zip_code = $5))
+  AND ($6::float8 IS NULL OR r.rating >= $6)
+ORDER BY h.rank DESC, h.restaurant_id, h.menu_item_id NULLS FIRST
+LIMIT $7::int
+`
+
+type SearchParams struct {
+	Query            string   `json:"query"`
+	ExcludeAllergens []string `json:"exclude_allergens"`
+	DietaryTags      []string `json:"dietary_tags"`
+	Category         *string  `json:"category"`
+	ZipCode          *int32   `json:"zip_code"`
+	MinRating        *float64 `json:"min_rating"`
+	MaxHits          int32    `json:"max_hits"`
+}
+
+type SearchRow struct {
+	Kind           string   `json:"kind"`
+	RestaurantID   int32    `json:"restaurant_id"`
+	MenuItemID     *int32   `json:"menu_item_id"`
+	Name           string   `json:"name"`
+	Price          *float64 `json:"price"`
+	Snippet        string   `json:"snippet"`
+	Rank           float64  `json:"rank"`
+	RestaurantName string   `json:"restaurant_name"`
+	Category       *string  `json:"category"`
+	Rating         *float64 `json:"rating"`
+	OrderingPaused bool     `json:"ordering_paused"`
+}
+
+func (q *Queries) Search(ctx context.Context, arg SearchParams) ([]SearchRow, error) {
+	rows, err := q.db.Query(ctx, search,
+		arg.Query,
+		arg.ExcludeAllergens,
+		arg.DietaryTags,
+		arg.Category,
+		arg.ZipCode,
+		arg.MinRating,
+		arg.MaxHits,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchRow
+	for rows.Next() {
+		var i SearchRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.RestaurantID,
+			&i.MenuItemID,
+			&i.Name,
+			&i.Price,
+			&i.Snippet,
+			&i.Rank,
+			&i.RestaurantName,
+			&i.Category,
+			&i.Rating,
+			&i.OrderingPaused,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setBusyMode = `-- name: SetBusyMode :exec
+INSERT INTO kitchen_capacity (restaurant_id, busy_mode)
+VALUES ($1, $2)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET busy_mode = EXCLUDED.busy_mode
+`
+
+type SetBusyModeParams struct {
+	RestaurantID int32 `json:"restaurant_id"`
+	BusyMode     bool  `json:"busy_mode"`
+}
+
+func (q *Queries) SetBusyMode(ctx context.Context, arg SetBusyModeParams) error {
+	_, err := q.db.Exec(ctx, setBusyMode, arg.RestaurantID, arg.BusyMode)
+	return err
+}
+
+const setKitchenCapacity = `-- name: SetKitchenCapacity :exec
+INSERT INTO kitchen_capacity (restaurant_id, max_open_orders, max_items_per_slot, prep_minutes, busy_extra_minutes)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET max_open_orders = EXCLUDED.max_open_orders,
+    max_items_per_slot = EXCLUDED.max_items_per_slot,
+    prep_minutes = EXCLUDED.prep_minutes,
+    busy_extra_minutes = EXCLUDED.busy_extra_minutes
+`
+
+type SetKitchenCapacityParams struct {
+	RestaurantID     int32  `json:"restaurant_id"`
+	MaxOpenOrders    *int32 `json:"max_open_orders"`
+	MaxItemsPerSlot  *int32 `json:"max_items_per_slot"`
+	PrepMinutes      int32  `json:"prep_minutes"`
+	BusyExtraMinutes int32  `json:"busy_extra_minutes"`
+}
+
+func (q *Queries) SetKitchenCapacity(ctx context.Context, arg SetKitchenCapacityParams) error {
+	_, err := q.db.Exec(ctx, setKitchenCapacity,
+		arg.RestaurantID,
+		arg.MaxOpenOrders,
+		arg.MaxItemsPerSlot,
+		arg.PrepMinutes,
+		arg.BusyExtraMinutes,
+	)
+	return err
+}
+
+const setMenuItemAvailability = `-- name: SetMenuItemAvailability :exec
+INSERT INTO menu_item_availability (menu_item_id, available, daily_stock, sold_out_until)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (menu_item_id) DO UPDATE
+SET available = EXCLUDED.available, daily_stock = EXCLUDED.daily_stock, sold_out_until = EXCLUDED.sold_out_until
+`
+
+type SetMenuItemAvailabilityParams struct {
+	MenuItemID   int32      `json:"menu_item_id"`
+	Available    bool       `json:"available"`
+	DailyStock   *int32     `json:"daily_stock"`
+	SoldOutUntil *time.Time `json:"sold_out_until"`
+}
+
+func (q *Queries) SetMenuItemAvailability(ctx context.Context, arg SetMenuItemAvailabilityParams) error {
+	_, err := q.db.Exec(ctx, setMenuItemAvailability,
+		arg.MenuItemID,
+		arg.Available,
+		arg.DailyStock,
+		arg.SoldOutUntil,
+	)
+	return err
+}
+
+const setMenuItemPrice = `-- name: SetMenuItemPrice :exec
+UPDATE menuitem
+SET price = $2
+WHERE id = $1
+`
+
+type SetMenuItemPriceParams struct {
+	ID    int32   `json:"id"`
+	Price float64 `json:"price"`
+}
+
+func (q *Queries) SetMenuItemPrice(ctx context.Context, arg SetMenuItemPriceParams) error {
+	_, err := q.db.Exec(ctx, setMenuItemPrice, arg.ID, arg.Price)
+	return err
+}
+
+const setPriceVersionEnd = `-- name: SetPriceVersionEnd :exec
+UPDATE menu_item_price
+SET valid_to = $2
+WHERE id = $1
+`
+
+type SetPriceVersionEndParams struct {
+	ID      int32      `json:"id"`
+	ValidTo *time.Time `json:"valid_to"`
+}
+
+func (q *Queries) SetPriceVersionEnd(ctx context.Context, arg SetPriceVersionEndParams) error {
+	_, err := q.db.Exec(ctx, setPriceVersionEnd, arg.ID, arg.ValidTo)
+	return err
+}
+
+const setPrimaryCategory = `-- name: SetPrimaryCategory :exec
+UPDATE restaurant
+SET category = $2
+WHERE id = $1
+`
+
+type SetPrimaryCategoryParams struct {
+	ID       int32   `json:"id"`
+	Category *string `json:"category"`
+}
+
+func (q *Queries) SetPrimaryCategory(ctx context.Context, arg SetPrimaryCategoryParams) error {
+	_, err := q.db.Exec(ctx, setPrimaryCategory, arg.ID, arg.Category)
+	return err
+}
+
+const setRestaurantLocation = `-- name: SetRestaurantLocation :exec
+INSERT INTO restaurant_location (restaurant_id, latitude, longitude)
+VALUES ($1, $2, $3)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude
+`
+
+type SetRestaurantLocationParams struct {
+	RestaurantID int32   `json:"restaurant_id"`
+	Latitude     float64 `json:"latitude"`
+	Longitude    float64 `json:"longitude"`
+}
+
+func (q *Queries) SetRestaurantLocation(ctx context.Context, arg SetRestaurantLocationParams) error {
+	_, err := q.db.Exec(ctx, setRestaurantLocation, arg.RestaurantID, arg.Latitude, arg.Longitude)
+	return err
+}
+
+const softDeleteMenuItem = `-- name: SoftDeleteMenuItem :execrows
+UPDATE menuitem
+SET deleted_at = NOW()
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL
+`
+
+type SoftDeleteMenuItemParams struct {
+	Restaurantid int32 `json:"restaurantid"`
+	ID           int32 `json:"id"`
+}
+
+func (q *Queries) SoftDeleteMenuItem(ctx context.Context, arg SoftDeleteMenuItemParams) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeleteMenuItem, arg.Restaurantid, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const softDeleteRestaurant = `-- name: SoftDeleteRestaurant :execrows
+UPDATE restaurant
+SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) SoftDeleteRestaurant(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, softDeleteRestaurant, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateCategory = `-- name: UpdateCategory :one
+UPDATE category
+SET slug = $2, name_da = $3, name_en = $4
+WHERE id = $1
+RETURNING id, slug, name_da, name_en
+`
+
+type UpdateCategoryParams struct {
+	ID     int32  `json:"id"`
+	Slug   string `json:"slug"`
+	NameDa string `json:"name_da"`
+	NameEn string `json:"name_en"`
+}
+
+// Renaming a slug renames the primary category of its restaurants too
+func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
+	row := q.db.QueryRow(ctx, updateCategory,
+		arg.ID,
+		arg.Slug,
+		arg.NameDa,
+		arg.NameEn,
+	)
+	var i Category
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.NameDa,
+		&i.NameEn,
+	)
+	return i, err
+}
+
+const updateMenuItem = `-- name: UpdateMenuItem :one
+UPDATE menuitem
+SET name = $3, price = $4, description = $5
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL
+RETURNING id, restaurantid, name, price, description, deleted_at
+`
+
+type UpdateMenuItemParams struct {
+	Restaurantid int32   `json:"restaurantid"`
+	ID           int32   `json:"id"`
+	Name         string  `json:"name"`
+	Price        float64 `json:"price"`
+	Description  *string `json:"description"`
+}
+
+func (q *Queries) UpdateMenuItem(ctx context.Context, arg UpdateMenuItemParams) (Menuitem, error) {
+	row := q.db.QueryRow(ctx, updateMenuItem,
+		arg.Restaurantid,
+		arg.ID,
+		arg.Name,
+		arg.Price,
+		arg.Description,
+	)
 	var i Menuitem
 	err := row.Scan(
 		&i.ID,
@@ -222,18 +2043,36 @@ func (q *Queries) GetMenuItemByRestaurantAndId(ctx context.Context, arg GetMenuI
 		&i.Name,
 		&i.Price,
 		&i.Description,
+		&i.DeletedAt,
 	)
 	return i, err
 }
 
-const getRestaurantById = `-- name: GetRestaurantById :one
-SELECT id, name, rating, category, address, zip_code
-FROM restaurant
-WHERE id = $1
+const updateRestaurant = `-- name: UpdateRestaurant :one
+UPDATE restaurant
+SET name = $2, category = $3, address = $4, zip_code = $5, ordering_paused = $6
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at
 `
 
-func (q *Queries) GetRestaurantById(ctx context.Context, id int32) (Restaurant, error) {
-	row := q.db.QueryRow(ctx, getRestaurantById, id)
+type UpdateRestaurantParams struct {
+	ID             int32   `json:"id"`
+	Name           string  `json:"name"`
+	Category       *string `json:"category"`
+	Address        *string `json:"address"`
+	ZipCode        *int32  `json:"zip_code"`
+	OrderingPaused bool    `json:"ordering_paused"`
+}
+
+func (q *Queries) UpdateRestaurant(ctx context.Context, arg UpdateRestaurantParams) (Restaurant, error) {
+	row := q.db.QueryRow(ctx, updateRestaurant,
+		arg.ID,
+		arg.Name,
+		arg.Category,
+		arg.Address,
+		arg.ZipCode,
+		arg.OrderingPaused,
+	)
 	var i Restaurant
 	err := row.Scan(
 		&i.ID,
@@ -242,6 +2081,122 @@ func (q *Queries) GetRestaurantById(ctx context.Context, id int32) (Restaurant,
 		&i.Category,
 		&i.Address,
 		&i.ZipCode,
+		&i.ReviewCount,
+		&i.OrderingPaused,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const updateRestaurantRating = `-- name: UpdateRestaurantRating :exec
+UPDATE restaurant
+SET rating = $1, review_count = $2
+WHERE id = $3
+`
+
+type UpdateRestaurantRatingParams struct {
+	Rating      *float64 `json:"rating"`
+	ReviewCount int32    `json:"review_count"`
+	ID          int32    `json:"id"`
+}
+
+func (q *Queries) UpdateRestaurantRating(ctx context.Context, arg UpdateRestaurantRatingParams) error {
+	_, err := q.db.Exec(ctx, updateRestaurantRating, arg.Rating, arg.ReviewCount, arg.ID)
+	return err
+}
+
+const upsertDeliveryZone = `-- name: UpsertDeliveryZone :one
+INSERT INTO delivery_zone (restaurant_id, zip_code, delivery_fee, minimum_order)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (restaurant_id, zip_code) DO UPDATE
+SET delivery_fee = EXCLUDED.delivery_fee, minimum_order = EXCLUDED.minimum_order
+RETURNING restaurant_id, zip_code, delivery_fee, minimum_order
+`
+
+type UpsertDeliveryZoneParams struct {
+	RestaurantID int32   `json:"restaurant_id"`
+	ZipCode      int32   `json:"zip_code"`
+	DeliveryFee  float64 `json:"delivery_fee"`
+	MinimumOrder float64 `json:"minimum_order"`
+}
+
+func (q *Queries) UpsertDeliveryZone(ctx context.Context, arg UpsertDeliveryZoneParams) (DeliveryZone, error) {
+	row := q.db.QueryRow(ctx, upsertDeliveryZone,
+		arg.RestaurantID,
+		arg.ZipCode,
+		arg.DeliveryFee,
+		arg.MinimumOrder,
+	)
+	var i DeliveryZone
+	err := row.Scan(
+		&i.RestaurantID,
+		&i.ZipCode,
+		&i.DeliveryFee,
+		&i.MinimumOrder,
 	)
 	return i, err
 }
+
+const upsertMenuItemDietary = `-- name: UpsertMenuItemDietary :exec
+INSERT INTO menu_item_dietary (menu_item_id, allergens, dietary_tags, energy_kcal, fat, saturated_fat, carbohydrates, sugars, protein, salt)
+VALUES ($1, COALESCE($2::text[], '{}'), COALESCE($3::text[], '{}'),
+    $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (menu_item_id) DO UPDATE
+SET allergens = COALESCE($2::text[], menu_item_dietary.allergens),
+    dietary_tags = COALESCE($3::text[], menu_item_dietary.dietary_tags),
+    energy_kcal = COALESCE($4, menu_item_dietary.energy_kcal),
+    fat = COALESCE($5, menu_item_dietary.fat),
+    saturated_fat = COALESCE($6, menu_item_dietary.saturated_fat),
+    carbohydrates = COALESCE($7, menu_item_dietary.carbohydrates),
+    sugars = COALESCE($8, menu_item_dietary.sugars),
+    protein = COALESCE($9, menu_item_dietary.protein),
+    salt = COALESCE($10, menu_item_dietary.salt)
+`
+
+type UpsertMenuItemDietaryParams struct {
+	MenuItemID    int32    `json:"menu_item_id"`
+	Allergens     []string `json:"allergens"`
+	DietaryTags   []string `json:"dietary_tags"`
+	EnergyKcal    *int32   `json:"energy_kcal"`
+	Fat           *float64 `json:"fat"`
+	SaturatedFat  *float64 `json:"saturated_fat"`
+	Carbohydrates *float64 `json:"carbohydrates"`
+	Sugars        *float64 `json:"sugars"`
+	Protein       *float64 `json:"protein"`
+	Salt          *float64 `json:"salt"`
+}
+
+// Null arguments leave the current values unchanged
+func (q *Queries) UpsertMenuItemDietary(ctx context.Context, arg UpsertMenuItemDietaryParams) error {
+	_, err := q.db.Exec(ctx, upsertMenuItemDietary,
+		arg.MenuItemID,
+		arg.Allergens,
+		arg.DietaryTags,
+		arg.EnergyKcal,
+		arg.Fat,
+		arg.SaturatedFat,
+		arg.Carbohydrates,
+		arg.Sugars,
+		arg.Protein,
+		arg.Salt,
+	)
+	return err
+}
+
+const upsertRestaurantReview = `-- name: UpsertRestaurantReview :exec
+INSERT INTO restaurant_review (feedback_id, restaurant_id, rating)
+VALUES ($1, $2, $3)
+ON CONFLICT (feedback_id) DO UPDATE
+SET restaurant_id = EXCLUDED.restaurant_id, rating = EXCLUDED.rating, updated_at = NOW()
+`
+
+type UpsertRestaurantReviewParams struct {
+	FeedbackID   int32 `json:"feedback_id"`
+	RestaurantID int32 `json:"restaurant_id"`
+	Rating       int32 `json:"rating"`
+}
+
+func (q *Queries) UpsertRestaurantReview(ctx context.Context, arg UpsertRestaurantReviewParams) error {
+	_, err := q.db.Exec(ctx, upsertRestaurantReview, arg.FeedbackID, arg.RestaurantID, arg.Rating)
+	return err
+}
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE Restaurant
+ADD COLUMN review_count INT NOT NULL DEFAULT 0;
+
+-- One rating per order feedback, kept so ratings can be recomputed and
+-- repeated feedback events do not count twice
+CREATE TABLE restaurant_review (
+    feedback_id INT PRIMARY KEY,
+    restaurant_id INT NOT NULL REFERENCES Restaurant (ID),
+    rating INT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_restaurant_review_restaurant ON restaurant_review (restaurant_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE restaurant_review;
+
+ALTER TABLE Restaurant
+DROP COLUMN review_count;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Restaurants and menu items are soft-deleted, as past orders refer to them
+ALTER TABLE Restaurant
+ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE MenuItem
+ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_menuitem_restaurant ON MenuItem (RestaurantID) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_menuitem_restaurant;
+
+ALTER TABLE MenuItem
+DROP COLUMN deleted_at;
+
+ALTER TABLE Restaurant
+DROP COLUMN deleted_at;
+-- +goose StatementEnd
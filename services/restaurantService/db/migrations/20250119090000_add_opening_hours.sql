@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Weekly opening hours, several intervals per day. Weekdays count from
+-- Sunday = 0, and an interval closing before it opens ends the next day.
+CREATE TABLE opening_hours (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT NOT NULL REFERENCES Restaurant (ID) ON DELETE CASCADE,
+    weekday SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    opens_at TIME NOT NULL,
+    closes_at TIME NOT NULL
+);
+
+CREATE INDEX idx_opening_hours_restaurant ON opening_hours (restaurant_id);
+
+-- Exceptions replace the weekly hours on their date, e.g. holidays. An
+-- exception without times closes the restaurant for the whole day.
+CREATE TABLE opening_exception (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT NOT NULL REFERENCES Restaurant (ID) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    opens_at TIME,
+    closes_at TIME,
+    note TEXT,
+    CHECK ((opens_at IS NULL) = (closes_at IS NULL))
+);
+
+CREATE INDEX idx_opening_exception_restaurant_date ON opening_exception (restaurant_id, date);
+
+ALTER TABLE Restaurant
+ADD COLUMN ordering_paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Restaurants without opening hours are closed, so existing restaurants get
+-- the hours they have been running with
+INSERT INTO opening_hours (restaurant_id, weekday, opens_at, closes_at)
+SELECT r.ID, d.weekday, '10:00', '22:00'
+FROM Restaurant r
+CROSS JOIN generate_series(0, 6) AS d(weekday);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE Restaurant
+DROP COLUMN ordering_paused;
+
+DROP TABLE opening_exception;
+DROP TABLE opening_hours;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The zip codes a restaurant delivers to, with a fee and minimum order per zone
+CREATE TABLE delivery_zone (
+    restaurant_id INT NOT NULL REFERENCES Restaurant (ID) ON DELETE CASCADE,
+    zip_code INT NOT NULL REFERENCES ZipCode (zip_code),
+    delivery_fee DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (delivery_fee >= 0),
+    minimum_order DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK (minimum_order >= 0),
+    PRIMARY KEY (restaurant_id, zip_code)
+);
+
+CREATE INDEX idx_delivery_zone_zip_code ON delivery_zone (zip_code);
+
+-- Existing restaurants keep delivering in their own zip code
+INSERT INTO delivery_zone (restaurant_id, zip_code)
+SELECT ID, zip_code
+FROM Restaurant
+WHERE zip_code IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE delivery_zone;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The searchable text of restaurants and menu items. Names weigh most, and
+-- the indexes below only apply to queries using the same functions.
+CREATE FUNCTION restaurant_document(name TEXT, category TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('danish', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('danish', coalesce(category, '')), 'B')
+$$;
+
+CREATE FUNCTION menu_item_document(name TEXT, description TEXT) RETURNS tsvector
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT setweight(to_tsvector('danish', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('danish', coalesce(description, '')), 'C')
+$$;
+
+CREATE INDEX idx_restaurant_document ON Restaurant USING GIN (restaurant_document(Name, category));
+CREATE INDEX idx_menuitem_document ON MenuItem USING GIN (menu_item_document(Name, Description));
+
+-- Trigram indexes for misspelled and partial names
+CREATE INDEX idx_restaurant_name_trgm ON Restaurant USING GIN (Name gin_trgm_ops);
+CREATE INDEX idx_menuitem_name_trgm ON MenuItem USING GIN (Name gin_trgm_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_menuitem_name_trgm;
+DROP INDEX idx_restaurant_name_trgm;
+DROP INDEX idx_menuitem_document;
+DROP INDEX idx_restaurant_document;
+DROP FUNCTION menu_item_document(TEXT, TEXT);
+DROP FUNCTION restaurant_document(TEXT, TEXT);
+DROP EXTENSION IF EXISTS pg_trgm;
+-- +goose StatementEnd
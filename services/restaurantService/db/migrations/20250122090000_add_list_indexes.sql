@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Keyset pagination walks these indexes instead of sorting the whole table
+CREATE INDEX idx_restaurant_name ON restaurant (name, id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_restaurant_rating ON restaurant ((COALESCE(rating, 0)), id) WHERE deleted_at IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_restaurant_rating;
+DROP INDEX idx_restaurant_name;
+-- +goose StatementEnd
@@ -0,0 +1,44 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Choices offered with a menu item, e.g. pizza size or extra toppings. A single
+-- select group takes at most one option, a multi select group up to max_select.
+CREATE TABLE option_group (
+    id SERIAL PRIMARY KEY,
+    menu_item_id INT NOT NULL REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    selection_type VARCHAR(10) NOT NULL DEFAULT 'single' CHECK (selection_type IN ('single', 'multi')),
+    min_select INT NOT NULL DEFAULT 0 CHECK (min_select >= 0),
+    max_select INT NOT NULL DEFAULT 1 CHECK (max_select >= 1 AND max_select >= min_select),
+    position INT NOT NULL DEFAULT 0,
+    CHECK (selection_type = 'multi' OR max_select = 1)
+);
+
+CREATE INDEX idx_option_group_menu_item ON option_group (menu_item_id);
+
+-- The options of a group, priced as a difference to the menu item price
+CREATE TABLE menu_option (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES option_group (id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    price_delta DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX idx_menu_option_group ON menu_option (group_id);
+
+-- The menu items a combo is made of. The combo is priced as a menu item of its own.
+CREATE TABLE combo_component (
+    combo_id INT NOT NULL REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    menu_item_id INT NOT NULL REFERENCES MenuItem (ID),
+    quantity INT NOT NULL DEFAULT 1 CHECK (quantity > 0),
+    PRIMARY KEY (combo_id, menu_item_id),
+    CHECK (combo_id <> menu_item_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE combo_component;
+DROP TABLE menu_option;
+DROP TABLE option_group;
+-- +goose StatementEnd
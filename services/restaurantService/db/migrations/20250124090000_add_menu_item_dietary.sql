@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Allergens use the 14 allergens EU law requires to be declared, nuts being
+-- tree nuts. Nutrition is per serving, in grams besides the energy, and is
+-- either given in full or not at all.
+CREATE TABLE menu_item_dietary (
+    menu_item_id INT PRIMARY KEY REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    allergens TEXT[] NOT NULL DEFAULT '{}' CHECK (allergens <@ ARRAY[
+        'celery', 'gluten', 'crustaceans', 'eggs', 'fish', 'lupin', 'milk',
+        'molluscs', 'mustard', 'nuts', 'peanuts', 'sesame', 'soya', 'sulphites']),
+    dietary_tags TEXT[] NOT NULL DEFAULT '{}' CHECK (dietary_tags <@ ARRAY[
+        'vegan', 'vegetarian', 'gluten-free', 'lactose-free', 'halal', 'kosher']),
+    energy_kcal INT CHECK (energy_kcal >= 0),
+    fat DECIMAL(6, 2) CHECK (fat >= 0),
+    saturated_fat DECIMAL(6, 2) CHECK (saturated_fat >= 0),
+    carbohydrates DECIMAL(6, 2) CHECK (carbohydrates >= 0),
+    sugars DECIMAL(6, 2) CHECK (sugars >= 0),
+    protein DECIMAL(6, 2) CHECK (protein >= 0),
+    salt DECIMAL(6, 2) CHECK (salt >= 0),
+    CHECK (num_nulls(energy_kcal, fat, saturated_fat, carbohydrates, sugars, protein, salt) IN (0, 7))
+);
+
+CREATE INDEX idx_menu_item_dietary_allergens ON menu_item_dietary USING GIN (allergens);
+CREATE INDEX idx_menu_item_dietary_tags ON menu_item_dietary USING GIN (dietary_tags);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE menu_item_dietary;
+-- +goose StatementEnd
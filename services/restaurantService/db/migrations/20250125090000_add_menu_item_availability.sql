@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Menu items without a row are available without a stock limit. Sold counts
+-- the portions of accepted orders on stock_date, so the daily stock starts
+-- over on a new day.
+CREATE TABLE menu_item_availability (
+    menu_item_id INT PRIMARY KEY REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    available BOOLEAN NOT NULL DEFAULT TRUE,
+    daily_stock INT CHECK (daily_stock >= 0),
+    sold INT NOT NULL DEFAULT 0 CHECK (sold >= 0),
+    stock_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    sold_out_until TIMESTAMP
+);
+
+-- Stock taken by accepted orders, so it is taken once per order and given
+-- back when the order is cancelled
+CREATE TABLE stock_reservation (
+    order_id INT NOT NULL,
+    menu_item_id INT NOT NULL REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    stock_date DATE NOT NULL,
+    PRIMARY KEY (order_id, menu_item_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE stock_reservation;
+DROP TABLE menu_item_availability;
+-- +goose StatementEnd
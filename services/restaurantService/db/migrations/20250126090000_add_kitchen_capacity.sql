@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Restaurants without a row have no capacity limits. Busy mode is switched on
+-- by the restaurant, and lengthens the prep time like being at capacity does.
+CREATE TABLE kitchen_capacity (
+    restaurant_id INT PRIMARY KEY REFERENCES restaurant (id) ON DELETE CASCADE,
+    max_open_orders INT CHECK (max_open_orders > 0),
+    max_items_per_slot INT CHECK (max_items_per_slot > 0),
+    prep_minutes INT NOT NULL DEFAULT 15 CHECK (prep_minutes > 0),
+    busy_extra_minutes INT NOT NULL DEFAULT 15 CHECK (busy_extra_minutes >= 0),
+    busy_mode BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- Accepted orders in the kitchen. Orders are open until they leave the
+-- kitchen, and their items count towards the 15-minute slot they were
+-- accepted in.
+CREATE TABLE kitchen_order (
+    order_id INT PRIMARY KEY,
+    restaurant_id INT NOT NULL REFERENCES restaurant (id) ON DELETE CASCADE,
+    item_count INT NOT NULL CHECK (item_count >= 0),
+    accepted_at TIMESTAMP DEFAULT NOW(),
+    closed_at TIMESTAMP
+);
+
+CREATE INDEX idx_kitchen_order_restaurant ON kitchen_order (restaurant_id, accepted_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE kitchen_order;
+DROP TABLE kitchen_capacity;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Categories are managed by admins. Restaurants can have several, and the
+-- category column of a restaurant is the slug of its primary category.
+CREATE TABLE category (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name_da VARCHAR(100) NOT NULL,
+    name_en VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE restaurant_category (
+    restaurant_id INT NOT NULL REFERENCES restaurant (id) ON DELETE CASCADE,
+    category_id INT NOT NULL REFERENCES category (id) ON DELETE CASCADE,
+    PRIMARY KEY (restaurant_id, category_id)
+);
+
+CREATE INDEX idx_restaurant_category_category ON restaurant_category (category_id);
+
+INSERT INTO category (slug, name_da, name_en) VALUES
+    ('burger', 'Burger', 'Burger'),
+    ('coffee', 'Kaffe', 'Coffee'),
+    ('grill', 'Grill', 'Grill'),
+    ('italian', 'Italiensk', 'Italian'),
+    ('mexican', 'Mexicansk', 'Mexican'),
+    ('pizza', 'Pizza', 'Pizza'),
+    ('sushi', 'Sushi', 'Sushi');
+
+-- Typed categories are mapped to slugs the way the service slugifies them, so
+-- 'Pizza ' and 'pizza' end up in the same category
+UPDATE restaurant
+SET category = NULLIF(trim(BOTH '-' FROM regexp_replace(lower(trim(category)), '[^[:alnum:]]+', '-', 'g')), '');
+
+INSERT INTO category (slug, name_da, name_en)
+SELECT DISTINCT category, initcap(replace(category, '-', ' ')), initcap(replace(category, '-', ' '))
+FROM restaurant
+WHERE category IS NOT NULL
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO restaurant_category (restaurant_id, category_id)
+SELECT r.id, c.id
+FROM restaurant r
+JOIN category c ON c.slug = r.category;
+
+ALTER TABLE restaurant
+    ADD CONSTRAINT fk_restaurant_category FOREIGN KEY (category) REFERENCES category (slug)
+    ON UPDATE CASCADE ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE restaurant DROP CONSTRAINT fk_restaurant_category;
+DROP TABLE restaurant_category;
+DROP TABLE category;
+-- +goose StatementEnd
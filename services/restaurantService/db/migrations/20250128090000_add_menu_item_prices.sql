@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Versions of the menu item prices. A version is charged from valid_from
+-- until valid_to, or until further notice without one. The versions of a
+-- menu item follow each other without gaps, and MenuItem.Price is the price
+-- of the current one.
+CREATE TABLE menu_item_price (
+    id SERIAL PRIMARY KEY,
+    menu_item_id INT NOT NULL REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+    valid_from TIMESTAMP NOT NULL,
+    valid_to TIMESTAMP CHECK (valid_to > valid_from),
+    UNIQUE (menu_item_id, valid_from)
+);
+
+CREATE INDEX menu_item_price_valid_from_idx ON menu_item_price (valid_from);
+
+-- The prices so far become the first versions
+INSERT INTO menu_item_price (menu_item_id, price, valid_from)
+SELECT ID, Price, NOW()
+FROM MenuItem;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE menu_item_price;
+-- +goose StatementEnd
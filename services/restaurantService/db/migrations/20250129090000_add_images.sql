@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Uploaded images, e.g. restaurant logos and menu item photos. The files are
+-- kept in the media storage under storage_key, with a resized copy under
+-- thumbnail_key. A restaurant has at most one logo and one cover image, and
+-- a menu item at most one photo.
+CREATE TABLE image (
+    id SERIAL PRIMARY KEY,
+    restaurant_id INT NOT NULL REFERENCES Restaurant (ID) ON DELETE CASCADE,
+    menu_item_id INT REFERENCES MenuItem (ID) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('logo', 'cover', 'menu_item')),
+    storage_key VARCHAR(100) NOT NULL UNIQUE,
+    thumbnail_key VARCHAR(100) NOT NULL UNIQUE,
+    content_type VARCHAR(50) NOT NULL,
+    width INT NOT NULL CHECK (width > 0),
+    height INT NOT NULL CHECK (height > 0),
+    size_bytes INT NOT NULL CHECK (size_bytes > 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'menu_item') = (menu_item_id IS NOT NULL))
+);
+
+CREATE UNIQUE INDEX image_restaurant_kind_idx ON image (restaurant_id, kind)
+WHERE
+    menu_item_id IS NULL;
+
+CREATE UNIQUE INDEX image_menu_item_idx ON image (menu_item_id)
+WHERE
+    menu_item_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE image;
+-- +goose StatementEnd
@@ -0,0 +1,43 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Approximate centre of each zip code, an offline geocoding of the seeded
+-- Danish zip codes
+ALTER TABLE ZipCode
+    ADD COLUMN latitude double precision,
+    ADD COLUMN longitude double precision;
+
+UPDATE ZipCode z
+SET latitude = c.latitude, longitude = c.longitude
+FROM (VALUES
+    (2800, 55.7704, 12.5038),
+    (2970, 55.8810, 12.5010),
+    (2980, 55.9080, 12.5000)
+) AS c (zip_code, latitude, longitude)
+WHERE z.zip_code = c.zip_code;
+
+-- Where a restaurant is. Restaurants are placed at the centre of their zip
+-- code unless their coordinates are given, and restaurants without a row are
+-- left out of distance searches.
+CREATE TABLE restaurant_location (
+    restaurant_id INT PRIMARY KEY REFERENCES restaurant (id) ON DELETE CASCADE,
+    latitude double precision NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude double precision NOT NULL CHECK (longitude BETWEEN -180 AND 180)
+);
+
+CREATE INDEX idx_restaurant_location_coordinates ON restaurant_location (latitude, longitude);
+
+INSERT INTO restaurant_location (restaurant_id, latitude, longitude)
+SELECT r.id, z.latitude, z.longitude
+FROM restaurant r
+JOIN ZipCode z ON z.zip_code = r.zip_code
+WHERE z.latitude IS NOT NULL AND z.longitude IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE restaurant_location;
+
+ALTER TABLE ZipCode
+    DROP COLUMN latitude,
+    DROP COLUMN longitude;
+-- +goose StatementEnd
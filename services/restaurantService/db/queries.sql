@@ -1,46 +1,514 @@
--- name: FetchAllRestaurants :many
-SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code
-FROM restaurant r
-JOIN zipcode a ON r.zip_code = a.zip_code;
+-- The pages of restaurants are queried in domain/listings.go, which writes
+-- out the ORDER BY of the requested sort so it can be served from an index
 
 -- name: GetRestaurantById :one
-SELECT id, name, rating, category, address, zip_code
+SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at
 FROM restaurant
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: GetMenuItemByRestaurantAndId :one
-SELECT id, restaurantid, name, price, description
+SELECT id, restaurantid, name, price, description, deleted_at
 FROM menuitem
-WHERE restaurantid = $1 AND id = $2;
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL;
 
 -- name: FetchMenuItemsByRestaurantId :many
-SELECT id, restaurantid, name, price, description
+SELECT id, restaurantid, name, price, description, deleted_at
 FROM menuitem
-WHERE restaurantid = $1;
+WHERE restaurantid = $1 AND deleted_at IS NULL;
 
 -- name: CreateZipCode :exec
 INSERT INTO zipcode (zip_code, city)
 VALUES ($1, $2)
 ON CONFLICT (zip_code) DO NOTHING;
 
+-- name: GetZipCode :one
+SELECT zip_code, city, latitude, longitude
+FROM zipcode
+WHERE zip_code = $1;
+
 -- name: CreateRestaurant :one
 INSERT INTO restaurant (name, rating, category, address, zip_code)
 VALUES ($1, $2, $3, $4, $5)
 RETURNING id;
 
+-- name: UpdateRestaurant :one
+UPDATE restaurant
+SET name = $2, category = $3, address = $4, zip_code = $5, ordering_paused = $6
+WHERE id = $1 AND deleted_at IS NULL
+RETURNING id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at;
+
+-- name: SoftDeleteRestaurant :execrows
+UPDATE restaurant
+SET deleted_at = NOW()
+WHERE id = $1 AND deleted_at IS NULL;
+
 -- name: CreateMenuItem :one
 INSERT INTO menuitem (restaurantid, name, price, description)
 VALUES ($1, $2, $3, $4)
 RETURNING id;
 
+-- name: UpdateMenuItem :one
+UPDATE menuitem
+SET name = $3, price = $4, description = $5
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL
+RETURNING id, restaurantid, name, price, description, deleted_at;
+
+-- name: SoftDeleteMenuItem :execrows
+UPDATE menuitem
+SET deleted_at = NOW()
+WHERE restaurantid = $1 AND id = $2 AND deleted_at IS NULL;
+
 -- name: FetchAllCategories :many
-SELECT DISTINCT category
-FROM restaurant
-WHERE category IS NOT NULL
-ORDER BY category;
+SELECT id, slug, name_da, name_en
+FROM category
+ORDER BY slug;
 
 -- name: FilterRestaurantsByCategory :many
-SELECT id, name, rating, category, address, zip_code
-FROM restaurant
-WHERE category ILIKE $1;
+SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code, r.review_count, r.ordering_paused, r.deleted_at
+FROM restaurant r
+JOIN restaurant_category rc ON rc.restaurant_id = r.id
+JOIN category c ON c.id = rc.category_id
+WHERE c.slug = $1 AND r.deleted_at IS NULL
+ORDER BY r.id;
+
+-- name: UpsertRestaurantReview :exec
+INSERT INTO restaurant_review (feedback_id, restaurant_id, rating)
+VALUES ($1, $2, $3)
+ON CONFLICT (feedback_id) DO UPDATE
+SET restaurant_id = EXCLUDED.restaurant_id, rating = EXCLUDED.rating, updated_at = NOW();
+
+-- name: GetRestaurantReviewStats :one
+SELECT COUNT(*)::int AS review_count, COALESCE(SUM(rating), 0)::int AS rating_sum
+FROM restaurant_review
+WHERE restaurant_id = $1;
+
+-- name: UpdateRestaurantRating :exec
+UPDATE restaurant
+SET rating = $1, review_count = $2
+WHERE id = $3;
+
+-- name: GetOpeningHoursByRestaurantIds :many
+SELECT id, restaurant_id, weekday, opens_at, closes_at
+FROM opening_hours
+WHERE restaurant_id = ANY(@restaurant_ids::int[])
+ORDER BY restaurant_id, weekday, opens_at;
+
+-- name: DeleteOpeningHours :exec
+DELETE FROM opening_hours
+WHERE restaurant_id = $1;
+
+-- name: CreateOpeningHours :exec
+INSERT INTO opening_hours (restaurant_id, weekday, opens_at, closes_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetOpeningExceptionsByRestaurantIds :many
+SELECT id, restaurant_id, date, opens_at, closes_at, note
+FROM opening_exception
+WHERE restaurant_id = ANY(@restaurant_ids::int[]) AND date >= @from_date
+ORDER BY restaurant_id, date, opens_at;
+
+-- name: CreateOpeningException :one
+INSERT INTO opening_exception (restaurant_id, date, opens_at, closes_at, note)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, restaurant_id, date, opens_at, closes_at, note;
+
+-- name: DeleteOpeningException :execrows
+DELETE FROM opening_exception
+WHERE restaurant_id = $1 AND id = $2;
+
+-- name: GetDeliveryZonesByRestaurantId :many
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE restaurant_id = $1
+ORDER BY zip_code;
+
+-- name: GetDeliveryZonesByZipCode :many
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE zip_code = $1;
+
+-- name: GetDeliveryZone :one
+SELECT restaurant_id, zip_code, delivery_fee, minimum_order
+FROM delivery_zone
+WHERE restaurant_id = $1 AND zip_code = $2;
+
+-- name: UpsertDeliveryZone :one
+INSERT INTO delivery_zone (restaurant_id, zip_code, delivery_fee, minimum_order)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (restaurant_id, zip_code) DO UPDATE
+SET delivery_fee = EXCLUDED.delivery_fee, minimum_order = EXCLUDED.minimum_order
+RETURNING restaurant_id, zip_code, delivery_fee, minimum_order;
+
+-- name: DeleteDeliveryZone :execrows
+DELETE FROM delivery_zone
+WHERE restaurant_id = $1 AND zip_code = $2;
+
+-- name: Search :many
+WITH search_query AS (
+    SELECT websearch_to_tsquery('danish', @query::text) AS tsq
+),
+hits AS (
+    SELECT
+        'restaurant'::text AS kind,
+        r.id AS restaurant_id,
+        NULL::int AS menu_item_id,
+        r.name,
+        NULL::numeric AS price,
+        ts_headline('danish', concat_ws(' - ', r.name, r.category), q.tsq, 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5') AS snippet,
+        (ts_rank(restaurant_document(r.name, r.category), q.tsq) + word_similarity(@query::text, r.name))::float8 AS rank
+    FROM restaurant r, search_query q
+    WHERE r.deleted_at IS NULL
+      AND (restaurant_document(r.name, r.category) @@ q.tsq OR @query::text <% r.name)
+    UNION ALL
+    SELECT
+        'menu_item'::text,
+        m.restaurantid,
+        m.id,
+        m.name,
+        m.price,
+        ts_headline('danish', concat_ws(' - ', m.name, m.description), q.tsq, 'StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=5'),
+        (ts_rank(menu_item_document(m.name, m.description), q.tsq) + word_similarity(@query::text, m.name))::float8
+    FROM menuitem m, search_query q
+    WHERE m.deleted_at IS NULL
+      AND (menu_item_document(m.name, m.description) @@ q.tsq OR @query::text <% m.name)
+      AND NOT EXISTS (
+            SELECT 1 FROM menu_item_dietary d
+            WHERE d.menu_item_id = m.id AND d.allergens && @exclude_allergens::text[])
+      AND (COALESCE(cardinality(@dietary_tags::text[]), 0) = 0 OR EXISTS (
+            SELECT 1 FROM menu_item_dietary d
+            WHERE d.menu_item_id = m.id AND d.dietary_tags @> @dietary_tags::text[]))
+)
+SELECT
+    h.kind,
+    h.restaurant_id,
+    h.menu_item_id,
+    h.name,
+    h.price,
+    h.snippet,
+    h.rank,
+    r.name AS restaurant_name,
+    r.category,
+    r.rating,
+    r.ordering_paused
+FROM hits h
+JOIN restaurant r ON r.id = h.restaurant_id
+WHERE r.deleted_at IS NULL
+  AND (sqlc.narg(category)::text IS NULL OR EXISTS (
+        SELECT 1 FROM restaurant_category rc JOIN category c ON c.id = rc.category_id
+        WHERE rc.restaurant_id = r.id AND c.slug = sqlc.narg(category)))
+  AND (sqlc.narg(zip_code)::int IS NULL OR EXISTS (
+        SELECT 1 FROM delivery_zone z WHERE z.restaurant_id = r.id AND z.zip_code = sqlc.narg(zip_code)))
+  AND (sqlc.narg(min_rating)::float8 IS NULL OR r.rating >= sqlc.narg(min_rating))
+ORDER BY h.rank DESC, h.restaurant_id, h.menu_item_id NULLS FIRST
+LIMIT @max_hits::int;
+
+-- name: GetOptionGroupsByMenuItemIds :many
+SELECT id, menu_item_id, name, selection_type, min_select, max_select, position
+FROM option_group
+WHERE menu_item_id = ANY(@menu_item_ids::int[])
+ORDER BY menu_item_id, position, id;
+
+-- name: GetMenuOptionsByMenuItemIds :many
+SELECT o.id, o.group_id, o.name, o.price_delta, o.position
+FROM menu_option o
+JOIN option_group g ON g.id = o.group_id
+WHERE g.menu_item_id = ANY(@menu_item_ids::int[])
+ORDER BY o.group_id, o.position, o.id;
+
+-- name: DeleteOptionGroups :exec
+DELETE FROM option_group
+WHERE menu_item_id = $1;
+
+-- name: CreateOptionGroup :one
+INSERT INTO option_group (menu_item_id, name, selection_type, min_select, max_select, position)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: CreateMenuOption :exec
+INSERT INTO menu_option (group_id, name, price_delta, position)
+VALUES ($1, $2, $3, $4);
+
+-- name: GetComboComponentsByComboIds :many
+SELECT c.combo_id, c.menu_item_id, m.name, c.quantity
+FROM combo_component c
+JOIN menuitem m ON m.id = c.menu_item_id
+WHERE c.combo_id = ANY(@combo_ids::int[])
+ORDER BY c.combo_id, m.name;
+
+-- name: DeleteComboComponents :exec
+DELETE FROM combo_component
+WHERE combo_id = $1;
+
+-- name: CreateComboComponent :exec
+INSERT INTO combo_component (combo_id, menu_item_id, quantity)
+VALUES ($1, $2, $3);
+
+-- name: GetDietaryByMenuItemIds :many
+SELECT menu_item_id, allergens, dietary_tags, energy_kcal, fat, saturated_fat, carbohydrates, sugars, protein, salt
+FROM menu_item_dietary
+WHERE menu_item_id = ANY(@menu_item_ids::int[]);
+
+-- Null arguments leave the current values unchanged
+-- name: UpsertMenuItemDietary :exec
+INSERT INTO menu_item_dietary (menu_item_id, allergens, dietary_tags, energy_kcal, fat, saturated_fat, carbohydrates, sugars, protein, salt)
+VALUES (@menu_item_id, COALESCE(sqlc.narg(allergens)::text[], '{}'), COALESCE(sqlc.narg(dietary_tags)::text[], '{}'),
+    sqlc.narg(energy_kcal), sqlc.narg(fat), sqlc.narg(saturated_fat), sqlc.narg(carbohydrates), sqlc.narg(sugars), sqlc.narg(protein), sqlc.narg(salt))
+ON CONFLICT (menu_item_id) DO UPDATE
+SET allergens = COALESCE(sqlc.narg(allergens)::text[], menu_item_dietary.allergens),
+    dietary_tags = COALESCE(sqlc.narg(dietary_tags)::text[], menu_item_dietary.dietary_tags),
+    energy_kcal = COALESCE(sqlc.narg(energy_kcal), menu_item_dietary.energy_kcal),
+    fat = COALESCE(sqlc.narg(fat), menu_item_dietary.fat),
+    saturated_fat = COALESCE(sqlc.narg(saturated_fat), menu_item_dietary.saturated_fat),
+    carbohydrates = COALESCE(sqlc.narg(carbohydrates), menu_item_dietary.carbohydrates),
+    sugars = COALESCE(sqlc.narg(sugars), menu_item_dietary.sugars),
+    protein = COALESCE(sqlc.narg(protein), menu_item_dietary.protein),
+    salt = COALESCE(sqlc.narg(salt), menu_item_dietary.salt);
+
+-- name: GetAvailabilityByMenuItemIds :many
+SELECT menu_item_id, available, daily_stock, sold, stock_date, sold_out_until
+FROM menu_item_availability
+WHERE menu_item_id = ANY(@menu_item_ids::int[]);
+
+-- name: SetMenuItemAvailability :exec
+INSERT INTO menu_item_availability (menu_item_id, available, daily_stock, sold_out_until)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (menu_item_id) DO UPDATE
+SET available = EXCLUDED.available, daily_stock = EXCLUDED.daily_stock, sold_out_until = EXCLUDED.sold_out_until;
+
+-- name: CreateStockReservation :execrows
+INSERT INTO stock_reservation (order_id, menu_item_id, quantity, stock_date)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (order_id, menu_item_id) DO NOTHING;
+
+-- Counts the quantity as sold on the date, starting over on a new day.
+-- Nothing is counted when less than the quantity is left of the daily stock.
+-- name: AddSoldStock :execrows
+UPDATE menu_item_availability
+SET sold = CASE WHEN stock_date = @stock_date THEN sold ELSE 0 END + @quantity,
+    stock_date = @stock_date
+WHERE menu_item_id = @menu_item_id
+    AND (daily_stock IS NULL OR CASE WHEN stock_date = @stock_date THEN sold ELSE 0 END + @quantity <= daily_stock);
+
+-- name: DeleteStockReservations :many
+DELETE FROM stock_reservation
+WHERE order_id = $1
+RETURNING menu_item_id, quantity, stock_date;
+
+-- Stock sold on earlier days is not given back, as the daily stock has started over
+-- name: RemoveSoldStock :exec
+UPDATE menu_item_availability
+SET sold = GREATEST(sold - @quantity, 0)
+WHERE menu_item_id = @menu_item_id AND stock_date = @stock_date;
 
+-- name: GetKitchenCapacityByRestaurantIds :many
+SELECT restaurant_id, max_open_orders, max_items_per_slot, prep_minutes, busy_extra_minutes, busy_mode
+FROM kitchen_capacity
+WHERE restaurant_id = ANY(@restaurant_ids::int[]);
+
+-- name: SetKitchenCapacity :exec
+INSERT INTO kitchen_capacity (restaurant_id, max_open_orders, max_items_per_slot, prep_minutes, busy_extra_minutes)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET max_open_orders = EXCLUDED.max_open_orders,
+    max_items_per_slot = EXCLUDED.max_items_per_slot,
+    prep_minutes = EXCLUDED.prep_minutes,
+    busy_extra_minutes = EXCLUDED.busy_extra_minutes;
+
+-- name: SetBusyMode :exec
+INSERT INTO kitchen_capacity (restaurant_id, busy_mode)
+VALUES ($1, $2)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET busy_mode = EXCLUDED.busy_mode;
+
+-- Open orders, and the items of the orders accepted since the slot started
+-- name: GetKitchenLoadByRestaurantIds :many
+SELECT restaurant_id,
+       COUNT(*) FILTER (WHERE closed_at IS NULL)::int AS open_orders,
+       COALESCE(SUM(item_count) FILTER (WHERE accepted_at >= @slot_start), 0)::int AS slot_items
+FROM kitchen_order
+WHERE restaurant_id = ANY(@restaurant_ids::int[])
+  AND (closed_at IS NULL OR accepted_at >= @slot_start)
+GROUP BY restaurant_id;
+
+-- name: OpenKitchenOrder :exec
+INSERT INTO kitchen_order (order_id, restaurant_id, item_count, accepted_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (order_id) DO NOTHING;
+
+-- name: CloseKitchenOrder :exec
+UPDATE kitchen_order
+SET closed_at = NOW()
+WHERE order_id = $1 AND closed_at IS NULL;
+
+-- name: GetCategoryById :one
+SELECT id, slug, name_da, name_en
+FROM category
+WHERE id = $1;
+
+-- name: GetCategoriesBySlugs :many
+SELECT id, slug, name_da, name_en
+FROM category
+WHERE slug = ANY(@slugs::text[]);
+
+-- name: CreateCategory :one
+INSERT INTO category (slug, name_da, name_en)
+VALUES ($1, $2, $3)
+RETURNING id, slug, name_da, name_en;
+
+-- Renaming a slug renames the primary category of its restaurants too
+-- name: UpdateCategory :one
+UPDATE category
+SET slug = $2, name_da = $3, name_en = $4
+WHERE id = $1
+RETURNING id, slug, name_da, name_en;
+
+-- name: DeleteCategory :execrows
+DELETE FROM category
+WHERE id = $1;
+
+-- The primary category comes first
+-- name: GetCategoriesByRestaurantId :many
+SELECT c.id, c.slug, c.name_da, c.name_en
+FROM category c
+JOIN restaurant_category rc ON rc.category_id = c.id
+JOIN restaurant r ON r.id = rc.restaurant_id
+WHERE rc.restaurant_id = $1
+ORDER BY c.slug = r.category DESC, c.slug;
+
+-- name: AddRestaurantCategory :exec
+INSERT INTO restaurant_category (restaurant_id, category_id)
+VALUES ($1, $2)
+ON CONFLICT (restaurant_id, category_id) DO NOTHING;
+
+-- name: DeleteRestaurantCategories :exec
+DELETE FROM restaurant_category
+WHERE restaurant_id = $1;
+
+-- name: SetPrimaryCategory :exec
+UPDATE restaurant
+SET category = $2
+WHERE id = $1;
+
+-- name: GetPriceVersionsByMenuItemId :many
+SELECT id, menu_item_id, price, valid_from, valid_to
+FROM menu_item_price
+WHERE menu_item_id = $1
+ORDER BY valid_from;
+
+-- The price version of the menu item charged at the time
+-- name: GetPriceVersionAt :one
+SELECT id, menu_item_id, price, valid_from, valid_to
+FROM menu_item_price
+WHERE menu_item_id = $1 AND valid_from <= $2 AND (valid_to IS NULL OR valid_to > $2);
+
+-- name: CreatePriceVersion :one
+INSERT INTO menu_item_price (menu_item_id, price, valid_from, valid_to)
+VALUES ($1, $2, $3, $4)
+RETURNING id, menu_item_id, price, valid_from, valid_to;
+
+-- name: SetPriceVersionEnd :exec
+UPDATE menu_item_price
+SET valid_to = $2
+WHERE id = $1;
+
+-- name: DeletePriceVersion :exec
+DELETE FROM menu_item_price
+WHERE id = $1;
+
+-- name: SetMenuItemPrice :exec
+UPDATE menuitem
+SET price = $2
+WHERE id = $1;
+
+-- Sets menu items to the price of their version at the time, once scheduled
+-- price changes take effect
+-- name: ApplyCurrentPrices :many
+UPDATE menuitem m
+SET price = p.price
+FROM menu_item_price p
+WHERE p.menu_item_id = m.id
+    AND p.valid_from <= $1 AND (p.valid_to IS NULL OR p.valid_to > $1)
+    AND m.price <> p.price AND m.deleted_at IS NULL
+RETURNING m.id, m.restaurantid;
+
+-- name: GetRestaurantImages :many
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE restaurant_id = ANY(@restaurant_ids::int[]) AND menu_item_id IS NULL;
+
+-- name: GetMenuItemImages :many
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE menu_item_id = ANY(@menu_item_ids::int[]);
+
+-- The image a new upload replaces, locked until the upload is saved
+-- name: LockRestaurantImage :one
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE restaurant_id = $1 AND kind = $2 AND menu_item_id IS NULL
+FOR UPDATE;
+
+-- name: LockMenuItemImage :one
+SELECT id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at
+FROM image
+WHERE menu_item_id = $1
+FOR UPDATE;
+
+-- name: CreateImage :one
+INSERT INTO image (restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, restaurant_id, menu_item_id, kind, storage_key, thumbnail_key, content_type, width, height, size_bytes, created_at;
+
+-- name: DeleteImage :exec
+DELETE FROM image
+WHERE id = $1;
+
+-- Images of deleted restaurants and menu items, which are no longer shown
+-- name: GetOrphanedImages :many
+SELECT i.id, i.restaurant_id, i.menu_item_id, i.kind, i.storage_key, i.thumbnail_key, i.content_type, i.width, i.height, i.size_bytes, i.created_at
+FROM image i
+JOIN restaurant r ON r.id = i.restaurant_id
+LEFT JOIN menuitem m ON m.id = i.menu_item_id
+WHERE r.deleted_at IS NOT NULL OR m.deleted_at IS NOT NULL;
+
+-- name: GetImageKeys :many
+SELECT storage_key, thumbnail_key
+FROM image;
+
+-- name: GetRestaurantLocations :many
+SELECT restaurant_id, latitude, longitude
+FROM restaurant_location
+WHERE restaurant_id = ANY(@restaurant_ids::int[]);
+
+-- name: SetRestaurantLocation :exec
+INSERT INTO restaurant_location (restaurant_id, latitude, longitude)
+VALUES ($1, $2, $3)
+ON CONFLICT (restaurant_id) DO UPDATE
+SET latitude = EXCLUDED.latitude, longitude = EXCLUDED.longitude;
+
+-- name: DeleteRestaurantLocation :exec
+DELETE FROM restaurant_location
+WHERE restaurant_id = $1;
+
+-- Restaurants within radius_km of a point, nearest first. The bounding box
+-- lets the coordinates index skip far away restaurants before the haversine
+-- distance is worked out. A box reaching a pole spans every longitude.
+-- name: FetchNearbyRestaurants :many
+SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code, r.review_count, r.ordering_paused, r.deleted_at,
+    l.latitude, l.longitude, d.distance_km::float8 AS distance_km
+FROM restaurant r
+JOIN restaurant_location l ON l.restaurant_id = r.id
+CROSS JOIN LATERAL (
+    SELECT 2 * 6371 * asin(sqrt(
+        power(sin(radians(l.latitude - @latitude::float8) / 2), 2)
+        + cos(radians(@latitude)) * cos(radians(l.latitude))
+        * power(sin(radians(l.longitude - @longitude::float8) / 2), 2))) AS distance_km
+) d
+WHERE r.deleted_at IS NULL
+AND l.latitude BETWEEN @latitude - @radius_km::float8 / 111.2 AND @latitude + @radius_km / 111.2
+AND (abs(@latitude) + @radius_km / 111.2 >= 90
+    OR l.longitude BETWEEN @longitude - @radius_km / (111.2 * NULLIF(cos(radians(@latitude)), 0))
+        AND @longitude + @radius_km / (111.2 * NULLIF(cos(radians(@latitude)), 0)))
+AND d.distance_km <= @radius_km
+ORDER BY d.distance_km, r.id
+LIMIT @page_limit::int;
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ConnectRedis connects to the Redis shared by the replicas for caching. It
+// returns nil when REDIS_HOST is not set, so the service caches in-process only.
+func ConnectRedis() (*redis.Client, error) {
+	redisHost := os.Getenv("REDIS_HOST")
+	if redisHost == "" {
+		return nil, nil
+	}
+	redisPort := os.Getenv("REDIS_PORT")
+	if redisPort == "" {
+		redisPort = "6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%s", redisHost, redisPort),
+		Password: os.Getenv("REDIS_PASSWORD"),
+		DB:       0,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return nil, err
+	}
+	fmt.Println("Successfully connected to Redis!")
+
+	return client, nil
+}
@@ -0,0 +1,314 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	orderEventsExchange = "order_events"
+	// Durable queue shared by the restaurant service replicas
+	orderEventsQueue = "restaurant_order_queue"
+)
+
+// Order statuses that take and give back stock
+const (
+	orderStatusAccepted  = "Accepted"
+	orderStatusCancelled = "Cancelled"
+)
+
+var (
+	ErrInvalidAvailability = errors.New("invalid availability")
+	ErrMenuItemUnavailable = errors.New("menu item unavailable")
+)
+
+// Availability tells whether a menu item can be ordered. Items are
+// unavailable when switched off, sold out until a later time or out of their
+// daily stock. RemainingStock is left out for items without a daily stock.
+type Availability struct {
+	Available      bool       `json:"available" example:"true"`
+	DailyStock     *int32     `json:"daily_stock,omitempty" example:"20"`
+	RemainingStock *int32     `json:"remaining_stock,omitempty" example:"4"`
+	SoldOutUntil   *time.Time `json:"sold_out_until,omitempty"`
+}
+
+// AvailabilityParams replaces the availability of a menu item. Items are
+// available unless switched off, and a missing daily stock means no limit.
+type AvailabilityParams struct {
+	Available    *bool      `json:"available" example:"true"`
+	DailyStock   *int32     `json:"daily_stock" example:"20"`
+	SoldOutUntil *time.Time `json:"sold_out_until"`
+}
+
+// OrderedItem is a menu item of an order with the quantity ordered
+type OrderedItem struct {
+	MenuItemID int32 `json:"menu_item_id"`
+	Quantity   int32 `json:"quantity"`
+}
+
+// OrderStatusChange is the data of the order service's status events
+type OrderStatusChange struct {
+	OrderID      int32         `json:"order_id"`
+	Status       string        `json:"status"`
+	RestaurantID *int32        `json:"restaurant_id"`
+	Items        []OrderedItem `json:"items"`
+}
+
+// orderEvent is the payload of the order service's order events
+type orderEvent struct {
+	OrderID int32           `json:"order_id"`
+	Type    string          `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// toAvailability works out the availability of a menu item at now. Stock sold
+// on an earlier day does not count, as the daily stock starts over.
+func toAvailability(row generated.MenuItemAvailability, now time.Time) Availability {
+	availability := Availability{
+		Available:  row.Available,
+		DailyStock: row.DailyStock,
+	}
+	if row.SoldOutUntil != nil && row.SoldOutUntil.After(now) {
+		availability.Available = false
+		availability.SoldOutUntil = row.SoldOutUntil
+	}
+	if row.DailyStock != nil {
+		remaining := *row.DailyStock
+		if row.StockDate.Valid && row.StockDate.Time.Equal(today()) {
+			remaining = max(0, remaining-row.Sold)
+		}
+		availability.RemainingStock = &remaining
+		if remaining == 0 {
+			availability.Available = false
+		}
+	}
+	return availability
+}
+
+// NextAvailabilityChange is when the availability of the menu items next
+// changes without an event: an item that is sold out until then comes back,
+// or the daily stock starts over at midnight. It is zero when nothing changes
+// by itself.
+func NextAvailabilityChange(items []MenuItemDetail, now time.Time) time.Time {
+	var next time.Time
+	earlier := func(t time.Time) {
+		if t.After(now) && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	for _, item := range items {
+		if item.Availability.SoldOutUntil != nil {
+			earlier(*item.Availability.SoldOutUntil)
+		}
+		if item.Availability.DailyStock != nil {
+			local := now.In(openingHoursLocation)
+			earlier(time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, openingHoursLocation))
+		}
+	}
+	return next
+}
+
+// allows checks that quantity portions of the menu item can be ordered
+func (a Availability) allows(name string, quantity int32) error {
+	switch {
+	case a.SoldOutUntil != nil:
+		return fmt.Errorf("%w: %s is sold out until %s", ErrMenuItemUnavailable, name, a.SoldOutUntil.Format(time.RFC3339))
+	case a.RemainingStock != nil && *a.RemainingStock == 0:
+		return fmt.Errorf("%w: %s is sold out for today", ErrMenuItemUnavailable, name)
+	case !a.Available:
+		return fmt.Errorf("%w: %s is not available", ErrMenuItemUnavailable, name)
+	case a.RemainingStock != nil && quantity > *a.RemainingStock:
+		return fmt.Errorf("%w: only %d of %s left today", ErrMenuItemUnavailable, *a.RemainingStock, name)
+	}
+	return nil
+}
+
+// SetAvailabilityDomain replaces the availability of a menu item
+func (d *RestaurantDomain) SetAvailabilityDomain(ctx context.Context, restaurantId, menuItemId int32, params AvailabilityParams) (*MenuItemDetail, error) {
+	if params.DailyStock != nil && *params.DailyStock < 0 {
+		return nil, fmt.Errorf("%w: daily stock must be zero or more", ErrInvalidAvailability)
+	}
+	available := params.Available == nil || *params.Available
+
+	if _, err := d.GetMenuItemDetailDomain(ctx, restaurantId, menuItemId); err != nil {
+		return nil, err
+	}
+
+	err := d.repo.SetMenuItemAvailability(ctx, generated.SetMenuItemAvailabilityParams{
+		MenuItemID:   menuItemId,
+		Available:    available,
+		DailyStock:   params.DailyStock,
+		SoldOutUntil: params.SoldOutUntil,
+	})
+	if err != nil {
+		return nil, errors.New("failed to save availability: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+
+	return d.GetMenuItemDetailDomain(ctx, restaurantId, menuItemId)
+}
+
+// ConsumeOrderEvents takes stock for accepted orders and gives it back when
+// they are cancelled, and tracks the orders in the kitchen, from the order
+// service's order events
+func (d *RestaurantDomain) ConsumeOrderEvents() {
+	broker.ConsumeFanout(orderEventsExchange, orderEventsQueue, func(event broker.Event) {
+		if event.Type != broker.OrderStatusChanged && event.Type != broker.OrderAgentAssigned {
+			return
+		}
+
+		payloadBytes, err := json.Marshal(event.Payload)
+		if err != nil {
+			log.Printf("Failed to marshal event payload: %v", err)
+			return
+		}
+
+		var orderEvent orderEvent
+		if err := json.Unmarshal(payloadBytes, &orderEvent); err != nil {
+			log.Printf("Failed to unmarshal order event: %v", err)
+			return
+		}
+		var change OrderStatusChange
+		if err := json.Unmarshal(orderEvent.Data, &change); err != nil {
+			log.Printf("Failed to unmarshal order status change: %v", err)
+			return
+		}
+
+		// Handing an order to its delivery agent only takes it out of the kitchen
+		apply := d.ApplyOrderStatusDomain
+		if event.Type == broker.OrderAgentAssigned {
+			apply = d.TrackKitchenOrderDomain
+		}
+		if err := apply(context.Background(), change); err != nil {
+			log.Printf("Failed to apply status of order %d: %v", change.OrderID, err)
+		}
+	})
+}
+
+// ApplyOrderStatusDomain takes the stock of the ordered items when an order is
+// accepted, and gives it back when it is cancelled, or rejected after the
+// order service reserved it. The kitchen load is kept up to date on every
+// status.
+func (d *RestaurantDomain) ApplyOrderStatusDomain(ctx context.Context, change OrderStatusChange) error {
+	if err := d.TrackKitchenOrderDomain(ctx, change); err != nil {
+		return err
+	}
+
+	var err error
+	switch change.Status {
+	case orderStatusAccepted:
+		// The order service reserves the stock before accepting, so this only
+		// fails when it could not
+		err = d.ReserveStockDomain(ctx, change.OrderID, change.Items)
+		if errors.Is(err, ErrMenuItemUnavailable) {
+			log.Printf("Order %d was accepted without the stock for it: %v", change.OrderID, err)
+		}
+	case orderStatusCancelled, orderStatusRejected:
+		err = d.ReleaseStockDomain(ctx, change.OrderID)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if change.RestaurantID != nil {
+		d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: *change.RestaurantID, Action: ActionUpdated})
+	}
+	return nil
+}
+
+// ReserveStockDomain counts the ordered items as sold today. Stock is taken
+// once per order, so redelivered events do not count twice. When less is left
+// of an item's daily stock than was ordered, nothing is taken and it fails
+// with ErrMenuItemUnavailable.
+func (d *RestaurantDomain) ReserveStockDomain(ctx context.Context, orderId int32, items []OrderedItem) error {
+	quantities := map[int32]int32{}
+	for _, item := range items {
+		if item.MenuItemID != 0 && item.Quantity > 0 {
+			quantities[item.MenuItemID] += item.Quantity
+		}
+	}
+	stockDate := pgtype.Date{Time: today(), Valid: true}
+
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		// Rows are locked in id order, so concurrent orders cannot deadlock
+		for _, menuItemId := range slices.Sorted(maps.Keys(quantities)) {
+			quantity := quantities[menuItemId]
+			reserved, err := q.CreateStockReservation(ctx, generated.CreateStockReservationParams{
+				OrderID:    orderId,
+				MenuItemID: menuItemId,
+				Quantity:   quantity,
+				StockDate:  stockDate,
+			})
+			if err != nil {
+				return err
+			}
+			if reserved == 0 {
+				continue
+			}
+			counted, err := q.AddSoldStock(ctx, generated.AddSoldStockParams{
+				StockDate:  stockDate,
+				Quantity:   quantity,
+				MenuItemID: menuItemId,
+			})
+			if err != nil {
+				return err
+			}
+			if counted > 0 {
+				continue
+			}
+			// Nothing is counted for items without a stock limit either
+			availability, err := q.GetAvailabilityByMenuItemIds(ctx, []int32{menuItemId})
+			if err != nil {
+				return err
+			}
+			if len(availability) > 0 && availability[0].DailyStock != nil {
+				return fmt.Errorf("%w: less than %d of menu item %d left today", ErrMenuItemUnavailable, quantity, menuItemId)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, ErrMenuItemUnavailable) {
+		return err
+	}
+	if err != nil {
+		return errors.New("failed to reserve stock: " + err.Error())
+	}
+	return nil
+}
+
+// ReleaseStockDomain gives back the stock taken by an order
+func (d *RestaurantDomain) ReleaseStockDomain(ctx context.Context, orderId int32) error {
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		reservations, err := q.DeleteStockReservations(ctx, orderId)
+		if err != nil {
+			return err
+		}
+		for _, reservation := range reservations {
+			if err := q.RemoveSoldStock(ctx, generated.RemoveSoldStockParams{
+				Quantity:   reservation.Quantity,
+				MenuItemID: reservation.MenuItemID,
+				StockDate:  reservation.StockDate,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.New("failed to release stock: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var availabilityColumns = []string{"menu_item_id", "available", "daily_stock", "sold", "stock_date", "sold_out_until"}
+
+func TestPriceSelectionDomainAvailability(t *testing.T) {
+	todayDate := pgtype.Date{Time: today(), Valid: true}
+	yesterday := pgtype.Date{Time: today().AddDate(0, 0, -1), Valid: true}
+	later := time.Now().Add(time.Hour)
+	earlier := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name     string
+		row      []any
+		quantity int32
+		wantErr  error
+	}{
+		{"No limits", nil, 5, nil},
+		{"Switched off", []any{int32(1), false, nil, int32(0), todayDate, nil}, 1, ErrMenuItemUnavailable},
+		{"Sold out until later", []any{int32(1), true, nil, int32(0), todayDate, &later}, 1, ErrMenuItemUnavailable},
+		{"Sold out until earlier", []any{int32(1), true, nil, int32(0), todayDate, &earlier}, 1, nil},
+		{"Enough stock left", []any{int32(1), true, int32Ptr(10), int32(7), todayDate, nil}, 3, nil},
+		{"Not enough stock left", []any{int32(1), true, int32Ptr(10), int32(7), todayDate, nil}, 4, ErrMenuItemUnavailable},
+		{"Stock sold yesterday", []any{int32(1), true, int32Ptr(10), int32(10), yesterday, nil}, 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			rows := pgxmock.NewRows(availabilityColumns)
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			expectPizza(mock, rows)
+			if tt.wantErr == nil {
+				expectCurrentPrice(mock, pgxmock.NewRows(priceColumns).AddRow(int32(3), int32(1), float64(10), time.Now().Add(-time.Hour), nil))
+			}
+
+			// Act
+			_, err := domain.PriceSelectionDomain(context.Background(), 1, 1, []int32{1}, tt.quantity)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSetAvailabilityDomain(t *testing.T) {
+	t.Run("Rejects a negative daily stock", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		_, err := domain.SetAvailabilityDomain(context.Background(), 1, 1, AvailabilityParams{DailyStock: int32Ptr(-1)})
+
+		if !errors.Is(err, ErrInvalidAvailability) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidAvailability)
+		}
+	})
+
+	t.Run("Sets the daily stock", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectPizza(mock, pgxmock.NewRows(availabilityColumns))
+		mock.ExpectExec(`INSERT INTO menu_item_availability`).
+			WithArgs(int32(1), true, int32Ptr(20), (*time.Time)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectPizza(mock, pgxmock.NewRows(availabilityColumns).
+			AddRow(int32(1), true, int32Ptr(20), int32(0), pgtype.Date{Time: today(), Valid: true}, nil))
+
+		// Act
+		menuItem, err := domain.SetAvailabilityDomain(context.Background(), 1, 1, AvailabilityParams{DailyStock: int32Ptr(20)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !menuItem.Availability.Available || menuItem.Availability.RemainingStock == nil || *menuItem.Availability.RemainingStock != 20 {
+			t.Errorf("got availability %+v, want 20 left", menuItem.Availability)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestApplyOrderStatusDomain(t *testing.T) {
+	stockDate := pgtype.Date{Time: today(), Valid: true}
+
+	t.Run("Takes stock once per order", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO stock_reservation`).
+			WithArgs(int32(7), int32(1), int32(3), stockDate).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE menu_item_availability\s+SET sold = CASE`).
+			WithArgs(stockDate, int32(3), int32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`INSERT INTO stock_reservation`).
+			WithArgs(int32(7), int32(2), int32(1), stockDate).
+			WillReturnResult(pgxmock.NewResult("INSERT", 0))
+		mock.ExpectCommit()
+
+		// Act
+		err := domain.ApplyOrderStatusDomain(context.Background(), OrderStatusChange{
+			OrderID: 7,
+			Status:  "Accepted",
+			Items:   []OrderedItem{{MenuItemID: 2, Quantity: 1}, {MenuItemID: 1, Quantity: 2}, {MenuItemID: 1, Quantity: 1}},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Gives back stock of cancelled orders", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectExec(`UPDATE kitchen_order\s+SET closed_at = NOW\(\)`).
+			WithArgs(int32(7)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM stock_reservation`).
+			WithArgs(int32(7)).
+			WillReturnRows(pgxmock.NewRows([]string{"menu_item_id", "quantity", "stock_date"}).AddRow(int32(1), int32(3), stockDate))
+		mock.ExpectExec(`UPDATE menu_item_availability\s+SET sold = GREATEST`).
+			WithArgs(int32(3), int32(1), stockDate).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		// Act
+		err := domain.ApplyOrderStatusDomain(context.Background(), OrderStatusChange{OrderID: 7, Status: "Cancelled"})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Ignores other statuses", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		err := domain.ApplyOrderStatusDomain(context.Background(), OrderStatusChange{OrderID: 7, Status: "Pending"})
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
+
+func TestReserveStockDomain(t *testing.T) {
+	stockDate := pgtype.Date{Time: today(), Valid: true}
+	expectReservation := func(mock pgxmock.PgxPoolIface) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`INSERT INTO stock_reservation`).
+			WithArgs(int32(7), int32(1), int32(3), stockDate).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE menu_item_availability\s+SET sold = CASE`).
+			WithArgs(stockDate, int32(3), int32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	}
+
+	t.Run("Refuses more than is left of the daily stock", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectReservation(mock)
+		mock.ExpectQuery(`FROM menu_item_availability`).
+			WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows(availabilityColumns).AddRow(int32(1), true, int32Ptr(10), int32(8), stockDate, nil))
+		mock.ExpectRollback()
+
+		// Act
+		err := domain.ReserveStockDomain(context.Background(), 7, []OrderedItem{{MenuItemID: 1, Quantity: 3}})
+
+		// Assert
+		if !errors.Is(err, ErrMenuItemUnavailable) {
+			t.Errorf("got error %v, want %v", err, ErrMenuItemUnavailable)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Takes nothing of items without a daily stock", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectReservation(mock)
+		mock.ExpectQuery(`FROM menu_item_availability`).
+			WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows(availabilityColumns))
+		mock.ExpectCommit()
+
+		// Act
+		err := domain.ReserveStockDomain(context.Background(), 7, []OrderedItem{{MenuItemID: 1, Quantity: 3}})
+
+		// Assert
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestNextAvailabilityChange(t *testing.T) {
+	// 22:30 in Copenhagen
+	now := time.Date(2025, time.January, 25, 21, 30, 0, 0, time.UTC)
+	midnight := time.Date(2025, time.January, 26, 0, 0, 0, 0, openingHoursLocation)
+	soon := now.Add(20 * time.Minute)
+	past := now.Add(-time.Hour)
+	stock := int32(20)
+
+	tests := []struct {
+		name  string
+		items []MenuItemDetail
+		want  time.Time
+	}{
+		{"Nothing Changes By Itself", []MenuItemDetail{{Availability: Availability{Available: true}}}, time.Time{}},
+		{"Sold Out Until", []MenuItemDetail{{Availability: Availability{SoldOutUntil: &soon}}}, soon},
+		{"Daily Stock Starts Over", []MenuItemDetail{{Availability: Availability{DailyStock: &stock}}}, midnight},
+		{"Earliest Change", []MenuItemDetail{
+			{Availability: Availability{DailyStock: &stock}},
+			{Availability: Availability{SoldOutUntil: &soon}},
+		}, soon},
+		{"Past Changes Are Ignored", []MenuItemDetail{{Availability: Availability{SoldOutUntil: &past}}}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := NextAvailabilityChange(tt.items, now)
+
+			// Assert
+			if !got.Equal(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
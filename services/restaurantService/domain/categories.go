@@ -0,0 +1,271 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	uniqueViolation = "23505"
+	maxSlugLength   = 50
+	maxCategoryName = 100
+)
+
+var (
+	ErrCategoryNotFound = errors.New("category not found")
+	ErrInvalidCategory  = errors.New("invalid category")
+	ErrUnknownCategory  = errors.New("unknown category")
+	ErrCategoryExists   = errors.New("category already exists")
+)
+
+var nonSlugChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// CategoryParams creates or changes a category of the taxonomy. The slug is
+// derived from the English name when left out.
+type CategoryParams struct {
+	Slug   *string `json:"slug" example:"pizza"`
+	NameDa *string `json:"name_da" example:"Pizza"`
+	NameEn *string `json:"name_en" example:"Pizza"`
+}
+
+// Slugify normalizes a category to its slug, e.g. ' Italian Food' to
+// 'italian-food'. It matches the slugs the taxonomy migration derived from
+// the categories typed before.
+func Slugify(category string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(category)), "-"), "-")
+}
+
+// slugFilter normalizes a category filter to its slug
+func slugFilter(category *string) *string {
+	if category == nil {
+		return nil
+	}
+	slug := Slugify(*category)
+	return &slug
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// GetAllCategoriesDomain fetches the category taxonomy
+func (d *RestaurantDomain) GetAllCategoriesDomain(ctx context.Context) ([]generated.Category, error) {
+	categories, err := d.repo.FetchAllCategories(ctx)
+	if err != nil {
+		return nil, errors.New("failed to fetch categories")
+	}
+	if categories == nil {
+		categories = []generated.Category{}
+	}
+	return categories, nil
+}
+
+// categoryFields validates the names and slug of a category, falling back to
+// those of current for missing fields
+func categoryFields(params CategoryParams, current generated.Category) (generated.Category, error) {
+	category := current
+	if params.NameDa != nil {
+		category.NameDa = strings.TrimSpace(*params.NameDa)
+	}
+	if params.NameEn != nil {
+		category.NameEn = strings.TrimSpace(*params.NameEn)
+	}
+	switch {
+	case params.Slug != nil:
+		category.Slug = Slugify(*params.Slug)
+	case category.Slug == "":
+		category.Slug = Slugify(category.NameEn)
+	}
+
+	switch {
+	case category.NameDa == "" || category.NameEn == "":
+		return category, fmt.Errorf("%w: both a Danish and an English name are required", ErrInvalidCategory)
+	case len(category.NameDa) > maxCategoryName || len(category.NameEn) > maxCategoryName:
+		return category, fmt.Errorf("%w: names are at most %d characters", ErrInvalidCategory, maxCategoryName)
+	case category.Slug == "":
+		return category, fmt.Errorf("%w: the slug needs a letter or digit", ErrInvalidCategory)
+	case len(category.Slug) > maxSlugLength:
+		return category, fmt.Errorf("%w: slugs are at most %d characters", ErrInvalidCategory, maxSlugLength)
+	}
+	return category, nil
+}
+
+// CreateCategoryDomain adds a category to the taxonomy
+func (d *RestaurantDomain) CreateCategoryDomain(ctx context.Context, params CategoryParams) (*generated.Category, error) {
+	fields, err := categoryFields(params, generated.Category{})
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := d.repo.CreateCategory(ctx, generated.CreateCategoryParams{
+		Slug:   fields.Slug,
+		NameDa: fields.NameDa,
+		NameEn: fields.NameEn,
+	})
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryExists, fields.Slug)
+	}
+	if err != nil {
+		return nil, errors.New("failed to create category: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.CategoryUpdated, RestaurantEvent{CategoryID: &category.ID, Action: ActionCreated})
+	return &category, nil
+}
+
+// UpdateCategoryDomain changes a category. Renaming its slug renames the
+// primary category of its restaurants too.
+func (d *RestaurantDomain) UpdateCategoryDomain(ctx context.Context, categoryId int32, params CategoryParams) (*generated.Category, error) {
+	current, err := d.repo.GetCategoryById(ctx, categoryId)
+	if err != nil {
+		return nil, ErrCategoryNotFound
+	}
+	fields, err := categoryFields(params, current)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := d.repo.UpdateCategory(ctx, generated.UpdateCategoryParams{
+		ID:     categoryId,
+		Slug:   fields.Slug,
+		NameDa: fields.NameDa,
+		NameEn: fields.NameEn,
+	})
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryExists, fields.Slug)
+	}
+	if err != nil {
+		return nil, errors.New("failed to update category: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.CategoryUpdated, RestaurantEvent{CategoryID: &categoryId, Action: ActionUpdated})
+	return &category, nil
+}
+
+// DeleteCategoryDomain removes a category from the taxonomy and from its
+// restaurants
+func (d *RestaurantDomain) DeleteCategoryDomain(ctx context.Context, categoryId int32) error {
+	deleted, err := d.repo.DeleteCategory(ctx, categoryId)
+	if err != nil {
+		return errors.New("failed to delete category: " + err.Error())
+	}
+	if deleted == 0 {
+		return ErrCategoryNotFound
+	}
+
+	d.publishRestaurantEvent(broker.CategoryUpdated, RestaurantEvent{CategoryID: &categoryId, Action: ActionDeleted})
+	return nil
+}
+
+// lookupCategories finds the categories of the slugs, in the order given and
+// without duplicates
+func (d *RestaurantDomain) lookupCategories(ctx context.Context, slugs []string) ([]generated.Category, error) {
+	var normalized []string
+	seen := map[string]bool{}
+	for _, slug := range slugs {
+		slug = Slugify(slug)
+		if !seen[slug] {
+			seen[slug] = true
+			normalized = append(normalized, slug)
+		}
+	}
+
+	if len(normalized) == 0 {
+		return []generated.Category{}, nil
+	}
+
+	rows, err := d.repo.GetCategoriesBySlugs(ctx, normalized)
+	if err != nil {
+		return nil, errors.New("failed to fetch categories: " + err.Error())
+	}
+	bySlug := map[string]generated.Category{}
+	for _, row := range rows {
+		bySlug[row.Slug] = row
+	}
+
+	categories := make([]generated.Category, 0, len(normalized))
+	for _, slug := range normalized {
+		category, ok := bySlug[slug]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCategory, slug)
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// GetRestaurantCategoriesDomain fetches the categories of a restaurant, its
+// primary category first
+func (d *RestaurantDomain) GetRestaurantCategoriesDomain(ctx context.Context, restaurantId int32) ([]generated.Category, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	categories, err := d.repo.GetCategoriesByRestaurantId(ctx, restaurantId)
+	if err != nil {
+		return nil, errors.New("failed to fetch categories: " + err.Error())
+	}
+	if categories == nil {
+		categories = []generated.Category{}
+	}
+	return categories, nil
+}
+
+// SetRestaurantCategoriesDomain replaces the categories of a restaurant by
+// their slugs. The first one becomes the primary category.
+func (d *RestaurantDomain) SetRestaurantCategoriesDomain(ctx context.Context, restaurantId int32, slugs []string) ([]generated.Category, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+	categories, err := d.lookupCategories(ctx, slugs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if err := q.DeleteRestaurantCategories(ctx, restaurantId); err != nil {
+			return err
+		}
+		var primary *string
+		for i, category := range categories {
+			if i == 0 {
+				primary = &category.Slug
+			}
+			if err := q.AddRestaurantCategory(ctx, generated.AddRestaurantCategoryParams{
+				RestaurantID: restaurantId,
+				CategoryID:   category.ID,
+			}); err != nil {
+				return err
+			}
+		}
+		return q.SetPrimaryCategory(ctx, generated.SetPrimaryCategoryParams{ID: restaurantId, Category: primary})
+	})
+	if err != nil {
+		return nil, errors.New("failed to save categories: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return d.GetRestaurantCategoriesDomain(ctx, restaurantId)
+}
+
+// primaryCategory normalizes the primary category of restaurant params to the
+// slug of a known category. Empty categories clear it.
+func (d *RestaurantDomain) primaryCategory(ctx context.Context, category *string) (*generated.Category, error) {
+	if category == nil || Slugify(*category) == "" {
+		return nil, nil
+	}
+	categories, err := d.lookupCategories(ctx, []string{*category})
+	if err != nil {
+		return nil, err
+	}
+	return &categories[0], nil
+}
@@ -0,0 +1,161 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var categoryColumns = []string{"id", "slug", "name_da", "name_en"}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"pizza":         "pizza",
+		"Pizza ":        "pizza",
+		" Italian Food": "italian-food",
+		"Fish & Chips":  "fish-chips",
+		"Smørrebrød":    "smørrebrød",
+		"--Thai--":      "thai",
+		"  ":            "",
+	}
+
+	for category, want := range tests {
+		if got := Slugify(category); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", category, got, want)
+		}
+	}
+}
+
+func TestCreateCategoryDomain(t *testing.T) {
+	t.Run("Derives the slug from the English name", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`INSERT INTO category`).
+			WithArgs("street-food", "Streetfood", "Street Food").
+			WillReturnRows(pgxmock.NewRows(categoryColumns).AddRow(int32(8), "street-food", "Streetfood", "Street Food"))
+
+		// Act
+		category, err := domain.CreateCategoryDomain(context.Background(), CategoryParams{
+			NameDa: stringPtr("Streetfood"),
+			NameEn: stringPtr(" Street Food "),
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if category.Slug != "street-food" {
+			t.Errorf("got slug %q, want %q", category.Slug, "street-food")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Requires both names", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		_, err := domain.CreateCategoryDomain(context.Background(), CategoryParams{NameEn: stringPtr("Pizza")})
+
+		if !errors.Is(err, ErrInvalidCategory) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCategory)
+		}
+	})
+
+	t.Run("Slug taken", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`INSERT INTO category`).
+			WithArgs("pizza", "Pizza", "Pizza").
+			WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+
+		// Act
+		_, err := domain.CreateCategoryDomain(context.Background(), CategoryParams{
+			Slug:   stringPtr("Pizza"),
+			NameDa: stringPtr("Pizza"),
+			NameEn: stringPtr("Pizza"),
+		})
+
+		// Assert
+		if !errors.Is(err, ErrCategoryExists) {
+			t.Errorf("got error %v, want %v", err, ErrCategoryExists)
+		}
+	})
+}
+
+func TestSetRestaurantCategoriesDomain(t *testing.T) {
+	restaurantRow := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil)
+	}
+
+	t.Run("Makes the first category the primary one", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM restaurant\s+WHERE id = \$1`).WithArgs(int32(1)).WillReturnRows(restaurantRow())
+		mock.ExpectQuery(`FROM category\s+WHERE slug = ANY\(\$1::text\[\]\)`).
+			WithArgs([]string{"italian", "pizza"}).
+			WillReturnRows(pgxmock.NewRows(categoryColumns).
+				AddRow(int32(2), "pizza", "Pizza", "Pizza").
+				AddRow(int32(1), "italian", "Italiensk", "Italian"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM restaurant_category`).
+			WithArgs(int32(1)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectExec(`INSERT INTO restaurant_category`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO restaurant_category`).
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`UPDATE restaurant\s+SET category = \$2`).
+			WithArgs(int32(1), stringPtr("italian")).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`FROM restaurant\s+WHERE id = \$1`).WithArgs(int32(1)).WillReturnRows(restaurantRow())
+		mock.ExpectQuery(`JOIN restaurant_category rc ON rc.category_id = c.id`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows(categoryColumns).
+				AddRow(int32(1), "italian", "Italiensk", "Italian").
+				AddRow(int32(2), "pizza", "Pizza", "Pizza"))
+
+		// Act
+		categories, err := domain.SetRestaurantCategoriesDomain(context.Background(), 1, []string{"Italian", "pizza", "italian"})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(categories) != 2 || categories[0].Slug != "italian" {
+			t.Errorf("got categories %+v, want italian first", categories)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Unknown category", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM restaurant\s+WHERE id = \$1`).WithArgs(int32(1)).WillReturnRows(restaurantRow())
+		mock.ExpectQuery(`FROM category\s+WHERE slug = ANY\(\$1::text\[\]\)`).
+			WithArgs([]string{"thai"}).
+			WillReturnRows(pgxmock.NewRows(categoryColumns))
+
+		// Act
+		_, err := domain.SetRestaurantCategoriesDomain(context.Background(), 1, []string{"Thai"})
+
+		// Assert
+		if !errors.Is(err, ErrUnknownCategory) {
+			t.Errorf("got error %v, want %v", err, ErrUnknownCategory)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"context"
+	"errors"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+var (
+	ErrNotDelivered        = errors.New("restaurant does not deliver to the zip code")
+	ErrNegativeDeliveryFee = errors.New("delivery fee and minimum order must be zero or more")
+)
+
+// DeliveryZoneParams sets the delivery terms for a zip code. Missing amounts are zero.
+type DeliveryZoneParams struct {
+	DeliveryFee  *float64 `json:"delivery_fee" example:"29"`
+	MinimumOrder *float64 `json:"minimum_order" example:"100"`
+}
+
+// withDeliveryTerms adds the delivery terms for the zip code to listed restaurants
+func (d *RestaurantDomain) withDeliveryTerms(ctx context.Context, listings []RestaurantListing, zipCode int32) error {
+	if len(listings) == 0 {
+		return nil
+	}
+
+	zones, err := d.repo.GetDeliveryZonesByZipCode(ctx, zipCode)
+	if err != nil {
+		return errors.New("failed to fetch delivery zones: " + err.Error())
+	}
+	byRestaurant := map[int32]generated.DeliveryZone{}
+	for _, zone := range zones {
+		byRestaurant[zone.RestaurantID] = zone
+	}
+	for i := range listings {
+		if zone, ok := byRestaurant[listings[i].ID]; ok {
+			listings[i].Delivery = &zone
+		}
+	}
+
+	return nil
+}
+
+func (d *RestaurantDomain) GetDeliveryZonesDomain(ctx context.Context, restaurantId int32) ([]generated.DeliveryZone, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	zones, err := d.repo.GetDeliveryZonesByRestaurantId(ctx, restaurantId)
+	if err != nil {
+		return nil, errors.New("failed to fetch delivery zones: " + err.Error())
+	}
+	if zones == nil {
+		zones = []generated.DeliveryZone{}
+	}
+	return zones, nil
+}
+
+// GetDeliveryZoneDomain returns the delivery terms of the restaurant for the
+// zip code, or ErrNotDelivered when it does not deliver there
+func (d *RestaurantDomain) GetDeliveryZoneDomain(ctx context.Context, restaurantId int32, zipCode int32) (*generated.DeliveryZone, error) {
+	zone, err := d.repo.GetDeliveryZone(ctx, generated.GetDeliveryZoneParams{
+		RestaurantID: restaurantId,
+		ZipCode:      zipCode,
+	})
+	if err != nil {
+		return nil, ErrNotDelivered
+	}
+	return &zone, nil
+}
+
+// SetDeliveryZoneDomain adds the zip code to the zones of the restaurant, or
+// changes its delivery terms
+func (d *RestaurantDomain) SetDeliveryZoneDomain(ctx context.Context, restaurantId int32, zipCode int32, params DeliveryZoneParams) (*generated.DeliveryZone, error) {
+	upsert := generated.UpsertDeliveryZoneParams{
+		RestaurantID: restaurantId,
+		ZipCode:      zipCode,
+	}
+	if params.DeliveryFee != nil {
+		upsert.DeliveryFee = *params.DeliveryFee
+	}
+	if params.MinimumOrder != nil {
+		upsert.MinimumOrder = *params.MinimumOrder
+	}
+	if upsert.DeliveryFee < 0 || upsert.MinimumOrder < 0 {
+		return nil, ErrNegativeDeliveryFee
+	}
+
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+	if _, err := d.checkZipCode(ctx, &zipCode); err != nil {
+		return nil, err
+	}
+
+	zone, err := d.repo.UpsertDeliveryZone(ctx, upsert)
+	if err != nil {
+		return nil, errors.New("failed to save delivery zone: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return &zone, nil
+}
+
+func (d *RestaurantDomain) DeleteDeliveryZoneDomain(ctx context.Context, restaurantId int32, zipCode int32) error {
+	deleted, err := d.repo.DeleteDeliveryZone(ctx, generated.DeleteDeliveryZoneParams{
+		RestaurantID: restaurantId,
+		ZipCode:      zipCode,
+	})
+	if err != nil {
+		return errors.New("failed to delete delivery zone: " + err.Error())
+	}
+	if deleted == 0 {
+		return ErrNotDelivered
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var deliveryZoneColumns = []string{"restaurant_id", "zip_code", "delivery_fee", "minimum_order"}
+
+func TestGetAllRestaurantsDomainDeliveringTo(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	mock.ExpectQuery(`SELECT 1 FROM delivery_zone z WHERE z.restaurant_id = r.id AND z.zip_code = \$1::int\)\s+ORDER BY COALESCE\(r.rating, 0\) DESC, r.id DESC`).
+		WithArgs(int32Ptr(2800), int32(2)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Sushi"), stringPtr("Second Street 456"), int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil))
+	expectNoOpeningHours(mock, 2)
+	mock.ExpectQuery(`FROM delivery_zone\s+WHERE zip_code = \$1`).
+		WithArgs(int32(2800)).
+		WillReturnRows(pgxmock.NewRows(deliveryZoneColumns).AddRow(int32(2), int32(2800), float64(29), float64(100)))
+	expectLocations(mock, pgxmock.NewRows(locationColumns), 2)
+
+	// Act
+	page, err := domain.GetAllRestaurantsDomain(context.Background(), RestaurantFilter{ZipCode: int32Ptr(2800)}, ListParams{Limit: 1, Sort: "-rating"})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	listings := page.Items
+	if len(listings) != 1 || listings[0].Delivery == nil || listings[0].Delivery.DeliveryFee != 29 || listings[0].Delivery.MinimumOrder != 100 {
+		t.Errorf("unexpected listings: %+v", listings)
+	}
+	if page.NextCursor == nil {
+		t.Fatal("expected a next page")
+	}
+	next, err := ListParams{Cursor: *page.NextCursor, Sort: "-rating"}.decodeCursor("-rating")
+	if err != nil || next.ID != 2 || next.Value != "4.8" {
+		t.Errorf("unexpected next cursor %+v: %v", next, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetDeliveryZoneDomain(t *testing.T) {
+	t.Run("Returns ErrNotDelivered outside the zones", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM delivery_zone\s+WHERE restaurant_id = \$1 AND zip_code = \$2`).
+			WithArgs(int32(1), int32(2100)).
+			WillReturnRows(pgxmock.NewRows(deliveryZoneColumns))
+
+		// Act
+		_, err := domain.GetDeliveryZoneDomain(context.Background(), 1, 2100)
+
+		// Assert
+		if !errors.Is(err, ErrNotDelivered) {
+			t.Errorf("got error %v, want %v", err, ErrNotDelivered)
+		}
+	})
+}
+
+func TestSetDeliveryZoneDomain(t *testing.T) {
+	t.Run("Saves the delivery terms", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at\s+FROM restaurant`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+				AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil))
+		mock.ExpectQuery(`SELECT zip_code, city, latitude, longitude\s+FROM zipcode`).
+			WithArgs(int32(2900)).
+			WillReturnRows(pgxmock.NewRows(zipCodeColumns).AddRow(int32(2900), "Hellerup", nil, nil))
+		mock.ExpectQuery(`INSERT INTO delivery_zone`).
+			WithArgs(int32(1), int32(2900), float64(39), float64(0)).
+			WillReturnRows(pgxmock.NewRows(deliveryZoneColumns).AddRow(int32(1), int32(2900), float64(39), float64(0)))
+
+		// Act
+		zone, err := domain.SetDeliveryZoneDomain(context.Background(), 1, 2900, DeliveryZoneParams{DeliveryFee: float64Ptr(39)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if zone.DeliveryFee != 39 || zone.MinimumOrder != 0 {
+			t.Errorf("unexpected zone: %+v", zone)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects negative amounts", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		// Act
+		_, err := domain.SetDeliveryZoneDomain(context.Background(), 1, 2900, DeliveryZoneParams{MinimumOrder: float64Ptr(-1)})
+
+		// Assert
+		if !errors.Is(err, ErrNegativeDeliveryFee) {
+			t.Errorf("got error %v, want %v", err, ErrNegativeDeliveryFee)
+		}
+	})
+}
+
+func TestDeleteDeliveryZoneDomain(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	mock.ExpectExec(`DELETE FROM delivery_zone`).
+		WithArgs(int32(1), int32(2100)).
+		WillReturnResult(pgxmock.NewResult("DELETE", 0))
+
+	// Act
+	err := domain.DeleteDeliveryZoneDomain(context.Background(), 1, 2100)
+
+	// Assert
+	if !errors.Is(err, ErrNotDelivered) {
+		t.Errorf("got error %v, want %v", err, ErrNotDelivered)
+	}
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Allergens are the 14 allergens EU law requires restaurants to declare.
+// Nuts are tree nuts, and gluten covers all cereals containing it.
+var Allergens = []string{
+	"celery", "gluten", "crustaceans", "eggs", "fish", "lupin", "milk",
+	"molluscs", "mustard", "nuts", "peanuts", "sesame", "soya", "sulphites",
+}
+
+// DietaryTags are the dietary labels menu items can carry
+var DietaryTags = []string{"vegan", "vegetarian", "gluten-free", "lactose-free", "halal", "kosher"}
+
+var ErrInvalidDietaryInfo = errors.New("invalid dietary info")
+
+// Nutrition is the nutritional info of a serving, in grams besides the energy
+type Nutrition struct {
+	EnergyKcal    int32   `json:"energy_kcal" example:"850"`
+	Fat           float64 `json:"fat" example:"32"`
+	SaturatedFat  float64 `json:"saturated_fat" example:"14"`
+	Carbohydrates float64 `json:"carbohydrates" example:"98"`
+	Sugars        float64 `json:"sugars" example:"8"`
+	Protein       float64 `json:"protein" example:"36"`
+	Salt          float64 `json:"salt" example:"4.2"`
+}
+
+// MenuFilter narrows the listed menu items. Items containing any of the
+// excluded allergens are left out, and items must carry all dietary tags.
+type MenuFilter struct {
+	ExcludeAllergens []string
+	DietaryTags      []string
+}
+
+// normalizeTags lowercases and sorts tags, failing on tags not in known
+func normalizeTags(tags []string, known []string, kind string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !slices.Contains(known, tag) {
+			return nil, fmt.Errorf("%w: unknown %s %q", ErrInvalidDietaryInfo, kind, tag)
+		}
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// Validate normalizes the tags of the filter
+func (f *MenuFilter) Validate() error {
+	var err error
+	if f.ExcludeAllergens, err = normalizeTags(f.ExcludeAllergens, Allergens, "allergen"); err != nil {
+		return err
+	}
+	f.DietaryTags, err = normalizeTags(f.DietaryTags, DietaryTags, "dietary tag")
+	return err
+}
+
+func (f MenuFilter) matches(menuItem MenuItemDetail) bool {
+	for _, allergen := range f.ExcludeAllergens {
+		if slices.Contains(menuItem.Allergens, allergen) {
+			return false
+		}
+	}
+	for _, tag := range f.DietaryTags {
+		if !slices.Contains(menuItem.DietaryTags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func validateNutrition(nutrition *Nutrition) error {
+	if nutrition == nil {
+		return nil
+	}
+	if nutrition.EnergyKcal < 0 || nutrition.Fat < 0 || nutrition.SaturatedFat < 0 || nutrition.Carbohydrates < 0 ||
+		nutrition.Sugars < 0 || nutrition.Protein < 0 || nutrition.Salt < 0 {
+		return fmt.Errorf("%w: nutrition values must be zero or more", ErrInvalidDietaryInfo)
+	}
+	return nil
+}
+
+// validateDietary normalizes the allergens and dietary tags of the params
+func validateDietary(params *MenuItemParams) error {
+	var err error
+	if params.Allergens, err = normalizeTags(params.Allergens, Allergens, "allergen"); err != nil {
+		return err
+	}
+	if params.DietaryTags, err = normalizeTags(params.DietaryTags, DietaryTags, "dietary tag"); err != nil {
+		return err
+	}
+	return validateNutrition(params.Nutrition)
+}
+
+// saveDietary stores the dietary info given in the params, leaving the rest unchanged
+func saveDietary(ctx context.Context, q *generated.Queries, menuItemId int32, params MenuItemParams) error {
+	if params.Allergens == nil && params.DietaryTags == nil && params.Nutrition == nil {
+		return nil
+	}
+
+	upsert := generated.UpsertMenuItemDietaryParams{
+		MenuItemID:  menuItemId,
+		Allergens:   params.Allergens,
+		DietaryTags: params.DietaryTags,
+	}
+	if n := params.Nutrition; n != nil {
+		upsert.EnergyKcal = &n.EnergyKcal
+		upsert.Fat = &n.Fat
+		upsert.SaturatedFat = &n.SaturatedFat
+		upsert.Carbohydrates = &n.Carbohydrates
+		upsert.Sugars = &n.Sugars
+		upsert.Protein = &n.Protein
+		upsert.Salt = &n.Salt
+	}
+	return q.UpsertMenuItemDietary(ctx, upsert)
+}
+
+// toNutrition returns the nutrition of the row, nil when it has none
+func toNutrition(row generated.MenuItemDietary) *Nutrition {
+	if row.EnergyKcal == nil || row.Fat == nil || row.SaturatedFat == nil || row.Carbohydrates == nil ||
+		row.Sugars == nil || row.Protein == nil || row.Salt == nil {
+		return nil
+	}
+	return &Nutrition{
+		EnergyKcal:    *row.EnergyKcal,
+		Fat:           *row.Fat,
+		SaturatedFat:  *row.SaturatedFat,
+		Carbohydrates: *row.Carbohydrates,
+		Sugars:        *row.Sugars,
+		Protein:       *row.Protein,
+		Salt:          *row.Salt,
+	}
+}
@@ -0,0 +1,158 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var dietaryColumns = []string{"menu_item_id", "allergens", "dietary_tags", "energy_kcal", "fat", "saturated_fat", "carbohydrates", "sugars", "protein", "salt"}
+
+func TestMenuFilterValidate(t *testing.T) {
+	t.Run("Normalizes the tags", func(t *testing.T) {
+		filter := MenuFilter{ExcludeAllergens: []string{" Nuts", "milk", "nuts"}, DietaryTags: []string{"Vegan"}}
+
+		err := filter.Validate()
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := MenuFilter{ExcludeAllergens: []string{"milk", "nuts"}, DietaryTags: []string{"vegan"}}
+		if !reflect.DeepEqual(filter, want) {
+			t.Errorf("got %+v, want %+v", filter, want)
+		}
+	})
+
+	t.Run("Rejects unknown tags", func(t *testing.T) {
+		for _, filter := range []MenuFilter{
+			{ExcludeAllergens: []string{"shellfish"}},
+			{DietaryTags: []string{"keto"}},
+		} {
+			if err := filter.Validate(); !errors.Is(err, ErrInvalidDietaryInfo) {
+				t.Errorf("%+v: got error %v, want %v", filter, err, ErrInvalidDietaryInfo)
+			}
+		}
+	})
+}
+
+func TestGetMenuItemsByRestaurantIdDomainFiltered(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  MenuFilter
+		wantIds []int32
+	}{
+		{"No filter", MenuFilter{}, []int32{1, 2, 3}},
+		{"Without nuts", MenuFilter{ExcludeAllergens: []string{"nuts"}}, []int32{1, 3}},
+		{"Without milk or nuts", MenuFilter{ExcludeAllergens: []string{"milk", "nuts"}}, []int32{3}},
+		{"Vegan", MenuFilter{DietaryTags: []string{"vegan"}}, []int32{3}},
+		{"Vegetarian and gluten-free", MenuFilter{DietaryTags: []string{"vegetarian", "gluten-free"}}, []int32{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			mock.ExpectQuery(`FROM menuitem WHERE restaurantid = \$1`).
+				WithArgs(int32(1)).
+				WillReturnRows(pgxmock.NewRows(menuItemColumns).
+					AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), nil, nil).
+					AddRow(int32(2), int32(1), "Pesto Pasta", float64(11), nil, nil).
+					AddRow(int32(3), int32(1), "Green Salad", float64(9), nil, nil))
+			mock.ExpectQuery(`FROM option_group`).WithArgs([]int32{1, 2, 3}).WillReturnRows(pgxmock.NewRows(optionGroupColumns))
+			mock.ExpectQuery(`FROM menu_option o`).WithArgs([]int32{1, 2, 3}).WillReturnRows(pgxmock.NewRows(menuOptionColumns))
+			mock.ExpectQuery(`FROM combo_component c`).WithArgs([]int32{1, 2, 3}).WillReturnRows(pgxmock.NewRows(comboComponentColumns))
+			mock.ExpectQuery(`FROM menu_item_dietary`).
+				WithArgs([]int32{1, 2, 3}).
+				WillReturnRows(pgxmock.NewRows(dietaryColumns).
+					AddRow(int32(1), []string{"gluten", "milk"}, []string{"vegetarian"}, nil, nil, nil, nil, nil, nil, nil).
+					AddRow(int32(2), []string{"gluten", "milk", "nuts"}, []string{"vegetarian"}, nil, nil, nil, nil, nil, nil, nil).
+					AddRow(int32(3), []string{}, []string{"gluten-free", "vegan", "vegetarian"},
+						int32Ptr(180), float64Ptr(9), float64Ptr(1.2), float64Ptr(16), float64Ptr(5), float64Ptr(6), float64Ptr(0.8)))
+			mock.ExpectQuery(`FROM menu_item_availability`).WithArgs([]int32{1, 2, 3}).WillReturnRows(pgxmock.NewRows(availabilityColumns))
+
+			// Act
+			got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), 1, tt.filter)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var ids []int32
+			for _, menuItem := range got {
+				ids = append(ids, menuItem.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIds) {
+				t.Errorf("got menu items %v, want %v", ids, tt.wantIds)
+			}
+		})
+	}
+}
+
+func TestUpdateMenuItemDietary(t *testing.T) {
+	t.Run("Saves the dietary info", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), nil, nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE menuitem`).
+			WithArgs(int32(1), int32(1), "Cheese Pizza", float64(12.5), (*string)(nil)).
+			WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), nil, nil))
+		mock.ExpectExec(`INSERT INTO menu_item_dietary`).
+			WithArgs(int32(1), []string{"gluten", "milk"}, []string{}, (*int32)(nil), (*float64)(nil), (*float64)(nil), (*float64)(nil), (*float64)(nil), (*float64)(nil), (*float64)(nil)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`FROM option_group`).WithArgs([]int32{1}).WillReturnRows(pgxmock.NewRows(optionGroupColumns))
+		mock.ExpectQuery(`FROM menu_option o`).WithArgs([]int32{1}).WillReturnRows(pgxmock.NewRows(menuOptionColumns))
+		mock.ExpectQuery(`FROM combo_component c`).WithArgs([]int32{1}).WillReturnRows(pgxmock.NewRows(comboComponentColumns))
+		mock.ExpectQuery(`FROM menu_item_dietary`).
+			WithArgs([]int32{1}).
+			WillReturnRows(pgxmock.NewRows(dietaryColumns).
+				AddRow(int32(1), []string{"gluten", "milk"}, []string{}, nil, nil, nil, nil, nil, nil, nil))
+		mock.ExpectQuery(`FROM menu_item_availability`).WithArgs([]int32{1}).WillReturnRows(pgxmock.NewRows(availabilityColumns))
+
+		// Act
+		menuItem, err := domain.UpdateMenuItemDomain(context.Background(), 1, 1, MenuItemParams{
+			Allergens:   []string{"Milk", "gluten"},
+			DietaryTags: []string{},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(menuItem.Allergens, []string{"gluten", "milk"}) || menuItem.Nutrition != nil {
+			t.Errorf("got allergens %v and nutrition %+v", menuItem.Allergens, menuItem.Nutrition)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects invalid dietary info", func(t *testing.T) {
+		invalid := map[string]MenuItemParams{
+			"unknown allergen":   {Allergens: []string{"shellfish"}},
+			"unknown tag":        {DietaryTags: []string{"keto"}},
+			"negative nutrition": {Nutrition: &Nutrition{EnergyKcal: 500, Fat: -1}},
+		}
+		for name, params := range invalid {
+			mock, _, domain := SetupTestMocks(t)
+			mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+				WithArgs(int32(1), int32(1)).
+				WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), nil, nil))
+
+			_, err := domain.UpdateMenuItemDomain(context.Background(), 1, 1, params)
+
+			if !errors.Is(err, ErrInvalidDietaryInfo) {
+				t.Errorf("%s: got error %v, want %v", name, err, ErrInvalidDietaryInfo)
+			}
+			CloseMocks(mock)
+		}
+	})
+}
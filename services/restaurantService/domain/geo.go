@@ -0,0 +1,191 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	earthRadiusKm = 6371.0
+
+	defaultNearbyRadiusKm = 5.0
+	maxNearbyRadiusKm     = 50.0
+	defaultNearbyLimit    = 20
+	maxNearbyLimit        = 100
+)
+
+var (
+	ErrInvalidCoordinates = errors.New("latitude must be between -90 and 90 and longitude between -180 and 180, and both must be given")
+	ErrInvalidRadius      = fmt.Errorf("radius must be more than 0 and at most %g km", maxNearbyRadiusKm)
+)
+
+// Location is a point on the map, in degrees
+type Location struct {
+	Latitude  float64 `json:"latitude" example:"55.7704"`
+	Longitude float64 `json:"longitude" example:"12.5038"`
+}
+
+func (l Location) valid() bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// HaversineKm is the great-circle distance between two points, in km
+func HaversineKm(from, to Location) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(to.Latitude - from.Latitude)
+	dLon := toRadians(to.Longitude - from.Longitude)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(from.Latitude))*math.Cos(toRadians(to.Latitude))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// roundKm rounds a distance to the meter
+func roundKm(km float64) float64 {
+	return math.Round(km*1000) / 1000
+}
+
+// NearbyParams finds the restaurants within the radius of a point. A missing
+// radius is 5 km, and a missing limit lists 20 restaurants.
+type NearbyParams struct {
+	Location
+	RadiusKm *float64
+	Limit    int
+}
+
+// coordinates reads the location given by a latitude and longitude, nil when
+// neither is given
+func coordinates(latitude, longitude *float64) (*Location, error) {
+	if latitude == nil && longitude == nil {
+		return nil, nil
+	}
+	if latitude == nil || longitude == nil {
+		return nil, ErrInvalidCoordinates
+	}
+	location := Location{Latitude: *latitude, Longitude: *longitude}
+	if !location.valid() {
+		return nil, ErrInvalidCoordinates
+	}
+	return &location, nil
+}
+
+// zipCodeCentre is the centre of a zip code, nil when it is not geocoded
+func zipCodeCentre(zipCode *generated.Zipcode) *Location {
+	if zipCode == nil || zipCode.Latitude == nil || zipCode.Longitude == nil {
+		return nil
+	}
+	return &Location{Latitude: *zipCode.Latitude, Longitude: *zipCode.Longitude}
+}
+
+// saveLocation places the restaurant at location, or removes it from the map
+// when location is nil
+func saveLocation(ctx context.Context, q *generated.Queries, restaurantId int32, location *Location) error {
+	if location == nil {
+		return q.DeleteRestaurantLocation(ctx, restaurantId)
+	}
+	return q.SetRestaurantLocation(ctx, generated.SetRestaurantLocationParams{
+		RestaurantID: restaurantId,
+		Latitude:     location.Latitude,
+		Longitude:    location.Longitude,
+	})
+}
+
+// loadLocations finds where the restaurants are. Restaurants without a
+// location are left out.
+func (d *RestaurantDomain) loadLocations(ctx context.Context, restaurantIds []int32) (map[int32]*Location, error) {
+	rows, err := d.repo.GetRestaurantLocations(ctx, restaurantIds)
+	if err != nil {
+		return nil, errors.New("failed to fetch restaurant locations: " + err.Error())
+	}
+	locations := map[int32]*Location{}
+	for _, row := range rows {
+		locations[row.RestaurantID] = &Location{Latitude: row.Latitude, Longitude: row.Longitude}
+	}
+	return locations, nil
+}
+
+// withLocations adds the location of listed restaurants, and their distance
+// from origin when it is given
+func (d *RestaurantDomain) withLocations(ctx context.Context, listings []RestaurantListing, origin *Location) error {
+	if len(listings) == 0 {
+		return nil
+	}
+	ids := make([]int32, len(listings))
+	for i, listing := range listings {
+		ids[i] = listing.ID
+	}
+	locations, err := d.loadLocations(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range listings {
+		location := locations[listings[i].ID]
+		listings[i].Location = location
+		if origin != nil && location != nil {
+			distance := roundKm(HaversineKm(*origin, *location))
+			listings[i].DistanceKm = &distance
+		}
+	}
+	return nil
+}
+
+// GetNearbyRestaurantsDomain lists the restaurants within the radius of a
+// point, nearest first. Restaurants without a location are never listed.
+func (d *RestaurantDomain) GetNearbyRestaurantsDomain(ctx context.Context, params NearbyParams) ([]RestaurantListing, error) {
+	if !params.valid() {
+		return nil, ErrInvalidCoordinates
+	}
+	radius := defaultNearbyRadiusKm
+	if params.RadiusKm != nil {
+		radius = *params.RadiusKm
+	}
+	if radius <= 0 || radius > maxNearbyRadiusKm {
+		return nil, ErrInvalidRadius
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultNearbyLimit
+	}
+	limit = min(limit, maxNearbyLimit)
+
+	rows, err := d.repo.FetchNearbyRestaurants(ctx, generated.FetchNearbyRestaurantsParams{
+		Latitude:  params.Latitude,
+		Longitude: params.Longitude,
+		RadiusKm:  radius,
+		PageLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch nearby restaurants: " + err.Error())
+	}
+
+	restaurants := make([]generated.Restaurant, len(rows))
+	for i, row := range rows {
+		restaurants[i] = generated.Restaurant{
+			ID:             row.ID,
+			Name:           row.Name,
+			Rating:         row.Rating,
+			Category:       row.Category,
+			Address:        row.Address,
+			ZipCode:        row.ZipCode,
+			ReviewCount:    row.ReviewCount,
+			OrderingPaused: row.OrderingPaused,
+		}
+	}
+	listings, err := d.withOpeningStatus(ctx, restaurants)
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range rows {
+		distance := roundKm(row.DistanceKm)
+		listings[i].Location = &Location{Latitude: row.Latitude, Longitude: row.Longitude}
+		listings[i].DistanceKm = &distance
+	}
+	if listings == nil {
+		listings = []RestaurantListing{}
+	}
+	return listings, nil
+}
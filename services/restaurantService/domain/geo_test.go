@@ -0,0 +1,225 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var nearbyColumns = []string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at", "latitude", "longitude", "distance_km"}
+
+func TestHaversineKm(t *testing.T) {
+	lyngby := Location{Latitude: 55.7704, Longitude: 12.5038}
+	hoersholm := Location{Latitude: 55.8810, Longitude: 12.5010}
+
+	if got := HaversineKm(lyngby, hoersholm); math.Abs(got-12.3) > 0.1 {
+		t.Errorf("got %.3f km, want about 12.3 km", got)
+	}
+	if got := HaversineKm(lyngby, lyngby); got != 0 {
+		t.Errorf("got %.3f km between the same points, want 0", got)
+	}
+}
+
+func TestGetNearbyRestaurantsDomain(t *testing.T) {
+	t.Run("Lists the restaurants nearest first", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_location l ON l.restaurant_id = r.id`).
+			WithArgs(55.77, 12.5, float64(2), int32(20)).
+			WillReturnRows(pgxmock.NewRows(nearbyColumns).
+				AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("pizza"), stringPtr("Lyngby Hovedgade 25"), int32Ptr(2800), int32(12), false, nil, 55.7704, 12.5038, 0.40049))
+		expectNoOpeningHours(mock, 1)
+
+		// Act
+		got, err := domain.GetNearbyRestaurantsDomain(context.Background(), NearbyParams{
+			Location: Location{Latitude: 55.77, Longitude: 12.5},
+			RadiusKm: float64Ptr(2),
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != 1 || got[0].DistanceKm == nil || *got[0].DistanceKm != 0.4 {
+			t.Fatalf("got %+v, want Pizza Paradise 0.4 km away", got)
+		}
+		if got[0].Location == nil || got[0].Location.Latitude != 55.7704 {
+			t.Errorf("got location %+v, want 55.7704, 12.5038", got[0].Location)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Nothing nearby", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_location l ON l.restaurant_id = r.id`).
+			WithArgs(56.0, 10.0, defaultNearbyRadiusKm, int32(maxNearbyLimit)).
+			WillReturnRows(pgxmock.NewRows(nearbyColumns))
+
+		got, err := domain.GetNearbyRestaurantsDomain(context.Background(), NearbyParams{
+			Location: Location{Latitude: 56, Longitude: 10},
+			Limit:    500,
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got == nil || len(got) != 0 {
+			t.Errorf("got %+v, want an empty list", got)
+		}
+	})
+
+	t.Run("Searches from a pole", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`abs\(\$1\) \+ \$3 / 111.2 >= 90\s+OR l.longitude BETWEEN .* NULLIF\(cos\(radians\(\$1\)\), 0\)`).
+			WithArgs(-90.0, 0.0, defaultNearbyRadiusKm, int32(defaultNearbyLimit)).
+			WillReturnRows(pgxmock.NewRows(nearbyColumns))
+
+		_, err := domain.GetNearbyRestaurantsDomain(context.Background(), NearbyParams{
+			Location: Location{Latitude: -90, Longitude: 0},
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		params  NearbyParams
+		wantErr error
+	}{
+		{"Latitude out of range", NearbyParams{Location: Location{Latitude: 91, Longitude: 12.5}}, ErrInvalidCoordinates},
+		{"Longitude out of range", NearbyParams{Location: Location{Latitude: 55.77, Longitude: -181}}, ErrInvalidCoordinates},
+		{"Zero radius", NearbyParams{Location: Location{Latitude: 55.77, Longitude: 12.5}, RadiusKm: float64Ptr(0)}, ErrInvalidRadius},
+		{"Radius too large", NearbyParams{Location: Location{Latitude: 55.77, Longitude: 12.5}, RadiusKm: float64Ptr(51)}, ErrInvalidRadius},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+
+			_, err := domain.GetNearbyRestaurantsDomain(context.Background(), tt.params)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetAllRestaurantsDomainWithDistance(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	mock.ExpectQuery(`FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a`).
+		WithArgs(noRestaurantFilter...).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("pizza"), nil, int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(2), "Burger Bonanza", float64Ptr(4.2), stringPtr("burger"), nil, int32Ptr(2970), int32(3), false, nil))
+	expectNoOpeningHours(mock, 1, 2)
+	expectLocations(mock, pgxmock.NewRows(locationColumns).AddRow(int32(1), 55.7704, 12.5038), 1, 2)
+
+	// Act
+	page, err := domain.GetAllRestaurantsDomain(context.Background(), RestaurantFilter{Origin: &Location{Latitude: 55.8810, Longitude: 12.5010}}, ListParams{})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if distance := page.Items[0].DistanceKm; distance == nil || math.Abs(*distance-12.3) > 0.1 {
+		t.Errorf("got distance %v, want about 12.3 km", distance)
+	}
+	if page.Items[1].Location != nil || page.Items[1].DistanceKm != nil {
+		t.Errorf("expected no distance without a location, got %+v", page.Items[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestUpdateRestaurantDomainLocation(t *testing.T) {
+	restaurantColumns := []string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}
+	expectUpdate := func(mock pgxmock.PgxPoolIface, zipCode int32) {
+		mock.ExpectQuery(`UPDATE restaurant`).
+			WithArgs(int32(1), "Pizza Paradise", (*string)(nil), (*string)(nil), int32Ptr(zipCode), false).
+			WillReturnRows(pgxmock.NewRows(restaurantColumns).AddRow(int32(1), "Pizza Paradise", nil, nil, nil, int32Ptr(zipCode), int32(0), false, nil))
+	}
+
+	t.Run("Moves to the centre of the new zip code", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectRestaurant(mock)
+		mock.ExpectQuery(`FROM zipcode`).
+			WithArgs(int32(2970)).
+			WillReturnRows(pgxmock.NewRows(zipCodeColumns).AddRow(int32(2970), "Hørsholm", float64Ptr(55.8810), float64Ptr(12.5010)))
+		mock.ExpectBegin()
+		expectUpdate(mock, 2970)
+		mock.ExpectExec(`INSERT INTO restaurant_location`).
+			WithArgs(int32(1), 55.8810, 12.5010).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		// Act
+		_, err := domain.UpdateRestaurantDomain(context.Background(), 1, RestaurantParams{ZipCode: int32Ptr(2970)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Given coordinates win over the zip code", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectRestaurant(mock)
+		mock.ExpectQuery(`FROM zipcode`).
+			WithArgs(int32(2970)).
+			WillReturnRows(pgxmock.NewRows(zipCodeColumns).AddRow(int32(2970), "Hørsholm", float64Ptr(55.8810), float64Ptr(12.5010)))
+		mock.ExpectBegin()
+		expectUpdate(mock, 2970)
+		mock.ExpectExec(`INSERT INTO restaurant_location`).
+			WithArgs(int32(1), 55.8835, 12.4982).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+
+		_, err := domain.UpdateRestaurantDomain(context.Background(), 1, RestaurantParams{
+			ZipCode:   int32Ptr(2970),
+			Latitude:  float64Ptr(55.8835),
+			Longitude: float64Ptr(12.4982),
+		})
+
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects a latitude without a longitude", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectRestaurant(mock)
+
+		_, err := domain.UpdateRestaurantDomain(context.Background(), 1, RestaurantParams{Latitude: float64Ptr(55.8835)})
+
+		if !errors.Is(err, ErrInvalidCoordinates) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCoordinates)
+		}
+	})
+}
@@ -0,0 +1,323 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/media"
+)
+
+// Kinds of images. Restaurants have a logo and a cover image, and menu items
+// a photo.
+const (
+	ImageLogo     = "logo"
+	ImageCover    = "cover"
+	ImageMenuItem = "menu_item"
+)
+
+var (
+	ErrImageNotFound    = errors.New("image not found")
+	ErrInvalidImageKind = errors.New("image kind must be logo or cover")
+	ErrMediaUnavailable = errors.New("image storage is not configured")
+)
+
+// ImageURLs are where clients download an image and its thumbnail
+type ImageURLs struct {
+	URL          string `json:"url" example:"/media/3f2a9c0e4b7d1a6e8c5b2f0d9e7a4c1b.jpg"`
+	ThumbnailURL string `json:"thumbnail_url" example:"/media/8d1e6b3a0f9c2e7d4a5b1c8f0e3d6a9b.jpg"`
+	Width        int32  `json:"width" example:"1200"`
+	Height       int32  `json:"height" example:"800"`
+}
+
+// RestaurantImages are the images of a restaurant, nil when not uploaded
+type RestaurantImages struct {
+	Logo  *ImageURLs `json:"logo"`
+	Cover *ImageURLs `json:"cover"`
+}
+
+// RestaurantDetail is a restaurant with its images and location
+type RestaurantDetail struct {
+	generated.Restaurant
+	Images   RestaurantImages `json:"images"`
+	Location *Location        `json:"location,omitempty"`
+}
+
+func (d *RestaurantDomain) imageURLs(image generated.Image) *ImageURLs {
+	return &ImageURLs{
+		URL:          d.media.URL(image.StorageKey),
+		ThumbnailURL: d.media.URL(image.ThumbnailKey),
+		Width:        image.Width,
+		Height:       image.Height,
+	}
+}
+
+// loadRestaurantImages finds the images of the restaurants. Without a media
+// storage there are no URLs to show, so none are looked up.
+func (d *RestaurantDomain) loadRestaurantImages(ctx context.Context, restaurantIds []int32) (map[int32]RestaurantImages, error) {
+	images := map[int32]RestaurantImages{}
+	if d.media == nil {
+		return images, nil
+	}
+
+	rows, err := d.repo.GetRestaurantImages(ctx, restaurantIds)
+	if err != nil {
+		return nil, errors.New("failed to fetch restaurant images: " + err.Error())
+	}
+	for _, row := range rows {
+		restaurantImages := images[row.RestaurantID]
+		switch row.Kind {
+		case ImageLogo:
+			restaurantImages.Logo = d.imageURLs(row)
+		case ImageCover:
+			restaurantImages.Cover = d.imageURLs(row)
+		}
+		images[row.RestaurantID] = restaurantImages
+	}
+	return images, nil
+}
+
+// loadMenuItemImages finds the photos of the menu items, like
+// loadRestaurantImages
+func (d *RestaurantDomain) loadMenuItemImages(ctx context.Context, menuItemIds []int32) (map[int32]*ImageURLs, error) {
+	images := map[int32]*ImageURLs{}
+	if d.media == nil {
+		return images, nil
+	}
+
+	rows, err := d.repo.GetMenuItemImages(ctx, menuItemIds)
+	if err != nil {
+		return nil, errors.New("failed to fetch menu item images: " + err.Error())
+	}
+	for _, row := range rows {
+		if row.MenuItemID != nil {
+			images[*row.MenuItemID] = d.imageURLs(row)
+		}
+	}
+	return images, nil
+}
+
+// UploadRestaurantImageDomain sets the logo or cover image of a restaurant,
+// replacing the one it had
+func (d *RestaurantDomain) UploadRestaurantImageDomain(ctx context.Context, restaurantId int32, kind string, data []byte) (*ImageURLs, error) {
+	if kind != ImageLogo && kind != ImageCover {
+		return nil, ErrInvalidImageKind
+	}
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	image, err := d.saveImage(ctx, generated.CreateImageParams{RestaurantID: restaurantId, Kind: kind}, data, func(q *generated.Queries) (generated.Image, error) {
+		return q.LockRestaurantImage(ctx, generated.LockRestaurantImageParams{RestaurantID: restaurantId, Kind: kind})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return d.imageURLs(*image), nil
+}
+
+// UploadMenuItemImageDomain sets the photo of a menu item, replacing the one
+// it had
+func (d *RestaurantDomain) UploadMenuItemImageDomain(ctx context.Context, restaurantId, menuItemId int32, data []byte) (*ImageURLs, error) {
+	_, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	})
+	if err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+
+	image, err := d.saveImage(ctx, generated.CreateImageParams{RestaurantID: restaurantId, MenuItemID: &menuItemId, Kind: ImageMenuItem}, data, func(q *generated.Queries) (generated.Image, error) {
+		return q.LockMenuItemImage(ctx, &menuItemId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+
+	return d.imageURLs(*image), nil
+}
+
+// saveImage stores an upload with its thumbnail and saves it in place of the
+// image found by lock. The files of the replaced image are deleted once the
+// new one is saved. Files left behind by failures are removed by
+// CleanupImagesDomain.
+func (d *RestaurantDomain) saveImage(ctx context.Context, params generated.CreateImageParams, data []byte, lock func(q *generated.Queries) (generated.Image, error)) (*generated.Image, error) {
+	if d.media == nil {
+		return nil, ErrMediaUnavailable
+	}
+	processed, err := media.Process(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.StorageKey, err = media.NewKey(media.Extension(processed.ContentType)); err != nil {
+		return nil, err
+	}
+	if params.ThumbnailKey, err = media.NewKey(media.Extension(processed.ThumbnailContentType)); err != nil {
+		return nil, err
+	}
+	params.ContentType = processed.ContentType
+	params.Width = int32(processed.Width)
+	params.Height = int32(processed.Height)
+	params.SizeBytes = int32(len(processed.Data))
+
+	if err := d.media.Put(ctx, params.StorageKey, processed.ContentType, processed.Data); err != nil {
+		return nil, errors.New("failed to store image: " + err.Error())
+	}
+	if err := d.media.Put(ctx, params.ThumbnailKey, processed.ThumbnailContentType, processed.Thumbnail); err != nil {
+		d.deleteFiles(ctx, params.StorageKey)
+		return nil, errors.New("failed to store thumbnail: " + err.Error())
+	}
+
+	var image generated.Image
+	var replaced *generated.Image
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		current, err := lock(q)
+		switch {
+		case err == nil:
+			replaced = &current
+			if err := q.DeleteImage(ctx, current.ID); err != nil {
+				return err
+			}
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+
+		image, err = q.CreateImage(ctx, params)
+		return err
+	})
+	if err != nil {
+		d.deleteFiles(ctx, params.StorageKey, params.ThumbnailKey)
+		return nil, errors.New("failed to save image: " + err.Error())
+	}
+
+	if replaced != nil {
+		d.deleteFiles(ctx, replaced.StorageKey, replaced.ThumbnailKey)
+	}
+	return &image, nil
+}
+
+// deleteFiles removes files from the media storage. Failures are logged, as
+// CleanupImagesDomain removes the files later.
+func (d *RestaurantDomain) deleteFiles(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := d.media.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete image file %s: %v", key, err)
+		}
+	}
+}
+
+func (d *RestaurantDomain) DeleteRestaurantImageDomain(ctx context.Context, restaurantId int32, kind string) error {
+	if kind != ImageLogo && kind != ImageCover {
+		return ErrInvalidImageKind
+	}
+
+	err := d.deleteImage(ctx, func(q *generated.Queries) (generated.Image, error) {
+		return q.LockRestaurantImage(ctx, generated.LockRestaurantImageParams{RestaurantID: restaurantId, Kind: kind})
+	})
+	if err != nil {
+		return err
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return nil
+}
+
+func (d *RestaurantDomain) DeleteMenuItemImageDomain(ctx context.Context, restaurantId, menuItemId int32) error {
+	err := d.deleteImage(ctx, func(q *generated.Queries) (generated.Image, error) {
+		image, err := q.LockMenuItemImage(ctx, &menuItemId)
+		if err == nil && image.RestaurantID != restaurantId {
+			return generated.Image{}, pgx.ErrNoRows
+		}
+		return image, err
+	})
+	if err != nil {
+		return err
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+
+	return nil
+}
+
+// deleteImage deletes the image found by lock, and then its files
+func (d *RestaurantDomain) deleteImage(ctx context.Context, lock func(q *generated.Queries) (generated.Image, error)) error {
+	if d.media == nil {
+		return ErrMediaUnavailable
+	}
+
+	var image generated.Image
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		if image, err = lock(q); err != nil {
+			return err
+		}
+		return q.DeleteImage(ctx, image.ID)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return errors.New("failed to delete image: " + err.Error())
+	}
+
+	d.deleteFiles(ctx, image.StorageKey, image.ThumbnailKey)
+	return nil
+}
+
+// CleanupImagesDomain deletes the images of deleted restaurants and menu
+// items, and then removes the files in the media storage that belong to no
+// image, e.g. left behind by failed uploads. Files modified after cutoff are
+// kept, as their upload may still be in progress. It returns the number of
+// files removed.
+func (d *RestaurantDomain) CleanupImagesDomain(ctx context.Context, cutoff time.Time) (int, error) {
+	if d.media == nil {
+		return 0, nil
+	}
+
+	orphaned, err := d.repo.GetOrphanedImages(ctx)
+	if err != nil {
+		return 0, errors.New("failed to fetch orphaned images: " + err.Error())
+	}
+	for _, image := range orphaned {
+		if err := d.repo.DeleteImage(ctx, image.ID); err != nil {
+			return 0, errors.New("failed to delete orphaned image: " + err.Error())
+		}
+	}
+
+	keys, err := d.repo.GetImageKeys(ctx)
+	if err != nil {
+		return 0, errors.New("failed to fetch image keys: " + err.Error())
+	}
+	referenced := map[string]bool{}
+	for _, key := range keys {
+		referenced[key.StorageKey] = true
+		referenced[key.ThumbnailKey] = true
+	}
+
+	objects, err := d.media.List(ctx)
+	if err != nil {
+		return 0, errors.New("failed to list image files: " + err.Error())
+	}
+	removed := 0
+	for _, object := range objects {
+		if referenced[object.Key] || object.ModTime.After(cutoff) {
+			continue
+		}
+		if err := d.media.Delete(ctx, object.Key); err != nil {
+			return removed, errors.New("failed to remove image file: " + err.Error())
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/media"
+)
+
+var imageColumns = []string{"id", "restaurant_id", "menu_item_id", "kind", "storage_key", "thumbnail_key", "content_type", "width", "height", "size_bytes", "created_at"}
+
+// memStorage keeps files in memory
+type memStorage struct {
+	files map[string]time.Time
+}
+
+func newMemStorage(keys ...string) *memStorage {
+	storage := &memStorage{files: map[string]time.Time{}}
+	for _, key := range keys {
+		storage.files[key] = time.Now().Add(-2 * time.Hour)
+	}
+	return storage
+}
+
+func (s *memStorage) Put(ctx context.Context, key, contentType string, data []byte) error {
+	s.files[key] = time.Now()
+	return nil
+}
+
+func (s *memStorage) Delete(ctx context.Context, key string) error {
+	delete(s.files, key)
+	return nil
+}
+
+func (s *memStorage) List(ctx context.Context) ([]media.Object, error) {
+	var objects []media.Object
+	for key, modTime := range s.files {
+		objects = append(objects, media.Object{Key: key, ModTime: modTime})
+	}
+	return objects, nil
+}
+
+func (s *memStorage) URL(key string) string {
+	return "/media/" + key
+}
+
+func setupImageMocks(t *testing.T, storage media.Storage) (pgxmock.PgxPoolIface, *RestaurantDomain) {
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create pgxmock pool: %v", err)
+	}
+	return mock, NewRestaurantDomain(generated.New(mock), mock, nil, storage)
+}
+
+func expectRestaurant(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery(`FROM restaurant WHERE id = \$1`).
+		WithArgs(int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", nil, nil, nil, nil, int32(0), false, nil))
+}
+
+func pngUpload(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 300))); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadRestaurantImageDomain(t *testing.T) {
+	t.Run("Replaces the logo", func(t *testing.T) {
+		// Arrange
+		storage := newMemStorage("old.png", "old-thumb.png")
+		mock, domain := setupImageMocks(t, storage)
+		defer mock.Close()
+
+		expectRestaurant(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM image\s+WHERE restaurant_id = \$1 AND kind = \$2 AND menu_item_id IS NULL\s+FOR UPDATE`).
+			WithArgs(int32(1), ImageLogo).
+			WillReturnRows(pgxmock.NewRows(imageColumns).AddRow(int32(3), int32(1), nil, ImageLogo, "old.png", "old-thumb.png", "image/png", int32(100), int32(100), int32(512), time.Now()))
+		mock.ExpectExec(`DELETE FROM image`).WithArgs(int32(3)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectQuery(`INSERT INTO image`).
+			WithArgs(int32(1), (*int32)(nil), ImageLogo, pgxmock.AnyArg(), pgxmock.AnyArg(), "image/png", int32(600), int32(300), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows(imageColumns).AddRow(int32(4), int32(1), nil, ImageLogo, "new.png", "new-thumb.png", "image/png", int32(600), int32(300), int32(1024), time.Now()))
+		mock.ExpectCommit()
+
+		// Act
+		got, err := domain.UploadRestaurantImageDomain(context.Background(), 1, ImageLogo, pngUpload(t))
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.URL != "/media/new.png" || got.ThumbnailURL != "/media/new-thumb.png" || got.Width != 600 {
+			t.Errorf("got %+v, want the urls of the new image", got)
+		}
+		if _, ok := storage.files["old.png"]; ok {
+			t.Errorf("expected the files of the replaced logo to be deleted")
+		}
+		if len(storage.files) != 2 {
+			t.Errorf("expected the image and its thumbnail to be stored, got %v", storage.files)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Unsupported type", func(t *testing.T) {
+		storage := newMemStorage()
+		mock, domain := setupImageMocks(t, storage)
+		defer mock.Close()
+		expectRestaurant(mock)
+
+		_, err := domain.UploadRestaurantImageDomain(context.Background(), 1, ImageCover, []byte("%PDF-1.4"))
+
+		if !errors.Is(err, media.ErrUnsupportedType) {
+			t.Errorf("expected %v, got %v", media.ErrUnsupportedType, err)
+		}
+		if len(storage.files) != 0 {
+			t.Errorf("expected nothing to be stored, got %v", storage.files)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Unknown kind", func(t *testing.T) {
+		mock, domain := setupImageMocks(t, newMemStorage())
+		defer mock.Close()
+
+		_, err := domain.UploadRestaurantImageDomain(context.Background(), 1, "banner", pngUpload(t))
+
+		if !errors.Is(err, ErrInvalidImageKind) {
+			t.Errorf("expected %v, got %v", ErrInvalidImageKind, err)
+		}
+	})
+
+	t.Run("Without storage", func(t *testing.T) {
+		mock, domain := setupImageMocks(t, nil)
+		defer mock.Close()
+		expectRestaurant(mock)
+
+		_, err := domain.UploadRestaurantImageDomain(context.Background(), 1, ImageLogo, pngUpload(t))
+
+		if !errors.Is(err, ErrMediaUnavailable) {
+			t.Errorf("expected %v, got %v", ErrMediaUnavailable, err)
+		}
+	})
+}
+
+func TestDeleteMenuItemImageDomain(t *testing.T) {
+	t.Run("Deletes the photo and its files", func(t *testing.T) {
+		storage := newMemStorage("photo.jpg", "photo-thumb.jpg")
+		mock, domain := setupImageMocks(t, storage)
+		defer mock.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM image\s+WHERE menu_item_id = \$1\s+FOR UPDATE`).
+			WithArgs(int32Ptr(2)).
+			WillReturnRows(pgxmock.NewRows(imageColumns).AddRow(int32(5), int32(1), int32Ptr(2), ImageMenuItem, "photo.jpg", "photo-thumb.jpg", "image/jpeg", int32(800), int32(600), int32(2048), time.Now()))
+		mock.ExpectExec(`DELETE FROM image`).WithArgs(int32(5)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectCommit()
+
+		if err := domain.DeleteMenuItemImageDomain(context.Background(), 1, 2); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(storage.files) != 0 {
+			t.Errorf("expected the files to be deleted, got %v", storage.files)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("No photo", func(t *testing.T) {
+		mock, domain := setupImageMocks(t, newMemStorage())
+		defer mock.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM image\s+WHERE menu_item_id = \$1\s+FOR UPDATE`).
+			WithArgs(int32Ptr(2)).
+			WillReturnError(pgx.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := domain.DeleteMenuItemImageDomain(context.Background(), 1, 2)
+
+		if !errors.Is(err, ErrImageNotFound) {
+			t.Errorf("expected %v, got %v", ErrImageNotFound, err)
+		}
+	})
+}
+
+func TestCleanupImagesDomain(t *testing.T) {
+	// Arrange
+	storage := newMemStorage("kept.jpg", "kept-thumb.jpg", "deleted.jpg", "deleted-thumb.jpg", "failed-upload.jpg")
+	storage.files["uploading.jpg"] = time.Now()
+	mock, domain := setupImageMocks(t, storage)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM image i\s+JOIN restaurant r`).
+		WillReturnRows(pgxmock.NewRows(imageColumns).AddRow(int32(7), int32(2), nil, ImageLogo, "deleted.jpg", "deleted-thumb.jpg", "image/jpeg", int32(100), int32(100), int32(512), time.Now()))
+	mock.ExpectExec(`DELETE FROM image`).WithArgs(int32(7)).WillReturnResult(pgxmock.NewResult("DELETE", 1))
+	mock.ExpectQuery(`SELECT storage_key, thumbnail_key\s+FROM image`).
+		WillReturnRows(pgxmock.NewRows([]string{"storage_key", "thumbnail_key"}).AddRow("kept.jpg", "kept-thumb.jpg"))
+
+	// Act
+	removed, err := domain.CleanupImagesDomain(context.Background(), time.Now().Add(-time.Hour))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("got %d files removed, want 3", removed)
+	}
+	for _, key := range []string{"kept.jpg", "kept-thumb.jpg", "uploading.jpg"} {
+		if _, ok := storage.files[key]; !ok {
+			t.Errorf("expected %s to be kept", key)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestGetRestaurantByIdDomainWithImages(t *testing.T) {
+	mock, domain := setupImageMocks(t, newMemStorage())
+	defer mock.Close()
+
+	expectRestaurant(mock)
+	mock.ExpectQuery(`FROM image\s+WHERE restaurant_id = ANY\(\$1::int\[\]\) AND menu_item_id IS NULL`).
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(imageColumns).AddRow(int32(3), int32(1), nil, ImageCover, "cover.jpg", "cover-thumb.jpg", "image/jpeg", int32(1200), int32(400), int32(4096), time.Now()))
+	expectLocations(mock, pgxmock.NewRows(locationColumns), 1)
+
+	got, err := domain.GetRestaurantByIdDomain(context.Background(), 1)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Images.Logo != nil || got.Images.Cover == nil || got.Images.Cover.ThumbnailURL != "/media/cover-thumb.jpg" {
+		t.Errorf("got images %+v, want only the cover", got.Images)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
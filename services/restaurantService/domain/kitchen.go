@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/metrics"
+)
+
+// Items are limited per slot of this length, starting on the quarter hour
+const slotLength = 15 * time.Minute
+
+// Kitchen defaults of restaurants without capacity rules
+const (
+	defaultPrepMinutes      = 15
+	defaultBusyExtraMinutes = 15
+)
+
+// Order statuses that take an order out of the kitchen
+const (
+	orderStatusOnItsWay  = "On its way"
+	orderStatusDelivered = "Delivered"
+	orderStatusRejected  = "Rejected"
+)
+
+var ErrInvalidCapacity = errors.New("invalid kitchen capacity")
+
+// KitchenStatus tells whether the kitchen of a restaurant is busy, and how long
+// orders take to prepare. A busy kitchen takes longer, and a kitchen at
+// capacity takes no new orders until orders leave it.
+type KitchenStatus struct {
+	Busy        bool  `json:"busy" example:"false"`
+	AtCapacity  bool  `json:"at_capacity" example:"false"`
+	PrepMinutes int32 `json:"prep_minutes" example:"15"`
+}
+
+// KitchenCapacity are the capacity rules of a restaurant. Missing limits are
+// not enforced.
+type KitchenCapacity struct {
+	MaxOpenOrders    *int32 `json:"max_open_orders" example:"10"`
+	MaxItemsPerSlot  *int32 `json:"max_items_per_slot" example:"30"`
+	PrepMinutes      int32  `json:"prep_minutes" example:"15"`
+	BusyExtraMinutes int32  `json:"busy_extra_minutes" example:"15"`
+	BusyMode         bool   `json:"busy_mode" example:"false"`
+}
+
+// CapacityParams replaces the capacity rules of a restaurant. Missing limits
+// are not enforced, and missing prep times fall back to the defaults.
+type CapacityParams struct {
+	MaxOpenOrders    *int32 `json:"max_open_orders" example:"10"`
+	MaxItemsPerSlot  *int32 `json:"max_items_per_slot" example:"30"`
+	PrepMinutes      *int32 `json:"prep_minutes" example:"15"`
+	BusyExtraMinutes *int32 `json:"busy_extra_minutes" example:"15"`
+}
+
+// BusyModeParams switches busy mode of a restaurant on or off
+type BusyModeParams struct {
+	Busy bool `json:"busy" example:"true"`
+}
+
+// KitchenLoad is the current load of a restaurant's kitchen against its
+// capacity rules. Open orders are accepted orders not yet on their way, and
+// slot items are the items of the orders accepted since the slot started.
+type KitchenLoad struct {
+	RestaurantID int32           `json:"restaurant_id"`
+	Capacity     KitchenCapacity `json:"capacity"`
+	OpenOrders   int32           `json:"open_orders" example:"4"`
+	SlotItems    int32           `json:"slot_items" example:"12"`
+	SlotStart    time.Time       `json:"slot_start"`
+	KitchenStatus
+}
+
+// status works out the kitchen status from the load
+func (k *KitchenLoad) status() KitchenStatus {
+	c := k.Capacity
+	atCapacity := (c.MaxOpenOrders != nil && k.OpenOrders >= *c.MaxOpenOrders) ||
+		(c.MaxItemsPerSlot != nil && k.SlotItems >= *c.MaxItemsPerSlot)
+	status := KitchenStatus{
+		Busy:        c.BusyMode || atCapacity,
+		AtCapacity:  atCapacity,
+		PrepMinutes: c.PrepMinutes,
+	}
+	if status.Busy {
+		status.PrepMinutes += c.BusyExtraMinutes
+	}
+	return status
+}
+
+// record exposes the load in the kitchen metrics
+func (k *KitchenLoad) record() {
+	id := strconv.Itoa(int(k.RestaurantID))
+	metrics.KitchenOpenOrders.WithLabelValues(id).Set(float64(k.OpenOrders))
+	metrics.KitchenSlotItems.WithLabelValues(id).Set(float64(k.SlotItems))
+	metrics.KitchenBusy.WithLabelValues(id).Set(gaugeValue(k.Busy))
+	metrics.KitchenAtCapacity.WithLabelValues(id).Set(gaugeValue(k.AtCapacity))
+}
+
+func gaugeValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// loadKitchens works out the kitchen load of the restaurants at now in two
+// queries, and records it in the kitchen metrics
+func (d *RestaurantDomain) loadKitchens(ctx context.Context, restaurantIds []int32, now time.Time) (map[int32]*KitchenLoad, error) {
+	// Orders are stored with their UTC acceptance time
+	slotStart := now.UTC().Truncate(slotLength)
+	kitchens := map[int32]*KitchenLoad{}
+	for _, id := range restaurantIds {
+		kitchens[id] = &KitchenLoad{
+			RestaurantID: id,
+			Capacity:     KitchenCapacity{PrepMinutes: defaultPrepMinutes, BusyExtraMinutes: defaultBusyExtraMinutes},
+			SlotStart:    slotStart,
+		}
+	}
+
+	capacities, err := d.repo.GetKitchenCapacityByRestaurantIds(ctx, restaurantIds)
+	if err != nil {
+		return nil, errors.New("failed to fetch kitchen capacity: " + err.Error())
+	}
+	for _, capacity := range capacities {
+		kitchens[capacity.RestaurantID].Capacity = KitchenCapacity{
+			MaxOpenOrders:    capacity.MaxOpenOrders,
+			MaxItemsPerSlot:  capacity.MaxItemsPerSlot,
+			PrepMinutes:      capacity.PrepMinutes,
+			BusyExtraMinutes: capacity.BusyExtraMinutes,
+			BusyMode:         capacity.BusyMode,
+		}
+	}
+
+	loads, err := d.repo.GetKitchenLoadByRestaurantIds(ctx, generated.GetKitchenLoadByRestaurantIdsParams{
+		SlotStart:     &slotStart,
+		RestaurantIds: restaurantIds,
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch kitchen load: " + err.Error())
+	}
+	for _, load := range loads {
+		kitchens[load.RestaurantID].OpenOrders = load.OpenOrders
+		kitchens[load.RestaurantID].SlotItems = load.SlotItems
+	}
+
+	for _, kitchen := range kitchens {
+		kitchen.KitchenStatus = kitchen.status()
+		kitchen.record()
+	}
+	return kitchens, nil
+}
+
+// GetKitchenLoadDomain fetches the current kitchen load of a restaurant
+func (d *RestaurantDomain) GetKitchenLoadDomain(ctx context.Context, restaurantId int32) (*KitchenLoad, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	kitchens, err := d.loadKitchens(ctx, []int32{restaurantId}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return kitchens[restaurantId], nil
+}
+
+// SetKitchenCapacityDomain replaces the capacity rules of a restaurant,
+// keeping its busy mode
+func (d *RestaurantDomain) SetKitchenCapacityDomain(ctx context.Context, restaurantId int32, params CapacityParams) (*KitchenLoad, error) {
+	switch {
+	case params.MaxOpenOrders != nil && *params.MaxOpenOrders <= 0:
+		return nil, fmt.Errorf("%w: max open orders must be more than zero", ErrInvalidCapacity)
+	case params.MaxItemsPerSlot != nil && *params.MaxItemsPerSlot <= 0:
+		return nil, fmt.Errorf("%w: max items per slot must be more than zero", ErrInvalidCapacity)
+	case params.PrepMinutes != nil && *params.PrepMinutes <= 0:
+		return nil, fmt.Errorf("%w: prep minutes must be more than zero", ErrInvalidCapacity)
+	case params.BusyExtraMinutes != nil && *params.BusyExtraMinutes < 0:
+		return nil, fmt.Errorf("%w: busy extra minutes must be zero or more", ErrInvalidCapacity)
+	}
+	prepMinutes := int32(defaultPrepMinutes)
+	if params.PrepMinutes != nil {
+		prepMinutes = *params.PrepMinutes
+	}
+	busyExtraMinutes := int32(defaultBusyExtraMinutes)
+	if params.BusyExtraMinutes != nil {
+		busyExtraMinutes = *params.BusyExtraMinutes
+	}
+
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	err := d.repo.SetKitchenCapacity(ctx, generated.SetKitchenCapacityParams{
+		RestaurantID:     restaurantId,
+		MaxOpenOrders:    params.MaxOpenOrders,
+		MaxItemsPerSlot:  params.MaxItemsPerSlot,
+		PrepMinutes:      prepMinutes,
+		BusyExtraMinutes: busyExtraMinutes,
+	})
+	if err != nil {
+		return nil, errors.New("failed to save kitchen capacity: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return d.GetKitchenLoadDomain(ctx, restaurantId)
+}
+
+// SetBusyModeDomain switches busy mode of a restaurant on or off. Busy mode
+// lengthens the prep time, but the restaurant keeps taking orders.
+func (d *RestaurantDomain) SetBusyModeDomain(ctx context.Context, restaurantId int32, params BusyModeParams) (*KitchenLoad, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	err := d.repo.SetBusyMode(ctx, generated.SetBusyModeParams{RestaurantID: restaurantId, BusyMode: params.Busy})
+	if err != nil {
+		return nil, errors.New("failed to save busy mode: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return d.GetKitchenLoadDomain(ctx, restaurantId)
+}
+
+// TrackKitchenOrderDomain keeps the kitchen load up to date. Accepted orders
+// are open in the kitchen until they are on their way, delivered, cancelled or
+// rejected. Orders are opened once, so redelivered events do not count twice.
+func (d *RestaurantDomain) TrackKitchenOrderDomain(ctx context.Context, change OrderStatusChange) error {
+	var err error
+	switch change.Status {
+	case orderStatusAccepted:
+		if change.RestaurantID == nil {
+			return nil
+		}
+		var itemCount int32
+		for _, item := range change.Items {
+			itemCount += max(item.Quantity, 0)
+		}
+		acceptedAt := time.Now().UTC()
+		err = d.repo.OpenKitchenOrder(ctx, generated.OpenKitchenOrderParams{
+			OrderID:      change.OrderID,
+			RestaurantID: *change.RestaurantID,
+			ItemCount:    itemCount,
+			AcceptedAt:   &acceptedAt,
+		})
+	case orderStatusOnItsWay, orderStatusDelivered, orderStatusCancelled, orderStatusRejected:
+		err = d.repo.CloseKitchenOrder(ctx, change.OrderID)
+	default:
+		return nil
+	}
+	if err != nil {
+		return errors.New("failed to track kitchen order: " + err.Error())
+	}
+
+	// Refreshes the kitchen metrics
+	if change.RestaurantID != nil {
+		if _, err := d.loadKitchens(ctx, []int32{*change.RestaurantID}, time.Now()); err != nil {
+			log.Printf("Failed to refresh kitchen load of restaurant %d: %v", *change.RestaurantID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestKitchenStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		load KitchenLoad
+		want KitchenStatus
+	}{
+		{
+			"No limits",
+			KitchenLoad{Capacity: KitchenCapacity{PrepMinutes: 15, BusyExtraMinutes: 10}, OpenOrders: 50, SlotItems: 200},
+			KitchenStatus{PrepMinutes: 15},
+		},
+		{
+			"Busy mode",
+			KitchenLoad{Capacity: KitchenCapacity{PrepMinutes: 15, BusyExtraMinutes: 10, BusyMode: true}},
+			KitchenStatus{Busy: true, PrepMinutes: 25},
+		},
+		{
+			"Too many open orders",
+			KitchenLoad{Capacity: KitchenCapacity{MaxOpenOrders: int32Ptr(5), PrepMinutes: 15, BusyExtraMinutes: 10}, OpenOrders: 5},
+			KitchenStatus{Busy: true, AtCapacity: true, PrepMinutes: 25},
+		},
+		{
+			"Too many items in the slot",
+			KitchenLoad{Capacity: KitchenCapacity{MaxItemsPerSlot: int32Ptr(20), PrepMinutes: 15}, OpenOrders: 1, SlotItems: 21},
+			KitchenStatus{Busy: true, AtCapacity: true, PrepMinutes: 15},
+		},
+		{
+			"Below the limits",
+			KitchenLoad{Capacity: KitchenCapacity{MaxOpenOrders: int32Ptr(5), MaxItemsPerSlot: int32Ptr(20), PrepMinutes: 15}, OpenOrders: 4, SlotItems: 19},
+			KitchenStatus{PrepMinutes: 15},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.load.status(); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetRestaurantStatusDomainAtCapacity(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	mock.ExpectQuery(`FROM restaurant\s+WHERE id = \$1`).
+		WithArgs(int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil))
+	// Open around the clock, so only the kitchen keeps it from taking orders
+	hours := pgxmock.NewRows([]string{"id", "restaurant_id", "weekday", "opens_at", "closes_at"})
+	for weekday := int16(0); weekday < 7; weekday++ {
+		midnight := pgtype.Time{Microseconds: 0, Valid: true}
+		hours.AddRow(int32(weekday+1), int32(1), weekday, midnight, midnight)
+	}
+	mock.ExpectQuery(`FROM opening_hours\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs([]int32{1}).
+		WillReturnRows(hours)
+	mock.ExpectQuery(`FROM opening_exception\s+WHERE restaurant_id = ANY\(\$1::int\[\]\) AND date >= \$2`).
+		WithArgs([]int32{1}, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurant_id", "date", "opens_at", "closes_at", "note"}))
+	expectKitchen(mock,
+		pgxmock.NewRows(kitchenCapacityColumns).AddRow(int32(1), int32Ptr(3), nil, int32(20), int32(10), false),
+		pgxmock.NewRows(kitchenLoadColumns).AddRow(int32(1), int32(3), int32(9)),
+		1)
+
+	// Act
+	status, err := domain.GetRestaurantStatusDomain(context.Background(), 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := KitchenStatus{Busy: true, AtCapacity: true, PrepMinutes: 30}
+	if status.IsOpen || status.State != StateAtCapacity || status.KitchenStatus != want {
+		t.Errorf("got %+v, want at capacity with kitchen %+v", status, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
+func TestSetKitchenCapacityDomain(t *testing.T) {
+	tests := []struct {
+		name   string
+		params CapacityParams
+	}{
+		{"Zero open orders", CapacityParams{MaxOpenOrders: int32Ptr(0)}},
+		{"Negative items per slot", CapacityParams{MaxItemsPerSlot: int32Ptr(-1)}},
+		{"Zero prep minutes", CapacityParams{PrepMinutes: int32Ptr(0)}},
+		{"Negative busy extra minutes", CapacityParams{BusyExtraMinutes: int32Ptr(-5)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+
+			_, err := domain.SetKitchenCapacityDomain(context.Background(), 1, tt.params)
+
+			if !errors.Is(err, ErrInvalidCapacity) {
+				t.Errorf("got error %v, want %v", err, ErrInvalidCapacity)
+			}
+		})
+	}
+}
+
+func TestTrackKitchenOrderDomain(t *testing.T) {
+	t.Run("Opens accepted orders with their item count", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectExec(`INSERT INTO kitchen_order`).
+			WithArgs(int32(7), int32(1), int32(3), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		expectKitchen(mock, pgxmock.NewRows(kitchenCapacityColumns), pgxmock.NewRows(kitchenLoadColumns).AddRow(int32(1), int32(1), int32(3)), 1)
+
+		// Act
+		err := domain.TrackKitchenOrderDomain(context.Background(), OrderStatusChange{
+			OrderID:      7,
+			Status:       "Accepted",
+			RestaurantID: int32Ptr(1),
+			Items:        []OrderedItem{{MenuItemID: 1, Quantity: 2}, {MenuItemID: 2, Quantity: 1}},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Closes orders on their way", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectExec(`UPDATE kitchen_order\s+SET closed_at = NOW\(\)`).
+			WithArgs(int32(7)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		// Act
+		err := domain.TrackKitchenOrderDomain(context.Background(), OrderStatusChange{OrderID: 7, Status: "On its way"})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package domain
+
+// Listings are built with the listing package rather than generated by sqlc,
+// so they write out the ORDER BY of their sort fields and Postgres can walk
+// the indexes the sorts have.
+
+import (
+	"context"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/listing"
+)
+
+const restaurantListing = `SELECT r.id, r.name, r.rating, r.category, r.address, r.zip_code, r.review_count, r.ordering_paused, r.deleted_at
+FROM restaurant r
+JOIN zipcode a ON r.zip_code = a.zip_code`
+
+// restaurantSortKeys are the fields restaurants sort on besides their id
+var restaurantSortKeys = map[string]listing.SortKey{
+	"name":   {Expr: "r.name", CursorType: "text"},
+	"rating": {Expr: "COALESCE(r.rating, 0)", CursorType: "float8"},
+}
+
+type restaurantListParams struct {
+	Category     *string
+	MinRating    *float64
+	ZipCode      *int32
+	CursorID     *int32
+	SortField    string
+	Descending   bool
+	CursorName   *string
+	CursorRating *float64
+	PageLimit    int32
+}
+
+// listRestaurants fetches a page of restaurants that are not deleted, sorted
+// on a whitelisted field with id breaking ties and continuing after the
+// cursor of the previous page
+func listRestaurants(ctx context.Context, db generated.DBTX, arg restaurantListParams) ([]generated.Restaurant, error) {
+	key, err := listing.SortKeyOf(restaurantSortKeys, arg.SortField)
+	if err != nil {
+		return nil, err
+	}
+	list := listing.New(restaurantListing)
+	list.Where("r.deleted_at IS NULL")
+	listing.WhereSet(list, `EXISTS (
+    SELECT 1 FROM restaurant_category rc JOIN category c ON c.id = rc.category_id
+    WHERE rc.restaurant_id = r.id AND c.slug = %[1]s::text)`, arg.Category)
+	listing.WhereSet(list, "r.rating >= %[1]s::float8", arg.MinRating)
+	listing.WhereSet(list, `EXISTS (
+    SELECT 1 FROM delivery_zone z WHERE z.restaurant_id = r.id AND z.zip_code = %[1]s::int)`, arg.ZipCode)
+	var cursorValue any
+	switch arg.SortField {
+	case "name":
+		cursorValue = arg.CursorName
+	case "rating":
+		cursorValue = arg.CursorRating
+	}
+	query, args := list.Page(key, "r.id", arg.Descending, cursorValue, arg.CursorID, arg.PageLimit)
+
+	rows, err := db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []generated.Restaurant
+	for rows.Next() {
+		var i generated.Restaurant
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Rating,
+			&i.Category,
+			&i.Address,
+			&i.ZipCode,
+			&i.ReviewCount,
+			&i.OrderingPaused,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,384 @@
+package domain
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Menu file formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ActionImported is the action of the menu.updated event sent after an import
+const ActionImported = "imported"
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported menu format, use csv or json")
+	ErrInvalidMenuFile   = errors.New("invalid menu file")
+	ErrInvalidMenuRows   = errors.New("menu has invalid rows")
+)
+
+// csvColumns are the columns of exported CSV menus. Imports need name and price.
+var csvColumns = []string{"id", "name", "price", "description"}
+
+// MenuRow is a menu item as it is imported and exported
+type MenuRow struct {
+	ID          *int32  `json:"id,omitempty" example:"1"`
+	Name        string  `json:"name" example:"Pepperoni Pizza"`
+	Price       float64 `json:"price" example:"12.99"`
+	Description *string `json:"description,omitempty" example:"Classic pepperoni pizza with mozzarella cheese."`
+}
+
+// MenuItemChange is an existing menu item before and after the import
+type MenuItemChange struct {
+	Before MenuRow `json:"before"`
+	After  MenuRow `json:"after"`
+}
+
+// RowError is a validation error of a single row. Rows are numbered from 1,
+// not counting the CSV header.
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// MenuImportResult is the difference between the imported and the current menu
+type MenuImportResult struct {
+	DryRun  bool             `json:"dry_run"`
+	Added   []MenuRow        `json:"added"`
+	Changed []MenuItemChange `json:"changed"`
+	Removed []MenuRow        `json:"removed"`
+	Errors  []RowError       `json:"errors,omitempty"`
+}
+
+// menuInput is a parsed row before validation
+type menuInput struct {
+	row         int
+	id          *int32
+	name        *string
+	price       *float64
+	description *string
+}
+
+func (in menuInput) toMenuRow() MenuRow {
+	return MenuRow{ID: in.id, Name: strings.TrimSpace(*in.name), Price: *in.price, Description: in.description}
+}
+
+func menuItemToRow(item generated.Menuitem) MenuRow {
+	id := item.ID
+	return MenuRow{ID: &id, Name: item.Name, Price: item.Price, Description: item.Description}
+}
+
+func parseMenuCSV(body io.Reader) ([]menuInput, []RowError, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		switch column {
+		case "id", "name", "price", "description":
+			columns[column] = i
+		default:
+			return nil, nil, fmt.Errorf("%w: unknown column %q", ErrInvalidMenuFile, column)
+		}
+	}
+	for _, column := range []string{"name", "price"} {
+		if _, ok := columns[column]; !ok {
+			return nil, nil, fmt.Errorf("%w: missing column %q", ErrInvalidMenuFile, column)
+		}
+	}
+
+	var inputs []menuInput
+	var rowErrors []RowError
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+		}
+
+		field := func(column string) *string {
+			i, ok := columns[column]
+			if !ok || strings.TrimSpace(record[i]) == "" {
+				return nil
+			}
+			value := strings.TrimSpace(record[i])
+			return &value
+		}
+
+		in := menuInput{row: row, name: field("name"), description: field("description")}
+		if id := field("id"); id != nil {
+			parsed, err := strconv.ParseInt(*id, 10, 32)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Field: "id", Message: "id must be a whole number"})
+				continue
+			}
+			id32 := int32(parsed)
+			in.id = &id32
+		}
+		if price := field("price"); price != nil {
+			parsed, err := strconv.ParseFloat(*price, 64)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Row: row, Field: "price", Message: "price must be a number"})
+				continue
+			}
+			in.price = &parsed
+		}
+		inputs = append(inputs, in)
+	}
+
+	return inputs, rowErrors, nil
+}
+
+func parseMenuJSON(body io.Reader) ([]menuInput, []RowError, error) {
+	var rows []struct {
+		ID          *int32   `json:"id"`
+		Name        *string  `json:"name"`
+		Price       *float64 `json:"price"`
+		Description *string  `json:"description"`
+	}
+	if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidMenuFile, err)
+	}
+
+	inputs := make([]menuInput, len(rows))
+	for i, row := range rows {
+		inputs[i] = menuInput{row: i + 1, id: row.ID, name: row.Name, price: row.Price, description: row.Description}
+	}
+	return inputs, nil, nil
+}
+
+// diffMenu validates the imported rows and compares them with the current
+// menu. Rows are matched on id when given and otherwise on the item name.
+func diffMenu(current []generated.Menuitem, inputs []menuInput, result *MenuImportResult) {
+	byID := map[int32]generated.Menuitem{}
+	byName := map[string]generated.Menuitem{}
+	for _, item := range current {
+		byID[item.ID] = item
+		byName[strings.ToLower(item.Name)] = item
+	}
+
+	matched := map[int32]bool{}
+	names := map[string]int{}
+	for _, in := range inputs {
+		if !validName(in.name) {
+			result.Errors = append(result.Errors, RowError{Row: in.row, Field: "name", Message: ErrNameRequired.Error()})
+			continue
+		}
+		if in.price == nil || *in.price < 0 || math.IsNaN(*in.price) || math.IsInf(*in.price, 0) {
+			result.Errors = append(result.Errors, RowError{Row: in.row, Field: "price", Message: ErrNegativePrice.Error()})
+			continue
+		}
+
+		row := in.toMenuRow()
+		name := strings.ToLower(row.Name)
+		if first, ok := names[name]; ok {
+			result.Errors = append(result.Errors, RowError{Row: in.row, Field: "name", Message: fmt.Sprintf("duplicate of row %d", first)})
+			continue
+		}
+		names[name] = in.row
+
+		var item generated.Menuitem
+		var found bool
+		if row.ID != nil {
+			if item, found = byID[*row.ID]; !found {
+				result.Errors = append(result.Errors, RowError{Row: in.row, Field: "id", Message: "menu item not found"})
+				continue
+			}
+		} else {
+			item, found = byName[name]
+		}
+
+		if !found {
+			result.Added = append(result.Added, row)
+			continue
+		}
+		if matched[item.ID] {
+			result.Errors = append(result.Errors, RowError{Row: in.row, Message: fmt.Sprintf("menu item %d appears more than once", item.ID)})
+			continue
+		}
+		matched[item.ID] = true
+
+		row.ID = &item.ID
+		before := menuItemToRow(item)
+		if before.Name != row.Name || before.Price != row.Price || !sameDescription(before.Description, row.Description) {
+			result.Changed = append(result.Changed, MenuItemChange{Before: before, After: row})
+		}
+	}
+
+	for _, item := range current {
+		if !matched[item.ID] {
+			result.Removed = append(result.Removed, menuItemToRow(item))
+		}
+	}
+}
+
+func sameDescription(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ImportMenuDomain replaces the menu of a restaurant with the imported one.
+// Items missing from the import are soft-deleted. The returned diff is only
+// applied when dryRun is false and every row is valid, and then in a single
+// transaction. ErrInvalidMenuRows is returned together with the row errors.
+func (d *RestaurantDomain) ImportMenuDomain(ctx context.Context, restaurantId int32, format string, body io.Reader, dryRun bool) (*MenuImportResult, error) {
+	var inputs []menuInput
+	var rowErrors []RowError
+	var err error
+	switch format {
+	case FormatCSV:
+		inputs, rowErrors, err = parseMenuCSV(body)
+	case FormatJSON:
+		inputs, rowErrors, err = parseMenuJSON(body)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+	current, err := d.repo.FetchMenuItemsByRestaurantId(ctx, restaurantId)
+	if err != nil {
+		return nil, errors.New("failed to fetch menuitems: " + err.Error())
+	}
+
+	result := &MenuImportResult{
+		DryRun:  dryRun,
+		Added:   []MenuRow{},
+		Changed: []MenuItemChange{},
+		Removed: []MenuRow{},
+		Errors:  rowErrors,
+	}
+	diffMenu(current, inputs, result)
+	if len(result.Errors) > 0 {
+		result.DryRun = true
+		return result, ErrInvalidMenuRows
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	now := priceTime(time.Now())
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		for _, row := range result.Removed {
+			if _, err := q.SoftDeleteMenuItem(ctx, generated.SoftDeleteMenuItemParams{Restaurantid: restaurantId, ID: *row.ID}); err != nil {
+				return err
+			}
+		}
+		for _, change := range result.Changed {
+			_, err := q.UpdateMenuItem(ctx, generated.UpdateMenuItemParams{
+				Restaurantid: restaurantId,
+				ID:           *change.After.ID,
+				Name:         change.After.Name,
+				Price:        change.After.Price,
+				Description:  change.After.Description,
+			})
+			if err != nil {
+				return err
+			}
+			if change.After.Price != change.Before.Price {
+				if _, err := setPrice(ctx, q, *change.After.ID, change.After.Price, now); err != nil {
+					return err
+				}
+			}
+		}
+		for i, row := range result.Added {
+			id, err := q.CreateMenuItem(ctx, generated.CreateMenuItemParams{
+				Restaurantid: restaurantId,
+				Name:         row.Name,
+				Price:        row.Price,
+				Description:  row.Description,
+			})
+			if err != nil {
+				return err
+			}
+			if _, err := setPrice(ctx, q, id, row.Price, now); err != nil {
+				return err
+			}
+			result.Added[i].ID = &id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to import menu: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionImported})
+
+	return result, nil
+}
+
+// ExportMenuDomain writes the menu of a restaurant in a format ImportMenuDomain reads
+func (d *RestaurantDomain) ExportMenuDomain(ctx context.Context, restaurantId int32, format string, w io.Writer) error {
+	if format != FormatCSV && format != FormatJSON {
+		return ErrUnsupportedFormat
+	}
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return ErrRestaurantNotFound
+	}
+	menuItems, err := d.repo.FetchMenuItemsByRestaurantId(ctx, restaurantId)
+	if err != nil {
+		return errors.New("failed to fetch menuitems: " + err.Error())
+	}
+
+	if format == FormatJSON {
+		rows := make([]MenuRow, len(menuItems))
+		for i, item := range menuItems {
+			rows[i] = menuItemToRow(item)
+		}
+		return json.NewEncoder(w).Encode(rows)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvColumns); err != nil {
+		return err
+	}
+	for _, item := range menuItems {
+		description := ""
+		if item.Description != nil {
+			description = *item.Description
+		}
+		record := []string{
+			strconv.Itoa(int(item.ID)),
+			item.Name,
+			strconv.FormatFloat(item.Price, 'f', -1, 64),
+			description,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func expectMenu(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at\s+FROM restaurant`).
+		WithArgs(int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil))
+	mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at\s+FROM menuitem\s+WHERE restaurantid = \$1`).
+		WithArgs(int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil).
+			AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"), nil))
+}
+
+func TestImportMenuDomainDryRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		body        string
+		wantAdded   int
+		wantChanged int
+		wantRemoved int
+		wantErrors  []RowError
+	}{
+		{
+			name:        "CSV matched on name",
+			format:      FormatCSV,
+			body:        "name,price,description\nCheese Pizza,13,Delicious cheese pizza\nPepperoni Pizza,14.5,\n",
+			wantAdded:   1,
+			wantChanged: 1,
+			wantRemoved: 1,
+		},
+		{
+			name:        "JSON matched on id renames the item",
+			format:      FormatJSON,
+			body:        `[{"id": 1, "name": "Margherita", "price": 12.5, "description": "Delicious cheese pizza"}, {"id": 2, "name": "Veggie Pizza", "price": 10, "description": "Healthy veggie pizza"}]`,
+			wantChanged: 1,
+		},
+		{
+			name:   "Errors are reported per row",
+			format: FormatCSV,
+			body:   "id,name,price\n,Cheese Pizza,-1\n,,5\n,Cola,abc\n99,Water,2\n,Veggie Pizza,10\n,veggie pizza,11\n",
+			wantErrors: []RowError{
+				{Row: 3, Field: "price", Message: "price must be a number"},
+				{Row: 1, Field: "price", Message: ErrNegativePrice.Error()},
+				{Row: 2, Field: "name", Message: ErrNameRequired.Error()},
+				{Row: 4, Field: "id", Message: "menu item not found"},
+				{Row: 6, Field: "name", Message: "duplicate of row 5"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			expectMenu(mock)
+
+			// Act
+			result, err := domain.ImportMenuDomain(context.Background(), 1, tt.format, strings.NewReader(tt.body), true)
+
+			// Assert
+			if len(tt.wantErrors) > 0 {
+				if !errors.Is(err, ErrInvalidMenuRows) {
+					t.Fatalf("got error %v, want %v", err, ErrInvalidMenuRows)
+				}
+				if len(result.Errors) != len(tt.wantErrors) {
+					t.Fatalf("got errors %+v, want %+v", result.Errors, tt.wantErrors)
+				}
+				for i, want := range tt.wantErrors {
+					if result.Errors[i] != want {
+						t.Errorf("error %d: got %+v, want %+v", i, result.Errors[i], want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(result.Added) != tt.wantAdded || len(result.Changed) != tt.wantChanged || len(result.Removed) != tt.wantRemoved {
+				t.Errorf("got %d added, %d changed, %d removed, want %d, %d, %d",
+					len(result.Added), len(result.Changed), len(result.Removed), tt.wantAdded, tt.wantChanged, tt.wantRemoved)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestImportMenuDomainCommit(t *testing.T) {
+	t.Run("Applies all changes in one transaction", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenu(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE menuitem\s+SET deleted_at = NOW\(\)`).
+			WithArgs(int32(1), int32(2)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery(`UPDATE menuitem\s+SET name = \$3`).
+			WithArgs(int32(1), int32(1), "Cheese Pizza", float64(13), stringPtr("Delicious cheese pizza")).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+				AddRow(int32(1), int32(1), "Cheese Pizza", float64(13), stringPtr("Delicious cheese pizza"), nil))
+		expectFirstPrice(mock, 1, 13)
+		mock.ExpectQuery(`INSERT INTO menuitem`).
+			WithArgs(int32(1), "Pepperoni Pizza", float64(14.5), (*string)(nil)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(3)))
+		expectFirstPrice(mock, 3, 14.5)
+		mock.ExpectCommit()
+
+		// Act
+		result, err := domain.ImportMenuDomain(context.Background(), 1, FormatCSV,
+			strings.NewReader("name,price,description\nCheese Pizza,13,Delicious cheese pizza\nPepperoni Pizza,14.5,\n"), false)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.DryRun || *result.Added[0].ID != 3 {
+			t.Errorf("unexpected result: %+v", result)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rolls back when a change fails", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenu(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE menuitem\s+SET deleted_at = NOW\(\)`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectExec(`UPDATE menuitem\s+SET deleted_at = NOW\(\)`).
+			WithArgs(int32(1), int32(2)).
+			WillReturnError(errors.New("connection lost"))
+		mock.ExpectRollback()
+
+		// Act
+		_, err := domain.ImportMenuDomain(context.Background(), 1, FormatJSON, strings.NewReader(`[]`), false)
+
+		// Assert
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestExportMenuDomain(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	expectMenu(mock)
+	var buf bytes.Buffer
+
+	// Act
+	err := domain.ExportMenuDomain(context.Background(), 1, FormatCSV, &buf)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "id,name,price,description\n1,Cheese Pizza,12.5,Delicious cheese pizza\n2,Veggie Pizza,10,Healthy veggie pizza\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,445 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Selection types of option groups
+const (
+	SelectionSingle = "single"
+	SelectionMulti  = "multi"
+)
+
+var (
+	ErrInvalidOptionGroups = errors.New("invalid option groups")
+	ErrInvalidCombo        = errors.New("invalid combo")
+	ErrInvalidSelection    = errors.New("invalid option selection")
+)
+
+// MenuOption is a choice within an option group, e.g. a large size or extra cheese
+type MenuOption struct {
+	ID         int32   `json:"id" example:"3"`
+	Name       string  `json:"name" example:"Large"`
+	PriceDelta float64 `json:"price_delta" example:"2.5"`
+}
+
+// OptionGroup is a set of options offered with a menu item. Between
+// MinSelect and MaxSelect options are chosen, and single select groups take
+// at most one.
+type OptionGroup struct {
+	ID            int32        `json:"id" example:"1"`
+	Name          string       `json:"name" example:"Size"`
+	SelectionType string       `json:"selection_type" example:"single"`
+	MinSelect     int32        `json:"min_select" example:"1"`
+	MaxSelect     int32        `json:"max_select" example:"1"`
+	Options       []MenuOption `json:"options"`
+}
+
+// ComboComponent is a menu item included in a combo
+type ComboComponent struct {
+	MenuItemID int32  `json:"menu_item_id" example:"2"`
+	Name       string `json:"name" example:"Coca-Cola"`
+	Quantity   int32  `json:"quantity" example:"1"`
+}
+
+// MenuItemDetail is a menu item with its options, dietary info and
+// availability. Combos also list the items they are made of.
+type MenuItemDetail struct {
+	generated.Menuitem
+	OptionGroups []OptionGroup    `json:"option_groups"`
+	Components   []ComboComponent `json:"components,omitempty"`
+	Allergens    []string         `json:"allergens" example:"gluten,milk"`
+	DietaryTags  []string         `json:"dietary_tags" example:"vegetarian"`
+	Nutrition    *Nutrition       `json:"nutrition,omitempty"`
+	Availability Availability     `json:"availability"`
+	Image        *ImageURLs       `json:"image"`
+}
+
+// OptionGroupParams replaces the option groups of a menu item. The selection
+// type defaults to single, taking at most one option.
+type OptionGroupParams struct {
+	Name          string             `json:"name" example:"Size"`
+	SelectionType string             `json:"selection_type" example:"single"`
+	MinSelect     int32              `json:"min_select" example:"1"`
+	MaxSelect     int32              `json:"max_select" example:"1"`
+	Options       []MenuOptionParams `json:"options"`
+}
+
+type MenuOptionParams struct {
+	Name       string  `json:"name" example:"Large"`
+	PriceDelta float64 `json:"price_delta" example:"2.5"`
+}
+
+type ComboComponentParams struct {
+	MenuItemID int32 `json:"menu_item_id" example:"2"`
+	Quantity   int32 `json:"quantity" example:"1"`
+}
+
+// SelectedOption is an option chosen for an ordered menu item
+type SelectedOption struct {
+	OptionID   int32   `json:"option_id" example:"3"`
+	Group      string  `json:"group" example:"Size"`
+	Name       string  `json:"name" example:"Large"`
+	PriceDelta float64 `json:"price_delta" example:"2.5"`
+}
+
+// PricedSelection is a menu item with the chosen options. The unit price
+// includes the price deltas of the options, on top of the price version
+// charged for the menu item.
+type PricedSelection struct {
+	MenuItemID     int32            `json:"menu_item_id"`
+	RestaurantID   int32            `json:"restaurant_id"`
+	Name           string           `json:"name"`
+	UnitPrice      float64          `json:"unit_price"`
+	PriceVersionID *int32           `json:"price_version_id,omitempty"`
+	Options        []SelectedOption `json:"options"`
+	Components     []ComboComponent `json:"components,omitempty"`
+	Allergens      []string         `json:"allergens"`
+	DietaryTags    []string         `json:"dietary_tags"`
+}
+
+// withDetails adds the option groups, combo components, dietary info and
+// availability to menu items
+func (d *RestaurantDomain) withDetails(ctx context.Context, menuItems []generated.Menuitem) ([]MenuItemDetail, error) {
+	if len(menuItems) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int32, len(menuItems))
+	for i, menuItem := range menuItems {
+		ids[i] = menuItem.ID
+	}
+	groups, err := d.repo.GetOptionGroupsByMenuItemIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch option groups: " + err.Error())
+	}
+	options, err := d.repo.GetMenuOptionsByMenuItemIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch options: " + err.Error())
+	}
+	components, err := d.repo.GetComboComponentsByComboIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch combo components: " + err.Error())
+	}
+	dietary, err := d.repo.GetDietaryByMenuItemIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch dietary info: " + err.Error())
+	}
+	availability, err := d.repo.GetAvailabilityByMenuItemIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch availability: " + err.Error())
+	}
+	images, err := d.loadMenuItemImages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	optionsByGroup := map[int32][]MenuOption{}
+	for _, option := range options {
+		optionsByGroup[option.GroupID] = append(optionsByGroup[option.GroupID], MenuOption{
+			ID:         option.ID,
+			Name:       option.Name,
+			PriceDelta: option.PriceDelta,
+		})
+	}
+	groupsByItem := map[int32][]OptionGroup{}
+	for _, group := range groups {
+		groupOptions := optionsByGroup[group.ID]
+		if groupOptions == nil {
+			groupOptions = []MenuOption{}
+		}
+		groupsByItem[group.MenuItemID] = append(groupsByItem[group.MenuItemID], OptionGroup{
+			ID:            group.ID,
+			Name:          group.Name,
+			SelectionType: group.SelectionType,
+			MinSelect:     group.MinSelect,
+			MaxSelect:     group.MaxSelect,
+			Options:       groupOptions,
+		})
+	}
+	componentsByCombo := map[int32][]ComboComponent{}
+	for _, component := range components {
+		componentsByCombo[component.ComboID] = append(componentsByCombo[component.ComboID], ComboComponent{
+			MenuItemID: component.MenuItemID,
+			Name:       component.Name,
+			Quantity:   component.Quantity,
+		})
+	}
+
+	dietaryByItem := map[int32]generated.MenuItemDietary{}
+	for _, row := range dietary {
+		dietaryByItem[row.MenuItemID] = row
+	}
+	availabilityByItem := map[int32]generated.MenuItemAvailability{}
+	for _, row := range availability {
+		availabilityByItem[row.MenuItemID] = row
+	}
+	now := time.Now()
+
+	details := make([]MenuItemDetail, len(menuItems))
+	for i, menuItem := range menuItems {
+		details[i] = MenuItemDetail{
+			Menuitem:     menuItem,
+			OptionGroups: groupsByItem[menuItem.ID],
+			Components:   componentsByCombo[menuItem.ID],
+			Allergens:    []string{},
+			DietaryTags:  []string{},
+			Availability: Availability{Available: true},
+			Image:        images[menuItem.ID],
+		}
+		if details[i].OptionGroups == nil {
+			details[i].OptionGroups = []OptionGroup{}
+		}
+		if row, ok := dietaryByItem[menuItem.ID]; ok {
+			details[i].Allergens = row.Allergens
+			details[i].DietaryTags = row.DietaryTags
+			details[i].Nutrition = toNutrition(row)
+		}
+		if row, ok := availabilityByItem[menuItem.ID]; ok {
+			details[i].Availability = toAvailability(row, now)
+		}
+	}
+	return details, nil
+}
+
+// GetMenuItemDetailDomain finds a menu item of the restaurant with its options and dietary info
+func (d *RestaurantDomain) GetMenuItemDetailDomain(ctx context.Context, restaurantId, menuItemId int32) (*MenuItemDetail, error) {
+	menuItem, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	})
+	if err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+
+	details, err := d.withDetails(ctx, []generated.Menuitem{menuItem})
+	if err != nil {
+		return nil, err
+	}
+	return &details[0], nil
+}
+
+func validateOptionGroup(group *OptionGroupParams) error {
+	group.Name = strings.TrimSpace(group.Name)
+	if group.Name == "" {
+		return fmt.Errorf("%w: groups need a name", ErrInvalidOptionGroups)
+	}
+	switch group.SelectionType {
+	case "":
+		group.SelectionType = SelectionSingle
+	case SelectionSingle, SelectionMulti:
+	default:
+		return fmt.Errorf("%w: selection type of %s must be single or multi", ErrInvalidOptionGroups, group.Name)
+	}
+	if len(group.Options) == 0 {
+		return fmt.Errorf("%w: %s has no options", ErrInvalidOptionGroups, group.Name)
+	}
+	if group.MaxSelect == 0 {
+		group.MaxSelect = 1
+		if group.SelectionType == SelectionMulti {
+			group.MaxSelect = int32(len(group.Options))
+		}
+	}
+	if group.SelectionType == SelectionSingle && group.MaxSelect != 1 {
+		return fmt.Errorf("%w: single select group %s takes one option at most", ErrInvalidOptionGroups, group.Name)
+	}
+	if group.MinSelect < 0 || group.MaxSelect < group.MinSelect || int(group.MinSelect) > len(group.Options) {
+		return fmt.Errorf("%w: %s cannot take between %d and %d of its %d options",
+			ErrInvalidOptionGroups, group.Name, group.MinSelect, group.MaxSelect, len(group.Options))
+	}
+	for i := range group.Options {
+		group.Options[i].Name = strings.TrimSpace(group.Options[i].Name)
+		if group.Options[i].Name == "" {
+			return fmt.Errorf("%w: options of %s need a name", ErrInvalidOptionGroups, group.Name)
+		}
+	}
+	return nil
+}
+
+// SetOptionGroupsDomain replaces the option groups of a menu item
+func (d *RestaurantDomain) SetOptionGroupsDomain(ctx context.Context, restaurantId, menuItemId int32, groups []OptionGroupParams) (*MenuItemDetail, error) {
+	for i := range groups {
+		if err := validateOptionGroup(&groups[i]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	}); err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if err := q.DeleteOptionGroups(ctx, menuItemId); err != nil {
+			return err
+		}
+		for i, group := range groups {
+			groupId, err := q.CreateOptionGroup(ctx, generated.CreateOptionGroupParams{
+				MenuItemID:    menuItemId,
+				Name:          group.Name,
+				SelectionType: group.SelectionType,
+				MinSelect:     group.MinSelect,
+				MaxSelect:     group.MaxSelect,
+				Position:      int32(i),
+			})
+			if err != nil {
+				return err
+			}
+			for j, option := range group.Options {
+				if err := q.CreateMenuOption(ctx, generated.CreateMenuOptionParams{
+					GroupID:    groupId,
+					Name:       option.Name,
+					PriceDelta: option.PriceDelta,
+					Position:   int32(j),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to save option groups: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+
+	return d.GetMenuItemDetailDomain(ctx, restaurantId, menuItemId)
+}
+
+// SetComboComponentsDomain replaces the menu items a combo is made of. The
+// components must be menu items of the same restaurant, and no components
+// makes the menu item an ordinary item again.
+func (d *RestaurantDomain) SetComboComponentsDomain(ctx context.Context, restaurantId, comboId int32, components []ComboComponentParams) (*MenuItemDetail, error) {
+	seen := map[int32]bool{}
+	for i, component := range components {
+		if component.MenuItemID == comboId {
+			return nil, fmt.Errorf("%w: a combo cannot include itself", ErrInvalidCombo)
+		}
+		if seen[component.MenuItemID] {
+			return nil, fmt.Errorf("%w: menu item %d is listed twice", ErrInvalidCombo, component.MenuItemID)
+		}
+		seen[component.MenuItemID] = true
+		if component.Quantity == 0 {
+			components[i].Quantity = 1
+		} else if component.Quantity < 0 {
+			return nil, fmt.Errorf("%w: quantities must be at least 1", ErrInvalidCombo)
+		}
+	}
+
+	if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           comboId,
+	}); err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+	for _, component := range components {
+		if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+			Restaurantid: restaurantId,
+			ID:           component.MenuItemID,
+		}); err != nil {
+			return nil, fmt.Errorf("%w: menu item %d is not on the menu", ErrInvalidCombo, component.MenuItemID)
+		}
+	}
+
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if err := q.DeleteComboComponents(ctx, comboId); err != nil {
+			return err
+		}
+		for _, component := range components {
+			if err := q.CreateComboComponent(ctx, generated.CreateComboComponentParams{
+				ComboID:    comboId,
+				MenuItemID: component.MenuItemID,
+				Quantity:   component.Quantity,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to save combo: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &comboId, Action: ActionUpdated})
+
+	return d.GetMenuItemDetailDomain(ctx, restaurantId, comboId)
+}
+
+// PriceSelectionDomain checks that quantity portions of a menu item can be
+// ordered and the chosen options against its option groups, and prices the
+// item with them
+func (d *RestaurantDomain) PriceSelectionDomain(ctx context.Context, restaurantId, menuItemId int32, optionIds []int32, quantity int32) (*PricedSelection, error) {
+	menuItem, err := d.GetMenuItemDetailDomain(ctx, restaurantId, menuItemId)
+	if err != nil {
+		return nil, err
+	}
+	if err := menuItem.Availability.allows(menuItem.Name, quantity); err != nil {
+		return nil, err
+	}
+
+	chosen := map[int32]bool{}
+	for _, id := range optionIds {
+		if chosen[id] {
+			return nil, fmt.Errorf("%w: option %d is chosen twice", ErrInvalidSelection, id)
+		}
+		chosen[id] = true
+	}
+
+	selection := &PricedSelection{
+		MenuItemID:   menuItem.ID,
+		RestaurantID: menuItem.Restaurantid,
+		Name:         menuItem.Name,
+		Options:      []SelectedOption{},
+		Components:   menuItem.Components,
+		Allergens:    menuItem.Allergens,
+		DietaryTags:  menuItem.DietaryTags,
+	}
+	for _, group := range menuItem.OptionGroups {
+		var count int32
+		for _, option := range group.Options {
+			if !chosen[option.ID] {
+				continue
+			}
+			delete(chosen, option.ID)
+			count++
+			selection.UnitPrice += option.PriceDelta
+			selection.Options = append(selection.Options, SelectedOption{
+				OptionID:   option.ID,
+				Group:      group.Name,
+				Name:       option.Name,
+				PriceDelta: option.PriceDelta,
+			})
+		}
+		if count < group.MinSelect || count > group.MaxSelect {
+			if group.MinSelect == group.MaxSelect {
+				return nil, fmt.Errorf("%w: choose %d of %s", ErrInvalidSelection, group.MinSelect, group.Name)
+			}
+			return nil, fmt.Errorf("%w: choose between %d and %d of %s", ErrInvalidSelection, group.MinSelect, group.MaxSelect, group.Name)
+		}
+	}
+	for id := range chosen {
+		return nil, fmt.Errorf("%w: option %d is not offered with %s", ErrInvalidSelection, id, menuItem.Name)
+	}
+
+	price, priceVersionId, err := d.currentPrice(ctx, *menuItem)
+	if err != nil {
+		return nil, err
+	}
+	selection.UnitPrice += price
+	selection.PriceVersionID = priceVersionId
+
+	// Price deltas may be negative, but an item never costs less than nothing
+	selection.UnitPrice = math.Max(0, math.Round(selection.UnitPrice*100)/100)
+
+	return selection, nil
+}
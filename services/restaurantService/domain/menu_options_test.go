@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var (
+	menuItemColumns       = []string{"id", "restaurantid", "name", "price", "description", "deleted_at"}
+	optionGroupColumns    = []string{"id", "menu_item_id", "name", "selection_type", "min_select", "max_select", "position"}
+	menuOptionColumns     = []string{"id", "group_id", "name", "price_delta", "position"}
+	comboComponentColumns = []string{"combo_id", "menu_item_id", "name", "quantity"}
+)
+
+// expectPizza expects a vegetarian pizza to be looked up, offered in two sizes
+// with up to two toppings, with the given availability rows
+func expectPizza(mock pgxmock.PgxPoolIface, availability *pgxmock.Rows) {
+	mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+		WithArgs(int32(1), int32(1)).
+		WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(10), nil, nil))
+	mock.ExpectQuery(`FROM option_group\s+WHERE menu_item_id = ANY`).
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(optionGroupColumns).
+			AddRow(int32(1), int32(1), "Size", SelectionSingle, int32(1), int32(1), int32(0)).
+			AddRow(int32(2), int32(1), "Toppings", SelectionMulti, int32(0), int32(2), int32(1)))
+	mock.ExpectQuery(`FROM menu_option o\s+JOIN option_group g`).
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(menuOptionColumns).
+			AddRow(int32(1), int32(1), "Medium", float64(0), int32(0)).
+			AddRow(int32(2), int32(1), "Large", float64(2.5), int32(1)).
+			AddRow(int32(3), int32(2), "Extra cheese", float64(1), int32(0)).
+			AddRow(int32(4), int32(2), "Ham", float64(1.5), int32(1)).
+			AddRow(int32(5), int32(2), "Olives", float64(1), int32(2)))
+	mock.ExpectQuery(`FROM combo_component c`).
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(comboComponentColumns))
+	mock.ExpectQuery(`FROM menu_item_dietary`).
+		WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows(dietaryColumns).
+			AddRow(int32(1), []string{"gluten", "milk"}, []string{"vegetarian"}, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery(`FROM menu_item_availability`).
+		WithArgs([]int32{1}).
+		WillReturnRows(availability)
+}
+
+func TestPriceSelectionDomain(t *testing.T) {
+	tests := []struct {
+		name        string
+		optionIds   []int32
+		wantPrice   float64
+		wantOptions int
+		wantErr     error
+	}{
+		{"Required option only", []int32{1}, 10, 1, nil},
+		{"Large with two toppings", []int32{2, 3, 4}, 15, 3, nil},
+		{"Missing required option", nil, 0, 0, ErrInvalidSelection},
+		{"Two sizes", []int32{1, 2}, 0, 0, ErrInvalidSelection},
+		{"Too many toppings", []int32{1, 3, 4, 5}, 0, 0, ErrInvalidSelection},
+		{"Option of another item", []int32{1, 99}, 0, 0, ErrInvalidSelection},
+		{"Option chosen twice", []int32{1, 3, 3}, 0, 0, ErrInvalidSelection},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			expectPizza(mock, pgxmock.NewRows(availabilityColumns))
+			if tt.wantErr == nil {
+				expectCurrentPrice(mock, pgxmock.NewRows(priceColumns).AddRow(int32(3), int32(1), float64(10), time.Now().Add(-time.Hour), nil))
+			}
+
+			// Act
+			got, err := domain.PriceSelectionDomain(context.Background(), 1, 1, tt.optionIds, 1)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if got.UnitPrice != tt.wantPrice || len(got.Options) != tt.wantOptions {
+				t.Errorf("got price %v with %d options, want %v with %d", got.UnitPrice, len(got.Options), tt.wantPrice, tt.wantOptions)
+			}
+			if got.PriceVersionID == nil || *got.PriceVersionID != 3 {
+				t.Errorf("got price version %v, want 3", got.PriceVersionID)
+			}
+			if len(got.Allergens) != 2 || len(got.DietaryTags) != 1 {
+				t.Errorf("got allergens %v and dietary tags %v of the pizza", got.Allergens, got.DietaryTags)
+			}
+		})
+	}
+}
+
+func TestSetOptionGroupsDomain(t *testing.T) {
+	t.Run("Rejects invalid groups", func(t *testing.T) {
+		options := []MenuOptionParams{{Name: "Medium"}, {Name: "Large", PriceDelta: 2.5}}
+		invalid := map[string]OptionGroupParams{
+			"no name":                    {Options: options},
+			"unknown selection type":     {Name: "Size", SelectionType: "some", Options: options},
+			"single taking two":          {Name: "Size", SelectionType: SelectionSingle, MaxSelect: 2, Options: options},
+			"minimum above maximum":      {Name: "Toppings", SelectionType: SelectionMulti, MinSelect: 2, MaxSelect: 1, Options: options},
+			"more required than options": {Name: "Toppings", SelectionType: SelectionMulti, MinSelect: 3, MaxSelect: 3, Options: options},
+			"no options":                 {Name: "Size"},
+		}
+		for name, group := range invalid {
+			mock, _, domain := SetupTestMocks(t)
+
+			_, err := domain.SetOptionGroupsDomain(context.Background(), 1, 1, []OptionGroupParams{group})
+
+			if !errors.Is(err, ErrInvalidOptionGroups) {
+				t.Errorf("%s: got error %v, want %v", name, err, ErrInvalidOptionGroups)
+			}
+			CloseMocks(mock)
+		}
+	})
+
+	t.Run("Replaces the groups", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(10), nil, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM option_group`).
+			WithArgs(int32(1)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 2))
+		mock.ExpectQuery(`INSERT INTO option_group`).
+			WithArgs(int32(1), "Size", SelectionSingle, int32(1), int32(1), int32(0)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(7)))
+		mock.ExpectExec(`INSERT INTO menu_option`).
+			WithArgs(int32(7), "Medium", float64(0), int32(0)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO menu_option`).
+			WithArgs(int32(7), "Large", float64(2.5), int32(1)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		expectPizza(mock, pgxmock.NewRows(availabilityColumns))
+
+		// Act
+		got, err := domain.SetOptionGroupsDomain(context.Background(), 1, 1, []OptionGroupParams{
+			{Name: " Size ", MinSelect: 1, Options: []MenuOptionParams{{Name: "Medium"}, {Name: "Large", PriceDelta: 2.5}}},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(got.OptionGroups) != 2 {
+			t.Errorf("got %d option groups, want 2", len(got.OptionGroups))
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
+
+func TestSetComboComponentsDomain(t *testing.T) {
+	t.Run("Rejects a combo including itself", func(t *testing.T) {
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		_, err := domain.SetComboComponentsDomain(context.Background(), 1, 3, []ComboComponentParams{{MenuItemID: 3}})
+
+		if !errors.Is(err, ErrInvalidCombo) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCombo)
+		}
+	})
+
+	t.Run("Rejects items of other restaurants", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(3)).
+			WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(3), int32(1), "Pizza Menu", float64(15), nil, nil))
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(42)).
+			WillReturnRows(pgxmock.NewRows(menuItemColumns))
+
+		// Act
+		_, err := domain.SetComboComponentsDomain(context.Background(), 1, 3, []ComboComponentParams{{MenuItemID: 42, Quantity: 1}})
+
+		// Assert
+		if !errors.Is(err, ErrInvalidCombo) {
+			t.Errorf("got error %v, want %v", err, ErrInvalidCombo)
+		}
+	})
+}
@@ -0,0 +1,460 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	_ "time/tzdata" // the service image has no zoneinfo
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	clockLayout = "15:04"
+	dateLayout  = "2006-01-02"
+	// lookaheadDays is how far ahead the next opening is searched for
+	lookaheadDays = 14
+)
+
+// openingHoursLocation is the time zone opening hours are given in
+var openingHoursLocation = mustLoadLocation("Europe/Copenhagen")
+
+var (
+	ErrInvalidOpeningHours      = errors.New("invalid opening hours")
+	ErrOpeningExceptionNotFound = errors.New("opening exception not found")
+)
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+// OpeningInterval is an interval of the weekly opening hours. Weekdays count
+// from Sunday = 0, and an interval closing at or before its opening time ends
+// the next day, so 00:00-00:00 is open around the clock.
+type OpeningInterval struct {
+	Weekday  int    `json:"weekday" example:"1"`
+	OpensAt  string `json:"opens_at" example:"11:00"`
+	ClosesAt string `json:"closes_at" example:"22:00"`
+}
+
+// OpeningExceptionParams replaces the weekly hours on a date. Without times
+// the restaurant is closed for the whole day.
+type OpeningExceptionParams struct {
+	Date     string  `json:"date" example:"2025-12-24"`
+	OpensAt  *string `json:"opens_at" example:"10:00"`
+	ClosesAt *string `json:"closes_at" example:"14:00"`
+	Note     *string `json:"note" example:"Christmas Eve"`
+}
+
+// OpeningException is an exception to the weekly opening hours
+type OpeningException struct {
+	ID       int32   `json:"id"`
+	Date     string  `json:"date" example:"2025-12-24"`
+	OpensAt  *string `json:"opens_at" example:"10:00"`
+	ClosesAt *string `json:"closes_at" example:"14:00"`
+	Note     *string `json:"note" example:"Christmas Eve"`
+}
+
+// OpeningHours are the weekly hours and the upcoming exceptions of a restaurant
+type OpeningHours struct {
+	RestaurantID   int32              `json:"restaurant_id"`
+	OrderingPaused bool               `json:"ordering_paused"`
+	Weekly         []OpeningInterval  `json:"weekly"`
+	Exceptions     []OpeningException `json:"exceptions"`
+}
+
+// States of a restaurant. An open restaurant with its kitchen at capacity is
+// not closed, but takes no orders until orders leave the kitchen.
+const (
+	StateOpen       = "open"
+	StateClosed     = "closed"
+	StatePaused     = "paused"
+	StateAtCapacity = "at_capacity"
+)
+
+// OpeningStatus tells whether a restaurant takes orders right now, and
+// otherwise why not and when it opens next. There is no next opening while
+// ordering is paused, while the kitchen is at capacity or when the restaurant
+// has no upcoming hours.
+type OpeningStatus struct {
+	IsOpen      bool       `json:"is_open"`
+	State       string     `json:"state" example:"open"`
+	NextOpening *time.Time `json:"next_opening"`
+}
+
+// RestaurantStatus is the opening and kitchen status of a single restaurant
+type RestaurantStatus struct {
+	RestaurantID   int32 `json:"restaurant_id"`
+	OrderingPaused bool  `json:"ordering_paused"`
+	OpeningStatus
+	KitchenStatus
+}
+
+// RestaurantListing is a restaurant as it is listed, with its opening and
+// kitchen status. Listings for a zip code also carry the delivery terms there,
+// and listings around a point the distance to it.
+type RestaurantListing struct {
+	generated.Restaurant
+	OpeningStatus
+	KitchenStatus
+	Images     RestaurantImages        `json:"images"`
+	Location   *Location               `json:"location,omitempty"`
+	DistanceKm *float64                `json:"distance_km,omitempty" example:"1.234"`
+	Delivery   *generated.DeliveryZone `json:"delivery,omitempty"`
+}
+
+type clockInterval struct {
+	opens  time.Duration
+	closes time.Duration
+}
+
+// schedule holds the opening hours of a restaurant. Exceptions are keyed by
+// date, and an exception without intervals closes the whole day.
+type schedule struct {
+	weekly     map[time.Weekday][]clockInterval
+	exceptions map[string][]clockInterval
+	paused     bool
+}
+
+func newSchedule() *schedule {
+	return &schedule{
+		weekly:     map[time.Weekday][]clockInterval{},
+		exceptions: map[string][]clockInterval{},
+	}
+}
+
+// clockOn is the time of day on the date of day, in Copenhagen time. Building
+// it from the wall clock keeps opening times right across DST changes.
+func clockOn(day time.Time, clock time.Duration) time.Time {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, openingHoursLocation)
+}
+
+// status evaluates the schedule at now
+func (s *schedule) status(now time.Time) OpeningStatus {
+	if s.paused {
+		return OpeningStatus{State: StatePaused}
+	}
+
+	now = now.In(openingHoursLocation)
+	var next *time.Time
+	// Start the day before, as its intervals can run past midnight
+	for offset := -1; offset <= lookaheadDays; offset++ {
+		day := now.AddDate(0, 0, offset)
+		intervals, ok := s.exceptions[day.Format(dateLayout)]
+		if !ok {
+			intervals = s.weekly[day.Weekday()]
+		}
+
+		for _, interval := range intervals {
+			opens := clockOn(day, interval.opens)
+			closes := clockOn(day, interval.closes)
+			if interval.closes <= interval.opens {
+				closes = clockOn(day.AddDate(0, 0, 1), interval.closes)
+			}
+
+			if !now.Before(opens) && now.Before(closes) {
+				return OpeningStatus{IsOpen: true, State: StateOpen}
+			}
+			if opens.After(now) && (next == nil || opens.Before(*next)) {
+				next = &opens
+			}
+		}
+	}
+
+	return OpeningStatus{State: StateClosed, NextOpening: next}
+}
+
+func parseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a time like 11:30", ErrInvalidOpeningHours, value)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func toPgTime(clock time.Duration) pgtype.Time {
+	return pgtype.Time{Microseconds: clock.Microseconds(), Valid: true}
+}
+
+func fromPgTime(t pgtype.Time) time.Duration {
+	return time.Duration(t.Microseconds) * time.Microsecond
+}
+
+func formatClock(t pgtype.Time) *string {
+	if !t.Valid {
+		return nil
+	}
+	clock := fromPgTime(t)
+	formatted := fmt.Sprintf("%02d:%02d", int(clock/time.Hour), int(clock%time.Hour/time.Minute))
+	return &formatted
+}
+
+// today is the current date in Copenhagen
+func today() time.Time {
+	y, m, d := time.Now().In(openingHoursLocation).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// loadSchedules fetches the opening hours of the restaurants in two queries
+func (d *RestaurantDomain) loadSchedules(ctx context.Context, restaurants []generated.Restaurant) (map[int32]*schedule, error) {
+	schedules := map[int32]*schedule{}
+	ids := make([]int32, len(restaurants))
+	for i, restaurant := range restaurants {
+		ids[i] = restaurant.ID
+		schedules[restaurant.ID] = newSchedule()
+		schedules[restaurant.ID].paused = restaurant.OrderingPaused
+	}
+
+	hours, err := d.repo.GetOpeningHoursByRestaurantIds(ctx, ids)
+	if err != nil {
+		return nil, errors.New("failed to fetch opening hours: " + err.Error())
+	}
+	for _, hour := range hours {
+		s := schedules[hour.RestaurantID]
+		weekday := time.Weekday(hour.Weekday)
+		s.weekly[weekday] = append(s.weekly[weekday], clockInterval{fromPgTime(hour.OpensAt), fromPgTime(hour.ClosesAt)})
+	}
+
+	// Yesterday's exceptions can still be open after midnight
+	exceptions, err := d.repo.GetOpeningExceptionsByRestaurantIds(ctx, generated.GetOpeningExceptionsByRestaurantIdsParams{
+		RestaurantIds: ids,
+		FromDate:      pgtype.Date{Time: today().AddDate(0, 0, -1), Valid: true},
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch opening exceptions: " + err.Error())
+	}
+	for _, exception := range exceptions {
+		s := schedules[exception.RestaurantID]
+		date := exception.Date.Time.Format(dateLayout)
+		if !exception.OpensAt.Valid {
+			s.exceptions[date] = []clockInterval{}
+			continue
+		}
+		s.exceptions[date] = append(s.exceptions[date], clockInterval{fromPgTime(exception.OpensAt), fromPgTime(exception.ClosesAt)})
+	}
+
+	return schedules, nil
+}
+
+// withOpeningStatus adds the current opening and kitchen status to listed
+// restaurants. Open restaurants with their kitchen at capacity take no orders
+// until orders leave it.
+func (d *RestaurantDomain) withOpeningStatus(ctx context.Context, restaurants []generated.Restaurant) ([]RestaurantListing, error) {
+	if len(restaurants) == 0 {
+		return nil, nil
+	}
+
+	schedules, err := d.loadSchedules(ctx, restaurants)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	ids := make([]int32, len(restaurants))
+	for i, restaurant := range restaurants {
+		ids[i] = restaurant.ID
+	}
+	kitchens, err := d.loadKitchens(ctx, ids, now)
+	if err != nil {
+		return nil, err
+	}
+	images, err := d.loadRestaurantImages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	listings := make([]RestaurantListing, len(restaurants))
+	for i, restaurant := range restaurants {
+		listings[i] = RestaurantListing{
+			Restaurant:    restaurant,
+			OpeningStatus: schedules[restaurant.ID].status(now),
+			KitchenStatus: kitchens[restaurant.ID].KitchenStatus,
+			Images:        images[restaurant.ID],
+		}
+		if listings[i].IsOpen && listings[i].AtCapacity {
+			listings[i].OpeningStatus = OpeningStatus{State: StateAtCapacity}
+		}
+	}
+	return listings, nil
+}
+
+// GetRestaurantStatusDomain tells whether the restaurant takes orders right now
+func (d *RestaurantDomain) GetRestaurantStatusDomain(ctx context.Context, restaurantId int32) (*RestaurantStatus, error) {
+	restaurant, err := d.repo.GetRestaurantById(ctx, restaurantId)
+	if err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	listings, err := d.withOpeningStatus(ctx, []generated.Restaurant{restaurant})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestaurantStatus{
+		RestaurantID:   restaurantId,
+		OrderingPaused: restaurant.OrderingPaused,
+		OpeningStatus:  listings[0].OpeningStatus,
+		KitchenStatus:  listings[0].KitchenStatus,
+	}, nil
+}
+
+func (d *RestaurantDomain) GetOpeningHoursDomain(ctx context.Context, restaurantId int32) (*OpeningHours, error) {
+	restaurant, err := d.repo.GetRestaurantById(ctx, restaurantId)
+	if err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	hours, err := d.repo.GetOpeningHoursByRestaurantIds(ctx, []int32{restaurantId})
+	if err != nil {
+		return nil, errors.New("failed to fetch opening hours: " + err.Error())
+	}
+	exceptions, err := d.repo.GetOpeningExceptionsByRestaurantIds(ctx, generated.GetOpeningExceptionsByRestaurantIdsParams{
+		RestaurantIds: []int32{restaurantId},
+		FromDate:      pgtype.Date{Time: today(), Valid: true},
+	})
+	if err != nil {
+		return nil, errors.New("failed to fetch opening exceptions: " + err.Error())
+	}
+
+	openingHours := &OpeningHours{
+		RestaurantID:   restaurantId,
+		OrderingPaused: restaurant.OrderingPaused,
+		Weekly:         []OpeningInterval{},
+		Exceptions:     []OpeningException{},
+	}
+	for _, hour := range hours {
+		openingHours.Weekly = append(openingHours.Weekly, OpeningInterval{
+			Weekday:  int(hour.Weekday),
+			OpensAt:  *formatClock(hour.OpensAt),
+			ClosesAt: *formatClock(hour.ClosesAt),
+		})
+	}
+	for _, exception := range exceptions {
+		openingHours.Exceptions = append(openingHours.Exceptions, toOpeningException(exception))
+	}
+
+	return openingHours, nil
+}
+
+func toOpeningException(exception generated.OpeningException) OpeningException {
+	return OpeningException{
+		ID:       exception.ID,
+		Date:     exception.Date.Time.Format(dateLayout),
+		OpensAt:  formatClock(exception.OpensAt),
+		ClosesAt: formatClock(exception.ClosesAt),
+		Note:     exception.Note,
+	}
+}
+
+// SetOpeningHoursDomain replaces the weekly opening hours of the restaurant
+func (d *RestaurantDomain) SetOpeningHoursDomain(ctx context.Context, restaurantId int32, intervals []OpeningInterval) (*OpeningHours, error) {
+	params := make([]generated.CreateOpeningHoursParams, len(intervals))
+	for i, interval := range intervals {
+		if interval.Weekday < 0 || interval.Weekday > 6 {
+			return nil, fmt.Errorf("%w: weekday must be between 0 (Sunday) and 6 (Saturday)", ErrInvalidOpeningHours)
+		}
+		opens, err := parseClock(interval.OpensAt)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := parseClock(interval.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		params[i] = generated.CreateOpeningHoursParams{
+			RestaurantID: restaurantId,
+			Weekday:      int16(interval.Weekday),
+			OpensAt:      toPgTime(opens),
+			ClosesAt:     toPgTime(closes),
+		}
+	}
+
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if err := q.DeleteOpeningHours(ctx, restaurantId); err != nil {
+			return err
+		}
+		for _, param := range params {
+			if err := q.CreateOpeningHours(ctx, param); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to save opening hours: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return d.GetOpeningHoursDomain(ctx, restaurantId)
+}
+
+func (d *RestaurantDomain) AddOpeningExceptionDomain(ctx context.Context, restaurantId int32, params OpeningExceptionParams) (*OpeningException, error) {
+	date, err := time.Parse(dateLayout, params.Date)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a date like 2025-12-24", ErrInvalidOpeningHours, params.Date)
+	}
+	if (params.OpensAt == nil) != (params.ClosesAt == nil) {
+		return nil, fmt.Errorf("%w: give both opens_at and closes_at, or neither to close the whole day", ErrInvalidOpeningHours)
+	}
+
+	create := generated.CreateOpeningExceptionParams{
+		RestaurantID: restaurantId,
+		Date:         pgtype.Date{Time: date, Valid: true},
+		Note:         params.Note,
+	}
+	if params.OpensAt != nil {
+		opens, err := parseClock(*params.OpensAt)
+		if err != nil {
+			return nil, err
+		}
+		closes, err := parseClock(*params.ClosesAt)
+		if err != nil {
+			return nil, err
+		}
+		create.OpensAt = toPgTime(opens)
+		create.ClosesAt = toPgTime(closes)
+	}
+
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	exception, err := d.repo.CreateOpeningException(ctx, create)
+	if err != nil {
+		return nil, errors.New("failed to create opening exception: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	result := toOpeningException(exception)
+	return &result, nil
+}
+
+func (d *RestaurantDomain) DeleteOpeningExceptionDomain(ctx context.Context, restaurantId int32, exceptionId int32) error {
+	deleted, err := d.repo.DeleteOpeningException(ctx, generated.DeleteOpeningExceptionParams{
+		RestaurantID: restaurantId,
+		ID:           exceptionId,
+	})
+	if err != nil {
+		return errors.New("failed to delete opening exception: " + err.Error())
+	}
+	if deleted == 0 {
+		return ErrOpeningExceptionNotFound
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return nil
+}
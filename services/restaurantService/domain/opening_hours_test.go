@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleStatus(t *testing.T) {
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, openingHoursLocation)
+	}
+	clock := func(hour, minute int) time.Duration {
+		return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute
+	}
+
+	// Lunch and dinner on weekdays, late nights on Saturday
+	s := newSchedule()
+	for weekday := time.Monday; weekday <= time.Friday; weekday++ {
+		s.weekly[weekday] = []clockInterval{{clock(11, 0), clock(14, 0)}, {clock(17, 0), clock(22, 0)}}
+	}
+	s.weekly[time.Saturday] = []clockInterval{{clock(18, 0), clock(2, 0)}}
+	// Closed on Christmas Eve, short hours on the 23rd
+	s.exceptions["2025-12-24"] = []clockInterval{}
+	s.exceptions["2025-12-23"] = []clockInterval{{clock(11, 0), clock(13, 0)}}
+
+	paused := newSchedule()
+	paused.weekly = s.weekly
+	paused.paused = true
+
+	tests := []struct {
+		name      string
+		schedule  *schedule
+		now       time.Time
+		wantOpen  bool
+		wantState string
+		wantNext  *time.Time
+	}{
+		{"Open at lunch", s, at(time.January, 20, 12, 0), true, StateOpen, nil},
+		{"Closed between lunch and dinner", s, at(time.January, 20, 15, 0), false, StateClosed, ptr(at(time.January, 20, 17, 0))},
+		{"Closes at the closing time", s, at(time.January, 20, 22, 0), false, StateClosed, ptr(at(time.January, 21, 11, 0))},
+		{"Open after midnight on Saturday night", s, at(time.January, 26, 1, 30), true, StateOpen, nil},
+		{"Closed on Sunday until Monday lunch", s, at(time.January, 26, 3, 0), false, StateClosed, ptr(at(time.January, 27, 11, 0))},
+		{"Exception closes the whole day", s, at(time.December, 24, 12, 0), false, StateClosed, ptr(at(time.December, 25, 11, 0))},
+		{"Exception replaces the weekly hours", s, at(time.December, 23, 13, 30), false, StateClosed, ptr(at(time.December, 25, 11, 0))},
+		{"Open in the night DST starts", s, at(time.March, 30, 1, 30), true, StateOpen, nil},
+		{"Opening time is kept after DST starts", s, at(time.March, 30, 12, 0), false, StateClosed, ptr(at(time.March, 31, 11, 0))},
+		{"Paused restaurants are closed", paused, at(time.January, 20, 12, 0), false, StatePaused, nil},
+		{"No opening hours", newSchedule(), at(time.January, 20, 12, 0), false, StateClosed, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			got := tt.schedule.status(tt.now)
+
+			// Assert
+			if got.IsOpen != tt.wantOpen || got.State != tt.wantState {
+				t.Errorf("got open %v in state %s, want %v in state %s", got.IsOpen, got.State, tt.wantOpen, tt.wantState)
+			}
+			if (got.NextOpening == nil) != (tt.wantNext == nil) ||
+				(got.NextOpening != nil && !got.NextOpening.Equal(*tt.wantNext)) {
+				t.Errorf("got next opening %v, want %v", got.NextOpening, tt.wantNext)
+			}
+		})
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"11:30", 11*time.Hour + 30*time.Minute, false},
+		{"00:00", 0, false},
+		{"24:00", 0, true},
+		{"11.30", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseClock(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,209 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+var (
+	ErrPriceVersionNotFound = errors.New("price version not found")
+	ErrInvalidPriceChange   = errors.New("invalid price change")
+	ErrPriceInEffect        = errors.New("price version already in effect")
+)
+
+// PriceChangeParams changes the price of a menu item from a time on. Without
+// a time the price changes right away.
+type PriceChangeParams struct {
+	Price     *float64   `json:"price" example:"13.99"`
+	ValidFrom *time.Time `json:"valid_from" example:"2025-02-01T00:00:00Z"`
+}
+
+// priceTime is the time as stored in the price versions, which keep UTC
+// times at microsecond precision
+func priceTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// setPrice adds a price version of the menu item from the time on. The version
+// in effect then ends, and the new one lasts until the next scheduled version.
+// A version from the same time is replaced.
+func setPrice(ctx context.Context, q *generated.Queries, menuItemId int32, price float64, from time.Time) (generated.MenuItemPrice, error) {
+	versions, err := q.GetPriceVersionsByMenuItemId(ctx, menuItemId)
+	if err != nil {
+		return generated.MenuItemPrice{}, err
+	}
+
+	var validTo *time.Time
+	for _, version := range versions {
+		switch {
+		case version.ValidFrom.Equal(from):
+			validTo = version.ValidTo
+			if err := q.DeletePriceVersion(ctx, version.ID); err != nil {
+				return generated.MenuItemPrice{}, err
+			}
+		case version.ValidFrom.Before(from) && (version.ValidTo == nil || version.ValidTo.After(from)):
+			if err := q.SetPriceVersionEnd(ctx, generated.SetPriceVersionEndParams{ID: version.ID, ValidTo: &from}); err != nil {
+				return generated.MenuItemPrice{}, err
+			}
+		case version.ValidFrom.After(from) && validTo == nil:
+			validTo = &version.ValidFrom
+		}
+	}
+
+	return q.CreatePriceVersion(ctx, generated.CreatePriceVersionParams{
+		MenuItemID: menuItemId,
+		Price:      price,
+		ValidFrom:  from,
+		ValidTo:    validTo,
+	})
+}
+
+// currentPrice finds the price version of the menu item charged now. Menu
+// items without versions are charged their price, without a version.
+func (d *RestaurantDomain) currentPrice(ctx context.Context, menuItem MenuItemDetail) (float64, *int32, error) {
+	version, err := d.repo.GetPriceVersionAt(ctx, generated.GetPriceVersionAtParams{
+		MenuItemID: menuItem.ID,
+		ValidFrom:  priceTime(time.Now()),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return menuItem.Price, nil, nil
+	}
+	if err != nil {
+		return 0, nil, errors.New("failed to fetch price: " + err.Error())
+	}
+	return version.Price, &version.ID, nil
+}
+
+// GetPriceHistoryDomain lists the price versions of a menu item, past,
+// current and scheduled. With a time only the version charged then is listed.
+func (d *RestaurantDomain) GetPriceHistoryDomain(ctx context.Context, restaurantId, menuItemId int32, at *time.Time) ([]generated.MenuItemPrice, error) {
+	if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	}); err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+
+	versions, err := d.repo.GetPriceVersionsByMenuItemId(ctx, menuItemId)
+	if err != nil {
+		return nil, errors.New("failed to fetch price history: " + err.Error())
+	}
+
+	history := []generated.MenuItemPrice{}
+	for _, version := range versions {
+		if at != nil && (version.ValidFrom.After(*at) || (version.ValidTo != nil && !version.ValidTo.After(*at))) {
+			continue
+		}
+		history = append(history, version)
+	}
+	return history, nil
+}
+
+// ChangePriceDomain changes the price of a menu item, right away or from a
+// future time on. Scheduled changes take effect through
+// ApplyScheduledPricesDomain.
+func (d *RestaurantDomain) ChangePriceDomain(ctx context.Context, restaurantId, menuItemId int32, params PriceChangeParams) (*generated.MenuItemPrice, error) {
+	if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	}); err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+	if params.Price == nil || *params.Price < 0 {
+		return nil, ErrNegativePrice
+	}
+
+	now := priceTime(time.Now())
+	from := now
+	if params.ValidFrom != nil {
+		from = priceTime(*params.ValidFrom)
+		if !from.After(now) {
+			return nil, fmt.Errorf("%w: valid_from must be in the future, or left out to change the price right away", ErrInvalidPriceChange)
+		}
+	}
+
+	var version generated.MenuItemPrice
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		if version, err = setPrice(ctx, q, menuItemId, *params.Price, from); err != nil {
+			return err
+		}
+		if params.ValidFrom == nil {
+			return q.SetMenuItemPrice(ctx, generated.SetMenuItemPriceParams{ID: menuItemId, Price: *params.Price})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.New("failed to change price: " + err.Error())
+	}
+
+	if params.ValidFrom == nil {
+		d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+	}
+
+	return &version, nil
+}
+
+// CancelPriceChangeDomain removes a scheduled price change. The version
+// before it lasts in its place.
+func (d *RestaurantDomain) CancelPriceChangeDomain(ctx context.Context, restaurantId, menuItemId, priceId int32) error {
+	if _, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	}); err != nil {
+		return ErrMenuItemNotFound
+	}
+
+	versions, err := d.repo.GetPriceVersionsByMenuItemId(ctx, menuItemId)
+	if err != nil {
+		return errors.New("failed to fetch price history: " + err.Error())
+	}
+	var previous, cancelled *generated.MenuItemPrice
+	for i := range versions {
+		if versions[i].ID == priceId {
+			cancelled = &versions[i]
+			break
+		}
+		previous = &versions[i]
+	}
+	if cancelled == nil {
+		return ErrPriceVersionNotFound
+	}
+	if !cancelled.ValidFrom.After(priceTime(time.Now())) {
+		return ErrPriceInEffect
+	}
+
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		if err := q.DeletePriceVersion(ctx, cancelled.ID); err != nil {
+			return err
+		}
+		if previous == nil {
+			return nil
+		}
+		return q.SetPriceVersionEnd(ctx, generated.SetPriceVersionEndParams{ID: previous.ID, ValidTo: cancelled.ValidTo})
+	})
+	if err != nil {
+		return errors.New("failed to cancel price change: " + err.Error())
+	}
+	return nil
+}
+
+// ApplyScheduledPricesDomain sets menu items to the price of their current
+// version once scheduled changes take effect, and returns how many changed
+func (d *RestaurantDomain) ApplyScheduledPricesDomain(ctx context.Context) (int, error) {
+	changed, err := d.repo.ApplyCurrentPrices(ctx, priceTime(time.Now()))
+	if err != nil {
+		return 0, errors.New("failed to apply scheduled prices: " + err.Error())
+	}
+
+	for _, menuItem := range changed {
+		d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: menuItem.Restaurantid, MenuItemID: &menuItem.ID, Action: ActionUpdated})
+	}
+	return len(changed), nil
+}
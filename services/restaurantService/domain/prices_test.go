@@ -0,0 +1,269 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var priceColumns = []string{"id", "menu_item_id", "price", "valid_from", "valid_to"}
+
+// expectCurrentPrice expects the price version of menu item 1 charged now to
+// be looked up
+func expectCurrentPrice(mock pgxmock.PgxPoolIface, rows *pgxmock.Rows) {
+	mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1 AND valid_from <= \$2`).
+		WithArgs(int32(1), pgxmock.AnyArg()).
+		WillReturnRows(rows)
+}
+
+// expectFirstPrice expects the first price version of a menu item to be added
+func expectFirstPrice(mock pgxmock.PgxPoolIface, menuItemId int32, price float64) {
+	mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+		WithArgs(menuItemId).
+		WillReturnRows(pgxmock.NewRows(priceColumns))
+	mock.ExpectQuery(`INSERT INTO menu_item_price`).
+		WithArgs(menuItemId, price, pgxmock.AnyArg(), (*time.Time)(nil)).
+		WillReturnRows(pgxmock.NewRows(priceColumns).AddRow(int32(10), menuItemId, price, time.Now(), nil))
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+func expectMenuItem(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+		WithArgs(int32(1), int32(1)).
+		WillReturnRows(pgxmock.NewRows(menuItemColumns).AddRow(int32(1), int32(1), "Cheese Pizza", float64(10), nil, nil))
+}
+
+func TestPriceSelectionDomainPriceVersion(t *testing.T) {
+	// Arrange
+	mock, _, domain := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	expectPizza(mock, pgxmock.NewRows(availabilityColumns))
+	// The price went up a minute ago, before the menu item price was updated
+	expectCurrentPrice(mock, pgxmock.NewRows(priceColumns).AddRow(int32(4), int32(1), float64(11), time.Now().Add(-time.Minute), nil))
+
+	// Act
+	got, err := domain.PriceSelectionDomain(context.Background(), 1, 1, []int32{2}, 1)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.UnitPrice != 13.5 || got.PriceVersionID == nil || *got.PriceVersionID != 4 {
+		t.Errorf("got price %v of version %v, want 13.5 of version 4", got.UnitPrice, got.PriceVersionID)
+	}
+}
+
+func TestChangePriceDomain(t *testing.T) {
+	now := time.Now().UTC()
+	firstOfMonth := now.AddDate(0, 1, 0)
+	lastMonth := now.AddDate(0, -1, 0)
+
+	t.Run("Schedules a change, ending the current version", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenuItem(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows(priceColumns).AddRow(int32(1), int32(1), float64(10), lastMonth, nil))
+		mock.ExpectExec(`UPDATE menu_item_price\s+SET valid_to = \$2`).
+			WithArgs(int32(1), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery(`INSERT INTO menu_item_price`).
+			WithArgs(int32(1), float64(12), priceTime(firstOfMonth), (*time.Time)(nil)).
+			WillReturnRows(pgxmock.NewRows(priceColumns).AddRow(int32(2), int32(1), float64(12), priceTime(firstOfMonth), nil))
+		mock.ExpectCommit()
+
+		// Act
+		version, err := domain.ChangePriceDomain(context.Background(), 1, 1, PriceChangeParams{Price: float64Ptr(12), ValidFrom: &firstOfMonth})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if version.ID != 2 || version.Price != 12 {
+			t.Errorf("got version %+v, want version 2 at 12", version)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Changes right away until the scheduled change", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		scheduled := priceTime(firstOfMonth)
+		expectMenuItem(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows(priceColumns).
+				AddRow(int32(1), int32(1), float64(10), lastMonth, &scheduled).
+				AddRow(int32(2), int32(1), float64(12), scheduled, nil))
+		mock.ExpectExec(`UPDATE menu_item_price\s+SET valid_to = \$2`).
+			WithArgs(int32(1), pgxmock.AnyArg()).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectQuery(`INSERT INTO menu_item_price`).
+			WithArgs(int32(1), float64(9), pgxmock.AnyArg(), &scheduled).
+			WillReturnRows(pgxmock.NewRows(priceColumns).AddRow(int32(3), int32(1), float64(9), now, &scheduled))
+		mock.ExpectExec(`UPDATE menuitem\s+SET price = \$2`).
+			WithArgs(int32(1), float64(9)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		// Act
+		version, err := domain.ChangePriceDomain(context.Background(), 1, 1, PriceChangeParams{Price: float64Ptr(9)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if version.ValidTo == nil || !version.ValidTo.Equal(scheduled) {
+			t.Errorf("got version valid to %v, want %v", version.ValidTo, scheduled)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects invalid changes", func(t *testing.T) {
+		tests := []struct {
+			name    string
+			params  PriceChangeParams
+			wantErr error
+		}{
+			{"No price", PriceChangeParams{}, ErrNegativePrice},
+			{"Negative price", PriceChangeParams{Price: float64Ptr(-1)}, ErrNegativePrice},
+			{"In the past", PriceChangeParams{Price: float64Ptr(12), ValidFrom: &lastMonth}, ErrInvalidPriceChange},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				mock, _, domain := SetupTestMocks(t)
+				defer CloseMocks(mock)
+				expectMenuItem(mock)
+
+				_, err := domain.ChangePriceDomain(context.Background(), 1, 1, tt.params)
+
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+			})
+		}
+	})
+}
+
+func TestCancelPriceChangeDomain(t *testing.T) {
+	lastMonth := time.Now().UTC().AddDate(0, -1, 0)
+	nextMonth := priceTime(time.Now().AddDate(0, 1, 0))
+	versions := func() *pgxmock.Rows {
+		return pgxmock.NewRows(priceColumns).
+			AddRow(int32(1), int32(1), float64(10), lastMonth, &nextMonth).
+			AddRow(int32(2), int32(1), float64(12), nextMonth, nil)
+	}
+
+	t.Run("Extends the previous version", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenuItem(mock)
+		mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+			WithArgs(int32(1)).
+			WillReturnRows(versions())
+		mock.ExpectBegin()
+		mock.ExpectExec(`DELETE FROM menu_item_price`).
+			WithArgs(int32(2)).
+			WillReturnResult(pgxmock.NewResult("DELETE", 1))
+		mock.ExpectExec(`UPDATE menu_item_price\s+SET valid_to = \$2`).
+			WithArgs(int32(1), (*time.Time)(nil)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		mock.ExpectCommit()
+
+		// Act
+		err := domain.CancelPriceChangeDomain(context.Background(), 1, 1, 2)
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Keeps versions in effect", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenuItem(mock)
+		mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+			WithArgs(int32(1)).
+			WillReturnRows(versions())
+
+		// Act
+		err := domain.CancelPriceChangeDomain(context.Background(), 1, 1, 1)
+
+		// Assert
+		if !errors.Is(err, ErrPriceInEffect) {
+			t.Errorf("got error %v, want %v", err, ErrPriceInEffect)
+		}
+	})
+}
+
+func TestGetPriceHistoryDomain(t *testing.T) {
+	lastMonth := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	thisMonth := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		at      *time.Time
+		wantIds []int32
+	}{
+		{"Whole history", nil, []int32{1, 2}},
+		{"Last month", timePtr(lastMonth.AddDate(0, 0, 14)), []int32{1}},
+		{"Start of this month", &thisMonth, []int32{2}},
+		{"Before the first version", timePtr(lastMonth.AddDate(0, 0, -1)), []int32{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			expectMenuItem(mock)
+			mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1\s+ORDER BY valid_from`).
+				WithArgs(int32(1)).
+				WillReturnRows(pgxmock.NewRows(priceColumns).
+					AddRow(int32(1), int32(1), float64(10), lastMonth, &thisMonth).
+					AddRow(int32(2), int32(1), float64(12), thisMonth, nil))
+
+			// Act
+			history, err := domain.GetPriceHistoryDomain(context.Background(), 1, 1, tt.at)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ids := []int32{}
+			for _, version := range history {
+				ids = append(ids, version.ID)
+			}
+			if len(ids) != len(tt.wantIds) {
+				t.Fatalf("got versions %v, want %v", ids, tt.wantIds)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIds[i] {
+					t.Errorf("got versions %v, want %v", ids, tt.wantIds)
+				}
+			}
+		})
+	}
+}
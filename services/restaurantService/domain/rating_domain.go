@@ -0,0 +1,116 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+const (
+	feedbackEventsExchange = "feedback_events"
+	// Durable queue shared by the restaurant service replicas
+	feedbackEventsQueue = "restaurant_feedback_queue"
+	// Number of average reviews a restaurant starts out with, so a few
+	// reviews do not swing its rating to the extremes
+	ratingPriorWeight = 5.0
+	// Rating of those reviews. It is fixed rather than the mean of all
+	// reviews, which would shift with every review while only the reviewed
+	// restaurant is recomputed, leaving the others on a stale prior.
+	ratingPriorMean = 3.5
+)
+
+// FeedbackEvent is the payload of the order service's feedback events
+type FeedbackEvent struct {
+	FeedbackID       int32  `json:"feedback_id"`
+	OrderID          int32  `json:"order_id"`
+	RestaurantID     *int32 `json:"restaurant_id"`
+	RestaurantRating *int32 `json:"restaurant_rating"`
+}
+
+// BayesianAverage pulls the average of a restaurant's ratings towards the
+// prior mean, weighted as priorWeight extra reviews
+func BayesianAverage(ratingSum float64, reviewCount int, priorMean float64, priorWeight float64) float64 {
+	return (priorMean*priorWeight + ratingSum) / (priorWeight + float64(reviewCount))
+}
+
+// ConsumeFeedbackEvents keeps restaurant ratings up to date from the order
+// service's feedback events
+func (d *RestaurantDomain) ConsumeFeedbackEvents() {
+	broker.ConsumeFanout(feedbackEventsExchange, feedbackEventsQueue, func(event broker.Event) {
+		if event.Type != broker.FeedbackCreated && event.Type != broker.FeedbackUpdated {
+			log.Printf("Ignored event of unexpected type: %v", event.Type)
+			return
+		}
+
+		payloadBytes, err := json.Marshal(event.Payload)
+		if err != nil {
+			log.Printf("Failed to marshal event payload: %v", err)
+			return
+		}
+
+		var feedback FeedbackEvent
+		if err := json.Unmarshal(payloadBytes, &feedback); err != nil {
+			log.Printf("Failed to unmarshal feedback event: %v", err)
+			return
+		}
+
+		if err := d.ApplyFeedbackRatingDomain(context.Background(), feedback); err != nil {
+			log.Printf("Failed to apply feedback %d: %v", feedback.FeedbackID, err)
+		}
+	})
+}
+
+// ApplyFeedbackRatingDomain records the restaurant rating of a feedback and
+// recomputes the restaurant's rating. Feedback is keyed by its ID, so
+// redelivered or backfilled events are not counted twice.
+func (d *RestaurantDomain) ApplyFeedbackRatingDomain(ctx context.Context, feedback FeedbackEvent) error {
+	if feedback.RestaurantID == nil || feedback.RestaurantRating == nil {
+		return nil
+	}
+	if *feedback.RestaurantRating < 1 || *feedback.RestaurantRating > 5 {
+		return errors.New("rating must be between 1 and 5")
+	}
+
+	err := d.repo.UpsertRestaurantReview(ctx, generated.UpsertRestaurantReviewParams{
+		FeedbackID:   feedback.FeedbackID,
+		RestaurantID: *feedback.RestaurantID,
+		Rating:       *feedback.RestaurantRating,
+	})
+	if err != nil {
+		return errors.New("failed to save review: " + err.Error())
+	}
+
+	return d.RecalculateRatingDomain(ctx, *feedback.RestaurantID)
+}
+
+// RecalculateRatingDomain sets the rating and review count of a restaurant
+// from its reviews
+func (d *RestaurantDomain) RecalculateRatingDomain(ctx context.Context, restaurantId int32) error {
+	stats, err := d.repo.GetRestaurantReviewStats(ctx, restaurantId)
+	if err != nil {
+		return errors.New("failed to fetch review stats: " + err.Error())
+	}
+	if stats.ReviewCount == 0 {
+		return nil
+	}
+
+	rating := BayesianAverage(float64(stats.RatingSum), int(stats.ReviewCount), ratingPriorMean, ratingPriorWeight)
+	rating = math.Round(rating*10) / 10
+
+	err = d.repo.UpdateRestaurantRating(ctx, generated.UpdateRestaurantRatingParams{
+		Rating:      &rating,
+		ReviewCount: stats.ReviewCount,
+		ID:          restaurantId,
+	})
+	if err != nil {
+		return errors.New("failed to update restaurant rating: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+	return nil
+}
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestBayesianAverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratingSum   float64
+		reviewCount int
+		want        float64
+	}{
+		{"No reviews gives the prior", 0, 0, 4.0},
+		{"Single perfect review stays close to the prior", 5, 1, 25.0 / 6},
+		{"Many reviews dominate the prior", 500, 100, 520.0 / 105},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BayesianAverage(tt.ratingSum, tt.reviewCount, 4.0, 5)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFeedbackRatingDomain(t *testing.T) {
+	t.Run("Stores the review and recomputes the rating", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectExec(`INSERT INTO restaurant_review`).
+			WithArgs(int32(7), int32(1), int32(5)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectQuery(`FROM restaurant_review\s+WHERE restaurant_id = \$1`).
+			WithArgs(int32(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"review_count", "rating_sum"}).AddRow(int32(1), int32(5)))
+		// (3.5*5 + 5) / 6 = 3.75, rounded to one decimal
+		mock.ExpectExec(`UPDATE restaurant`).
+			WithArgs(float64Ptr(3.8), int32(1), int32(1)).
+			WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+		// Act
+		err := domain.ApplyFeedbackRatingDomain(context.Background(), FeedbackEvent{
+			FeedbackID:       7,
+			OrderID:          3,
+			RestaurantID:     int32Ptr(1),
+			RestaurantRating: int32Ptr(5),
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Feedback without restaurant rating is ignored", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		// Act
+		err := domain.ApplyFeedbackRatingDomain(context.Background(), FeedbackEvent{FeedbackID: 7, RestaurantID: int32Ptr(1)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+}
@@ -6,38 +6,108 @@ import (
 	"fmt"
 
 	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/media"
 )
 
 type RestaurantDomain struct {
-	repo *generated.Queries
+	repo    *generated.Queries
+	db      DB
+	publish EventPublisher
+	media   media.Storage
 }
 
-// NewRestaurantDomain initializes the domain layer
-func NewRestaurantDomain(repo *generated.Queries) *RestaurantDomain {
-	return &RestaurantDomain{repo: repo}
+// NewRestaurantDomain initializes the domain layer. Events are not published
+// when publish is nil, and without media images can neither be uploaded nor
+// shown.
+func NewRestaurantDomain(repo *generated.Queries, db DB, publish EventPublisher, media media.Storage) *RestaurantDomain {
+	return &RestaurantDomain{repo: repo, db: db, publish: publish, media: media}
 }
 
-func (d *RestaurantDomain) GetAllRestaurantsDomain(ctx context.Context) ([]generated.Restaurant, error) {
-	rows, err := d.repo.FetchAllRestaurants(ctx)
+// RestaurantFilter narrows the listed restaurants. Nil fields do not filter.
+type RestaurantFilter struct {
+	// Category is the slug of any of the restaurant's categories
+	Category  *string
+	MinRating *float64
+	// ZipCode lists only the restaurants delivering there, with their
+	// delivery terms
+	ZipCode *int32
+	// Origin adds the distance from it to the listed restaurants
+	Origin *Location
+}
+
+// RestaurantSortFields are the fields restaurants can be sorted on, by id by
+// default. Restaurants without a rating sort as rated 0.
+var RestaurantSortFields = []string{"id", "name", "rating"}
+
+func (d *RestaurantDomain) GetAllRestaurantsDomain(ctx context.Context, filter RestaurantFilter, list ListParams) (*Page[RestaurantListing], error) {
+	sortField, descending, err := list.sortField(RestaurantSortFields, "id")
+	if err != nil {
+		return nil, err
+	}
+	if filter.Origin != nil && !filter.Origin.valid() {
+		return nil, ErrInvalidCoordinates
+	}
+	after, err := list.decodeCursor(list.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	params := restaurantListParams{
+		Category:   slugFilter(filter.Category),
+		MinRating:  filter.MinRating,
+		ZipCode:    filter.ZipCode,
+		SortField:  sortField,
+		Descending: descending,
+		PageLimit:  int32(list.limit() + 1),
+	}
+	if after != nil {
+		params.CursorID = &after.ID
+		switch sortField {
+		case "name":
+			params.CursorName = &after.Value
+		case "rating":
+			params.CursorRating, err = parseCursorNumber(after.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := listRestaurants(ctx, d.db, params)
 	if err != nil {
 		return nil, errors.New("failed to fetch restaurants")
 	}
 
-	var restaurants []generated.Restaurant
-	for _, row := range rows {
-		restaurants = append(restaurants, generated.Restaurant{
-			ID:       row.ID,
-			Name:     row.Name,
-			Rating:   row.Rating,
-			Category: row.Category,
-			Address:  row.Address,
-			ZipCode:  row.ZipCode,
-		})
+	page := newPage(rows, list.limit(), func(last generated.Restaurant) cursor {
+		next := cursor{Sort: list.Sort, ID: last.ID}
+		switch sortField {
+		case "name":
+			next.Value = last.Name
+		case "rating":
+			next.Value = formatCursorNumber(last.Rating)
+		}
+		return next
+	})
+
+	listings, err := d.withOpeningStatus(ctx, page.Items)
+	if err != nil {
+		return nil, err
+	}
+	if filter.ZipCode != nil {
+		if err := d.withDeliveryTerms(ctx, listings, *filter.ZipCode); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.withLocations(ctx, listings, filter.Origin); err != nil {
+		return nil, err
 	}
-	return restaurants, nil
+	if listings == nil {
+		listings = []RestaurantListing{}
+	}
+	return &Page[RestaurantListing]{Items: listings, NextCursor: page.NextCursor}, nil
 }
 
-func (d *RestaurantDomain) GetRestaurantByIdDomain(ctx context.Context, restaurantId int32) (*generated.Restaurant, error) {
+func (d *RestaurantDomain) GetRestaurantByIdDomain(ctx context.Context, restaurantId int32) (*RestaurantDetail, error) {
 	if restaurantId <= 0 {
 		return nil, errors.New("invalid restaurant id")
 	}
@@ -48,28 +118,57 @@ func (d *RestaurantDomain) GetRestaurantByIdDomain(ctx context.Context, restaura
 		return nil, errors.New("restaurant not found")
 	}
 
-	restaurant := &generated.Restaurant{
-		ID:       row.ID,
-		Name:     row.Name,
-		Rating:   row.Rating,
-		Category: row.Category,
-		Address:  row.Address,
-		ZipCode:  row.ZipCode,
+	images, err := d.loadRestaurantImages(ctx, []int32{restaurantId})
+	if err != nil {
+		return nil, err
+	}
+	locations, err := d.loadLocations(ctx, []int32{restaurantId})
+	if err != nil {
+		return nil, err
+	}
+
+	restaurant := &RestaurantDetail{
+		Restaurant: generated.Restaurant{
+			ID:             row.ID,
+			Name:           row.Name,
+			Rating:         row.Rating,
+			Category:       row.Category,
+			Address:        row.Address,
+			ZipCode:        row.ZipCode,
+			ReviewCount:    row.ReviewCount,
+			OrderingPaused: row.OrderingPaused,
+		},
+		Images:   images[restaurantId],
+		Location: locations[restaurantId],
 	}
 
 	return restaurant, nil
 }
 
-func (d *RestaurantDomain) GetMenuItemsByRestaurantIdDomain(ctx context.Context, restaurantId int32) ([]generated.Menuitem, error) {
+func (d *RestaurantDomain) GetMenuItemsByRestaurantIdDomain(ctx context.Context, restaurantId int32, filter MenuFilter) ([]MenuItemDetail, error) {
 	if restaurantId <= 0 {
 		return nil, errors.New("invalid restaurant id")
 	}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
 
 	menuitems, err := d.repo.FetchMenuItemsByRestaurantId(ctx, restaurantId)
 	if err != nil {
 		return nil, errors.New("failed to fetch menuitems")
 	}
-	return menuitems, nil
+	details, err := d.withDetails(ctx, menuitems)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []MenuItemDetail
+	for _, menuItem := range details {
+		if filter.matches(menuItem) {
+			matching = append(matching, menuItem)
+		}
+	}
+	return matching, nil
 }
 
 func (d *RestaurantDomain) GetMenuItemByRestaurantAndIdDomain(ctx context.Context, params generated.GetMenuItemByRestaurantAndIdParams) (*generated.Menuitem, error) {
@@ -89,28 +188,15 @@ func (d *RestaurantDomain) GetMenuItemByRestaurantAndIdDomain(ctx context.Contex
 	return menuitem, nil
 }
 
-func (d *RestaurantDomain) GetAllCategoriesDomain(ctx context.Context) ([]string, error) {
-	rows, err := d.repo.FetchAllCategories(ctx)
-	if err != nil {
-		return nil, errors.New("failed to fetch categories")
-	}
-
-	var categories []string
-	for _, row := range rows {
-		if row != nil {
-			categories = append(categories, *row)
-		}
-	}
-
-	return categories, nil
-}
-
-func (d *RestaurantDomain) FilterRestaurantsByCategoryDomain(ctx context.Context, category string) ([]generated.Restaurant, error) {
-	if len(category) == 0 {
+// FilterRestaurantsByCategoryDomain lists the restaurants in a category of the
+// taxonomy, by its slug
+func (d *RestaurantDomain) FilterRestaurantsByCategoryDomain(ctx context.Context, category string) ([]RestaurantListing, error) {
+	slug := Slugify(category)
+	if len(slug) == 0 {
 		return nil, errors.New("category cannot be empty")
 	}
 
-	rows, err := d.repo.FilterRestaurantsByCategory(ctx, &category)
+	rows, err := d.repo.FilterRestaurantsByCategory(ctx, slug)
 	if err != nil {
 		return nil, errors.New("failed to filter restaurants by category")
 	}
@@ -119,13 +205,22 @@ func (d *RestaurantDomain) FilterRestaurantsByCategoryDomain(ctx context.Context
 	var restaurants []generated.Restaurant
 	for _, row := range rows {
 		restaurants = append(restaurants, generated.Restaurant{
-			ID:       row.ID,
-			Name:     row.Name,
-			Rating:   row.Rating,
-			Category: row.Category,
-			Address:  row.Address,
-			ZipCode:  row.ZipCode,
+			ID:             row.ID,
+			Name:           row.Name,
+			Rating:         row.Rating,
+			Category:       row.Category,
+			Address:        row.Address,
+			ZipCode:        row.ZipCode,
+			ReviewCount:    row.ReviewCount,
+			OrderingPaused: row.OrderingPaused,
 		})
 	}
-	return restaurants, nil
+	listings, err := d.withOpeningStatus(ctx, restaurants)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.withLocations(ctx, listings, nil); err != nil {
+		return nil, err
+	}
+	return listings, nil
 }
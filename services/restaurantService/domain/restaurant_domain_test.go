@@ -16,7 +16,7 @@ func SetupTestMocks(t *testing.T) (pgxmock.PgxPoolIface, *generated.Queries, *Re
 	}
 
 	queries := generated.New(mock)
-	domain := NewRestaurantDomain(queries)
+	domain := NewRestaurantDomain(queries, mock, nil, nil)
 
 	return mock, queries, domain
 }
@@ -25,6 +25,67 @@ func CloseMocks(mock pgxmock.PgxPoolIface) {
 	mock.Close()
 }
 
+// expectNoOpeningHours expects the opening hours and kitchen load of listed
+// restaurants to be looked up, finding none, so they are listed as closed
+func expectNoOpeningHours(mock pgxmock.PgxPoolIface, restaurantIds ...int32) {
+	mock.ExpectQuery(`FROM opening_hours\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurant_id", "weekday", "opens_at", "closes_at"}))
+	mock.ExpectQuery(`FROM opening_exception\s+WHERE restaurant_id = ANY\(\$1::int\[\]\) AND date >= \$2`).
+		WithArgs(restaurantIds, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurant_id", "date", "opens_at", "closes_at", "note"}))
+	expectKitchen(mock, pgxmock.NewRows(kitchenCapacityColumns), pgxmock.NewRows(kitchenLoadColumns), restaurantIds...)
+}
+
+var (
+	zipCodeColumns  = []string{"zip_code", "city", "latitude", "longitude"}
+	locationColumns = []string{"restaurant_id", "latitude", "longitude"}
+)
+
+// expectLocations expects the locations of the restaurants to be looked up
+func expectLocations(mock pgxmock.PgxPoolIface, rows *pgxmock.Rows, restaurantIds ...int32) {
+	mock.ExpectQuery(`FROM restaurant_location\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(rows)
+}
+
+var (
+	kitchenCapacityColumns = []string{"restaurant_id", "max_open_orders", "max_items_per_slot", "prep_minutes", "busy_extra_minutes", "busy_mode"}
+	kitchenLoadColumns     = []string{"restaurant_id", "open_orders", "slot_items"}
+)
+
+// expectKitchen expects the kitchen capacity and load of the restaurants to be
+// looked up
+func expectKitchen(mock pgxmock.PgxPoolIface, capacity, load *pgxmock.Rows, restaurantIds ...int32) {
+	mock.ExpectQuery(`FROM kitchen_capacity\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(capacity)
+	mock.ExpectQuery(`FROM kitchen_order\s+WHERE restaurant_id = ANY\(\$2::int\[\]\)`).
+		WithArgs(pgxmock.AnyArg(), restaurantIds).
+		WillReturnRows(load)
+}
+
+// expectNoMenuDetails expects the options and dietary info of listed menu
+// items to be looked up, finding no option groups, combo components, dietary
+// info or availability
+func expectNoMenuDetails(mock pgxmock.PgxPoolIface, menuItemIds ...int32) {
+	mock.ExpectQuery(`FROM option_group\s+WHERE menu_item_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows(optionGroupColumns))
+	mock.ExpectQuery(`FROM menu_option o\s+JOIN option_group g`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows(menuOptionColumns))
+	mock.ExpectQuery(`FROM combo_component c`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows(comboComponentColumns))
+	mock.ExpectQuery(`FROM menu_item_dietary\s+WHERE menu_item_id = ANY`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows(dietaryColumns))
+	mock.ExpectQuery(`FROM menu_item_availability\s+WHERE menu_item_id = ANY`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows(availabilityColumns))
+}
+
 // Helper functions to create pointers for literals
 func int32Ptr(i int32) *int32 {
 	return &i
@@ -36,26 +97,33 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+// noRestaurantFilter are the query arguments listing the first page of all
+// restaurants
+var noRestaurantFilter = []any{int32(21)}
+
 func TestGetAllRestaurantsDomain(t *testing.T) {
 	mock, _, domain := SetupTestMocks(t)
 	defer CloseMocks(mock)
 
 	t.Run("Valid Restaurant Data", func(t *testing.T) {
 		// Arrange mock data
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800)).
-			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Sushi"), stringPtr("Second Street 456"), int32Ptr(2900))
-		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Sushi"), stringPtr("Second Street 456"), int32Ptr(2900), int32(12), false, nil)
+		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code,\s+r\.review_count,\s+r\.ordering_paused,\s+r\.deleted_at\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+			WithArgs(noRestaurantFilter...).
 			WillReturnRows(rows)
+		expectNoOpeningHours(mock, 1, 2)
+		expectLocations(mock, pgxmock.NewRows(locationColumns), 1, 2)
 
 		// Act
-		got, err := domain.GetAllRestaurantsDomain(context.Background())
+		got, err := domain.GetAllRestaurantsDomain(context.Background(), RestaurantFilter{}, ListParams{})
 
 		// Assert
-		want := []generated.Restaurant{
-			{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800)},
-			{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Sushi"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900)},
-		}
+		want := &Page[RestaurantListing]{Items: []RestaurantListing{
+			{Restaurant: generated.Restaurant{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800), ReviewCount: 12}, OpeningStatus: OpeningStatus{State: StateClosed}, KitchenStatus: KitchenStatus{PrepMinutes: defaultPrepMinutes}},
+			{Restaurant: generated.Restaurant{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Sushi"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900), ReviewCount: 12}, OpeningStatus: OpeningStatus{State: StateClosed}, KitchenStatus: KitchenStatus{PrepMinutes: defaultPrepMinutes}},
+		}}
 
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -69,20 +137,21 @@ func TestGetAllRestaurantsDomain(t *testing.T) {
 	})
 
 	t.Run("No Restaurants", func(t *testing.T) {
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"})
-		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"})
+		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code,\s+r\.review_count,\s+r\.ordering_paused,\s+r\.deleted_at\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+			WithArgs(noRestaurantFilter...).
 			WillReturnRows(rows)
 
 		// Act
-		got, err := domain.GetAllRestaurantsDomain(context.Background())
+		got, err := domain.GetAllRestaurantsDomain(context.Background(), RestaurantFilter{}, ListParams{})
 
 		// Assert
-		want := []generated.Restaurant{}
+		want := &Page[RestaurantListing]{Items: []RestaurantListing{}}
 
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
-		if (got == nil && len(want) != 0) || (got != nil && !reflect.DeepEqual(got, want)) {
+		if !reflect.DeepEqual(got, want) {
 			t.Errorf("got %+v, want %+v", got, want)
 		}
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -91,11 +160,12 @@ func TestGetAllRestaurantsDomain(t *testing.T) {
 	})
 
 	t.Run("Database Error", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code,\s+r\.review_count,\s+r\.ordering_paused,\s+r\.deleted_at\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+			WithArgs(noRestaurantFilter...).
 			WillReturnError(context.DeadlineExceeded)
 
 		// Act
-		got, err := domain.GetAllRestaurantsDomain(context.Background())
+		got, err := domain.GetAllRestaurantsDomain(context.Background(), RestaurantFilter{}, ListParams{})
 
 		if err == nil {
 			t.Fatalf("expected an error but got nil")
@@ -115,24 +185,26 @@ func TestGetRestaurantByIdDomain(t *testing.T) {
 
 	t.Run("Valid ID", func(t *testing.T) {
 		// Arrange
-		row := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800))
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE id = \$1`).
+		row := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil)
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at FROM restaurant WHERE id = \$1`).
 			WithArgs(int32(1)).
 			WillReturnRows(row)
+		expectLocations(mock, pgxmock.NewRows(locationColumns).AddRow(int32(1), 55.7704, 12.5038), 1)
 
 		// Act
 		got, err := domain.GetRestaurantByIdDomain(context.Background(), int32(1))
 
 		// Assert
-		want := &generated.Restaurant{
-			ID:       1,
-			Name:     "Pizza Paradise",
-			Rating:   float64Ptr(4.5),
-			Category: stringPtr("Pizza"),
-			Address:  stringPtr("Main Street 123"),
-			ZipCode:  int32Ptr(2800),
-		}
+		want := &RestaurantDetail{Restaurant: generated.Restaurant{
+			ID:          1,
+			Name:        "Pizza Paradise",
+			Rating:      float64Ptr(4.5),
+			Category:    stringPtr("Pizza"),
+			Address:     stringPtr("Main Street 123"),
+			ZipCode:     int32Ptr(2800),
+			ReviewCount: 12,
+		}, Location: &Location{Latitude: 55.7704, Longitude: 12.5038}}
 
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -156,7 +228,7 @@ func TestGetRestaurantByIdDomain(t *testing.T) {
 	})
 
 	t.Run("Non-Existent ID", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at FROM restaurant WHERE id = \$1`).
 			WithArgs(int32(999)).
 			WillReturnError(context.DeadlineExceeded)
 
@@ -180,20 +252,21 @@ func TestGetMenuItemsByRestaurantIdDomain(t *testing.T) {
 
 	t.Run("Valid Restaurant ID", func(t *testing.T) {
 		// Arrange
-		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description"}).
-			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza")).
-			AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"))
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1`).
+		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil).
+			AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"), nil)
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1`).
 			WithArgs(int32(1)).
 			WillReturnRows(rows)
+		expectNoMenuDetails(mock, 1, 2)
 
 		// Act
-		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(1))
+		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(1), MenuFilter{})
 
 		// Assert
-		want := []generated.Menuitem{
-			{ID: 1, Restaurantid: 1, Name: "Cheese Pizza", Price: 12.5, Description: stringPtr("Delicious cheese pizza")},
-			{ID: 2, Restaurantid: 1, Name: "Veggie Pizza", Price: 10.0, Description: stringPtr("Healthy veggie pizza")},
+		want := []MenuItemDetail{
+			{Menuitem: generated.Menuitem{ID: 1, Restaurantid: 1, Name: "Cheese Pizza", Price: 12.5, Description: stringPtr("Delicious cheese pizza")}, OptionGroups: []OptionGroup{}, Allergens: []string{}, DietaryTags: []string{}, Availability: Availability{Available: true}},
+			{Menuitem: generated.Menuitem{ID: 2, Restaurantid: 1, Name: "Veggie Pizza", Price: 10.0, Description: stringPtr("Healthy veggie pizza")}, OptionGroups: []OptionGroup{}, Allergens: []string{}, DietaryTags: []string{}, Availability: Availability{Available: true}},
 		}
 
 		if err != nil {
@@ -208,12 +281,12 @@ func TestGetMenuItemsByRestaurantIdDomain(t *testing.T) {
 	})
 
 	t.Run("No MenuItems", func(t *testing.T) {
-		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description"})
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1`).
+		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"})
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1`).
 			WithArgs(int32(999)).
 			WillReturnRows(rows)
 
-		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(999))
+		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(999), MenuFilter{})
 
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -227,11 +300,11 @@ func TestGetMenuItemsByRestaurantIdDomain(t *testing.T) {
 	})
 
 	t.Run("Database Error", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1`).
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1`).
 			WithArgs(int32(1)).
 			WillReturnError(context.DeadlineExceeded)
 
-		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(1))
+		got, err := domain.GetMenuItemsByRestaurantIdDomain(context.Background(), int32(1), MenuFilter{})
 
 		if err == nil {
 			t.Fatalf("expected an error, got nil")
@@ -251,9 +324,9 @@ func TestGetMenuItemsByRestaurantAndIdDomain(t *testing.T) {
 
 	t.Run("Valid Restaurant ID and Menu Item ID", func(t *testing.T) {
 		// Arrange
-		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description"}).
-			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"))
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1 AND id = \$2`).
+		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil)
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1 AND id = \$2`).
 			WithArgs(int32(1), int32(1)).
 			WillReturnRows(rows)
 
@@ -285,7 +358,7 @@ func TestGetMenuItemsByRestaurantAndIdDomain(t *testing.T) {
 	})
 
 	t.Run("MenuItem Not Found", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1 AND id = \$2`).
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1 AND id = \$2`).
 			WithArgs(int32(1), int32(99)).
 			WillReturnError(context.DeadlineExceeded)
 
@@ -313,17 +386,20 @@ func TestGetAllCategoriesDomain(t *testing.T) {
 
 	t.Run("Valid Categories Data", func(t *testing.T) {
 		// Arrange mock data
-		rows := pgxmock.NewRows([]string{"category"}).
-			AddRow(stringPtr("Pizza")).
-			AddRow(stringPtr("Sushi"))
-		mock.ExpectQuery(`SELECT DISTINCT category FROM restaurant WHERE category IS NOT NULL ORDER BY category`).
+		rows := pgxmock.NewRows(categoryColumns).
+			AddRow(int32(1), "italian", "Italiensk", "Italian").
+			AddRow(int32(2), "pizza", "Pizza", "Pizza")
+		mock.ExpectQuery(`SELECT id, slug, name_da, name_en FROM category ORDER BY slug`).
 			WillReturnRows(rows)
 
 		// Act
 		got, err := domain.GetAllCategoriesDomain(context.Background())
 
 		// Assert
-		want := []string{"Pizza", "Sushi"}
+		want := []generated.Category{
+			{ID: 1, Slug: "italian", NameDa: "Italiensk", NameEn: "Italian"},
+			{ID: 2, Slug: "pizza", NameDa: "Pizza", NameEn: "Pizza"},
+		}
 
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
@@ -337,8 +413,8 @@ func TestGetAllCategoriesDomain(t *testing.T) {
 	})
 
 	t.Run("No Categories", func(t *testing.T) {
-		rows := pgxmock.NewRows([]string{"category"})
-		mock.ExpectQuery(`SELECT DISTINCT category FROM restaurant WHERE category IS NOT NULL ORDER BY category`).
+		rows := pgxmock.NewRows(categoryColumns)
+		mock.ExpectQuery(`SELECT id, slug, name_da, name_en FROM category ORDER BY slug`).
 			WillReturnRows(rows)
 
 		got, err := domain.GetAllCategoriesDomain(context.Background())
@@ -347,7 +423,7 @@ func TestGetAllCategoriesDomain(t *testing.T) {
 			t.Fatalf("unexpected error: %v", err)
 		}
 		if len(got) != 0 {
-			t.Errorf("expected no menuitems, got %+v", got)
+			t.Errorf("expected no categories, got %+v", got)
 		}
 	})
 }
@@ -358,20 +434,22 @@ func TestFilterRestaurantsByCategoryDomain(t *testing.T) {
 
 	t.Run("Valid Category", func(t *testing.T) {
 		// Arrange
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800)).
-			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Pizza"), stringPtr("Second Street 456"), int32Ptr(2900))
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE category ILIKE \$1`).
-			WithArgs(stringPtr("Pizza")).
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Pizza"), stringPtr("Second Street 456"), int32Ptr(2900), int32(12), false, nil)
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_category rc ON rc.restaurant_id = r.id\s+JOIN category c ON c.id = rc.category_id\s+WHERE c.slug = \$1`).
+			WithArgs("pizza").
 			WillReturnRows(rows)
+		expectNoOpeningHours(mock, 1, 2)
+		expectLocations(mock, pgxmock.NewRows(locationColumns), 1, 2)
 
 		// Act
 		got, err := domain.FilterRestaurantsByCategoryDomain(context.Background(), "Pizza")
 
 		// Assert
-		want := []generated.Restaurant{
-			{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800)},
-			{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Pizza"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900)},
+		want := []RestaurantListing{
+			{Restaurant: generated.Restaurant{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800), ReviewCount: 12}, OpeningStatus: OpeningStatus{State: StateClosed}, KitchenStatus: KitchenStatus{PrepMinutes: defaultPrepMinutes}},
+			{Restaurant: generated.Restaurant{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Pizza"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900), ReviewCount: 12}, OpeningStatus: OpeningStatus{State: StateClosed}, KitchenStatus: KitchenStatus{PrepMinutes: defaultPrepMinutes}},
 		}
 
 		if err != nil {
@@ -386,9 +464,9 @@ func TestFilterRestaurantsByCategoryDomain(t *testing.T) {
 	})
 
 	t.Run("No Restaurants in Category", func(t *testing.T) {
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"})
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE category ILIKE \$1`).
-			WithArgs(stringPtr("NonExistent")).
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"})
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_category rc ON rc.restaurant_id = r.id\s+JOIN category c ON c.id = rc.category_id\s+WHERE c.slug = \$1`).
+			WithArgs("nonexistent").
 			WillReturnRows(rows)
 
 		got, err := domain.FilterRestaurantsByCategoryDomain(context.Background(), "NonExistent")
@@ -405,8 +483,8 @@ func TestFilterRestaurantsByCategoryDomain(t *testing.T) {
 	})
 
 	t.Run("Database Error", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE category ILIKE \$1`).
-			WithArgs(stringPtr("NonExistent")).
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_category rc ON rc.restaurant_id = r.id\s+JOIN category c ON c.id = rc.category_id\s+WHERE c.slug = \$1`).
+			WithArgs("nonexistent").
 			WillReturnError(context.DeadlineExceeded)
 
 		got, err := domain.FilterRestaurantsByCategoryDomain(context.Background(), "Pizza")
@@ -0,0 +1,367 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// RestaurantEventsExchange is the fanout exchange restaurant and menu changes are published on
+const RestaurantEventsExchange = "restaurant_events"
+
+// Actions of restaurant events
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// Management errors, distinct so handlers can map them to status codes
+var (
+	ErrRestaurantNotFound = errors.New("restaurant not found")
+	ErrMenuItemNotFound   = errors.New("menu item not found")
+	ErrNameRequired       = errors.New("name is required")
+	ErrNegativePrice      = errors.New("price must be zero or more")
+	ErrUnknownZipCode     = errors.New("unknown zip code")
+)
+
+// EventPublisher publishes an event on an exchange, e.g. broker.PublishFanout
+type EventPublisher func(exchange string, event broker.Event) error
+
+// RestaurantParams creates or changes a restaurant. On update nil fields are left unchanged.
+type RestaurantParams struct {
+	Name *string `json:"name" example:"Pizza Paradise"`
+	// Category is the slug of the primary category, which must be in the taxonomy
+	Category *string `json:"category" example:"pizza"`
+	Address  *string `json:"address" example:"Lyngby Hovedgade 25"`
+	ZipCode  *int32  `json:"zip_code" example:"2800"`
+	// Latitude and Longitude place the restaurant on the map. Without them it
+	// is placed at the centre of its zip code, also when the zip code changes.
+	Latitude  *float64 `json:"latitude" example:"55.7704"`
+	Longitude *float64 `json:"longitude" example:"12.5038"`
+	// OrderingPaused stops the restaurant from taking orders, whatever its opening hours
+	OrderingPaused *bool `json:"ordering_paused" example:"false"`
+}
+
+// MenuItemParams creates or changes a menu item. On update nil fields are left
+// unchanged, while empty allergens or dietary tags clear them.
+type MenuItemParams struct {
+	Name        *string    `json:"name" example:"Pepperoni Pizza"`
+	Price       *float64   `json:"price" example:"12.99"`
+	Description *string    `json:"description" example:"Classic pepperoni pizza with mozzarella cheese."`
+	Allergens   []string   `json:"allergens" example:"gluten,milk"`
+	DietaryTags []string   `json:"dietary_tags" example:"vegetarian"`
+	Nutrition   *Nutrition `json:"nutrition"`
+}
+
+// RestaurantEvent is the payload of menu.updated, restaurant.updated and
+// category.updated events. Category events carry no restaurant.
+type RestaurantEvent struct {
+	RestaurantID int32  `json:"restaurant_id"`
+	MenuItemID   *int32 `json:"menu_item_id,omitempty"`
+	CategoryID   *int32 `json:"category_id,omitempty"`
+	Action       string `json:"action"`
+}
+
+// publishRestaurantEvent tells other services about the change. Failures are
+// logged, as the change has already been saved.
+func (d *RestaurantDomain) publishRestaurantEvent(eventType string, payload RestaurantEvent) {
+	if d.publish == nil {
+		return
+	}
+	err := d.publish(RestaurantEventsExchange, broker.Event{Type: eventType, Payload: payload})
+	if err != nil {
+		log.Printf("Failed to publish %s for restaurant %d: %v", eventType, payload.RestaurantID, err)
+	}
+}
+
+func validName(name *string) bool {
+	return name != nil && strings.TrimSpace(*name) != ""
+}
+
+func (d *RestaurantDomain) checkZipCode(ctx context.Context, zipCode *int32) (*generated.Zipcode, error) {
+	if zipCode == nil {
+		return nil, ErrUnknownZipCode
+	}
+	zip, err := d.repo.GetZipCode(ctx, *zipCode)
+	if err != nil {
+		return nil, ErrUnknownZipCode
+	}
+	return &zip, nil
+}
+
+func categorySlug(category *generated.Category) *string {
+	if category == nil {
+		return nil
+	}
+	return &category.Slug
+}
+
+// addCategory links the restaurant to its primary category, keeping its other
+// categories
+func addCategory(ctx context.Context, q *generated.Queries, restaurantId int32, category *generated.Category) error {
+	if category == nil {
+		return nil
+	}
+	return q.AddRestaurantCategory(ctx, generated.AddRestaurantCategoryParams{RestaurantID: restaurantId, CategoryID: category.ID})
+}
+
+func (d *RestaurantDomain) CreateRestaurantDomain(ctx context.Context, params RestaurantParams) (*RestaurantDetail, error) {
+	if !validName(params.Name) {
+		return nil, ErrNameRequired
+	}
+	zip, err := d.checkZipCode(ctx, params.ZipCode)
+	if err != nil {
+		return nil, err
+	}
+	location, err := coordinates(params.Latitude, params.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	if location == nil {
+		location = zipCodeCentre(zip)
+	}
+	category, err := d.primaryCategory(ctx, params.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	// The rating is aggregated from feedback, so new restaurants start without one
+	var restaurantId int32
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		restaurantId, err = q.CreateRestaurant(ctx, generated.CreateRestaurantParams{
+			Name:     strings.TrimSpace(*params.Name),
+			Category: categorySlug(category),
+			Address:  params.Address,
+			ZipCode:  params.ZipCode,
+		})
+		if err != nil {
+			return err
+		}
+		if location != nil {
+			if err := saveLocation(ctx, q, restaurantId, location); err != nil {
+				return err
+			}
+		}
+		return addCategory(ctx, q, restaurantId, category)
+	})
+	if err != nil {
+		return nil, errors.New("failed to create restaurant: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionCreated})
+
+	return d.GetRestaurantByIdDomain(ctx, restaurantId)
+}
+
+func (d *RestaurantDomain) UpdateRestaurantDomain(ctx context.Context, restaurantId int32, params RestaurantParams) (*generated.Restaurant, error) {
+	current, err := d.repo.GetRestaurantById(ctx, restaurantId)
+	if err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+
+	update := generated.UpdateRestaurantParams{
+		ID:             restaurantId,
+		Name:           current.Name,
+		Category:       current.Category,
+		Address:        current.Address,
+		ZipCode:        current.ZipCode,
+		OrderingPaused: current.OrderingPaused,
+	}
+	if params.Name != nil {
+		if !validName(params.Name) {
+			return nil, ErrNameRequired
+		}
+		update.Name = strings.TrimSpace(*params.Name)
+	}
+	var category *generated.Category
+	if params.Category != nil {
+		if category, err = d.primaryCategory(ctx, params.Category); err != nil {
+			return nil, err
+		}
+		update.Category = categorySlug(category)
+	}
+	if params.Address != nil {
+		update.Address = params.Address
+	}
+	location, err := coordinates(params.Latitude, params.Longitude)
+	if err != nil {
+		return nil, err
+	}
+	relocate := location != nil
+	if params.ZipCode != nil {
+		zip, err := d.checkZipCode(ctx, params.ZipCode)
+		if err != nil {
+			return nil, err
+		}
+		if location == nil && (current.ZipCode == nil || *current.ZipCode != zip.ZipCode) {
+			location, relocate = zipCodeCentre(zip), true
+		}
+		update.ZipCode = params.ZipCode
+	}
+	if params.OrderingPaused != nil {
+		update.OrderingPaused = *params.OrderingPaused
+	}
+
+	var restaurant generated.Restaurant
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		if restaurant, err = q.UpdateRestaurant(ctx, update); err != nil {
+			return err
+		}
+		if relocate {
+			if err := saveLocation(ctx, q, restaurantId, location); err != nil {
+				return err
+			}
+		}
+		return addCategory(ctx, q, restaurantId, category)
+	})
+	if err != nil {
+		return nil, errors.New("failed to update restaurant: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionUpdated})
+
+	return &restaurant, nil
+}
+
+// DeleteRestaurantDomain soft-deletes the restaurant, hiding it and its menu
+func (d *RestaurantDomain) DeleteRestaurantDomain(ctx context.Context, restaurantId int32) error {
+	deleted, err := d.repo.SoftDeleteRestaurant(ctx, restaurantId)
+	if err != nil {
+		return errors.New("failed to delete restaurant: " + err.Error())
+	}
+	if deleted == 0 {
+		return ErrRestaurantNotFound
+	}
+
+	d.publishRestaurantEvent(broker.RestaurantUpdated, RestaurantEvent{RestaurantID: restaurantId, Action: ActionDeleted})
+
+	return nil
+}
+
+func (d *RestaurantDomain) CreateMenuItemDomain(ctx context.Context, restaurantId int32, params MenuItemParams) (*MenuItemDetail, error) {
+	if _, err := d.repo.GetRestaurantById(ctx, restaurantId); err != nil {
+		return nil, ErrRestaurantNotFound
+	}
+	if !validName(params.Name) {
+		return nil, ErrNameRequired
+	}
+	if params.Price == nil || *params.Price < 0 {
+		return nil, ErrNegativePrice
+	}
+	if err := validateDietary(&params); err != nil {
+		return nil, err
+	}
+
+	var menuItemId int32
+	err := inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		menuItemId, err = q.CreateMenuItem(ctx, generated.CreateMenuItemParams{
+			Restaurantid: restaurantId,
+			Name:         strings.TrimSpace(*params.Name),
+			Price:        *params.Price,
+			Description:  params.Description,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := setPrice(ctx, q, menuItemId, *params.Price, priceTime(time.Now())); err != nil {
+			return err
+		}
+		return saveDietary(ctx, q, menuItemId, params)
+	})
+	if err != nil {
+		return nil, errors.New("failed to create menu item: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionCreated})
+
+	return d.GetMenuItemDetailDomain(ctx, restaurantId, menuItemId)
+}
+
+func (d *RestaurantDomain) UpdateMenuItemDomain(ctx context.Context, restaurantId int32, menuItemId int32, params MenuItemParams) (*MenuItemDetail, error) {
+	current, err := d.repo.GetMenuItemByRestaurantAndId(ctx, generated.GetMenuItemByRestaurantAndIdParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	})
+	if err != nil {
+		return nil, ErrMenuItemNotFound
+	}
+
+	update := generated.UpdateMenuItemParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+		Name:         current.Name,
+		Price:        current.Price,
+		Description:  current.Description,
+	}
+	if params.Name != nil {
+		if !validName(params.Name) {
+			return nil, ErrNameRequired
+		}
+		update.Name = strings.TrimSpace(*params.Name)
+	}
+	if params.Price != nil {
+		if *params.Price < 0 {
+			return nil, ErrNegativePrice
+		}
+		update.Price = *params.Price
+	}
+	if params.Description != nil {
+		update.Description = params.Description
+	}
+	if err := validateDietary(&params); err != nil {
+		return nil, err
+	}
+
+	var menuItem generated.Menuitem
+	err = inTx(ctx, d.db, d.repo, func(q *generated.Queries) error {
+		var err error
+		if menuItem, err = q.UpdateMenuItem(ctx, update); err != nil {
+			return err
+		}
+		if update.Price != current.Price {
+			if _, err := setPrice(ctx, q, menuItemId, update.Price, priceTime(time.Now())); err != nil {
+				return err
+			}
+		}
+		return saveDietary(ctx, q, menuItemId, params)
+	})
+	if err != nil {
+		return nil, errors.New("failed to update menu item: " + err.Error())
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionUpdated})
+
+	details, err := d.withDetails(ctx, []generated.Menuitem{menuItem})
+	if err != nil {
+		return nil, err
+	}
+	return &details[0], nil
+}
+
+// DeleteMenuItemDomain soft-deletes the menu item. Past orders keep referring
+// to it, while it can no longer be selected.
+func (d *RestaurantDomain) DeleteMenuItemDomain(ctx context.Context, restaurantId int32, menuItemId int32) error {
+	deleted, err := d.repo.SoftDeleteMenuItem(ctx, generated.SoftDeleteMenuItemParams{
+		Restaurantid: restaurantId,
+		ID:           menuItemId,
+	})
+	if err != nil {
+		return errors.New("failed to delete menu item: " + err.Error())
+	}
+	if deleted == 0 {
+		return ErrMenuItemNotFound
+	}
+
+	d.publishRestaurantEvent(broker.MenuUpdated, RestaurantEvent{RestaurantID: restaurantId, MenuItemID: &menuItemId, Action: ActionDeleted})
+
+	return nil
+}
@@ -0,0 +1,217 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/broker"
+)
+
+func TestCreateRestaurantDomain(t *testing.T) {
+	t.Run("Creates the restaurant and publishes an event", func(t *testing.T) {
+		// Arrange
+		mock, queries, _ := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		var published []broker.Event
+		domain := NewRestaurantDomain(queries, mock, func(exchange string, event broker.Event) error {
+			published = append(published, event)
+			return nil
+		}, nil)
+		mock.ExpectQuery(`SELECT zip_code, city, latitude, longitude\s+FROM zipcode`).
+			WithArgs(int32(2800)).
+			WillReturnRows(pgxmock.NewRows(zipCodeColumns).AddRow(int32(2800), "Kongens Lyngby", float64Ptr(55.7704), float64Ptr(12.5038)))
+		mock.ExpectQuery(`FROM category\s+WHERE slug = ANY\(\$1::text\[\]\)`).
+			WithArgs([]string{"pizza"}).
+			WillReturnRows(pgxmock.NewRows(categoryColumns).AddRow(int32(6), "pizza", "Pizza", "Pizza"))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO restaurant`).
+			WithArgs("Pizza Paradise", (*float64)(nil), stringPtr("pizza"), (*string)(nil), int32Ptr(2800)).
+			WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(int32(3)))
+		mock.ExpectExec(`INSERT INTO restaurant_location`).
+			WithArgs(int32(3), 55.7704, 12.5038).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectExec(`INSERT INTO restaurant_category`).
+			WithArgs(int32(3), int32(6)).
+			WillReturnResult(pgxmock.NewResult("INSERT", 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at\s+FROM restaurant`).
+			WithArgs(int32(3)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+				AddRow(int32(3), "Pizza Paradise", nil, stringPtr("pizza"), nil, int32Ptr(2800), int32(0), false, nil))
+		expectLocations(mock, pgxmock.NewRows(locationColumns).AddRow(int32(3), 55.7704, 12.5038), 3)
+
+		// Act
+		restaurant, err := domain.CreateRestaurantDomain(context.Background(), RestaurantParams{
+			Name:     stringPtr(" Pizza Paradise "),
+			Category: stringPtr("Pizza"),
+			ZipCode:  int32Ptr(2800),
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if restaurant.ID != 3 || restaurant.Rating != nil || restaurant.Location == nil {
+			t.Errorf("unexpected restaurant: %+v", restaurant)
+		}
+		if len(published) != 1 || published[0].Type != broker.RestaurantUpdated {
+			t.Errorf("expected one %s event, got %+v", broker.RestaurantUpdated, published)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		params  RestaurantParams
+		zipRows bool
+		wantErr error
+	}{
+		{"Missing name", RestaurantParams{ZipCode: int32Ptr(2800)}, false, ErrNameRequired},
+		{"Blank name", RestaurantParams{Name: stringPtr("  "), ZipCode: int32Ptr(2800)}, false, ErrNameRequired},
+		{"Missing zip code", RestaurantParams{Name: stringPtr("Pizza Paradise")}, false, ErrUnknownZipCode},
+		{"Unknown zip code", RestaurantParams{Name: stringPtr("Pizza Paradise"), ZipCode: int32Ptr(9999)}, true, ErrUnknownZipCode},
+		{"Unknown category", RestaurantParams{Name: stringPtr("Pizza Paradise"), ZipCode: int32Ptr(2800), Category: stringPtr("Pizza ")}, true, ErrUnknownCategory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			if tt.zipRows {
+				zipRows := pgxmock.NewRows(zipCodeColumns)
+				if tt.params.Category != nil {
+					zipRows.AddRow(int32(2800), "Kongens Lyngby", nil, nil)
+				}
+				mock.ExpectQuery(`SELECT zip_code, city, latitude, longitude\s+FROM zipcode`).
+					WithArgs(*tt.params.ZipCode).
+					WillReturnRows(zipRows)
+			}
+			if tt.params.Category != nil {
+				mock.ExpectQuery(`FROM category\s+WHERE slug = ANY\(\$1::text\[\]\)`).
+					WithArgs([]string{"pizza"}).
+					WillReturnRows(pgxmock.NewRows(categoryColumns))
+			}
+
+			// Act
+			_, err := domain.CreateRestaurantDomain(context.Background(), tt.params)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestUpdateMenuItemDomain(t *testing.T) {
+	menuItemRow := func() *pgxmock.Rows {
+		return pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil)
+	}
+
+	t.Run("Only changes the given fields", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnRows(menuItemRow())
+		mock.ExpectBegin()
+		mock.ExpectQuery(`UPDATE menuitem`).
+			WithArgs(int32(1), int32(1), "Cheese Pizza", float64(9.5), stringPtr("Delicious cheese pizza")).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+				AddRow(int32(1), int32(1), "Cheese Pizza", float64(9.5), stringPtr("Delicious cheese pizza"), nil))
+		expectFirstPrice(mock, 1, 9.5)
+		mock.ExpectCommit()
+		expectNoMenuDetails(mock, 1)
+
+		// Act
+		menuItem, err := domain.UpdateMenuItemDomain(context.Background(), 1, 1, MenuItemParams{Price: float64Ptr(9.5)})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if menuItem.Price != 9.5 {
+			t.Errorf("got price %v, want 9.5", menuItem.Price)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Rejects a negative price", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnRows(menuItemRow())
+
+		// Act
+		_, err := domain.UpdateMenuItemDomain(context.Background(), 1, 1, MenuItemParams{Price: float64Ptr(-1)})
+
+		// Assert
+		if !errors.Is(err, ErrNegativePrice) {
+			t.Errorf("got error %v, want %v", err, ErrNegativePrice)
+		}
+	})
+
+	t.Run("Deleted or unknown menu item", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(99)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}))
+
+		// Act
+		_, err := domain.UpdateMenuItemDomain(context.Background(), 1, 99, MenuItemParams{Name: stringPtr("Veggie Pizza")})
+
+		// Assert
+		if !errors.Is(err, ErrMenuItemNotFound) {
+			t.Errorf("got error %v, want %v", err, ErrMenuItemNotFound)
+		}
+	})
+}
+
+func TestDeleteMenuItemDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted int64
+		wantErr error
+	}{
+		{"Soft-deletes the menu item", 1, nil},
+		{"Unknown menu item", 0, ErrMenuItemNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, _, domain := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			mock.ExpectExec(`UPDATE menuitem\s+SET deleted_at = NOW\(\)`).
+				WithArgs(int32(1), int32(2)).
+				WillReturnResult(pgxmock.NewResult("UPDATE", tt.deleted))
+
+			// Act
+			err := domain.DeleteMenuItemDomain(context.Background(), 1, 2)
+
+			// Assert
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet mock expectations: %v", err)
+			}
+		})
+	}
+}
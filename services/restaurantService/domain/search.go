@@ -0,0 +1,159 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strings"
+
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// Kinds of search hits
+const (
+	SearchKindRestaurant = "restaurant"
+	SearchKindMenuItem   = "menu_item"
+)
+
+const (
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = 50
+	// maxSearchHits caps the ranked hits fetched, before the open now filter
+	// and paging apply
+	maxSearchHits = 500
+)
+
+var ErrEmptySearchQuery = errors.New("search query is required")
+
+// SearchParams are the query and filters of a search. Pages count from 1.
+// The allergen and dietary filters apply to menu items, like in MenuFilter.
+type SearchParams struct {
+	Query            string
+	Category         *string
+	ZipCode          *int32
+	MinRating        *float64
+	OpenNow          bool
+	ExcludeAllergens []string
+	DietaryTags      []string
+	Page             int
+	PageSize         int
+}
+
+// SearchHit is a matching restaurant or menu item. The snippet is HTML
+// escaped, with the matched words wrapped in <mark> tags.
+type SearchHit struct {
+	Kind           string   `json:"kind" example:"menu_item"`
+	RestaurantID   int32    `json:"restaurant_id" example:"1"`
+	RestaurantName string   `json:"restaurant_name" example:"Pizza Paradise"`
+	MenuItemID     *int32   `json:"menu_item_id,omitempty" example:"2"`
+	Name           string   `json:"name" example:"Pepperoni Pizza"`
+	Price          *float64 `json:"price,omitempty" example:"12.99"`
+	Category       *string  `json:"category" example:"Pizza"`
+	Rating         *float64 `json:"rating" example:"4.5"`
+	Snippet        string   `json:"snippet" example:"<mark>Pepperoni</mark> Pizza - Classic pepperoni pizza"`
+	Rank           float64  `json:"rank" example:"0.86"`
+	IsOpen         bool     `json:"is_open"`
+}
+
+// SearchResult is a page of search hits. Total counts the hits on all pages.
+type SearchResult struct {
+	Query    string      `json:"query"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"page_size"`
+	Total    int         `json:"total"`
+	Hits     []SearchHit `json:"hits"`
+}
+
+// highlight escapes a snippet from ts_headline, keeping its <mark> tags
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, "&lt;mark&gt;", "<mark>")
+	return strings.ReplaceAll(escaped, "&lt;/mark&gt;", "</mark>")
+}
+
+// SearchDomain ranks restaurants and menu items by how well their names,
+// descriptions and categories match the query
+func (d *RestaurantDomain) SearchDomain(ctx context.Context, params SearchParams) (*SearchResult, error) {
+	query := strings.TrimSpace(params.Query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	filter := MenuFilter{ExcludeAllergens: params.ExcludeAllergens, DietaryTags: params.DietaryTags}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = defaultSearchPageSize
+	}
+	if params.PageSize > maxSearchPageSize {
+		params.PageSize = maxSearchPageSize
+	}
+
+	rows, err := d.repo.Search(ctx, generated.SearchParams{
+		Query:            query,
+		ExcludeAllergens: filter.ExcludeAllergens,
+		DietaryTags:      filter.DietaryTags,
+		Category:         slugFilter(params.Category),
+		ZipCode:          params.ZipCode,
+		MinRating:        params.MinRating,
+		MaxHits:          maxSearchHits,
+	})
+	if err != nil {
+		return nil, errors.New("failed to search: " + err.Error())
+	}
+
+	result := &SearchResult{Query: query, Page: params.Page, PageSize: params.PageSize, Hits: []SearchHit{}}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	var restaurants []generated.Restaurant
+	seen := map[int32]bool{}
+	for _, row := range rows {
+		if !seen[row.RestaurantID] {
+			seen[row.RestaurantID] = true
+			restaurants = append(restaurants, generated.Restaurant{ID: row.RestaurantID, OrderingPaused: row.OrderingPaused})
+		}
+	}
+	listings, err := d.withOpeningStatus(ctx, restaurants)
+	if err != nil {
+		return nil, err
+	}
+	open := map[int32]bool{}
+	for _, listing := range listings {
+		open[listing.ID] = listing.IsOpen
+	}
+
+	var hits []SearchHit
+	for _, row := range rows {
+		isOpen := open[row.RestaurantID]
+		if params.OpenNow && !isOpen {
+			continue
+		}
+		hits = append(hits, SearchHit{
+			Kind:           row.Kind,
+			RestaurantID:   row.RestaurantID,
+			RestaurantName: row.RestaurantName,
+			MenuItemID:     row.MenuItemID,
+			Name:           row.Name,
+			Price:          row.Price,
+			Category:       row.Category,
+			Rating:         row.Rating,
+			Snippet:        highlight(row.Snippet),
+			Rank:           row.Rank,
+			IsOpen:         isOpen,
+		})
+	}
+
+	result.Total = len(hits)
+	start := (params.Page - 1) * params.PageSize
+	if start < len(hits) {
+		end := min(start+params.PageSize, len(hits))
+		result.Hits = hits[start:end]
+	}
+
+	return result, nil
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+var searchColumns = []string{
+	"kind", "restaurant_id", "menu_item_id", "name", "price", "snippet", "rank",
+	"restaurant_name", "category", "rating", "ordering_paused",
+}
+
+func expectSearch(mock pgxmock.PgxPoolIface) {
+	mock.ExpectQuery(`websearch_to_tsquery\('danish', \$1::text\)`).
+		WithArgs("pizza", ([]string)(nil), ([]string)(nil), (*string)(nil), (*int32)(nil), float64Ptr(4), int32(maxSearchHits)).
+		WillReturnRows(pgxmock.NewRows(searchColumns).
+			AddRow(SearchKindRestaurant, int32(1), nil, "Pizza Paradise", nil, "<mark>Pizza</mark> Paradise - Pizza", 0.9,
+				"Pizza Paradise", stringPtr("Pizza"), float64Ptr(4.5), false).
+			AddRow(SearchKindMenuItem, int32(1), int32Ptr(1), "Cheese Pizza", float64Ptr(12.5), "Cheese <mark>Pizza</mark> - <b>Delicious</b>", 0.8,
+				"Pizza Paradise", stringPtr("Pizza"), float64Ptr(4.5), false).
+			AddRow(SearchKindMenuItem, int32(2), int32Ptr(7), "Sushi Pizza", float64Ptr(15), "Sushi <mark>Pizza</mark>", 0.5,
+				"Sushi World", stringPtr("Sushi"), float64Ptr(4.8), false))
+	expectNoOpeningHours(mock, 1, 2)
+}
+
+func TestSearchDomain(t *testing.T) {
+	t.Run("Pages the ranked hits", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectSearch(mock)
+
+		// Act
+		result, err := domain.SearchDomain(context.Background(), SearchParams{Query: " pizza ", MinRating: float64Ptr(4), Page: 2, PageSize: 2})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Total != 3 || len(result.Hits) != 1 {
+			t.Fatalf("got %d of %d hits, want 1 of 3", len(result.Hits), result.Total)
+		}
+		if hit := result.Hits[0]; hit.RestaurantName != "Sushi World" || *hit.MenuItemID != 7 || hit.IsOpen {
+			t.Errorf("unexpected hit: %+v", hit)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Open now leaves out closed restaurants", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectSearch(mock)
+
+		// Act
+		result, err := domain.SearchDomain(context.Background(), SearchParams{Query: "pizza", MinRating: float64Ptr(4), OpenNow: true})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Total != 0 || len(result.Hits) != 0 || result.PageSize != defaultSearchPageSize {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	t.Run("Filters menu items by allergens and dietary tags", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`d\.allergens && \$2::text\[\]`).
+			WithArgs("pizza", []string{"milk", "nuts"}, []string{"vegan"}, (*string)(nil), (*int32)(nil), (*float64)(nil), int32(maxSearchHits)).
+			WillReturnRows(pgxmock.NewRows(searchColumns))
+
+		// Act
+		result, err := domain.SearchDomain(context.Background(), SearchParams{
+			Query:            "pizza",
+			ExcludeAllergens: []string{"Nuts", "milk"},
+			DietaryTags:      []string{"vegan"},
+		})
+
+		// Assert
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Total != 0 {
+			t.Errorf("got %d hits, want none", result.Total)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Requires a query", func(t *testing.T) {
+		// Arrange
+		mock, _, domain := SetupTestMocks(t)
+		defer CloseMocks(mock)
+
+		// Act
+		_, err := domain.SearchDomain(context.Background(), SearchParams{Query: "  "})
+
+		// Assert
+		if !errors.Is(err, ErrEmptySearchQuery) {
+			t.Errorf("got error %v, want %v", err, ErrEmptySearchQuery)
+		}
+	})
+}
+
+func TestHighlight(t *testing.T) {
+	got := highlight("Cheese <mark>Pizza</mark> - <b>Delicious</b> & hot")
+	want := "Cheese <mark>Pizza</mark> - &lt;b&gt;Delicious&lt;/b&gt; &amp; hot"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
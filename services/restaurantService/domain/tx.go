@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rasm445f/soft-exam-2/db/generated"
+)
+
+// TxBeginner starts database transactions, e.g. *pgxpool.Pool, which reserves
+// a connection for each transaction
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// DB runs the hand-written listing queries and starts transactions, e.g.
+// *pgxpool.Pool
+type DB interface {
+	generated.DBTX
+	TxBeginner
+}
+
+// inTx runs fn with queries bound to a transaction, which is committed when
+// fn succeeds and rolled back otherwise
+func inTx(ctx context.Context, db TxBeginner, repo *generated.Queries, fn func(q *generated.Queries) error) error {
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction is committed
+	defer tx.Rollback(ctx)
+
+	if err := fn(repo.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -3,6 +3,7 @@ module github.com/rasm445f/soft-exam-2
 go 1.23.1
 
 require (
+	github.com/go-redis/redis/v8 v8.11.5
 	github.com/jackc/pgx/v5 v5.7.1
 	github.com/pashagolub/pgxmock/v4 v4.3.0
 	github.com/prometheus/client_golang v1.20.5
@@ -14,6 +15,8 @@ require (
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
 	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
@@ -36,7 +39,9 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/rabbitmq/amqp091-go v1.10.0 // indirect
+	github.com/rasm445f/soft-exam-2/auth v0.0.0
 	github.com/rasm445f/soft-exam-2/broker v0.0.0
+	github.com/rasm445f/soft-exam-2/listing v0.0.0
 	github.com/swaggo/files/v2 v2.0.0 // indirect
 	golang.org/x/crypto v0.27.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
@@ -45,3 +50,7 @@ require (
 )
 
 replace github.com/rasm445f/soft-exam-2/broker => ../../broker
+
+replace github.com/rasm445f/soft-exam-2/auth => ../../auth
+
+replace github.com/rasm445f/soft-exam-2/listing => ../../listing
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/cache"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// Cache tags of the cached responses. Every response about a restaurant
+// carries its tag, the restaurant details carry restaurantsTag too, as
+// renaming a category renames their primary category.
+const (
+	categoriesTag  = "categories"
+	restaurantsTag = "restaurants"
+)
+
+func restaurantTag(restaurantId int32) string {
+	return fmt.Sprintf("restaurant:%d", restaurantId)
+}
+
+// cacheKey identifies a response by its path and query, with the query
+// parameters sorted
+func cacheKey(r *http.Request) string {
+	if query := r.URL.Query().Encode(); query != "" {
+		return r.URL.Path + "?" + query
+	}
+	return r.URL.Path
+}
+
+// writeCached writes a cached JSON response with its validators, or 304 Not
+// Modified when the client's copy is still current
+func writeCached(w http.ResponseWriter, r *http.Request, entry cache.Entry) {
+	w.Header().Set("ETag", entry.ETag)
+	w.Header().Set("Last-Modified", entry.LastModified.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if notModified(r, entry) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(entry.Body)
+}
+
+// notModified checks the conditional headers of the request. If-None-Match
+// takes precedence over If-Modified-Since, as in RFC 9110.
+func notModified(r *http.Request, entry cache.Entry) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, etag := range strings.Split(match, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == "*" || etag == entry.ETag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !entry.LastModified.After(since)
+}
+
+// ConsumeRestaurantEvents invalidates the cached responses about changed
+// restaurants, menus and categories. Every replica has its own queue, so each
+// one clears its in-process cache.
+func (h *RestaurantHandler) ConsumeRestaurantEvents() {
+	broker.ConsumeFanout(domain.RestaurantEventsExchange, "", func(event broker.Event) {
+		payloadBytes, err := json.Marshal(event.Payload)
+		if err != nil {
+			log.Printf("Failed to marshal event payload: %v", err)
+			return
+		}
+
+		var payload domain.RestaurantEvent
+		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+			log.Printf("Failed to unmarshal restaurant event: %v", err)
+			return
+		}
+
+		h.invalidate(context.Background(), event.Type, payload)
+	})
+}
+
+func (h *RestaurantHandler) invalidate(ctx context.Context, eventType string, payload domain.RestaurantEvent) {
+	switch eventType {
+	case broker.MenuUpdated, broker.RestaurantUpdated:
+		h.cache.Invalidate(ctx, restaurantTag(payload.RestaurantID))
+	case broker.CategoryUpdated:
+		h.cache.Invalidate(ctx, categoriesTag, restaurantsTag)
+	default:
+		log.Printf("Ignored event of unexpected type: %v", eventType)
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/cache"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+func TestGetMenuItemsByRestaurantCached(t *testing.T) {
+	// Arrange
+	mock, handler := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	handler.cache = cache.New(nil, 10, time.Minute)
+
+	expectMenu := func() {
+		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil)
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1`).
+			WithArgs(int32(1)).
+			WillReturnRows(rows)
+		expectMenuDetails(mock, nil, nil, 1)
+	}
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/restaurants/1/menu-items", nil)
+		req.SetPathValue("restaurantId", "1")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.GetMenuItemsByRestaurant().ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act
+	expectMenu()
+	first := get("", "")
+	cached := get("", "")
+	revalidated := get("If-None-Match", first.Header().Get("ETag"))
+	modifiedSince := get("If-Modified-Since", first.Header().Get("Last-Modified"))
+	stale := get("If-None-Match", `"stale"`)
+	handler.invalidate(context.Background(), broker.MenuUpdated, domain.RestaurantEvent{RestaurantID: 1})
+	expectMenu()
+	refetched := get("", "")
+
+	// Assert
+	if first.Code != http.StatusOK || first.Header().Get("ETag") == "" || first.Header().Get("Last-Modified") == "" {
+		t.Fatalf("got status %d with headers %v, want 200 with validators", first.Code, first.Header())
+	}
+	if cached.Code != http.StatusOK || cached.Body.String() != first.Body.String() {
+		t.Errorf("got status %d with %s, want the cached %s", cached.Code, cached.Body, first.Body)
+	}
+	if revalidated.Code != http.StatusNotModified || revalidated.Body.Len() != 0 {
+		t.Errorf("got status %d for a matching ETag, want %d", revalidated.Code, http.StatusNotModified)
+	}
+	if modifiedSince.Code != http.StatusNotModified {
+		t.Errorf("got status %d for an unmodified menu, want %d", modifiedSince.Code, http.StatusNotModified)
+	}
+	if stale.Code != http.StatusOK {
+		t.Errorf("got status %d for a stale ETag, want %d", stale.Code, http.StatusOK)
+	}
+	if refetched.Code != http.StatusOK {
+		t.Errorf("got status %d after invalidation, want %d", refetched.Code, http.StatusOK)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
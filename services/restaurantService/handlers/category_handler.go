@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// CreateCategory godoc
+//
+// @Summary Create a category
+// @Description Adds a category to the taxonomy. The slug is derived from the English name when left out, and must be unique.
+// @Tags Category(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param category body domain.CategoryParams true "Category"
+// @Success 201 {object} generated.Category
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Category already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/categories [post]
+func (h *RestaurantHandler) CreateCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var params domain.CategoryParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		category, err := h.domain.CreateCategoryDomain(ctx, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(category)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// UpdateCategory godoc
+//
+// @Summary Update a category
+// @Description Changes the slug or display names of a category. Missing fields are left unchanged.
+// @Tags Category(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param categoryId path string true "Category ID"
+// @Param category body domain.CategoryParams true "Fields to change"
+// @Success 200 {object} generated.Category
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Category not found"
+// @Failure 409 {string} string "Category already exists"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/categories/{categoryId} [patch]
+func (h *RestaurantHandler) UpdateCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		categoryId, err := pathId(r, "categoryId")
+		if err != nil {
+			http.Error(w, "Invalid Category ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.CategoryParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		category, err := h.domain.UpdateCategoryDomain(ctx, categoryId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(category)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// DeleteCategory godoc
+//
+// @Summary Delete a category
+// @Description Removes a category from the taxonomy and from its restaurants
+// @Tags Category(Restaurant) CRUD
+// @Security BearerAuth
+// @Param categoryId path string true "Category ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Category not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/categories/{categoryId} [delete]
+func (h *RestaurantHandler) DeleteCategory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		categoryId, err := pathId(r, "categoryId")
+		if err != nil {
+			http.Error(w, "Invalid Category ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteCategoryDomain(ctx, categoryId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetRestaurantCategories godoc
+//
+// @Summary Get the categories of a restaurant
+// @Description Fetches the categories of a restaurant, its primary category first
+// @Tags Category(Restaurant) CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 200 {array} generated.Category
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/categories [get]
+func (h *RestaurantHandler) GetRestaurantCategories() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		categories, err := h.domain.GetRestaurantCategoriesDomain(ctx, restaurantId)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(categories)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetRestaurantCategories godoc
+//
+// @Summary Set the categories of a restaurant
+// @Description Replaces the categories of a restaurant by their slugs. The first one becomes the primary category, and an empty list clears them.
+// @Tags Category(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param categories body []string true "Category slugs"
+// @Success 200 {array} generated.Category
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/categories [put]
+func (h *RestaurantHandler) SetRestaurantCategories() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var slugs []string
+		if err := json.NewDecoder(r.Body).Decode(&slugs); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		categories, err := h.domain.SetRestaurantCategoriesDomain(ctx, restaurantId, slugs)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(categories)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// GetDeliveryZones godoc
+//
+// @Summary Get delivery zones
+// @Description Fetches the zip codes a restaurant delivers to, with the delivery fee and minimum order of each
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 200 {array} generated.DeliveryZone
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/delivery-zones [get]
+func (h *RestaurantHandler) GetDeliveryZones() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		zones, err := h.domain.GetDeliveryZonesDomain(ctx, restaurantId)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(zones)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// GetDeliveryZone godoc
+//
+// @Summary Get a delivery zone
+// @Description Fetches the delivery fee and minimum order of a restaurant for a zip code
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Param zipCode path string true "Zip code"
+// @Success 200 {object} generated.DeliveryZone
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant does not deliver to the zip code"
+// @Router /api/restaurants/{restaurantId}/delivery-zones/{zipCode} [get]
+func (h *RestaurantHandler) GetDeliveryZone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		zipCode, err := pathId(r, "zipCode")
+		if err != nil {
+			http.Error(w, "Invalid zip code", http.StatusBadRequest)
+			return
+		}
+
+		zone, err := h.domain.GetDeliveryZoneDomain(ctx, restaurantId, zipCode)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(zone)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetDeliveryZone godoc
+//
+// @Summary Set a delivery zone
+// @Description Adds a zip code to the delivery zones of a restaurant, or changes its delivery fee and minimum order
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param zipCode path string true "Zip code"
+// @Param zone body domain.DeliveryZoneParams true "Delivery terms"
+// @Success 200 {object} generated.DeliveryZone
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/delivery-zones/{zipCode} [put]
+func (h *RestaurantHandler) SetDeliveryZone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		zipCode, err := pathId(r, "zipCode")
+		if err != nil {
+			http.Error(w, "Invalid zip code", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.DeliveryZoneParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		zone, err := h.domain.SetDeliveryZoneDomain(ctx, restaurantId, zipCode, params)
+		if errors.Is(err, domain.ErrNegativeDeliveryFee) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(zone)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// DeleteDeliveryZone godoc
+//
+// @Summary Delete a delivery zone
+// @Description Stops a restaurant from delivering to a zip code
+// @Tags Restaurant CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param zipCode path string true "Zip code"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant does not deliver to the zip code"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/delivery-zones/{zipCode} [delete]
+func (h *RestaurantHandler) DeleteDeliveryZone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		zipCode, err := pathId(r, "zipCode")
+		if err != nil {
+			http.Error(w, "Invalid zip code", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteDeliveryZoneDomain(ctx, restaurantId, zipCode); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
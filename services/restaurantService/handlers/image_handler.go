@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/media"
+)
+
+// multipartOverhead allows for the form around an uploaded image
+const multipartOverhead = 64 << 10
+
+// readUpload reads the image from the "image" field of a multipart form. The
+// content type and size are checked by the domain, on the content itself.
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, media.MaxUploadSize+multipartOverhead)
+	file, _, err := r.FormFile("image")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, media.ErrTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// One byte more than allowed is enough to tell the image is too large
+	return io.ReadAll(io.LimitReader(file, media.MaxUploadSize+1))
+}
+
+func writeImage(w http.ResponseWriter, image any) {
+	res, _ := json.Marshal(image)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
+}
+
+// UploadRestaurantImage godoc
+//
+// @Summary Upload a restaurant image
+// @Description Sets the logo or cover image of a restaurant, replacing the one it had. JPEG, PNG and GIF images of at most 5 MB are accepted, and resized into a thumbnail.
+// @Tags Restaurant CRUD
+// @Accept multipart/form-data
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param kind path string true "logo or cover"
+// @Param image formData file true "Image"
+// @Success 201 {object} domain.ImageURLs
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 413 {string} string "Image too large"
+// @Failure 415 {string} string "Unsupported image type"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/images/{kind} [put]
+func (h *RestaurantHandler) UploadRestaurantImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		data, err := readUpload(w, r)
+		if errors.Is(err, media.ErrTooLarge) {
+			writeManagementError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Expected an image in the image field of a multipart form", http.StatusBadRequest)
+			return
+		}
+
+		image, err := h.domain.UploadRestaurantImageDomain(ctx, restaurantId, r.PathValue("kind"), data)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		writeImage(w, image)
+	}
+}
+
+// DeleteRestaurantImage godoc
+//
+// @Summary Delete a restaurant image
+// @Description Removes the logo or cover image of a restaurant
+// @Tags Restaurant CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param kind path string true "logo or cover"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Image not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/images/{kind} [delete]
+func (h *RestaurantHandler) DeleteRestaurantImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteRestaurantImageDomain(ctx, restaurantId, r.PathValue("kind")); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// UploadMenuItemImage godoc
+//
+// @Summary Upload a menu item photo
+// @Description Sets the photo of a menu item, replacing the one it had. JPEG, PNG and GIF images of at most 5 MB are accepted, and resized into a thumbnail.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept multipart/form-data
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param image formData file true "Image"
+// @Success 201 {object} domain.ImageURLs
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 413 {string} string "Image too large"
+// @Failure 415 {string} string "Unsupported image type"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/image [put]
+func (h *RestaurantHandler) UploadMenuItemImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+		data, err := readUpload(w, r)
+		if errors.Is(err, media.ErrTooLarge) {
+			writeManagementError(w, err)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Expected an image in the image field of a multipart form", http.StatusBadRequest)
+			return
+		}
+
+		image, err := h.domain.UploadMenuItemImageDomain(ctx, restaurantId, menuitemId, data)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		writeImage(w, image)
+	}
+}
+
+// DeleteMenuItemImage godoc
+//
+// @Summary Delete a menu item photo
+// @Description Removes the photo of a menu item
+// @Tags MenuItem(Restaurant) CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Image not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/image [delete]
+func (h *RestaurantHandler) DeleteMenuItemImage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteMenuItemImageDomain(ctx, restaurantId, menuitemId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// GetKitchenLoad godoc
+//
+// @Summary Get the kitchen load of a restaurant
+// @Description Fetches the open orders and the items of the current 15-minute slot against the capacity rules of the restaurant. A kitchen at capacity takes no new orders, and a busy kitchen takes longer to prepare them.
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 200 {object} domain.KitchenLoad
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/kitchen [get]
+func (h *RestaurantHandler) GetKitchenLoad() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		load, err := h.domain.GetKitchenLoadDomain(ctx, restaurantId)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(load)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetKitchenCapacity godoc
+//
+// @Summary Set the kitchen capacity of a restaurant
+// @Description Replaces the capacity rules of a restaurant. Once it has max_open_orders accepted orders in the kitchen, or max_items_per_slot items accepted in the current 15-minute slot, it takes no new orders. Missing limits are not enforced.
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param capacity body domain.CapacityParams true "Capacity rules"
+// @Success 200 {object} domain.KitchenLoad
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/kitchen/capacity [put]
+func (h *RestaurantHandler) SetKitchenCapacity() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.CapacityParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		load, err := h.domain.SetKitchenCapacityDomain(ctx, restaurantId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(load)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetBusyMode godoc
+//
+// @Summary Switch busy mode of a restaurant on or off
+// @Description A restaurant in busy mode keeps taking orders, but is listed as busy with its prep time lengthened by busy_extra_minutes.
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param busy body domain.BusyModeParams true "Busy mode"
+// @Success 200 {object} domain.KitchenLoad
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/kitchen/busy [put]
+func (h *RestaurantHandler) SetBusyMode() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.BusyModeParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		load, err := h.domain.SetBusyModeDomain(ctx, restaurantId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(load)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+	"github.com/rasm445f/soft-exam-2/media"
+)
+
+// writeManagementError maps domain errors to status codes
+func writeManagementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrRestaurantNotFound), errors.Is(err, domain.ErrMenuItemNotFound),
+		errors.Is(err, domain.ErrOpeningExceptionNotFound), errors.Is(err, domain.ErrNotDelivered),
+		errors.Is(err, domain.ErrCategoryNotFound), errors.Is(err, domain.ErrPriceVersionNotFound),
+		errors.Is(err, domain.ErrImageNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, domain.ErrNameRequired), errors.Is(err, domain.ErrNegativePrice), errors.Is(err, domain.ErrUnknownZipCode),
+		errors.Is(err, domain.ErrInvalidOpeningHours), errors.Is(err, domain.ErrInvalidOptionGroups), errors.Is(err, domain.ErrInvalidCombo),
+		errors.Is(err, domain.ErrInvalidDietaryInfo), errors.Is(err, domain.ErrInvalidAvailability), errors.Is(err, domain.ErrInvalidCapacity),
+		errors.Is(err, domain.ErrInvalidCategory), errors.Is(err, domain.ErrUnknownCategory), errors.Is(err, domain.ErrInvalidPriceChange),
+		errors.Is(err, domain.ErrInvalidImageKind), errors.Is(err, media.ErrInvalidImage), errors.Is(err, domain.ErrInvalidCoordinates):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, media.ErrTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, media.ErrUnsupportedType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, domain.ErrMediaUnavailable):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, domain.ErrCategoryExists), errors.Is(err, domain.ErrPriceInEffect):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		log.Println(err)
+	}
+}
+
+func pathId(r *http.Request, name string) (int32, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		return 0, err
+	}
+	return int32(id), nil
+}
+
+// CreateRestaurant godoc
+//
+// @Summary Create a restaurant
+// @Description Creates a restaurant. The zip code must be known.
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurant body domain.RestaurantParams true "Restaurant"
+// @Success 201 {object} domain.RestaurantDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants [post]
+func (h *RestaurantHandler) CreateRestaurant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		var params domain.RestaurantParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		restaurant, err := h.domain.CreateRestaurantDomain(ctx, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(restaurant)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// UpdateRestaurant godoc
+//
+// @Summary Update a restaurant
+// @Description Updates the given fields of a restaurant
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param restaurant body domain.RestaurantParams true "Fields to update"
+// @Success 200 {object} generated.Restaurant
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId} [patch]
+func (h *RestaurantHandler) UpdateRestaurant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.RestaurantParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		restaurant, err := h.domain.UpdateRestaurantDomain(ctx, restaurantId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(restaurant)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// DeleteRestaurant godoc
+//
+// @Summary Delete a restaurant
+// @Description Soft-deletes a restaurant, hiding it and its menu
+// @Tags Restaurant CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId} [delete]
+func (h *RestaurantHandler) DeleteRestaurant() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteRestaurantDomain(ctx, restaurantId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CreateMenuItem godoc
+//
+// @Summary Create a menu item
+// @Description Adds a menu item to a restaurant. The price must be zero or more. Allergens are any of the 14 EU allergens (celery, gluten, crustaceans, eggs, fish, lupin, milk, molluscs, mustard, nuts, peanuts, sesame, soya, sulphites), and dietary tags any of vegan, vegetarian, gluten-free, lactose-free, halal and kosher.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuItem body domain.MenuItemParams true "Menu item"
+// @Success 201 {object} domain.MenuItemDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items [post]
+func (h *RestaurantHandler) CreateMenuItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.MenuItemParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		menuItem, err := h.domain.CreateMenuItemDomain(ctx, restaurantId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(menuItem)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// UpdateMenuItem godoc
+//
+// @Summary Update a menu item
+// @Description Updates the given fields of a menu item. An empty list of allergens or dietary tags clears them.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param menuItem body domain.MenuItemParams true "Fields to update"
+// @Success 200 {object} domain.MenuItemDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId} [patch]
+func (h *RestaurantHandler) UpdateMenuItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.MenuItemParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		menuItem, err := h.domain.UpdateMenuItemDomain(ctx, restaurantId, menuitemId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(menuItem)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// DeleteMenuItem godoc
+//
+// @Summary Delete a menu item
+// @Description Soft-deletes a menu item so it can no longer be ordered
+// @Tags MenuItem(Restaurant) CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId} [delete]
+func (h *RestaurantHandler) DeleteMenuItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteMenuItemDomain(ctx, restaurantId, menuitemId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// maxMenuFileSize limits the size of imported menu files
+const maxMenuFileSize = 1 << 20
+
+// menuFormat takes the format from the format query parameter, falling back
+// to the content type and then to JSON
+func menuFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		return domain.FormatCSV
+	}
+	return domain.FormatJSON
+}
+
+// ImportMenu godoc
+//
+// @Summary Import a menu
+// @Description Compares a CSV or JSON menu with the current menu of the restaurant and returns the added, changed and removed items. Items are matched on id, or on name when no id is given. Nothing is saved unless dry_run=false, and then only when every row is valid.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Accept text/csv
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param format query string false "csv or json, defaults to the content type"
+// @Param dry_run query bool false "Only show the changes, defaults to true"
+// @Param menu body []domain.MenuRow true "Menu items"
+// @Success 200 {object} domain.MenuImportResult
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 422 {object} domain.MenuImportResult
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu/import [post]
+func (h *RestaurantHandler) ImportMenu() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		dryRun := r.URL.Query().Get("dry_run") != "false"
+		body := http.MaxBytesReader(w, r.Body, maxMenuFileSize)
+
+		result, err := h.domain.ImportMenuDomain(ctx, restaurantId, menuFormat(r), body, dryRun)
+		status := http.StatusOK
+		switch {
+		case err == nil:
+		case errors.Is(err, domain.ErrInvalidMenuRows):
+			status = http.StatusUnprocessableEntity
+		case errors.Is(err, domain.ErrUnsupportedFormat), errors.Is(err, domain.ErrInvalidMenuFile):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		default:
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(result)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(res)
+	}
+}
+
+// ExportMenu godoc
+//
+// @Summary Export a menu
+// @Description Exports the menu of a restaurant as CSV or JSON, in the format the import reads
+// @Tags MenuItem(Restaurant) CRUD
+// @Produce application/json
+// @Produce text/csv
+// @Param restaurantId path string true "Restaurant ID"
+// @Param format query string false "csv or json, defaults to json"
+// @Success 200 {array} domain.MenuRow
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu/export [get]
+func (h *RestaurantHandler) ExportMenu() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = domain.FormatJSON
+		}
+
+		// Export into a buffer first, so errors can still change the status
+		var buf bytes.Buffer
+		err = h.domain.ExportMenuDomain(ctx, restaurantId, format, &buf)
+		if errors.Is(err, domain.ErrUnsupportedFormat) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		if format == domain.FormatCSV {
+			w.Header().Set("Content-Type", "text/csv")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Header().Set("Content-Disposition", "attachment; filename=\"menu."+format+"\"")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// SetOptionGroups godoc
+//
+// @Summary Set the options of a menu item
+// @Description Replaces the option groups of a menu item, e.g. sizes or toppings. Single select groups take at most one option, multi select groups up to max_select. Option prices are added to the menu item price.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param groups body []domain.OptionGroupParams true "Option groups"
+// @Success 200 {object} domain.MenuItemDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/options [put]
+func (h *RestaurantHandler) SetOptionGroups() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var groups []domain.OptionGroupParams
+		if err := json.NewDecoder(r.Body).Decode(&groups); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		menuItem, err := h.domain.SetOptionGroupsDomain(ctx, restaurantId, menuitemId, groups)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(menuItem)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetComboComponents godoc
+//
+// @Summary Set the items of a combo
+// @Description Replaces the menu items a combo is made of. The combo keeps its own price, and an empty list makes it an ordinary menu item.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID of the combo"
+// @Param components body []domain.ComboComponentParams true "Menu items of the combo"
+// @Success 200 {object} domain.MenuItemDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/components [put]
+func (h *RestaurantHandler) SetComboComponents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var components []domain.ComboComponentParams
+		if err := json.NewDecoder(r.Body).Decode(&components); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		menuItem, err := h.domain.SetComboComponentsDomain(ctx, restaurantId, menuitemId, components)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(menuItem)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetAvailability godoc
+//
+// @Summary Set the availability of a menu item
+// @Description Switches a menu item on or off, limits the portions sold a day and marks it sold out until a given time. Items are available unless switched off, and without a daily stock there is no limit. Stock is taken when an order is accepted and given back when it is cancelled.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param availability body domain.AvailabilityParams true "Availability"
+// @Success 200 {object} domain.MenuItemDetail
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/availability [put]
+func (h *RestaurantHandler) SetAvailability() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var availability domain.AvailabilityParams
+		if err := json.NewDecoder(r.Body).Decode(&availability); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		menuItem, err := h.domain.SetAvailabilityDomain(ctx, restaurantId, menuitemId, availability)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(menuItem)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// StockReservation is the menu items of an order to take the stock of
+type StockReservation struct {
+	Items []domain.OrderedItem `json:"items"`
+}
+
+// ReserveStock godoc
+//
+// @Summary Reserve the stock of an order
+// @Description Counts the menu items of an order as sold today, before the order service accepts it. Stock is taken once per order, and given back when the order is rejected or cancelled. Only other services may reserve stock.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param orderId path string true "Order ID"
+// @Param reservation body StockReservation true "Ordered menu items"
+// @Success 204 "Stock reserved"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 409 {string} string "Not enough of a menu item left today"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/orders/{orderId}/stock [put]
+func (h *RestaurantHandler) ReserveStock() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		orderId, err := pathId(r, "orderId")
+		if err != nil {
+			http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+			return
+		}
+
+		var reservation StockReservation
+		if err := json.NewDecoder(r.Body).Decode(&reservation); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		err = h.domain.ReserveStockDomain(ctx, orderId, reservation.Items)
+		if errors.Is(err, domain.ErrMenuItemUnavailable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to reserve stock", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// origin reads the point given by the lat and lon query parameters, nil when
+// neither is given
+func (p *queryParser) origin() *domain.Location {
+	latitude, longitude := p.float("lat"), p.float("lon")
+	if p.err != nil || (latitude == nil && longitude == nil) {
+		return nil
+	}
+	if latitude == nil || longitude == nil {
+		p.err = errors.New("lat and lon must be given together")
+		return nil
+	}
+	return &domain.Location{Latitude: *latitude, Longitude: *longitude}
+}
+
+// GetNearbyRestaurants godoc
+//
+// @Summary Get restaurants near a point
+// @Description Lists the restaurants within the radius of a point, nearest first, with their distance to it. Restaurants are placed at the centre of their zip code unless their coordinates are set.
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param lat query number true "Latitude, e.g. 55.7704"
+// @Param lon query number true "Longitude, e.g. 12.5038"
+// @Param radius query number false "Radius in km, 5 by default and at most 50"
+// @Param limit query int false "Number of restaurants, 20 by default and at most 100"
+// @Success 200 {array} domain.RestaurantListing
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/nearby [get]
+func (h *RestaurantHandler) GetNearbyRestaurants() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		query := queryParser{r: r}
+		origin := query.origin()
+		params := domain.NearbyParams{RadiusKm: query.float("radius")}
+		query.value("limit", func(value string) error {
+			n, err := strconv.Atoi(value)
+			if err == nil && n < 1 {
+				err = errInvalidQuery
+			}
+			params.Limit = n
+			return err
+		})
+		if query.err == nil && origin == nil {
+			query.err = errors.New("lat and lon are required")
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+		params.Location = *origin
+
+		restaurants, err := h.domain.GetNearbyRestaurantsDomain(ctx, params)
+		if errors.Is(err, domain.ErrInvalidCoordinates) || errors.Is(err, domain.ErrInvalidRadius) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to get nearby restaurants", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(restaurants)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// GetRestaurantStatus godoc
+//
+// @Summary Get the opening status of a restaurant
+// @Description Tells whether the restaurant takes orders right now, and otherwise when it opens next. Opening hours are in Europe/Copenhagen time.
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 200 {object} domain.RestaurantStatus
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/status [get]
+func (h *RestaurantHandler) GetRestaurantStatus() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		status, err := h.domain.GetRestaurantStatusDomain(ctx, restaurantId)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(status)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// GetOpeningHours godoc
+//
+// @Summary Get opening hours
+// @Description Fetches the weekly opening hours and the upcoming exceptions of a restaurant
+// @Tags Restaurant CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Success 200 {object} domain.OpeningHours
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/opening-hours [get]
+func (h *RestaurantHandler) GetOpeningHours() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		openingHours, err := h.domain.GetOpeningHoursDomain(ctx, restaurantId)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(openingHours)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// SetOpeningHours godoc
+//
+// @Summary Set opening hours
+// @Description Replaces the weekly opening hours of a restaurant. Weekdays count from Sunday = 0, and an interval closing at or before its opening time ends the next day.
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param hours body []domain.OpeningInterval true "Weekly opening hours"
+// @Success 200 {object} domain.OpeningHours
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/opening-hours [put]
+func (h *RestaurantHandler) SetOpeningHours() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var intervals []domain.OpeningInterval
+		if err := json.NewDecoder(r.Body).Decode(&intervals); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		openingHours, err := h.domain.SetOpeningHoursDomain(ctx, restaurantId, intervals)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(openingHours)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// AddOpeningException godoc
+//
+// @Summary Add an opening exception
+// @Description Replaces the weekly opening hours on a date, e.g. a holiday. Without times the restaurant is closed all day.
+// @Tags Restaurant CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param exception body domain.OpeningExceptionParams true "Opening exception"
+// @Success 201 {object} domain.OpeningException
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/opening-exceptions [post]
+func (h *RestaurantHandler) AddOpeningException() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.OpeningExceptionParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		exception, err := h.domain.AddOpeningExceptionDomain(ctx, restaurantId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(exception)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// DeleteOpeningException godoc
+//
+// @Summary Delete an opening exception
+// @Description Removes an exception, so the weekly opening hours apply again on its date
+// @Tags Restaurant CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param exceptionId path string true "Exception ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Opening exception not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/opening-exceptions/{exceptionId} [delete]
+func (h *RestaurantHandler) DeleteOpeningException() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		exceptionId, err := pathId(r, "exceptionId")
+		if err != nil {
+			http.Error(w, "Invalid Exception ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.DeleteOpeningExceptionDomain(ctx, restaurantId, exceptionId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
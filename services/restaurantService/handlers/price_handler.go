@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// GetPriceHistory godoc
+//
+// @Summary Get the price history of a menu item
+// @Description Lists the price versions of a menu item, past, current and scheduled, oldest first. With a time only the version charged then is listed.
+// @Tags MenuItem(Restaurant) CRUD
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param at query string false "Only the version charged at this RFC 3339 time, e.g. 2025-01-01T12:00:00Z"
+// @Success 200 {array} generated.MenuItemPrice
+// @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices [get]
+func (h *RestaurantHandler) GetPriceHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var at *time.Time
+		if value := r.URL.Query().Get("at"); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				http.Error(w, "Invalid time, expected RFC 3339", http.StatusBadRequest)
+				return
+			}
+			at = &t
+		}
+
+		history, err := h.domain.GetPriceHistoryDomain(ctx, restaurantId, menuitemId, at)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(history)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+// ChangePrice godoc
+//
+// @Summary Change the price of a menu item
+// @Description Changes the price of a menu item right away, or from a future time on. Scheduled changes take effect automatically, and a change scheduled for the same time is replaced.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param price body domain.PriceChangeParams true "New price"
+// @Success 201 {object} generated.MenuItemPrice
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices [post]
+func (h *RestaurantHandler) ChangePrice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var params domain.PriceChangeParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		version, err := h.domain.ChangePriceDomain(ctx, restaurantId, menuitemId, params)
+		if err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		res, _ := json.Marshal(version)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(res)
+	}
+}
+
+// CancelPriceChange godoc
+//
+// @Summary Cancel a scheduled price change
+// @Description Removes a price change that has not taken effect yet. The price before it lasts in its place.
+// @Tags MenuItem(Restaurant) CRUD
+// @Security BearerAuth
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param priceId path string true "Price version ID"
+// @Success 204 "No Content"
+// @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Menu item or price version not found"
+// @Failure 409 {string} string "Price version already in effect"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices/{priceId} [delete]
+func (h *RestaurantHandler) CancelPriceChange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := pathId(r, "restaurantId")
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := pathId(r, "menuitemId")
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+		priceId, err := pathId(r, "priceId")
+		if err != nil {
+			http.Error(w, "Invalid Price ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.domain.CancelPriceChangeDomain(ctx, restaurantId, menuitemId, priceId); err != nil {
+			writeManagementError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
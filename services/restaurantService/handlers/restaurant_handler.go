@@ -2,30 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
-	"github.com/rasm445f/soft-exam-2/db/generated"
+	"github.com/rasm445f/soft-exam-2/cache"
 	"github.com/rasm445f/soft-exam-2/domain"
 )
 
 type RestaurantHandler struct {
 	domain *domain.RestaurantDomain
+	cache  *cache.Cache
 }
 
-func NewRestaurantHandler(domain *domain.RestaurantDomain) *RestaurantHandler {
-	return &RestaurantHandler{domain: domain}
+// NewRestaurantHandler creates the handler. Restaurant details, menus and
+// categories are served from the cache, which may be nil to disable caching.
+func NewRestaurantHandler(domain *domain.RestaurantDomain, cache *cache.Cache) *RestaurantHandler {
+	return &RestaurantHandler{domain: domain, cache: cache}
 }
 
 // GetAllRestaurants godoc
 //
 // @Summary Get all restaurants
-// @Description Fetches a list of all restaurants from the database
+// @Description Fetches a page of restaurants from the database. With a zip code only the restaurants delivering there are listed, with their delivery fee and minimum order.
 // @Tags Restaurant CRUD
 // @Produce application/json
-// @Success 200 {array} generated.Restaurant
+// @Param limit query int false "Page size, 20 by default and at most 100"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "id, name or rating, prefixed by - for descending order" default(id)
+// @Param category query string false "Only restaurants in the category, by its slug"
+// @Param min_rating query number false "Only restaurants rated at least this"
+// @Param zip query int false "Only restaurants delivering to this zip code"
+// @Param lat query number false "Latitude to add the distance from, with lon"
+// @Param lon query number false "Longitude to add the distance from, with lat"
+// @Success 200 {object} domain.Page[domain.RestaurantListing]
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/restaurants [get]
@@ -33,7 +47,24 @@ func (h *RestaurantHandler) GetAllRestaurants() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		restaurants, err := h.domain.GetAllRestaurantsDomain(ctx)
+		query := queryParser{r: r}
+		list := query.listParams()
+		filter := domain.RestaurantFilter{
+			Category:  query.string("category"),
+			MinRating: query.float("min_rating"),
+			ZipCode:   query.int32("zip"),
+			Origin:    query.origin(),
+		}
+		if query.err != nil {
+			http.Error(w, query.err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		restaurants, err := h.domain.GetAllRestaurantsDomain(ctx, filter, list)
+		if isListError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to get restaurants", http.StatusInternalServerError)
 			log.Println(err)
@@ -54,7 +85,9 @@ func (h *RestaurantHandler) GetAllRestaurants() http.HandlerFunc {
 // @Tags Restaurant CRUD
 // @Produce application/json
 // @Param id path string true "Restaurant ID"
-// @Success 200 {object} generated.Restaurant
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {object} domain.RestaurantDetail
+// @Success 304 "Not Modified"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/restaurants/{id} [get]
@@ -74,6 +107,12 @@ func (h *RestaurantHandler) GetRestaurantById() http.HandlerFunc {
 			return
 		}
 
+		key := cacheKey(r)
+		if entry, ok := h.cache.Get(ctx, key); ok {
+			writeCached(w, r, entry)
+			return
+		}
+
 		restaurant, err := h.domain.GetRestaurantByIdDomain(ctx, int32(restaurantId))
 		if err != nil {
 			http.Error(w, "Restaurant not found", http.StatusNotFound)
@@ -82,20 +121,22 @@ func (h *RestaurantHandler) GetRestaurantById() http.HandlerFunc {
 		}
 
 		res, _ := json.Marshal(restaurant)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(res)
+		writeCached(w, r, h.cache.Put(ctx, key, []string{restaurantTag(int32(restaurantId)), restaurantsTag}, res))
 	}
 }
 
 // GetMenuItemsByRestaurant godoc
 //
 // @Summary Get menu items by restaurant ID
-// @Description Fetches all menu items associated with a specific restaurant ID
+// @Description Fetches all menu items associated with a specific restaurant ID, with their allergens, dietary tags and nutrition
 // @Tags MenuItem(Restaurant) CRUD
 // @Produce application/json
 // @Param restaurantId path string true "Restaurant ID"
-// @Success 200 {array} generated.Menuitem
+// @Param exclude_allergens query string false "Comma separated allergens the items must not contain, e.g. nuts,milk"
+// @Param diet query string false "Comma separated dietary tags the items must carry, e.g. vegan,gluten-free"
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {array} domain.MenuItemDetail
+// @Success 304 "Not Modified"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/restaurants/{restaurantId}/menu-items [get]
@@ -115,17 +156,32 @@ func (h *RestaurantHandler) GetMenuItemsByRestaurant() http.HandlerFunc {
 			return
 		}
 
-		menuItems, err := h.domain.GetMenuItemsByRestaurantIdDomain(ctx, int32(restaurantId))
+		key := cacheKey(r)
+		if entry, ok := h.cache.Get(ctx, key); ok {
+			writeCached(w, r, entry)
+			return
+		}
+
+		filter := domain.MenuFilter{
+			ExcludeAllergens: commaList(r.URL.Query().Get("exclude_allergens")),
+			DietaryTags:      commaList(r.URL.Query().Get("diet")),
+		}
+		menuItems, err := h.domain.GetMenuItemsByRestaurantIdDomain(ctx, int32(restaurantId), filter)
+		if errors.Is(err, domain.ErrInvalidDietaryInfo) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to get menu items", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
 
+		// Availability changes at known times without an event, so the entry
+		// is not kept past the next change
 		res, _ := json.Marshal(menuItems)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(res)
+		until := domain.NextAvailabilityChange(menuItems, time.Now())
+		writeCached(w, r, h.cache.PutUntil(ctx, key, []string{restaurantTag(int32(restaurantId))}, res, until))
 	}
 }
 
@@ -137,8 +193,11 @@ func (h *RestaurantHandler) GetMenuItemsByRestaurant() http.HandlerFunc {
 // @Produce application/json
 // @Param restaurantId path string true "Restaurant ID"
 // @Param menuitemId path string true "Menu Item ID"
-// @Success 200 {object} generated.Menuitem
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {object} domain.MenuItemDetail
+// @Success 304 "Not Modified"
 // @Failure 400 {string} string "Bad request"
+// @Failure 404 {string} string "Menu item not found"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId} [get]
 func (h *RestaurantHandler) GetMenuItemByRestaurantAndId() http.HandlerFunc {
@@ -164,22 +223,26 @@ func (h *RestaurantHandler) GetMenuItemByRestaurantAndId() http.HandlerFunc {
 			return
 		}
 
-		params := generated.GetMenuItemByRestaurantAndIdParams{
-			Restaurantid: int32(restaurantId),
-			ID:           int32(menuitemId),
+		key := cacheKey(r)
+		if entry, ok := h.cache.Get(ctx, key); ok {
+			writeCached(w, r, entry)
+			return
 		}
 
-		menuItem, err := h.domain.GetMenuItemByRestaurantAndIdDomain(ctx, params)
-		if err != nil {
+		menuItem, err := h.domain.GetMenuItemDetailDomain(ctx, int32(restaurantId), int32(menuitemId))
+		if errors.Is(err, domain.ErrMenuItemNotFound) {
 			http.Error(w, "Menu Item not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to get menu item", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
 
 		res, _ := json.Marshal(menuItem)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(res)
+		until := domain.NextAvailabilityChange([]domain.MenuItemDetail{*menuItem}, time.Now())
+		writeCached(w, r, h.cache.PutUntil(ctx, key, []string{restaurantTag(int32(restaurantId))}, res, until))
 	}
 }
 
@@ -188,10 +251,12 @@ func (h *RestaurantHandler) GetMenuItemByRestaurantAndId() http.HandlerFunc {
 // GetAllCategories godoc
 //
 // @Summary Get all categories
-// @Description Fetches a list of all unique categories from the restaurant
+// @Description Fetches the category taxonomy, with Danish and English display names
 // @Tags Category(Restaurant) CRUD
 // @Produce application/json
-// @Success 200 {array} string
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Success 200 {array} generated.Category
+// @Success 304 "Not Modified"
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/categories [get]
@@ -199,6 +264,12 @@ func (h *RestaurantHandler) GetAllCategories() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
+		key := cacheKey(r)
+		if entry, ok := h.cache.Get(ctx, key); ok {
+			writeCached(w, r, entry)
+			return
+		}
+
 		categories, err := h.domain.GetAllCategoriesDomain(ctx)
 		if err != nil {
 			http.Error(w, "Get to fetch restaurants", http.StatusInternalServerError)
@@ -207,19 +278,17 @@ func (h *RestaurantHandler) GetAllCategories() http.HandlerFunc {
 		}
 
 		res, _ := json.Marshal(categories)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(res)
+		writeCached(w, r, h.cache.Put(ctx, key, []string{categoriesTag}, res))
 	}
 }
 
 // FilterRestaurantByCategory godoc
 //
 // @Summary Filter restaurants by category
-// @Description Fetches all restaurants for a given category
+// @Description Fetches all restaurants in a category, by its slug
 // @Tags Category(Restaurant) CRUD
 // @Produce application/json
-// @Param category path string true "Restaurant Category"
+// @Param category path string true "Category slug"
 // @Success 200 {array} generated.Restaurant
 // @Failure 400 {string} string "Bad request"
 // @Failure 500 {string} string "Internal server error"
@@ -253,26 +322,42 @@ type SelectItemParams struct {
 	RestaurantId int32 `json:"restaurantId" example:"10"`
 	ItemId       int32 `json:"id"`
 	Quantity     int   `json:"quantity" example:"2"`
+	// Options are the ids of the chosen options of the item
+	Options []int32 `json:"options" example:"3,7"`
 }
 
+// MenuItemSelection is the selected item as it is added to the shopping cart.
+// The price includes the chosen options, and the price version is the
+// version of the menu item price charged, recorded on the cart and order.
 type MenuItemSelection struct {
-	CustomerID   int32   `json:"customerId" example:"1"`
-	RestaurantId int32   `json:"restaurantId" example:"10"`
-	Name         string  `json:"name" example:"Cheese Burger"`
-	Price        float64 `json:"price" example:"10.00"`
-	Quantity     int     `json:"quantity" example:"2"`
+	CustomerID     int32                   `json:"customerId" example:"1"`
+	RestaurantId   int32                   `json:"restaurantId" example:"10"`
+	MenuItemID     int32                   `json:"menuItemId" example:"2"`
+	Name           string                  `json:"name" example:"Cheese Burger"`
+	Price          float64                 `json:"price" example:"10.00"`
+	Quantity       int                     `json:"quantity" example:"2"`
+	Options        []domain.SelectedOption `json:"options"`
+	Components     []domain.ComboComponent `json:"components,omitempty"`
+	Allergens      []string                `json:"allergens" example:"gluten,milk"`
+	DietaryTags    []string                `json:"dietaryTags" example:"vegetarian"`
+	PriceVersionID *int32                  `json:"priceVersionId,omitempty" example:"14"`
 }
 
 // SelectMenuItem godoc
 //
 // @Summary Selecting MenuItems
-// @Description Customer selects a MenuItem or more
+// @Description Customer selects a MenuItem or more, with options from its option groups. The item is priced with the chosen options. Customers only select items for their own cart.
 // @Tags Restaurant Broker
 // @Accept  application/json
 // @Produce application/json
+// @Security BearerAuth
 // @Param customer body SelectItemParams true "Menu item selection details"
 // @Success 201 {object} MenuItemSelection "Menu item successfully selected"
-// @Failure 400 {string} string "Bad request"
+// @Failure 400 {string} string "Bad request or options not matching the option groups"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
+// @Failure 404 {string} string "Restaurant or menu item not found"
+// @Failure 409 {string} string "Restaurant closed or menu item unavailable"
 // @Failure 500 {string} string "Internal server error"
 // @Router /api/restaurants/menu/select [post]
 func (h *RestaurantHandler) SelectMenuItem() http.HandlerFunc {
@@ -286,26 +371,57 @@ func (h *RestaurantHandler) SelectMenuItem() http.HandlerFunc {
 
 		ctx := r.Context()
 
-		// Get menuItem based on restaurantId and menuItemId
-		var menuSelectionParams = generated.GetMenuItemByRestaurantAndIdParams{
-			Restaurantid: selectionParams.RestaurantId,
-			ID:           selectionParams.ItemId,
+		// Customers only fill their own cart
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, selectionParams.CustomerId == identity.UserID) {
+			return
 		}
 
-		intermediateMenuItem, err := h.domain.GetMenuItemByRestaurantAndIdDomain(ctx, menuSelectionParams)
+		// Closed restaurants do not take orders
+		status, err := h.domain.GetRestaurantStatusDomain(ctx, selectionParams.RestaurantId)
 		if err != nil {
+			http.Error(w, "Restaurant not found", http.StatusNotFound)
+			log.Println(err)
+			return
+		}
+		if !status.IsOpen {
+			http.Error(w, closedMessage(status), http.StatusConflict)
+			return
+		}
+
+		// Check the availability and chosen options, and price the menu item with them
+		selection, err := h.domain.PriceSelectionDomain(ctx, selectionParams.RestaurantId, selectionParams.ItemId, selectionParams.Options, int32(selectionParams.Quantity))
+		if errors.Is(err, domain.ErrMenuItemNotFound) {
 			http.Error(w, "Menu Item not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSelection) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrMenuItemUnavailable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to select menu item", http.StatusInternalServerError)
 			log.Println(err)
 			return
 		}
 
 		// Create final menuItem to send to rabbitMQ
 		menuItemSelection := MenuItemSelection{
-			CustomerID:   selectionParams.CustomerId,
-			RestaurantId: intermediateMenuItem.Restaurantid,
-			Name:         intermediateMenuItem.Name,
-			Price:        intermediateMenuItem.Price,
-			Quantity:     selectionParams.Quantity,
+			CustomerID:     selectionParams.CustomerId,
+			RestaurantId:   selection.RestaurantID,
+			MenuItemID:     selection.MenuItemID,
+			Name:           selection.Name,
+			Price:          selection.UnitPrice,
+			Quantity:       selectionParams.Quantity,
+			Options:        selection.Options,
+			Components:     selection.Components,
+			Allergens:      selection.Allergens,
+			DietaryTags:    selection.DietaryTags,
+			PriceVersionID: selection.PriceVersionID,
 		}
 
 		// Publish event to RabbitMQ
@@ -324,3 +440,86 @@ func (h *RestaurantHandler) SelectMenuItem() http.HandlerFunc {
 		w.Write([]byte(`{"message": "Menu item selected successfully"}`))
 	}
 }
+
+// QuoteItemParams are the chosen options and quantity of a menu item to price
+type QuoteItemParams struct {
+	Quantity int `json:"quantity" example:"2"`
+	// Options are the ids of the chosen options of the item
+	Options []int32 `json:"options" example:"3,7"`
+}
+
+// QuoteMenuItem godoc
+//
+// @Summary Price a menu item
+// @Description Prices a menu item with the chosen options at its current price, as it is when selected. The shopping cart prices its items again with it at checkout. Nothing is published or reserved.
+// @Tags MenuItem(Restaurant) CRUD
+// @Accept application/json
+// @Produce application/json
+// @Param restaurantId path string true "Restaurant ID"
+// @Param menuitemId path string true "Menu Item ID"
+// @Param selection body QuoteItemParams true "Chosen options and quantity"
+// @Success 200 {object} domain.PricedSelection
+// @Failure 400 {string} string "Bad request or options not matching the option groups"
+// @Failure 404 {string} string "Menu item not found"
+// @Failure 409 {string} string "Menu item unavailable"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/restaurants/{restaurantId}/menu-items/{menuitemId}/quote [post]
+func (h *RestaurantHandler) QuoteMenuItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		restaurantId, err := strconv.Atoi(r.PathValue("restaurantId"))
+		if err != nil {
+			http.Error(w, "Invalid Restaurant ID", http.StatusBadRequest)
+			return
+		}
+		menuitemId, err := strconv.Atoi(r.PathValue("menuitemId"))
+		if err != nil {
+			http.Error(w, "Invalid Menu Item ID", http.StatusBadRequest)
+			return
+		}
+
+		var params QuoteItemParams
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil || params.Quantity <= 0 {
+			http.Error(w, "Invalid request payload", http.StatusBadRequest)
+			return
+		}
+
+		selection, err := h.domain.PriceSelectionDomain(ctx, int32(restaurantId), int32(menuitemId), params.Options, int32(params.Quantity))
+		if errors.Is(err, domain.ErrMenuItemNotFound) {
+			http.Error(w, "Menu Item not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidSelection) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, domain.ErrMenuItemUnavailable) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to price menu item", http.StatusInternalServerError)
+			log.Println(err)
+			return
+		}
+
+		res, _ := json.Marshal(selection)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
+
+func closedMessage(status *domain.RestaurantStatus) string {
+	switch status.State {
+	case domain.StatePaused:
+		return "Restaurant is not taking orders right now"
+	case domain.StateAtCapacity:
+		return "Restaurant is too busy to take more orders right now"
+	}
+	if status.NextOpening != nil {
+		return "Restaurant is closed until " + status.NextOpening.Format(time.RFC3339)
+	}
+	return "Restaurant is closed"
+}
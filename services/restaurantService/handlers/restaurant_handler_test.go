@@ -9,14 +9,17 @@ import (
 	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	// "github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	"github.com/rasm445f/soft-exam-2/domain"
 
 	// "github.com/stretchr/testify/assert"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pashagolub/pgxmock/v4"
 )
 
@@ -27,8 +30,8 @@ func SetupTestMocks(t *testing.T) (pgxmock.PgxPoolIface, *RestaurantHandler) {
 	}
 
 	queries := generated.New(mock)
-	restaurantDomain := domain.NewRestaurantDomain(queries)
-	handler := NewRestaurantHandler(restaurantDomain)
+	restaurantDomain := domain.NewRestaurantDomain(queries, mock, nil, nil)
+	handler := NewRestaurantHandler(restaurantDomain, nil)
 
 	return mock, handler
 }
@@ -37,6 +40,60 @@ func CloseMocks(mock pgxmock.PgxPoolIface) {
 	mock.Close()
 }
 
+// expectOpeningHours expects the opening hours and kitchen load of the
+// restaurants to be looked up. Open restaurants are open around the clock, the
+// others have no hours, and kitchens have no capacity limits.
+func expectOpeningHours(mock pgxmock.PgxPoolIface, open bool, restaurantIds ...int32) {
+	hours := pgxmock.NewRows([]string{"id", "restaurant_id", "weekday", "opens_at", "closes_at"})
+	if open {
+		for _, restaurantId := range restaurantIds {
+			for weekday := int16(0); weekday < 7; weekday++ {
+				midnight := pgtype.Time{Microseconds: 0, Valid: true}
+				hours.AddRow(int32(weekday+1), restaurantId, weekday, midnight, midnight)
+			}
+		}
+	}
+	mock.ExpectQuery(`FROM opening_hours\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(hours)
+	mock.ExpectQuery(`FROM opening_exception\s+WHERE restaurant_id = ANY\(\$1::int\[\]\) AND date >= \$2`).
+		WithArgs(restaurantIds, pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurant_id", "date", "opens_at", "closes_at", "note"}))
+	expectKitchen(mock, pgxmock.NewRows(kitchenCapacityColumns), pgxmock.NewRows(kitchenLoadColumns), restaurantIds...)
+}
+
+var (
+	kitchenCapacityColumns = []string{"restaurant_id", "max_open_orders", "max_items_per_slot", "prep_minutes", "busy_extra_minutes", "busy_mode"}
+	kitchenLoadColumns     = []string{"restaurant_id", "open_orders", "slot_items"}
+)
+
+// expectKitchen expects the kitchen capacity and load of the restaurants to be
+// looked up
+func expectKitchen(mock pgxmock.PgxPoolIface, capacity, load *pgxmock.Rows, restaurantIds ...int32) {
+	mock.ExpectQuery(`FROM kitchen_capacity\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(capacity)
+	mock.ExpectQuery(`FROM kitchen_order\s+WHERE restaurant_id = ANY\(\$2::int\[\]\)`).
+		WithArgs(pgxmock.AnyArg(), restaurantIds).
+		WillReturnRows(load)
+}
+
+// expectLocations expects the locations of the restaurants to be looked up
+func expectLocations(mock pgxmock.PgxPoolIface, rows *pgxmock.Rows, restaurantIds ...int32) {
+	mock.ExpectQuery(`FROM restaurant_location\s+WHERE restaurant_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(restaurantIds).
+		WillReturnRows(rows)
+}
+
+var locationColumns = []string{"restaurant_id", "latitude", "longitude"}
+
+func expectRestaurant(mock pgxmock.PgxPoolIface, restaurantId int32, orderingPaused bool) {
+	mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at\s+FROM restaurant\s+WHERE id = \$1`).
+		WithArgs(restaurantId).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(restaurantId, "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), orderingPaused, nil))
+}
+
 // Helper functions to create pointers for literals
 func int32Ptr(i int32) *int32 {
 	return &i
@@ -54,12 +111,15 @@ func TestGetAllRestaurantsHandler(t *testing.T) {
 
 	t.Run("Valid Data", func(t *testing.T) {
 		// Arrange mock data
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800)).
-			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Sushi"), stringPtr("Second Street 456"), int32Ptr(2900))
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Sushi"), stringPtr("Second Street 456"), int32Ptr(2900), int32(12), false, nil)
 
-		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code,\s+r\.review_count,\s+r\.ordering_paused,\s+r\.deleted_at\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+			WithArgs(int32(21)).
 			WillReturnRows(rows)
+		expectOpeningHours(mock, false, 1, 2)
+		expectLocations(mock, pgxmock.NewRows(locationColumns), 1, 2)
 
 		req := httptest.NewRequest(http.MethodGet, "/api/restaurants", nil)
 		rec := httptest.NewRecorder()
@@ -69,11 +129,14 @@ func TestGetAllRestaurantsHandler(t *testing.T) {
 
 		// Assert
 		want := []generated.Restaurant{
-			{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800)},
-			{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Sushi"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900)},
+			{ID: 1, Name: "Pizza Paradise", Rating: float64Ptr(4.5), Category: stringPtr("Pizza"), Address: stringPtr("Main Street 123"), ZipCode: int32Ptr(2800), ReviewCount: 12},
+			{ID: 2, Name: "Sushi World", Rating: float64Ptr(4.8), Category: stringPtr("Sushi"), Address: stringPtr("Second Street 456"), ZipCode: int32Ptr(2900), ReviewCount: 12},
 		}
 
-		var got []generated.Restaurant
+		var got struct {
+			Items      []generated.Restaurant `json:"items"`
+			NextCursor *string                `json:"next_cursor"`
+		}
 		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
 			t.Fatalf("failed to unmarshal response: %v", err)
 		}
@@ -81,7 +144,7 @@ func TestGetAllRestaurantsHandler(t *testing.T) {
 		if rec.Code != http.StatusOK {
 			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
 		}
-		if !reflect.DeepEqual(got, want) {
+		if !reflect.DeepEqual(got.Items, want) || got.NextCursor != nil {
 			t.Errorf("got %+v, want %+v", got, want)
 		}
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -91,7 +154,8 @@ func TestGetAllRestaurantsHandler(t *testing.T) {
 
 	t.Run("Database Error", func(t *testing.T) {
 		// Arrange
-		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+		mock.ExpectQuery(`SELECT\s+r\.id,\s+r\.name,\s+r\.rating,\s+r\.category,\s+r\.address,\s+r\.zip_code,\s+r\.review_count,\s+r\.ordering_paused,\s+r\.deleted_at\s+FROM\s+restaurant\s+r\s+JOIN\s+zipcode\s+a\s+ON\s+r\.zip_code\s+=\s+a\.zip_code`).
+			WithArgs(int32(21)).
 			WillReturnError(context.DeadlineExceeded)
 
 		req := httptest.NewRequest(http.MethodGet, "/api/restaurants", nil)
@@ -112,6 +176,82 @@ func TestGetAllRestaurantsHandler(t *testing.T) {
 			t.Errorf("unmet mock expectations: %v", err)
 		}
 	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		for _, target := range []string{"/api/restaurants?limit=-1", "/api/restaurants?zip=abc", "/api/restaurants?sort=address", "/api/restaurants?cursor=abc", "/api/restaurants?lat=55.77", "/api/restaurants?lat=95&lon=12.5"} {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.GetAllRestaurants().ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got status %d, want %d", target, rec.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestGetNearbyRestaurantsHandler(t *testing.T) {
+	mock, handler := SetupTestMocks(t)
+	defer CloseMocks(mock)
+
+	t.Run("Valid Point", func(t *testing.T) {
+		// Arrange
+		mock.ExpectQuery(`FROM restaurant r\s+JOIN restaurant_location l ON l.restaurant_id = r.id`).
+			WithArgs(55.77, 12.5, float64(3), int32(20)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at", "latitude", "longitude", "distance_km"}).
+				AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("pizza"), stringPtr("Lyngby Hovedgade 25"), int32Ptr(2800), int32(12), false, nil, 55.7704, 12.5038, 0.4))
+		expectOpeningHours(mock, true, 1)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/restaurants/nearby?lat=55.77&lon=12.5&radius=3", nil)
+		rec := httptest.NewRecorder()
+
+		// Act
+		handler.GetNearbyRestaurants().ServeHTTP(rec, req)
+
+		// Assert
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusOK)
+		}
+		var got []struct {
+			ID         int32   `json:"id"`
+			IsOpen     bool    `json:"is_open"`
+			DistanceKm float64 `json:"distance_km"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != 1 || !got[0].IsOpen || got[0].DistanceKm != 0.4 {
+			t.Errorf("got %+v, want Pizza Paradise open 0.4 km away", got)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		for _, target := range []string{
+			"/api/restaurants/nearby",
+			"/api/restaurants/nearby?lat=55.77",
+			"/api/restaurants/nearby?lat=abc&lon=12.5",
+			"/api/restaurants/nearby?lat=55.77&lon=200",
+			"/api/restaurants/nearby?lat=55.77&lon=12.5&radius=100",
+			"/api/restaurants/nearby?lat=55.77&lon=12.5&limit=0",
+		} {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.GetNearbyRestaurants().ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: got status %d, want %d", target, rec.Code, http.StatusBadRequest)
+			}
+		}
+	})
 }
 
 func TestGetRestaurantByIdHandler(t *testing.T) {
@@ -120,11 +260,12 @@ func TestGetRestaurantByIdHandler(t *testing.T) {
 
 	t.Run("Valid Restaurant ID", func(t *testing.T) {
 		// Arrange
-		row := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800))
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE id = \$1`).
+		row := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil)
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at FROM restaurant WHERE id = \$1`).
 			WithArgs(int32(1)).
 			WillReturnRows(row)
+		expectLocations(mock, pgxmock.NewRows(locationColumns), 1)
 
 		// Create a request and simulate the expected path
 		req := httptest.NewRequest(http.MethodGet, "/api/restaurants/1", nil)
@@ -142,7 +283,7 @@ func TestGetRestaurantByIdHandler(t *testing.T) {
 
 	t.Run("Non-Existent Restaurant ID", func(t *testing.T) {
 		// Arrange
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE id = \$1`).
+		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code, review_count, ordering_paused, deleted_at FROM restaurant WHERE id = \$1`).
 			WithArgs(int32(99))
 
 		// Create a request and simulate the expected path
@@ -160,18 +301,46 @@ func TestGetRestaurantByIdHandler(t *testing.T) {
 	})
 }
 
+// expectMenuDetails expects the details of the menu items to be looked up,
+// finding the given option groups and options, no combo components, no
+// dietary info and no availability limits
+func expectMenuDetails(mock pgxmock.PgxPoolIface, groups, options *pgxmock.Rows, menuItemIds ...int32) {
+	if groups == nil {
+		groups = pgxmock.NewRows([]string{"id", "menu_item_id", "name", "selection_type", "min_select", "max_select", "position"})
+	}
+	if options == nil {
+		options = pgxmock.NewRows([]string{"id", "group_id", "name", "price_delta", "position"})
+	}
+	mock.ExpectQuery(`FROM option_group\s+WHERE menu_item_id = ANY\(\$1::int\[\]\)`).
+		WithArgs(menuItemIds).
+		WillReturnRows(groups)
+	mock.ExpectQuery(`FROM menu_option o\s+JOIN option_group g`).
+		WithArgs(menuItemIds).
+		WillReturnRows(options)
+	mock.ExpectQuery(`FROM combo_component c`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows([]string{"combo_id", "menu_item_id", "name", "quantity"}))
+	mock.ExpectQuery(`FROM menu_item_dietary`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows([]string{"menu_item_id", "allergens", "dietary_tags", "energy_kcal", "fat", "saturated_fat", "carbohydrates", "sugars", "protein", "salt"}))
+	mock.ExpectQuery(`FROM menu_item_availability`).
+		WithArgs(menuItemIds).
+		WillReturnRows(pgxmock.NewRows([]string{"menu_item_id", "available", "daily_stock", "sold", "stock_date", "sold_out_until"}))
+}
+
 func TestGetMenuItemsByRestaurantHandler(t *testing.T) {
 	mock, handler := SetupTestMocks(t)
 	defer CloseMocks(mock)
 
 	t.Run("Valid Restaurant ID", func(t *testing.T) {
 		// Arrange
-		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description"}).
-			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza")).
-			AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"))
-		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description FROM menuitem WHERE restaurantid = \$1`).
+		rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil).
+			AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"), nil)
+		mock.ExpectQuery(`SELECT id, restaurantid, name, price, description, deleted_at FROM menuitem WHERE restaurantid = \$1`).
 			WithArgs(int32(1)).
 			WillReturnRows(rows)
+		expectMenuDetails(mock, nil, nil, 1, 2)
 
 		req := httptest.NewRequest(http.MethodGet, "/api/restaurants/1/menu-items", nil)
 		rec := httptest.NewRecorder()
@@ -198,6 +367,20 @@ func TestGetMenuItemsByRestaurantHandler(t *testing.T) {
 			t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
 		}
 	})
+
+	t.Run("Unknown allergen", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/restaurants/1/menu-items?exclude_allergens=nuts,shellfish", nil)
+		rec := httptest.NewRecorder()
+		req.SetPathValue("restaurantId", "1")
+
+		// Act
+		handler.GetMenuItemsByRestaurant().ServeHTTP(rec, req)
+
+		// Assert
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
 }
 
 func TestFilterRestaurantByCategoryHandler(t *testing.T) {
@@ -206,12 +389,14 @@ func TestFilterRestaurantByCategoryHandler(t *testing.T) {
 
 	t.Run("Filter Restaurant by Category", func(t *testing.T) {
 		// Arrange
-		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code"}).
-			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800)).
-			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Pizza"), stringPtr("Second Street 456"), int32Ptr(2900))
-		mock.ExpectQuery(`SELECT id, name, rating, category, address, zip_code FROM restaurant WHERE category ILIKE \$1`).
-			WithArgs(stringPtr("Pizza")).
+		rows := pgxmock.NewRows([]string{"id", "name", "rating", "category", "address", "zip_code", "review_count", "ordering_paused", "deleted_at"}).
+			AddRow(int32(1), "Pizza Paradise", float64Ptr(4.5), stringPtr("Pizza"), stringPtr("Main Street 123"), int32Ptr(2800), int32(12), false, nil).
+			AddRow(int32(2), "Sushi World", float64Ptr(4.8), stringPtr("Pizza"), stringPtr("Second Street 456"), int32Ptr(2900), int32(12), false, nil)
+		mock.ExpectQuery(`JOIN category c ON c.id = rc.category_id\s+WHERE c.slug = \$1`).
+			WithArgs("pizza").
 			WillReturnRows(rows)
+		expectOpeningHours(mock, false, 1, 2)
+		expectLocations(mock, pgxmock.NewRows(locationColumns), 1, 2)
 
 		req := httptest.NewRequest(http.MethodGet, "/api/filter/Pizza", nil)
 		rec := httptest.NewRecorder()
@@ -240,6 +425,31 @@ func TestFilterRestaurantByCategoryHandler(t *testing.T) {
 	})
 }
 
+// asCustomer makes the request on behalf of the customer
+func asCustomer(req *http.Request, customerId int32) *http.Request {
+	return req.WithContext(auth.WithIdentity(req.Context(), auth.Identity{UserID: customerId, Role: auth.RoleCustomer}))
+}
+
+func TestSelectMenuItemOtherCustomer(t *testing.T) {
+	// Arrange
+	mock, handler := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	itemJSON, _ := json.Marshal(SelectItemParams{CustomerId: 2, RestaurantId: 1, ItemId: 1, Quantity: 1})
+	req := asCustomer(httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON)), 1)
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.SelectMenuItem().ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet mock expectations: %v", err)
+	}
+}
+
 // integration test
 func TestSelectMenuItem(t *testing.T) {
 	mock, handler := SetupTestMocks(t)
@@ -249,16 +459,19 @@ func TestSelectMenuItem(t *testing.T) {
 	broker.InitRabbitMQ()
 	defer broker.CloseRabbitMQ()
 
-	rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description"}).
-		AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza")).
-		AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"))
+	rows := pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+		AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil).
+		AddRow(int32(2), int32(1), "Veggie Pizza", float64(10.0), stringPtr("Healthy veggie pizza"), nil)
 
+	expectRestaurant(mock, 1, false)
+	expectOpeningHours(mock, true, 1)
 	mock.ExpectQuery(`
-SELECT id, restaurantid, name, price, description
+SELECT id, restaurantid, name, price, description, deleted_at
 FROM menuitem
 WHERE restaurantid = \$1 AND id = \$2`).
 		WithArgs(int32(1), int32(1)).
 		WillReturnRows(rows)
+	expectMenuDetails(mock, nil, nil, 1)
 	// create test item
 	item := SelectItemParams{
 		CustomerId:   1,
@@ -268,7 +481,7 @@ WHERE restaurantid = \$1 AND id = \$2`).
 	}
 
 	itemJSON, _ := json.Marshal(item)
-	req := httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON))
+	req := asCustomer(httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON)), 1)
 	rec := httptest.NewRecorder()
 
 	handler.SelectMenuItem().ServeHTTP(rec, req)
@@ -281,3 +494,191 @@ WHERE restaurantid = \$1 AND id = \$2`).
 		t.Errorf("expected body %q, got %q", want, string(got))
 	}
 }
+
+func TestSelectMenuItemClosedRestaurant(t *testing.T) {
+	tests := []struct {
+		name           string
+		orderingPaused bool
+		wantMessage    string
+	}{
+		{"Outside opening hours", false, "Restaurant is closed\n"},
+		{"Ordering paused", true, "Restaurant is not taking orders right now\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, handler := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			expectRestaurant(mock, 1, tt.orderingPaused)
+			expectOpeningHours(mock, false, 1)
+
+			itemJSON, _ := json.Marshal(SelectItemParams{CustomerId: 1, RestaurantId: 1, ItemId: 1, Quantity: 1})
+			req := asCustomer(httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON)), 1)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.SelectMenuItem().ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("got status %d, want %d", rec.Code, http.StatusConflict)
+			}
+			if rec.Body.String() != tt.wantMessage {
+				t.Errorf("got body %q, want %q", rec.Body.String(), tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestSelectMenuItemInvalidOptions(t *testing.T) {
+	// Arrange
+	mock, handler := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	expectRestaurant(mock, 1, false)
+	expectOpeningHours(mock, true, 1)
+	mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+		WithArgs(int32(1), int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil))
+	expectMenuDetails(mock,
+		pgxmock.NewRows([]string{"id", "menu_item_id", "name", "selection_type", "min_select", "max_select", "position"}).
+			AddRow(int32(1), int32(1), "Size", "single", int32(1), int32(1), int32(0)),
+		pgxmock.NewRows([]string{"id", "group_id", "name", "price_delta", "position"}).
+			AddRow(int32(1), int32(1), "Medium", float64(0), int32(0)).
+			AddRow(int32(2), int32(1), "Large", float64(2.5), int32(1)),
+		1)
+
+	itemJSON, _ := json.Marshal(SelectItemParams{CustomerId: 1, RestaurantId: 1, ItemId: 1, Quantity: 1, Options: []int32{1, 2}})
+	req := asCustomer(httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON)), 1)
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.SelectMenuItem().ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if want := "invalid option selection: choose 1 of Size\n"; rec.Body.String() != want {
+		t.Errorf("got body %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestSelectMenuItemUnavailable(t *testing.T) {
+	// Arrange
+	mock, handler := SetupTestMocks(t)
+	defer CloseMocks(mock)
+	expectRestaurant(mock, 1, false)
+	expectOpeningHours(mock, true, 1)
+	mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+		WithArgs(int32(1), int32(1)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+			AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil))
+	mock.ExpectQuery(`FROM option_group`).WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "menu_item_id", "name", "selection_type", "min_select", "max_select", "position"}))
+	mock.ExpectQuery(`FROM menu_option o`).WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "group_id", "name", "price_delta", "position"}))
+	mock.ExpectQuery(`FROM combo_component c`).WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"combo_id", "menu_item_id", "name", "quantity"}))
+	mock.ExpectQuery(`FROM menu_item_dietary`).WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"menu_item_id", "allergens", "dietary_tags", "energy_kcal", "fat", "saturated_fat", "carbohydrates", "sugars", "protein", "salt"}))
+	mock.ExpectQuery(`FROM menu_item_availability`).WithArgs([]int32{1}).
+		WillReturnRows(pgxmock.NewRows([]string{"menu_item_id", "available", "daily_stock", "sold", "stock_date", "sold_out_until"}).
+			AddRow(int32(1), false, nil, int32(0), pgtype.Date{}, nil))
+
+	itemJSON, _ := json.Marshal(SelectItemParams{CustomerId: 1, RestaurantId: 1, ItemId: 1, Quantity: 1})
+	req := asCustomer(httptest.NewRequest(http.MethodPost, "/api/restaurants/menu/select", bytes.NewBuffer(itemJSON)), 1)
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.SelectMenuItem().ServeHTTP(rec, req)
+
+	// Assert
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if want := "menu item unavailable: Cheese Pizza is not available\n"; rec.Body.String() != want {
+		t.Errorf("got body %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestQuoteMenuItem(t *testing.T) {
+	optionGroups := func() (*pgxmock.Rows, *pgxmock.Rows) {
+		return pgxmock.NewRows([]string{"id", "menu_item_id", "name", "selection_type", "min_select", "max_select", "position"}).
+				AddRow(int32(1), int32(1), "Size", "single", int32(1), int32(1), int32(0)),
+			pgxmock.NewRows([]string{"id", "group_id", "name", "price_delta", "position"}).
+				AddRow(int32(1), int32(1), "Medium", float64(0), int32(0)).
+				AddRow(int32(2), int32(1), "Large", float64(2.5), int32(1))
+	}
+	expectMenuItem := func(mock pgxmock.PgxPoolIface) {
+		mock.ExpectQuery(`FROM menuitem\s+WHERE restaurantid = \$1 AND id = \$2`).
+			WithArgs(int32(1), int32(1)).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "restaurantid", "name", "price", "description", "deleted_at"}).
+				AddRow(int32(1), int32(1), "Cheese Pizza", float64(12.5), stringPtr("Delicious cheese pizza"), nil))
+		groups, options := optionGroups()
+		expectMenuDetails(mock, groups, options, 1)
+	}
+
+	t.Run("Priced With Options", func(t *testing.T) {
+		// Arrange
+		mock, handler := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenuItem(mock)
+		mock.ExpectQuery(`FROM menu_item_price\s+WHERE menu_item_id = \$1 AND valid_from <= \$2`).
+			WithArgs(int32(1), pgxmock.AnyArg()).
+			WillReturnRows(pgxmock.NewRows([]string{"id", "menu_item_id", "price", "valid_from", "valid_to"}).
+				AddRow(int32(14), int32(1), float64(13), time.Now().Add(-time.Hour), nil))
+
+		body, _ := json.Marshal(QuoteItemParams{Quantity: 2, Options: []int32{2}})
+		req := httptest.NewRequest(http.MethodPost, "/api/restaurants/1/menu-items/1/quote", bytes.NewBuffer(body))
+		req.SetPathValue("restaurantId", "1")
+		req.SetPathValue("menuitemId", "1")
+		rec := httptest.NewRecorder()
+
+		// Act
+		handler.QuoteMenuItem().ServeHTTP(rec, req)
+
+		// Assert
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var selection domain.PricedSelection
+		if err := json.NewDecoder(rec.Body).Decode(&selection); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if selection.UnitPrice != 15.5 || selection.PriceVersionID == nil || *selection.PriceVersionID != 14 {
+			t.Errorf("expected 15.50 at price version 14, got %+v", selection)
+		}
+		if len(selection.Options) != 1 || selection.Options[0].Name != "Large" {
+			t.Errorf("expected the large option, got %+v", selection.Options)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet mock expectations: %v", err)
+		}
+	})
+
+	t.Run("Invalid Options", func(t *testing.T) {
+		// Arrange
+		mock, handler := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		expectMenuItem(mock)
+
+		body, _ := json.Marshal(QuoteItemParams{Quantity: 1})
+		req := httptest.NewRequest(http.MethodPost, "/api/restaurants/1/menu-items/1/quote", bytes.NewBuffer(body))
+		req.SetPathValue("restaurantId", "1")
+		req.SetPathValue("menuitemId", "1")
+		rec := httptest.NewRecorder()
+
+		// Act
+		handler.QuoteMenuItem().ServeHTTP(rec, req)
+
+		// Assert
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		if want := "invalid option selection: choose 1 of Size\n"; rec.Body.String() != want {
+			t.Errorf("got body %q, want %q", rec.Body.String(), want)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+// searchParams reads the search query and filters from the query string
+func searchParams(r *http.Request) (domain.SearchParams, error) {
+	query := r.URL.Query()
+	params := domain.SearchParams{Query: query.Get("q")}
+
+	if category := query.Get("category"); category != "" {
+		params.Category = &category
+	}
+	if zip := query.Get("zip"); zip != "" {
+		zipCode, err := strconv.ParseInt(zip, 10, 32)
+		if err != nil {
+			return params, errors.New("invalid zip code")
+		}
+		zipCode32 := int32(zipCode)
+		params.ZipCode = &zipCode32
+	}
+	if minRating := query.Get("min_rating"); minRating != "" {
+		rating, err := strconv.ParseFloat(minRating, 64)
+		if err != nil {
+			return params, errors.New("invalid min_rating")
+		}
+		params.MinRating = &rating
+	}
+	if openNow := query.Get("open_now"); openNow != "" {
+		open, err := strconv.ParseBool(openNow)
+		if err != nil {
+			return params, errors.New("invalid open_now")
+		}
+		params.OpenNow = open
+	}
+	params.ExcludeAllergens = commaList(query.Get("exclude_allergens"))
+	params.DietaryTags = commaList(query.Get("diet"))
+	for name, value := range map[string]*int{"page": &params.Page, "page_size": &params.PageSize} {
+		if raw := query.Get(name); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil || n < 1 {
+				return params, errors.New("invalid " + name)
+			}
+			*value = n
+		}
+	}
+
+	return params, nil
+}
+
+// Search godoc
+//
+// @Summary Search restaurants and menu items
+// @Description Full-text search in the names, descriptions and categories of restaurants and menu items, tolerating misspelled names. Hits are ranked best first, and snippets mark the matched words with <mark> tags.
+// @Tags Search
+// @Produce application/json
+// @Param q query string true "Search query"
+// @Param category query string false "Only restaurants in the category"
+// @Param zip query int false "Only restaurants delivering to the zip code"
+// @Param open_now query bool false "Only restaurants taking orders right now"
+// @Param min_rating query number false "Only restaurants rated at least this"
+// @Param exclude_allergens query string false "Comma separated allergens menu items must not contain, e.g. nuts,milk"
+// @Param diet query string false "Comma separated dietary tags menu items must carry, e.g. vegan"
+// @Param page query int false "Page, from 1"
+// @Param page_size query int false "Hits per page, at most 50, defaults to 20"
+// @Success 200 {object} domain.SearchResult
+// @Failure 400 {string} string "Bad request"
+// @Failure 500 {string} string "Internal server error"
+// @Router /api/search [get]
+func (h *RestaurantHandler) Search() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		params, err := searchParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := h.domain.SearchDomain(ctx, params)
+		if errors.Is(err, domain.ErrEmptySearchQuery) || errors.Is(err, domain.ErrInvalidDietaryInfo) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Failed to search", http.StatusInternalServerError)
+			return
+		}
+
+		res, _ := json.Marshal(result)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(res)
+	}
+}
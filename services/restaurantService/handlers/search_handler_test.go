@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+	"github.com/rasm445f/soft-exam-2/domain"
+)
+
+func TestSearchHandler(t *testing.T) {
+	t.Run("Open now", func(t *testing.T) {
+		// Arrange
+		mock, handler := SetupTestMocks(t)
+		defer CloseMocks(mock)
+		mock.ExpectQuery(`websearch_to_tsquery`).
+			WithArgs("sushi", ([]string)(nil), ([]string)(nil), (*string)(nil), int32Ptr(2800), (*float64)(nil), int32(500)).
+			WillReturnRows(pgxmock.NewRows([]string{"kind", "restaurant_id", "menu_item_id", "name", "price", "snippet", "rank", "restaurant_name", "category", "rating", "ordering_paused"}).
+				AddRow(domain.SearchKindRestaurant, int32(2), nil, "Sushi World", nil, "<mark>Sushi</mark> World", 0.9, "Sushi World", stringPtr("Sushi"), float64Ptr(4.8), false).
+				AddRow(domain.SearchKindRestaurant, int32(3), nil, "Sushi Corner", nil, "<mark>Sushi</mark> Corner", 0.8, "Sushi Corner", stringPtr("Sushi"), nil, false))
+		expectOpeningHours(mock, true, 2, 3)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q=sushi&zip=2800&open_now=true", nil)
+		rec := httptest.NewRecorder()
+
+		// Act
+		handler.Search().ServeHTTP(rec, req)
+
+		// Assert
+		if rec.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var result domain.SearchResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if result.Total != 2 || !result.Hits[0].IsOpen {
+			t.Errorf("unexpected result: %+v", result)
+		}
+	})
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Missing query", "/api/search"},
+		{"Invalid zip code", "/api/search?q=pizza&zip=abc"},
+		{"Invalid page", "/api/search?q=pizza&page=0"},
+		{"Unknown allergen", "/api/search?q=pizza&exclude_allergens=shellfish"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mock, handler := SetupTestMocks(t)
+			defer CloseMocks(mock)
+			req := httptest.NewRequest(http.MethodGet, tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.Search().ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
@@ -1,47 +1,150 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/cache"
 	"github.com/rasm445f/soft-exam-2/db"
 	"github.com/rasm445f/soft-exam-2/db/generated"
 	_ "github.com/rasm445f/soft-exam-2/docs"
 	"github.com/rasm445f/soft-exam-2/domain"
 	"github.com/rasm445f/soft-exam-2/handlers"
+	"github.com/rasm445f/soft-exam-2/media"
 	"github.com/rasm445f/soft-exam-2/metrics"
 	"github.com/rs/cors"
 
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
+// Entries expire even without invalidation, which bounds how stale a response
+// gets when an event is lost
+const (
+	cacheSize = 1000
+	cacheTTL  = 5 * time.Minute
+)
+
+// How often scheduled price changes are checked for taking effect
+const scheduledPricesInterval = time.Minute
+
+// Uploaded images are kept in MEDIA_DIR and served under MEDIA_BASE_URL,
+// which defaults to the service itself. Images of deleted restaurants and
+// files left by failed uploads are cleaned up every cleanupImagesInterval,
+// leaving files younger than orphanedFileAge alone.
+const (
+	defaultMediaDir       = "uploads"
+	mediaPath             = "/media/"
+	cleanupImagesInterval = time.Hour
+	orphanedFileAge       = time.Hour
+)
+
 func run() (http.Handler, error) {
+	// Cache restaurant details, menus and categories in-process, shared
+	// through Redis when it is configured
+	redisClient, err := db.ConnectRedis()
+	if err != nil {
+		return nil, err
+	}
+	var store cache.Store
+	if redisClient != nil {
+		store = cache.NewRedisStore(redisClient)
+	}
+	responseCache := cache.New(store, cacheSize, cacheTTL)
+
 	db, err := db.ConnectDB()
 	if err != nil {
 		return nil, err
 	}
 
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = defaultMediaDir
+	}
+	mediaBaseURL := os.Getenv("MEDIA_BASE_URL")
+	if mediaBaseURL == "" {
+		mediaBaseURL = mediaPath
+	}
+	storage, err := media.NewLocalStorage(mediaDir, mediaBaseURL)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize queries and domain layer
 	queries := generated.New(db)
-	restaurantDomain := domain.NewRestaurantDomain(queries)
-	restaurantHandler := handlers.NewRestaurantHandler(restaurantDomain)
+	restaurantDomain := domain.NewRestaurantDomain(queries, db, broker.PublishFanout, storage)
+	restaurantDomain.ConsumeFeedbackEvents()
+	restaurantDomain.ConsumeOrderEvents()
+	go applyScheduledPrices(restaurantDomain)
+	go cleanupImages(restaurantDomain)
+	restaurantHandler := handlers.NewRestaurantHandler(restaurantDomain, responseCache)
+	restaurantHandler.ConsumeRestaurantEvents()
 
 	mux := http.NewServeMux()
 
 	// Routes
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("GET /api/docs/", httpSwagger.WrapHandler)
+	mux.Handle("GET "+mediaPath, http.StripPrefix(mediaPath, storage.Handler()))
 	mux.HandleFunc("GET /api/restaurants", restaurantHandler.GetAllRestaurants())
+	mux.HandleFunc("GET /api/restaurants/nearby", restaurantHandler.GetNearbyRestaurants())
 	mux.HandleFunc("GET /api/restaurants/{restaurantId}", restaurantHandler.GetRestaurantById())
 	mux.HandleFunc("GET /api/restaurants/{restaurantId}/menu-items", restaurantHandler.GetMenuItemsByRestaurant())
 	mux.HandleFunc("GET /api/restaurants/{restaurantId}/menu-items/{menuitemId}", restaurantHandler.GetMenuItemByRestaurantAndId())
 	mux.HandleFunc("GET /api/categories", restaurantHandler.GetAllCategories())
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/categories", restaurantHandler.GetRestaurantCategories())
 	mux.HandleFunc("GET /api/filter/{category}", restaurantHandler.FilterRestaurantByCategory())
+	mux.HandleFunc("GET /api/search", restaurantHandler.Search())
+	// Management. Restaurant staff manage their own restaurant, only admins add
+	// and remove restaurants and the categories shared by all.
+	mux.HandleFunc("POST /api/restaurants", auth.Allow(restaurantHandler.CreateRestaurant()))
+	mux.HandleFunc("PATCH /api/restaurants/{restaurantId}", auth.Allow(restaurantHandler.UpdateRestaurant(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}", auth.Allow(restaurantHandler.DeleteRestaurant()))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/categories", auth.Allow(restaurantHandler.SetRestaurantCategories(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/categories", auth.Allow(restaurantHandler.CreateCategory()))
+	mux.HandleFunc("PATCH /api/categories/{categoryId}", auth.Allow(restaurantHandler.UpdateCategory()))
+	mux.HandleFunc("DELETE /api/categories/{categoryId}", auth.Allow(restaurantHandler.DeleteCategory()))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/menu-items", auth.Allow(restaurantHandler.CreateMenuItem(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PATCH /api/restaurants/{restaurantId}/menu-items/{menuitemId}", auth.Allow(restaurantHandler.UpdateMenuItem(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/menu-items/{menuitemId}", auth.Allow(restaurantHandler.DeleteMenuItem(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/menu-items/{menuitemId}/options", auth.Allow(restaurantHandler.SetOptionGroups(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/menu-items/{menuitemId}/components", auth.Allow(restaurantHandler.SetComboComponents(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/menu-items/{menuitemId}/availability", auth.Allow(restaurantHandler.SetAvailability(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/orders/{orderId}/stock", auth.Allow(restaurantHandler.ReserveStock(), auth.Roles(auth.RoleService)))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/menu-items/{menuitemId}/quote", restaurantHandler.QuoteMenuItem())
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices", restaurantHandler.GetPriceHistory())
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices", auth.Allow(restaurantHandler.ChangePrice(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/menu-items/{menuitemId}/prices/{priceId}", auth.Allow(restaurantHandler.CancelPriceChange(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/menu/import", auth.Allow(restaurantHandler.ImportMenu(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/menu/export", restaurantHandler.ExportMenu())
+	// Images
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/images/{kind}", auth.Allow(restaurantHandler.UploadRestaurantImage(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/images/{kind}", auth.Allow(restaurantHandler.DeleteRestaurantImage(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/menu-items/{menuitemId}/image", auth.Allow(restaurantHandler.UploadMenuItemImage(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/menu-items/{menuitemId}/image", auth.Allow(restaurantHandler.DeleteMenuItemImage(), auth.RestaurantStaff("restaurantId")))
+	// Delivery zones
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/delivery-zones", restaurantHandler.GetDeliveryZones())
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/delivery-zones/{zipCode}", restaurantHandler.GetDeliveryZone())
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/delivery-zones/{zipCode}", auth.Allow(restaurantHandler.SetDeliveryZone(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/delivery-zones/{zipCode}", auth.Allow(restaurantHandler.DeleteDeliveryZone(), auth.RestaurantStaff("restaurantId")))
+	// Opening hours
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/status", restaurantHandler.GetRestaurantStatus())
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/opening-hours", restaurantHandler.GetOpeningHours())
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/opening-hours", auth.Allow(restaurantHandler.SetOpeningHours(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("POST /api/restaurants/{restaurantId}/opening-exceptions", auth.Allow(restaurantHandler.AddOpeningException(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("DELETE /api/restaurants/{restaurantId}/opening-exceptions/{exceptionId}", auth.Allow(restaurantHandler.DeleteOpeningException(), auth.RestaurantStaff("restaurantId")))
+	// Kitchen capacity
+	mux.HandleFunc("GET /api/restaurants/{restaurantId}/kitchen", restaurantHandler.GetKitchenLoad())
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/kitchen/capacity", auth.Allow(restaurantHandler.SetKitchenCapacity(), auth.RestaurantStaff("restaurantId")))
+	mux.HandleFunc("PUT /api/restaurants/{restaurantId}/kitchen/busy", auth.Allow(restaurantHandler.SetBusyMode(), auth.RestaurantStaff("restaurantId")))
 	// Broker
-	mux.HandleFunc("POST /api/restaurants/menu/select", restaurantHandler.SelectMenuItem())
+	mux.HandleFunc("POST /api/restaurants/menu/select", auth.Allow(restaurantHandler.SelectMenuItem(), auth.Roles(auth.RoleCustomer)))
 
 	//CORS stuff
 	corsHandler := cors.New(cors.Options{
@@ -52,12 +155,51 @@ func run() (http.Handler, error) {
 		Debug:            true,
 	})
 
-	metrics := metrics.MetricsMiddleware(mux)
+	// Access tokens are verified against the keys the customer service
+	// publishes, and the caller's identity put into the request context
+	verifier := auth.NewVerifierFromEnv()
+	metrics := metrics.MetricsMiddleware(verifier.Middleware(mux))
 	handler := corsHandler.Handler(metrics)
 
 	return handler, err
 }
 
+// applyScheduledPrices sets the prices of menu items once scheduled price
+// changes take effect
+func applyScheduledPrices(restaurantDomain *domain.RestaurantDomain) {
+	ticker := time.NewTicker(scheduledPricesInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		changed, err := restaurantDomain.ApplyScheduledPricesDomain(context.Background())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if changed > 0 {
+			log.Printf("Applied scheduled prices of %d menu items", changed)
+		}
+	}
+}
+
+// cleanupImages removes the images of deleted restaurants and menu items, and
+// files in the media storage that belong to no image
+func cleanupImages(restaurantDomain *domain.RestaurantDomain) {
+	ticker := time.NewTicker(cleanupImagesInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		removed, err := restaurantDomain.CleanupImagesDomain(context.Background(), time.Now().Add(-orphanedFileAge))
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if removed > 0 {
+			log.Printf("Removed %d orphaned image files", removed)
+		}
+	}
+}
+
 // @title Restaurant Service API
 // @version 1.0
 // @description This is the API documentation for the Restaurant Service.
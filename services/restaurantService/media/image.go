@@ -0,0 +1,156 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxUploadSize is the largest image accepted, in bytes
+	MaxUploadSize = 5 << 20
+	// maxPixels bounds the decoded size of an image, as small files can
+	// decode to huge images
+	maxPixels = 40_000_000
+	// ThumbnailSize is the largest width and height of thumbnails
+	ThumbnailSize = 320
+	jpegQuality   = 85
+)
+
+var (
+	ErrTooLarge        = fmt.Errorf("image must be at most %d MB", MaxUploadSize>>20)
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	ErrInvalidImage    = errors.New("image cannot be decoded")
+)
+
+// extensions of the accepted content types
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is a validated upload with its thumbnail
+type Image struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+}
+
+// Extension is the file extension of content type
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// Process validates an upload by its content, whatever type the client
+// claims, and resizes it into a thumbnail. JPEGs keep their type, while
+// thumbnails of PNGs and GIFs are PNGs, keeping their transparency.
+func Process(data []byte) (*Image, error) {
+	if len(data) > MaxUploadSize {
+		return nil, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := extensions[contentType]; !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: image is larger than %d megapixels", ErrInvalidImage, maxPixels/1_000_000)
+	}
+
+	var decoded image.Image
+	switch contentType {
+	case "image/jpeg":
+		decoded, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/png":
+		decoded, err = png.Decode(bytes.NewReader(data))
+	case "image/gif":
+		decoded, err = gif.Decode(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	thumbnail := Resize(decoded, ThumbnailSize)
+	var buf bytes.Buffer
+	thumbnailType := "image/png"
+	if contentType == "image/jpeg" {
+		thumbnailType = contentType
+		err = jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, thumbnail)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:                 data,
+		ContentType:          contentType,
+		Width:                config.Width,
+		Height:               config.Height,
+		Thumbnail:            buf.Bytes(),
+		ThumbnailContentType: thumbnailType,
+	}, nil
+}
+
+// Resize scales src down to fit within size by size, keeping its aspect
+// ratio. Each pixel is the average of the source pixels it covers. Images
+// that fit already keep their size.
+func Resize(src image.Image, size int) *image.RGBA {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	// Averaging premultiplied colors keeps transparent pixels from bleeding
+	rgba := image.NewRGBA(image.Rect(0, 0, srcW, srcH))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	if dstW == srcW && dstH == srcH {
+		return rgba
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0, y1 := y*srcH/dstH, max((y+1)*srcH/dstH, y*srcH/dstH+1)
+		for x := 0; x < dstW; x++ {
+			x0, x1 := x*srcW/dstW, max((x+1)*srcW/dstW, x*srcW/dstW+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
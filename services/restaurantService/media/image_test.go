@@ -0,0 +1,125 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("Thumbnail keeps the aspect ratio", func(t *testing.T) {
+		processed, err := Process(encodePNG(t, 800, 400))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if processed.ContentType != "image/png" || processed.Width != 800 || processed.Height != 400 {
+			t.Errorf("got %s of %dx%d, want image/png of 800x400", processed.ContentType, processed.Width, processed.Height)
+		}
+
+		thumbnail, err := png.Decode(bytes.NewReader(processed.Thumbnail))
+		if err != nil {
+			t.Fatalf("thumbnail is not a png: %v", err)
+		}
+		if got := thumbnail.Bounds().Size(); got != image.Pt(ThumbnailSize, ThumbnailSize/2) {
+			t.Errorf("got thumbnail of %v, want %dx%d", got, ThumbnailSize, ThumbnailSize/2)
+		}
+		if r, g, b, _ := thumbnail.At(10, 10).RGBA(); r>>8 != 200 || g>>8 != 40 || b>>8 != 40 {
+			t.Errorf("thumbnail color changed to %d,%d,%d", r>>8, g>>8, b>>8)
+		}
+	})
+
+	t.Run("JPEG thumbnails stay JPEG", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 100, 50)), nil); err != nil {
+			t.Fatalf("failed to encode jpeg: %v", err)
+		}
+
+		processed, err := Process(buf.Bytes())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if processed.ContentType != "image/jpeg" || processed.ThumbnailContentType != "image/jpeg" {
+			t.Errorf("got %s with a %s thumbnail, want image/jpeg", processed.ContentType, processed.ThumbnailContentType)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{"Not an image", []byte("<html><body>hello</body></html>"), ErrUnsupportedType},
+		{"Truncated image", encodePNG(t, 10, 10)[:40], ErrInvalidImage},
+		{"Too large", append(encodePNG(t, 10, 10), make([]byte, MaxUploadSize)...), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Process(tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	storage, err := NewLocalStorage(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	key, err := NewKey(".png")
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if err := storage.Put(ctx, key, "image/png", []byte("data")); err != nil {
+		t.Fatalf("failed to put file: %v", err)
+	}
+	if got := storage.URL(key); got != "/media/"+key {
+		t.Errorf("got url %s, want /media/%s", got, key)
+	}
+
+	objects, err := storage.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list files: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Key != key || time.Since(objects[0].ModTime) > time.Minute {
+		t.Errorf("got %+v, want only %s", objects, key)
+	}
+
+	if err := storage.Put(ctx, "../escape.png", "image/png", []byte("data")); err == nil {
+		t.Errorf("expected keys outside the directory to be refused")
+	}
+
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete file: %v", err)
+	}
+	if err := storage.Delete(ctx, key); err != nil {
+		t.Errorf("deleting a missing file should succeed, got %v", err)
+	}
+	if objects, _ := storage.List(ctx); len(objects) != 0 {
+		t.Errorf("expected no files, got %+v", objects)
+	}
+}
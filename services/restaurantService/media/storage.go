@@ -0,0 +1,38 @@
+// Package media keeps uploaded images, e.g. restaurant logos and menu item
+// photos, and resizes them into thumbnails. Files are kept in a Storage by
+// key, with a local filesystem backend; other backends, like an
+// S3-compatible bucket, implement the same interface.
+package media
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Object is a file kept in a storage
+type Object struct {
+	Key     string
+	ModTime time.Time
+}
+
+// Storage keeps files by key
+type Storage interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Delete removes the file of key. Missing files are not an error.
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) ([]Object, error)
+	// URL is where clients download the file of key
+	URL(key string) string
+}
+
+// NewKey creates a random key for a file with the extension, so replaced
+// images get new URLs and cached copies never go stale
+func NewKey(extension string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b) + extension, nil
+}
@@ -22,3 +22,47 @@ var (
 		[]string{"method", "path"},
 	)
 )
+
+// Kitchen load of each restaurant, as of the last time it was worked out
+var (
+	KitchenOpenOrders = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kitchen_open_orders",
+			Help: "Accepted orders still in the kitchen",
+		},
+		[]string{"restaurant_id"},
+	)
+
+	KitchenSlotItems = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kitchen_slot_items",
+			Help: "Items of the orders accepted in the current 15-minute slot",
+		},
+		[]string{"restaurant_id"},
+	)
+
+	KitchenBusy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kitchen_busy",
+			Help: "Whether the kitchen is busy (1) or not (0)",
+		},
+		[]string{"restaurant_id"},
+	)
+
+	KitchenAtCapacity = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "kitchen_at_capacity",
+			Help: "Whether the kitchen is at capacity (1) or not (0)",
+		},
+		[]string{"restaurant_id"},
+	)
+)
+
+// Lookups of the response cache, by layer (local or redis) and result (hit or miss)
+var CacheRequestsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Total number of response cache lookups",
+	},
+	[]string{"layer", "result"},
+)
@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+)
+
+const defaultCustomerServiceURL = "http://localhost:8081"
+
+// CustomerClient looks up customer addresses in the customer service
+type CustomerClient struct {
+	customerServiceURL string
+	httpClient         *http.Client
+	services           *auth.ServiceClient
+}
+
+// NewCustomerClient reads the service URL from CUSTOMER_SERVICE_URL,
+// falling back to the local development port
+func NewCustomerClient(services *auth.ServiceClient) *CustomerClient {
+	url := os.Getenv("CUSTOMER_SERVICE_URL")
+	if url == "" {
+		url = defaultCustomerServiceURL
+	}
+	return &CustomerClient{
+		customerServiceURL: url,
+		httpClient:         &http.Client{Timeout: requestTimeout},
+		services:           services,
+	}
+}
+
+// CustomerZipCode returns the zip code of the customer's address
+func (c *CustomerClient) CustomerZipCode(ctx context.Context, customerId int) (int, error) {
+	url := fmt.Sprintf("%s/api/customer/%d/location", c.customerServiceURL, customerId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// Other services only get to see where a customer is delivered to
+	if err := c.services.Authorize(ctx, req); err != nil {
+		return 0, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body struct {
+		ZipCode *int `json:"zip_code"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, err
+	}
+	if body.ZipCode == nil {
+		return 0, errors.New("no zip code in response from " + url)
+	}
+
+	return *body.ZipCode, nil
+}
@@ -0,0 +1,101 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rasm445f/soft-exam-2/auth"
+	"github.com/rasm445f/soft-exam-2/db"
+)
+
+const defaultOrderServiceURL = "http://localhost:8082"
+
+// PromotionClient checks promotion codes in the order service, which keeps
+// the promotions and the orders placed with them
+type PromotionClient struct {
+	orderServiceURL string
+	httpClient      *http.Client
+	services        *auth.ServiceClient
+}
+
+// NewPromotionClient reads the service URL from ORDER_SERVICE_URL,
+// falling back to the local development port
+func NewPromotionClient(services *auth.ServiceClient) *PromotionClient {
+	url := os.Getenv("ORDER_SERVICE_URL")
+	if url == "" {
+		url = defaultOrderServiceURL
+	}
+	return &PromotionClient{
+		orderServiceURL: url,
+		httpClient:      &http.Client{Timeout: requestTimeout},
+		services:        services,
+	}
+}
+
+// ValidatePromotion returns the discount of the promotion code on the
+// subtotal of a customer's cart. When the code is unknown or does not apply,
+// the discount is nil and reason tells why.
+func (c *PromotionClient) ValidatePromotion(ctx context.Context, code string, restaurantId, customerId int, subtotal float64) (discount *db.CartDiscount, reason string, err error) {
+	url := c.orderServiceURL + "/api/promotions/validate"
+	body, err := json.Marshal(map[string]any{
+		"code":          code,
+		"restaurant_id": restaurantId,
+		"customer_id":   customerId,
+		"subtotal":      subtotal,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.services.Authorize(ctx, req); err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusConflict {
+		message, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, strings.TrimSpace(string(message)), nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var applied struct {
+		PromotionId    int     `json:"promotion_id"`
+		Code           string  `json:"code"`
+		Description    *string `json:"description"`
+		DiscountType   string  `json:"discount_type"`
+		DiscountValue  float64 `json:"discount_value"`
+		DiscountAmount float64 `json:"discount_amount"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&applied); err != nil {
+		return nil, "", err
+	}
+
+	return &db.CartDiscount{
+		PromotionId:   applied.PromotionId,
+		Code:          applied.Code,
+		Description:   applied.Description,
+		DiscountType:  applied.DiscountType,
+		DiscountValue: applied.DiscountValue,
+		Amount:        applied.DiscountAmount,
+	}, "", nil
+}
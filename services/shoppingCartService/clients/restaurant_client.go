@@ -0,0 +1,215 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rasm445f/soft-exam-2/db"
+)
+
+const (
+	defaultRestaurantServiceURL = "http://localhost:8083"
+	requestTimeout              = 3 * time.Second
+)
+
+// RestaurantClient asks the restaurant service whether restaurants take orders,
+// where they deliver, which menu items are available and what they cost
+type RestaurantClient struct {
+	restaurantServiceURL string
+	httpClient           *http.Client
+}
+
+// NewRestaurantClient reads the service URL from RESTAURANT_SERVICE_URL,
+// falling back to the local development port
+func NewRestaurantClient() *RestaurantClient {
+	url := os.Getenv("RESTAURANT_SERVICE_URL")
+	if url == "" {
+		url = defaultRestaurantServiceURL
+	}
+	return &RestaurantClient{
+		restaurantServiceURL: url,
+		httpClient:           &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// RestaurantStatus tells whether the restaurant is open, and otherwise when
+// it opens next, if known
+func (c *RestaurantClient) RestaurantStatus(ctx context.Context, restaurantId int) (bool, *time.Time, error) {
+	url := fmt.Sprintf("%s/api/restaurants/%d/status", c.restaurantServiceURL, restaurantId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body struct {
+		IsOpen      bool       `json:"is_open"`
+		NextOpening *time.Time `json:"next_opening"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, nil, err
+	}
+
+	return body.IsOpen, body.NextOpening, nil
+}
+
+// DeliveryZone returns the delivery fee and minimum order of the restaurant
+// for the zip code. delivered is false when the restaurant does not deliver there.
+func (c *RestaurantClient) DeliveryZone(ctx context.Context, restaurantId, zipCode int) (deliveryFee, minimumOrder float64, delivered bool, err error) {
+	url := fmt.Sprintf("%s/api/restaurants/%d/delivery-zones/%d", c.restaurantServiceURL, restaurantId, zipCode)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, 0, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, false, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body struct {
+		DeliveryFee  float64 `json:"delivery_fee"`
+		MinimumOrder float64 `json:"minimum_order"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, 0, false, err
+	}
+
+	return body.DeliveryFee, body.MinimumOrder, true, nil
+}
+
+// MenuItemAvailability tells whether the menu item can be ordered, and how
+// many portions are left today when its stock is limited. Deleted menu items
+// are unavailable.
+func (c *RestaurantClient) MenuItemAvailability(ctx context.Context, restaurantId, menuItemId int) (bool, *int, error) {
+	url := fmt.Sprintf("%s/api/restaurants/%d/menu-items/%d", c.restaurantServiceURL, restaurantId, menuItemId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var body struct {
+		Availability struct {
+			Available      bool `json:"available"`
+			RemainingStock *int `json:"remaining_stock"`
+		} `json:"availability"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, nil, err
+	}
+
+	return body.Availability.Available, body.Availability.RemainingStock, nil
+}
+
+// QuoteMenuItem prices quantity portions of the menu item with the chosen
+// options at the restaurant's current price. When the item can no longer be
+// ordered so, the quote is nil and reason tells why.
+func (c *RestaurantClient) QuoteMenuItem(ctx context.Context, restaurantId, menuItemId int, optionIds []int, quantity int) (quote *db.ShoppingCartItem, reason string, err error) {
+	url := fmt.Sprintf("%s/api/restaurants/%d/menu-items/%d/quote", c.restaurantServiceURL, restaurantId, menuItemId)
+	body, err := json.Marshal(map[string]any{
+		"quantity": quantity,
+		"options":  optionIds,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+		message, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, strings.TrimSpace(string(message)), nil
+	default:
+		return nil, "", fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	var priced struct {
+		Name           string  `json:"name"`
+		UnitPrice      float64 `json:"unit_price"`
+		PriceVersionId *int    `json:"price_version_id"`
+		Options        []struct {
+			OptionId   int     `json:"option_id"`
+			Group      string  `json:"group"`
+			Name       string  `json:"name"`
+			PriceDelta float64 `json:"price_delta"`
+		} `json:"options"`
+		Components []struct {
+			MenuItemId int    `json:"menu_item_id"`
+			Name       string `json:"name"`
+			Quantity   int    `json:"quantity"`
+		} `json:"components"`
+		Allergens   []string `json:"allergens"`
+		DietaryTags []string `json:"dietary_tags"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&priced); err != nil {
+		return nil, "", err
+	}
+
+	quote = &db.ShoppingCartItem{
+		MenuItemId:     menuItemId,
+		Name:           priced.Name,
+		Price:          priced.UnitPrice,
+		Quantity:       quantity,
+		PriceVersionId: priced.PriceVersionId,
+		Allergens:      priced.Allergens,
+		DietaryTags:    priced.DietaryTags,
+	}
+	for _, option := range priced.Options {
+		quote.Options = append(quote.Options, db.ItemOption(option))
+	}
+	for _, component := range priced.Components {
+		quote.Components = append(quote.Components, db.ComboComponent(component))
+	}
+	return quote, "", nil
+}
@@ -5,12 +5,48 @@ type ShoppingCart struct {
 	RestaurantId int                `json:"restaurant_id"`
 	TotalAmount  float64            `json:"total_amount"`
 	VatAmount    float64            `json:"vat_amount"`
+	DeliveryFee  float64            `json:"delivery_fee"`
 	Items        []ShoppingCartItem `json:"items"`
+	// Discount is the promotion applied to the cart, already taken off TotalAmount
+	Discount *CartDiscount `json:"discount,omitempty"`
 }
 
 type ShoppingCartItem struct {
-	Id       int     `json:"id"`
-	Name     string  `json:"name"`
-	Price    float64 `json:"price"`
-	Quantity int     `json:"quantity"`
+	Id         int              `json:"id"`
+	MenuItemId int              `json:"menu_item_id,omitempty"`
+	Name       string           `json:"name"`
+	Price      float64          `json:"price"`
+	Quantity   int              `json:"quantity"`
+	Options    []ItemOption     `json:"options,omitempty"`
+	Components []ComboComponent `json:"components,omitempty"`
+	// Allergens and DietaryTags are copied from the menu item for traceability
+	Allergens   []string `json:"allergens,omitempty"`
+	DietaryTags []string `json:"dietary_tags,omitempty"`
+	// PriceVersionId is the version of the menu item price the item is charged at
+	PriceVersionId *int `json:"price_version_id,omitempty"`
+}
+
+// CartDiscount is a promotion code applied to the items of a cart
+type CartDiscount struct {
+	PromotionId   int     `json:"promotion_id"`
+	Code          string  `json:"code"`
+	Description   *string `json:"description,omitempty"`
+	DiscountType  string  `json:"discount_type"`
+	DiscountValue float64 `json:"discount_value"`
+	Amount        float64 `json:"amount"`
+}
+
+// ItemOption is an option chosen for a cart item, its price delta is included in the item price
+type ItemOption struct {
+	OptionId   int     `json:"option_id"`
+	Group      string  `json:"group"`
+	Name       string  `json:"name"`
+	PriceDelta float64 `json:"price_delta"`
+}
+
+// ComboComponent is a menu item served as part of a combo
+type ComboComponent struct {
+	MenuItemId int    `json:"menu_item_id"`
+	Name       string `json:"name"`
+	Quantity   int    `json:"quantity"`
 }
@@ -3,6 +3,9 @@ package domain
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/rasm445f/soft-exam-2/db"
@@ -15,38 +18,141 @@ type ShoppingCartPort interface {
 	// UpdateCartDomain updates the quantity of an item in the cart.
 	UpdateCartDomain(ctx context.Context, customerId, itemID, quantity int) error
 
-	// ViewCartDomain retrieves the shopping cart for a customer.
+	// ViewCartDomain retrieves the shopping cart for a customer, with the
+	// discount of its promotion code, if any.
 	ViewCartDomain(ctx context.Context, customerId int) (*db.ShoppingCart, error)
 
 	// ClearCartDomain clears the shopping cart for a customer.
 	ClearCartDomain(ctx context.Context, customerId int) error
+
+	// CheckRestaurantOpenDomain fails with ErrRestaurantClosed when the restaurant does not take orders.
+	CheckRestaurantOpenDomain(ctx context.Context, restaurantId int) error
+
+	// CheckDeliveryDomain updates the delivery fee of the cart and fails with
+	// ErrOutsideDeliveryZone or ErrBelowMinimumOrder when it cannot be ordered.
+	CheckDeliveryDomain(ctx context.Context, cart *db.ShoppingCart) error
+
+	// CheckPricesDomain prices the items of the cart again at the restaurant,
+	// failing with ErrPricesChanged when the cart was priced differently.
+	CheckPricesDomain(ctx context.Context, cart *db.ShoppingCart) error
+
+	// CheckAvailabilityDomain fails with ErrItemUnavailable when an item of the
+	// cart is unavailable or not enough of it is left.
+	CheckAvailabilityDomain(ctx context.Context, cart *db.ShoppingCart) error
+
+	// ApplyPromotionDomain applies a promotion code to the cart, failing with
+	// ErrPromotionNotApplicable when it is unknown or does not apply.
+	ApplyPromotionDomain(ctx context.Context, customerId int, code string) (*db.ShoppingCart, error)
+
+	// RemovePromotionDomain removes the promotion code of the cart.
+	RemovePromotionDomain(ctx context.Context, customerId int) (*db.ShoppingCart, error)
+
+	// CheckPromotionDomain validates the promotion code of the cart once more
+	// and updates its discount, failing with ErrPromotionNotApplicable when
+	// it no longer applies.
+	CheckPromotionDomain(ctx context.Context, cart *db.ShoppingCart) error
 }
 
+// RestaurantLookup tells whether a restaurant takes orders, where it delivers,
+// which menu items are available and what they cost, e.g.
+// clients.RestaurantClient. When a menu item cannot be ordered as chosen, its
+// quote is nil and reason tells why.
+type RestaurantLookup interface {
+	RestaurantStatus(ctx context.Context, restaurantId int) (isOpen bool, nextOpening *time.Time, err error)
+	DeliveryZone(ctx context.Context, restaurantId, zipCode int) (deliveryFee, minimumOrder float64, delivered bool, err error)
+	MenuItemAvailability(ctx context.Context, restaurantId, menuItemId int) (available bool, remainingStock *int, err error)
+	QuoteMenuItem(ctx context.Context, restaurantId, menuItemId int, optionIds []int, quantity int) (quote *db.ShoppingCartItem, reason string, err error)
+}
+
+// PromotionLookup checks promotion codes, e.g. clients.PromotionClient. When
+// the code does not apply the discount is nil and reason tells why.
+type PromotionLookup interface {
+	ValidatePromotion(ctx context.Context, code string, restaurantId, customerId int, subtotal float64) (discount *db.CartDiscount, reason string, err error)
+}
+
+// CustomerLookup finds the address of a customer, e.g. clients.CustomerClient
+type CustomerLookup interface {
+	CustomerZipCode(ctx context.Context, customerId int) (int, error)
+}
+
+var (
+	ErrRestaurantClosed    = errors.New("restaurant is closed")
+	ErrOutsideDeliveryZone = errors.New("restaurant does not deliver to the customer's address")
+	ErrBelowMinimumOrder   = errors.New("order is below the minimum order amount")
+	ErrItemUnavailable     = errors.New("menu item is unavailable")
+	// ErrPricesChanged is returned when the items of the cart cost something
+	// else at the restaurant. The cart is saved with the restaurant's prices.
+	ErrPricesChanged = errors.New("prices of items in the cart have changed")
+	// ErrOtherRestaurant is returned when adding an item from another
+	// restaurant than the one the cart orders from
+	ErrOtherRestaurant = errors.New("cart holds items from another restaurant")
+	// ErrPromotionNotApplicable is returned for unknown codes, and codes whose rules the cart does not meet
+	ErrPromotionNotApplicable = errors.New("promotion code does not apply")
+)
+
 type ShoppingCartDomain struct {
-	repo *db.ShoppingCartRepository
+	repo        *db.ShoppingCartRepository
+	restaurants RestaurantLookup
+	customers   CustomerLookup
+	promotions  PromotionLookup
 }
 
-func NewShoppingCartDomain(repo *db.ShoppingCartRepository) *ShoppingCartDomain {
-	return &ShoppingCartDomain{repo: repo}
+// NewShoppingCartDomain initializes the domain layer. Without restaurants,
+// opening hours are not checked, and without both restaurants and customers
+// delivery zones are not checked. Without promotions, promotion codes cannot
+// be applied.
+func NewShoppingCartDomain(repo *db.ShoppingCartRepository, restaurants RestaurantLookup, customers CustomerLookup, promotions PromotionLookup) *ShoppingCartDomain {
+	return &ShoppingCartDomain{repo: repo, restaurants: restaurants, customers: customers, promotions: promotions}
 }
 
+// AddItemParams is an item as it was selected. Its name, price, options,
+// allergens, dietary tags and price version are taken from the restaurant
+// again when the cart is published.
 type AddItemParams struct {
-	CustomerId   int     `json:"customerId"`
-	RestaurantId int     `json:"restaurantId"`
-	Name         string  `json:"name"`
-	Price        float64 `json:"price"`
-	Quantity     int     `json:"quantity"`
+	CustomerId     int                 `json:"customerId"`
+	RestaurantId   int                 `json:"restaurantId"`
+	MenuItemId     int                 `json:"menuItemId"`
+	Name           string              `json:"name"`
+	Price          float64             `json:"price"`
+	Quantity       int                 `json:"quantity"`
+	Options        []db.ItemOption     `json:"options"`
+	Components     []db.ComboComponent `json:"components"`
+	Allergens      []string            `json:"allergens"`
+	DietaryTags    []string            `json:"dietaryTags"`
+	PriceVersionId *int                `json:"priceVersionId"`
 }
 
-// Helper function to calculate cart totals
+// Helper function to calculate cart totals. The discount is taken off the
+// items before VAT.
 func (d *ShoppingCartDomain) recalculateCartTotals(cart *db.ShoppingCart) {
 	cart.TotalAmount = 0
 	for _, item := range cart.Items {
 		cart.TotalAmount += item.Price * float64(item.Quantity)
 	}
+	if cart.Discount != nil {
+		cart.Discount.Amount = discountAmount(*cart.Discount, cart.TotalAmount)
+		cart.TotalAmount -= cart.Discount.Amount
+	}
 	cart.VatAmount = cart.TotalAmount * 0.20
 }
 
+// discountAmount is the discount on the subtotal, rounded to cents as the
+// order service does. Fixed discounts never exceed the subtotal.
+func discountAmount(discount db.CartDiscount, subtotal float64) float64 {
+	if discount.DiscountType == "percentage" {
+		return math.Round(subtotal*discount.DiscountValue) / 100
+	}
+	return math.Min(discount.DiscountValue, subtotal)
+}
+
+// subtotal is the amount of the cart's items before the discount
+func subtotal(cart *db.ShoppingCart) float64 {
+	if cart.Discount == nil {
+		return cart.TotalAmount
+	}
+	return cart.TotalAmount + cart.Discount.Amount
+}
+
 func (d *ShoppingCartDomain) AddItemDomain(ctx context.Context, itemParams AddItemParams) error {
 	// Business validation
 	if itemParams.Quantity <= 0 {
@@ -61,16 +167,42 @@ func (d *ShoppingCartDomain) AddItemDomain(ctx context.Context, itemParams AddIt
 			RestaurantId: itemParams.RestaurantId,
 			Items:        []db.ShoppingCartItem{},
 		}
+		// New carts are only started for restaurants delivering to the customer
+		deliveryFee, _, err := d.deliveryTerms(ctx, cart)
+		if err != nil {
+			return err
+		}
+		cart.DeliveryFee = deliveryFee
 	} else if err != nil {
 		return err
+	} else if cart.RestaurantId != itemParams.RestaurantId {
+		// An order goes to one restaurant, which alone prices its items
+		if len(cart.Items) > 0 {
+			return ErrOtherRestaurant
+		}
+		// An emptied cart moves on to the new restaurant, dropping the promotion
+		// validated for the previous one
+		cart.RestaurantId = itemParams.RestaurantId
+		cart.Discount = nil
+		deliveryFee, _, err := d.deliveryTerms(ctx, cart)
+		if err != nil {
+			return err
+		}
+		cart.DeliveryFee = deliveryFee
 	}
 
 	// Add item
 	item := db.ShoppingCartItem{
-		Id:       len(cart.Items) + 1, // Simple ID generation instead of redis INCR command
-		Name:     itemParams.Name,
-		Price:    itemParams.Price,
-		Quantity: itemParams.Quantity,
+		Id:             len(cart.Items) + 1, // Simple ID generation instead of redis INCR command
+		MenuItemId:     itemParams.MenuItemId,
+		Name:           itemParams.Name,
+		Price:          itemParams.Price,
+		Quantity:       itemParams.Quantity,
+		Options:        itemParams.Options,
+		Components:     itemParams.Components,
+		Allergens:      itemParams.Allergens,
+		DietaryTags:    itemParams.DietaryTags,
+		PriceVersionId: itemParams.PriceVersionId,
 	}
 
 	cart.Items = append(cart.Items, item)
@@ -119,3 +251,201 @@ func (d *ShoppingCartDomain) ViewCartDomain(ctx context.Context, customerId int)
 func (d *ShoppingCartDomain) ClearCartDomain(ctx context.Context, customerId int) error {
 	return d.repo.ClearCart(ctx, customerId)
 }
+
+func (d *ShoppingCartDomain) CheckRestaurantOpenDomain(ctx context.Context, restaurantId int) error {
+	if d.restaurants == nil {
+		return nil
+	}
+
+	isOpen, nextOpening, err := d.restaurants.RestaurantStatus(ctx, restaurantId)
+	if err != nil {
+		return fmt.Errorf("failed to check whether restaurant %d is open: %w", restaurantId, err)
+	}
+	if isOpen {
+		return nil
+	}
+	if nextOpening != nil {
+		return fmt.Errorf("%w until %s", ErrRestaurantClosed, nextOpening.Format(time.RFC3339))
+	}
+	return ErrRestaurantClosed
+}
+
+// deliveryTerms looks up the delivery fee and minimum order of the cart's
+// restaurant at the customer's address
+func (d *ShoppingCartDomain) deliveryTerms(ctx context.Context, cart *db.ShoppingCart) (float64, float64, error) {
+	if d.restaurants == nil || d.customers == nil {
+		return 0, 0, nil
+	}
+
+	zipCode, err := d.customers.CustomerZipCode(ctx, cart.CustomerId)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to look up the address of customer %d: %w", cart.CustomerId, err)
+	}
+	deliveryFee, minimumOrder, delivered, err := d.restaurants.DeliveryZone(ctx, cart.RestaurantId, zipCode)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to look up the delivery zone of restaurant %d: %w", cart.RestaurantId, err)
+	}
+	if !delivered {
+		return 0, 0, fmt.Errorf("%w (zip code %d)", ErrOutsideDeliveryZone, zipCode)
+	}
+	return deliveryFee, minimumOrder, nil
+}
+
+func (d *ShoppingCartDomain) CheckDeliveryDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	// The terms are looked up again, as the address or the zone may have changed
+	deliveryFee, minimumOrder, err := d.deliveryTerms(ctx, cart)
+	if err != nil {
+		return err
+	}
+	if subtotal(cart) < minimumOrder {
+		return fmt.Errorf("%w of %.2f", ErrBelowMinimumOrder, minimumOrder)
+	}
+	cart.DeliveryFee = deliveryFee
+	return nil
+}
+
+func (d *ShoppingCartDomain) CheckPricesDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if d.restaurants == nil {
+		return nil
+	}
+
+	// Items are added with the price they were selected at, and the
+	// restaurant's prices alone are ever ordered
+	changed := false
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		if item.MenuItemId == 0 {
+			return fmt.Errorf("%w: %s is not on the menu", ErrItemUnavailable, item.Name)
+		}
+		optionIds := make([]int, len(item.Options))
+		for j, option := range item.Options {
+			optionIds[j] = option.OptionId
+		}
+
+		quote, reason, err := d.restaurants.QuoteMenuItem(ctx, cart.RestaurantId, item.MenuItemId, optionIds, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("failed to price menu item %d: %w", item.MenuItemId, err)
+		}
+		if quote == nil {
+			return fmt.Errorf("%w: %s: %s", ErrItemUnavailable, item.Name, reason)
+		}
+		if quote.Price != item.Price {
+			changed = true
+		}
+		item.Name = quote.Name
+		item.Price = quote.Price
+		item.Options = quote.Options
+		item.Components = quote.Components
+		// The price version charged is the restaurant's, as is the price
+		item.PriceVersionId = quote.PriceVersionId
+		// Allergens and dietary tags are recorded on the order as the
+		// restaurant lists them
+		item.Allergens = quote.Allergens
+		item.DietaryTags = quote.DietaryTags
+	}
+
+	d.recalculateCartTotals(cart)
+	if !changed {
+		return nil
+	}
+	if err := d.repo.SaveCart(ctx, cart); err != nil {
+		return err
+	}
+	return ErrPricesChanged
+}
+
+func (d *ShoppingCartDomain) CheckAvailabilityDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if d.restaurants == nil {
+		return nil
+	}
+
+	// The same menu item may be in the cart several times with other options
+	quantities := map[int]int{}
+	names := map[int]string{}
+	var menuItemIds []int
+	for _, item := range cart.Items {
+		if item.MenuItemId == 0 {
+			continue
+		}
+		if _, ok := quantities[item.MenuItemId]; !ok {
+			menuItemIds = append(menuItemIds, item.MenuItemId)
+			names[item.MenuItemId] = item.Name
+		}
+		quantities[item.MenuItemId] += item.Quantity
+	}
+
+	for _, menuItemId := range menuItemIds {
+		available, remainingStock, err := d.restaurants.MenuItemAvailability(ctx, cart.RestaurantId, menuItemId)
+		if err != nil {
+			return fmt.Errorf("failed to check the availability of menu item %d: %w", menuItemId, err)
+		}
+		if !available {
+			return fmt.Errorf("%w: %s", ErrItemUnavailable, names[menuItemId])
+		}
+		if remainingStock != nil && quantities[menuItemId] > *remainingStock {
+			return fmt.Errorf("%w: only %d of %s left", ErrItemUnavailable, *remainingStock, names[menuItemId])
+		}
+	}
+	return nil
+}
+
+func (d *ShoppingCartDomain) ApplyPromotionDomain(ctx context.Context, customerId int, code string) (*db.ShoppingCart, error) {
+	if d.promotions == nil {
+		return nil, errors.New("promotion codes cannot be checked")
+	}
+
+	cart, err := d.repo.GetCart(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	discount, reason, err := d.promotions.ValidatePromotion(ctx, code, cart.RestaurantId, cart.CustomerId, subtotal(cart))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check promotion code %s: %w", code, err)
+	}
+	if discount == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPromotionNotApplicable, reason)
+	}
+
+	cart.Discount = discount
+	d.recalculateCartTotals(cart)
+	if err := d.repo.SaveCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (d *ShoppingCartDomain) RemovePromotionDomain(ctx context.Context, customerId int) (*db.ShoppingCart, error) {
+	cart, err := d.repo.GetCart(ctx, customerId)
+	if err != nil {
+		return nil, err
+	}
+
+	cart.Discount = nil
+	d.recalculateCartTotals(cart)
+	if err := d.repo.SaveCart(ctx, cart); err != nil {
+		return nil, err
+	}
+	return cart, nil
+}
+
+func (d *ShoppingCartDomain) CheckPromotionDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if cart.Discount == nil {
+		return nil
+	}
+	if d.promotions == nil {
+		return errors.New("promotion codes cannot be checked")
+	}
+
+	// The promotion may have expired or been used up since it was applied
+	discount, reason, err := d.promotions.ValidatePromotion(ctx, cart.Discount.Code, cart.RestaurantId, cart.CustomerId, subtotal(cart))
+	if err != nil {
+		return fmt.Errorf("failed to check promotion code %s: %w", cart.Discount.Code, err)
+	}
+	if discount == nil {
+		return fmt.Errorf("%w: %s", ErrPromotionNotApplicable, reason)
+	}
+	cart.Discount = discount
+	d.recalculateCartTotals(cart)
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
@@ -18,7 +20,7 @@ func TestAddItemDomain(t *testing.T) {
 
 	// Initialize the repository with the mock Redis client
 	repo := db.NewShoppingCartRepository(redisDb)
-	domain := NewShoppingCartDomain(repo)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
 
 	// Test data
 	cart := &db.ShoppingCart{
@@ -43,11 +45,11 @@ func TestAddItemDomain(t *testing.T) {
 
 	// add item params
 	itemParams := AddItemParams{
-		123,
-		456,
-		"Sample Item",
-		30.0,
-		2,
+		CustomerId:   123,
+		RestaurantId: 456,
+		Name:         "Sample Item",
+		Price:        30.0,
+		Quantity:     2,
 	}
 
 	t.Run("successfully create a new cart", func(t *testing.T) {
@@ -141,7 +143,7 @@ func TestUpdateCartDomain(t *testing.T) {
 	defer redisDb.Close()
 
 	repo := db.NewShoppingCartRepository(redisDb)
-	domain := NewShoppingCartDomain(repo)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
 
 	cart := &db.ShoppingCart{
 		CustomerId:   123,
@@ -241,7 +243,7 @@ func TestViewCartDomain(t *testing.T) {
 	defer redisDb.Close()
 
 	repo := db.NewShoppingCartRepository(redisDb)
-	domain := NewShoppingCartDomain(repo)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
 
 	cart := &db.ShoppingCart{
 		CustomerId:   123,
@@ -301,7 +303,7 @@ func TestClearCartDomain(t *testing.T) {
 	defer redisDb.Close()
 
 	repo := db.NewShoppingCartRepository(redisDb)
-	domain := NewShoppingCartDomain(repo)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
 
 	t.Run("successfully clear cart", func(t *testing.T) {
 		cartKey := "cart:123"
@@ -317,3 +319,458 @@ func TestClearCartDomain(t *testing.T) {
 		}
 	})
 }
+
+type stubRestaurantLookup struct {
+	isOpen       bool
+	nextOpening  *time.Time
+	delivered    bool
+	deliveryFee  float64
+	minimumOrder float64
+	// Menu items are available without a stock limit unless listed
+	unavailable    map[int]bool
+	remainingStock map[int]int
+	// Menu items are quoted at the price listed, and not on the menu otherwise
+	prices        map[int]float64
+	priceVersions map[int]int
+	err           error
+}
+
+func (s stubRestaurantLookup) RestaurantStatus(ctx context.Context, restaurantId int) (bool, *time.Time, error) {
+	return s.isOpen, s.nextOpening, s.err
+}
+
+func (s stubRestaurantLookup) DeliveryZone(ctx context.Context, restaurantId, zipCode int) (float64, float64, bool, error) {
+	return s.deliveryFee, s.minimumOrder, s.delivered, s.err
+}
+
+func (s stubRestaurantLookup) MenuItemAvailability(ctx context.Context, restaurantId, menuItemId int) (bool, *int, error) {
+	if remaining, ok := s.remainingStock[menuItemId]; ok {
+		return !s.unavailable[menuItemId], &remaining, s.err
+	}
+	return !s.unavailable[menuItemId], nil, s.err
+}
+
+func (s stubRestaurantLookup) QuoteMenuItem(ctx context.Context, restaurantId, menuItemId int, optionIds []int, quantity int) (*db.ShoppingCartItem, string, error) {
+	price, ok := s.prices[menuItemId]
+	if !ok || s.err != nil {
+		return nil, "Menu Item not found", s.err
+	}
+	quote := &db.ShoppingCartItem{MenuItemId: menuItemId, Name: "Cheese Pizza", Price: price, Quantity: quantity,
+		Allergens: []string{"gluten", "milk"}, DietaryTags: []string{"vegetarian"}}
+	if priceVersionId, ok := s.priceVersions[menuItemId]; ok {
+		quote.PriceVersionId = &priceVersionId
+	}
+	return quote, "", nil
+}
+
+type stubCustomerLookup struct {
+	zipCode int
+}
+
+func (s stubCustomerLookup) CustomerZipCode(ctx context.Context, customerId int) (int, error) {
+	return s.zipCode, nil
+}
+
+func TestCheckRestaurantOpenDomain(t *testing.T) {
+	nextOpening := time.Date(2025, time.January, 21, 11, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		lookup     stubRestaurantLookup
+		wantClosed bool
+		wantErr    bool
+	}{
+		{"Open restaurant", stubRestaurantLookup{isOpen: true}, false, false},
+		{"Closed restaurant", stubRestaurantLookup{nextOpening: &nextOpening}, true, true},
+		{"Restaurant service unavailable", stubRestaurantLookup{err: errors.New("connection refused")}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := NewShoppingCartDomain(nil, tt.lookup, nil, nil)
+
+			err := domain.CheckRestaurantOpenDomain(context.Background(), 1)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrRestaurantClosed) != tt.wantClosed {
+				t.Errorf("got error %v, want closed %v", err, tt.wantClosed)
+			}
+		})
+	}
+}
+
+func TestCheckDeliveryDomain(t *testing.T) {
+	tests := []struct {
+		name            string
+		lookup          stubRestaurantLookup
+		wantDeliveryFee float64
+		wantErr         error
+	}{
+		{"Delivers to the customer", stubRestaurantLookup{delivered: true, deliveryFee: 29, minimumOrder: 50}, 29, nil},
+		{"Outside the delivery zone", stubRestaurantLookup{}, 0, ErrOutsideDeliveryZone},
+		{"Below the minimum order", stubRestaurantLookup{delivered: true, deliveryFee: 29, minimumOrder: 100}, 0, ErrBelowMinimumOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := NewShoppingCartDomain(nil, tt.lookup, stubCustomerLookup{zipCode: 2800}, nil)
+			cart := &db.ShoppingCart{CustomerId: 123, RestaurantId: 1, TotalAmount: 60}
+
+			err := domain.CheckDeliveryDomain(context.Background(), cart)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if cart.DeliveryFee != tt.wantDeliveryFee {
+				t.Errorf("got delivery fee %v, want %v", cart.DeliveryFee, tt.wantDeliveryFee)
+			}
+		})
+	}
+}
+
+func TestCheckAvailabilityDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		lookup  stubRestaurantLookup
+		wantErr error
+	}{
+		{"Available items", stubRestaurantLookup{remainingStock: map[int]int{7: 3}}, nil},
+		{"Unavailable item", stubRestaurantLookup{unavailable: map[int]bool{8: true}}, ErrItemUnavailable},
+		{"Not enough left", stubRestaurantLookup{remainingStock: map[int]int{7: 2}}, ErrItemUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := NewShoppingCartDomain(nil, tt.lookup, nil, nil)
+			// The pizza is in the cart twice, in different sizes
+			cart := &db.ShoppingCart{CustomerId: 123, RestaurantId: 1, Items: []db.ShoppingCartItem{
+				{Id: 1, MenuItemId: 7, Name: "Cheese Pizza", Quantity: 2},
+				{Id: 2, MenuItemId: 7, Name: "Cheese Pizza", Quantity: 1},
+				{Id: 3, MenuItemId: 8, Name: "Coca-Cola", Quantity: 1},
+			}}
+
+			err := domain.CheckAvailabilityDomain(context.Background(), cart)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPricesDomain(t *testing.T) {
+	newCart := func() *db.ShoppingCart {
+		return &db.ShoppingCart{CustomerId: 123, RestaurantId: 1, TotalAmount: 20, VatAmount: 4, Items: []db.ShoppingCartItem{
+			{Id: 1, MenuItemId: 7, Name: "Cheese Pizza", Price: 10, Quantity: 2},
+		}}
+	}
+
+	t.Run("Prices unchanged", func(t *testing.T) {
+		domain := NewShoppingCartDomain(nil, stubRestaurantLookup{prices: map[int]float64{7: 10}, priceVersions: map[int]int{7: 14}}, nil, nil)
+		cart := newCart()
+		// The client claims another price version at the same price, and no allergens
+		claimed := 3
+		cart.Items[0].PriceVersionId = &claimed
+		cart.Items[0].Allergens = []string{}
+
+		err := domain.CheckPricesDomain(context.Background(), cart)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if got := cart.Items[0].PriceVersionId; got == nil || *got != 14 {
+			t.Errorf("expected the restaurant's price version 14, got %v", got)
+		}
+		if got := cart.Items[0]; !reflect.DeepEqual(got.Allergens, []string{"gluten", "milk"}) || !reflect.DeepEqual(got.DietaryTags, []string{"vegetarian"}) {
+			t.Errorf("expected the restaurant's allergens and dietary tags, got %v and %v", got.Allergens, got.DietaryTags)
+		}
+	})
+
+	t.Run("Price changed", func(t *testing.T) {
+		redisDb, mock := redismock.NewClientMock()
+		defer redisDb.Close()
+		domain := NewShoppingCartDomain(db.NewShoppingCartRepository(redisDb), stubRestaurantLookup{prices: map[int]float64{7: 12}}, nil, nil)
+		cart := newCart()
+		repriced := newCart()
+		repriced.Items[0].Price = 12
+		repriced.Items[0].Allergens = []string{"gluten", "milk"}
+		repriced.Items[0].DietaryTags = []string{"vegetarian"}
+		repriced.TotalAmount = 24
+		repriced.VatAmount = repriced.TotalAmount * 0.20
+		cartData, _ := json.Marshal(repriced)
+		mock.ExpectSet("cart:123", cartData, 0).SetVal("OK")
+
+		err := domain.CheckPricesDomain(context.Background(), cart)
+
+		if !errors.Is(err, ErrPricesChanged) {
+			t.Fatalf("got error %v, want %v", err, ErrPricesChanged)
+		}
+		if cart.TotalAmount != 24 {
+			t.Errorf("expected the cart to be priced at 24, got %v", cart.TotalAmount)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %s", err)
+		}
+	})
+
+	t.Run("Item not on the menu", func(t *testing.T) {
+		domain := NewShoppingCartDomain(nil, stubRestaurantLookup{}, nil, nil)
+
+		err := domain.CheckPricesDomain(context.Background(), newCart())
+
+		if !errors.Is(err, ErrItemUnavailable) {
+			t.Errorf("got error %v, want %v", err, ErrItemUnavailable)
+		}
+	})
+}
+
+func TestAddItemDomainOtherRestaurant(t *testing.T) {
+	redisDb, mock := redismock.NewClientMock()
+	defer redisDb.Close()
+
+	repo := db.NewShoppingCartRepository(redisDb)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
+	cartData, _ := json.Marshal(&db.ShoppingCart{CustomerId: 123, RestaurantId: 456, TotalAmount: 30, VatAmount: 6,
+		Items: []db.ShoppingCartItem{{Id: 1, Name: "Sample Item", Price: 30, Quantity: 1}}})
+	mock.ExpectGet("cart:123").SetVal(string(cartData))
+
+	err := domain.AddItemDomain(context.Background(), AddItemParams{CustomerId: 123, RestaurantId: 789, Name: "Sample Item", Price: 30, Quantity: 1})
+	if !errors.Is(err, ErrOtherRestaurant) {
+		t.Errorf("got error %v, want %v", err, ErrOtherRestaurant)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %s", err)
+	}
+}
+
+func TestAddItemDomainAfterEmptyingCart(t *testing.T) {
+	redisDb, mock := redismock.NewClientMock()
+	defer redisDb.Close()
+
+	repo := db.NewShoppingCartRepository(redisDb)
+	domain := NewShoppingCartDomain(repo, stubRestaurantLookup{isOpen: true, delivered: true, deliveryFee: 39}, stubCustomerLookup{zipCode: 2100}, nil)
+
+	cart := &db.ShoppingCart{CustomerId: 123, RestaurantId: 456, TotalAmount: 30, VatAmount: 6, DeliveryFee: 29,
+		Items: []db.ShoppingCartItem{{Id: 1, Name: "Sample Item", Price: 30, Quantity: 1}}}
+	cartData, _ := json.Marshal(cart)
+
+	// Remove the last item
+	mock.ExpectGet("cart:123").SetVal(string(cartData))
+	cart.Items = []db.ShoppingCartItem{}
+	cart.TotalAmount = 0
+	cart.VatAmount = 0
+	emptiedCartData, _ := json.Marshal(cart)
+	mock.ExpectSet("cart:123", emptiedCartData, 0).SetVal("OK")
+
+	// Add an item from another restaurant
+	mock.ExpectGet("cart:123").SetVal(string(emptiedCartData))
+	cart.RestaurantId = 789
+	cart.DeliveryFee = 39
+	cart.Items = []db.ShoppingCartItem{{Id: 1, Name: "Other Item", Price: 50, Quantity: 1}}
+	cart.TotalAmount = 50
+	cart.VatAmount = 10
+	switchedCartData, _ := json.Marshal(cart)
+	mock.ExpectSet("cart:123", switchedCartData, 0).SetVal("OK")
+
+	if err := domain.UpdateCartDomain(context.Background(), 123, 1, 0); err != nil {
+		t.Fatalf("unexpected error removing the item: %v", err)
+	}
+	err := domain.AddItemDomain(context.Background(), AddItemParams{CustomerId: 123, RestaurantId: 789, Name: "Other Item", Price: 50, Quantity: 1})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %s", err)
+	}
+}
+
+func TestAddItemDomainOutsideDeliveryZone(t *testing.T) {
+	redisDb, mock := redismock.NewClientMock()
+	defer redisDb.Close()
+
+	repo := db.NewShoppingCartRepository(redisDb)
+	domain := NewShoppingCartDomain(repo, stubRestaurantLookup{isOpen: true}, stubCustomerLookup{zipCode: 2100}, nil)
+
+	mock.ExpectGet("cart:123").RedisNil()
+
+	err := domain.AddItemDomain(context.Background(), AddItemParams{CustomerId: 123, RestaurantId: 456, Name: "Sample Item", Price: 30, Quantity: 1})
+	if !errors.Is(err, ErrOutsideDeliveryZone) {
+		t.Errorf("got error %v, want %v", err, ErrOutsideDeliveryZone)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %s", err)
+	}
+}
+
+func TestAddItemDomainWithOptionsAllergensAndPriceVersion(t *testing.T) {
+	redisDb, mock := redismock.NewClientMock()
+	defer redisDb.Close()
+
+	repo := db.NewShoppingCartRepository(redisDb)
+	domain := NewShoppingCartDomain(repo, nil, nil, nil)
+
+	options := []db.ItemOption{
+		{OptionId: 2, Group: "Size", Name: "Large", PriceDelta: 2.5},
+		{OptionId: 3, Group: "Toppings", Name: "Extra cheese", PriceDelta: 1},
+	}
+	components := []db.ComboComponent{{MenuItemId: 4, Name: "Coca-Cola", Quantity: 1}}
+	priceVersionId := 12
+	cart := &db.ShoppingCart{
+		CustomerId:   123,
+		RestaurantId: 456,
+		TotalAmount:  27.0,
+		VatAmount:    5.4,
+		Items: []db.ShoppingCartItem{
+			{Id: 1, MenuItemId: 7, Name: "Pizza Menu", Price: 13.5, Quantity: 2, Options: options, Components: components,
+				Allergens: []string{"gluten", "milk"}, DietaryTags: []string{"vegetarian"}, PriceVersionId: &priceVersionId},
+		},
+	}
+	cartData, err := json.Marshal(cart)
+	if err != nil {
+		t.Fatalf("unexpected error marshalling cart: %v", err)
+	}
+
+	mock.ExpectGet("cart:123").RedisNil()
+	mock.ExpectSet("cart:123", cartData, 0).SetVal("OK")
+
+	err = domain.AddItemDomain(context.Background(), AddItemParams{
+		CustomerId:     123,
+		RestaurantId:   456,
+		MenuItemId:     7,
+		Name:           "Pizza Menu",
+		Price:          13.5,
+		Quantity:       2,
+		Options:        options,
+		Components:     components,
+		Allergens:      []string{"gluten", "milk"},
+		DietaryTags:    []string{"vegetarian"},
+		PriceVersionId: &priceVersionId,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %s", err)
+	}
+}
+
+type stubPromotionLookup struct {
+	discount *db.CartDiscount
+	reason   string
+	err      error
+}
+
+func (s stubPromotionLookup) ValidatePromotion(ctx context.Context, code string, restaurantId, customerId int, subtotal float64) (*db.CartDiscount, string, error) {
+	if s.discount == nil {
+		return nil, s.reason, s.err
+	}
+	discount := *s.discount
+	discount.Amount = discountAmount(discount, subtotal)
+	return &discount, "", s.err
+}
+
+func TestApplyPromotionDomain(t *testing.T) {
+	welcome := &db.CartDiscount{PromotionId: 1, Code: "WELCOME10", DiscountType: "percentage", DiscountValue: 10}
+	tests := []struct {
+		name      string
+		lookup    stubPromotionLookup
+		wantTotal float64
+		wantErr   error
+	}{
+		{"Percentage discount", stubPromotionLookup{discount: welcome}, 135, nil},
+		{"Fixed discount", stubPromotionLookup{discount: &db.CartDiscount{PromotionId: 2, Code: "TAKE20", DiscountType: "fixed", DiscountValue: 20}}, 130, nil},
+		{"Not applicable", stubPromotionLookup{reason: "WELCOME10 only applies to a first order"}, 0, ErrPromotionNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redisDb, mock := redismock.NewClientMock()
+			defer redisDb.Close()
+
+			repo := db.NewShoppingCartRepository(redisDb)
+			domain := NewShoppingCartDomain(repo, nil, nil, tt.lookup)
+
+			cart := &db.ShoppingCart{
+				CustomerId:   123,
+				RestaurantId: 456,
+				TotalAmount:  150,
+				VatAmount:    30,
+				Items:        []db.ShoppingCartItem{{Id: 1, MenuItemId: 7, Name: "Pizza", Price: 75, Quantity: 2}},
+			}
+			cartData, _ := json.Marshal(cart)
+			mock.ExpectGet("cart:123").SetVal(string(cartData))
+			if tt.wantErr == nil {
+				mock.Regexp().ExpectSet("cart:123", ".*", 0).SetVal("OK")
+			}
+
+			got, err := domain.ApplyPromotionDomain(context.Background(), 123, "welcome10")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil {
+				if got.Discount == nil || got.Discount.Amount != 150-tt.wantTotal {
+					t.Errorf("expected a discount of %.2f, got %+v", 150-tt.wantTotal, got.Discount)
+				}
+				if got.TotalAmount != tt.wantTotal || got.VatAmount != tt.wantTotal*0.20 {
+					t.Errorf("expected total %.2f and VAT %.2f, got %.2f and %.2f", tt.wantTotal, tt.wantTotal*0.20, got.TotalAmount, got.VatAmount)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("unmet expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateCartDomainRecalculatesDiscount(t *testing.T) {
+	domain := NewShoppingCartDomain(nil, nil, nil, nil)
+	cart := &db.ShoppingCart{
+		Items:    []db.ShoppingCartItem{{Id: 1, MenuItemId: 7, Name: "Pizza", Price: 75, Quantity: 1}},
+		Discount: &db.CartDiscount{Code: "TAKE20", DiscountType: "fixed", DiscountValue: 100, Amount: 100},
+	}
+
+	domain.recalculateCartTotals(cart)
+
+	// A fixed discount never exceeds the items it is taken off
+	if cart.Discount.Amount != 75 || cart.TotalAmount != 0 {
+		t.Errorf("expected a discount of 75 and a total of 0, got %.2f and %.2f", cart.Discount.Amount, cart.TotalAmount)
+	}
+	if subtotal(cart) != 75 {
+		t.Errorf("expected a subtotal of 75, got %.2f", subtotal(cart))
+	}
+}
+
+func TestCheckPromotionDomain(t *testing.T) {
+	welcome := &db.CartDiscount{PromotionId: 1, Code: "WELCOME10", DiscountType: "percentage", DiscountValue: 10}
+	tests := []struct {
+		name    string
+		lookup  stubPromotionLookup
+		wantErr error
+	}{
+		{"Still applies", stubPromotionLookup{discount: welcome}, nil},
+		{"Expired", stubPromotionLookup{reason: "WELCOME10 expired"}, ErrPromotionNotApplicable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := NewShoppingCartDomain(nil, nil, nil, tt.lookup)
+			discount := *welcome
+			discount.Amount = 15
+			cart := &db.ShoppingCart{
+				TotalAmount: 135,
+				Items:       []db.ShoppingCartItem{{Id: 1, MenuItemId: 7, Name: "Pizza", Price: 75, Quantity: 2}},
+				Discount:    &discount,
+			}
+
+			err := domain.CheckPromotionDomain(context.Background(), cart)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && (cart.Discount.Amount != 15 || cart.TotalAmount != 135) {
+				t.Errorf("expected the discount of 15 to be kept, got %.2f off %.2f", cart.Discount.Amount, cart.TotalAmount)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ require (
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/golang-jwt/jwt/v5 v5.2.1 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
 	github.com/prometheus/client_model v0.6.1 // indirect
@@ -33,6 +34,7 @@ require (
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/rabbitmq/amqp091-go v1.10.0 // indirect
+	github.com/rasm445f/soft-exam-2/auth v0.0.0
 	github.com/rasm445f/soft-exam-2/broker v0.0.0
 	github.com/swaggo/files/v2 v2.0.0 // indirect
 	golang.org/x/text v0.18.0 // indirect
@@ -41,3 +43,5 @@ require (
 )
 
 replace github.com/rasm445f/soft-exam-2/broker => ../../broker
+
+replace github.com/rasm445f/soft-exam-2/auth => ../../auth
@@ -3,11 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
 
+	"github.com/go-redis/redis/v8"
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/domain"
 )
@@ -23,13 +26,16 @@ func NewShoppingCartHandler(domain domain.ShoppingCartPort) *ShoppingCartHandler
 // AddItem godoc
 //
 //	@Summary		Add a MenuItem
-//	@Description	Add a MenuItem to the shopping cart
+//	@Description	Add a MenuItem to the shopping cart of a restaurant. The items are priced again by the restaurant when the cart is published.
 //	@Tags			ShoppingCart CRUD
 //	@Accept			application/json
 //	@Produce		application/json
 //	@Param			item	body		domain.AddItemParams	true	"item object"
 //	@Success		201		{object}	domain.AddItemParams
 //	@Failure		400		{string}	string	"Bad request"
+//	@Failure		401		{string}	string	"Unauthorized"
+//	@Failure		403		{string}	string	"Forbidden"
+//	@Failure		409		{string}	string	"Restaurant does not deliver to the customer, or cart holds items from another restaurant"
 //	@Failure		500		{string}	string	"Internal server error"
 //	@Router			/api/shopping [post]
 func (h *ShoppingCartHandler) AddItem() http.HandlerFunc {
@@ -42,7 +48,17 @@ func (h *ShoppingCartHandler) AddItem() http.HandlerFunc {
 			return
 		}
 
+		// Customers can only add to their own cart
+		identity, _ := auth.IdentityFrom(ctx)
+		if !auth.Check(w, r, item.CustomerId == int(identity.UserID)) {
+			return
+		}
+
 		if err := h.domain.AddItemDomain(ctx, item); err != nil {
+			if errors.Is(err, domain.ErrOutsideDeliveryZone) || errors.Is(err, domain.ErrOtherRestaurant) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
@@ -69,6 +85,8 @@ type UpdateQuantityRequest struct {
 //	@Success		200			{string}	string					"ShoppingCart updated successfully"
 //
 // @Failure 400 {string} string "Bad request"
+// @Failure 401 {string} string "Unauthorized"
+// @Failure 403 {string} string "Forbidden"
 // @Failure 500 {string} string "Internal server error"
 //
 //	@Router			/api/shopping/{customerId}/{itemId} [patch]
@@ -109,6 +127,8 @@ func (h *ShoppingCartHandler) UpdateCart() http.HandlerFunc {
 //	@Param			id	path		string	true	"customer ID"
 //	@Success		200	{string}	string	"Viewed Cart"
 //	@Failure		400	{string}	string	"Bad request"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		403	{string}	string	"Forbidden"
 //	@Failure		500	{string}	string	"Internal server error"
 //	@Router			/api/shopping/{id} [get]
 func (h *ShoppingCartHandler) ViewCart() http.HandlerFunc {
@@ -144,6 +164,8 @@ func (h *ShoppingCartHandler) ViewCart() http.HandlerFunc {
 //	@Param			customerId	path		int		true	"customer ID"
 //	@Success		200			{string}	string	"Cart Cleared"
 //	@Failure		400			{string}	string	"Bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"Forbidden"
 //	@Failure		500			{string}	string	"Internal server error"
 //	@Router			/api/shopping/{customerId} [delete]
 func (h *ShoppingCartHandler) ClearCart() http.HandlerFunc {
@@ -163,6 +185,99 @@ func (h *ShoppingCartHandler) ClearCart() http.HandlerFunc {
 	}
 }
 
+type ApplyPromotionRequest struct {
+	Code string `json:"code" example:"WELCOME10"`
+}
+
+// ApplyPromotion godoc
+//
+//	@Summary		Apply a promotion code
+//	@Description	Applies a promotion code to the customer's cart, replacing any code applied before. The discount is shown in the cart and taken off its total.
+//	@Tags			ShoppingCart CRUD
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			customerId	path		int						true	"customer ID"
+//	@Param			body		body		ApplyPromotionRequest	true	"Promotion code"
+//	@Success		200			{object}	db.ShoppingCart
+//	@Failure		400			{string}	string	"Bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"Forbidden"
+//	@Failure		409			{string}	string	"Promotion code unknown or not applicable"
+//	@Failure		503			{string}	string	"Order service unavailable"
+//	@Router			/api/shopping/{customerId}/promotion [post]
+func (h *ShoppingCartHandler) ApplyPromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		customerId, err := strconv.Atoi(r.PathValue("customerId"))
+		if err != nil {
+			http.Error(w, "Malformed customer_id", http.StatusBadRequest)
+			return
+		}
+
+		var req ApplyPromotionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		shoppingCart, err := h.domain.ApplyPromotionDomain(ctx, customerId, req.Code)
+		if err != nil {
+			if errors.Is(err, domain.ErrPromotionNotApplicable) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if errors.Is(err, redis.Nil) {
+				http.Error(w, "Cart not found", http.StatusBadRequest)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not check the promotion code", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(shoppingCart); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// RemovePromotion godoc
+//
+//	@Summary		Remove the promotion code
+//	@Description	Removes the promotion code of the customer's cart
+//	@Tags			ShoppingCart CRUD
+//	@Produce		application/json
+//	@Param			customerId	path		int	true	"customer ID"
+//	@Success		200			{object}	db.ShoppingCart
+//	@Failure		400			{string}	string	"Bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"Forbidden"
+//	@Router			/api/shopping/{customerId}/promotion [delete]
+func (h *ShoppingCartHandler) RemovePromotion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		customerId, err := strconv.Atoi(r.PathValue("customerId"))
+		if err != nil {
+			http.Error(w, "Malformed customer_id", http.StatusBadRequest)
+			return
+		}
+
+		shoppingCart, err := h.domain.RemovePromotionDomain(ctx, customerId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(shoppingCart); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 // Consume Shopping Cart's MenuItems godoc
 //
 //	@Summary		Consume the chosen Menu Items for a Customer
@@ -171,6 +286,8 @@ func (h *ShoppingCartHandler) ClearCart() http.HandlerFunc {
 //	@Produce		application/json
 //	@Success		200	{string}	string	"Shopping Cart's Menu Items Consumed"
 //	@Failure		400	{string}	string	"Bad request"
+//	@Failure		401	{string}	string	"Unauthorized"
+//	@Failure		403	{string}	string	"Forbidden"
 //	@Failure		500	{string}	string	"Internal server error"
 //	@Router			/api/shopping/consume [get]
 func (h *ShoppingCartHandler) ConsumeMenuItem() http.HandlerFunc {
@@ -229,7 +346,11 @@ type PublishShoppingCartRequest struct {
 //	@Param			comment		body		PublishShoppingCartRequest		true	"Customer Comment (optional)"
 //	@Success		200			{string}	string	"Order Selected Successfully"
 //	@Failure		400			{string}	string	"Bad request"
+//	@Failure		401			{string}	string	"Unauthorized"
+//	@Failure		403			{string}	string	"Forbidden"
+//	@Failure		409			{string}	string	"Restaurant closed, prices changed, outside the delivery zone, below the minimum order, items unavailable or promotion code not applicable"
 //	@Failure		500			{string}	string	"Internal server error"
+//	@Failure		503			{string}	string	"Restaurant, customer or order service unavailable"
 //	@Router			/api/shopping/publish/{customerId} [post]
 func (h *ShoppingCartHandler) PublishShoppingCart() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -255,6 +376,61 @@ func (h *ShoppingCartHandler) PublishShoppingCart() http.HandlerFunc {
 			return
 		}
 
+		// Closed restaurants do not take orders
+		if err := h.domain.CheckRestaurantOpenDomain(ctx, shoppingCart.RestaurantId); err != nil {
+			if errors.Is(err, domain.ErrRestaurantClosed) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not check whether the restaurant is open", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Items are priced by the restaurant, never as they were added to the cart
+		if err := h.domain.CheckPricesDomain(ctx, shoppingCart); err != nil {
+			if errors.Is(err, domain.ErrPricesChanged) || errors.Is(err, domain.ErrItemUnavailable) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not price the items", http.StatusServiceUnavailable)
+			return
+		}
+
+		// The restaurant must deliver to the customer, and the minimum order be reached
+		if err := h.domain.CheckDeliveryDomain(ctx, shoppingCart); err != nil {
+			if errors.Is(err, domain.ErrOutsideDeliveryZone) || errors.Is(err, domain.ErrBelowMinimumOrder) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not check the delivery zone", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Items may have sold out since they were added to the cart
+		if err := h.domain.CheckAvailabilityDomain(ctx, shoppingCart); err != nil {
+			if errors.Is(err, domain.ErrItemUnavailable) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not check the availability of the items", http.StatusServiceUnavailable)
+			return
+		}
+
+		// The promotion code may no longer apply, and its discount is computed again
+		if err := h.domain.CheckPromotionDomain(ctx, shoppingCart); err != nil {
+			if errors.Is(err, domain.ErrPromotionNotApplicable) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Println(err)
+			http.Error(w, "Could not check the promotion code", http.StatusServiceUnavailable)
+			return
+		}
+
 		// Publish event to RabbitMQ
 		event := broker.Event{
 			Type:    broker.OrderCreated,
@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
 	"github.com/rasm445f/soft-exam-2/db"
 	"github.com/rasm445f/soft-exam-2/domain"
@@ -22,6 +23,15 @@ type MockShoppingCartDomain struct {
 	UpdateCartDomainFunc func(ctx context.Context, customerId, itemID, quantity int) error
 	ViewCartDomainFunc   func(ctx context.Context, customerId int) (*db.ShoppingCart, error)
 	ClearCartDomainFunc  func(ctx context.Context, customerId int) error
+
+	CheckRestaurantOpenDomainFunc func(ctx context.Context, restaurantId int) error
+	CheckDeliveryDomainFunc       func(ctx context.Context, cart *db.ShoppingCart) error
+	CheckPricesDomainFunc         func(ctx context.Context, cart *db.ShoppingCart) error
+	CheckAvailabilityDomainFunc   func(ctx context.Context, cart *db.ShoppingCart) error
+
+	ApplyPromotionDomainFunc  func(ctx context.Context, customerId int, code string) (*db.ShoppingCart, error)
+	RemovePromotionDomainFunc func(ctx context.Context, customerId int) (*db.ShoppingCart, error)
+	CheckPromotionDomainFunc  func(ctx context.Context, cart *db.ShoppingCart) error
 }
 
 func (m *MockShoppingCartDomain) AddItemDomain(ctx context.Context, params domain.AddItemParams) error {
@@ -59,6 +69,61 @@ func (m *MockShoppingCartDomain) ClearCartDomain(ctx context.Context, customerId
 	return nil
 }
 
+func (m *MockShoppingCartDomain) CheckRestaurantOpenDomain(ctx context.Context, restaurantId int) error {
+	if m.CheckRestaurantOpenDomainFunc != nil {
+		return m.CheckRestaurantOpenDomainFunc(ctx, restaurantId)
+	}
+	return nil
+}
+
+func (m *MockShoppingCartDomain) CheckDeliveryDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if m.CheckDeliveryDomainFunc != nil {
+		return m.CheckDeliveryDomainFunc(ctx, cart)
+	}
+	return nil
+}
+
+func (m *MockShoppingCartDomain) CheckPricesDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if m.CheckPricesDomainFunc != nil {
+		return m.CheckPricesDomainFunc(ctx, cart)
+	}
+	return nil
+}
+
+func (m *MockShoppingCartDomain) CheckAvailabilityDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if m.CheckAvailabilityDomainFunc != nil {
+		return m.CheckAvailabilityDomainFunc(ctx, cart)
+	}
+	return nil
+}
+
+func (m *MockShoppingCartDomain) ApplyPromotionDomain(ctx context.Context, customerId int, code string) (*db.ShoppingCart, error) {
+	if m.ApplyPromotionDomainFunc != nil {
+		return m.ApplyPromotionDomainFunc(ctx, customerId, code)
+	}
+	return m.ViewCartDomain(ctx, customerId)
+}
+
+func (m *MockShoppingCartDomain) RemovePromotionDomain(ctx context.Context, customerId int) (*db.ShoppingCart, error) {
+	if m.RemovePromotionDomainFunc != nil {
+		return m.RemovePromotionDomainFunc(ctx, customerId)
+	}
+	return m.ViewCartDomain(ctx, customerId)
+}
+
+func (m *MockShoppingCartDomain) CheckPromotionDomain(ctx context.Context, cart *db.ShoppingCart) error {
+	if m.CheckPromotionDomainFunc != nil {
+		return m.CheckPromotionDomainFunc(ctx, cart)
+	}
+	return nil
+}
+
+// asCustomer makes the request as the customer, as if their access token had
+// been verified
+func asCustomer(r *http.Request, customerId int32) *http.Request {
+	return r.WithContext(auth.WithIdentity(r.Context(), auth.Identity{UserID: customerId, Role: auth.RoleCustomer}))
+}
+
 func TestAddItem(t *testing.T) {
 	mockDomain := &MockShoppingCartDomain{}
 	handler := NewShoppingCartHandler(mockDomain)
@@ -81,7 +146,7 @@ func TestAddItem(t *testing.T) {
 			t.Error(err)
 		}
 
-		handler.AddItem().ServeHTTP(rec, req)
+		handler.AddItem().ServeHTTP(rec, asCustomer(req, 123))
 
 		got := rec.Result().StatusCode
 		want := http.StatusCreated
@@ -108,6 +173,26 @@ func TestAddItem(t *testing.T) {
 		}
 	})
 
+	t.Run("other customer's cart", func(t *testing.T) {
+		item := domain.AddItemParams{
+			CustomerId:   123,
+			RestaurantId: 456,
+			Name:         "ting",
+			Price:        1,
+			Quantity:     1,
+		}
+
+		itemJSON, _ := json.Marshal(item)
+		r := httptest.NewRequest(http.MethodPost, "/add-item", bytes.NewBuffer(itemJSON))
+		w := httptest.NewRecorder()
+
+		handler.AddItem().ServeHTTP(w, asCustomer(r, 124))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status %v, got %v", http.StatusForbidden, w.Code)
+		}
+	})
+
 	t.Run("domain error", func(t *testing.T) {
 		mockDomain := &MockShoppingCartDomain{
 			AddItemDomainFunc: func(ctx context.Context, params domain.AddItemParams) error {
@@ -128,7 +213,7 @@ func TestAddItem(t *testing.T) {
 		r := httptest.NewRequest(http.MethodPost, "/add-item", bytes.NewBuffer([]byte(itemJSON)))
 		w := httptest.NewRecorder()
 
-		handler.AddItem().ServeHTTP(w, r)
+		handler.AddItem().ServeHTTP(w, asCustomer(r, 123))
 
 		if w.Code != http.StatusBadRequest {
 			t.Errorf("expected status %v, got %v", http.StatusBadRequest, w.Code)
@@ -261,6 +346,69 @@ func TestClearCart(t *testing.T) {
 	}
 }
 
+func TestPublishShoppingCartClosedRestaurant(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"Closed restaurant", domain.ErrRestaurantClosed, http.StatusConflict},
+		{"Restaurant service unavailable", errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDomain := &MockShoppingCartDomain{
+				CheckRestaurantOpenDomainFunc: func(ctx context.Context, restaurantId int) error {
+					return tt.checkErr
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(`{"comment": ""}`))
+			req.SetPathValue("customerId", "123")
+			handler.PublishShoppingCart().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
+func TestPublishShoppingCartDelivery(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"Outside the delivery zone", domain.ErrOutsideDeliveryZone, http.StatusConflict},
+		{"Below the minimum order", domain.ErrBelowMinimumOrder, http.StatusConflict},
+		{"Customer service unavailable", errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDomain := &MockShoppingCartDomain{
+				CheckDeliveryDomainFunc: func(ctx context.Context, cart *db.ShoppingCart) error {
+					return tt.checkErr
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(`{"comment": ""}`))
+			req.SetPathValue("customerId", "123")
+			handler.PublishShoppingCart().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
 func TestConsumeMenuItem(t *testing.T) {
 	mockDomain := &MockShoppingCartDomain{}
 	handler := NewShoppingCartHandler(mockDomain)
@@ -299,3 +447,146 @@ func TestConsumeMenuItem(t *testing.T) {
 	}
 
 }
+
+func TestPublishShoppingCartPrices(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"Prices changed", domain.ErrPricesChanged, http.StatusConflict},
+		{"Item no longer on the menu", domain.ErrItemUnavailable, http.StatusConflict},
+		{"Restaurant service unavailable", errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDomain := &MockShoppingCartDomain{
+				CheckPricesDomainFunc: func(ctx context.Context, cart *db.ShoppingCart) error {
+					return tt.checkErr
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(`{"comment": ""}`))
+			req.SetPathValue("customerId", "123")
+			handler.PublishShoppingCart().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
+func TestPublishShoppingCartAvailability(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"Unavailable item", domain.ErrItemUnavailable, http.StatusConflict},
+		{"Restaurant service unavailable", errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDomain := &MockShoppingCartDomain{
+				CheckAvailabilityDomainFunc: func(ctx context.Context, cart *db.ShoppingCart) error {
+					return tt.checkErr
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(`{"comment": ""}`))
+			req.SetPathValue("customerId", "123")
+			handler.PublishShoppingCart().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
+
+func TestApplyPromotion(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		applyErr   error
+		wantStatus int
+	}{
+		{"Applied", `{"code": "WELCOME10"}`, nil, http.StatusOK},
+		{"No code", `{}`, nil, http.StatusBadRequest},
+		{"Not applicable", `{"code": "WELCOME10"}`, fmt.Errorf("%w: WELCOME10 only applies to a first order", domain.ErrPromotionNotApplicable), http.StatusConflict},
+		{"Order service unavailable", `{"code": "WELCOME10"}`, errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotCode string
+			mockDomain := &MockShoppingCartDomain{
+				ApplyPromotionDomainFunc: func(ctx context.Context, customerId int, code string) (*db.ShoppingCart, error) {
+					gotCode = code
+					if tt.applyErr != nil {
+						return nil, tt.applyErr
+					}
+					return &db.ShoppingCart{CustomerId: customerId, TotalAmount: 90, Discount: &db.CartDiscount{Code: code, Amount: 10}}, nil
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(tt.body))
+			req.SetPathValue("customerId", "123")
+			handler.ApplyPromotion().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var cart db.ShoppingCart
+			if err := json.NewDecoder(rec.Body).Decode(&cart); err != nil {
+				t.Fatalf("failed to decode cart: %v", err)
+			}
+			if gotCode != "WELCOME10" || cart.Discount == nil || cart.Discount.Amount != 10 {
+				t.Errorf("expected the discount of WELCOME10 in the cart, got %+v", cart.Discount)
+			}
+		})
+	}
+}
+
+func TestPublishShoppingCartPromotion(t *testing.T) {
+	tests := []struct {
+		name       string
+		checkErr   error
+		wantStatus int
+	}{
+		{"Promotion expired", fmt.Errorf("%w: WELCOME10 expired", domain.ErrPromotionNotApplicable), http.StatusConflict},
+		{"Order service unavailable", errors.New("connection refused"), http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDomain := &MockShoppingCartDomain{
+				CheckPromotionDomainFunc: func(ctx context.Context, cart *db.ShoppingCart) error {
+					return tt.checkErr
+				},
+			}
+			handler := NewShoppingCartHandler(mockDomain)
+			rec := httptest.NewRecorder()
+
+			req, _ := http.NewRequest(http.MethodPost, "", bytes.NewBufferString(`{"comment": ""}`))
+			req.SetPathValue("customerId", "123")
+			handler.PublishShoppingCart().ServeHTTP(rec, req)
+
+			if got := rec.Result().StatusCode; got != tt.wantStatus {
+				t.Fatalf("expected status %v, got %v", tt.wantStatus, got)
+			}
+		})
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/rs/cors"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 
+	"github.com/rasm445f/soft-exam-2/auth"
 	"github.com/rasm445f/soft-exam-2/broker"
+	"github.com/rasm445f/soft-exam-2/clients"
 	"github.com/rasm445f/soft-exam-2/db"
 	_ "github.com/rasm445f/soft-exam-2/docs"
 	"github.com/rasm445f/soft-exam-2/domain"
@@ -24,21 +26,27 @@ func run() (http.Handler, error) {
 	}
 
 	repo := db.NewShoppingCartRepository(redisClient)
-	shoppingDomain := domain.NewShoppingCartDomain(repo)
+	// Customers and promotions are looked up as this service, as carts are
+	// also filled from broker events, without a customer's token
+	services := auth.NewServiceClientFromEnv()
+	shoppingDomain := domain.NewShoppingCartDomain(repo, clients.NewRestaurantClient(), clients.NewCustomerClient(services), clients.NewPromotionClient(services))
 	shoppingHandler := handlers.NewShoppingCartHandler(shoppingDomain)
 
 	mux := http.NewServeMux()
 
-	// Routes
+	// Routes. Customers reach only their own cart, the broker consumer is
+	// for admins
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("GET /api/docs/", httpSwagger.WrapHandler)
-	mux.HandleFunc("POST /api/shopping", shoppingHandler.AddItem())
-	mux.HandleFunc("PATCH /api/shopping/{customerId}/{itemId}", shoppingHandler.UpdateCart())
-	mux.HandleFunc("GET /api/shopping/{customerId}", shoppingHandler.ViewCart())
-	mux.HandleFunc("DELETE /api/shopping/{customerId}", shoppingHandler.ClearCart())
+	mux.HandleFunc("POST /api/shopping", auth.Allow(shoppingHandler.AddItem(), auth.Roles(auth.RoleCustomer)))
+	mux.HandleFunc("PATCH /api/shopping/{customerId}/{itemId}", auth.Allow(shoppingHandler.UpdateCart(), auth.Self("customerId")))
+	mux.HandleFunc("GET /api/shopping/{customerId}", auth.Allow(shoppingHandler.ViewCart(), auth.Self("customerId")))
+	mux.HandleFunc("DELETE /api/shopping/{customerId}", auth.Allow(shoppingHandler.ClearCart(), auth.Self("customerId")))
+	mux.HandleFunc("POST /api/shopping/{customerId}/promotion", auth.Allow(shoppingHandler.ApplyPromotion(), auth.Self("customerId")))
+	mux.HandleFunc("DELETE /api/shopping/{customerId}/promotion", auth.Allow(shoppingHandler.RemovePromotion(), auth.Self("customerId")))
 	// Broker
-	mux.HandleFunc("GET /api/shopping/consume", shoppingHandler.ConsumeMenuItem())
-	mux.HandleFunc("POST /api/shopping/publish/{customerId}", shoppingHandler.PublishShoppingCart())
+	mux.HandleFunc("GET /api/shopping/consume", auth.Allow(shoppingHandler.ConsumeMenuItem()))
+	mux.HandleFunc("POST /api/shopping/publish/{customerId}", auth.Allow(shoppingHandler.PublishShoppingCart(), auth.Self("customerId")))
 
 	//CORS stuff
 	corsHandler := cors.New(cors.Options{
@@ -49,7 +57,10 @@ func run() (http.Handler, error) {
 		Debug:            true,
 	})
 
-	metrics := metrics.MetricsMiddleware(mux)
+	// Access tokens are verified against the keys the customer service
+	// publishes, and the caller's identity put into the request context
+	verifier := auth.NewVerifierFromEnv()
+	metrics := metrics.MetricsMiddleware(verifier.Middleware(mux))
 	handler := corsHandler.Handler(metrics)
 
 	//test change for cicd